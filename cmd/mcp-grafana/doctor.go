@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// doctorTimeout limits how long each tool call made by `mcp-grafana doctor`
+// may take.
+const doctorTimeout = 30 * time.Second
+
+// doctorProbe is a safe, read-only call exercising a tool.
+type doctorProbe struct {
+	// datasourceType is the type of datasource the tool queries, if any. The
+	// probe is skipped if there is no datasource of this type.
+	datasourceType string
+
+	// args returns the arguments of the call, given the UID of a datasource
+	// of datasourceType.
+	args func(uid string) map[string]any
+
+	// permission is what the token needs to use the tool.
+	permission string
+}
+
+func noArgs(string) map[string]any { return map[string]any{} }
+
+// doctorProbes are the calls made by `mcp-grafana doctor` for each tool.
+// Tools without a probe, such as those modifying Grafana or needing the ID of
+// an existing resource, are skipped.
+var doctorProbes = map[string]doctorProbe{
+	"search_dashboards": {args: func(string) map[string]any { return map[string]any{"query": ""} }, permission: "dashboards:read"},
+	"list_datasources":  {args: noArgs, permission: "datasources:read"},
+	"get_datasource_by_uid": {datasourceType: "*", permission: "datasources:read", args: func(uid string) map[string]any {
+		return map[string]any{"uid": uid}
+	}},
+	"list_prometheus_label_names": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "limit": 1}
+	}},
+	"list_prometheus_metric_names": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "limit": 1}
+	}},
+	"query_prometheus": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "vector(1)", "startTime": "now", "queryType": "instant"}
+	}},
+	"query_prometheus_instant": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "vector(1)"}
+	}},
+	"query_prometheus_batch": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "queries": []map[string]any{{"name": "one", "expr": "vector(1)"}}, "startTime": "now", "queryType": "instant"}
+	}},
+	"list_prometheus_label_values": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "labelName": "__name__", "limit": 1}
+	}},
+	"list_prometheus_series": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "match": []string{"up"}, "limit": 1}
+	}},
+	"query_prometheus_exemplars": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "up", "startRfc3339": "now-5m", "limit": 1}
+	}},
+	"list_prometheus_rules": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "type": "alerting"}
+	}},
+	"get_prometheus_tsdb_status": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "limit": 1}
+	}},
+	"get_prometheus_topk": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "up", "k": 1}
+	}},
+	"suggest_prometheus_recording_rule": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "count(up)", "startTime": "now-5m"}
+	}},
+	"estimate_prometheus_query_cost": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "count(up)", "startTime": "now-5m"}
+	}},
+	"compare_prometheus_windows": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "expr": "count(up)", "startTime": "now-5m", "offset": "5m"}
+	}},
+	"list_alertmanager_alerts": {datasourceType: "alertmanager", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "limit": 1}
+	}},
+	"list_alertmanager_alert_groups": {datasourceType: "alertmanager", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid}
+	}},
+	"list_alertmanager_silences": {datasourceType: "alertmanager", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid}
+	}},
+	"validate_promql": {args: func(string) map[string]any { return map[string]any{"expr": "up"} }},
+	"list_prometheus_metric_metadata": {datasourceType: "prometheus", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "limit": 1}
+	}},
+	"list_loki_label_names": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid}
+	}},
+	"query_loki_logs": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "logql": `{mcp_grafana_doctor="1"}`, "startRfc3339": "now-5m", "limit": 1}
+	}},
+	"query_loki_instant": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "logql": "vector(1)"}
+	}},
+	"list_loki_label_values": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "labelName": "mcp_grafana_doctor"}
+	}},
+	"list_loki_series": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "match": []string{`{mcp_grafana_doctor="1"}`}, "limit": 1}
+	}},
+	"list_loki_rules": {datasourceType: "loki", permission: "alert.rules.external:read", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid}
+	}},
+	"find_traces_for_logs": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "logql": `{mcp_grafana_doctor="1"}`, "startRfc3339": "now-5m", "limit": 1}
+	}},
+	"summarize_loki_errors": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "startRfc3339": "now-5m", "serviceLabel": "mcp_grafana_doctor", "limit": 1}
+	}},
+	"get_loki_status": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid}
+	}},
+	"detect_loki_anomalies": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "selector": `{mcp_grafana_doctor="1"}`, "startRfc3339": "now-5m", "baselineStartRfc3339": "now-1h"}
+	}},
+	"query_loki_stats": {datasourceType: "loki", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "logql": `{mcp_grafana_doctor="1"}`}
+	}},
+	"list_tempo_tag_names": {datasourceType: "tempo", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "startTime": "now-5m"}
+	}},
+	"list_tempo_tag_values": {datasourceType: "tempo", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"datasourceUid": uid, "tagName": "resource.service.name", "startTime": "now-5m"}
+	}},
+	"list_pyroscope_label_names": {datasourceType: "grafana-pyroscope-datasource", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"data_source_uid": uid}
+	}},
+	"list_pyroscope_profile_types": {datasourceType: "grafana-pyroscope-datasource", permission: "datasources:query", args: func(uid string) map[string]any {
+		return map[string]any{"data_source_uid": uid}
+	}},
+	"list_alert_rules":    {args: func(string) map[string]any { return map[string]any{"limit": 1} }, permission: "alert.rules:read"},
+	"list_contact_points": {args: func(string) map[string]any { return map[string]any{"limit": 1} }, permission: "alert.notifications:read"},
+	"list_incidents":      {args: func(string) map[string]any { return map[string]any{"limit": 1} }, permission: "access to the Grafana Incident app"},
+	"build_incident_timeline": {args: func(string) map[string]any {
+		return map[string]any{"service": "mcp-grafana-doctor", "startTime": "now-5m"}
+	}, permission: "annotations:read"},
+	"list_oncall_teams":        {args: noArgs, permission: "access to the Grafana OnCall app"},
+	"list_oncall_schedules":    {args: noArgs, permission: "access to the Grafana OnCall app"},
+	"list_sift_investigations": {args: func(string) map[string]any { return map[string]any{"limit": 1} }, permission: "access to the Grafana Machine Learning app"},
+	"list_teams":               {args: func(string) map[string]any { return map[string]any{"query": ""} }, permission: "teams:read"},
+}
+
+// httpStatusPattern finds the HTTP status in the errors of the Grafana and
+// datasource clients, e.g. "[GET /search][403]", "(status 404)" or "client
+// error: 401".
+var httpStatusPattern = regexp.MustCompile(`(?:status code:? |status:? |client error: |\]\[)(\d{3})\b`)
+
+// runDoctor calls each enabled tool of s with a safe probe against the
+// configured Grafana instance and prints whether it worked. It returns an
+// error if any tool failed.
+func runDoctor(ctx context.Context, s *server.MCPServer, gc mcpgrafana.GrafanaConfig, out io.Writer) error {
+	ctx = mcpgrafana.ComposedStdioContextFunc(gc)(ctx)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	fmt.Fprintf(out, "Checking the enabled tools against Grafana at %s.\n\n", config.URL)
+
+	datasources := map[string]string{}
+	resp, err := mcpgrafana.GrafanaClientFromContext(ctx).Datasources.GetDataSources()
+	if err != nil {
+		fmt.Fprintf(out, "Could not list datasources%s, datasource tools will be skipped: %v\n\n", statusSuffix(err.Error(), "datasources:read"), err)
+	} else {
+		for _, ds := range resp.Payload {
+			if _, ok := datasources[ds.Type]; !ok {
+				datasources[ds.Type] = ds.UID
+			}
+			if _, ok := datasources["*"]; !ok {
+				datasources["*"] = ds.UID
+			}
+		}
+	}
+
+	listed, ok := handleDoctorMessage(ctx, s, mcp.MethodToolsList, map[string]any{}).(mcp.ListToolsResult)
+	if !ok {
+		return fmt.Errorf("could not list the enabled tools")
+	}
+
+	var passed, failed, skipped int
+	for _, tool := range listed.Tools {
+		probe, ok := doctorProbes[tool.Name]
+		if !ok {
+			skipped++
+			fmt.Fprintf(out, "SKIP  %s: no safe check\n", tool.Name)
+			continue
+		}
+		uid := datasources[probe.datasourceType]
+		if probe.datasourceType != "" && uid == "" {
+			skipped++
+			fmt.Fprintf(out, "SKIP  %s: no %s datasource\n", tool.Name, strings.ReplaceAll(probe.datasourceType, "*", "usable"))
+			continue
+		}
+
+		if msg := callDoctorProbe(ctx, s, tool.Name, probe.args(uid)); msg != "" {
+			failed++
+			fmt.Fprintf(out, "FAIL  %s%s: %s\n", tool.Name, statusSuffix(msg, probe.permission), msg)
+			continue
+		}
+		passed++
+		fmt.Fprintf(out, "OK    %s\n", tool.Name)
+	}
+
+	fmt.Fprintf(out, "\n%d OK, %d failed, %d skipped.\n", passed, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("%d tools failed", failed)
+	}
+	return nil
+}
+
+// callDoctorProbe calls a tool, returning the error message if it failed.
+func callDoctorProbe(ctx context.Context, s *server.MCPServer, name string, args map[string]any) string {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	switch result := handleDoctorMessage(ctx, s, mcp.MethodToolsCall, map[string]any{"name": name, "arguments": args}).(type) {
+	case mcp.CallToolResult:
+		if !result.IsError {
+			return ""
+		}
+		var texts []string
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+		return strings.Join(texts, " ")
+	case error:
+		return result.Error()
+	default:
+		return fmt.Sprintf("unexpected response %v", result)
+	}
+}
+
+// handleDoctorMessage sends a request to the server in process, returning
+// its result or an error.
+func handleDoctorMessage(ctx context.Context, s *server.MCPServer, method mcp.MCPMethod, params any) any {
+	message, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	switch resp := s.HandleMessage(ctx, message).(type) {
+	case mcp.JSONRPCResponse:
+		return resp.Result
+	case mcp.JSONRPCError:
+		return fmt.Errorf("%s", resp.Error.Message)
+	default:
+		return fmt.Errorf("unexpected response %T", resp)
+	}
+}
+
+// statusSuffix describes the HTTP status found in an error message and, for
+// authorization errors, the permission the token needs.
+func statusSuffix(msg, permission string) string {
+	m := httpStatusPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+	if m[1] == "401" || m[1] == "403" {
+		return fmt.Sprintf(" (HTTP %s, requires %s)", m[1], permission)
+	}
+	return fmt.Sprintf(" (HTTP %s)", m[1])
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/client"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// defaultInitConfigFile is where `mcp-grafana init` writes the client
+// configuration unless another path is given.
+const defaultInitConfigFile = "mcp-grafana.json"
+
+// mcpServerConfig is the configuration of an MCP server in the `mcpServers`
+// section of Claude Desktop and Cursor configuration files.
+type mcpServerConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+}
+
+// initWizard walks a user through verifying their Grafana URL and
+// credentials and writing the MCP client configuration for the server.
+type initWizard struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// runInit runs the `mcp-grafana init` wizard.
+func runInit(in io.Reader, out io.Writer) error {
+	w := &initWizard{in: bufio.NewScanner(in), out: out}
+	fmt.Fprintln(out, "This will check that mcp-grafana can reach Grafana and write the configuration for your MCP client.")
+
+	var grafanaURL, apiKey string
+	for {
+		grafanaURL = strings.TrimRight(w.prompt("Grafana URL", stringOrDefault(os.Getenv("GRAFANA_URL"), "http://localhost:3000")), "/")
+		apiKey = w.promptToken("Service account token (leave empty for none)")
+		err := w.verify(grafanaURL, apiKey)
+		if err == nil {
+			break
+		}
+		fmt.Fprintf(out, "Could not use Grafana at %s: %v\n", grafanaURL, err)
+		if !w.confirm("Try again?", true) {
+			return fmt.Errorf("could not verify the Grafana URL and credentials: %w", err)
+		}
+	}
+
+	command, err := os.Executable()
+	if err != nil {
+		command = "mcp-grafana"
+	}
+	config := map[string]any{
+		"mcpServers": map[string]mcpServerConfig{
+			"grafana": {
+				Command: command,
+				Args:    []string{},
+				Env: map[string]string{
+					"GRAFANA_URL":     grafanaURL,
+					"GRAFANA_API_KEY": apiKey,
+				},
+			},
+		},
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode configuration: %w", err)
+	}
+
+	path := w.prompt("Write the configuration to", defaultInitConfigFile)
+	// The file holds the service account token, so only the user may read it.
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write configuration: %w", err)
+	}
+	fmt.Fprintf(out, "\nWrote %s. Add the \"grafana\" server below to the \"mcpServers\" section of your client's configuration:\n\n%s\n\n", path, data)
+	fmt.Fprintf(out, "Claude Desktop: %s\n", claudeDesktopConfigPath())
+	fmt.Fprintf(out, "Cursor: %s (or .cursor/mcp.json in a project)\n", filepath.Join(homeDir(), ".cursor", "mcp.json"))
+	return nil
+}
+
+// prompt asks for a value, returning def if the answer is empty.
+func (w *initWizard) prompt(question, def string) string {
+	return w.ask(question, def, def)
+}
+
+// promptToken asks for a token, defaulting to the GRAFANA_API_KEY
+// environment variable without printing it.
+func (w *initWizard) promptToken(question string) string {
+	def := os.Getenv("GRAFANA_API_KEY")
+	shown := ""
+	if def != "" {
+		shown = "from GRAFANA_API_KEY"
+	}
+	return w.ask(question, shown, def)
+}
+
+func (w *initWizard) ask(question, shown, def string) string {
+	if shown != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, shown)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	if !w.in.Scan() {
+		return def
+	}
+	return stringOrDefault(strings.TrimSpace(w.in.Text()), def)
+}
+
+// confirm asks a yes or no question.
+func (w *initWizard) confirm(question string, def bool) bool {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	answer := strings.ToLower(w.prompt(fmt.Sprintf("%s (%s)", question, choices), ""))
+	if answer == "" {
+		return def
+	}
+	return answer == "y" || answer == "yes"
+}
+
+// verify checks that Grafana is reachable at the URL and that the token can
+// list datasources, printing the Grafana version and the datasources found.
+func (w *initWizard) verify(grafanaURL, apiKey string) error {
+	c, err := newInitGrafanaClient(grafanaURL, apiKey)
+	if err != nil {
+		return err
+	}
+	health, err := c.Health.GetHealth()
+	if err != nil {
+		return fmt.Errorf("checking health: %w", err)
+	}
+	fmt.Fprintf(w.out, "Connected to Grafana %s.\n", health.Payload.Version)
+
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return fmt.Errorf("listing datasources, check the token has the Viewer role or datasources:read permission: %w", err)
+	}
+	fmt.Fprintf(w.out, "Found %d datasources:\n", len(resp.Payload))
+	for _, ds := range resp.Payload {
+		fmt.Fprintf(w.out, "  %-30s %-20s %s\n", ds.Name, ds.Type, ds.UID)
+	}
+	return nil
+}
+
+// newInitGrafanaClient creates a Grafana client, returning an error rather
+// than panicking if the URL is invalid.
+func newInitGrafanaClient(grafanaURL, apiKey string) (c *client.GrafanaHTTPAPI, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return mcpgrafana.NewGrafanaClient(context.Background(), grafanaURL, apiKey), nil
+}
+
+// claudeDesktopConfigPath returns where Claude Desktop reads its
+// configuration on this platform.
+func claudeDesktopConfigPath() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(homeDir(), "Library", "Application Support", "Claude", "claude_desktop_config.json")
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Claude", "claude_desktop_config.json")
+	}
+	return filepath.Join(homeDir(), ".config", "Claude", "claude_desktop_config.json")
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "~"
+	}
+	return home
+}
+
+func stringOrDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
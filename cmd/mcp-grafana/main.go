@@ -10,6 +10,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 
@@ -47,10 +48,26 @@ func maybeAddTools(s *server.MCPServer, tf func(*server.MCPServer), enabledTools
 type disabledTools struct {
 	enabledTools string
 
+	// enableWriteTools enables tools which modify Grafana or datasource
+	// state. They are disabled by default.
+	enableWriteTools bool
+
+	// serviceCatalog configures the catalog used by the catalog tools, which
+	// are only enabled if it is set.
+	serviceCatalog tools.ServiceCatalogConfig
+
+	// desiredStateFile is the path of the desired-state file used by the
+	// check_config_drift tool when no desired state is given inline.
+	desiredStateFile string
+
+	// notificationSinks configures the sinks of the send_notification tool, which
+	// is only enabled if at least one is set.
+	notificationSinks tools.NotificationConfig
+
 	search, datasource, incident,
 	prometheus, loki, alerting,
 	dashboard, oncall, asserts, sift, admin,
-	pyroscope bool
+	pyroscope, tempo, catalog, notifications bool
 }
 
 // Configuration for the Grafana client.
@@ -63,10 +80,24 @@ type grafanaConfig struct {
 	tlsKeyFile    string
 	tlsCAFile     string
 	tlsSkipVerify bool
+
+	// How long tools cache rarely changing metadata for.
+	cacheTTL time.Duration
+
+	// The URL users reach Grafana at, used in links.
+	publicURL string
+
+	// Overrides of the defaults tools apply when arguments are omitted.
+	toolDefaults map[string]mcpgrafana.ToolDefaults
+
+	// Patterns of values masked in tool results.
+	redactions []mcpgrafana.Redaction
 }
 
 func (dt *disabledTools) addFlags() {
-	flag.StringVar(&dt.enabledTools, "enabled-tools", "search,datasource,incident,prometheus,loki,alerting,dashboard,oncall,asserts,sift,admin,pyroscope", "A comma separated list of tools enabled for this server. Can be overwritten entirely or by disabling specific components, e.g. --disable-search.")
+	flag.StringVar(&dt.enabledTools, "enabled-tools", "search,datasource,incident,prometheus,loki,alerting,dashboard,oncall,asserts,sift,admin,pyroscope,tempo,catalog,notifications", "A comma separated list of tools enabled for this server. Can be overwritten entirely or by disabling specific components, e.g. --disable-search.")
+
+	flag.BoolVar(&dt.enableWriteTools, "enable-write-tools", false, "Enable tools which modify Grafana or datasource state, such as datasource migration. These are disabled by default")
 
 	flag.BoolVar(&dt.search, "disable-search", false, "Disable search tools")
 	flag.BoolVar(&dt.datasource, "disable-datasource", false, "Disable datasource tools")
@@ -80,6 +111,25 @@ func (dt *disabledTools) addFlags() {
 	flag.BoolVar(&dt.sift, "disable-sift", false, "Disable sift tools")
 	flag.BoolVar(&dt.admin, "disable-admin", false, "Disable admin tools")
 	flag.BoolVar(&dt.pyroscope, "disable-pyroscope", false, "Disable pyroscope tools")
+	flag.BoolVar(&dt.tempo, "disable-tempo", false, "Disable tempo tools")
+	flag.BoolVar(&dt.catalog, "disable-catalog", false, "Disable service catalog tools")
+	flag.BoolVar(&dt.notifications, "disable-notifications", false, "Disable notification tools")
+
+	flag.StringVar(&dt.serviceCatalog.File, "service-catalog-file", "", "Path to a YAML file mapping service names to teams, repos, dashboards and labels, used by the lookup_service tool")
+	flag.StringVar(&dt.serviceCatalog.BackstageURL, "backstage-url", "", "Base URL of a Backstage instance whose catalog components are used by the lookup_service tool. The BACKSTAGE_TOKEN environment variable may hold an API token")
+
+	flag.StringVar(&dt.desiredStateFile, "desired-state-file", "", "Path to a JSON file describing the desired Grafana configuration, used by the check_config_drift tool")
+
+	flag.StringVar(&dt.notificationSinks.SlackWebhookURL, "notify-slack-webhook-url", "", "URL of a Slack incoming webhook the send_notification tool posts to")
+	flag.StringVar(&dt.notificationSinks.WebhookURL, "notify-webhook-url", "", "URL the send_notification tool POSTs notifications to as JSON")
+	flag.Func("notify-email", "Comma-separated email addresses the send_notification tool sends to, using Grafana's SMTP settings", func(s string) error {
+		for _, address := range strings.Split(s, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				dt.notificationSinks.EmailAddresses = append(dt.notificationSinks.EmailAddresses, address)
+			}
+		}
+		return nil
+	})
 }
 
 func (gc *grafanaConfig) addFlags() {
@@ -90,6 +140,25 @@ func (gc *grafanaConfig) addFlags() {
 	flag.StringVar(&gc.tlsKeyFile, "tls-key-file", "", "Path to TLS private key file for client authentication")
 	flag.StringVar(&gc.tlsCAFile, "tls-ca-file", "", "Path to TLS CA certificate file for server verification")
 	flag.BoolVar(&gc.tlsSkipVerify, "tls-skip-verify", false, "Skip TLS certificate verification (insecure)")
+
+	flag.StringVar(&gc.publicURL, "grafana-public-url", "", "URL users reach Grafana at, used to build links in tool results. Defaults to GRAFANA_URL, or to Grafana's root URL when GRAFANA_URL is a unix socket")
+
+	flag.DurationVar(&gc.cacheTTL, "cache-ttl", time.Minute, "How long to cache rarely changing metadata, such as datasources and Tempo tag names, per datasource. Set to 0 to disable caching")
+
+	flag.Func("tool-defaults", "Comma-separated overrides of the defaults tools apply when arguments are omitted, as [category.]setting=value, e.g. 'time-range=15m,loki.limit=50,prometheus.step=30s'. Settings are time-range, limit, max-limit, step, max-lookback, max-range, split-interval, timeout, retries and retry-backoff; categories are loki, tempo, prometheus and pyroscope", func(s string) error {
+		var err error
+		gc.toolDefaults, err = tools.ParseToolDefaults(s)
+		return err
+	})
+
+	flag.Func("redact", "Mask values matching a regular expression in all tool results, given as name=regex, e.g. 'ip=\\d+\\.\\d+\\.\\d+\\.\\d+'. Matches are replaced by a placeholder which is the same for equal values, such as <redacted:ip:kqzbdhxa>. May be repeated", func(s string) error {
+		r, err := mcpgrafana.ParseRedaction(s)
+		if err != nil {
+			return err
+		}
+		gc.redactions = append(gc.redactions, r)
+		return nil
+	})
 }
 
 func (dt *disabledTools) addTools(s *server.MCPServer) {
@@ -104,12 +173,28 @@ func (dt *disabledTools) addTools(s *server.MCPServer) {
 	maybeAddTools(s, tools.AddOnCallTools, enabledTools, dt.oncall, "oncall")
 	maybeAddTools(s, tools.AddAssertsTools, enabledTools, dt.asserts, "asserts")
 	maybeAddTools(s, tools.AddSiftTools, enabledTools, dt.sift, "sift")
-	maybeAddTools(s, tools.AddAdminTools, enabledTools, dt.admin, "admin")
+	maybeAddTools(s, func(s *server.MCPServer) { tools.AddAdminTools(s, dt.desiredStateFile) }, enabledTools, dt.admin, "admin")
 	maybeAddTools(s, tools.AddPyroscopeTools, enabledTools, dt.pyroscope, "pyroscope")
+	maybeAddTools(s, tools.AddTempoTools, enabledTools, dt.tempo, "tempo")
+	if dt.serviceCatalog.Enabled() {
+		maybeAddTools(s, func(s *server.MCPServer) { tools.AddServiceCatalogTools(s, dt.serviceCatalog) }, enabledTools, dt.catalog, "catalog")
+	}
+	if dt.notificationSinks.Enabled() {
+		maybeAddTools(s, func(s *server.MCPServer) { tools.AddNotificationTools(s, dt.notificationSinks) }, enabledTools, dt.notifications, "notifications")
+	}
+
+	if dt.enableWriteTools {
+		maybeAddTools(s, tools.AddDatasourceWriteTools, enabledTools, dt.datasource, "datasource")
+		maybeAddTools(s, tools.AddDashboardWriteTools, enabledTools, dt.dashboard, "dashboard")
+		maybeAddTools(s, tools.AddLokiWriteTools, enabledTools, dt.loki, "loki")
+		maybeAddTools(s, tools.AddAlertingWriteTools, enabledTools, dt.alerting, "alerting")
+		maybeAddTools(s, tools.AddSLOWriteTools, enabledTools, dt.tempo, "tempo")
+	}
 }
 
 func newServer(dt disabledTools) *server.MCPServer {
-	s := server.NewMCPServer("mcp-grafana", version())
+	// Tool calls are recorded so that export_investigation can export them.
+	s := server.NewMCPServer("mcp-grafana", version(), server.WithToolHandlerMiddleware(tools.RecordToolCalls))
 	dt.addTools(s)
 	return s
 }
@@ -152,6 +237,19 @@ func run(transport, addr, basePath, endpointPath string, logLevel slog.Level, dt
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		if err := runInit(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	// `mcp-grafana doctor` takes the same flags as the server.
+	doctor := len(os.Args) > 1 && os.Args[1] == "doctor"
+	if doctor {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	var transport string
 	flag.StringVar(&transport, "t", "stdio", "Transport type (stdio, sse or streamable-http)")
 	flag.StringVar(
@@ -165,19 +263,29 @@ func main() {
 	endpointPath := flag.String("endpoint-path", "/mcp", "Endpoint path for the streamable-http server")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
 	showVersion := flag.Bool("version", false, "Print the version and exit")
+	latestToolVersions := flag.Bool("latest-tool-versions", false, "Serve the latest version of tools with several versions under their unversioned names, instead of the oldest version kept for compatibility")
 	var dt disabledTools
 	dt.addFlags()
 	var gc grafanaConfig
 	gc.addFlags()
 	flag.Parse()
+	dt.serviceCatalog.BackstageToken = os.Getenv("BACKSTAGE_TOKEN")
+	if s := os.Getenv("GRAFANA_TOOL_DEFAULTS"); s != "" && gc.toolDefaults == nil {
+		var err error
+		if gc.toolDefaults, err = tools.ParseToolDefaults(s); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: GRAFANA_TOOL_DEFAULTS:", err)
+			os.Exit(1)
+		}
+	}
 
 	if *showVersion {
 		fmt.Println(version())
 		os.Exit(0)
 	}
+	mcpgrafana.ServeLatestToolVersions = *latestToolVersions
 
 	// Convert local grafanaConfig to mcpgrafana.GrafanaConfig
-	grafanaConfig := mcpgrafana.GrafanaConfig{Debug: gc.debug}
+	grafanaConfig := mcpgrafana.GrafanaConfig{Debug: gc.debug, PublicURL: gc.publicURL, CacheTTL: gc.cacheTTL, ToolDefaults: gc.toolDefaults, Redactions: gc.redactions, WriteToolsEnabled: dt.enableWriteTools}
 	if gc.tlsCertFile != "" || gc.tlsKeyFile != "" || gc.tlsCAFile != "" || gc.tlsSkipVerify {
 		grafanaConfig.TLSConfig = &mcpgrafana.TLSConfig{
 			CertFile:   gc.tlsCertFile,
@@ -187,6 +295,15 @@ func main() {
 		}
 	}
 
+	if doctor {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: parseLevel(*logLevel)})))
+		if err := runDoctor(context.Background(), newServer(dt), grafanaConfig, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(transport, *addr, *basePath, *endpointPath, parseLevel(*logLevel), dt, grafanaConfig); err != nil {
 		panic(err)
 	}
@@ -1,18 +1,23 @@
 package mcpgrafana
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
+	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
 	"github.com/grafana/grafana-openapi-client-go/client"
+	"github.com/grafana/grafana-openapi-client-go/pkg/transport"
 	"github.com/grafana/incident-go"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -40,6 +45,81 @@ func urlAndAPIKeyFromHeaders(req *http.Request) (string, string) {
 	return u, apiKey
 }
 
+// grafanaURLFromRequest returns the Grafana URL and API key of a request,
+// taken from its headers or else from the environment. URLs given in headers
+// must be http or https URLs: unix sockets are only accepted from the
+// environment, as a client could otherwise make the server send the Grafana
+// credentials to any local socket.
+func grafanaURLFromRequest(req *http.Request) (string, string, error) {
+	u, apiKey := urlAndAPIKeyFromHeaders(req)
+	uEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
+	if apiKey == "" {
+		apiKey = apiKeyEnv
+	}
+	if u == "" {
+		return cmp.Or(uEnv, defaultGrafanaURL), apiKey, nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", "", fmt.Errorf("invalid %s header %q: must be an http or https URL", grafanaURLHeader, u)
+	}
+	return u, apiKey, nil
+}
+
+// grafanaConfigErrorKey is the context key of an error in the Grafana
+// configuration of a request, such as an invalid Grafana URL header.
+type grafanaConfigErrorKey struct{}
+
+func withGrafanaConfigError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, grafanaConfigErrorKey{}, err)
+}
+
+// grafanaConfigError returns the error in the Grafana configuration of the
+// request in the context, if any. Tool calls fail with it.
+func grafanaConfigError(ctx context.Context) error {
+	err, _ := ctx.Value(grafanaConfigErrorKey{}).(error)
+	return err
+}
+
+// unixSocketHost is the host of the HTTP URLs built for a Grafana reached
+// over a unix socket. It is only used in the Host header.
+const unixSocketHost = "localhost"
+
+// resolveGrafanaURL resolves a Grafana URL to the HTTP URL requests are built
+// from and, if Grafana is reached over a unix socket, the socket's path.
+// Sockets are given as unix:///path/to/grafana.sock, for a Grafana served at
+// the root of the socket, or as http+unix://%2Fpath%2Fto%2Fgrafana.sock/subpath
+// with the socket path escaped. Other URLs, including ones with IPv6 literal
+// hosts such as http://[::1]:3000, are returned unchanged.
+func resolveGrafanaURL(u string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(u, "unix://"):
+		socket := strings.TrimPrefix(u, "unix://")
+		if !strings.HasPrefix(socket, "/") {
+			return "", "", fmt.Errorf("invalid Grafana URL %s: the socket path must be absolute", u)
+		}
+		return "http://" + unixSocketHost, socket, nil
+	case strings.HasPrefix(u, "http+unix://"):
+		// The escaped socket path is not a valid URL host, so it is split
+		// off before parsing the rest of the URL.
+		rest := strings.TrimPrefix(u, "http+unix://")
+		host, path, _ := strings.Cut(rest, "/")
+		socket, err := url.PathUnescape(host)
+		if err != nil || socket == "" {
+			return "", "", fmt.Errorf("invalid Grafana URL %s: invalid socket path %q", u, host)
+		}
+		httpURL, err := url.Parse("http://" + unixSocketHost + "/" + path)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid Grafana URL %s: %w", u, err)
+		}
+		return strings.TrimRight(httpURL.String(), "/"), socket, nil
+	}
+	if _, err := url.Parse(u); err != nil {
+		return "", "", fmt.Errorf("invalid Grafana URL %s: %w", u, err)
+	}
+	return u, "", nil
+}
+
 // grafanaConfigKey is the context key for Grafana configuration.
 type grafanaConfigKey struct{}
 
@@ -70,8 +150,68 @@ type GrafanaConfig struct {
 	// It is used for on-behalf-of auth in Grafana Cloud.
 	IDToken string
 
+	// UnixSocket is the path of the unix socket Grafana is reached over, if
+	// any. URL is then an HTTP URL on localhost, used to build requests.
+	UnixSocket string
+
+	// PublicURL is the URL users reach Grafana at, used to build links in tool
+	// results. If empty, links use URL, or Grafana's root URL (appUrl) when
+	// Grafana is reached over a unix socket.
+	PublicURL string
+
 	// TLSConfig holds TLS configuration for all Grafana clients.
 	TLSConfig *TLSConfig
+
+	// CacheTTL is how long rarely changing metadata, such as datasources and
+	// Tempo tag names, is cached for by tools. Caching is disabled if zero.
+	CacheTTL time.Duration
+
+	// ToolDefaults overrides the defaults tools apply when optional arguments
+	// are omitted, keyed by tool category (e.g. "loki"). The entry with an
+	// empty key applies to all categories. Zero fields keep the built-in
+	// defaults.
+	ToolDefaults map[string]ToolDefaults
+
+	// Redactions mask values matching patterns, such as IP addresses, in
+	// all tool results.
+	Redactions []Redaction
+
+	// WriteToolsEnabled is whether the server runs with write tools enabled.
+	// Tools which only modify Grafana when asked to, such as
+	// export_investigation saving a snapshot, check it.
+	WriteToolsEnabled bool
+}
+
+// ToolDefaults are the defaults applied by a category of tools when optional
+// arguments are omitted.
+type ToolDefaults struct {
+	// TimeRange is how far back queries look when no start time is given.
+	TimeRange time.Duration
+	// Limit is the number of results returned when no limit is given.
+	Limit int
+	// MaxLimit is the largest limit that may be requested.
+	MaxLimit int
+	// Step is the resolution of range queries when no step is given.
+	Step time.Duration
+	// MaxLookback is how far back queries may look. Older start times are
+	// clamped. Zero means unlimited.
+	MaxLookback time.Duration
+	// MaxRange is the longest time range a query may cover. Longer ranges
+	// are clamped to their most recent part. Zero means unlimited.
+	MaxRange time.Duration
+	// SplitInterval is the longest time range queried at once. Longer log
+	// queries are split into sequential queries of at most this range. Zero
+	// means queries are not split. Only Loki splits queries.
+	SplitInterval time.Duration
+	// Timeout is how long a datasource request may take, including retries.
+	// Zero means no timeout.
+	Timeout time.Duration
+	// Retries is how many times datasource requests failing with connection
+	// errors or a 429, 502, 503 or 504 status are retried.
+	Retries int
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further retry. Datasources can request longer waits with Retry-After.
+	RetryBackoff time.Duration
 }
 
 // WithGrafanaConfig adds Grafana configuration to the context.
@@ -138,6 +278,28 @@ func (tc *TLSConfig) HTTPTransport(defaultTransport *http.Transport) (http.Round
 	return transport, nil
 }
 
+// HTTPTransport returns the transport used for requests to Grafana, based on
+// defaultTransport. It uses the TLS configuration and dials the unix socket,
+// if set, and is defaultTransport itself otherwise.
+func (c GrafanaConfig) HTTPTransport(defaultTransport *http.Transport) (http.RoundTripper, error) {
+	if c.TLSConfig == nil && c.UnixSocket == "" {
+		return defaultTransport, nil
+	}
+	rt, err := c.TLSConfig.HTTPTransport(defaultTransport)
+	if err != nil {
+		return nil, err
+	}
+	t := rt.(*http.Transport)
+	if c.UnixSocket != "" {
+		socket := c.UnixSocket
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	return t, nil
+}
+
 // ExtractGrafanaInfoFromEnv is a StdioContextFunc that extracts Grafana configuration
 // from environment variables and injects a configured client into the context.
 var ExtractGrafanaInfoFromEnv server.StdioContextFunc = func(ctx context.Context) context.Context {
@@ -145,16 +307,21 @@ var ExtractGrafanaInfoFromEnv server.StdioContextFunc = func(ctx context.Context
 	if u == "" {
 		u = defaultGrafanaURL
 	}
-	parsedURL, err := url.Parse(u)
+	httpURL, socket, err := resolveGrafanaURL(u)
+	if err != nil {
+		panic(err)
+	}
+	parsedURL, err := url.Parse(httpURL)
 	if err != nil {
 		panic(fmt.Errorf("invalid Grafana URL %s: %w", u, err))
 	}
-	slog.Info("Using Grafana configuration", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
+	slog.Info("Using Grafana configuration", "url", parsedURL.Redacted(), "unix_socket", socket, "api_key_set", apiKey != "")
 
 	// Get existing config or create a new one.
 	// This will respect the existing debug flag, if set.
 	config := GrafanaConfigFromContext(ctx)
-	config.URL = u
+	config.URL = httpURL
+	config.UnixSocket = socket
 	config.APIKey = apiKey
 	return WithGrafanaConfig(ctx, config)
 }
@@ -167,23 +334,28 @@ type httpContextFunc func(ctx context.Context, req *http.Request) context.Contex
 // ExtractGrafanaInfoFromHeaders is a HTTPContextFunc that extracts Grafana configuration
 // from request headers and injects a configured client into the context.
 var ExtractGrafanaInfoFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
-	u, apiKey := urlAndAPIKeyFromHeaders(req)
-	uEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
-	if u == "" {
-		u = uEnv
-	}
-	if u == "" {
-		u = defaultGrafanaURL
+	u, apiKey, err := grafanaURLFromRequest(req)
+	if err != nil {
+		slog.Error("Invalid Grafana URL", "error", err)
+		return withGrafanaConfigError(ctx, err)
 	}
-	if apiKey == "" {
-		apiKey = apiKeyEnv
+	httpURL, socket, err := resolveGrafanaURL(u)
+	if err != nil {
+		slog.Error("Invalid Grafana URL", "error", err)
+		return withGrafanaConfigError(ctx, err)
 	}
 
 	// Get existing config or create a new one.
 	// This will respect the existing debug flag, if set.
 	config := GrafanaConfigFromContext(ctx)
-	config.URL = u
+	config.URL = httpURL
+	config.UnixSocket = socket
 	config.APIKey = apiKey
+	if req.Header.Get(grafanaURLHeader) != "" {
+		// The configured public URL is for the instance in the environment,
+		// not the one named by the request.
+		config.PublicURL = ""
+	}
 	return WithGrafanaConfig(ctx, config)
 }
 
@@ -227,7 +399,11 @@ func NewGrafanaClient(ctx context.Context, grafanaURL, apiKey string) *client.Gr
 		grafanaURL = defaultGrafanaURL
 	}
 
-	parsedURL, err = url.Parse(grafanaURL)
+	httpURL, socket, err := resolveGrafanaURL(grafanaURL)
+	if err != nil {
+		panic(err)
+	}
+	parsedURL, err = url.Parse(httpURL)
 	if err != nil {
 		panic(fmt.Errorf("invalid Grafana URL: %w", err))
 	}
@@ -260,8 +436,23 @@ func NewGrafanaClient(ctx context.Context, grafanaURL, apiKey string) *client.Gr
 			"skip_verify", tlsConfig.SkipVerify)
 	}
 
-	slog.Debug("Creating Grafana client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
-	return client.NewHTTPClientWithConfig(strfmt.Default, cfg)
+	slog.Debug("Creating Grafana client", "url", parsedURL.Redacted(), "unix_socket", socket, "api_key_set", apiKey != "")
+	grafanaClient := client.NewHTTPClientWithConfig(strfmt.Default, cfg)
+	if socket != "" {
+		// The client always sends requests through http.DefaultTransport, so
+		// swap in a transport dialing the socket.
+		config.UnixSocket = socket
+		rt, err := config.HTTPTransport(http.DefaultTransport.(*http.Transport))
+		if err != nil {
+			panic(fmt.Errorf("failed to create transport: %w", err))
+		}
+		if runtime, ok := grafanaClient.Transport.(*httptransport.Runtime); ok {
+			if retryable, ok := runtime.Transport.(*transport.RetryableTransport); ok {
+				retryable.Transport = rt
+			}
+		}
+	}
+	return grafanaClient
 }
 
 // ExtractGrafanaClientFromEnv is a StdioContextFunc that extracts Grafana configuration
@@ -282,16 +473,13 @@ var ExtractGrafanaClientFromEnv server.StdioContextFunc = func(ctx context.Conte
 // from request headers and injects a configured client into the context.
 var ExtractGrafanaClientFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
 	// Extract transport config from request headers, and set it on the context.
-	u, apiKey := urlAndAPIKeyFromHeaders(req)
-	uEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
-	if u == "" {
-		u = uEnv
+	u, apiKey, err := grafanaURLFromRequest(req)
+	if err == nil {
+		_, _, err = resolveGrafanaURL(u)
 	}
-	if u == "" {
-		u = defaultGrafanaURL
-	}
-	if apiKey == "" {
-		apiKey = apiKeyEnv
+	if err != nil {
+		// NewGrafanaClient panics on invalid URLs, so leave the client out.
+		return withGrafanaConfigError(ctx, err)
 	}
 
 	grafanaClient := NewGrafanaClient(ctx, u, apiKey)
@@ -321,6 +509,10 @@ var ExtractIncidentClientFromEnv server.StdioContextFunc = func(ctx context.Cont
 	if grafanaURL == "" {
 		grafanaURL = defaultGrafanaURL
 	}
+	grafanaURL, socket, err := resolveGrafanaURL(grafanaURL)
+	if err != nil {
+		panic(err)
+	}
 	incidentURL := fmt.Sprintf("%s/api/plugins/grafana-irm-app/resources/api/v1/", grafanaURL)
 	parsedURL, err := url.Parse(incidentURL)
 	if err != nil {
@@ -329,17 +521,21 @@ var ExtractIncidentClientFromEnv server.StdioContextFunc = func(ctx context.Cont
 	slog.Debug("Creating Incident client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
 	client := incident.NewClient(incidentURL, apiKey)
 
-	// Configure custom TLS if available
-	if tlsConfig := GrafanaConfigFromContext(ctx).TLSConfig; tlsConfig != nil {
-		transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	// Configure custom TLS and the unix socket if available
+	config := GrafanaConfigFromContext(ctx)
+	config.UnixSocket = socket
+	if tlsConfig := config.TLSConfig; tlsConfig != nil || socket != "" {
+		transport, err := config.HTTPTransport(http.DefaultTransport.(*http.Transport))
 		if err != nil {
 			slog.Error("Failed to create custom transport for incident client, using default", "error", err)
 		} else {
 			client.HTTPClient.Transport = transport
-			slog.Debug("Using custom TLS configuration for incident client",
-				"cert_file", tlsConfig.CertFile,
-				"ca_file", tlsConfig.CAFile,
-				"skip_verify", tlsConfig.SkipVerify)
+			if tlsConfig != nil {
+				slog.Debug("Using custom TLS configuration for incident client",
+					"cert_file", tlsConfig.CertFile,
+					"ca_file", tlsConfig.CAFile,
+					"skip_verify", tlsConfig.SkipVerify)
+			}
 		}
 	}
 
@@ -347,31 +543,32 @@ var ExtractIncidentClientFromEnv server.StdioContextFunc = func(ctx context.Cont
 }
 
 var ExtractIncidentClientFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
-	grafanaURL, apiKey := urlAndAPIKeyFromHeaders(req)
-	grafanaURLEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
-	if grafanaURL == "" {
-		grafanaURL = grafanaURLEnv
-	}
-	if grafanaURL == "" {
-		grafanaURL = defaultGrafanaURL
+	grafanaURL, apiKey, err := grafanaURLFromRequest(req)
+	if err != nil {
+		return withGrafanaConfigError(ctx, err)
 	}
-	if apiKey == "" {
-		apiKey = apiKeyEnv
+	grafanaURL, socket, err := resolveGrafanaURL(grafanaURL)
+	if err != nil {
+		return withGrafanaConfigError(ctx, err)
 	}
 	incidentURL := fmt.Sprintf("%s/api/plugins/grafana-irm-app/resources/api/v1/", grafanaURL)
 	client := incident.NewClient(incidentURL, apiKey)
 
-	// Configure custom TLS if available
-	if tlsConfig := GrafanaConfigFromContext(ctx).TLSConfig; tlsConfig != nil {
-		transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	// Configure custom TLS and the unix socket if available
+	config := GrafanaConfigFromContext(ctx)
+	config.UnixSocket = socket
+	if tlsConfig := config.TLSConfig; tlsConfig != nil || socket != "" {
+		transport, err := config.HTTPTransport(http.DefaultTransport.(*http.Transport))
 		if err != nil {
 			slog.Error("Failed to create custom transport for incident client, using default", "error", err)
 		} else {
 			client.HTTPClient.Transport = transport
-			slog.Debug("Using custom TLS configuration for incident client",
-				"cert_file", tlsConfig.CertFile,
-				"ca_file", tlsConfig.CAFile,
-				"skip_verify", tlsConfig.SkipVerify)
+			if tlsConfig != nil {
+				slog.Debug("Using custom TLS configuration for incident client",
+					"cert_file", tlsConfig.CertFile,
+					"ca_file", tlsConfig.CAFile,
+					"skip_verify", tlsConfig.SkipVerify)
+			}
 		}
 	}
 
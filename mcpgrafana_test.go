@@ -5,11 +5,15 @@ package mcpgrafana
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-openapi/runtime/client"
 	grafana_client "github.com/grafana/grafana-openapi-client-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -210,3 +214,105 @@ func TestExtractGrafanaClientFromHeaders(t *testing.T) {
 		assert.Equal(t, "/api", url.basePath)
 	})
 }
+
+func TestResolveGrafanaURL(t *testing.T) {
+	for _, tc := range []struct {
+		url, httpURL, socket string
+	}{
+		{"http://localhost:3000", "http://localhost:3000", ""},
+		{"http://[::1]:3000/grafana", "http://[::1]:3000/grafana", ""},
+		{"https://[2001:db8::1]", "https://[2001:db8::1]", ""},
+		{"unix:///var/run/grafana/grafana.sock", "http://localhost", "/var/run/grafana/grafana.sock"},
+		{"http+unix://%2Fvar%2Frun%2Fgrafana.sock", "http://localhost", "/var/run/grafana.sock"},
+		{"http+unix://%2Fvar%2Frun%2Fgrafana.sock/grafana/", "http://localhost/grafana", "/var/run/grafana.sock"},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			httpURL, socket, err := resolveGrafanaURL(tc.url)
+			require.NoError(t, err)
+			assert.Equal(t, tc.httpURL, httpURL)
+			assert.Equal(t, tc.socket, socket)
+		})
+	}
+
+	for _, u := range []string{"unix://relative.sock", "http+unix:///grafana", "http+unix://%zz/grafana", "http://[::1"} {
+		_, _, err := resolveGrafanaURL(u)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestGrafanaURLHeaderRejectsSockets(t *testing.T) {
+	for _, u := range []string{"unix:///var/run/docker.sock", "http+unix://%2Fvar%2Frun%2Fdocker.sock", "file:///etc/passwd", "not a url"} {
+		t.Run(u, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+			req.Header.Set(grafanaURLHeader, u)
+			req.Header.Set(grafanaAPIKeyHeader, "secret")
+			ctx := ComposedHTTPContextFunc(GrafanaConfig{})(context.Background(), req)
+
+			assert.Error(t, grafanaConfigError(ctx))
+			assert.Empty(t, GrafanaConfigFromContext(ctx).UnixSocket)
+			assert.Nil(t, GrafanaClientFromContext(ctx))
+			assert.Nil(t, IncidentClientFromContext(ctx))
+
+			// Tool calls fail with the error.
+			_, handler, err := ConvertTool("test", "Test", func(ctx context.Context, args emptyToolParams) (string, error) {
+				return "called", nil
+			})
+			require.NoError(t, err)
+			_, err = handler(ctx, mcp.CallToolRequest{})
+			assert.ErrorContains(t, err, "must be an http or https URL")
+		})
+	}
+
+	// Sockets are still accepted from the environment.
+	t.Setenv("GRAFANA_URL", "unix:///var/run/grafana.sock")
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	ctx := ComposedHTTPContextFunc(GrafanaConfig{})(context.Background(), req)
+	require.NoError(t, grafanaConfigError(ctx))
+	assert.Equal(t, "/var/run/grafana.sock", GrafanaConfigFromContext(ctx).UnixSocket)
+}
+
+func TestExtractGrafanaClientIPv6(t *testing.T) {
+	t.Setenv("GRAFANA_URL", "http://[::1]:3000/grafana")
+	ctx := ExtractGrafanaClientFromEnv(context.Background())
+	url := minURLFromClient(GrafanaClientFromContext(ctx))
+	assert.Equal(t, "[::1]:3000", url.host)
+	assert.Equal(t, "/grafana/api", url.basePath)
+}
+
+func TestGrafanaOverUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "grafana.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	var paths []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"database":"ok","version":"12.0.0"}`))
+	}))
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	t.Setenv("GRAFANA_URL", "unix://"+socket)
+	ctx := ComposedStdioContextFunc(GrafanaConfig{})(context.Background())
+
+	config := GrafanaConfigFromContext(ctx)
+	assert.Equal(t, "http://localhost", config.URL)
+	assert.Equal(t, socket, config.UnixSocket)
+
+	// Requests built from the config's URL are sent over the socket.
+	transport, err := config.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(config.URL + "/api/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// As are requests made with the Grafana client.
+	health, err := GrafanaClientFromContext(ctx).Health.GetHealth()
+	require.NoError(t, err)
+	assert.Equal(t, "12.0.0", health.Payload.Version)
+	assert.Equal(t, []string{"/api/health", "/api/health"}, paths)
+}
@@ -0,0 +1,87 @@
+package mcpgrafana
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type progressTokenKey struct{}
+
+// withProgressToken adds the progress token of a tool call to the context.
+func withProgressToken(ctx context.Context, token mcp.ProgressToken) context.Context {
+	return context.WithValue(ctx, progressTokenKey{}, token)
+}
+
+// SendProgress notifies the client of the progress of the current tool call,
+// such as the number of chunks of a query completed out of the total, if the
+// client asked for progress notifications by sending a progress token with
+// the call. Otherwise, it does nothing. Failures to send the notification are
+// logged but otherwise ignored, since they don't affect the tool call.
+func SendProgress(ctx context.Context, progress, total float64, message string) {
+	SendProgressData(ctx, progress, total, message, nil)
+}
+
+// SendProgressData is like SendProgress, but also sends the partial result of
+// the step which completed, such as the series of a query chunk, in the
+// notification's `data` field. Clients which don't know the field ignore it.
+func SendProgressData(ctx context.Context, progress, total float64, message string, data any) {
+	token, ok := ctx.Value(progressTokenKey{}).(mcp.ProgressToken)
+	if !ok || token == nil {
+		return
+	}
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return
+	}
+	redactions := GrafanaConfigFromContext(ctx).Redactions
+	params := map[string]any{
+		"progressToken": token,
+		"progress":      progress,
+		"total":         total,
+	}
+	if message != "" {
+		params["message"] = redactString(redactions, message)
+	}
+	if data != nil {
+		if len(redactions) > 0 {
+			var err error
+			if data, err = redactAny(redactions, data); err != nil {
+				slog.Debug("failed to redact progress notification", "error", err)
+				return
+			}
+		}
+		params["data"] = data
+	}
+	if err := s.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+		slog.Debug("failed to send progress notification", "error", err)
+	}
+}
+
+// SendLogMessage sends data to the client as an MCP logging notification,
+// such as lines of a tailed log as they arrive, redacting it like tool
+// results. It does nothing outside of a client session, and failures to send
+// the notification are logged but otherwise ignored.
+func SendLogMessage(ctx context.Context, level mcp.LoggingLevel, logger string, data any) {
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return
+	}
+	if redactions := GrafanaConfigFromContext(ctx).Redactions; len(redactions) > 0 {
+		var err error
+		if data, err = redactAny(redactions, data); err != nil {
+			slog.Debug("failed to redact log notification", "error", err)
+			return
+		}
+	}
+	params := map[string]any{
+		"level":  level,
+		"logger": logger,
+		"data":   data,
+	}
+	if err := s.SendNotificationToClient(ctx, "notifications/message", params); err != nil {
+		slog.Debug("failed to send log notification", "error", err)
+	}
+}
@@ -0,0 +1,107 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return "test" }
+
+type progressParams struct {
+	Steps int `json:"steps"`
+}
+
+func TestSendProgress(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	tool := MustTool("count", "Counts", func(ctx context.Context, args progressParams) (string, error) {
+		for i := 1; i <= args.Steps; i++ {
+			if i == args.Steps {
+				SendProgressData(ctx, float64(i), float64(args.Steps), "last step", []int{i})
+				continue
+			}
+			SendProgress(ctx, float64(i), float64(args.Steps), "")
+		}
+		return "done", nil
+	})
+	tool.Register(s)
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	require.NoError(t, s.RegisterSession(context.Background(), session))
+	ctx := s.WithContext(context.Background(), session)
+
+	call := func(meta string) {
+		t.Helper()
+		msg := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"count","arguments":{"steps":2}` + meta + `}}`
+		resp := s.HandleMessage(ctx, json.RawMessage(msg))
+		_, ok := resp.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response %#v", resp)
+	}
+
+	// Without a progress token, no notifications are sent.
+	call("")
+	assert.Empty(t, session.notifications)
+
+	call(`,"_meta":{"progressToken":"abc"}`)
+	require.Len(t, session.notifications, 2)
+	for i := 1; i <= 2; i++ {
+		n := <-session.notifications
+		assert.Equal(t, "notifications/progress", n.Method)
+		assert.Equal(t, "abc", n.Params.AdditionalFields["progressToken"])
+		assert.Equal(t, float64(i), n.Params.AdditionalFields["progress"])
+		assert.Equal(t, 2.0, n.Params.AdditionalFields["total"])
+		if i == 2 {
+			assert.Equal(t, "last step", n.Params.AdditionalFields["message"])
+			assert.Equal(t, []int{2}, n.Params.AdditionalFields["data"])
+		} else {
+			assert.NotContains(t, n.Params.AdditionalFields, "data")
+		}
+	}
+
+	// Outside of tool calls, progress is ignored.
+	SendProgress(context.Background(), 1, 1, "")
+}
+
+func TestSendLogMessage(t *testing.T) {
+	ip, err := ParseRedaction(`ip=\d+\.\d+\.\d+\.\d+`)
+	require.NoError(t, err)
+	s := server.NewMCPServer("test", "1.0.0")
+	tool := MustTool("tail", "Tails", func(ctx context.Context, args progressParams) (string, error) {
+		SendLogMessage(ctx, mcp.LoggingLevelInfo, "tail", []map[string]string{{"line": "from 10.0.0.1"}})
+		return "done", nil
+	})
+	tool.Register(s)
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	require.NoError(t, s.RegisterSession(context.Background(), session))
+	ctx := s.WithContext(context.Background(), session)
+	ctx = WithGrafanaConfig(ctx, GrafanaConfig{Redactions: []Redaction{ip}})
+
+	resp := s.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"tail","arguments":{}}}`))
+	_, ok := resp.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response %#v", resp)
+
+	require.Len(t, session.notifications, 1)
+	n := <-session.notifications
+	assert.Equal(t, "notifications/message", n.Method)
+	assert.Equal(t, "tail", n.Params.AdditionalFields["logger"])
+	assert.Equal(t, []any{map[string]any{"line": "from " + redactionPlaceholder("ip", "10.0.0.1")}}, n.Params.AdditionalFields["data"])
+
+	// Outside of tool calls, messages are ignored.
+	SendLogMessage(context.Background(), mcp.LoggingLevelInfo, "tail", "ignored")
+}
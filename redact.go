@@ -0,0 +1,191 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Redaction masks the parts of tool results matching Pattern, such as IP
+// addresses or account numbers, before they are returned to the client.
+type Redaction struct {
+	// Name identifies the redaction in its placeholders, e.g. "ip".
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseRedaction parses a redaction given as name=regex, e.g.
+// 'ip=\d+\.\d+\.\d+\.\d+'. Without a name, the redaction is called "value".
+func ParseRedaction(s string) (Redaction, error) {
+	name, expr, ok := strings.Cut(s, "=")
+	if !ok || !regexp.MustCompile(`^[A-Za-z0-9_-]+$`).MatchString(name) {
+		name, expr = "value", s
+	}
+	if expr == "" {
+		return Redaction{}, fmt.Errorf("redaction %q has an empty pattern", s)
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return Redaction{}, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+	}
+	return Redaction{Name: name, Pattern: pattern}, nil
+}
+
+// redactionKey keys the hashes in placeholders, so that they are consistent
+// for the lifetime of the server but cannot be reversed by hashing guesses.
+var redactionKey = sync.OnceValue(func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("generating redaction key: %v", err))
+	}
+	return key
+})
+
+// redactionPlaceholder returns the placeholder replacing a matched value. The
+// same value always gets the same placeholder, so redacted values can still
+// be correlated across tool results. The hash is written with letters only so
+// that placeholders are not matched by numeric patterns.
+func redactionPlaceholder(name, value string) string {
+	mac := hmac.New(sha256.New, redactionKey())
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	hash := make([]byte, 8)
+	for i := range hash {
+		hash[i] = 'a' + sum[i]%26
+	}
+	return fmt.Sprintf("<redacted:%s:%s>", name, hash)
+}
+
+// redactString replaces the parts of s matching any of the redactions with
+// their placeholders.
+func redactString(redactions []Redaction, s string) string {
+	for _, r := range redactions {
+		s = r.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			return redactionPlaceholder(r.Name, match)
+		})
+	}
+	return s
+}
+
+// RedactText masks the values matching the redactions configured in ctx, for
+// text which leaves the server other than through the tool results,
+// notifications and errors redacted by ConvertTool, SendProgressData and
+// SendLogMessage, such as exports and text written to Grafana.
+func RedactText(ctx context.Context, s string) string {
+	return redactString(GrafanaConfigFromContext(ctx).Redactions, s)
+}
+
+// redactValue redacts the strings in a value decoded from JSON. Object keys,
+// such as label names, are left as is.
+func redactValue(redactions []Redaction, value any) any {
+	switch v := value.(type) {
+	case string:
+		return redactString(redactions, v)
+	case map[string]any:
+		for k, item := range v {
+			v[k] = redactValue(redactions, item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(redactions, item)
+		}
+	}
+	return value
+}
+
+// decodeJSONForRedaction decodes a JSON document, keeping numbers as
+// json.Number so that large integers such as IDs are not rounded when the
+// document is encoded again.
+func decodeJSONForRedaction(jsonBytes []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// redactJSON redacts the string values in a JSON document.
+func redactJSON(redactions []Redaction, jsonBytes []byte) ([]byte, error) {
+	value, err := decodeJSONForRedaction(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("decode result for redaction: %w", err)
+	}
+	// Placeholders contain angle brackets, which json.Marshal would escape.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(redactions, value)); err != nil {
+		return nil, fmt.Errorf("encode redacted result: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// redactToolResult redacts the text and structured content of a tool result
+// returned as is by a tool handler.
+func redactToolResult(redactions []Redaction, result *mcp.CallToolResult) error {
+	for i, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			text.Text = redactString(redactions, text.Text)
+			result.Content[i] = text
+		}
+	}
+	if result.StructuredContent == nil {
+		return nil
+	}
+	jsonBytes, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return fmt.Errorf("marshal structured content for redaction: %w", err)
+	}
+	value, err := decodeJSONForRedaction(jsonBytes)
+	if err != nil {
+		return fmt.Errorf("decode structured content for redaction: %w", err)
+	}
+	result.StructuredContent = redactValue(redactions, value)
+	return nil
+}
+
+// redactAny redacts the strings in any value which can be encoded as JSON,
+// such as the data of a notification.
+func redactAny(redactions []Redaction, v any) (any, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal value for redaction: %w", err)
+	}
+	value, err := decodeJSONForRedaction(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("decode value for redaction: %w", err)
+	}
+	return redactValue(redactions, value), nil
+}
+
+// redactedError is an error whose message is redacted, which still unwraps
+// to the original error.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError redacts the message of an error returned by a tool handler,
+// since it may quote values from Grafana or a datasource.
+func redactError(redactions []Redaction, err error) error {
+	msg := err.Error()
+	redacted := redactString(redactions, msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{err: err, msg: redacted}
+}
@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedaction(t *testing.T) {
+	r, err := ParseRedaction(`ip=\d+\.\d+\.\d+\.\d+`)
+	require.NoError(t, err)
+	assert.Equal(t, "ip", r.Name)
+	assert.Equal(t, `\d+\.\d+\.\d+\.\d+`, r.Pattern.String())
+
+	r, err = ParseRedaction(`acct-\d{8}`)
+	require.NoError(t, err)
+	assert.Equal(t, "value", r.Name)
+
+	r, err = ParseRedaction(`(?i)token=\w+`)
+	require.NoError(t, err)
+	assert.Equal(t, "value", r.Name, "a pattern containing = is not split on an invalid name")
+	assert.Equal(t, `(?i)token=\w+`, r.Pattern.String())
+
+	_, err = ParseRedaction(`ip=(`)
+	assert.Error(t, err)
+	_, err = ParseRedaction(`ip=`)
+	assert.Error(t, err)
+}
+
+func TestRedactToolResults(t *testing.T) {
+	ip, err := ParseRedaction(`ip=\d+\.\d+\.\d+\.\d+`)
+	require.NoError(t, err)
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{Redactions: []Redaction{ip}})
+	call := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) *mcp.CallToolResult {
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{"name": "test"}
+		result, err := handler(ctx, request)
+		require.NoError(t, err)
+		return result
+	}
+	placeholder := redactionPlaceholder("ip", "10.0.0.1")
+	assert.Regexp(t, `^<redacted:ip:[a-z]{8}>$`, placeholder)
+	assert.NotEqual(t, placeholder, redactionPlaceholder("ip", "10.0.0.2"))
+
+	t.Run("structured", func(t *testing.T) {
+		_, handler, err := ConvertTool("labels", "", func(ctx context.Context, args testToolParams) ([]map[string]string, error) {
+			return []map[string]string{{"client_ip": "10.0.0.1", "msg": "from 10.0.0.1 to 10.0.0.2"}}, nil
+		})
+		require.NoError(t, err)
+		result := call(handler)
+		assert.Equal(t, map[string]any{"result": []any{map[string]any{
+			"client_ip": placeholder,
+			"msg":       "from " + placeholder + " to " + redactionPlaceholder("ip", "10.0.0.2"),
+		}}}, result.StructuredContent)
+		text := result.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"client_ip":"`+placeholder+`"`)
+		assert.NotContains(t, text, "10.0.0.1")
+	})
+
+	t.Run("large numbers", func(t *testing.T) {
+		_, handler, err := ConvertTool("ids", "", func(ctx context.Context, args testToolParams) (map[string]any, error) {
+			return map[string]any{"id": int64(1234567890123456789), "ip": "10.0.0.1"}, nil
+		})
+		require.NoError(t, err)
+		text := call(handler).Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"id":1234567890123456789`)
+		assert.NotContains(t, text, "10.0.0.1")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, handler, err := ConvertTool("fail", "", func(ctx context.Context, args testToolParams) (string, error) {
+			return "", fmt.Errorf("query failed: %w", context.DeadlineExceeded)
+		})
+		require.NoError(t, err)
+		_, handler2, err := ConvertTool("fail", "", func(ctx context.Context, args testToolParams) (string, error) {
+			return "", fmt.Errorf("connecting to 10.0.0.1: %w", context.DeadlineExceeded)
+		})
+		require.NoError(t, err)
+
+		var request mcp.CallToolRequest
+		_, err = handler(ctx, request)
+		assert.EqualError(t, err, "query failed: context deadline exceeded")
+		_, err = handler2(ctx, request)
+		assert.EqualError(t, err, "connecting to "+placeholder+": context deadline exceeded")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		_, handler, err := ConvertTool("text", "", func(ctx context.Context, args testToolParams) (string, error) {
+			return "connection from 10.0.0.1", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "connection from "+placeholder, call(handler).Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("tool result", func(t *testing.T) {
+		_, handler, err := ConvertTool("result", "", func(ctx context.Context, args testToolParams) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("connection from 10.0.0.1"), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "connection from "+placeholder, call(handler).Content[0].(mcp.TextContent).Text)
+	})
+}
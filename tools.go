@@ -1,10 +1,12 @@
 package mcpgrafana
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"reflect"
 
 	"github.com/invopop/jsonschema"
@@ -34,6 +36,85 @@ func (t *Tool) Register(mcp *server.MCPServer) {
 	mcp.AddTool(t.Tool, t.Handler)
 }
 
+// ServeLatestToolVersions controls which version of a VersionedTool is
+// served under its unversioned name. By default it is the oldest version, so
+// that existing clients and prompts keep working; set it before registering
+// tools to serve the latest versions instead.
+var ServeLatestToolVersions bool
+
+// VersionedTool is a tool whose result shape has changed between versions.
+//
+// Every version is registered with a version suffix, such as
+// search_tempo_traces_v2, so clients can pin the version they depend on. The
+// unversioned name serves the oldest version as a compatibility shim during
+// the deprecation window, or the latest version if ServeLatestToolVersions
+// is set. Once the window is over, the old versions should be dropped.
+type VersionedTool struct {
+	Name string
+	// Versions of the tool, oldest first: Versions[0] is v1.
+	Versions []Tool
+	// Deprecation is added to the descriptions of the old versions, e.g.
+	// "It will be removed in the next minor release".
+	Deprecation string
+}
+
+// NewVersionedTool creates a VersionedTool from the versions of a tool, oldest
+// first. All versions must have the same name.
+func NewVersionedTool(deprecation string, versions ...Tool) VersionedTool {
+	if len(versions) == 0 {
+		panic("versioned tool needs at least one version")
+	}
+	name := versions[0].Tool.Name
+	for _, v := range versions[1:] {
+		if v.Tool.Name != name {
+			panic(fmt.Sprintf("versions of tool %q have different names: %q", name, v.Tool.Name))
+		}
+	}
+	return VersionedTool{Name: name, Versions: versions, Deprecation: deprecation}
+}
+
+// VersionedName returns the name of a version of a tool, e.g.
+// search_tempo_traces_v2.
+func VersionedName(name string, version int) string {
+	return fmt.Sprintf("%s_v%d", name, version)
+}
+
+// Tools returns the tools registered for each version, followed by the tool
+// registered under the unversioned name.
+func (t *VersionedTool) Tools() []Tool {
+	latest := len(t.Versions)
+	tools := make([]Tool, 0, latest+1)
+	for i, v := range t.Versions {
+		v.Tool.Name = VersionedName(t.Name, i+1)
+		if i+1 < latest {
+			v.Tool.Description = t.deprecatedDescription(v.Tool.Description)
+		}
+		tools = append(tools, v)
+	}
+	unversioned := t.Versions[0]
+	if ServeLatestToolVersions {
+		unversioned = t.Versions[latest-1]
+	} else if latest > 1 {
+		unversioned.Tool.Description = t.deprecatedDescription(unversioned.Tool.Description)
+	}
+	return append(tools, unversioned)
+}
+
+func (t *VersionedTool) deprecatedDescription(description string) string {
+	d := fmt.Sprintf("%s\n\nDeprecated: this version of the tool is kept for compatibility. Use %s for the latest version.", description, VersionedName(t.Name, len(t.Versions)))
+	if t.Deprecation != "" {
+		d += " " + t.Deprecation
+	}
+	return d
+}
+
+// Register adds every version of the tool to the given MCPServer.
+func (t *VersionedTool) Register(mcp *server.MCPServer) {
+	for _, tool := range t.Tools() {
+		tool.Register(mcp)
+	}
+}
+
 // MustTool creates a new Tool from the given name, description, and toolHandler.
 // It panics if the tool cannot be created.
 func MustTool[T any, R any](
@@ -83,7 +164,15 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		return zero, nil, errors.New("tool handler second argument must be a struct")
 	}
 
+	outputSchema := createOutputSchema(handlerType.Out(0))
+
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := grafanaConfigError(ctx); err != nil {
+			return nil, err
+		}
+		if meta := request.Params.Meta; meta != nil && meta.ProgressToken != nil {
+			ctx = withProgressToken(ctx, meta.ProgressToken)
+		}
 
 		s, err := json.Marshal(request.Params.Arguments)
 		if err != nil {
@@ -121,9 +210,11 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 			}
 		}
 
+		redactions := GrafanaConfigFromContext(ctx).Redactions
+
 		// If there's an error, return nil result and the error
 		if handlerErr != nil {
-			return nil, handlerErr
+			return nil, redactError(redactions, handlerErr)
 		}
 
 		// Check if the first return value is nil (only for pointer, interface, map, etc.)
@@ -139,16 +230,35 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		}
 
 		returnVal := output[0].Interface()
-		returnType := output[0].Type()
+		for {
+			union, ok := returnVal.(UnionResult)
+			if !ok {
+				break
+			}
+			if returnVal = union.ResultValue(); returnVal == nil {
+				return nil, nil
+			}
+		}
+		returnType := reflect.TypeOf(returnVal)
 
 		// Case 1: Already a *mcp.CallToolResult
 		if callResult, ok := returnVal.(*mcp.CallToolResult); ok {
+			if len(redactions) > 0 {
+				if err := redactToolResult(redactions, callResult); err != nil {
+					return nil, err
+				}
+			}
 			return callResult, nil
 		}
 
 		// Case 2: An mcp.CallToolResult (not a pointer)
 		if returnType.ConvertibleTo(reflect.TypeOf(mcp.CallToolResult{})) {
 			callResult := returnVal.(mcp.CallToolResult)
+			if len(redactions) > 0 {
+				if err := redactToolResult(redactions, &callResult); err != nil {
+					return nil, err
+				}
+			}
 			return &callResult, nil
 		}
 
@@ -157,23 +267,41 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 			if str == "" {
 				return nil, nil
 			}
-			return mcp.NewToolResultText(str), nil
+			return mcp.NewToolResultText(redactString(redactions, str)), nil
 		}
 
 		if strPtr, ok := returnVal.(*string); ok {
 			if strPtr == nil || *strPtr == "" {
 				return nil, nil
 			}
-			return mcp.NewToolResultText(*strPtr), nil
+			return mcp.NewToolResultText(redactString(redactions, *strPtr)), nil
 		}
 
-		// Case 4: Any other type - marshal to JSON
+		// Case 4: Any other type - marshal to JSON, returning it both as
+		// structured content and as text for clients which do not support
+		// structured output
 		jsonBytes, err := json.Marshal(returnVal)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal return value: %s", err)
 		}
+		if len(redactions) > 0 {
+			if jsonBytes, err = redactJSON(redactions, jsonBytes); err != nil {
+				return nil, err
+			}
+		}
+		structured, err := structuredContent(jsonBytes)
+		if err != nil {
+			return nil, err
+		}
+		// A result not matching the output schema is a bug in the tool, but
+		// the result is still more useful to the client than an error.
+		if outputSchema != nil {
+			if err := validateStructuredContent(outputSchema, structured); err != nil {
+				slog.Warn("tool result does not match its output schema", "tool", name, "error", err)
+			}
+		}
 
-		return mcp.NewToolResultText(string(jsonBytes)), nil
+		return mcp.NewToolResultStructured(structured, string(jsonBytes)), nil
 	}
 
 	jsonSchema := createJSONSchemaFromHandler(toolHandler)
@@ -192,6 +320,13 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		Description: description,
 		InputSchema: inputSchema,
 	}
+	if outputSchema != nil {
+		raw, err := json.Marshal(outputSchema)
+		if err != nil {
+			return zero, nil, fmt.Errorf("marshal output schema: %w", err)
+		}
+		t.RawOutputSchema = raw
+	}
 	for _, option := range options {
 		option(&t)
 	}
@@ -207,6 +342,185 @@ func createJSONSchemaFromHandler(handler any) *jsonschema.Schema {
 	return inputSchema
 }
 
+// structuredResultKey is the property under which results that are not JSON
+// objects are returned as structured content, as MCP requires structured
+// content to be an object.
+const structuredResultKey = "result"
+
+var (
+	callToolResultType = reflect.TypeOf(mcp.CallToolResult{})
+	stringType         = reflect.TypeOf("")
+)
+
+// UnionResult is implemented by tool results which take one of several
+// shapes, for example depending on the tool's arguments. The output schema
+// of a tool returning a UnionResult accepts any of its ResultTypes, and the
+// tool's result is the ResultValue.
+//
+// ResultTypes is called on the zero value of the type to create the schema.
+type UnionResult interface {
+	// ResultTypes returns the types the result's value may have.
+	ResultTypes() []reflect.Type
+	// ResultValue returns the result's value.
+	ResultValue() any
+}
+
+var unionResultType = reflect.TypeOf((*UnionResult)(nil)).Elem()
+
+// unionResultTypes returns the types of a UnionResult's values, or nil if t
+// is not a UnionResult.
+func unionResultTypes(t reflect.Type) []reflect.Type {
+	if t.Kind() == reflect.Interface || !t.Implements(unionResultType) {
+		return nil
+	}
+	return reflect.Zero(t).Interface().(UnionResult).ResultTypes()
+}
+
+// createOutputSchema creates the output schema of a tool from the type its
+// handler returns. Handlers returning text or a CallToolResult have no
+// structured output and get no schema. Types which are not JSON objects, such
+// as slices, are described as an object with the value under "result".
+// UnionResults are described by the schemas of their types, which the result
+// may match any of.
+func createOutputSchema(returnType reflect.Type) *jsonschema.Schema {
+	schemas, ok := resultSchemas(returnType)
+	if !ok {
+		return nil
+	}
+	var objects, values []*jsonschema.Schema
+	for _, schema := range schemas {
+		if schema.Type == "object" {
+			objects = append(objects, schema)
+		} else {
+			values = append(values, schema)
+		}
+	}
+	if len(values) > 0 {
+		value := values[0]
+		if len(values) > 1 {
+			value = &jsonschema.Schema{AnyOf: values}
+		}
+		properties := jsonschema.NewProperties()
+		properties.Set(structuredResultKey, value)
+		objects = append(objects, &jsonschema.Schema{
+			Type:       "object",
+			Properties: properties,
+			Required:   []string{structuredResultKey},
+		})
+	}
+	if len(objects) == 1 {
+		return objects[0]
+	}
+	return &jsonschema.Schema{Type: "object", AnyOf: objects}
+}
+
+// resultSchemas returns the schemas of the values a handler returning the
+// given type may return, or false if some of them are not structured.
+func resultSchemas(returnType reflect.Type) ([]*jsonschema.Schema, bool) {
+	t := derefType(returnType)
+	if t == callToolResultType || t == stringType {
+		return nil, false
+	}
+	if types := unionResultTypes(t); types != nil {
+		var schemas []*jsonschema.Schema
+		for _, resultType := range types {
+			s, ok := resultSchemas(resultType)
+			if !ok {
+				return nil, false
+			}
+			schemas = append(schemas, s...)
+		}
+		return schemas, true
+	}
+	if t.Kind() == reflect.Interface {
+		// The result's type is only known at runtime.
+		return []*jsonschema.Schema{{Type: "object"}}, true
+	}
+	schema := newOutputSchemaMapper().reflect(t)
+	if schema.Type == "" {
+		// Types with custom JSON marshalling may be objects or not.
+		return []*jsonschema.Schema{{Type: "object"}}, true
+	}
+	return []*jsonschema.Schema{schema}, true
+}
+
+// structuredContent decodes a tool's JSON result into the structured content
+// of a tool result, wrapping it under "result" if it is not an object.
+// Numbers are kept as json.Number so that large integers such as IDs are not
+// rounded.
+func structuredContent(jsonBytes []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("decode structured content: %w", err)
+	}
+	if object, ok := value.(map[string]any); ok {
+		return object, nil
+	}
+	return map[string]any{structuredResultKey: value}, nil
+}
+
+// validateStructuredContent checks that structured content has the required
+// properties of a tool's output schema, or of one of its alternatives, and
+// that its properties have the types declared in the schema. Null values are
+// allowed for any type, as Go marshals nil slices, maps and pointers to null.
+func validateStructuredContent(schema *jsonschema.Schema, content map[string]any) error {
+	if len(schema.AnyOf) > 0 {
+		var errs []error
+		for _, alternative := range schema.AnyOf {
+			err := validateStructuredContent(alternative, content)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return fmt.Errorf("result matches none of the result types: %w", errors.Join(errs...))
+	}
+	for _, name := range schema.Required {
+		if _, ok := content[name]; !ok {
+			return fmt.Errorf("missing required property %q", name)
+		}
+	}
+	if schema.Properties == nil {
+		return nil
+	}
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		value, ok := content[pair.Key]
+		if !ok || value == nil || pair.Value == nil || pair.Value.Type == "" {
+			continue
+		}
+		if !jsonValueHasType(value, pair.Value.Type) {
+			return fmt.Errorf("property %q is not of type %s", pair.Key, pair.Value.Type)
+		}
+	}
+	return nil
+}
+
+// jsonValueHasType reports whether a value decoded from JSON has the given
+// JSON schema type.
+func jsonValueHasType(value any, schemaType string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return schemaType == "object"
+	case []any:
+		return schemaType == "array"
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case json.Number:
+		if schemaType == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return schemaType == "number"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && v == float64(int64(v)))
+	}
+	return false
+}
+
 var (
 	jsonSchemaReflector = jsonschema.Reflector{
 		BaseSchemaID:               "",
@@ -226,3 +540,72 @@ var (
 		CommentMap:                 nil,
 	}
 )
+
+// outputSchemaMapper maps the types in a tool's output schema whose schema
+// is not reflected from their Go type: UnionResults, described by the schemas
+// of their types, and recursive types nested in themselves, described only as
+// objects.
+type outputSchemaMapper struct {
+	// reflecting holds the struct types being reflected.
+	reflecting map[reflect.Type]bool
+	// next is the type about to be reflected, which is not mapped.
+	next reflect.Type
+}
+
+func newOutputSchemaMapper() *outputSchemaMapper {
+	return &outputSchemaMapper{reflecting: map[reflect.Type]bool{}}
+}
+
+// reflect returns the schema of a type.
+func (m *outputSchemaMapper) reflect(t reflect.Type) *jsonschema.Schema {
+	t = derefType(t)
+	if unionResultTypes(t) != nil {
+		return m.mapType(t)
+	}
+	if t.Kind() == reflect.Struct {
+		m.reflecting[t] = true
+		defer delete(m.reflecting, t)
+	}
+	reflector := jsonSchemaReflector
+	reflector.Mapper = m.mapType
+	// Mapped structs can't be expanded into the root schema.
+	reflector.ExpandedStruct = false
+	m.next = t
+	schema := reflector.ReflectFromType(t)
+	schema.Version = ""
+	return schema
+}
+
+func (m *outputSchemaMapper) mapType(t reflect.Type) *jsonschema.Schema {
+	if t == m.next {
+		m.next = nil
+		return nil
+	}
+	if types := unionResultTypes(t); types != nil {
+		union := &jsonschema.Schema{}
+		for _, resultType := range types {
+			schema := m.reflect(resultType)
+			if schema.Type == "" && schema.AnyOf != nil {
+				union.AnyOf = append(union.AnyOf, schema.AnyOf...)
+			} else {
+				union.AnyOf = append(union.AnyOf, schema)
+			}
+		}
+		return union
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if m.reflecting[t] {
+		return &jsonschema.Schema{Type: "object"}
+	}
+	return m.reflect(t)
+}
+
+// derefType returns the type pointed to by t, following any pointers.
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
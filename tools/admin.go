@@ -2,10 +2,17 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/grafana/grafana-openapi-client-go/client/search"
 	"github.com/grafana/grafana-openapi-client-go/client/teams"
 	"github.com/grafana/grafana-openapi-client-go/models"
 	mcpgrafana "github.com/grafana/mcp-grafana"
@@ -34,6 +41,384 @@ var ListTeams = mcpgrafana.MustTool(
 	listTeams,
 )
 
-func AddAdminTools(mcp *server.MCPServer) {
+// DesiredGrafanaState describes the expected configuration of a Grafana
+// instance, used to detect configuration drift.
+type DesiredGrafanaState struct {
+	Settings             map[string]map[string]string `json:"settings,omitempty" jsonschema:"description=Expected settings keyed by ini section and key as reported by /api/admin/settings (e.g. {\"auth\": {\"disable_login_form\": \"true\"}\\, \"unified_alerting\": {\"enabled\": \"true\"}})"`
+	FeatureToggles       map[string]bool              `json:"featureToggles,omitempty" jsonschema:"description=Expected feature toggle states keyed by toggle name"`
+	DefaultDatasourceUID string                       `json:"defaultDatasourceUid,omitempty" jsonschema:"description=The UID of the datasource expected to be the default datasource"`
+}
+
+type CheckConfigDriftParams struct {
+	DesiredState *DesiredGrafanaState `json:"desiredState,omitempty" jsonschema:"description=Optionally\\, the desired state. Defaults to the desired-state file configured on the server with --desired-state-file"`
+}
+
+// desired returns the desired state, reading it from the server-configured
+// file if it was not given inline.
+func (p CheckConfigDriftParams) desired(desiredStateFile string) (*DesiredGrafanaState, error) {
+	if p.DesiredState != nil {
+		return p.DesiredState, nil
+	}
+	if desiredStateFile == "" {
+		return nil, errors.New("desiredState is required as the server has no --desired-state-file configured")
+	}
+	data, err := os.ReadFile(desiredStateFile)
+	if err != nil {
+		return nil, fmt.Errorf("read desired state file: %w", err)
+	}
+	var desired DesiredGrafanaState
+	if err := json.Unmarshal(data, &desired); err != nil {
+		return nil, fmt.Errorf("parse desired state file: %w", err)
+	}
+	return &desired, nil
+}
+
+// configDrift is a single difference between the desired and actual state.
+type configDrift struct {
+	Category string `json:"category"`
+	Key      string `json:"key"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type configDriftReport struct {
+	InSync  bool          `json:"inSync"`
+	Checked int           `json:"checked"`
+	Drift   []configDrift `json:"drift"`
+}
+
+func checkConfigDrift(ctx context.Context, args CheckConfigDriftParams, desiredStateFile string) (*configDriftReport, error) {
+	desired, err := args.desired(desiredStateFile)
+	if err != nil {
+		return nil, fmt.Errorf("check config drift: %w", err)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+
+	var settings models.SettingsBag
+	if len(desired.Settings) > 0 || len(desired.FeatureToggles) > 0 {
+		resp, err := c.Admin.AdminGetSettings()
+		if err != nil {
+			return nil, fmt.Errorf("check config drift: get settings: %w", err)
+		}
+		settings = resp.Payload
+	}
+
+	var datasources models.DataSourceList
+	if desired.DefaultDatasourceUID != "" {
+		resp, err := c.Datasources.GetDataSources()
+		if err != nil {
+			return nil, fmt.Errorf("check config drift: list datasources: %w", err)
+		}
+		datasources = resp.Payload
+	}
+
+	return compareGrafanaState(desired, settings, datasources), nil
+}
+
+// compareGrafanaState compares the desired state against the actual settings
+// and datasources, returning a report of every difference found.
+func compareGrafanaState(desired *DesiredGrafanaState, settings models.SettingsBag, datasources models.DataSourceList) *configDriftReport {
+	report := &configDriftReport{Drift: []configDrift{}}
+
+	for _, section := range sortedKeys(desired.Settings) {
+		for _, key := range sortedKeys(desired.Settings[section]) {
+			report.Checked++
+			expected := desired.Settings[section][key]
+			actual, ok := settings[section][key]
+			if !ok {
+				actual = "<unset>"
+			}
+			if actual != expected {
+				report.Drift = append(report.Drift, configDrift{
+					Category: "settings",
+					Key:      section + "." + key,
+					Expected: expected,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(desired.FeatureToggles) {
+		report.Checked++
+		expected := desired.FeatureToggles[name]
+		actual := featureToggleEnabled(settings, name)
+		if actual != expected {
+			report.Drift = append(report.Drift, configDrift{
+				Category: "featureToggles",
+				Key:      name,
+				Expected: fmt.Sprintf("%t", expected),
+				Actual:   fmt.Sprintf("%t", actual),
+			})
+		}
+	}
+
+	if desired.DefaultDatasourceUID != "" {
+		report.Checked++
+		actual := "<none>"
+		for _, ds := range datasources {
+			if ds.IsDefault {
+				actual = ds.UID
+				break
+			}
+		}
+		if actual != desired.DefaultDatasourceUID {
+			report.Drift = append(report.Drift, configDrift{
+				Category: "defaultDatasource",
+				Key:      "uid",
+				Expected: desired.DefaultDatasourceUID,
+				Actual:   actual,
+			})
+		}
+	}
+
+	report.InSync = len(report.Drift) == 0
+	return report
+}
+
+// featureToggleEnabled reports whether a feature toggle is explicitly enabled
+// in the [feature_toggles] section of the settings, either through the
+// `enable` list or through a key named after the toggle.
+func featureToggleEnabled(settings models.SettingsBag, name string) bool {
+	toggles := settings["feature_toggles"]
+	if v, ok := toggles[name]; ok {
+		return strings.EqualFold(strings.TrimSpace(v), "true")
+	}
+	enabled := strings.FieldsFunc(toggles["enable"], func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, t := range enabled {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newCheckConfigDrift returns the check_config_drift tool. The desired state
+// is given inline or read from desiredStateFile, which is set by the server
+// operator so that clients cannot read arbitrary files on the server host.
+func newCheckConfigDrift(desiredStateFile string) mcpgrafana.Tool {
+	return mcpgrafana.MustTool(
+		"check_config_drift",
+		"Compares key Grafana settings (e.g. auth and unified alerting sections), explicitly configured feature toggles, and the default datasource against a desired state and reports any drift. The desired state can be given inline, or is otherwise read from the desired-state file configured on the server, with the fields `settings` (section -> key -> value, as returned by /api/admin/settings), `featureToggles` (name -> enabled), and `defaultDatasourceUid`. Requires permission to read server settings. Note that secret settings are redacted by Grafana and cannot be compared.",
+		func(ctx context.Context, args CheckConfigDriftParams) (*configDriftReport, error) {
+			return checkConfigDrift(ctx, args, desiredStateFile)
+		},
+		mcp.WithTitleAnnotation("Check configuration drift"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
+
+// Resource types audited by find_unmanaged_resources.
+const (
+	resourceTypeDashboard = "dashboard"
+	resourceTypeFolder    = "folder"
+	resourceTypeAlertRule = "alertRule"
+)
+
+// searchPageSize is the page size used when listing all dashboards or folders
+// through the search API.
+const searchPageSize = 1000
+
+type FindUnmanagedResourcesParams struct {
+	ManagedDashboardUIDs []string `json:"managedDashboardUids,omitempty" jsonschema:"description=UIDs of the dashboards managed by Terraform (or other infrastructure as code)"`
+	ManagedFolderUIDs    []string `json:"managedFolderUids,omitempty" jsonschema:"description=UIDs of the folders managed by Terraform (or other infrastructure as code)"`
+	ManagedAlertRuleUIDs []string `json:"managedAlertRuleUids,omitempty" jsonschema:"description=UIDs of the alert rules managed by Terraform (or other infrastructure as code)"`
+	ResourceTypes        []string `json:"resourceTypes,omitempty" jsonschema:"description=Optionally\\, the resource types to audit: 'dashboard'\\, 'folder' and 'alertRule'. Defaults to all types"`
+}
+
+func (p FindUnmanagedResourcesParams) types() (map[string]bool, error) {
+	if len(p.ResourceTypes) == 0 {
+		return map[string]bool{resourceTypeDashboard: true, resourceTypeFolder: true, resourceTypeAlertRule: true}, nil
+	}
+	types := make(map[string]bool, len(p.ResourceTypes))
+	for _, t := range p.ResourceTypes {
+		switch t {
+		case resourceTypeDashboard, resourceTypeFolder, resourceTypeAlertRule:
+			types[t] = true
+		default:
+			return nil, fmt.Errorf("unknown resource type %q: must be one of %q, %q or %q", t, resourceTypeDashboard, resourceTypeFolder, resourceTypeAlertRule)
+		}
+	}
+	return types, nil
+}
+
+// grafanaResource identifies a single resource in Grafana.
+type grafanaResource struct {
+	Type       string `json:"type"`
+	UID        string `json:"uid"`
+	Title      string `json:"title,omitempty"`
+	FolderUID  string `json:"folderUid,omitempty"`
+	Provenance string `json:"provenance,omitempty"`
+}
+
+type unmanagedResourcesReport struct {
+	// Checked is the number of existing resources audited, by type.
+	Checked map[string]int `json:"checked"`
+	// Unmanaged lists resources which exist in Grafana but are not in the
+	// managed set.
+	Unmanaged []grafanaResource `json:"unmanaged"`
+	// Missing lists managed UIDs which do not exist in Grafana.
+	Missing []grafanaResource `json:"missing,omitempty"`
+}
+
+func findUnmanagedResources(ctx context.Context, args FindUnmanagedResourcesParams) (*unmanagedResourcesReport, error) {
+	types, err := args.types()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []grafanaResource
+	if types[resourceTypeDashboard] {
+		dashboards, err := searchAllResources(ctx, dashboardTypeStr, resourceTypeDashboard)
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, dashboards...)
+	}
+	if types[resourceTypeFolder] {
+		folders, err := searchAllResources(ctx, "dash-folder", resourceTypeFolder)
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, folders...)
+	}
+	if types[resourceTypeAlertRule] {
+		rules, err := provisionedAlertRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rules {
+			resource := grafanaResource{
+				Type:       resourceTypeAlertRule,
+				UID:        r.UID,
+				Provenance: string(r.Provenance),
+			}
+			if r.Title != nil {
+				resource.Title = *r.Title
+			}
+			if r.FolderUID != nil {
+				resource.FolderUID = *r.FolderUID
+			}
+			existing = append(existing, resource)
+		}
+	}
+
+	managed := map[string][]string{}
+	for t := range types {
+		switch t {
+		case resourceTypeDashboard:
+			managed[t] = args.ManagedDashboardUIDs
+		case resourceTypeFolder:
+			managed[t] = args.ManagedFolderUIDs
+		case resourceTypeAlertRule:
+			managed[t] = args.ManagedAlertRuleUIDs
+		}
+	}
+	return compareManagedResources(managed, existing), nil
+}
+
+// searchAllResources lists every resource of the given search type, following
+// pagination.
+func searchAllResources(ctx context.Context, searchType, resourceType string) ([]grafanaResource, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var resources []grafanaResource
+	limit := int64(searchPageSize)
+	for page := int64(1); ; page++ {
+		params := search.NewSearchParamsWithContext(ctx)
+		params.SetType(&searchType)
+		params.SetLimit(&limit)
+		params.SetPage(&page)
+		result, err := c.Search.Search(params)
+		if err != nil {
+			return nil, fmt.Errorf("search %ss: %w", resourceType, err)
+		}
+		for _, hit := range result.Payload {
+			resources = append(resources, grafanaResource{
+				Type:      resourceType,
+				UID:       hit.UID,
+				Title:     hit.Title,
+				FolderUID: hit.FolderUID,
+			})
+		}
+		if int64(len(result.Payload)) < limit {
+			return resources, nil
+		}
+	}
+}
+
+// compareManagedResources compares the managed UIDs for each resource type
+// against the resources which exist in Grafana.
+func compareManagedResources(managed map[string][]string, existing []grafanaResource) *unmanagedResourcesReport {
+	report := &unmanagedResourcesReport{
+		Checked:   map[string]int{},
+		Unmanaged: []grafanaResource{},
+	}
+
+	managedSet := map[string]map[string]bool{}
+	for t, uids := range managed {
+		managedSet[t] = map[string]bool{}
+		for _, uid := range uids {
+			managedSet[t][uid] = true
+		}
+	}
+
+	found := map[string]map[string]bool{}
+	for _, r := range existing {
+		report.Checked[r.Type]++
+		if found[r.Type] == nil {
+			found[r.Type] = map[string]bool{}
+		}
+		found[r.Type][r.UID] = true
+		if !managedSet[r.Type][r.UID] {
+			report.Unmanaged = append(report.Unmanaged, r)
+		}
+	}
+
+	for _, t := range sortedKeys(managedSet) {
+		for _, uid := range sortedKeys(managedSet[t]) {
+			if !found[t][uid] {
+				report.Missing = append(report.Missing, grafanaResource{Type: t, UID: uid})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Unmanaged, func(i, j int) bool {
+		a, b := report.Unmanaged[i], report.Unmanaged[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.UID < b.UID
+	})
+	return report
+}
+
+var FindUnmanagedResources = mcpgrafana.MustTool(
+	"find_unmanaged_resources",
+	"Audits which Grafana resources exist outside a set managed by Terraform (or other infrastructure as code). Given the UIDs of managed dashboards, folders, and alert rules, lists every existing resource of those types which is not in the managed set (with its title, folder UID, and for alert rules the provisioning provenance), as well as any managed UIDs which no longer exist in Grafana. Use the resourceTypes parameter to audit only some resource types.",
+	findUnmanagedResources,
+	mcp.WithTitleAnnotation("Find unmanaged resources"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// AddAdminTools registers the admin tools. desiredStateFile is the optional
+// path of the desired-state file used by check_config_drift.
+func AddAdminTools(mcp *server.MCPServer, desiredStateFile string) {
 	ListTeams.Register(mcp)
+	checkConfigDriftTool := newCheckConfigDrift(desiredStateFile)
+	checkConfigDriftTool.Register(mcp)
+	FindUnmanagedResources.Register(mcp)
 }
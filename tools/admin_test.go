@@ -0,0 +1,129 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareGrafanaState(t *testing.T) {
+	settings := models.SettingsBag{
+		"auth": {
+			"disable_login_form": "false",
+		},
+		"unified_alerting": {
+			"enabled": "true",
+		},
+		"feature_toggles": {
+			"enable":         "foo, bar",
+			"explicitToggle": "false",
+		},
+	}
+	datasources := models.DataSourceList{
+		{UID: "prometheus", IsDefault: true},
+		{UID: "loki"},
+	}
+
+	t.Run("in sync", func(t *testing.T) {
+		report := compareGrafanaState(&DesiredGrafanaState{
+			Settings: map[string]map[string]string{
+				"unified_alerting": {"enabled": "true"},
+			},
+			FeatureToggles:       map[string]bool{"foo": true, "bar": true, "explicitToggle": false},
+			DefaultDatasourceUID: "prometheus",
+		}, settings, datasources)
+		assert.True(t, report.InSync)
+		assert.Equal(t, 5, report.Checked)
+		assert.Empty(t, report.Drift)
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		report := compareGrafanaState(&DesiredGrafanaState{
+			Settings: map[string]map[string]string{
+				"auth":      {"disable_login_form": "true"},
+				"auth.jwt":  {"enabled": "true"},
+				"analytics": {},
+			},
+			FeatureToggles:       map[string]bool{"baz": true},
+			DefaultDatasourceUID: "loki",
+		}, settings, datasources)
+		assert.False(t, report.InSync)
+		assert.Equal(t, 4, report.Checked)
+		assert.Equal(t, []configDrift{
+			{Category: "settings", Key: "auth.disable_login_form", Expected: "true", Actual: "false"},
+			{Category: "settings", Key: "auth.jwt.enabled", Expected: "true", Actual: "<unset>"},
+			{Category: "featureToggles", Key: "baz", Expected: "true", Actual: "false"},
+			{Category: "defaultDatasource", Key: "uid", Expected: "loki", Actual: "prometheus"},
+		}, report.Drift)
+	})
+}
+
+func TestCheckConfigDriftParams(t *testing.T) {
+	t.Run("prefers inline desired state", func(t *testing.T) {
+		inline := &DesiredGrafanaState{DefaultDatasourceUID: "loki"}
+		desired, err := CheckConfigDriftParams{DesiredState: inline}.desired("does-not-exist.json")
+		require.NoError(t, err)
+		assert.Same(t, inline, desired)
+	})
+
+	t.Run("requires desired state without a configured file", func(t *testing.T) {
+		_, err := CheckConfigDriftParams{}.desired("")
+		assert.Error(t, err)
+	})
+
+	t.Run("reads desired state from configured file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"featureToggles": {"foo": true}, "defaultDatasourceUid": "prometheus"}`), 0o600))
+
+		desired, err := CheckConfigDriftParams{}.desired(path)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"foo": true}, desired.FeatureToggles)
+		assert.Equal(t, "prometheus", desired.DefaultDatasourceUID)
+	})
+}
+
+func TestCompareManagedResources(t *testing.T) {
+	report := compareManagedResources(map[string][]string{
+		resourceTypeDashboard: {"dash-a", "dash-gone"},
+		resourceTypeFolder:    {"folder-a"},
+		resourceTypeAlertRule: nil,
+	}, []grafanaResource{
+		{Type: resourceTypeDashboard, UID: "dash-b", Title: "B"},
+		{Type: resourceTypeDashboard, UID: "dash-a", Title: "A"},
+		{Type: resourceTypeFolder, UID: "folder-a", Title: "Folder A"},
+		{Type: resourceTypeAlertRule, UID: "rule-a", Title: "Rule A", Provenance: "api"},
+	})
+
+	assert.Equal(t, map[string]int{
+		resourceTypeDashboard: 2,
+		resourceTypeFolder:    1,
+		resourceTypeAlertRule: 1,
+	}, report.Checked)
+	assert.Equal(t, []grafanaResource{
+		{Type: resourceTypeAlertRule, UID: "rule-a", Title: "Rule A", Provenance: "api"},
+		{Type: resourceTypeDashboard, UID: "dash-b", Title: "B"},
+	}, report.Unmanaged)
+	assert.Equal(t, []grafanaResource{
+		{Type: resourceTypeDashboard, UID: "dash-gone"},
+	}, report.Missing)
+}
+
+func TestFindUnmanagedResourcesParams(t *testing.T) {
+	types, err := FindUnmanagedResourcesParams{}.types()
+	require.NoError(t, err)
+	assert.Len(t, types, 3)
+
+	types, err = FindUnmanagedResourcesParams{ResourceTypes: []string{"folder"}}.types()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{resourceTypeFolder: true}, types)
+
+	_, err = FindUnmanagedResourcesParams{ResourceTypes: []string{"datasource"}}.types()
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
 	"github.com/grafana/grafana-openapi-client-go/models"
@@ -21,6 +22,9 @@ type ListAlertRulesParams struct {
 	Limit          int        `json:"limit,omitempty" jsonschema:"description=The maximum number of results to return. Default is 100."`
 	Page           int        `json:"page,omitempty" jsonschema:"description=The page number to return."`
 	LabelSelectors []Selector `json:"label_selectors,omitempty" jsonschema:"description=Optionally\\, a list of matchers to filter alert rules by labels"`
+	Folder         string     `json:"folder,omitempty" jsonschema:"description=Optionally\\, only list the alert rules in this folder\\, given by UID or title"`
+	PageSize       int        `json:"pageSize,omitempty" jsonschema:"description=Optionally\\, return the alert rules in pages of this size. The result is then an object with the rules in 'items' and a 'nextPageToken' while more pages remain"`
+	PageToken      string     `json:"pageToken,omitempty" jsonschema:"description=Optionally\\, the 'nextPageToken' from a previous call to fetch the next page of its results. The other parameters are ignored"`
 }
 
 func (p ListAlertRulesParams) validate() error {
@@ -39,8 +43,11 @@ type alertRuleSummary struct {
 	Title string `json:"title"`
 	// State can be one of: pending, firing, error, recovering, inactive.
 	// "inactive" means the alert state is normal, not firing.
-	State  string            `json:"state"`
-	Labels map[string]string `json:"labels,omitempty"`
+	State     string            `json:"state"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Folder    string            `json:"folder,omitempty"`
+	FolderUID string            `json:"folderUid,omitempty"`
+	RuleGroup string            `json:"ruleGroup,omitempty"`
 }
 
 func listAlertRules(ctx context.Context, args ListAlertRulesParams) ([]alertRuleSummary, error) {
@@ -59,7 +66,16 @@ func listAlertRules(ctx context.Context, args ListAlertRulesParams) ([]alertRule
 
 	alertRules := []alertingRule{}
 	for _, group := range response.Data.RuleGroups {
-		alertRules = append(alertRules, group.Rules...)
+		if args.Folder != "" && group.FolderUID != args.Folder && !strings.EqualFold(group.File, args.Folder) {
+			continue
+		}
+		for _, rule := range group.Rules {
+			rule.RuleGroup, rule.Folder = group.Name, group.File
+			if rule.FolderUID == "" {
+				rule.FolderUID = group.FolderUID
+			}
+			alertRules = append(alertRules, rule)
+		}
 	}
 
 	alertRules, err = filterAlertRules(alertRules, args.LabelSelectors)
@@ -75,6 +91,14 @@ func listAlertRules(ctx context.Context, args ListAlertRulesParams) ([]alertRule
 	return summarizeAlertRules(alertRules), nil
 }
 
+// listAlertRulesTool handles calls to the list_alert_rules tool, paginating
+// the rules when a page size is set.
+func listAlertRulesTool(ctx context.Context, args ListAlertRulesParams) (paginated[alertRuleSummary], error) {
+	return withPagination(ctx, "list_alert_rules", args.PageSize, args.PageToken, func(ctx context.Context) ([]alertRuleSummary, error) {
+		return listAlertRules(ctx, args)
+	})
+}
+
 // filterAlertRules filters a list of alert rules based on label selectors
 func filterAlertRules(rules []alertingRule, selectors []Selector) ([]alertingRule, error) {
 	if len(selectors) == 0 {
@@ -114,10 +138,13 @@ func summarizeAlertRules(alertRules []alertingRule) []alertRuleSummary {
 	result := make([]alertRuleSummary, 0, len(alertRules))
 	for _, r := range alertRules {
 		result = append(result, alertRuleSummary{
-			UID:    r.UID,
-			Title:  r.Name,
-			State:  r.State,
-			Labels: r.Labels.Map(),
+			UID:       r.UID,
+			Title:     r.Name,
+			State:     r.State,
+			Labels:    r.Labels.Map(),
+			Folder:    r.Folder,
+			FolderUID: r.FolderUID,
+			RuleGroup: r.RuleGroup,
 		})
 	}
 	return result
@@ -147,8 +174,8 @@ func applyPagination(items []alertingRule, limit, page int) ([]alertingRule, err
 
 var ListAlertRules = mcpgrafana.MustTool(
 	"list_alert_rules",
-	"Lists Grafana alert rules, returning a summary including UID, title, current state (e.g., 'pending', 'firing', 'inactive'), and labels. Supports filtering by labels using selectors and pagination. Example label selector: `[{'name': 'severity', 'type': '=', 'value': 'critical'}]`. Inactive state means the alert state is normal, not firing",
-	listAlertRules,
+	"Lists Grafana alert rules, returning a summary including UID, title, current state (e.g., 'pending', 'firing', 'inactive'), labels, folder and rule group. Supports filtering by labels using selectors, by folder UID or title, and pagination. Example label selector: `[{'name': 'severity', 'type': '=', 'value': 'critical'}]`. Inactive state means the alert state is normal, not firing. Set `pageSize` to receive the rules in pages, passing the returned `nextPageToken` as `pageToken` to fetch the next one",
+	listAlertRulesTool,
 	mcp.WithTitleAnnotation("List alert rules"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -159,11 +186,7 @@ type GetAlertRuleByUIDParams struct {
 }
 
 func (p GetAlertRuleByUIDParams) validate() error {
-	if p.UID == "" {
-		return fmt.Errorf("uid is required")
-	}
-
-	return nil
+	return validatePathSegment("uid", p.UID)
 }
 
 func getAlertRuleByUID(ctx context.Context, args GetAlertRuleByUIDParams) (*models.ProvisionedAlertRule, error) {
@@ -181,7 +204,7 @@ func getAlertRuleByUID(ctx context.Context, args GetAlertRuleByUIDParams) (*mode
 
 var GetAlertRuleByUID = mcpgrafana.MustTool(
 	"get_alert_rule_by_uid",
-	"Retrieves the full configuration and detailed status of a specific Grafana alert rule identified by its unique ID (UID). The response includes fields like title, condition, query data, folder UID, rule group, state settings (no data, error), evaluation interval, annotations, and labels.",
+	"Retrieves the full configuration and detailed status of a specific Grafana alert rule identified by its unique ID (UID). The response includes fields like title, condition (the refId of the query or expression that decides whether the rule fires), query data with their datasources and relative time ranges, folder UID, rule group, pending period ('for'), state settings (no data, error), annotations, labels, and notification settings. Use it to explain exactly when and why an alert fires.",
 	getAlertRuleByUID,
 	mcp.WithTitleAnnotation("Get alert rule details"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -268,4 +291,18 @@ func AddAlertingTools(mcp *server.MCPServer) {
 	ListAlertRules.Register(mcp)
 	GetAlertRuleByUID.Register(mcp)
 	ListContactPoints.Register(mcp)
+	GetReliabilityReport.Register(mcp)
+	ListAlertmanagerAlerts.Register(mcp)
+	ListAlertmanagerAlertGroups.Register(mcp)
+	ListAlertmanagerSilences.Register(mcp)
+}
+
+// AddAlertingWriteTools registers alerting tools which modify alert rules
+// and Alertmanagers. They are only enabled when the server runs with write
+// tools enabled.
+func AddAlertingWriteTools(mcp *server.MCPServer) {
+	CreateAlertRule.Register(mcp)
+	UpdateAlertRule.Register(mcp)
+	DeleteAlertRule.Register(mcp)
+	CreateAlertmanagerSilence.Register(mcp)
 }
@@ -46,21 +46,32 @@ func newAlertingClientFromContext(ctx context.Context) (*alertingClient, error)
 		},
 	}
 
-	// Create custom transport with TLS configuration if available
-	if tlsConfig := mcpgrafana.GrafanaConfigFromContext(ctx).TLSConfig; tlsConfig != nil {
-		client.httpClient.Transport, err = tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
+	// Create the transport for the TLS configuration and unix socket, if any
+	client.httpClient.Transport, err = cfg.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
 	}
 
 	return client, nil
 }
 
 func (c *alertingClient) makeRequest(ctx context.Context, path string) (*http.Response, error) {
-	p := c.baseURL.JoinPath(path).String()
+	return c.do(ctx, http.MethodGet, path, nil)
+}
+
+// do sends a request with an optional JSON body to the Grafana API, returning
+// an error unless the response is successful. The path may include a query
+// string.
+func (c *alertingClient) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path %q: %w", path, err)
+	}
+	u := c.baseURL.JoinPath(ref.EscapedPath())
+	u.RawQuery = ref.RawQuery
+	p := u.String()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p, nil)
+	req, err := http.NewRequestWithContext(ctx, method, p, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request to %s: %w", p, err)
 	}
@@ -80,7 +91,7 @@ func (c *alertingClient) makeRequest(ctx context.Context, path string) (*http.Re
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request to %s: %w", p, err)
 	}
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		return nil, fmt.Errorf("Grafana API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
@@ -114,7 +125,9 @@ type rulesResponse struct {
 }
 
 type ruleGroup struct {
-	Name           string         `json:"name"`
+	Name string `json:"name"`
+	// File is the title of the group's folder.
+	File           string         `json:"file"`
 	FolderUID      string         `json:"folderUid"`
 	Rules          []alertingRule `json:"rules"`
 	Interval       float64        `json:"interval"`
@@ -141,6 +154,11 @@ type alertingRule struct {
 	Type           string           `json:"type"`
 	LastEvaluation time.Time        `json:"lastEvaluation"`
 	EvaluationTime float64          `json:"evaluationTime"`
+
+	// RuleGroup and Folder are the names of the rule's group and folder,
+	// which are set from the group rather than returned for each rule.
+	RuleGroup string `json:"-"`
+	Folder    string `json:"-"`
 }
 
 type alert struct {
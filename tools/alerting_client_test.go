@@ -115,3 +115,83 @@ func TestNewAlertingClientFromContext(t *testing.T) {
 	require.Equal(t, "test-api-key", client.apiKey)
 	require.NotNil(t, client.httpClient)
 }
+
+func TestListAlertRulesByFolder(t *testing.T) {
+	otherGroup := ruleGroup{
+		Name:      "OtherGroup",
+		File:      "Other",
+		FolderUID: "other-folder",
+		Rules:     []alertingRule{{Name: "Other Rule", UID: "other-rule-uid", State: "inactive"}},
+	}
+	testGroup := fakeruleGroup
+	testGroup.File = "Test Folder"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := rulesResponse{}
+		resp.Data.RuleGroups = []ruleGroup{testGroup, otherGroup}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer server.Close()
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+
+	rules, err := listAlertRules(ctx, ListAlertRulesParams{})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, alertRuleSummary{
+		UID:       "other-rule-uid",
+		Title:     "Other Rule",
+		State:     "inactive",
+		Labels:    map[string]string{},
+		Folder:    "Other",
+		FolderUID: "other-folder",
+		RuleGroup: "OtherGroup",
+	}, rules[1])
+
+	for _, folder := range []string{"test-folder", "test folder"} {
+		rules, err = listAlertRules(ctx, ListAlertRulesParams{Folder: folder})
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, "test-rule-uid", rules[0].UID)
+		require.Equal(t, "Test Folder", rules[0].Folder)
+	}
+}
+
+func TestGetAlertRuleByUID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/provisioning/alert-rules/cpu-high" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"uid": "cpu-high", "title": "CPU high", "folderUID": "infra", "ruleGroup": "nodes",
+			"condition": "C", "for": "5m", "noDataState": "NoData", "execErrState": "Error",
+			"data": [
+				{"refId": "A", "datasourceUid": "prom", "relativeTimeRange": {"from": 600, "to": 0}, "model": {"expr": "avg(node_cpu_usage)"}},
+				{"refId": "C", "datasourceUid": "__expr__", "model": {"type": "threshold", "expression": "A"}}
+			],
+			"labels": {"severity": "critical"},
+			"annotations": {"summary": "CPU usage is high"},
+			"notification_settings": {"receiver": "oncall", "group_wait": "30s"}
+		}`))
+	}))
+	defer server.Close()
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+	rule, err := getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: "cpu-high"})
+	require.NoError(t, err)
+	require.Equal(t, "C", *rule.Condition)
+	require.Equal(t, "5m0s", rule.For.String())
+	require.Len(t, rule.Data, 2)
+	require.Equal(t, "prom", rule.Data[0].DatasourceUID)
+	require.Equal(t, map[string]any{"expr": "avg(node_cpu_usage)"}, rule.Data[0].Model)
+	require.Equal(t, map[string]string{"severity": "critical"}, rule.Labels)
+	require.Equal(t, "CPU usage is high", rule.Annotations["summary"])
+	require.Equal(t, "oncall", *rule.NotificationSettings.Receiver)
+
+	_, err = getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: "missing"})
+	require.Error(t, err)
+	_, err = getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: "../admin"})
+	require.Error(t, err)
+}
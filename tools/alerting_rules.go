@@ -0,0 +1,443 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultAlertRuleLookback is how far back the queries of created alert
+	// rules look at each evaluation, unless another lookback is given.
+	DefaultAlertRuleLookback = 10 * time.Minute
+
+	// DefaultAlertRulePendingPeriod is how long the condition of created
+	// alert rules must hold before they fire, as in the Grafana UI.
+	DefaultAlertRulePendingPeriod = time.Minute
+)
+
+// alertRuleQuery is a query of an alert rule, evaluated by a datasource or,
+// for the '__expr__' datasource, a server-side expression.
+type alertRuleQuery struct {
+	RefID         string         `json:"refId" jsonschema:"required,description=The reference ID of the query\\, e.g. 'A'"`
+	DatasourceUID string         `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query\\, or '__expr__' for a server-side expression given in model"`
+	Expr          string         `json:"expr,omitempty" jsonschema:"description=The query expression\\, e.g. a PromQL or LogQL query. Set either expr or model"`
+	Model         map[string]any `json:"model,omitempty" jsonschema:"description=Optionally\\, the full query model\\, as in the targets of a dashboard panel\\, for queries which are not a single expression"`
+	Lookback      string         `json:"lookback,omitempty" jsonschema:"description=Optionally\\, how far back the query looks at each evaluation (default: 10m)"`
+}
+
+// alertRuleThreshold is the condition of an alert rule: the rule fires for
+// the series of a query whose reduced value passes the threshold.
+type alertRuleThreshold struct {
+	Query    string  `json:"query,omitempty" jsonschema:"description=Optionally\\, the refId of the query to compare. Defaults to the first query"`
+	Reducer  string  `json:"reducer,omitempty" jsonschema:"enum=last,enum=mean,enum=median,enum=min,enum=max,enum=sum,enum=count,description=Optionally\\, how the values of each series are reduced to one (default: last)"`
+	Operator string  `json:"operator" jsonschema:"required,enum=gt,enum=lt,enum=within_range,enum=outside_range,description=How the reduced values are compared: 'gt' fires above value\\, 'lt' below value\\, 'within_range' and 'outside_range' compare with value and value2"`
+	Value    float64 `json:"value" jsonschema:"required,description=The threshold\\, or the lower bound of a range"`
+	Value2   float64 `json:"value2,omitempty" jsonschema:"description=The upper bound of a range"`
+}
+
+// AlertRuleSpec is the definition of an alert rule taken by
+// create_alert_rule and update_alert_rule.
+type AlertRuleSpec struct {
+	Queries      []alertRuleQuery    `json:"queries,omitempty" jsonschema:"description=The queries of the rule. When updating a rule\\, they replace all its queries and expressions"`
+	Threshold    *alertRuleThreshold `json:"threshold,omitempty" jsonschema:"description=The condition of the rule as a threshold on a query. Set either threshold or condition"`
+	Condition    string              `json:"condition,omitempty" jsonschema:"description=The refId of the query or expression which decides whether the rule fires\\, for rules whose expressions are given in queries. Set either threshold or condition"`
+	For          string              `json:"for,omitempty" jsonschema:"description=Optionally\\, how long the condition must hold before the rule fires (default: 1m)"`
+	Interval     string              `json:"interval,omitempty" jsonschema:"description=Optionally\\, how often the rule group is evaluated (e.g. '1m'). This applies to all the rules of the group. Defaults to 1m for new groups"`
+	Labels       map[string]string   `json:"labels,omitempty" jsonschema:"description=Optionally\\, the labels of the alerts\\, such as severity\\, which notification policies route on"`
+	Annotations  map[string]string   `json:"annotations,omitempty" jsonschema:"description=Optionally\\, the annotations of the alerts\\, such as summary\\, description and runbook_url"`
+	NoDataState  string              `json:"noDataState,omitempty" jsonschema:"enum=NoData,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its queries return no data (default: NoData)"`
+	ExecErrState string              `json:"execErrState,omitempty" jsonschema:"enum=Error,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its evaluation fails (default: Error)"`
+	Receiver     string              `json:"receiver,omitempty" jsonschema:"description=Optionally\\, the name of a contact point to send the alerts to directly\\, instead of routing them with notification policies"`
+	Paused       *bool               `json:"paused,omitempty" jsonschema:"description=Optionally\\, whether the evaluation of the rule is paused"`
+}
+
+// nextRefID returns the first letter which is not a refId yet.
+func nextRefID(used map[string]bool) string {
+	for c := 'A'; c <= 'Z'; c++ {
+		if !used[string(c)] {
+			used[string(c)] = true
+			return string(c)
+		}
+	}
+	for i := 1; ; i++ {
+		refID := fmt.Sprintf("E%d", i)
+		if !used[refID] {
+			used[refID] = true
+			return refID
+		}
+	}
+}
+
+func expressionModel(refID string, model map[string]any) map[string]any {
+	model["refId"] = refID
+	model["datasource"] = map[string]any{"type": expressionDatasourceUID, "uid": expressionDatasourceUID}
+	return model
+}
+
+// alertRuleData converts the queries and condition of an alert rule spec to
+// the data of a rule, and returns the refId of its condition. A threshold
+// adds a reduce and a threshold expression after the queries.
+func alertRuleData(spec AlertRuleSpec) ([]*models.AlertQuery, string, error) {
+	if len(spec.Queries) == 0 {
+		return nil, "", fmt.Errorf("at least one query is required")
+	}
+	if (spec.Threshold == nil) == (spec.Condition == "") {
+		return nil, "", fmt.Errorf("exactly one of threshold and condition is required")
+	}
+
+	used := map[string]bool{}
+	data := make([]*models.AlertQuery, 0, len(spec.Queries)+2)
+	for i, q := range spec.Queries {
+		switch {
+		case q.RefID == "":
+			return nil, "", fmt.Errorf("query %d: refId is required", i)
+		case used[q.RefID]:
+			return nil, "", fmt.Errorf("query %d: refId %s is used by another query", i, q.RefID)
+		case q.DatasourceUID == "":
+			return nil, "", fmt.Errorf("query %s: datasourceUid is required", q.RefID)
+		case (q.Expr == "") == (q.Model == nil):
+			return nil, "", fmt.Errorf("query %s: exactly one of expr and model is required", q.RefID)
+		}
+		used[q.RefID] = true
+
+		m := map[string]any{}
+		for k, v := range q.Model {
+			m[k] = v
+		}
+		if q.Expr != "" {
+			m["expr"] = q.Expr
+		}
+		query := &models.AlertQuery{RefID: q.RefID, DatasourceUID: q.DatasourceUID}
+		if q.DatasourceUID == expressionDatasourceUID {
+			// Expressions have no time range of their own.
+			query.Model = expressionModel(q.RefID, m)
+			query.RelativeTimeRange = &models.RelativeTimeRange{}
+		} else {
+			m["refId"] = q.RefID
+			query.Model = m
+			lookback := DefaultAlertRuleLookback
+			if q.Lookback != "" {
+				d, err := model.ParseDuration(q.Lookback)
+				if err != nil || d <= 0 {
+					return nil, "", fmt.Errorf("query %s: invalid lookback %q", q.RefID, q.Lookback)
+				}
+				lookback = time.Duration(d)
+			}
+			query.RelativeTimeRange = &models.RelativeTimeRange{From: models.Duration(lookback / time.Second)}
+		}
+		data = append(data, query)
+	}
+
+	if spec.Threshold == nil {
+		if !used[spec.Condition] {
+			return nil, "", fmt.Errorf("condition %s is not the refId of a query", spec.Condition)
+		}
+		return data, spec.Condition, nil
+	}
+
+	t := spec.Threshold
+	input := t.Query
+	if input == "" {
+		input = spec.Queries[0].RefID
+	} else if !used[input] {
+		return nil, "", fmt.Errorf("threshold query %s is not the refId of a query", input)
+	}
+	params := []any{t.Value}
+	switch t.Operator {
+	case "gt", "lt":
+	case "within_range", "outside_range":
+		if t.Value2 <= t.Value {
+			return nil, "", fmt.Errorf("value2 must be greater than value for %s thresholds", t.Operator)
+		}
+		params = append(params, t.Value2)
+	default:
+		return nil, "", fmt.Errorf("invalid threshold operator %q: must be one of gt, lt, within_range, outside_range", t.Operator)
+	}
+
+	reduceRefID := nextRefID(used)
+	data = append(data, &models.AlertQuery{
+		RefID:             reduceRefID,
+		DatasourceUID:     expressionDatasourceUID,
+		RelativeTimeRange: &models.RelativeTimeRange{},
+		Model: expressionModel(reduceRefID, map[string]any{
+			"type":       "reduce",
+			"expression": input,
+			"reducer":    stringOrDefault(t.Reducer, "last"),
+		}),
+	})
+	thresholdRefID := nextRefID(used)
+	data = append(data, &models.AlertQuery{
+		RefID:             thresholdRefID,
+		DatasourceUID:     expressionDatasourceUID,
+		RelativeTimeRange: &models.RelativeTimeRange{},
+		Model: expressionModel(thresholdRefID, map[string]any{
+			"type":       "threshold",
+			"expression": reduceRefID,
+			"conditions": []any{map[string]any{
+				"evaluator": map[string]any{"type": t.Operator, "params": params},
+			}},
+		}),
+	})
+	return data, thresholdRefID, nil
+}
+
+// applyAlertRuleSpec sets the fields of an alert rule given in a spec,
+// leaving the others as they are.
+func applyAlertRuleSpec(rule *models.ProvisionedAlertRule, spec AlertRuleSpec) error {
+	if len(spec.Queries) > 0 {
+		data, condition, err := alertRuleData(spec)
+		if err != nil {
+			return err
+		}
+		rule.Data = data
+		rule.Condition = &condition
+	} else if spec.Threshold != nil || spec.Condition != "" {
+		return fmt.Errorf("the queries are required to change the condition of a rule")
+	}
+	if spec.For != "" {
+		d, err := model.ParseDuration(spec.For)
+		if err != nil {
+			return fmt.Errorf("invalid for %q: %w", spec.For, err)
+		}
+		pending := strfmt.Duration(d)
+		rule.For = &pending
+	}
+	if spec.Labels != nil {
+		rule.Labels = spec.Labels
+	}
+	if spec.Annotations != nil {
+		rule.Annotations = spec.Annotations
+	}
+	if spec.NoDataState != "" {
+		rule.NoDataState = &spec.NoDataState
+	}
+	if spec.ExecErrState != "" {
+		rule.ExecErrState = &spec.ExecErrState
+	}
+	if spec.Receiver != "" {
+		rule.NotificationSettings = &models.AlertRuleNotificationSettings{Receiver: &spec.Receiver}
+	}
+	if spec.Paused != nil {
+		rule.IsPaused = *spec.Paused
+	}
+	return nil
+}
+
+// parseAlertRuleGroupInterval parses the evaluation interval of a rule group
+// to seconds, which must be a multiple of Grafana's base interval.
+func parseAlertRuleGroupInterval(interval string) (int64, error) {
+	d, err := model.ParseDuration(interval)
+	if err != nil || d <= 0 || time.Duration(d)%(10*time.Second) != 0 {
+		return 0, fmt.Errorf("invalid interval %q: must be a multiple of 10s", interval)
+	}
+	return int64(time.Duration(d) / time.Second), nil
+}
+
+// provenanceHeader returns the X-Disable-Provenance header which keeps rules
+// editable in the Grafana UI, for rules which aren't provisioned already.
+func provenanceHeader(provenance models.Provenance) *string {
+	if provenance != "" {
+		return nil
+	}
+	disable := "true"
+	return &disable
+}
+
+// setAlertRuleGroupInterval sets the evaluation interval of a rule group.
+func setAlertRuleGroupInterval(ctx context.Context, folderUID, group string, interval int64, provenance models.Provenance) error {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Provisioning.GetAlertRuleGroup(group, folderUID)
+	if err != nil {
+		return fmt.Errorf("get rule group %s: %w", group, err)
+	}
+	g := resp.Payload
+	if g.Interval == interval {
+		return nil
+	}
+	g.Interval = interval
+	params := provisioning.NewPutAlertRuleGroupParamsWithContext(ctx).
+		WithFolderUID(folderUID).
+		WithGroup(group).
+		WithBody(g).
+		WithXDisableProvenance(provenanceHeader(provenance))
+	if _, err := c.Provisioning.PutAlertRuleGroup(params); err != nil {
+		return fmt.Errorf("set the interval of rule group %s: %w", group, err)
+	}
+	return nil
+}
+
+type CreateAlertRuleParams struct {
+	Title     string `json:"title" jsonschema:"required,description=The title of the alert rule"`
+	FolderUID string `json:"folderUid" jsonschema:"required,description=The UID of the folder to create the rule in"`
+	RuleGroup string `json:"ruleGroup" jsonschema:"required,description=The name of the rule group\\, which is created if it doesn't exist. The rules of a group are evaluated together"`
+	AlertRuleSpec
+}
+
+func createAlertRule(ctx context.Context, args CreateAlertRuleParams) (*models.ProvisionedAlertRule, error) {
+	if args.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if err := validatePathSegment("folder UID", args.FolderUID); err != nil {
+		return nil, err
+	}
+	if err := validatePathSegment("rule group", args.RuleGroup); err != nil {
+		return nil, err
+	}
+	if len(args.Queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	var interval int64
+	if args.Interval != "" {
+		var err error
+		if interval, err = parseAlertRuleGroupInterval(args.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	noData, execErr := "NoData", "Error"
+	pending := strfmt.Duration(DefaultAlertRulePendingPeriod)
+	var orgID int64 = 1
+	rule := &models.ProvisionedAlertRule{
+		Title:        &args.Title,
+		FolderUID:    &args.FolderUID,
+		RuleGroup:    &args.RuleGroup,
+		For:          &pending,
+		NoDataState:  &noData,
+		ExecErrState: &execErr,
+		// Grafana creates the rule in the organization of the caller.
+		OrgID: &orgID,
+	}
+	if err := applyAlertRuleSpec(rule, args.AlertRuleSpec); err != nil {
+		return nil, err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := provisioning.NewPostAlertRuleParamsWithContext(ctx).
+		WithBody(rule).
+		WithXDisableProvenance(provenanceHeader(""))
+	resp, err := c.Provisioning.PostAlertRule(params)
+	if err != nil {
+		return nil, fmt.Errorf("create alert rule: %w", err)
+	}
+	if interval != 0 {
+		if err := setAlertRuleGroupInterval(ctx, args.FolderUID, args.RuleGroup, interval, ""); err != nil {
+			return nil, fmt.Errorf("alert rule %s was created, but: %w", resp.Payload.UID, err)
+		}
+	}
+	return resp.Payload, nil
+}
+
+var CreateAlertRule = mcpgrafana.MustTool(
+	"create_alert_rule",
+	"Creates a Grafana-managed alert rule. Give the queries, e.g. a PromQL query with refId 'A', and a threshold, e.g. {'operator': 'gt', 'value': 2} to fire when the last value of a series is above 2; the reduce and threshold expressions are added for you. Rules whose condition needs other expressions can give them as queries of the '__expr__' datasource and name the condition's refId instead. The rule is created in a folder and rule group, whose evaluation interval can be set. The rule stays editable in the Grafana UI. Returns the created rule with its UID.",
+	createAlertRule,
+	mcp.WithTitleAnnotation("Create alert rule"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type UpdateAlertRuleParams struct {
+	UID       string `json:"uid" jsonschema:"required,description=The UID of the alert rule"`
+	Title     string `json:"title,omitempty" jsonschema:"description=Optionally\\, the new title of the rule"`
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder to move the rule to"`
+	RuleGroup string `json:"ruleGroup,omitempty" jsonschema:"description=Optionally\\, the name of the rule group to move the rule to"`
+	AlertRuleSpec
+}
+
+func updateAlertRule(ctx context.Context, args UpdateAlertRuleParams) (*models.ProvisionedAlertRule, error) {
+	rule, err := getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	if args.Title != "" {
+		rule.Title = &args.Title
+	}
+	if args.FolderUID != "" {
+		if err := validatePathSegment("folder UID", args.FolderUID); err != nil {
+			return nil, err
+		}
+		rule.FolderUID = &args.FolderUID
+	}
+	if args.RuleGroup != "" {
+		if err := validatePathSegment("rule group", args.RuleGroup); err != nil {
+			return nil, err
+		}
+		rule.RuleGroup = &args.RuleGroup
+	}
+	var interval int64
+	if args.Interval != "" {
+		if interval, err = parseAlertRuleGroupInterval(args.Interval); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyAlertRuleSpec(rule, args.AlertRuleSpec); err != nil {
+		return nil, err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := provisioning.NewPutAlertRuleParamsWithContext(ctx).
+		WithUID(args.UID).
+		WithBody(rule).
+		WithXDisableProvenance(provenanceHeader(rule.Provenance))
+	resp, err := c.Provisioning.PutAlertRule(params)
+	if err != nil {
+		return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+	}
+	if interval != 0 {
+		if err := setAlertRuleGroupInterval(ctx, *rule.FolderUID, *rule.RuleGroup, interval, rule.Provenance); err != nil {
+			return nil, fmt.Errorf("alert rule %s was updated, but: %w", args.UID, err)
+		}
+	}
+	return resp.Payload, nil
+}
+
+var UpdateAlertRule = mcpgrafana.MustTool(
+	"update_alert_rule",
+	"Updates a Grafana-managed alert rule. Only the given fields change: queries replace all the queries and expressions of the rule and need a threshold or condition, as in create_alert_rule, while labels and annotations replace the rule's labels and annotations. Get the current definition with get_alert_rule_by_uid first. Returns the updated rule.",
+	updateAlertRule,
+	mcp.WithTitleAnnotation("Update alert rule"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type DeleteAlertRuleParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the alert rule"`
+}
+
+func deleteAlertRule(ctx context.Context, args DeleteAlertRuleParams) (string, error) {
+	rule, err := getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: args.UID})
+	if err != nil {
+		return "", err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := provisioning.NewDeleteAlertRuleParamsWithContext(ctx).
+		WithUID(args.UID).
+		WithXDisableProvenance(provenanceHeader(rule.Provenance))
+	if _, err := c.Provisioning.DeleteAlertRule(params); err != nil {
+		return "", fmt.Errorf("delete alert rule %s: %w", args.UID, err)
+	}
+	var title string
+	if rule.Title != nil {
+		title = *rule.Title
+	}
+	return fmt.Sprintf("Deleted alert rule %q (%s)", title, args.UID), nil
+}
+
+var DeleteAlertRule = mcpgrafana.MustTool(
+	"delete_alert_rule",
+	"Deletes a Grafana-managed alert rule by its UID. Its alerts stop firing and its state history is lost.",
+	deleteAlertRule,
+	mcp.WithTitleAnnotation("Delete alert rule"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
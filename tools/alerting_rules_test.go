@@ -0,0 +1,163 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRuleData(t *testing.T) {
+	data, condition, err := alertRuleData(AlertRuleSpec{
+		Queries: []alertRuleQuery{{
+			RefID:         "A",
+			DatasourceUID: "prom",
+			Expr:          "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))",
+			Lookback:      "15m",
+		}},
+		Threshold: &alertRuleThreshold{Operator: "gt", Value: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "C", condition)
+	require.Len(t, data, 3)
+	assert.Equal(t, "prom", data[0].DatasourceUID)
+	assert.EqualValues(t, 900, data[0].RelativeTimeRange.From)
+	assert.Equal(t, map[string]any{"type": "reduce", "expression": "A", "reducer": "last", "refId": "B",
+		"datasource": map[string]any{"type": "__expr__", "uid": "__expr__"}}, data[1].Model)
+	assert.Equal(t, []any{map[string]any{"evaluator": map[string]any{"type": "gt", "params": []any{2.0}}}}, data[2].Model.(map[string]any)["conditions"])
+
+	// Expressions given as queries are used as they are.
+	data, condition, err = alertRuleData(AlertRuleSpec{
+		Queries: []alertRuleQuery{
+			{RefID: "B", DatasourceUID: "loki", Model: map[string]any{"expr": `count_over_time({job="api"}[5m])`, "queryType": "instant"}},
+			{RefID: "A", DatasourceUID: "__expr__", Model: map[string]any{"type": "math", "expression": "$B > 10"}},
+		},
+		Condition: "A",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "A", condition)
+	require.Len(t, data, 2)
+	assert.Equal(t, "instant", data[0].Model.(map[string]any)["queryType"])
+	assert.EqualValues(t, 600, data[0].RelativeTimeRange.From)
+	assert.EqualValues(t, 0, data[1].RelativeTimeRange.From)
+
+	for _, spec := range []AlertRuleSpec{
+		{Threshold: &alertRuleThreshold{Operator: "gt"}},
+		{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "up"}}},
+		{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom"}}, Condition: "A"},
+		{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "up"}}, Condition: "B"},
+		{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "up"}}, Threshold: &alertRuleThreshold{Operator: "eq"}},
+		{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "up"}}, Threshold: &alertRuleThreshold{Operator: "within_range", Value: 2, Value2: 1}},
+	} {
+		_, _, err := alertRuleData(spec)
+		assert.Error(t, err)
+	}
+}
+
+func TestAlertRuleWriteTools(t *testing.T) {
+	var created, updated, group map[string]any
+	var provenanceHeaders []string
+	deleted := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/provisioning/alert-rules", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		provenanceHeaders = append(provenanceHeaders, r.Header.Get("X-Disable-Provenance"))
+		created["uid"] = "p99-latency"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(created))
+	})
+	mux.HandleFunc("GET /api/v1/provisioning/folder/infra/rule-groups/latency", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"title": "latency", "folderUid": "infra", "interval": 60, "rules": [{"uid": "p99-latency"}]}`))
+	})
+	mux.HandleFunc("PUT /api/v1/provisioning/folder/infra/rule-groups/latency", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&group))
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(group))
+	})
+	mux.HandleFunc("GET /api/v1/provisioning/alert-rules/{uid}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("uid") != "provisioned" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid": "provisioned", "title": "Old", "folderUID": "infra", "ruleGroup": "latency", "condition": "A",
+			"for": "5m", "noDataState": "NoData", "execErrState": "Error", "provenance": "file",
+			"data": [{"refId": "A", "datasourceUid": "prom", "model": {"expr": "up == 0"}}], "labels": {"team": "infra"}}`))
+	})
+	mux.HandleFunc("PUT /api/v1/provisioning/alert-rules/provisioned", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+		provenanceHeaders = append(provenanceHeaders, r.Header.Get("X-Disable-Provenance"))
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(updated))
+	})
+	mux.HandleFunc("DELETE /api/v1/provisioning/alert-rules/provisioned", func(w http.ResponseWriter, r *http.Request) {
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	rule, err := createAlertRule(ctx, CreateAlertRuleParams{
+		Title:     "API p99 latency",
+		FolderUID: "infra",
+		RuleGroup: "latency",
+		AlertRuleSpec: AlertRuleSpec{
+			Queries:     []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))"}},
+			Threshold:   &alertRuleThreshold{Operator: "gt", Value: 2},
+			Interval:    "30s",
+			Labels:      map[string]string{"severity": "critical"},
+			Annotations: map[string]string{"summary": "p99 latency is above 2s"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "p99-latency", rule.UID)
+	assert.Equal(t, "C", created["condition"])
+	assert.Equal(t, "1m0s", created["for"])
+	assert.Equal(t, "NoData", created["noDataState"])
+	assert.Len(t, created["data"], 3)
+	assert.Equal(t, 30.0, group["interval"])
+
+	_, err = createAlertRule(ctx, CreateAlertRuleParams{Title: "No queries", FolderUID: "infra", RuleGroup: "latency"})
+	assert.ErrorContains(t, err, "at least one query is required")
+	_, err = createAlertRule(ctx, CreateAlertRuleParams{
+		Title: "Odd interval", FolderUID: "infra", RuleGroup: "latency",
+		AlertRuleSpec: AlertRuleSpec{Queries: []alertRuleQuery{{RefID: "A", DatasourceUID: "prom", Expr: "up"}}, Condition: "A", Interval: "15s"},
+	})
+	assert.ErrorContains(t, err, "multiple of 10s")
+
+	// Only the given fields change.
+	paused := true
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{
+		UID:           "provisioned",
+		Title:         "New",
+		AlertRuleSpec: AlertRuleSpec{For: "10m", Paused: &paused},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "New", updated["title"])
+	assert.Equal(t, "10m0s", updated["for"])
+	assert.Equal(t, true, updated["isPaused"])
+	assert.Equal(t, "A", updated["condition"])
+	assert.Equal(t, map[string]any{"team": "infra"}, updated["labels"])
+
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{UID: "provisioned", AlertRuleSpec: AlertRuleSpec{Threshold: &alertRuleThreshold{Operator: "gt", Value: 1}}})
+	assert.ErrorContains(t, err, "queries are required")
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{UID: "missing"})
+	assert.Error(t, err)
+
+	// Rules created through the API stay editable in the UI, while the
+	// provenance of provisioned rules is kept.
+	assert.Equal(t, []string{"true", ""}, provenanceHeaders)
+
+	msg, err := deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "provisioned"})
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	assert.Contains(t, msg, `"Old"`)
+	_, err = deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "missing"})
+	assert.Error(t, err)
+}
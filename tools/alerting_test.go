@@ -18,6 +18,8 @@ const (
 	rule2Title      = "Test Alert Rule 2"
 	rulePausedUID   = "test_alert_rule_paused"
 	rulePausedTitle = "Test Alert Rule (Paused)"
+	ruleFolder      = "Test Alerts"
+	ruleGroupName   = "Test Alert Rules"
 )
 
 var (
@@ -38,32 +40,40 @@ var (
 	}
 
 	rule1 = alertRuleSummary{
-		UID:    rule1UID,
-		State:  "",
-		Title:  rule1Title,
-		Labels: rule1Labels,
+		UID:       rule1UID,
+		State:     "",
+		Title:     rule1Title,
+		Labels:    rule1Labels,
+		Folder:    ruleFolder,
+		RuleGroup: ruleGroupName,
 	}
 	rule2 = alertRuleSummary{
-		UID:    rule2UID,
-		State:  "",
-		Title:  rule2Title,
-		Labels: rule2Labels,
+		UID:       rule2UID,
+		State:     "",
+		Title:     rule2Title,
+		Labels:    rule2Labels,
+		Folder:    ruleFolder,
+		RuleGroup: ruleGroupName,
 	}
 	rulePaused = alertRuleSummary{
-		UID:    rulePausedUID,
-		State:  "",
-		Title:  rulePausedTitle,
-		Labels: rule3Labels,
+		UID:       rulePausedUID,
+		State:     "",
+		Title:     rulePausedTitle,
+		Labels:    rule3Labels,
+		Folder:    ruleFolder,
+		RuleGroup: ruleGroupName,
 	}
 	allExpectedRules = []alertRuleSummary{rule1, rule2, rulePaused}
 )
 
 // Because the state depends on the evaluation of the alert rules,
 // clear it before comparing the results to avoid waiting for the
-// alerts to start firing or be in the pending state.
+// alerts to start firing or be in the pending state. The folder UID
+// is generated when the rules are provisioned, so it is cleared too.
 func clearState(rules []alertRuleSummary) []alertRuleSummary {
 	for i := range rules {
 		rules[i].State = ""
+		rules[i].FolderUID = ""
 	}
 
 	return rules
@@ -0,0 +1,483 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	// DefaultAlertmanagerAlertsLimit is the default number of alerts returned
+	// by list_alertmanager_alerts.
+	DefaultAlertmanagerAlertsLimit = 100
+
+	// DefaultAlertmanagerSilenceDuration is the default duration of silences
+	// created by create_alertmanager_silence.
+	DefaultAlertmanagerSilenceDuration = time.Hour
+
+	// alertmanagerSilenceCreator is who silences are created by, unless
+	// another creator is given.
+	alertmanagerSilenceCreator = "mcp-grafana"
+)
+
+// alertmanagerClient queries the API of an Alertmanager datasource through
+// the Grafana datasource proxy.
+type alertmanagerClient struct {
+	httpClient *http.Client
+	// baseURL is the proxy URL of the datasource, followed by the path
+	// prefix of the Alertmanager API for its implementation.
+	baseURL string
+}
+
+func newAlertmanagerClient(ctx context.Context, uid string) (*alertmanagerClient, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	if ds.Type != "alertmanager" {
+		return nil, fmt.Errorf("datasource %s is a %s datasource, not an Alertmanager", uid, ds.Type)
+	}
+
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	escapedUID, err := sanitizePathSegment("datasource UID", uid)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), escapedUID)
+	// Mimir and Cortex serve the Alertmanager API under /alertmanager.
+	if implementation := alertmanagerImplementation(ds.JSONData); implementation == "mimir" || implementation == "cortex" {
+		baseURL += "/alertmanager"
+	}
+
+	// Create the transport for the TLS configuration and unix socket, if any
+	transport, err := cfg.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
+	}
+	return &alertmanagerClient{
+		httpClient: &http.Client{
+			Transport: inspectTransport(ctx, &authRoundTripper{
+				accessToken: cfg.AccessToken,
+				idToken:     cfg.IDToken,
+				apiKey:      cfg.APIKey,
+				underlying:  transport,
+			}),
+		},
+		baseURL: baseURL,
+	}, nil
+}
+
+// alertmanagerImplementation returns the implementation an Alertmanager
+// datasource is configured with, such as "prometheus" or "mimir".
+func alertmanagerImplementation(jsonData any) string {
+	data, err := json.Marshal(jsonData)
+	if err != nil {
+		return ""
+	}
+	var settings struct {
+		Implementation string `json:"implementation"`
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return ""
+	}
+	return settings.Implementation
+}
+
+// do sends a request with an optional JSON body to the Alertmanager API and
+// decodes the JSON response into result, if any.
+func (c *alertmanagerClient) do(ctx context.Context, method, path string, params url.Values, body any, result any) error {
+	u := c.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48))
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Alertmanager API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("unmarshalling response (content: %s): %w", string(respBody), err)
+	}
+	return nil
+}
+
+// alertmanagerAlert is an alert of an Alertmanager, as returned by its API.
+type alertmanagerAlert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	UpdatedAt    time.Time         `json:"updatedAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	Receivers    []struct {
+		Name string `json:"name"`
+	} `json:"receivers,omitempty"`
+	Status struct {
+		// State is "active", "suppressed" or "unprocessed".
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+// alertmanagerAlertFilter returns the parameters filtering the alerts of an
+// Alertmanager.
+func alertmanagerAlertFilter(filter []string, receiver string, includeSuppressed bool) url.Values {
+	params := url.Values{}
+	for _, f := range filter {
+		params.Add("filter", f)
+	}
+	if receiver != "" {
+		params.Set("receiver", receiver)
+	}
+	if !includeSuppressed {
+		params.Set("silenced", "false")
+		params.Set("inhibited", "false")
+	}
+	return params
+}
+
+type ListAlertmanagerAlertsParams struct {
+	DatasourceUID     string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Alertmanager datasource"`
+	Filter            []string `json:"filter,omitempty" jsonschema:"description=Optionally\\, label matchers the alerts must match\\, e.g. ['alertname=\"HighLatency\"'\\, 'severity=~\"critical|warning\"']"`
+	Receiver          string   `json:"receiver,omitempty" jsonschema:"description=Optionally\\, a regular expression matching the receivers the alerts are routed to"`
+	IncludeSuppressed bool     `json:"includeSuppressed,omitempty" jsonschema:"description=Optionally\\, set to true to include silenced and inhibited alerts"`
+	Limit             int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of alerts to return\\, most recent first (default: 100)"`
+}
+
+func listAlertmanagerAlerts(ctx context.Context, args ListAlertmanagerAlertsParams) ([]alertmanagerAlert, error) {
+	client, err := newAlertmanagerClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	alerts := []alertmanagerAlert{}
+	if err := client.do(ctx, http.MethodGet, "/api/v2/alerts", alertmanagerAlertFilter(args.Filter, args.Receiver, args.IncludeSuppressed), nil, &alerts); err != nil {
+		return nil, fmt.Errorf("list Alertmanager alerts: %w", err)
+	}
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].StartsAt.After(alerts[j].StartsAt) })
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultAlertmanagerAlertsLimit
+	}
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+var ListAlertmanagerAlerts = mcpgrafana.MustTool(
+	"list_alertmanager_alerts",
+	"Lists the alerts of an Alertmanager datasource, such as an external Prometheus Alertmanager or Mimir Alertmanager, with their labels, annotations, start time, receivers and state. Silenced and inhibited alerts are excluded unless `includeSuppressed` is set. Filter alerts with label matchers, e.g. `['severity=\"critical\"']`. For Grafana-managed alerts use `list_alert_rules` instead.",
+	listAlertmanagerAlerts,
+	mcp.WithTitleAnnotation("List Alertmanager alerts"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListAlertmanagerAlertGroupsParams struct {
+	DatasourceUID     string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Alertmanager datasource"`
+	Filter            []string `json:"filter,omitempty" jsonschema:"description=Optionally\\, label matchers the alerts of the groups must match\\, e.g. ['cluster=\"prod\"']"`
+	Receiver          string   `json:"receiver,omitempty" jsonschema:"description=Optionally\\, a regular expression matching the receivers of the groups"`
+	IncludeSuppressed bool     `json:"includeSuppressed,omitempty" jsonschema:"description=Optionally\\, set to true to include silenced and inhibited alerts"`
+}
+
+// alertmanagerAlertGroup is a group of alerts notified together.
+type alertmanagerAlertGroup struct {
+	Labels   map[string]string `json:"labels"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receiver"`
+	Alerts []alertmanagerAlert `json:"alerts"`
+}
+
+func listAlertmanagerAlertGroups(ctx context.Context, args ListAlertmanagerAlertGroupsParams) ([]alertmanagerAlertGroup, error) {
+	client, err := newAlertmanagerClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	groups := []alertmanagerAlertGroup{}
+	if err := client.do(ctx, http.MethodGet, "/api/v2/alerts/groups", alertmanagerAlertFilter(args.Filter, args.Receiver, args.IncludeSuppressed), nil, &groups); err != nil {
+		return nil, fmt.Errorf("list Alertmanager alert groups: %w", err)
+	}
+	return groups, nil
+}
+
+var ListAlertmanagerAlertGroups = mcpgrafana.MustTool(
+	"list_alertmanager_alert_groups",
+	"Lists the alert groups of an Alertmanager datasource: the alerts notified together, grouped by the labels of their route's `group_by`, with the group's labels, receiver and alerts. Silenced and inhibited alerts are excluded unless `includeSuppressed` is set. Use this to see what is being notified where, and `list_alertmanager_alerts` to filter individual alerts.",
+	listAlertmanagerAlertGroups,
+	mcp.WithTitleAnnotation("List Alertmanager alert groups"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// alertmanagerMatcher is a label matcher of a silence.
+type alertmanagerMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// String formats the matcher as in PromQL.
+func (m alertmanagerMatcher) String() string {
+	op := "="
+	switch {
+	case m.IsRegex && m.IsEqual:
+		op = "=~"
+	case m.IsRegex:
+		op = "!~"
+	case !m.IsEqual:
+		op = "!="
+	}
+	return m.Name + op + strconv.Quote(m.Value)
+}
+
+// alertmanagerSilence is a silence, as returned by the Alertmanager API.
+type alertmanagerSilence struct {
+	ID        string                `json:"id"`
+	Matchers  []alertmanagerMatcher `json:"matchers"`
+	StartsAt  time.Time             `json:"startsAt"`
+	EndsAt    time.Time             `json:"endsAt"`
+	CreatedBy string                `json:"createdBy"`
+	Comment   string                `json:"comment"`
+	Status    struct {
+		// State is "active", "pending" or "expired".
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// silenceSummary is a silence with its matchers formatted as in PromQL.
+type silenceSummary struct {
+	ID        string   `json:"id"`
+	State     string   `json:"state"`
+	Matchers  []string `json:"matchers"`
+	StartsAt  string   `json:"startsAt"`
+	EndsAt    string   `json:"endsAt"`
+	CreatedBy string   `json:"createdBy"`
+	Comment   string   `json:"comment"`
+}
+
+type ListAlertmanagerSilencesParams struct {
+	DatasourceUID  string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Alertmanager datasource"`
+	Filter         []string `json:"filter,omitempty" jsonschema:"description=Optionally\\, label matchers the silences must match\\, e.g. ['alertname=\"HighLatency\"']"`
+	IncludeExpired bool     `json:"includeExpired,omitempty" jsonschema:"description=Optionally\\, set to true to include expired silences"`
+}
+
+func listAlertmanagerSilences(ctx context.Context, args ListAlertmanagerSilencesParams) ([]silenceSummary, error) {
+	client, err := newAlertmanagerClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	for _, f := range args.Filter {
+		params.Add("filter", f)
+	}
+	var silences []alertmanagerSilence
+	if err := client.do(ctx, http.MethodGet, "/api/v2/silences", params, nil, &silences); err != nil {
+		return nil, fmt.Errorf("list Alertmanager silences: %w", err)
+	}
+
+	summaries := make([]silenceSummary, 0, len(silences))
+	for _, s := range silences {
+		if s.Status.State == "expired" && !args.IncludeExpired {
+			continue
+		}
+		summary := silenceSummary{
+			ID:        s.ID,
+			State:     s.Status.State,
+			Matchers:  make([]string, 0, len(s.Matchers)),
+			StartsAt:  s.StartsAt.UTC().Format(time.RFC3339),
+			EndsAt:    s.EndsAt.UTC().Format(time.RFC3339),
+			CreatedBy: s.CreatedBy,
+			Comment:   s.Comment,
+		}
+		for _, m := range s.Matchers {
+			summary.Matchers = append(summary.Matchers, m.String())
+		}
+		summaries = append(summaries, summary)
+	}
+	// Active silences first, then pending and expired ones, each ending
+	// soonest first.
+	order := map[string]int{"active": 0, "pending": 1, "expired": 2}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].State != summaries[j].State {
+			return order[summaries[i].State] < order[summaries[j].State]
+		}
+		return summaries[i].EndsAt < summaries[j].EndsAt
+	})
+	return summaries, nil
+}
+
+var ListAlertmanagerSilences = mcpgrafana.MustTool(
+	"list_alertmanager_silences",
+	"Lists the silences of an Alertmanager datasource with their ID, state ('active', 'pending' or 'expired'), matchers, time range, creator and comment. Expired silences are excluded unless `includeExpired` is set. Use this to check why alerts aren't being notified.",
+	listAlertmanagerSilences,
+	mcp.WithTitleAnnotation("List Alertmanager silences"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateAlertmanagerSilenceParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Alertmanager datasource"`
+	Matchers      []string `json:"matchers" jsonschema:"required,description=The label matchers of the alerts to silence\\, e.g. ['alertname=\"HighLatency\"'\\, 'cluster=~\"prod-.*\"']"`
+	Comment       string   `json:"comment" jsonschema:"required,description=Why the alerts are silenced"`
+	StartsAt      string   `json:"startsAt,omitempty" jsonschema:"description=Optionally\\, when the silence starts\\, in RFC3339 format or relative to now (e.g. 'now+1h'). Defaults to now"`
+	EndsAt        string   `json:"endsAt,omitempty" jsonschema:"description=Optionally\\, when the silence ends\\, in RFC3339 format or relative to now. Overrides duration"`
+	Duration      string   `json:"duration,omitempty" jsonschema:"description=Optionally\\, how long the silence lasts from its start\\, e.g. '30m' or '2h' (default: 1h)"`
+	CreatedBy     string   `json:"createdBy,omitempty" jsonschema:"description=Optionally\\, who the silence is created by (default: mcp-grafana)"`
+}
+
+// alertmanagerMatchers parses PromQL-style label matchers into the matchers
+// of a silence.
+func alertmanagerMatchers(matchers []string) ([]alertmanagerMatcher, error) {
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+	parsed, err := parser.ParseMetricSelector("{" + strings.Join(matchers, ", ") + "}")
+	if err != nil {
+		return nil, fmt.Errorf("parsing matchers: %w", err)
+	}
+	result := make([]alertmanagerMatcher, 0, len(parsed))
+	matchesAll := true
+	for _, m := range parsed {
+		matchesAll = matchesAll && m.Matches("")
+		result = append(result, alertmanagerMatcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.Type == labels.MatchRegexp || m.Type == labels.MatchNotRegexp,
+			IsEqual: m.Type == labels.MatchEqual || m.Type == labels.MatchRegexp,
+		})
+	}
+	if matchesAll {
+		return nil, fmt.Errorf("at least one matcher must not match the empty string, or the silence would match every alert")
+	}
+	return result, nil
+}
+
+func createAlertmanagerSilence(ctx context.Context, args CreateAlertmanagerSilenceParams) (*silenceSummary, error) {
+	matchers, err := alertmanagerMatchers(args.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Comment) == "" {
+		return nil, fmt.Errorf("comment is required")
+	}
+	startsAt := time.Now()
+	if args.StartsAt != "" {
+		if startsAt, err = parseTime(args.StartsAt); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	endsAt := startsAt.Add(DefaultAlertmanagerSilenceDuration)
+	switch {
+	case args.EndsAt != "":
+		if endsAt, err = parseTime(args.EndsAt); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	case args.Duration != "":
+		d, err := model.ParseDuration(args.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration: %w", err)
+		}
+		endsAt = startsAt.Add(time.Duration(d))
+	}
+	if !endsAt.After(startsAt) || !endsAt.After(time.Now()) {
+		return nil, fmt.Errorf("the silence must end after it starts and in the future, it ends at %s", endsAt.UTC().Format(time.RFC3339))
+	}
+	createdBy := args.CreatedBy
+	if createdBy == "" {
+		createdBy = alertmanagerSilenceCreator
+	}
+
+	client, err := newAlertmanagerClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	silence := alertmanagerSilence{
+		Matchers:  matchers,
+		StartsAt:  startsAt.UTC(),
+		EndsAt:    endsAt.UTC(),
+		CreatedBy: createdBy,
+		Comment:   args.Comment,
+	}
+	var response struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/api/v2/silences", nil, map[string]any{
+		"matchers":  silence.Matchers,
+		"startsAt":  silence.StartsAt,
+		"endsAt":    silence.EndsAt,
+		"createdBy": silence.CreatedBy,
+		"comment":   silence.Comment,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("create Alertmanager silence: %w", err)
+	}
+
+	summary := &silenceSummary{
+		ID:        response.SilenceID,
+		State:     "active",
+		Matchers:  make([]string, 0, len(matchers)),
+		StartsAt:  silence.StartsAt.Format(time.RFC3339),
+		EndsAt:    silence.EndsAt.Format(time.RFC3339),
+		CreatedBy: createdBy,
+		Comment:   args.Comment,
+	}
+	if startsAt.After(time.Now()) {
+		summary.State = "pending"
+	}
+	for _, m := range matchers {
+		summary.Matchers = append(summary.Matchers, m.String())
+	}
+	return summary, nil
+}
+
+var CreateAlertmanagerSilence = mcpgrafana.MustTool(
+	"create_alertmanager_silence",
+	"Creates a silence in an Alertmanager datasource, suppressing the notifications of the alerts matching label matchers, e.g. `['alertname=\"HighLatency\"', 'cluster=\"prod\"']`, for a time range (by default the next hour). A comment explaining why is required. Check which alerts the matchers select with `list_alertmanager_alerts` first. Returns the created silence with its ID.",
+	createAlertmanagerSilence,
+	mcp.WithTitleAnnotation("Create Alertmanager silence"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
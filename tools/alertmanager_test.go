@@ -0,0 +1,144 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertmanagerMatchers(t *testing.T) {
+	matchers, err := alertmanagerMatchers([]string{`alertname="HighLatency"`, `cluster=~"prod-.*"`, `env!="dev"`, `team!~"a|b"`})
+	require.NoError(t, err)
+	assert.Equal(t, []alertmanagerMatcher{
+		{Name: "alertname", Value: "HighLatency", IsEqual: true},
+		{Name: "cluster", Value: "prod-.*", IsRegex: true, IsEqual: true},
+		{Name: "env", Value: "dev"},
+		{Name: "team", Value: "a|b", IsRegex: true},
+	}, matchers)
+	for i, expected := range []string{`alertname="HighLatency"`, `cluster=~"prod-.*"`, `env!="dev"`, `team!~"a|b"`} {
+		assert.Equal(t, expected, matchers[i].String())
+	}
+
+	_, err = alertmanagerMatchers(nil)
+	assert.ErrorContains(t, err, "at least one matcher")
+	_, err = alertmanagerMatchers([]string{`env!="dev"`})
+	assert.ErrorContains(t, err, "every alert")
+	_, err = alertmanagerMatchers([]string{`alertname=`})
+	assert.ErrorContains(t, err, "parsing matchers")
+}
+
+func TestListAlertmanagerAlerts(t *testing.T) {
+	var query url.Values
+	ctx := newMockDatasourceContext(t, "am", "alertmanager", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/alerts", r.URL.Path)
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"fingerprint":"a","labels":{"alertname":"Old"},"startsAt":"2024-01-01T00:00:00Z","status":{"state":"active"}},
+			{"fingerprint":"b","labels":{"alertname":"New"},"startsAt":"2024-01-02T00:00:00Z","receivers":[{"name":"pager"}],"status":{"state":"active"}}
+		]`))
+	})
+
+	alerts, err := listAlertmanagerAlerts(ctx, ListAlertmanagerAlertsParams{DatasourceUID: "am", Filter: []string{`severity="critical"`}, Receiver: "pager", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`severity="critical"`}, query["filter"])
+	assert.Equal(t, "pager", query.Get("receiver"))
+	assert.Equal(t, "false", query.Get("silenced"))
+	assert.Equal(t, "false", query.Get("inhibited"))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "New", alerts[0].Labels["alertname"])
+	assert.Equal(t, "pager", alerts[0].Receivers[0].Name)
+
+	_, err = listAlertmanagerAlerts(ctx, ListAlertmanagerAlertsParams{DatasourceUID: "am", IncludeSuppressed: true})
+	require.NoError(t, err)
+	assert.Empty(t, query.Get("silenced"))
+}
+
+func TestListAlertmanagerSilences(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "am", "alertmanager", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/silences", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"id":"expired","matchers":[{"name":"alertname","value":"A","isRegex":false,"isEqual":true}],"startsAt":"2024-01-01T00:00:00Z","endsAt":"2024-01-01T01:00:00Z","createdBy":"bob","comment":"old","status":{"state":"expired"}},
+			{"id":"pending","matchers":[{"name":"env","value":"dev","isRegex":false,"isEqual":false}],"startsAt":"2024-01-03T00:00:00Z","endsAt":"2024-01-03T01:00:00Z","createdBy":"bob","comment":"later","status":{"state":"pending"}},
+			{"id":"active","matchers":[{"name":"cluster","value":"prod-.*","isRegex":true,"isEqual":true}],"startsAt":"2024-01-02T00:00:00Z","endsAt":"2024-01-02T05:00:00+02:00","createdBy":"alice","comment":"maintenance","status":{"state":"active"}}
+		]`))
+	})
+
+	silences, err := listAlertmanagerSilences(ctx, ListAlertmanagerSilencesParams{DatasourceUID: "am"})
+	require.NoError(t, err)
+	require.Len(t, silences, 2)
+	assert.Equal(t, silenceSummary{
+		ID:        "active",
+		State:     "active",
+		Matchers:  []string{`cluster=~"prod-.*"`},
+		StartsAt:  "2024-01-02T00:00:00Z",
+		EndsAt:    "2024-01-02T03:00:00Z",
+		CreatedBy: "alice",
+		Comment:   "maintenance",
+	}, silences[0])
+	assert.Equal(t, "pending", silences[1].ID)
+
+	silences, err = listAlertmanagerSilences(ctx, ListAlertmanagerSilencesParams{DatasourceUID: "am", IncludeExpired: true})
+	require.NoError(t, err)
+	assert.Len(t, silences, 3)
+	assert.Equal(t, "expired", silences[2].ID)
+}
+
+func TestCreateAlertmanagerSilence(t *testing.T) {
+	var body map[string]any
+	ctx := newMockDatasourceContext(t, "am", "alertmanager", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/api/v2/silences", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"silenceID":"abc"}`))
+	})
+
+	before := time.Now().UTC()
+	silence, err := createAlertmanagerSilence(ctx, CreateAlertmanagerSilenceParams{
+		DatasourceUID: "am",
+		Matchers:      []string{`alertname="HighLatency"`},
+		Comment:       "deploying a fix",
+		Duration:      "2h",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", silence.ID)
+	assert.Equal(t, "active", silence.State)
+	assert.Equal(t, []string{`alertname="HighLatency"`}, silence.Matchers)
+	assert.Equal(t, "mcp-grafana", body["createdBy"])
+	assert.Equal(t, "deploying a fix", body["comment"])
+	assert.Equal(t, []any{map[string]any{"name": "alertname", "value": "HighLatency", "isRegex": false, "isEqual": true}}, body["matchers"])
+	startsAt, err := time.Parse(time.RFC3339, body["startsAt"].(string))
+	require.NoError(t, err)
+	endsAt, err := time.Parse(time.RFC3339, body["endsAt"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, before, startsAt, time.Minute)
+	assert.Equal(t, 2*time.Hour, endsAt.Sub(startsAt))
+
+	_, err = createAlertmanagerSilence(ctx, CreateAlertmanagerSilenceParams{DatasourceUID: "am", Matchers: []string{`alertname="A"`}})
+	assert.ErrorContains(t, err, "comment is required")
+	_, err = createAlertmanagerSilence(ctx, CreateAlertmanagerSilenceParams{DatasourceUID: "am", Matchers: []string{`alertname="A"`}, Comment: "c", EndsAt: "now-1h"})
+	assert.ErrorContains(t, err, "must end after it starts")
+}
+
+func TestAlertmanagerClientDatasourceType(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected request")
+	})
+	_, err := listAlertmanagerSilences(ctx, ListAlertmanagerSilencesParams{DatasourceUID: "prom"})
+	assert.ErrorContains(t, err, "not an Alertmanager")
+}
+
+func TestAlertmanagerImplementation(t *testing.T) {
+	assert.Equal(t, "mimir", alertmanagerImplementation(map[string]any{"implementation": "mimir"}))
+	assert.Equal(t, "", alertmanagerImplementation(nil))
+}
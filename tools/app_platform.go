@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Grafana 12 serves dashboards and folders through Kubernetes-style app
+// platform APIs under /apis, which support watching for changes.
+const (
+	appPlatformAPIVersion = "v1beta1"
+	dashboardAPIGroup     = "dashboard.grafana.app"
+	folderAPIGroup        = "folder.grafana.app"
+
+	// appPlatformFolderAnnotation holds the UID of a resource's folder.
+	appPlatformFolderAnnotation = "grafana.app/folder"
+
+	DefaultWatchTimeoutSeconds = 30
+	MaxWatchTimeoutSeconds     = 300
+)
+
+// appPlatformClient is a client for the Grafana app platform APIs.
+type appPlatformClient struct {
+	httpClient *http.Client
+	base       *url.URL
+	namespace  string
+}
+
+func newAppPlatformClient(ctx context.Context) (*appPlatformClient, error) {
+	if api := grafanaAPIFromContext(ctx); !api.hasAppPlatformAPIs() {
+		return nil, fmt.Errorf("the app platform APIs require Grafana 12 or later, found %d.%d", api.version.Major, api.version.Minor)
+	}
+
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	// Create the transport for the TLS configuration and unix socket, if any
+	transport, err := cfg.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
+	}
+	base, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url: %w", err)
+	}
+
+	c := &appPlatformClient{
+		// Requests are bounded by their context rather than a client
+		// timeout, since watches are long-lived.
+		httpClient: &http.Client{
+			Transport: &authRoundTripper{
+				accessToken: cfg.AccessToken,
+				idToken:     cfg.IDToken,
+				apiKey:      cfg.APIKey,
+				underlying:  transport,
+			},
+		},
+		base: base,
+	}
+	if c.namespace, err = c.detectNamespace(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// detectNamespace returns the app platform namespace of the current
+// organization or stack, which Grafana publishes in its frontend settings.
+func (c *appPlatformClient) detectNamespace(ctx context.Context) (string, error) {
+	var settings struct {
+		Namespace string `json:"namespace"`
+	}
+	resp, err := c.do(ctx, c.base.JoinPath("api", "frontend", "settings"))
+	if err != nil {
+		return "", fmt.Errorf("get frontend settings: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+		return "", fmt.Errorf("decode frontend settings: %w", err)
+	}
+	return stringOrDefault(settings.Namespace, "default"), nil
+}
+
+func (c *appPlatformClient) resourceURL(group, resource string, query url.Values) *url.URL {
+	u := c.base.JoinPath("apis", group, appPlatformAPIVersion, "namespaces", c.namespace, resource)
+	u.RawQuery = query.Encode()
+	return u
+}
+
+func (c *appPlatformClient) do(ctx context.Context, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("Grafana API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// appPlatformObject is a dashboard or folder as returned by the app platform
+// APIs.
+type appPlatformObject struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		ResourceVersion string            `json:"resourceVersion,omitempty"`
+		Annotations     map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+	Spec map[string]any `json:"spec,omitempty"`
+}
+
+type appPlatformList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+}
+
+// appPlatformWatchEvent is a single event from a watch stream. Type is
+// ADDED, MODIFIED, DELETED, BOOKMARK or ERROR.
+type appPlatformWatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// currentResourceVersion returns the resource version of a collection, from
+// which a watch only reports subsequent changes.
+func (c *appPlatformClient) currentResourceVersion(ctx context.Context, group, resource string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	resp, err := c.do(ctx, c.resourceURL(group, resource, url.Values{"limit": {"1"}}))
+	if err != nil {
+		return "", fmt.Errorf("list %s: %w", resource, err)
+	}
+	defer resp.Body.Close()
+	var list appPlatformList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", fmt.Errorf("decode %s list: %w", resource, err)
+	}
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch streams changes to a collection after resourceVersion, calling fn
+// for each event until the context is done.
+func (c *appPlatformClient) watch(ctx context.Context, group, resource, resourceVersion string, fn func(appPlatformWatchEvent) error) error {
+	query := url.Values{"watch": {"true"}}
+	if resourceVersion != "" {
+		query.Set("resourceVersion", resourceVersion)
+	}
+	resp, err := c.do(ctx, c.resourceURL(group, resource, query))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("watch %s: %w", resource, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event appPlatformWatchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("decode %s watch event: %w", resource, err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+type WatchDashboardChangesParams struct {
+	ResourceType   string `json:"resourceType,omitempty" jsonschema:"enum=dashboard,enum=folder,description=Optionally\\, the type of resource to watch. Defaults to dashboard"`
+	FolderUID      string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, only report changes to resources in this folder"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty" jsonschema:"description=Optionally\\, how long to watch for in seconds (default: 30\\, max: 300)"`
+}
+
+// resourceChange is a single change reported by watch_dashboard_changes.
+type resourceChange struct {
+	Type            string `json:"type"`
+	ResourceType    string `json:"resourceType"`
+	UID             string `json:"uid"`
+	Title           string `json:"title,omitempty"`
+	FolderUID       string `json:"folderUid,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// URI identifies the resource in the resources/updated notification
+	// sent to the client for this change.
+	URI string `json:"uri"`
+}
+
+func resourceChangeURI(resourceType, uid string) string {
+	return fmt.Sprintf("grafana://%ss/%s", resourceType, url.PathEscape(uid))
+}
+
+func watchDashboardChanges(ctx context.Context, args WatchDashboardChangesParams) ([]resourceChange, error) {
+	resourceType := stringOrDefault(args.ResourceType, resourceTypeDashboard)
+	var group, resource string
+	switch resourceType {
+	case resourceTypeDashboard:
+		group, resource = dashboardAPIGroup, "dashboards"
+	case resourceTypeFolder:
+		group, resource = folderAPIGroup, "folders"
+	default:
+		return nil, fmt.Errorf("unknown resource type %q, expected %q or %q", resourceType, resourceTypeDashboard, resourceTypeFolder)
+	}
+	timeout := args.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = DefaultWatchTimeoutSeconds
+	}
+	timeout = min(timeout, MaxWatchTimeoutSeconds)
+
+	client, err := newAppPlatformClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resourceVersion, err := client.currentResourceVersion(ctx, group, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	srv := server.ServerFromContext(ctx)
+	changes := []resourceChange{}
+	err = client.watch(watchCtx, group, resource, resourceVersion, func(event appPlatformWatchEvent) error {
+		switch event.Type {
+		case "BOOKMARK":
+			return nil
+		case "ERROR":
+			return fmt.Errorf("watch %s: %s", resource, string(event.Object))
+		}
+		var obj appPlatformObject
+		if err := json.Unmarshal(event.Object, &obj); err != nil {
+			return fmt.Errorf("decode %s: %w", resourceType, err)
+		}
+		change := resourceChange{
+			Type:            event.Type,
+			ResourceType:    resourceType,
+			UID:             obj.Metadata.Name,
+			FolderUID:       obj.Metadata.Annotations[appPlatformFolderAnnotation],
+			ResourceVersion: obj.Metadata.ResourceVersion,
+			URI:             resourceChangeURI(resourceType, obj.Metadata.Name),
+		}
+		if args.FolderUID != "" && change.FolderUID != args.FolderUID {
+			return nil
+		}
+		change.Title, _ = obj.Spec["title"].(string)
+		changes = append(changes, change)
+		if srv != nil {
+			_ = srv.SendNotificationToClient(ctx, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": change.URI})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+var WatchDashboardChanges = mcpgrafana.MustTool(
+	"watch_dashboard_changes",
+	"Watches for dashboards (or folders) being created, modified or deleted using Grafana's app platform APIs, which require Grafana 12 or later. Blocks for up to `timeoutSeconds` (default 30, max 300) and returns each change seen with its type (ADDED, MODIFIED or DELETED), UID, title, folder UID and resource version. A `notifications/resources/updated` notification with the resource's URI (e.g. grafana://dashboards/<uid>) is also sent to the client as each change arrives.",
+	watchDashboardChanges,
+	mcp.WithTitleAnnotation("Watch dashboard changes"),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,65 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDashboardChanges(t *testing.T) {
+	t.Run("reports changes after the current resource version", func(t *testing.T) {
+		const dashboards = "/apis/dashboard.grafana.app/v1beta1/namespaces/stacks-1/dashboards"
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/frontend/settings", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"namespace":"stacks-1"}`))
+		})
+		mux.HandleFunc(dashboards, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("watch") != "true" {
+				assert.Equal(t, "1", r.URL.Query().Get("limit"))
+				_, _ = w.Write([]byte(`{"metadata":{"resourceVersion":"42"},"items":[]}`))
+				return
+			}
+			assert.Equal(t, "42", r.URL.Query().Get("resourceVersion"))
+			_, _ = w.Write([]byte(`{"type":"MODIFIED","object":{"metadata":{"name":"abc","resourceVersion":"43","annotations":{"grafana.app/folder":"team-a"}},"spec":{"title":"Checkout"}}}
+{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"44"}}}
+{"type":"DELETED","object":{"metadata":{"name":"def","resourceVersion":"45","annotations":{"grafana.app/folder":"team-b"}},"spec":{"title":"Old"}}}
+`))
+		})
+		ctx := newMockGrafanaVersionContext(t, "12.0.1", mux)
+
+		changes, err := watchDashboardChanges(ctx, WatchDashboardChangesParams{TimeoutSeconds: 1})
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, resourceChange{
+			Type:            "MODIFIED",
+			ResourceType:    "dashboard",
+			UID:             "abc",
+			Title:           "Checkout",
+			FolderUID:       "team-a",
+			ResourceVersion: "43",
+			URI:             "grafana://dashboards/abc",
+		}, changes[0])
+		assert.Equal(t, "DELETED", changes[1].Type)
+
+		changes, err = watchDashboardChanges(ctx, WatchDashboardChangesParams{FolderUID: "team-b", TimeoutSeconds: 1})
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "def", changes[0].UID)
+	})
+
+	t.Run("requires Grafana 12", func(t *testing.T) {
+		ctx := newMockGrafanaVersionContext(t, "11.3.0", http.NewServeMux())
+		_, err := watchDashboardChanges(ctx, WatchDashboardChangesParams{})
+		assert.ErrorContains(t, err, "Grafana 12 or later")
+	})
+
+	t.Run("unknown resource type", func(t *testing.T) {
+		_, err := watchDashboardChanges(t.Context(), WatchDashboardChangesParams{ResourceType: "alertRule"})
+		assert.ErrorContains(t, err, "unknown resource type")
+	})
+}
@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type FindBrokenPanelsParams struct {
+	DashboardUIDs []string `json:"dashboardUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of the dashboards to check. Defaults to all dashboards"`
+	DatasourceUID string   `json:"datasourceUid,omitempty" jsonschema:"description=Optionally\\, the UID of the Prometheus datasource to use for panels whose datasource is a template variable or unset"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start of the time range in which metrics must exist\\, in RFC3339 format or relative to now (e.g. 'now-7d'). Defaults to 24 hours ago"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end of the time range in which metrics must exist\\, in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to now"`
+}
+
+// brokenPanel is a panel query referencing metrics, labels or a datasource
+// which no longer exist.
+type brokenPanel struct {
+	DashboardUID   string `json:"dashboardUid"`
+	DashboardTitle string `json:"dashboardTitle"`
+	dashboardTarget
+	// Missing names each missing datasource, metric or label.
+	Missing []string `json:"missing"`
+}
+
+type brokenPanelsReport struct {
+	DashboardsChecked int           `json:"dashboardsChecked"`
+	QueriesChecked    int           `json:"queriesChecked"`
+	QueriesSkipped    int           `json:"queriesSkipped"`
+	Broken            []brokenPanel `json:"broken"`
+	Errors            []string      `json:"errors,omitempty"`
+}
+
+// promSeriesChecker checks whether metrics and labels exist in Prometheus
+// datasources, caching results per datasource.
+type promSeriesChecker struct {
+	start, end time.Time
+	// all lists the datasources of the Grafana instance, once loaded.
+	all         models.DataSourceList
+	datasources map[string]*promSeriesState
+}
+
+type promSeriesState struct {
+	client      promv1.API
+	missing     bool // the datasource does not exist
+	unsupported bool // the datasource is not a Prometheus datasource
+	metrics     map[string]bool
+	labels      map[string]map[string]bool
+}
+
+func newPromSeriesChecker(start, end time.Time) *promSeriesChecker {
+	return &promSeriesChecker{start: start, end: end, datasources: map[string]*promSeriesState{}}
+}
+
+// datasource returns the state of the datasource a panel references, by UID
+// or, as in older dashboards, by name.
+func (c *promSeriesChecker) datasource(ctx context.Context, ref string) (*promSeriesState, error) {
+	if state, ok := c.datasources[ref]; ok {
+		return state, nil
+	}
+	if c.all == nil {
+		resp, err := mcpgrafana.GrafanaClientFromContext(ctx).Datasources.GetDataSources()
+		if err != nil {
+			return nil, fmt.Errorf("list datasources: %w", err)
+		}
+		c.all = resp.Payload
+	}
+	state := &promSeriesState{labels: map[string]map[string]bool{}, missing: true}
+	for _, ds := range c.all {
+		if ds.UID != ref && ds.Name != ref {
+			continue
+		}
+		state.missing = false
+		if ds.Type != "prometheus" {
+			state.unsupported = true
+			break
+		}
+		var err error
+		if state.client, err = promClientFromContext(ctx, ds.UID); err != nil {
+			return nil, fmt.Errorf("getting Prometheus client: %w", err)
+		}
+		break
+	}
+	c.datasources[ref] = state
+	return state, nil
+}
+
+func (c *promSeriesChecker) metricExists(ctx context.Context, state *promSeriesState, metric string) (bool, error) {
+	if state.metrics == nil {
+		values, _, err := state.client.LabelValues(ctx, labels.MetricName, nil, c.start, c.end)
+		if err != nil {
+			return false, fmt.Errorf("listing Prometheus metric names: %w", err)
+		}
+		state.metrics = make(map[string]bool, len(values))
+		for _, v := range values {
+			state.metrics[string(v)] = true
+		}
+	}
+	return state.metrics[metric], nil
+}
+
+func (c *promSeriesChecker) labelExists(ctx context.Context, state *promSeriesState, metric, label string) (bool, error) {
+	names, ok := state.labels[metric]
+	if !ok {
+		selector := fmt.Sprintf("{%s=%q}", labels.MetricName, metric)
+		result, _, err := state.client.LabelNames(ctx, []string{selector}, c.start, c.end)
+		if err != nil {
+			return false, fmt.Errorf("listing Prometheus label names for %s: %w", metric, err)
+		}
+		names = make(map[string]bool, len(result))
+		for _, n := range result {
+			names[n] = true
+		}
+		state.labels[metric] = names
+	}
+	return names[label], nil
+}
+
+// missingSeries returns the metrics and labels referenced by the expression
+// which do not exist in the datasource. Selectors whose metric name is a
+// template variable are not checked, and neither are matchers which match
+// series without the label, such as `label!="value"` or `label=""`.
+func (c *promSeriesChecker) missingSeries(ctx context.Context, state *promSeriesState, expr parser.Expr) ([]string, error) {
+	var missing []string
+	seen := map[string]bool{}
+	add := func(m string) {
+		if !seen[m] {
+			seen[m] = true
+			missing = append(missing, m)
+		}
+	}
+	for _, s := range promqlSelectors(expr) {
+		if s.Metric == "" || s.hasVariable() {
+			continue
+		}
+		exists, err := c.metricExists(ctx, state, s.Metric)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			add(fmt.Sprintf("metric %q", s.Metric))
+			continue
+		}
+		for _, m := range s.Matchers {
+			if m.Name == labels.MetricName || strings.Contains(m.Name, promqlVariablePlaceholder) || m.Matches("") {
+				continue
+			}
+			exists, err := c.labelExists(ctx, state, s.Metric, m.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				add(fmt.Sprintf("label %q on metric %q", m.Name, s.Metric))
+			}
+		}
+	}
+	return missing, nil
+}
+
+// isTemplateVariable reports whether a datasource UID refers to a template
+// variable rather than a concrete datasource.
+func isTemplateVariable(uid string) bool {
+	return strings.HasPrefix(uid, "$") || strings.HasPrefix(uid, "[[")
+}
+
+func findBrokenPanels(ctx context.Context, args FindBrokenPanelsParams) (*brokenPanelsReport, error) {
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+	var err error
+	if args.StartRFC3339 != "" {
+		if start, err = parseTime(args.StartRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if args.EndRFC3339 != "" {
+		if end, err = parseTime(args.EndRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+
+	uids := args.DashboardUIDs
+	if len(uids) == 0 {
+		dashboards, err := searchAllResources(ctx, dashboardTypeStr, resourceTypeDashboard)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dashboards {
+			uids = append(uids, d.UID)
+		}
+	}
+
+	report := &brokenPanelsReport{Broken: []brokenPanel{}}
+	checker := newPromSeriesChecker(start, end)
+	for _, uid := range uids {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		db, ok := dashboard.Dashboard.(map[string]any)
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("dashboard %s is not a JSON object", uid))
+			continue
+		}
+		title, _ := db["title"].(string)
+		report.DashboardsChecked++
+
+		for _, target := range dashboardTargets(db) {
+			missing, skipped, err := checker.checkTarget(ctx, target, args.DatasourceUID)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("checking panel %q of dashboard %s: %s", target.PanelTitle, uid, err))
+				continue
+			}
+			if skipped {
+				report.QueriesSkipped++
+				continue
+			}
+			report.QueriesChecked++
+			if len(missing) > 0 {
+				report.Broken = append(report.Broken, brokenPanel{
+					DashboardUID:    uid,
+					DashboardTitle:  title,
+					dashboardTarget: target,
+					Missing:         missing,
+				})
+			}
+		}
+	}
+	return report, nil
+}
+
+// checkTarget checks a single panel target. Targets which cannot be checked,
+// such as those for non-Prometheus datasources or with unparseable queries,
+// are reported as skipped.
+func (c *promSeriesChecker) checkTarget(ctx context.Context, target dashboardTarget, fallbackUID string) ([]string, bool, error) {
+	if target.Datasource.Type != "" && target.Datasource.Type != "prometheus" {
+		return nil, true, nil
+	}
+	ref := target.Datasource.UID
+	if ref == "" || isTemplateVariable(ref) {
+		ref = fallbackUID
+	}
+	if ref == "" {
+		return nil, true, nil
+	}
+
+	state, err := c.datasource(ctx, ref)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case state.missing:
+		return []string{fmt.Sprintf("datasource %q", ref)}, false, nil
+	case state.unsupported:
+		return nil, true, nil
+	}
+
+	expr, err := parsePromQL(target.Expr)
+	if err != nil {
+		return nil, true, nil
+	}
+	missing, err := c.missingSeries(ctx, state, expr)
+	if err != nil {
+		return nil, false, err
+	}
+	return missing, false, nil
+}
+
+var FindBrokenPanels = mcpgrafana.MustTool(
+	"find_broken_panels",
+	"Finds dashboard panels which will render 'No data' because their Prometheus queries reference metrics or labels which no longer exist in the datasource (for example after a metric was renamed or dropped), or because their datasource was deleted. Checks every panel query across the given dashboards (or all dashboards) and returns the broken panels with the missing datasource, metrics and labels named. Metrics must have been present within the time range (default: the last 24 hours). Queries for other datasource types, queries whose metric name is a template variable, and queries whose datasource is a template variable (unless datasourceUid is given) are skipped.",
+	findBrokenPanels,
+	mcp.WithTitleAnnotation("Find broken panels"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
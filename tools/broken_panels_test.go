@@ -0,0 +1,142 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardTargets(t *testing.T) {
+	db := map[string]any{
+		"panels": []any{
+			map[string]any{
+				"id":         float64(1),
+				"title":      "Requests",
+				"datasource": map[string]any{"uid": "prom", "type": "prometheus"},
+				"targets": []any{
+					map[string]any{"refId": "A", "expr": "up"},
+					map[string]any{"refId": "B", "expr": "down", "datasource": map[string]any{"uid": "other", "type": "prometheus"}},
+					map[string]any{"refId": "C", "query": "not a prometheus query"},
+				},
+			},
+			map[string]any{
+				"id":    float64(2),
+				"type":  "row",
+				"title": "Collapsed",
+				"panels": []any{
+					map[string]any{
+						"id":         float64(3),
+						"title":      "Legacy",
+						"datasource": "$datasource",
+						"targets":    []any{map[string]any{"refId": "A", "expr": "legacy_metric"}},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []dashboardTarget{
+		{PanelID: 1, PanelTitle: "Requests", RefID: "A", Expr: "up", Datasource: datasourceInfo{UID: "prom", Type: "prometheus"}},
+		{PanelID: 1, PanelTitle: "Requests", RefID: "B", Expr: "down", Datasource: datasourceInfo{UID: "other", Type: "prometheus"}},
+		{PanelID: 3, PanelTitle: "Legacy", RefID: "A", Expr: "legacy_metric", Datasource: datasourceInfo{UID: "$datasource"}},
+	}, dashboardTargets(db))
+}
+
+func TestPromSeriesCheckerCheckTarget(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid":"prom","name":"Prometheus","type":"prometheus"},{"uid":"logs","name":"Logs/Prod","type":"loki"}]`))
+	})
+	mux.HandleFunc("/api/datasources/uid/prom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"prom","type":"prometheus"}`))
+	})
+	mux.Handle("/api/datasources/proxy/uid/prom/", http.StripPrefix("/api/datasources/proxy/uid/prom", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/label/__name__/values":
+			_, _ = w.Write([]byte(`{"status":"success","data":["up","http_requests_total"]}`))
+		case "/api/v1/labels":
+			assert.Equal(t, []string{`{__name__="http_requests_total"}`}, r.Form["match[]"])
+			_, _ = w.Write([]byte(`{"status":"success","data":["__name__","job","code"]}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})))
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+	checker := newPromSeriesChecker(time.Now().Add(-time.Hour), time.Now())
+	prom := datasourceInfo{UID: "prom", Type: "prometheus"}
+
+	for _, tc := range []struct {
+		name     string
+		target   dashboardTarget
+		fallback string
+		missing  []string
+		skipped  bool
+	}{
+		{
+			name:   "all series exist",
+			target: dashboardTarget{Expr: `sum(rate(http_requests_total{job="$job"}[$__rate_interval])) / up`, Datasource: prom},
+		},
+		{
+			name:    "missing metric and label",
+			target:  dashboardTarget{Expr: `rate(http_requests_total{env="prod"}[5m]) + renamed_metric + renamed_metric`, Datasource: prom},
+			missing: []string{`label "env" on metric "http_requests_total"`, `metric "renamed_metric"`},
+		},
+		{
+			name:   "absent labels in negative and empty matchers",
+			target: dashboardTarget{Expr: `http_requests_total{env!="prod", region="", code!~"5..", zone=~".*"}`, Datasource: prom},
+		},
+		{
+			name:    "legacy datasource name",
+			target:  dashboardTarget{Expr: `renamed_metric`, Datasource: datasourceInfo{UID: "Prometheus"}},
+			missing: []string{`metric "renamed_metric"`},
+		},
+		{
+			name:    "legacy datasource name with a slash",
+			target:  dashboardTarget{Expr: `up`, Datasource: datasourceInfo{UID: "Logs/Prod"}},
+			skipped: true,
+		},
+		{
+			name:    "missing datasource",
+			target:  dashboardTarget{Expr: `up`, Datasource: datasourceInfo{UID: "deleted", Type: "prometheus"}},
+			missing: []string{`datasource "deleted"`},
+		},
+		{
+			name:    "template variable datasource without fallback",
+			target:  dashboardTarget{Expr: `up`, Datasource: datasourceInfo{UID: "$datasource"}},
+			skipped: true,
+		},
+		{
+			name:     "template variable datasource with fallback",
+			target:   dashboardTarget{Expr: `gone`, Datasource: datasourceInfo{UID: "${datasource}"}},
+			fallback: "prom",
+			missing:  []string{`metric "gone"`},
+		},
+		{
+			name:    "other datasource type",
+			target:  dashboardTarget{Expr: `{app="foo"}`, Datasource: datasourceInfo{UID: "loki", Type: "loki"}},
+			skipped: true,
+		},
+		{
+			name:    "unparseable query",
+			target:  dashboardTarget{Expr: `rate(up[$window])`, Datasource: prom},
+			skipped: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			missing, skipped, err := checker.checkTarget(ctx, tc.target, tc.fallback)
+			require.NoError(t, err)
+			assert.Equal(t, tc.skipped, skipped)
+			assert.Equal(t, tc.missing, missing)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// ttlCache is a concurrency-safe in-process cache whose entries expire after
+// the TTL configured for the server (see GrafanaConfig.CacheTTL). Nothing is
+// cached if the TTL is zero.
+type ttlCache[V any] struct {
+	mu      sync.Mutex
+	entries map[string]ttlCacheEntry[V]
+}
+
+type ttlCacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newTTLCache[V any]() *ttlCache[V] {
+	return &ttlCache[V]{entries: map[string]ttlCacheEntry[V]{}}
+}
+
+// getOrLoad returns the cached value for key, calling load on a miss. Errors
+// are not cached.
+func (c *ttlCache[V]) getOrLoad(ctx context.Context, key string, load func() (V, error)) (V, error) {
+	ttl := mcpgrafana.GrafanaConfigFromContext(ctx).CacheTTL
+	if ttl <= 0 {
+		return load()
+	}
+	key = cacheKey(ctx, key)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	c.mu.Lock()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = ttlCacheEntry[V]{value: v, expires: now.Add(ttl)}
+	c.mu.Unlock()
+	return v, nil
+}
+
+// cacheKey scopes a key to the Grafana instance and credentials in the
+// context, since users may not be able to see the same resources.
+func cacheKey(ctx context.Context, key string) string {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	h := sha256.Sum256([]byte(strings.Join([]string{cfg.URL, cfg.APIKey, cfg.AccessToken, cfg.IDToken}, "\x00")))
+	return hex.EncodeToString(h[:]) + "/" + key
+}
+
+var datasourceCache = newTTLCache[*models.DataSource]()
+
+// cachedDatasourceByUID is getDatasourceByUID with caching, for tools which
+// check a datasource exists before every query.
+func cachedDatasourceByUID(ctx context.Context, uid string) (*models.DataSource, error) {
+	return datasourceCache.getOrLoad(ctx, uid, func() (*models.DataSource, error) {
+		return getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	})
+}
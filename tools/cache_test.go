@@ -0,0 +1,77 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	cfg.CacheTTL = ttl
+	return mcpgrafana.WithGrafanaConfig(ctx, cfg)
+}
+
+func TestTTLCache(t *testing.T) {
+	loads := 0
+	load := func() (int, error) {
+		loads++
+		return loads, nil
+	}
+
+	t.Run("disabled without a TTL", func(t *testing.T) {
+		loads = 0
+		c := newTTLCache[int]()
+		ctx := context.Background()
+		_, _ = c.getOrLoad(ctx, "k", load)
+		v, err := c.getOrLoad(ctx, "k", load)
+		require.NoError(t, err)
+		assert.Equal(t, 2, v)
+	})
+
+	t.Run("caches per key and credentials", func(t *testing.T) {
+		loads = 0
+		c := newTTLCache[int]()
+		ctx := withCacheTTL(mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://grafana", APIKey: "a"}), time.Minute)
+
+		v, _ := c.getOrLoad(ctx, "k", load)
+		assert.Equal(t, 1, v)
+		v, _ = c.getOrLoad(ctx, "k", load)
+		assert.Equal(t, 1, v)
+		v, _ = c.getOrLoad(ctx, "other", load)
+		assert.Equal(t, 2, v)
+
+		otherUser := withCacheTTL(mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://grafana", APIKey: "b"}), time.Minute)
+		v, _ = c.getOrLoad(otherUser, "k", load)
+		assert.Equal(t, 3, v)
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		loads = 0
+		c := newTTLCache[int]()
+		ctx := withCacheTTL(context.Background(), time.Nanosecond)
+		_, _ = c.getOrLoad(ctx, "k", load)
+		time.Sleep(time.Millisecond)
+		v, _ := c.getOrLoad(ctx, "k", load)
+		assert.Equal(t, 2, v)
+		assert.Len(t, c.entries, 1)
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		c := newTTLCache[int]()
+		ctx := withCacheTTL(context.Background(), time.Minute)
+		_, err := c.getOrLoad(ctx, "k", func() (int, error) { return 0, errors.New("boom") })
+		require.Error(t, err)
+		v, err := c.getOrLoad(ctx, "k", func() (int, error) { return 42, nil })
+		require.NoError(t, err)
+		assert.Equal(t, 42, v)
+	})
+}
@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// grafanaComURL is the URL of grafana.com, from which community dashboards
+// are downloaded.
+var grafanaComURL = "https://grafana.com"
+
+type ImportCommunityDashboardParams struct {
+	ID          int               `json:"id" jsonschema:"required,description=The grafana.com ID of the dashboard\\, as in https://grafana.com/grafana/dashboards/1860 for the Node Exporter Full dashboard"`
+	Revision    int               `json:"revision,omitempty" jsonschema:"description=Optionally\\, the revision of the dashboard to import. Defaults to the latest revision"`
+	Datasources map[string]string `json:"datasources,omitempty" jsonschema:"description=Optionally\\, the datasources to use\\, by input name or plugin type\\, as UIDs or names\\, e.g. {'DS_PROMETHEUS': 'prom-uid'} or {'prometheus': 'Prod Prometheus'}. Inputs which aren't given use the default datasource of their type\\, or the only one"`
+	Constants   map[string]string `json:"constants,omitempty" jsonschema:"description=Optionally\\, values of the dashboard's constant inputs by name. Defaults to the values in the dashboard"`
+	FolderUID   string            `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder to import the dashboard into. Defaults to the General folder"`
+	Overwrite   bool              `json:"overwrite,omitempty" jsonschema:"description=Set to true to replace a dashboard with the same UID"`
+}
+
+// communityDashboardInput is an input declared in the __inputs of a
+// dashboard exported for sharing, which has to be set on import.
+type communityDashboardInput struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId"`
+	Value    string `json:"value"`
+}
+
+type importedDashboardInput struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// Datasource is the name of the datasource chosen for a datasource input.
+	Datasource string `json:"datasource,omitempty"`
+}
+
+type importedCommunityDashboard struct {
+	UID       string                   `json:"uid"`
+	Title     string                   `json:"title"`
+	URL       string                   `json:"url"`
+	FolderUID string                   `json:"folderUid,omitempty"`
+	Revision  int                      `json:"revision"`
+	Inputs    []importedDashboardInput `json:"inputs"`
+}
+
+// getGrafanaCom gets a JSON document from the grafana.com API.
+func getGrafanaCom(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, grafanaComURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := (&http.Client{Timeout: defaultTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s from grafana.com: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("get %s from grafana.com: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s from grafana.com: %w", path, err)
+	}
+	return nil
+}
+
+// downloadCommunityDashboard downloads a revision of a grafana.com
+// dashboard, or its latest revision if revision is 0.
+func downloadCommunityDashboard(ctx context.Context, id, revision int) (map[string]any, int, error) {
+	if revision <= 0 {
+		var info struct {
+			Revision int `json:"revision"`
+		}
+		if err := getGrafanaCom(ctx, fmt.Sprintf("/api/dashboards/%d", id), &info); err != nil {
+			return nil, 0, err
+		}
+		revision = info.Revision
+	}
+	var db map[string]any
+	if err := getGrafanaCom(ctx, fmt.Sprintf("/api/dashboards/%d/revisions/%d/download", id, revision), &db); err != nil {
+		return nil, 0, err
+	}
+	return db, revision, nil
+}
+
+// chooseInputDatasource chooses the local datasource of a datasource input:
+// the one given by input name or plugin type, or else the default datasource
+// if it is of the input's type, or else the only datasource of that type.
+func chooseInputDatasource(input communityDashboardInput, chosen map[string]string, datasources models.DataSourceList) (*models.DataSourceListItemDTO, error) {
+	ref, ok := chosen[input.Name]
+	if !ok {
+		ref, ok = chosen[input.PluginID]
+	}
+	if ok {
+		for _, ds := range datasources {
+			if ds.UID == ref || ds.Name == ref {
+				return ds, nil
+			}
+		}
+		return nil, fmt.Errorf("datasource %q for input %s not found", ref, input.Name)
+	}
+
+	var candidates []*models.DataSourceListItemDTO
+	for _, ds := range datasources {
+		if ds.Type != input.PluginID {
+			continue
+		}
+		if ds.IsDefault {
+			return ds, nil
+		}
+		candidates = append(candidates, ds)
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("there is no %s datasource for input %s (%s)", input.PluginID, input.Name, input.Label)
+	case 1:
+		return candidates[0], nil
+	}
+	names := make([]string, len(candidates))
+	for i, ds := range candidates {
+		names[i] = fmt.Sprintf("%s (%s)", ds.Name, ds.UID)
+	}
+	return nil, fmt.Errorf("there are several %s datasources for input %s, choose one with datasources: %s", input.PluginID, input.Name, strings.Join(names, ", "))
+}
+
+// communityDashboardInputs returns the inputs of a dashboard and the values
+// to import it with.
+func communityDashboardInputs(db map[string]any, args ImportCommunityDashboardParams, datasources models.DataSourceList) ([]*models.ImportDashboardInput, []importedDashboardInput, error) {
+	var inputs []communityDashboardInput
+	if raw, ok := db["__inputs"]; ok {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(data, &inputs); err != nil {
+			return nil, nil, fmt.Errorf("the dashboard has invalid __inputs: %w", err)
+		}
+	}
+
+	values := []*models.ImportDashboardInput{}
+	summary := []importedDashboardInput{}
+	for _, input := range inputs {
+		value := importedDashboardInput{Name: input.Name, Type: input.Type}
+		switch input.Type {
+		case "datasource":
+			ds, err := chooseInputDatasource(input, args.Datasources, datasources)
+			if err != nil {
+				return nil, nil, err
+			}
+			value.Value, value.Datasource = ds.UID, ds.Name
+		case "constant":
+			value.Value = input.Value
+			if v, ok := args.Constants[input.Name]; ok {
+				value.Value = v
+			}
+		default:
+			continue
+		}
+		values = append(values, &models.ImportDashboardInput{Name: input.Name, Type: input.Type, PluginID: input.PluginID, Value: value.Value})
+		summary = append(summary, value)
+	}
+	return values, summary, nil
+}
+
+func importCommunityDashboard(ctx context.Context, args ImportCommunityDashboardParams) (*importedCommunityDashboard, error) {
+	if args.ID <= 0 {
+		return nil, fmt.Errorf("id must be a grafana.com dashboard ID")
+	}
+	if args.FolderUID != "" {
+		if err := validatePathSegment("folderUid", args.FolderUID); err != nil {
+			return nil, err
+		}
+	}
+	db, revision, err := downloadCommunityDashboard(ctx, args.ID, args.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("list datasources: %w", err)
+	}
+	inputs, summary, err := communityDashboardInputs(db, args, resp.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := c.Dashboards.ImportDashboard(&models.ImportDashboardRequest{
+		Dashboard: db,
+		FolderUID: args.FolderUID,
+		Inputs:    inputs,
+		Overwrite: args.Overwrite,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import dashboard %d revision %d: %w", args.ID, revision, err)
+	}
+	return &importedCommunityDashboard{
+		UID:       imported.Payload.UID,
+		Title:     imported.Payload.Title,
+		URL:       imported.Payload.ImportedURL,
+		FolderUID: imported.Payload.FolderUID,
+		Revision:  revision,
+		Inputs:    summary,
+	}, nil
+}
+
+var ImportCommunityDashboard = mcpgrafana.MustTool(
+	"import_community_dashboard",
+	"Download a dashboard from grafana.com by its ID, such as 1860 for Node Exporter Full, and import it, connecting its datasource inputs to local datasources. Each datasource input uses the datasource given in `datasources` by input name or plugin type, or else the default datasource of its type, or the only one. Returns the UID, URL and revision of the imported dashboard and the datasource chosen for each input.",
+	importCommunityDashboard,
+	mcp.WithTitleAnnotation("Import community dashboard"),
+)
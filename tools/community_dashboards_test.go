@@ -0,0 +1,116 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const communityTestDashboard = `{
+	"__inputs": [
+		{"name": "DS_PROMETHEUS", "label": "Prometheus", "type": "datasource", "pluginId": "prometheus", "pluginName": "Prometheus"},
+		{"name": "DS_LOKI", "label": "Loki", "type": "datasource", "pluginId": "loki", "pluginName": "Loki"},
+		{"name": "VAR_JOB", "label": "Job", "type": "constant", "value": "node"}
+	],
+	"uid": "rYdddlPWk", "title": "Node Exporter Full",
+	"panels": [{"id": 1, "type": "timeseries", "datasource": {"type": "prometheus", "uid": "${DS_PROMETHEUS}"}}]
+}`
+
+func TestChooseInputDatasource(t *testing.T) {
+	datasources := models.DataSourceList{
+		{UID: "prom", Name: "Prod Prometheus", Type: "prometheus"},
+		{UID: "prom-dev", Name: "Dev Prometheus", Type: "prometheus"},
+		{UID: "loki", Name: "Loki", Type: "loki", IsDefault: true},
+		{UID: "tempo", Name: "Tempo", Type: "tempo"},
+	}
+	prom := communityDashboardInput{Name: "DS_PROMETHEUS", Type: "datasource", PluginID: "prometheus"}
+
+	_, err := chooseInputDatasource(prom, nil, datasources)
+	assert.ErrorContains(t, err, "Prod Prometheus (prom), Dev Prometheus (prom-dev)")
+
+	ds, err := chooseInputDatasource(prom, map[string]string{"DS_PROMETHEUS": "prom-dev"}, datasources)
+	require.NoError(t, err)
+	assert.Equal(t, "prom-dev", ds.UID)
+	ds, err = chooseInputDatasource(prom, map[string]string{"prometheus": "Prod Prometheus"}, datasources)
+	require.NoError(t, err)
+	assert.Equal(t, "prom", ds.UID)
+	_, err = chooseInputDatasource(prom, map[string]string{"prometheus": "missing"}, datasources)
+	assert.ErrorContains(t, err, "not found")
+
+	ds, err = chooseInputDatasource(communityDashboardInput{Name: "DS_LOKI", PluginID: "loki"}, nil, datasources)
+	require.NoError(t, err)
+	assert.Equal(t, "loki", ds.UID)
+	ds, err = chooseInputDatasource(communityDashboardInput{Name: "DS_TEMPO", PluginID: "tempo"}, nil, datasources)
+	require.NoError(t, err)
+	assert.Equal(t, "tempo", ds.UID)
+	_, err = chooseInputDatasource(communityDashboardInput{Name: "DS_INFLUX", PluginID: "influxdb"}, nil, datasources)
+	assert.ErrorContains(t, err, "no influxdb datasource")
+}
+
+func TestImportCommunityDashboard(t *testing.T) {
+	grafanaCom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/dashboards/1860":
+			_, _ = w.Write([]byte(`{"id": 1860, "name": "Node Exporter Full", "revision": 37}`))
+		case "/api/dashboards/1860/revisions/37/download", "/api/dashboards/1860/revisions/30/download":
+			_, _ = w.Write([]byte(communityTestDashboard))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not found"}`))
+		}
+	}))
+	defer grafanaCom.Close()
+	defer func(url string) { grafanaComURL = url }(grafanaComURL)
+	grafanaComURL = grafanaCom.URL
+
+	var imported models.ImportDashboardRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"uid": "prom", "name": "Prometheus", "type": "prometheus", "isDefault": true},
+			{"uid": "loki-1", "name": "Loki", "type": "loki"}
+		]`))
+	})
+	mux.HandleFunc("POST /api/dashboards/import", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&imported))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid": "rYdddlPWk", "title": "Node Exporter Full", "importedUrl": "/d/rYdddlPWk/node-exporter-full", "folderUid": "infra", "imported": true}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := importCommunityDashboard(ctx, ImportCommunityDashboardParams{ID: 1860, FolderUID: "infra", Constants: map[string]string{"VAR_JOB": "node-exporter"}})
+	require.NoError(t, err)
+	assert.Equal(t, &importedCommunityDashboard{
+		UID:       "rYdddlPWk",
+		Title:     "Node Exporter Full",
+		URL:       "/d/rYdddlPWk/node-exporter-full",
+		FolderUID: "infra",
+		Revision:  37,
+		Inputs: []importedDashboardInput{
+			{Name: "DS_PROMETHEUS", Type: "datasource", Value: "prom", Datasource: "Prometheus"},
+			{Name: "DS_LOKI", Type: "datasource", Value: "loki-1", Datasource: "Loki"},
+			{Name: "VAR_JOB", Type: "constant", Value: "node-exporter"},
+		},
+	}, result)
+	assert.Equal(t, "infra", imported.FolderUID)
+	require.Len(t, imported.Inputs, 3)
+	assert.Equal(t, models.ImportDashboardInput{Name: "DS_PROMETHEUS", Type: "datasource", PluginID: "prometheus", Value: "prom"}, *imported.Inputs[0])
+	assert.Equal(t, "Node Exporter Full", imported.Dashboard.(map[string]any)["title"])
+
+	result, err = importCommunityDashboard(ctx, ImportCommunityDashboardParams{ID: 1860, Revision: 30})
+	require.NoError(t, err)
+	assert.Equal(t, 30, result.Revision)
+
+	_, err = importCommunityDashboard(ctx, ImportCommunityDashboardParams{ID: 1, Revision: 1})
+	assert.ErrorContains(t, err, "status 404")
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -12,16 +13,34 @@ import (
 )
 
 type GetDashboardByUIDParams struct {
-	UID string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	UID    string   `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	Fields []string `json:"fields,omitempty" jsonschema:"description=Optionally\\, JSONPath-style paths of the dashboard fields to return instead of the whole dashboard\\, e.g. ['title'\\, 'panels[*].title'\\, 'panels[*].type'\\, 'panels[*].targets[*].expr']. Paths support '.field'\\, '[n]'\\, '[*]' and '..field' for a field at any depth"`
 }
 
 func getDashboardByUID(ctx context.Context, args GetDashboardByUIDParams) (*models.DashboardFullWithMeta, error) {
+	if err := validatePathSegment("uid", args.UID); err != nil {
+		return nil, err
+	}
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	dashboard, err := c.Dashboards.GetDashboardByUID(args.UID)
 	if err != nil {
 		return nil, fmt.Errorf("get dashboard by uid %s: %w", args.UID, err)
 	}
-	return dashboard.Payload, nil
+	if len(args.Fields) == 0 {
+		return dashboard.Payload, nil
+	}
+
+	db, ok := dashboard.Payload.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	projected, err := projectDashboard(db, args.Fields)
+	if err != nil {
+		return nil, err
+	}
+	result := *dashboard.Payload
+	result.Dashboard = projected
+	return &result, nil
 }
 
 type UpdateDashboardParams struct {
@@ -29,13 +48,21 @@ type UpdateDashboardParams struct {
 	FolderUID string                 `json:"folderUid" jsonschema:"optional,description=The UID of the dashboard's folder"`
 	Message   string                 `json:"message" jsonschema:"optional,description=Set a commit message for the version history"`
 	Overwrite bool                   `json:"overwrite" jsonschema:"optional,description=Overwrite the dashboard if it exists. Otherwise create one"`
-	UserID    int64                  `json:"userId" jsonschema:"optional,ID of the user making the change"`
+	UserID    int64                  `json:"userId" jsonschema:"optional,description=ID of the user making the change"`
 }
 
 // updateDashboard can be used to save an existing dashboard, or create a new one.
 // DISCLAIMER: Large-sized dashboard JSON can exhaust context windows. We will
 // implement features that address this in https://github.com/grafana/mcp-grafana/issues/101.
 func updateDashboard(ctx context.Context, args UpdateDashboardParams) (*models.PostDashboardOKBody, error) {
+	if title, _ := args.Dashboard["title"].(string); strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("the dashboard must have a title")
+	}
+	if args.FolderUID != "" {
+		if err := validatePathSegment("folderUid", args.FolderUID); err != nil {
+			return nil, err
+		}
+	}
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	cmd := &models.SaveDashboardCommand{
 		Dashboard: args.Dashboard,
@@ -53,7 +80,7 @@ func updateDashboard(ctx context.Context, args UpdateDashboardParams) (*models.P
 
 var GetDashboardByUID = mcpgrafana.MustTool(
 	"get_dashboard_by_uid",
-	"Retrieves the complete dashboard, including panels, variables, and settings, for a specific dashboard identified by its UID.",
+	"Retrieves the complete dashboard, including panels, variables, and settings, for a specific dashboard identified by its UID. Large dashboards can be trimmed to the fields of interest with `fields`, a list of JSONPath-style paths such as `panels[*].title`, `panels[*].type` and `panels[*].targets[*].expr`; selected fields keep their place in the dashboard, so the fields of a panel stay together, and panels nested in collapsed rows are under `panels[*].panels[*]`.",
 	getDashboardByUID,
 	mcp.WithTitleAnnotation("Get dashboard details"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -62,7 +89,7 @@ var GetDashboardByUID = mcpgrafana.MustTool(
 
 var UpdateDashboard = mcpgrafana.MustTool(
 	"update_dashboard",
-	"Create or update a dashboard",
+	"Create or update a dashboard from its JSON model, in the folder with the UID `folderUid` or the General folder. To create a new dashboard, leave out the `id` and `uid` fields, or set only a `uid` of your choice; to update one, pass the dashboard as returned by get_dashboard_by_uid with your changes, and set `overwrite` to replace it even if it changed since. The `message` is recorded in the dashboard's version history. Returns the UID, URL and version of the saved dashboard.",
 	updateDashboard,
 	mcp.WithTitleAnnotation("Create or update dashboard"),
 	mcp.WithDestructiveHintAnnotation(true),
@@ -79,14 +106,34 @@ type datasourceInfo struct {
 
 type panelQuery struct {
 	Title      string         `json:"title"`
+	PanelID    int            `json:"panelId"`
+	RefID      string         `json:"refId,omitempty"`
 	Query      string         `json:"query"`
+	Format     string         `json:"format,omitempty"`
 	Datasource datasourceInfo `json:"datasource"`
 }
 
+// targetQueryFields are the fields holding the query of a panel target, in
+// order of preference: `expr` for Prometheus and Loki, `query` for Tempo and
+// others, `rawSql` for SQL datasources and `expression` for server-side
+// expressions.
+var targetQueryFields = []string{"expr", "query", "rawSql", "expression"}
+
+// targetQuery returns the query of a panel target, or an empty string if it
+// has none.
+func targetQuery(target map[string]any) string {
+	for _, field := range targetQueryFields {
+		if query, _ := target[field].(string); query != "" {
+			return query
+		}
+	}
+	return ""
+}
+
 func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQueriesParams) ([]panelQuery, error) {
 	result := make([]panelQuery, 0)
 
-	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams(args))
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
 	if err != nil {
 		return result, fmt.Errorf("get dashboard by uid: %w", err)
 	}
@@ -95,56 +142,120 @@ func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQuerie
 	if !ok {
 		return result, fmt.Errorf("dashboard is not a JSON object")
 	}
-	panels, ok := db["panels"].([]any)
-	if !ok {
+	if _, ok := db["panels"].([]any); !ok {
 		return result, fmt.Errorf("panels is not a JSON array")
 	}
 
-	for _, p := range panels {
-		panel, ok := p.(map[string]any)
-		if !ok {
-			continue
+	walkDashboardTargets(db, func(panel, target map[string]any, _ string) {
+		query := targetQuery(target)
+		if query == "" {
+			return
 		}
 		title, _ := panel["title"].(string)
+		id, _ := panel["id"].(float64)
+		refID, _ := target["refId"].(string)
+		format, _ := target["format"].(string)
+		result = append(result, panelQuery{
+			Title:      title,
+			PanelID:    int(id),
+			RefID:      refID,
+			Query:      query,
+			Format:     format,
+			Datasource: targetDatasource(panel, target),
+		})
+	})
+	return result, nil
+}
 
-		var datasourceInfo datasourceInfo
-		if dsField, dsExists := panel["datasource"]; dsExists && dsField != nil {
-			if dsMap, ok := dsField.(map[string]any); ok {
-				if uid, ok := dsMap["uid"].(string); ok {
-					datasourceInfo.UID = uid
-				}
-				if dsType, ok := dsMap["type"].(string); ok {
-					datasourceInfo.Type = dsType
-				}
-			}
-		}
+// dashboardTarget is a single query target of a dashboard panel.
+type dashboardTarget struct {
+	PanelID    int            `json:"panelId"`
+	PanelTitle string         `json:"panelTitle"`
+	RefID      string         `json:"refId,omitempty"`
+	Expr       string         `json:"expr"`
+	Datasource datasourceInfo `json:"datasource"`
+}
 
-		targets, ok := panel["targets"].([]any)
-		if !ok {
-			continue
+// dashboardTargets returns every target with an `expr` in the dashboard,
+// including panels nested in collapsed rows. A target's own datasource takes
+// precedence over its panel's datasource, as with mixed datasource panels.
+func dashboardTargets(db map[string]any) []dashboardTarget {
+	var result []dashboardTarget
+	walkDashboardTargets(db, func(panel, target map[string]any, _ string) {
+		expr, _ := target["expr"].(string)
+		if expr == "" {
+			return
 		}
-		for _, t := range targets {
-			target, ok := t.(map[string]any)
+		title, _ := panel["title"].(string)
+		id, _ := panel["id"].(float64)
+		refID, _ := target["refId"].(string)
+		result = append(result, dashboardTarget{
+			PanelID:    int(id),
+			PanelTitle: title,
+			RefID:      refID,
+			Expr:       expr,
+			Datasource: targetDatasource(panel, target),
+		})
+	})
+	return result
+}
+
+// walkDashboardTargets calls fn for every target of every panel in the
+// dashboard, including panels nested in collapsed rows, along with the JSON
+// path of the target. The panel and target may be modified in place.
+func walkDashboardTargets(db map[string]any, fn func(panel, target map[string]any, path string)) {
+	var walk func(panels []any, path string)
+	walk = func(panels []any, path string) {
+		for i, p := range panels {
+			panel, ok := p.(map[string]any)
 			if !ok {
 				continue
 			}
-			expr, _ := target["expr"].(string)
-			if expr != "" {
-				result = append(result, panelQuery{
-					Title:      title,
-					Query:      expr,
-					Datasource: datasourceInfo,
-				})
+			panelPath := fmt.Sprintf("%s[%d]", path, i)
+			if nested, ok := panel["panels"].([]any); ok {
+				walk(nested, panelPath+".panels")
+			}
+			targets, _ := panel["targets"].([]any)
+			for j, t := range targets {
+				if target, ok := t.(map[string]any); ok {
+					fn(panel, target, fmt.Sprintf("%s.targets[%d]", panelPath, j))
+				}
 			}
 		}
 	}
+	panels, _ := db["panels"].([]any)
+	walk(panels, "panels")
+}
 
-	return result, nil
+// targetDatasource returns the datasource of a panel target. A target's own
+// datasource takes precedence over its panel's datasource.
+func targetDatasource(panel, target map[string]any) datasourceInfo {
+	if ds := parseDatasourceRef(target["datasource"]); ds.UID != "" || ds.Type != "" {
+		return ds
+	}
+	return parseDatasourceRef(panel["datasource"])
+}
+
+// parseDatasourceRef parses a panel or target datasource reference, which is
+// either an object with `uid` and `type` fields or, in older dashboards, a
+// datasource name or template variable. Names are returned as the UID, so
+// callers resolving references must match them against datasource names as
+// well as UIDs.
+func parseDatasourceRef(v any) datasourceInfo {
+	switch ds := v.(type) {
+	case map[string]any:
+		uid, _ := ds["uid"].(string)
+		dsType, _ := ds["type"].(string)
+		return datasourceInfo{UID: uid, Type: dsType}
+	case string:
+		return datasourceInfo{UID: ds}
+	}
+	return datasourceInfo{}
 }
 
 var GetDashboardPanelQueries = mcpgrafana.MustTool(
 	"get_dashboard_panel_queries",
-	"Get the title, query string, and datasource information for each panel in a dashboard. The datasource is an object with fields `uid` (which may be a concrete UID or a template variable like \"$datasource\") and `type`. If the datasource UID is a template variable, it won't be usable directly for queries. Returns an array of objects, each representing a panel, with fields: title, query, and datasource (an object with uid and type).",
+	"Get the title, query string, and datasource information for each query of the panels in a dashboard, including panels in collapsed rows. The datasource is an object with fields `uid` (which may be a concrete UID or a template variable like \"$datasource\") and `type`; a query's own datasource takes precedence over its panel's, as in mixed datasource panels. If the datasource UID is a template variable, it won't be usable directly for queries. The query is the PromQL or LogQL expression, TraceQL query, raw SQL or server-side expression of the panel's target. Returns an array of objects, one per query, with fields: title, panelId, refId, query, format (such as time_series or table, if set), and datasource (an object with uid and type).",
 	GetDashboardPanelQueriesTool,
 	mcp.WithTitleAnnotation("Get dashboard panel queries"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -153,6 +264,37 @@ var GetDashboardPanelQueries = mcpgrafana.MustTool(
 
 func AddDashboardTools(mcp *server.MCPServer) {
 	GetDashboardByUID.Register(mcp)
-	UpdateDashboard.Register(mcp)
 	GetDashboardPanelQueries.Register(mcp)
+	GetDashboardVariableValues.Register(mcp)
+	ListDashboardVersions.Register(mcp)
+	DiffDashboardVersions.Register(mcp)
+	RenderPanelImage.Register(mcp)
+	FindBrokenPanels.Register(mcp)
+	FindMetricUsages.Register(mcp)
+	WatchDashboardChanges.Register(mcp)
+	ListLibraryPanels.Register(mcp)
+	GetLibraryPanel.Register(mcp)
+	FindLibraryPanelUsages.Register(mcp)
+	GetDashboardPermissions.Register(mcp)
+	ExportDashboard.Register(mcp)
+	GenerateDashboard.Register(mcp)
+	LintDashboard.Register(mcp)
+	MigrateDashboardSchema.Register(mcp)
+	QueryPanelData.Register(mcp)
+	GenerateDashboardDeeplink.Register(mcp)
+	GeneratePanelExploreDeeplink.Register(mcp)
+	addExportResources(mcp)
+}
+
+// AddDashboardWriteTools registers dashboard tools which modify Grafana. They
+// are only enabled when the server runs with write tools enabled.
+func AddDashboardWriteTools(mcp *server.MCPServer) {
+	UpdateDashboard.Register(mcp)
+	PatchDashboard.Register(mcp)
+	RenameLabel.Register(mcp)
+	CreateLibraryPanel.Register(mcp)
+	UpdateLibraryPanel.Register(mcp)
+	SetDashboardPermissions.Register(mcp)
+	ImportCommunityDashboard.Register(mcp)
+	ReplaceDashboardDatasource.Register(mcp)
 }
@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type GenerateDashboardDeeplinkParams struct {
+	UID       string         `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	StartTime string         `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to the dashboard's time range"`
+	EndTime   string         `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to the dashboard's time range"`
+	Variables map[string]any `json:"variables,omitempty" jsonschema:"description=Optionally\\, template variable values by name\\, each a value or a list of values for multi-value variables\\, e.g. {'namespace': 'prod'\\, 'pod': ['api-1'\\, 'api-2']}. Use '$__all' to select All"`
+	ViewPanel int            `json:"viewPanel,omitempty" jsonschema:"description=Optionally\\, the ID of a panel to open in view mode"`
+}
+
+// variableLinkValues converts a variable value given to
+// generate_dashboard_deeplink to the values of its URL parameter.
+func variableLinkValues(name string, value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			itemValues, err := variableLinkValues(name, item)
+			if err != nil || len(itemValues) != 1 {
+				return nil, fmt.Errorf("the values of variable %s must be strings", name)
+			}
+			values = append(values, itemValues[0])
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("the value of variable %s must be a string or a list of strings", name)
+}
+
+func generateDashboardDeeplink(ctx context.Context, args GenerateDashboardDeeplinkParams) (string, error) {
+	from, err := grafanaURLTime(args.StartTime, "")
+	if err != nil {
+		return "", fmt.Errorf("parsing start time: %w", err)
+	}
+	to, err := grafanaURLTime(args.EndTime, "")
+	if err != nil {
+		return "", fmt.Errorf("parsing end time: %w", err)
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return "", err
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("dashboard is not a JSON object")
+	}
+
+	params := url.Values{}
+	if from != "" {
+		params.Set("from", from)
+	}
+	if to != "" {
+		params.Set("to", to)
+	}
+	// Grafana ignores unknown variables, so check them to catch typos.
+	variables, names := dashboardVariables(db)
+	for _, name := range sortedKeys(args.Variables) {
+		if _, ok := variables[name]; !ok {
+			return "", fmt.Errorf("the dashboard has no variable %q; its variables are: %s", name, strings.Join(names, ", "))
+		}
+		values, err := variableLinkValues(name, args.Variables[name])
+		if err != nil {
+			return "", err
+		}
+		params["var-"+name] = values
+	}
+	if args.ViewPanel != 0 {
+		if _, err := findDashboardPanel(db, args.ViewPanel); err != nil {
+			return "", err
+		}
+		params.Set("viewPanel", strconv.Itoa(args.ViewPanel))
+	}
+
+	var slug string
+	if dashboard.Meta != nil {
+		slug = dashboard.Meta.Slug
+	}
+	grafanaURL, err := grafanaLinkURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	return dashboardURL(grafanaURL, args.UID, slug, params), nil
+}
+
+var GenerateDashboardDeeplink = mcpgrafana.MustTool(
+	"generate_dashboard_deeplink",
+	"Builds the full Grafana URL of a dashboard with the time range, template variable values and, optionally, a panel in view mode encoded, so the user can open what you looked at with one click. Relative times such as 'now-6h' are kept relative in the link; omitted values use the dashboard's defaults. Variables must exist in the dashboard. Returns the URL.",
+	generateDashboardDeeplink,
+	mcp.WithTitleAnnotation("Generate dashboard deeplink"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// mixedDatasourceUID is the UID of the datasource of Explore panes and
+// panels whose queries use different datasources.
+const mixedDatasourceUID = "-- Mixed --"
+
+type GeneratePanelExploreDeeplinkParams struct {
+	DashboardUID string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelID      int               `json:"panelId" jsonschema:"required,description=The ID of the panel\\, including panels in collapsed rows"`
+	Variables    map[string]string `json:"variables,omitempty" jsonschema:"description=Optionally\\, values to select for template variables by name\\, instead of their saved values\\, e.g. {'namespace': 'prod'}. Use '$__all' to select All"`
+	StartTime    string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to the dashboard's time range"`
+	EndTime      string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to the dashboard's time range"`
+}
+
+type panelExploreQuery struct {
+	RefID      string         `json:"refId"`
+	Datasource datasourceInfo `json:"datasource"`
+	// Query is the query with template variables replaced.
+	Query  string `json:"query"`
+	Hidden bool   `json:"hidden,omitempty"`
+}
+
+type panelExploreLink struct {
+	PanelID    int                 `json:"panelId"`
+	PanelTitle string              `json:"panelTitle"`
+	Queries    []panelExploreQuery `json:"queries"`
+	// SkippedExpressions are the refIds of server-side expressions, which
+	// Explore can't run.
+	SkippedExpressions []string `json:"skippedExpressions,omitempty"`
+	URL                string   `json:"url"`
+}
+
+// exploreRangeTime returns a time of an Explore range: relative times are
+// kept so the link stays relative, others are given in Unix milliseconds.
+func exploreRangeTime(raw string, t time.Time) string {
+	if raw = strings.TrimSpace(raw); strings.HasPrefix(raw, "now") {
+		return raw
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func generatePanelExploreDeeplink(ctx context.Context, args GeneratePanelExploreDeeplinkParams) (*panelExploreLink, error) {
+	q, err := loadPanelQueries(ctx, args.DashboardUID, args.PanelID, args.StartTime, args.EndTime, args.Variables)
+	if err != nil {
+		return nil, err
+	}
+	from, to := dashboardTimeRange(q.db)
+	pane := explorePane{
+		Queries: []map[string]any{},
+		Range: exploreRange{
+			From: exploreRangeTime(stringOrDefault(args.StartTime, from), q.variables.start),
+			To:   exploreRangeTime(stringOrDefault(args.EndTime, to), q.variables.end),
+		},
+	}
+	result := &panelExploreLink{PanelID: args.PanelID, PanelTitle: stringField(q.panel, "title"), Queries: []panelExploreQuery{}}
+	for _, t := range q.targets {
+		target, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		refID := stringField(target, "refId")
+		ds, err := resolveTargetDatasource(q.variables, q.panel, target)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", refID, err)
+		}
+		if ds.UID == expressionDatasourceUID {
+			result.SkippedExpressions = append(result.SkippedExpressions, refID)
+			continue
+		}
+		query := interpolateTarget(q.variables, target).(map[string]any)
+		query["datasource"] = ds
+		pane.Queries = append(pane.Queries, query)
+		hidden, _ := target["hide"].(bool)
+		result.Queries = append(result.Queries, panelExploreQuery{RefID: refID, Datasource: ds, Query: targetQuery(query), Hidden: hidden})
+
+		switch pane.Datasource {
+		case "":
+			pane.Datasource = ds.UID
+		case ds.UID, mixedDatasourceUID:
+		default:
+			pane.Datasource = mixedDatasourceUID
+		}
+	}
+	if len(pane.Queries) == 0 {
+		return nil, fmt.Errorf("panel %d only has server-side expressions, which can't be opened in Explore", args.PanelID)
+	}
+
+	grafanaURL, err := grafanaLinkURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.URL, err = exploreURL(grafanaURL, pane); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+var GeneratePanelExploreDeeplink = mcpgrafana.MustTool(
+	"generate_panel_explore_deeplink",
+	"Convert the queries of a dashboard panel to a Grafana Explore link, to investigate interactively what a panel shows. Template variables in the queries and datasources are replaced by their current values, or by the values given in `variables`, as Explore doesn't know the dashboard's variables. Panels querying several datasources open as a mixed query. Server-side expressions are left out. Returns the panel's queries, with their refId, datasource and interpolated query, and the Explore URL.",
+	generatePanelExploreDeeplink,
+	mcp.WithTitleAnnotation("Generate panel Explore deeplink"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,130 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestGenerateDashboardDeeplink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"meta": {"slug": "api-overview"}, "dashboard": ` + panelDataTestDashboard + `}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	link, err := generateDashboardDeeplink(ctx, GenerateDashboardDeeplinkParams{
+		UID:       "api",
+		StartTime: "2024-01-01T00:00:00Z",
+		EndTime:   "now",
+		Variables: map[string]any{"job": []any{"api", "web"}, "datasource": "prom"},
+		ViewPanel: 2,
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "/d/api/api-overview", u.Path)
+	assert.Equal(t, url.Values{
+		"from":           {"1704067200000"},
+		"to":             {"now"},
+		"var-datasource": {"prom"},
+		"var-job":        {"api", "web"},
+		"viewPanel":      {"2"},
+	}, u.Query())
+
+	link, err = generateDashboardDeeplink(ctx, GenerateDashboardDeeplinkParams{UID: "api"})
+	require.NoError(t, err)
+	assert.NotContains(t, link, "?")
+
+	_, err = generateDashboardDeeplink(ctx, GenerateDashboardDeeplinkParams{UID: "api", Variables: map[string]any{"namespace": "prod"}})
+	assert.ErrorContains(t, err, "its variables are: datasource, job")
+	_, err = generateDashboardDeeplink(ctx, GenerateDashboardDeeplinkParams{UID: "api", ViewPanel: 9})
+	assert.ErrorContains(t, err, "panel 9 not found")
+	_, err = generateDashboardDeeplink(ctx, GenerateDashboardDeeplinkParams{UID: "api", StartTime: "yesterday-ish"})
+	assert.Error(t, err)
+}
+
+func TestGeneratePanelExploreDeeplink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + panelDataTestDashboard + `}`))
+	})
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid": "prom", "name": "Prometheus", "type": "prometheus", "isDefault": true}]`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := generatePanelExploreDeeplink(ctx, GeneratePanelExploreDeeplinkParams{DashboardUID: "api", PanelID: 2, Variables: map[string]string{"job": "web"}})
+	require.NoError(t, err)
+	assert.Equal(t, []panelExploreQuery{{
+		RefID:      "A",
+		Datasource: datasourceInfo{UID: "prom", Type: "prometheus"},
+		Query:      `sum(rate(http_requests_total{job="web"}[$__rate_interval]))`,
+		Hidden:     true,
+	}}, result.Queries)
+	assert.Equal(t, []string{"B"}, result.SkippedExpressions)
+
+	u, err := url.Parse(result.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/explore", u.Path)
+	var panes map[string]explorePane
+	require.NoError(t, json.Unmarshal([]byte(u.Query().Get("panes")), &panes))
+	assert.Equal(t, "prom", panes["a"].Datasource)
+	assert.Equal(t, exploreRange{From: "now-1h", To: "now"}, panes["a"].Range)
+	assert.Equal(t, result.Queries[0].Query, panes["a"].Queries[0]["expr"])
+
+	result, err = generatePanelExploreDeeplink(ctx, GeneratePanelExploreDeeplinkParams{DashboardUID: "api", PanelID: 2, StartTime: "2024-01-01T00:00:00Z", EndTime: "2024-01-01T01:00:00Z"})
+	require.NoError(t, err)
+	u, err = url.Parse(result.URL)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(u.Query().Get("panes")), &panes))
+	assert.Equal(t, exploreRange{From: "1704067200000", To: "1704070800000"}, panes["a"].Range)
+
+	_, err = generatePanelExploreDeeplink(ctx, GeneratePanelExploreDeeplinkParams{DashboardUID: "api", PanelID: 3})
+	assert.ErrorContains(t, err, "no queries")
+}
+
+func TestGrafanaLinkURL(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "grafana.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/frontend/settings", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"appUrl": "https://grafana.example.com/"}`))
+	}))
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://localhost", UnixSocket: socket})
+	link, err := grafanaLinkURL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://grafana.example.com/", link)
+
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, mcpgrafana.GrafanaConfig{URL: "http://localhost", UnixSocket: socket, PublicURL: "https://public.example.com"})
+	link, err = grafanaLinkURL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://public.example.com", link)
+
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, mcpgrafana.GrafanaConfig{URL: "http://grafana:3000"})
+	link, err = grafanaLinkURL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "http://grafana:3000", link)
+}
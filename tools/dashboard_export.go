@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type ExportDashboardParams struct {
+	UID             string `json:"uid" jsonschema:"required,description=The UID of the dashboard to export"`
+	KeepDatasources bool   `json:"keepDatasources,omitempty" jsonschema:"description=Set to true to keep the datasource references of the dashboard\\, for file provisioning to instances with the same datasource UIDs. By default they are replaced by '__inputs' which are filled in on import"`
+	RemoveUID       bool   `json:"removeUid,omitempty" jsonschema:"description=Set to true to also remove the dashboard UID\\, so that each import creates a new dashboard"`
+	AsResource      bool   `json:"asResource,omitempty" jsonschema:"description=Set to true to store the exported dashboard as a JSON file resource and return its URI instead of the dashboard"`
+}
+
+// dashboardInput is a datasource which has to be chosen when importing an
+// exported dashboard, as in Grafana's "Export for sharing externally".
+type dashboardInput struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	PluginID    string `json:"pluginId"`
+	PluginName  string `json:"pluginName"`
+}
+
+type dashboardRequirement struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type exportedDashboard struct {
+	Dashboard map[string]any   `json:"dashboard,omitempty"`
+	Export    *exportSummary   `json:"export,omitempty"`
+	Inputs    []dashboardInput `json:"inputs,omitempty"`
+	// Warnings list datasource references which couldn't be externalized.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// dashboardIdentityFields are the fields of a dashboard which belong to the
+// instance it was exported from.
+var dashboardIdentityFields = []string{"id", "version", "iteration"}
+
+// builtinDatasources are datasources which exist in every Grafana instance.
+var builtinDatasources = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+	"__expr__":        true,
+}
+
+var nonInputNameChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// datasourceInputName returns the input name of a datasource, such as
+// DS_PROD_PROMETHEUS.
+func datasourceInputName(name string) string {
+	return "DS_" + strings.Trim(nonInputNameChars.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
+
+// dashboardExporter replaces the datasource references of a dashboard by
+// inputs.
+type dashboardExporter struct {
+	datasources models.DataSourceList
+	inputs      map[string]dashboardInput
+	warnings    map[string]bool
+}
+
+// datasourceInput returns the input replacing a datasource reference, or
+// false if the reference is kept, as for template variables, built-in
+// datasources and the default datasource.
+func (e *dashboardExporter) datasourceInput(ref any) (dashboardInput, bool) {
+	info := parseDatasourceRef(ref)
+	if info.UID == "" || strings.HasPrefix(info.UID, "$") || builtinDatasources[info.UID] || info.Type == "datasource" {
+		return dashboardInput{}, false
+	}
+	for _, ds := range e.datasources {
+		if ds.UID != info.UID && ds.Name != info.UID {
+			continue
+		}
+		input := dashboardInput{
+			Name:       datasourceInputName(ds.Name),
+			Label:      ds.Name,
+			Type:       "datasource",
+			PluginID:   ds.Type,
+			PluginName: stringOrDefault(ds.TypeName, ds.Type),
+		}
+		e.inputs[input.Name] = input
+		return input, true
+	}
+	e.warnings[fmt.Sprintf("datasource %q not found; its references are kept", info.UID)] = true
+	return dashboardInput{}, false
+}
+
+// externalize replaces datasource references at any depth of v.
+func (e *dashboardExporter) externalize(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if k != "datasource" {
+				e.externalize(child)
+				continue
+			}
+			input, ok := e.datasourceInput(child)
+			if !ok {
+				continue
+			}
+			placeholder := "${" + input.Name + "}"
+			if ref, isObject := child.(map[string]any); isObject {
+				ref["uid"] = placeholder
+			} else {
+				v[k] = placeholder
+			}
+		}
+	case []any:
+		for _, child := range v {
+			e.externalize(child)
+		}
+	}
+}
+
+// requirements lists the Grafana version, datasource plugins and panel
+// plugins an exported dashboard needs.
+func (e *dashboardExporter) requirements(db map[string]any, version string) []dashboardRequirement {
+	requires := []dashboardRequirement{{Type: "grafana", ID: "grafana", Name: "Grafana", Version: version}}
+	plugins := map[string]dashboardRequirement{}
+	for _, input := range e.inputs {
+		plugins["datasource/"+input.PluginID] = dashboardRequirement{Type: "datasource", ID: input.PluginID, Name: input.PluginName}
+	}
+	var walk func(panels []any)
+	walk = func(panels []any) {
+		for _, p := range panels {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			if panelType := stringField(panel, "type"); panelType != "" && panelType != "row" {
+				plugins["panel/"+panelType] = dashboardRequirement{Type: "panel", ID: panelType, Name: panelType}
+			}
+			nested, _ := panel["panels"].([]any)
+			walk(nested)
+		}
+	}
+	panels, _ := db["panels"].([]any)
+	walk(panels)
+	for _, k := range sortedKeys(plugins) {
+		requires = append(requires, plugins[k])
+	}
+	return requires
+}
+
+func exportDashboard(ctx context.Context, args ExportDashboardParams) (*exportedDashboard, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	original, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	db, err := copyDashboard(original)
+	if err != nil {
+		return nil, fmt.Errorf("copy dashboard: %w", err)
+	}
+	for _, field := range dashboardIdentityFields {
+		delete(db, field)
+	}
+	if args.RemoveUID {
+		delete(db, "uid")
+	}
+
+	result := &exportedDashboard{}
+	if !args.KeepDatasources {
+		resp, err := mcpgrafana.GrafanaClientFromContext(ctx).Datasources.GetDataSources()
+		if err != nil {
+			return nil, fmt.Errorf("list datasources: %w", err)
+		}
+		e := &dashboardExporter{datasources: resp.Payload, inputs: map[string]dashboardInput{}, warnings: map[string]bool{}}
+		e.externalize(db)
+		inputs := make([]dashboardInput, 0, len(e.inputs))
+		for _, name := range sortedKeys(e.inputs) {
+			inputs = append(inputs, e.inputs[name])
+		}
+		result.Inputs = inputs
+		result.Warnings = sortedKeys(e.warnings)
+
+		version := ""
+		if api := grafanaAPIFromContext(ctx); api.known {
+			version = fmt.Sprintf("%d.%d.%d", api.version.Major, api.version.Minor, api.version.Patch)
+		}
+		db["__inputs"] = inputs
+		db["__requires"] = e.requirements(db, version)
+	}
+
+	if !args.AsResource {
+		result.Dashboard = db
+		return result, nil
+	}
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding dashboard: %w", err)
+	}
+	if result.Export, err = exports.add(ctx, "json", "application/json", data, 1); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+var ExportDashboard = mcpgrafana.MustTool(
+	"export_dashboard",
+	"Export a dashboard as JSON ready to commit to version control, for provisioning or import into another Grafana instance. Instance-specific fields (`id`, `version`) are removed, and datasource references are replaced by `${DS_...}` placeholders declared in `__inputs`, with the plugins the dashboard needs in `__requires`, as in Grafana's 'Export for sharing externally'. Set `keepDatasources` for file provisioning to instances with the same datasource UIDs, and `asResource` to get the dashboard as a JSON file resource rather than in the result.",
+	exportDashboard,
+	mcp.WithTitleAnnotation("Export dashboard"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
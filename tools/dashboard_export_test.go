@@ -0,0 +1,119 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportTestDashboard = `{
+	"id": 12, "uid": "svc", "title": "Service", "version": 7,
+	"templating": {"list": [
+		{"name": "ds", "type": "datasource", "query": "prometheus"},
+		{"name": "job", "type": "query", "datasource": {"type": "prometheus", "uid": "prom"}, "query": "label_values(job)"}
+	]},
+	"annotations": {"list": [{"name": "Annotations & Alerts", "datasource": {"type": "grafana", "uid": "-- Grafana --"}}]},
+	"panels": [
+		{"id": 1, "type": "timeseries", "datasource": {"type": "prometheus", "uid": "prom"},
+			"targets": [{"refId": "A", "expr": "up"}, {"refId": "B", "datasource": {"type": "loki", "uid": "gone"}}]},
+		{"id": 2, "type": "row", "collapsed": true, "panels": [
+			{"id": 3, "type": "logs", "datasource": "Logs"},
+			{"id": 4, "type": "stat", "datasource": {"uid": "$ds"}}
+		]}
+	]
+}`
+
+func TestExportDashboard(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + exportTestDashboard + `}`))
+	})
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"uid": "prom", "name": "Prod Prometheus", "type": "prometheus", "typeName": "Prometheus"},
+			{"uid": "logs", "name": "Logs", "type": "loki", "typeName": "Loki"}
+		]`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.2.0", mux)
+
+	result, err := exportDashboard(ctx, ExportDashboardParams{UID: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []dashboardInput{
+		{Name: "DS_LOGS", Label: "Logs", Type: "datasource", PluginID: "loki", PluginName: "Loki"},
+		{Name: "DS_PROD_PROMETHEUS", Label: "Prod Prometheus", Type: "datasource", PluginID: "prometheus", PluginName: "Prometheus"},
+	}, result.Inputs)
+	assert.Equal(t, []string{`datasource "gone" not found; its references are kept`}, result.Warnings)
+
+	// Round trip through JSON to compare with the expected dashboard.
+	data, err := json.Marshal(result.Dashboard)
+	require.NoError(t, err)
+	var db map[string]any
+	require.NoError(t, json.Unmarshal(data, &db))
+	assert.NotContains(t, db, "id")
+	assert.NotContains(t, db, "version")
+	assert.Equal(t, "svc", db["uid"])
+
+	panels := db["panels"].([]any)
+	first := panels[0].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "prometheus", "uid": "${DS_PROD_PROMETHEUS}"}, first["datasource"])
+	targets := first["targets"].([]any)
+	assert.Equal(t, map[string]any{"type": "loki", "uid": "gone"}, targets[1].(map[string]any)["datasource"])
+	nested := panels[1].(map[string]any)["panels"].([]any)
+	assert.Equal(t, "${DS_LOGS}", nested[0].(map[string]any)["datasource"])
+	assert.Equal(t, map[string]any{"uid": "$ds"}, nested[1].(map[string]any)["datasource"])
+
+	variables := db["templating"].(map[string]any)["list"].([]any)
+	assert.Equal(t, "${DS_PROD_PROMETHEUS}", variables[1].(map[string]any)["datasource"].(map[string]any)["uid"])
+	annotations := db["annotations"].(map[string]any)["list"].([]any)
+	assert.Equal(t, "-- Grafana --", annotations[0].(map[string]any)["datasource"].(map[string]any)["uid"])
+
+	var requires []dashboardRequirement
+	data, _ = json.Marshal(db["__requires"])
+	require.NoError(t, json.Unmarshal(data, &requires))
+	assert.Equal(t, []dashboardRequirement{
+		{Type: "grafana", ID: "grafana", Name: "Grafana", Version: "11.2.0"},
+		{Type: "datasource", ID: "loki", Name: "Loki"},
+		{Type: "datasource", ID: "prometheus", Name: "Prometheus"},
+		{Type: "panel", ID: "logs", Name: "logs"},
+		{Type: "panel", ID: "stat", Name: "stat"},
+		{Type: "panel", ID: "timeseries", Name: "timeseries"},
+	}, requires)
+}
+
+func TestExportDashboardAsResource(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + exportTestDashboard + `}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.2.0", mux)
+
+	result, err := exportDashboard(ctx, ExportDashboardParams{UID: "svc", KeepDatasources: true, RemoveUID: true, AsResource: true})
+	require.NoError(t, err)
+	assert.Nil(t, result.Dashboard)
+	require.NotNil(t, result.Export)
+	assert.Equal(t, "application/json", result.Export.MIMEType)
+
+	contents, err := readExport(ctx, mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{URI: result.Export.ResourceURI}})
+	require.NoError(t, err)
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &db))
+	assert.Equal(t, "Service", db["title"])
+	assert.NotContains(t, db, "uid")
+	assert.NotContains(t, db, "__inputs")
+	assert.Equal(t, map[string]any{"type": "prometheus", "uid": "prom"}, db["panels"].([]any)[0].(map[string]any)["datasource"])
+
+	// Exports can only be read with the same credentials.
+	_, err = readExport(context.Background(), mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{URI: result.Export.ResourceURI}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,369 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// dashboardGridWidth is the number of columns of the dashboard grid.
+	dashboardGridWidth = 24
+	// generatedSchemaVersion is the dashboard schema version of generated
+	// dashboards, which Grafana migrates on load if needed.
+	generatedSchemaVersion = 39
+)
+
+type GeneratedPanel struct {
+	Title       string `json:"title" jsonschema:"required,description=The title of the panel"`
+	Query       string `json:"query" jsonschema:"required,description=The PromQL\\, LogQL or TraceQL query of the panel"`
+	Datasource  string `json:"datasource" jsonschema:"required,enum=prometheus,enum=loki,enum=tempo,description=The type of datasource the query is for: 'prometheus' for PromQL\\, 'loki' for LogQL or 'tempo' for TraceQL"`
+	Type        string `json:"type,omitempty" jsonschema:"enum=timeseries,enum=stat,enum=gauge,enum=bargauge,enum=table,enum=logs,enum=heatmap,description=Optionally\\, the panel type. Defaults to 'timeseries' for PromQL and LogQL metric queries\\, 'logs' for LogQL log queries and 'table' for TraceQL"`
+	Row         string `json:"row,omitempty" jsonschema:"description=Optionally\\, the title of the row to put the panel in. Panels of the same row are grouped under it"`
+	Description string `json:"description,omitempty" jsonschema:"description=Optionally\\, the description of the panel"`
+	Unit        string `json:"unit,omitempty" jsonschema:"description=Optionally\\, the unit of the values\\, such as 'percent'\\, 'percentunit'\\, 'bytes'\\, 's'\\, 'ms'\\, 'reqps' or 'short'"`
+	Legend      string `json:"legend,omitempty" jsonschema:"description=Optionally\\, the legend format of the series\\, such as '{{instance}}'"`
+	Width       int    `json:"width,omitempty" jsonschema:"description=Optionally\\, the width of the panel in grid columns (1-24). Defaults depend on the panel type"`
+	Height      int    `json:"height,omitempty" jsonschema:"description=Optionally\\, the height of the panel in grid rows. Defaults depend on the panel type"`
+}
+
+type GenerateDashboardParams struct {
+	Title       string            `json:"title" jsonschema:"required,description=The title of the dashboard"`
+	Panels      []GeneratedPanel  `json:"panels" jsonschema:"required,description=The panels of the dashboard\\, in order"`
+	UID         string            `json:"uid,omitempty" jsonschema:"description=Optionally\\, the UID of the dashboard. Generated by Grafana on save if left out"`
+	Description string            `json:"description,omitempty" jsonschema:"description=Optionally\\, the description of the dashboard"`
+	Tags        []string          `json:"tags,omitempty" jsonschema:"description=Optionally\\, the tags of the dashboard"`
+	Datasources map[string]string `json:"datasources,omitempty" jsonschema:"description=Optionally\\, the UIDs of the datasources selected by default\\, by datasource type\\, e.g. {'prometheus': 'prom-uid'}. Otherwise Grafana selects the default datasource of each type"`
+	From        string            `json:"from,omitempty" jsonschema:"description=Optionally\\, the start of the default time range (default: now-6h)"`
+	Refresh     string            `json:"refresh,omitempty" jsonschema:"description=Optionally\\, the refresh interval (default: 1m)"`
+}
+
+// GenerateAndSaveDashboardParams are the parameters of generate_dashboard
+// when write tools are enabled.
+type GenerateAndSaveDashboardParams struct {
+	GenerateDashboardParams
+	Save      bool   `json:"save,omitempty" jsonschema:"description=Set to true to save the dashboard in Grafana rather than only returning it"`
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder to save the dashboard in. Defaults to the General folder"`
+	Message   string `json:"message,omitempty" jsonschema:"description=Optionally\\, the message recorded in the dashboard's version history"`
+}
+
+type generatedDashboard struct {
+	Dashboard map[string]any `json:"dashboard"`
+	// The fields below are set if the dashboard was saved.
+	UID     string `json:"uid,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// defaultPanelSizes are the default widths and heights of panel types.
+var defaultPanelSizes = map[string][2]int{
+	"timeseries": {12, 8},
+	"stat":       {6, 4},
+	"gauge":      {6, 6},
+	"bargauge":   {12, 8},
+	"table":      {24, 8},
+	"logs":       {24, 10},
+	"heatmap":    {12, 8},
+}
+
+// defaultPanelOptions returns the options of a new panel of a type.
+func defaultPanelOptions(panelType string) map[string]any {
+	reduce := map[string]any{"calcs": []any{"lastNotNull"}, "fields": "", "values": false}
+	switch panelType {
+	case "timeseries":
+		return map[string]any{
+			"legend":  map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": true},
+			"tooltip": map[string]any{"mode": "multi", "sort": "desc"},
+		}
+	case "stat":
+		return map[string]any{"reduceOptions": reduce, "colorMode": "value", "graphMode": "area", "textMode": "auto"}
+	case "gauge":
+		return map[string]any{"reduceOptions": reduce, "showThresholdLabels": false, "showThresholdMarkers": true}
+	case "bargauge":
+		return map[string]any{"reduceOptions": reduce, "displayMode": "gradient", "orientation": "horizontal"}
+	case "table":
+		return map[string]any{"showHeader": true, "cellHeight": "sm"}
+	case "logs":
+		return map[string]any{"showTime": true, "wrapLogMessage": true, "sortOrder": "Descending", "enableLogDetails": true}
+	case "heatmap":
+		return map[string]any{"calculate": false, "yAxis": map[string]any{"axisPlacement": "left"}}
+	}
+	return map[string]any{}
+}
+
+// isLogQLLogQuery reports whether a LogQL query returns log lines rather
+// than a metric, which is the case if it starts with a stream selector.
+func isLogQLLogQuery(query string) bool {
+	return strings.HasPrefix(strings.TrimSpace(query), "{")
+}
+
+// generatedPanelType returns the type of a generated panel.
+func generatedPanelType(p GeneratedPanel) (string, error) {
+	if p.Type != "" {
+		if _, ok := defaultPanelSizes[p.Type]; !ok {
+			return "", fmt.Errorf("panel %q: unsupported panel type %q", p.Title, p.Type)
+		}
+		return p.Type, nil
+	}
+	switch {
+	case p.Datasource == "tempo":
+		return "table", nil
+	case p.Datasource == "loki" && isLogQLLogQuery(p.Query):
+		return "logs", nil
+	}
+	return "timeseries", nil
+}
+
+// datasourceVariableName returns the name of the template variable selecting
+// the datasource of a type.
+func datasourceVariableName(dsType string) string {
+	return dsType + "_datasource"
+}
+
+// generatedTarget returns the query target of a generated panel.
+func generatedTarget(p GeneratedPanel, panelType string, datasource map[string]any) map[string]any {
+	target := map[string]any{"refId": "A", "datasource": datasource}
+	switch p.Datasource {
+	case "prometheus":
+		target["expr"] = p.Query
+		target["range"] = true
+		if panelType == "table" {
+			target["range"], target["instant"], target["format"] = false, true, "table"
+		}
+		if panelType == "heatmap" {
+			target["format"] = "heatmap"
+		}
+	case "loki":
+		target["expr"] = p.Query
+		target["queryType"] = "range"
+	case "tempo":
+		target["query"] = p.Query
+		target["queryType"] = "traceql"
+		target["tableType"] = "traces"
+		target["limit"] = 20
+	}
+	if p.Legend != "" {
+		target["legendFormat"] = p.Legend
+	}
+	return target
+}
+
+// dashboardLayout places panels on the dashboard grid from left to right,
+// starting a new line when a panel doesn't fit.
+type dashboardLayout struct {
+	x, y, lineHeight int
+}
+
+func (l *dashboardLayout) place(w, h int) map[string]any {
+	if l.x+w > dashboardGridWidth {
+		l.x, l.y, l.lineHeight = 0, l.y+l.lineHeight, 0
+	}
+	pos := map[string]any{"x": l.x, "y": l.y, "w": w, "h": h}
+	l.x += w
+	l.lineHeight = max(l.lineHeight, h)
+	return pos
+}
+
+// newLine moves to the start of the next free line.
+func (l *dashboardLayout) newLine() {
+	if l.x > 0 {
+		l.x, l.y, l.lineHeight = 0, l.y+l.lineHeight, 0
+	}
+}
+
+// buildDashboard builds the JSON model of a dashboard from queries. Panels
+// without a row come first, followed by the rows in the order they first
+// appear, each with its panels.
+func buildDashboard(args GenerateDashboardParams) (map[string]any, error) {
+	if strings.TrimSpace(args.Title) == "" {
+		return nil, fmt.Errorf("the dashboard must have a title")
+	}
+	if len(args.Panels) == 0 {
+		return nil, fmt.Errorf("at least one panel is required")
+	}
+	if args.UID != "" {
+		if err := validatePathSegment("uid", args.UID); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []string
+	byRow := map[string][]GeneratedPanel{}
+	var dsTypes []string
+	seenTypes := map[string]bool{}
+	for _, p := range args.Panels {
+		if strings.TrimSpace(p.Title) == "" || strings.TrimSpace(p.Query) == "" {
+			return nil, fmt.Errorf("each panel needs a title and a query")
+		}
+		switch p.Datasource {
+		case "prometheus", "loki", "tempo":
+		default:
+			return nil, fmt.Errorf("panel %q: unsupported datasource %q, expected 'prometheus', 'loki' or 'tempo'", p.Title, p.Datasource)
+		}
+		if _, ok := byRow[p.Row]; !ok && p.Row != "" {
+			rows = append(rows, p.Row)
+		}
+		byRow[p.Row] = append(byRow[p.Row], p)
+		if !seenTypes[p.Datasource] {
+			seenTypes[p.Datasource] = true
+			dsTypes = append(dsTypes, p.Datasource)
+		}
+	}
+
+	id := 0
+	layout := &dashboardLayout{}
+	panels := []any{}
+	addPanels := func(ps []GeneratedPanel) error {
+		for _, p := range ps {
+			panelType, err := generatedPanelType(p)
+			if err != nil {
+				return err
+			}
+			size := defaultPanelSizes[panelType]
+			if p.Width > 0 {
+				size[0] = min(p.Width, dashboardGridWidth)
+			}
+			if p.Height > 0 {
+				size[1] = p.Height
+			}
+			datasource := map[string]any{"type": p.Datasource, "uid": "${" + datasourceVariableName(p.Datasource) + "}"}
+			fieldDefaults := map[string]any{}
+			if p.Unit != "" {
+				fieldDefaults["unit"] = p.Unit
+			}
+			id++
+			panel := map[string]any{
+				"id":          id,
+				"type":        panelType,
+				"title":       p.Title,
+				"datasource":  datasource,
+				"gridPos":     layout.place(size[0], size[1]),
+				"targets":     []any{generatedTarget(p, panelType, datasource)},
+				"fieldConfig": map[string]any{"defaults": fieldDefaults, "overrides": []any{}},
+				"options":     defaultPanelOptions(panelType),
+			}
+			if p.Description != "" {
+				panel["description"] = p.Description
+			}
+			panels = append(panels, panel)
+		}
+		return nil
+	}
+	if err := addPanels(byRow[""]); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		layout.newLine()
+		id++
+		panels = append(panels, map[string]any{
+			"id":        id,
+			"type":      "row",
+			"title":     row,
+			"collapsed": false,
+			"gridPos":   layout.place(dashboardGridWidth, 1),
+			"panels":    []any{},
+		})
+		layout.newLine()
+		if err := addPanels(byRow[row]); err != nil {
+			return nil, err
+		}
+	}
+
+	variables := []any{}
+	for _, dsType := range dsTypes {
+		variable := map[string]any{
+			"name":    datasourceVariableName(dsType),
+			"label":   strings.ToUpper(dsType[:1]) + dsType[1:],
+			"type":    "datasource",
+			"query":   dsType,
+			"refresh": 1,
+			"hide":    0,
+		}
+		if uid := args.Datasources[dsType]; uid != "" {
+			variable["current"] = map[string]any{"text": uid, "value": uid}
+		}
+		variables = append(variables, variable)
+	}
+
+	db := map[string]any{
+		"title":         args.Title,
+		"tags":          args.Tags,
+		"editable":      true,
+		"graphTooltip":  1,
+		"schemaVersion": generatedSchemaVersion,
+		"time":          map[string]any{"from": stringOrDefault(args.From, "now-6h"), "to": "now"},
+		"refresh":       stringOrDefault(args.Refresh, "1m"),
+		"timezone":      "browser",
+		"templating":    map[string]any{"list": variables},
+		"annotations": map[string]any{"list": []any{map[string]any{
+			"builtIn":    1,
+			"datasource": map[string]any{"type": "grafana", "uid": "-- Grafana --"},
+			"enable":     true,
+			"hide":       true,
+			"iconColor":  "rgba(0, 211, 255, 1)",
+			"name":       "Annotations & Alerts",
+			"type":       "dashboard",
+		}}},
+		"panels": panels,
+		"links":  []any{},
+	}
+	if args.Tags == nil {
+		db["tags"] = []string{}
+	}
+	if args.UID != "" {
+		db["uid"] = args.UID
+	}
+	if args.Description != "" {
+		db["description"] = args.Description
+	}
+	return db, nil
+}
+
+func generateDashboard(ctx context.Context, args GenerateDashboardParams) (*generatedDashboard, error) {
+	db, err := buildDashboard(args)
+	if err != nil {
+		return nil, err
+	}
+	return &generatedDashboard{Dashboard: db}, nil
+}
+
+func generateAndSaveDashboard(ctx context.Context, args GenerateAndSaveDashboardParams) (*generatedDashboard, error) {
+	if args.Save && !mcpgrafana.GrafanaConfigFromContext(ctx).WriteToolsEnabled {
+		return nil, fmt.Errorf("saving the dashboard modifies Grafana, which requires the server to run with --enable-write-tools")
+	}
+	result, err := generateDashboard(ctx, args.GenerateDashboardParams)
+	if err != nil || !args.Save {
+		return result, err
+	}
+	saved, err := updateDashboard(ctx, UpdateDashboardParams{
+		Dashboard: result.Dashboard,
+		FolderUID: args.FolderUID,
+		Message:   args.Message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if saved.UID != nil {
+		result.UID = *saved.UID
+	}
+	if saved.URL != nil {
+		result.URL = *saved.URL
+	}
+	if saved.Version != nil {
+		result.Version = *saved.Version
+	}
+	return result, nil
+}
+
+const generateDashboardDescription = "Generate a dashboard from a list of queries, without writing dashboard JSON by hand. Each panel has a title, a PromQL, LogQL or TraceQL query and its datasource type, and optionally a panel type, a row, a unit and a legend format. The dashboard gets a datasource template variable per datasource type, rows grouping the panels, a grid layout and sensible panel defaults."
+
+var GenerateDashboard = mcpgrafana.MustTool(
+	"generate_dashboard",
+	generateDashboardDescription+" Returns the dashboard JSON, and saves it in the folder `folderUid` if `save` is set, which requires the server to run with write tools enabled.",
+	generateAndSaveDashboard,
+	mcp.WithTitleAnnotation("Generate dashboard"),
+)
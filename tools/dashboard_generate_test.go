@@ -0,0 +1,118 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestBuildDashboard(t *testing.T) {
+	db, err := buildDashboard(GenerateDashboardParams{
+		Title:       "Checkout",
+		Tags:        []string{"generated"},
+		Datasources: map[string]string{"prometheus": "prom"},
+		Panels: []GeneratedPanel{
+			{Title: "Request rate", Datasource: "prometheus", Query: `sum(rate(http_requests_total[5m])) by (code)`, Legend: "{{code}}", Unit: "reqps"},
+			{Title: "Errors", Datasource: "prometheus", Query: `sum(rate(http_requests_total{code=~"5.."}[5m]))`, Type: "stat"},
+			{Title: "Error logs", Datasource: "loki", Query: `{app="checkout"} |= "error"`, Row: "Logs"},
+			{Title: "Log rate", Datasource: "loki", Query: `sum(rate({app="checkout"}[5m]))`, Row: "Logs", Width: 30},
+			{Title: "Slow traces", Datasource: "tempo", Query: `{duration > 1s}`, Row: "Traces"},
+			{Title: "Latency", Datasource: "prometheus", Query: `histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[5m])) by (le))`, Unit: "s"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Checkout", db["title"])
+	assert.Equal(t, []string{"generated"}, db["tags"])
+	assert.NotContains(t, db, "uid")
+
+	type gridPos struct{ X, Y, W, H int }
+	var layout []string
+	var positions []gridPos
+	for _, p := range db["panels"].([]any) {
+		panel := p.(map[string]any)
+		layout = append(layout, panel["type"].(string)+":"+panel["title"].(string))
+		pos := panel["gridPos"].(map[string]any)
+		positions = append(positions, gridPos{pos["x"].(int), pos["y"].(int), pos["w"].(int), pos["h"].(int)})
+	}
+	// Panels without a row come first, then each row with its panels.
+	assert.Equal(t, []string{
+		"timeseries:Request rate", "stat:Errors", "timeseries:Latency",
+		"row:Logs", "logs:Error logs", "timeseries:Log rate",
+		"row:Traces", "table:Slow traces",
+	}, layout)
+	assert.Equal(t, []gridPos{
+		{0, 0, 12, 8}, {12, 0, 6, 4}, {0, 8, 12, 8},
+		{0, 16, 24, 1}, {0, 17, 24, 10}, {0, 27, 24, 8},
+		{0, 35, 24, 1}, {0, 36, 24, 8},
+	}, positions)
+
+	first := db["panels"].([]any)[0].(map[string]any)
+	ds := map[string]any{"type": "prometheus", "uid": "${prometheus_datasource}"}
+	assert.Equal(t, ds, first["datasource"])
+	assert.Equal(t, []any{map[string]any{
+		"refId": "A", "datasource": ds, "expr": `sum(rate(http_requests_total[5m])) by (code)`, "range": true, "legendFormat": "{{code}}",
+	}}, first["targets"])
+	assert.Equal(t, map[string]any{"unit": "reqps"}, first["fieldConfig"].(map[string]any)["defaults"])
+
+	variables := db["templating"].(map[string]any)["list"].([]any)
+	require.Len(t, variables, 3)
+	assert.Equal(t, "prometheus_datasource", variables[0].(map[string]any)["name"])
+	assert.Equal(t, map[string]any{"text": "prom", "value": "prom"}, variables[0].(map[string]any)["current"])
+	assert.Equal(t, "loki", variables[1].(map[string]any)["query"])
+	assert.NotContains(t, variables[2].(map[string]any), "current")
+
+	// The dashboard is valid JSON.
+	_, err = json.Marshal(db)
+	require.NoError(t, err)
+
+	for _, args := range []GenerateDashboardParams{
+		{Panels: []GeneratedPanel{{Title: "A", Datasource: "prometheus", Query: "up"}}},
+		{Title: "No panels"},
+		{Title: "Bad", Panels: []GeneratedPanel{{Title: "A", Datasource: "graphite", Query: "up"}}},
+		{Title: "Bad", Panels: []GeneratedPanel{{Title: "A", Datasource: "prometheus", Query: "up", Type: "graph"}}},
+		{Title: "Bad", Panels: []GeneratedPanel{{Title: "A", Datasource: "prometheus"}}},
+	} {
+		_, err := buildDashboard(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestGenerateAndSaveDashboard(t *testing.T) {
+	var saved map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 5, "uid": "gen", "url": "/d/gen/checkout", "version": 1, "status": "success"}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+	params := GenerateDashboardParams{Title: "Checkout", Panels: []GeneratedPanel{{Title: "Up", Datasource: "prometheus", Query: "up"}}}
+
+	result, err := generateAndSaveDashboard(ctx, GenerateAndSaveDashboardParams{GenerateDashboardParams: params})
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+	assert.Empty(t, result.UID)
+
+	_, err = generateAndSaveDashboard(ctx, GenerateAndSaveDashboardParams{GenerateDashboardParams: params, Save: true})
+	assert.ErrorContains(t, err, "--enable-write-tools")
+	assert.Nil(t, saved)
+
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	cfg.WriteToolsEnabled = true
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, cfg)
+	result, err = generateAndSaveDashboard(ctx, GenerateAndSaveDashboardParams{GenerateDashboardParams: params, Save: true, FolderUID: "ops"})
+	require.NoError(t, err)
+	assert.Equal(t, "gen", result.UID)
+	assert.Equal(t, "/d/gen/checkout", result.URL)
+	assert.Equal(t, int64(1), result.Version)
+	assert.Equal(t, "ops", saved["folderUid"])
+	assert.Equal(t, "Checkout", saved["dashboard"].(map[string]any)["title"])
+}
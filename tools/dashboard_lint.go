@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// DefaultMaxPanelQueries is the number of queries a panel may have before
+// lint_dashboard suggests splitting it.
+const DefaultMaxPanelQueries = 6
+
+// Dashboard lint rules.
+const (
+	lintPanelUnits          = "panel-units"
+	lintRateInterval        = "target-rate-interval"
+	lintPanelDatasource     = "panel-datasource"
+	lintDeprecatedPanelType = "panel-deprecated-type"
+	lintPanelQueries        = "panel-too-many-queries"
+)
+
+type LintDashboardParams struct {
+	UID             string         `json:"uid,omitempty" jsonschema:"description=The UID of the dashboard to lint. Either 'uid' or 'dashboard' is required"`
+	Dashboard       map[string]any `json:"dashboard,omitempty" jsonschema:"description=The dashboard JSON to lint\\, such as a dashboard which hasn't been saved yet"`
+	MaxPanelQueries int            `json:"maxPanelQueries,omitempty" jsonschema:"description=Optionally\\, the number of queries a panel may have before it's reported (default: 6)"`
+}
+
+type dashboardLintFinding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	PanelID    int    `json:"panelId,omitempty"`
+	PanelTitle string `json:"panelTitle,omitempty"`
+	RefID      string `json:"refId,omitempty"`
+	Message    string `json:"message"`
+}
+
+type dashboardLintResult struct {
+	UID      string                 `json:"uid,omitempty"`
+	Title    string                 `json:"title"`
+	Findings []dashboardLintFinding `json:"findings"`
+	// Rules counts the findings of each rule.
+	Rules map[string]int `json:"rules,omitempty"`
+}
+
+// deprecatedPanelTypes maps deprecated panel types, most of them Angular
+// plugins which Grafana no longer loads, to their replacements.
+var deprecatedPanelTypes = map[string]string{
+	"graph":                    "timeseries",
+	"singlestat":               "stat",
+	"table-old":                "table",
+	"grafana-piechart-panel":   "piechart",
+	"grafana-worldmap-panel":   "geomap",
+	"grafana-singlestat-panel": "stat",
+	"natel-discrete-panel":     "state-timeline",
+}
+
+// unitPanelTypes are the panel types showing values which should have a
+// unit.
+var unitPanelTypes = map[string]bool{
+	"timeseries": true,
+	"stat":       true,
+	"gauge":      true,
+	"bargauge":   true,
+	"barchart":   true,
+}
+
+// rateFunctions are the PromQL functions whose range should adapt to the
+// scrape interval.
+var rateFunctions = map[string]bool{"rate": true, "irate": true, "increase": true}
+
+// fixedRangeRegex matches literal PromQL range durations such as [5m].
+var fixedRangeRegex = regexp.MustCompile(`\[\s*((?:\d+(?:ms|[smhdwy]))+)\s*\]`)
+
+// panelHasUnit reports whether a panel sets a unit for all or some fields.
+func panelHasUnit(panel map[string]any) bool {
+	fieldConfig, _ := panel["fieldConfig"].(map[string]any)
+	defaults, _ := fieldConfig["defaults"].(map[string]any)
+	if stringField(defaults, "unit") != "" {
+		return true
+	}
+	overrides, _ := fieldConfig["overrides"].([]any)
+	for _, o := range overrides {
+		override, _ := o.(map[string]any)
+		properties, _ := override["properties"].([]any)
+		for _, p := range properties {
+			if property, _ := p.(map[string]any); stringField(property, "id") == "unit" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isConcreteDatasource reports whether a datasource reference names a
+// specific datasource rather than a template variable, the default or a
+// built-in datasource.
+func isConcreteDatasource(ds datasourceInfo) bool {
+	return ds.UID != "" && !strings.Contains(ds.UID, "$") && !builtinDatasources[ds.UID] && ds.Type != "datasource"
+}
+
+// fixedRateRanges returns the literal ranges used by rate functions in a
+// PromQL expression. Expressions which don't parse are skipped, as are
+// ranges given by variables such as $__rate_interval.
+func fixedRateRanges(expr string) []string {
+	parsed, err := parsePromQL(expr)
+	if err != nil {
+		return nil
+	}
+	usesRate := false
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		if call, ok := node.(*parser.Call); ok && rateFunctions[call.Func.Name] {
+			usesRate = true
+		}
+		return nil
+	})
+	if !usesRate {
+		return nil
+	}
+	var ranges []string
+	for _, m := range fixedRangeRegex.FindAllStringSubmatch(expr, -1) {
+		ranges = append(ranges, "["+m[1]+"]")
+	}
+	return ranges
+}
+
+// lintDashboardModel checks a dashboard JSON model against best practices.
+func lintDashboardModel(db map[string]any, maxQueries int) []dashboardLintFinding {
+	findings := []dashboardLintFinding{}
+	var walk func(panels []any)
+	walk = func(panels []any) {
+		for _, p := range panels {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := panel["id"].(float64)
+			title := stringField(panel, "title")
+			add := func(rule, severity, refID, format string, args ...any) {
+				findings = append(findings, dashboardLintFinding{
+					Rule: rule, Severity: severity, PanelID: int(id), PanelTitle: title, RefID: refID,
+					Message: fmt.Sprintf(format, args...),
+				})
+			}
+			panelType := stringField(panel, "type")
+			if panelType == "row" {
+				nested, _ := panel["panels"].([]any)
+				walk(nested)
+				continue
+			}
+
+			if replacement, ok := deprecatedPanelTypes[panelType]; ok {
+				add(lintDeprecatedPanelType, "warning", "", "The %s panel type is deprecated and may not load in current Grafana versions; change the panel to %s.", panelType, replacement)
+			}
+			if unitPanelTypes[panelType] && !panelHasUnit(panel) {
+				add(lintPanelUnits, "info", "", "The panel has no unit; set fieldConfig.defaults.unit, e.g. to 'percent', 'bytes', 's' or 'reqps', or to 'short' or 'none' for plain numbers.")
+			}
+
+			targets, _ := panel["targets"].([]any)
+			if len(targets) > maxQueries {
+				add(lintPanelQueries, "warning", "", "The panel has %d queries, more than %d; split it into several panels or combine the queries, as each query is run on every refresh.", len(targets), maxQueries)
+			}
+			panelDatasource := parseDatasourceRef(panel["datasource"])
+			if isConcreteDatasource(panelDatasource) {
+				add(lintPanelDatasource, "warning", "", "The panel uses the datasource %q directly; use a datasource template variable such as ${datasource} so the dashboard works with other datasources and instances.", panelDatasource.UID)
+			}
+			for _, t := range targets {
+				target, ok := t.(map[string]any)
+				if !ok {
+					continue
+				}
+				refID := stringField(target, "refId")
+				// Targets often repeat their panel's datasource, which is
+				// reported once for the panel.
+				if ds := parseDatasourceRef(target["datasource"]); isConcreteDatasource(ds) && ds.UID != panelDatasource.UID {
+					add(lintPanelDatasource, "warning", refID, "The query uses the datasource %q directly; use a datasource template variable such as ${datasource}.", ds.UID)
+				}
+				expr := stringField(target, "expr")
+				if expr == "" || targetDatasource(panel, target).Type == "loki" {
+					continue
+				}
+				if ranges := fixedRateRanges(expr); len(ranges) > 0 {
+					add(lintRateInterval, "warning", refID, "The query uses the fixed range %s in rate(), irate() or increase(); use [$__rate_interval] so the range adapts to the time range and is at least four scrape intervals.", strings.Join(ranges, ", "))
+				}
+			}
+		}
+	}
+	panels, _ := db["panels"].([]any)
+	walk(panels)
+	return findings
+}
+
+func lintDashboard(ctx context.Context, args LintDashboardParams) (*dashboardLintResult, error) {
+	db := args.Dashboard
+	switch {
+	case args.UID != "" && db != nil:
+		return nil, fmt.Errorf("only one of uid and dashboard can be set")
+	case args.UID != "":
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if db, ok = dashboard.Dashboard.(map[string]any); !ok {
+			return nil, fmt.Errorf("dashboard is not a JSON object")
+		}
+	case db == nil:
+		return nil, fmt.Errorf("either uid or dashboard is required")
+	}
+	maxQueries := args.MaxPanelQueries
+	if maxQueries <= 0 {
+		maxQueries = DefaultMaxPanelQueries
+	}
+
+	result := &dashboardLintResult{
+		UID:      stringField(db, "uid"),
+		Title:    stringField(db, "title"),
+		Findings: lintDashboardModel(db, maxQueries),
+	}
+	for _, f := range result.Findings {
+		if result.Rules == nil {
+			result.Rules = map[string]int{}
+		}
+		result.Rules[f.Rule]++
+	}
+	return result, nil
+}
+
+var LintDashboard = mcpgrafana.MustTool(
+	"lint_dashboard",
+	"Check a dashboard, by UID or as JSON, against best practices: panels without units, rate queries with fixed ranges instead of $__rate_interval, datasources referenced by UID instead of a template variable, deprecated panel types such as graph and singlestat, and panels with too many queries. Returns findings with the rule, severity, panel ID and title, query ref ID and a message saying how to fix it.",
+	lintDashboard,
+	mcp.WithTitleAnnotation("Lint dashboard"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
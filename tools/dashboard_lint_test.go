@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintTestDashboard = `{
+	"uid": "lint", "title": "Lint me",
+	"panels": [
+		{"id": 1, "type": "timeseries", "title": "Requests", "datasource": {"type": "prometheus", "uid": "${datasource}"},
+			"fieldConfig": {"defaults": {"unit": "reqps"}},
+			"targets": [
+				{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"},
+				{"refId": "B", "expr": "sum(rate(http_requests_total[$__rate_interval]))"},
+				{"refId": "C", "expr": "sum(http_requests_in_flight[1h])"}
+			]},
+		{"id": 2, "type": "graph", "title": "Old", "datasource": {"type": "prometheus", "uid": "prom"},
+			"targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "prom"}, "expr": "up"}]},
+		{"id": 3, "type": "row", "title": "Details", "collapsed": true, "panels": [
+			{"id": 4, "type": "stat", "title": "Errors",
+				"fieldConfig": {"defaults": {}, "overrides": [{"matcher": {"id": "byName", "options": "A"}, "properties": [{"id": "unit", "value": "short"}]}]},
+				"targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "mimir"}, "expr": "sum(increase(errors_total[1h30m]))"}]},
+			{"id": 5, "type": "gauge", "title": "Many",
+				"targets": [{"refId": "A"}, {"refId": "B"}, {"refId": "C"}]},
+			{"id": 6, "type": "logs", "title": "Logs", "datasource": {"type": "loki", "uid": "$loki"},
+				"targets": [{"refId": "A", "expr": "rate({app=\"api\"}[5m])"}]}
+		]}
+	]
+}`
+
+func TestLintDashboardModel(t *testing.T) {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lintTestDashboard), &db))
+
+	var got []string
+	for _, f := range lintDashboardModel(db, 2) {
+		got = append(got, f.Rule+"/"+f.PanelTitle+"/"+f.RefID)
+	}
+	assert.Equal(t, []string{
+		"panel-too-many-queries/Requests/",
+		"target-rate-interval/Requests/A",
+		"panel-deprecated-type/Old/",
+		"panel-datasource/Old/",
+		"panel-datasource/Errors/A",
+		"target-rate-interval/Errors/A",
+		"panel-units/Many/",
+		"panel-too-many-queries/Many/",
+	}, got)
+
+	findings := lintDashboardModel(db, DefaultMaxPanelQueries)
+	assert.Equal(t, dashboardLintFinding{
+		Rule: lintRateInterval, Severity: "warning", PanelID: 1, PanelTitle: "Requests", RefID: "A",
+		Message: "The query uses the fixed range [5m] in rate(), irate() or increase(); use [$__rate_interval] so the range adapts to the time range and is at least four scrape intervals.",
+	}, findings[0])
+	assert.Contains(t, findings[1].Message, "change the panel to timeseries")
+	assert.Contains(t, findings[3].Message, `"mimir"`)
+}
+
+func TestLintDashboard(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/lint", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + lintTestDashboard + `}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := lintDashboard(ctx, LintDashboardParams{UID: "lint"})
+	require.NoError(t, err)
+	assert.Equal(t, "Lint me", result.Title)
+	assert.Equal(t, map[string]int{
+		lintRateInterval:        2,
+		lintDeprecatedPanelType: 1,
+		lintPanelDatasource:     2,
+		lintPanelUnits:          1,
+	}, result.Rules)
+
+	result, err = lintDashboard(ctx, LintDashboardParams{Dashboard: map[string]any{"title": "Empty"}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Findings)
+	assert.Nil(t, result.Rules)
+
+	_, err = lintDashboard(ctx, LintDashboardParams{})
+	assert.Error(t, err)
+}
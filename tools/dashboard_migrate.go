@@ -0,0 +1,784 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type MigrateDashboardSchemaParams struct {
+	UID       string         `json:"uid,omitempty" jsonschema:"description=The UID of the dashboard to migrate. Either 'uid' or 'dashboard' is required"`
+	Dashboard map[string]any `json:"dashboard,omitempty" jsonschema:"description=The dashboard JSON to migrate"`
+}
+
+// panelMigration describes the migration of a legacy panel. To is empty if
+// the panel couldn't be migrated and was left as it is.
+type panelMigration struct {
+	PanelID    int    `json:"panelId"`
+	PanelTitle string `json:"panelTitle,omitempty"`
+	From       string `json:"from"`
+	To         string `json:"to,omitempty"`
+	// Unconverted lists the settings which couldn't be converted, or why
+	// the panel wasn't migrated.
+	Unconverted []string `json:"unconverted,omitempty"`
+}
+
+type dashboardMigrationResult struct {
+	UID   string `json:"uid,omitempty"`
+	Title string `json:"title"`
+	// Migrated and Unconverted count the panels migrated and left as they
+	// are.
+	Migrated    int              `json:"migrated"`
+	Unconverted int              `json:"unconverted"`
+	Panels      []panelMigration `json:"panels"`
+	Notes       []string         `json:"notes,omitempty"`
+	Dashboard   map[string]any   `json:"dashboard"`
+}
+
+// panelMigrator converts a legacy panel in place, returning its new type and
+// the settings it couldn't convert. A panel which can't be migrated is left
+// unchanged, and its new type is empty.
+type panelMigrator func(panel map[string]any) (string, []string)
+
+// panelMigrators are the migrations of legacy panel types.
+var panelMigrators = map[string]panelMigrator{
+	"graph":                    migrateGraphPanel,
+	"singlestat":               migrateSinglestatPanel,
+	"grafana-singlestat-panel": migrateSinglestatPanel,
+	"table-old":                migrateTablePanel,
+	"grafana-piechart-panel":   migratePiechartPanel,
+}
+
+// reducerNames maps the value names of legacy panels to the reducers of
+// current panels.
+var reducerNames = map[string]string{
+	"avg":     "mean",
+	"current": "lastNotNull",
+	"min":     "min",
+	"max":     "max",
+	"total":   "sum",
+	"first":   "firstNotNull",
+	"delta":   "delta",
+	"diff":    "diff",
+	"range":   "range",
+	"count":   "count",
+}
+
+// numberValue returns a number from a legacy panel, where numbers are
+// sometimes saved as strings.
+func numberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// deleteFields removes legacy fields from a panel.
+func deleteFields(panel map[string]any, fields ...string) {
+	for _, f := range fields {
+		delete(panel, f)
+	}
+}
+
+// seriesMatcher returns the field matcher of a legacy series alias, which is
+// a regex if enclosed in slashes.
+func seriesMatcher(alias string) map[string]any {
+	if len(alias) > 1 && strings.HasPrefix(alias, "/") && strings.HasSuffix(alias, "/") {
+		return map[string]any{"id": "byRegexp", "options": alias}
+	}
+	return map[string]any{"id": "byName", "options": alias}
+}
+
+func fieldProperty(id string, value any) map[string]any {
+	return map[string]any{"id": id, "value": value}
+}
+
+func fixedColor(color string) map[string]any {
+	return map[string]any{"mode": "fixed", "fixedColor": color}
+}
+
+// addOverride adds a field config override to a panel.
+func addOverride(panel map[string]any, matcher map[string]any, properties []any) {
+	if len(properties) == 0 {
+		return
+	}
+	fieldConfig := childObject(panel, "fieldConfig")
+	overrides, _ := fieldConfig["overrides"].([]any)
+	fieldConfig["overrides"] = append(overrides, map[string]any{"matcher": matcher, "properties": properties})
+}
+
+// migrateAliasColors converts the colors of series by name to overrides.
+func migrateAliasColors(panel map[string]any) {
+	aliasColors, _ := panel["aliasColors"].(map[string]any)
+	for _, name := range sortedKeys(aliasColors) {
+		if color, ok := aliasColors[name].(string); ok {
+			addOverride(panel, map[string]any{"id": "byName", "options": name}, []any{fieldProperty("color", fixedColor(color))})
+		}
+	}
+	delete(panel, "aliasColors")
+}
+
+// migrateReduceOptions sets the reducer of a stat-like panel from its legacy
+// value name.
+func migrateReduceOptions(panel, options map[string]any, unconverted *[]string) {
+	calc := "mean"
+	if valueName := stringField(panel, "valueName"); valueName != "" {
+		if reducer, ok := reducerNames[valueName]; ok {
+			calc = reducer
+		} else {
+			*unconverted = append(*unconverted, fmt.Sprintf("valueName %q: showing the series name isn't supported; the mean is shown instead", valueName))
+		}
+	}
+	options["reduceOptions"] = map[string]any{"calcs": []any{calc}, "fields": "", "values": false}
+}
+
+// thresholdColors maps the color modes of graph thresholds to colors.
+var thresholdColors = map[string]string{"critical": "red", "warning": "orange", "ok": "green"}
+
+// migrateGraphThresholds converts the thresholds of a graph panel to
+// absolute thresholds shown as lines or areas.
+func migrateGraphThresholds(panel, custom map[string]any, unconverted *[]string) {
+	thresholds, _ := panel["thresholds"].([]any)
+	line, area := false, false
+	for _, t := range thresholds {
+		threshold, _ := t.(map[string]any)
+		value, ok := numberValue(threshold["value"])
+		if !ok {
+			continue
+		}
+		color := thresholdColors[stringField(threshold, "colorMode")]
+		if color == "" {
+			color = stringOrDefault(stringField(threshold, "lineColor"), stringOrDefault(stringField(threshold, "fillColor"), "red"))
+		}
+		if stringField(threshold, "op") == "lt" {
+			*unconverted = append(*unconverted, fmt.Sprintf("threshold %v: 'lt' thresholds color values below the threshold, while current thresholds color values above it", value))
+		}
+		addPanelThreshold(panel, value, color)
+		if l, ok := threshold["line"].(bool); !ok || l {
+			line = true
+		}
+		if f, _ := threshold["fill"].(bool); f {
+			area = true
+		}
+	}
+	switch {
+	case line && area:
+		custom["thresholdsStyle"] = map[string]any{"mode": "line+area"}
+	case area:
+		custom["thresholdsStyle"] = map[string]any{"mode": "area"}
+	case line:
+		custom["thresholdsStyle"] = map[string]any{"mode": "line"}
+	}
+}
+
+// migrateSeriesOverrides converts the series overrides of a graph panel to
+// field overrides.
+func migrateSeriesOverrides(panel map[string]any, rightAxisUnit string, unconverted *[]string) {
+	seriesOverrides, _ := panel["seriesOverrides"].([]any)
+	for _, o := range seriesOverrides {
+		override, _ := o.(map[string]any)
+		alias := stringField(override, "alias")
+		if alias == "" {
+			continue
+		}
+		var properties []any
+		for _, key := range sortedKeys(override) {
+			value := override[key]
+			switch key {
+			case "alias":
+			case "yaxis":
+				if n, _ := numberValue(value); n == 2 {
+					properties = append(properties, fieldProperty("custom.axisPlacement", "right"))
+					if rightAxisUnit != "" {
+						properties = append(properties, fieldProperty("unit", rightAxisUnit))
+					}
+				}
+			case "color":
+				properties = append(properties, fieldProperty("color", fixedColor(fmt.Sprint(value))))
+			case "fill":
+				if n, ok := numberValue(value); ok {
+					properties = append(properties, fieldProperty("custom.fillOpacity", n*10))
+				}
+			case "linewidth":
+				if n, ok := numberValue(value); ok {
+					properties = append(properties, fieldProperty("custom.lineWidth", n))
+				}
+			case "bars":
+				if value == true {
+					properties = append(properties, fieldProperty("custom.drawStyle", "bars"))
+				}
+			case "points":
+				if value == true && override["lines"] == false {
+					properties = append(properties, fieldProperty("custom.drawStyle", "points"))
+				}
+			case "lines":
+				if value == false && override["bars"] != true && override["points"] != true {
+					properties = append(properties, fieldProperty("custom.lineWidth", 0))
+				}
+			case "dashes":
+				if value == true {
+					properties = append(properties, fieldProperty("custom.lineStyle", map[string]any{"fill": "dash", "dash": []any{10, 10}}))
+				}
+			case "stack":
+				switch value {
+				case false:
+					properties = append(properties, fieldProperty("custom.stacking", map[string]any{"mode": "none"}))
+				case true:
+					properties = append(properties, fieldProperty("custom.stacking", map[string]any{"mode": "normal", "group": "A"}))
+				default:
+					properties = append(properties, fieldProperty("custom.stacking", map[string]any{"mode": "normal", "group": fmt.Sprint(value)}))
+				}
+			case "transform":
+				if value == "negative-Y" {
+					properties = append(properties, fieldProperty("custom.transform", "negative-Y"))
+				}
+			case "legend":
+				if value == false {
+					properties = append(properties, fieldProperty("custom.hideFrom", map[string]any{"legend": true, "tooltip": false, "viz": false}))
+				}
+			default:
+				*unconverted = append(*unconverted, fmt.Sprintf("seriesOverrides[%s].%s", alias, key))
+			}
+		}
+		addOverride(panel, seriesMatcher(alias), properties)
+	}
+}
+
+// legacyGraphFields are the fields of the graph panel which the timeseries
+// panel keeps elsewhere.
+var legacyGraphFields = []string{
+	"bars", "lines", "points", "fill", "fillGradient", "linewidth", "pointradius", "stack", "percentage",
+	"nullPointMode", "steppedLine", "dashes", "dashLength", "spaceLength", "yaxes", "yaxis", "xaxis",
+	"legend", "tooltip", "seriesOverrides", "thresholds", "timeRegions", "renderer", "decimals",
+	"hiddenSeries", "alert", "grid", "zindex",
+}
+
+func migrateGraphPanel(panel map[string]any) (string, []string) {
+	xaxis, _ := panel["xaxis"].(map[string]any)
+	if mode := stringField(xaxis, "mode"); mode != "" && mode != "time" {
+		replacement := map[string]string{"series": "barchart", "histogram": "histogram"}[mode]
+		return "", []string{fmt.Sprintf("the x-axis shows %s instead of time; convert the panel to a %s panel by hand", mode, stringOrDefault(replacement, "suitable"))}
+	}
+	var unconverted []string
+	defaults := childObject(childObject(panel, "fieldConfig"), "defaults")
+	custom := childObject(defaults, "custom")
+	options := childObject(panel, "options")
+
+	drawStyle, showPoints := "line", "never"
+	switch {
+	case panel["bars"] == true:
+		drawStyle = "bars"
+	case panel["lines"] == false && panel["points"] == true:
+		drawStyle = "points"
+	}
+	if panel["points"] == true {
+		showPoints = "always"
+	}
+	custom["drawStyle"], custom["showPoints"] = drawStyle, showPoints
+	if n, ok := numberValue(panel["linewidth"]); ok {
+		custom["lineWidth"] = n
+	}
+	if n, ok := numberValue(panel["fill"]); ok {
+		custom["fillOpacity"] = n * 10
+	}
+	if n, ok := numberValue(panel["fillGradient"]); ok && n > 0 {
+		custom["gradientMode"] = "opacity"
+	}
+	if n, ok := numberValue(panel["pointradius"]); ok {
+		custom["pointSize"] = n * 2
+	}
+	if panel["steppedLine"] == true {
+		custom["lineInterpolation"] = "stepAfter"
+	}
+	if panel["dashes"] == true {
+		custom["lineStyle"] = map[string]any{"fill": "dash", "dash": []any{10, 10}}
+	}
+	switch {
+	case panel["stack"] == true && panel["percentage"] == true:
+		custom["stacking"] = map[string]any{"mode": "percent", "group": "A"}
+	case panel["stack"] == true:
+		custom["stacking"] = map[string]any{"mode": "normal", "group": "A"}
+	}
+	switch stringField(panel, "nullPointMode") {
+	case "connected":
+		custom["spanNulls"] = true
+	case "null as zero":
+		unconverted = append(unconverted, "nullPointMode 'null as zero': missing values are shown as gaps; use a 'Convert field type' or query change if zeros are needed")
+	}
+	if n, ok := numberValue(panel["decimals"]); ok {
+		defaults["decimals"] = n
+	}
+
+	// The left axis sets the defaults, and the right axis the unit of the
+	// series moved to it.
+	yaxes, _ := panel["yaxes"].([]any)
+	var rightAxisUnit string
+	if len(yaxes) > 0 {
+		left, _ := yaxes[0].(map[string]any)
+		if format := stringField(left, "format"); format != "" {
+			defaults["unit"] = format
+		}
+		if n, ok := numberValue(left["min"]); ok {
+			defaults["min"] = n
+		}
+		if n, ok := numberValue(left["max"]); ok {
+			defaults["max"] = n
+		}
+		if label := stringField(left, "label"); label != "" {
+			custom["axisLabel"] = label
+		}
+		if n, ok := numberValue(left["logBase"]); ok && n > 1 {
+			custom["scaleDistribution"] = map[string]any{"type": "log", "log": n}
+		}
+		if left["show"] == false {
+			custom["axisPlacement"] = "hidden"
+		}
+	}
+	if len(yaxes) > 1 {
+		right, _ := yaxes[1].(map[string]any)
+		if format := stringField(right, "format"); format != "" && format != defaults["unit"] {
+			rightAxisUnit = format
+		}
+	}
+
+	legend, _ := panel["legend"].(map[string]any)
+	legendOptions := map[string]any{"showLegend": legend["show"] != false, "displayMode": "list", "placement": "bottom", "calcs": []any{}}
+	if legend["alignAsTable"] == true {
+		legendOptions["displayMode"] = "table"
+	}
+	if legend["rightSide"] == true {
+		legendOptions["placement"] = "right"
+	}
+	for _, calc := range []string{"current", "min", "max", "avg", "total"} {
+		if legend[calc] == true {
+			legendOptions["calcs"] = append(legendOptions["calcs"].([]any), reducerNames[calc])
+		}
+	}
+	options["legend"] = legendOptions
+
+	tooltip, _ := panel["tooltip"].(map[string]any)
+	tooltipOptions := map[string]any{"mode": "single", "sort": "none"}
+	if tooltip["shared"] != false {
+		tooltipOptions["mode"] = "multi"
+	}
+	switch n, _ := numberValue(tooltip["sort"]); n {
+	case 1:
+		tooltipOptions["sort"] = "asc"
+	case 2:
+		tooltipOptions["sort"] = "desc"
+	}
+	options["tooltip"] = tooltipOptions
+
+	migrateGraphThresholds(panel, custom, &unconverted)
+	migrateSeriesOverrides(panel, rightAxisUnit, &unconverted)
+	migrateAliasColors(panel)
+	if _, ok := panel["alert"]; ok {
+		unconverted = append(unconverted, "alert: legacy panel alerts were replaced by Grafana Alerting; recreate the alert as an alert rule")
+	}
+	if regions, _ := panel["timeRegions"].([]any); len(regions) > 0 {
+		unconverted = append(unconverted, "timeRegions: use time region annotations instead")
+	}
+	deleteFields(panel, legacyGraphFields...)
+	return "timeseries", unconverted
+}
+
+// singlestatThresholds converts the comma-separated thresholds and colors of
+// a singlestat panel to threshold steps.
+func singlestatThresholds(panel map[string]any) []any {
+	colors, _ := panel["colors"].([]any)
+	color := func(i int) string {
+		if i < len(colors) {
+			if c, ok := colors[i].(string); ok {
+				return c
+			}
+		}
+		return []string{"green", "orange", "red"}[min(i, 2)]
+	}
+	steps := []any{map[string]any{"color": color(0), "value": nil}}
+	if thresholds := stringField(panel, "thresholds"); thresholds != "" {
+		for i, t := range strings.Split(thresholds, ",") {
+			if value, ok := numberValue(t); ok {
+				steps = append(steps, map[string]any{"color": color(i + 1), "value": value})
+			}
+		}
+	}
+	return steps
+}
+
+// valueMappings converts the value and range maps of a legacy panel to value
+// mappings.
+func valueMappings(panel map[string]any) []any {
+	var mappings []any
+	valueMaps, _ := panel["valueMaps"].([]any)
+	for _, m := range valueMaps {
+		vm, _ := m.(map[string]any)
+		result := map[string]any{"text": stringField(vm, "text")}
+		switch value := vm["value"]; value {
+		case nil:
+		case "null":
+			mappings = append(mappings, map[string]any{"type": "special", "options": map[string]any{"match": "null", "result": result}})
+		default:
+			mappings = append(mappings, map[string]any{"type": "value", "options": map[string]any{fmt.Sprint(value): result}})
+		}
+	}
+	rangeMaps, _ := panel["rangeMaps"].([]any)
+	for _, m := range rangeMaps {
+		rm, _ := m.(map[string]any)
+		from, okFrom := numberValue(rm["from"])
+		to, okTo := numberValue(rm["to"])
+		if okFrom && okTo {
+			mappings = append(mappings, map[string]any{
+				"type":    "range",
+				"options": map[string]any{"from": from, "to": to, "result": map[string]any{"text": stringField(rm, "text")}},
+			})
+		}
+	}
+	return mappings
+}
+
+var legacySinglestatFields = []string{
+	"format", "decimals", "nullText", "valueName", "thresholds", "colors", "colorBackground", "colorValue",
+	"colorPrefix", "colorPostfix", "sparkline", "gauge", "valueMaps", "rangeMaps", "mappingType", "mappingTypes",
+	"prefix", "postfix", "prefixFontSize", "postfixFontSize", "valueFontSize", "tableColumn", "nullPointMode", "combine",
+}
+
+func migrateSinglestatPanel(panel map[string]any) (string, []string) {
+	var unconverted []string
+	defaults := childObject(childObject(panel, "fieldConfig"), "defaults")
+	options := childObject(panel, "options")
+	newType := "stat"
+	gauge, _ := panel["gauge"].(map[string]any)
+	if gauge["show"] == true {
+		newType = "gauge"
+		if n, ok := numberValue(gauge["minValue"]); ok {
+			defaults["min"] = n
+		}
+		if n, ok := numberValue(gauge["maxValue"]); ok {
+			defaults["max"] = n
+		}
+	}
+
+	if format := stringField(panel, "format"); format != "" {
+		defaults["unit"] = format
+	}
+	if n, ok := numberValue(panel["decimals"]); ok {
+		defaults["decimals"] = n
+	}
+	if nullText := stringField(panel, "nullText"); nullText != "" {
+		defaults["noValue"] = nullText
+	}
+	defaults["thresholds"] = map[string]any{"mode": "absolute", "steps": singlestatThresholds(panel)}
+	if mappings := valueMappings(panel); len(mappings) > 0 {
+		defaults["mappings"] = mappings
+	}
+
+	migrateReduceOptions(panel, options, &unconverted)
+	if column := stringField(panel, "tableColumn"); column != "" {
+		options["reduceOptions"].(map[string]any)["fields"] = "/^" + regexp.QuoteMeta(column) + "$/"
+	}
+	if newType == "stat" {
+		options["colorMode"] = "none"
+		switch {
+		case panel["colorBackground"] == true:
+			options["colorMode"] = "background"
+		case panel["colorValue"] == true:
+			options["colorMode"] = "value"
+		}
+		options["graphMode"] = "none"
+		if sparkline, _ := panel["sparkline"].(map[string]any); sparkline["show"] == true {
+			options["graphMode"] = "area"
+		}
+	}
+	for _, field := range []string{"prefix", "postfix"} {
+		if value := stringField(panel, field); value != "" {
+			unconverted = append(unconverted, fmt.Sprintf("%s %q: use a custom unit such as 'prefix:%s' or 'suffix:%s'", field, value, value, value))
+		}
+	}
+	deleteFields(panel, legacySinglestatFields...)
+	return newType, unconverted
+}
+
+// tableTransformations maps the transforms of the old table panel to
+// transformations.
+var tableTransformations = map[string]string{
+	"timeseries_to_rows":    "seriesToRows",
+	"timeseries_to_columns": "seriesToColumns",
+}
+
+// tableStyleProperties converts a column style of the old table panel to
+// field config properties.
+func tableStyleProperties(style map[string]any, unconverted *[]string) []any {
+	var properties []any
+	if alias := stringField(style, "alias"); alias != "" {
+		properties = append(properties, fieldProperty("displayName", alias))
+	}
+	switch stringField(style, "type") {
+	case "hidden":
+		properties = append(properties, fieldProperty("custom.hidden", true))
+	case "date":
+		properties = append(properties, fieldProperty("unit", "time:"+stringOrDefault(stringField(style, "dateFormat"), "YYYY-MM-DD HH:mm:ss")))
+	case "number":
+		if unit := stringField(style, "unit"); unit != "" {
+			properties = append(properties, fieldProperty("unit", unit))
+		}
+		if n, ok := numberValue(style["decimals"]); ok {
+			properties = append(properties, fieldProperty("decimals", n))
+		}
+		if colorMode := stringField(style, "colorMode"); colorMode != "" {
+			cellType := "color-background"
+			switch colorMode {
+			case "value":
+				cellType = "color-text"
+			case "row":
+				*unconverted = append(*unconverted, fmt.Sprintf("styles[%s].colorMode 'row': whole rows can't be colored; the cell is colored instead", stringField(style, "pattern")))
+			}
+			properties = append(properties,
+				fieldProperty("custom.cellOptions", map[string]any{"type": cellType}),
+				fieldProperty("thresholds", map[string]any{"mode": "absolute", "steps": singlestatThresholds(style)}))
+		}
+	}
+	if mappings := valueMappings(style); len(mappings) > 0 {
+		properties = append(properties, fieldProperty("mappings", mappings))
+	}
+	if style["link"] == true {
+		link := strings.NewReplacer("${__cell}", "${__value.raw}", "$__cell", "${__value.raw}").Replace(stringField(style, "linkUrl"))
+		if strings.Contains(link, "__cell_") {
+			*unconverted = append(*unconverted, fmt.Sprintf("styles[%s].linkUrl: references to other cells such as $__cell_0 must be rewritten as ${__data.fields[...]}", stringField(style, "pattern")))
+		}
+		properties = append(properties, fieldProperty("links", []any{map[string]any{
+			"title":       stringField(style, "linkTooltip"),
+			"url":         link,
+			"targetBlank": style["linkTargetBlank"] == true,
+		}}))
+	}
+	return properties
+}
+
+func migrateTablePanel(panel map[string]any) (string, []string) {
+	var unconverted []string
+	fieldConfig := childObject(panel, "fieldConfig")
+	options := childObject(panel, "options")
+	styles, _ := panel["styles"].([]any)
+	for _, s := range styles {
+		style, _ := s.(map[string]any)
+		properties := tableStyleProperties(style, &unconverted)
+		switch pattern := stringField(style, "pattern"); pattern {
+		case "", "/.*/":
+			// The default style applies to all columns.
+			defaults := childObject(fieldConfig, "defaults")
+			for _, p := range properties {
+				property := p.(map[string]any)
+				id := property["id"].(string)
+				if custom, ok := strings.CutPrefix(id, "custom."); ok {
+					childObject(defaults, "custom")[custom] = property["value"]
+				} else if id != "displayName" {
+					defaults[id] = property["value"]
+				}
+			}
+		default:
+			addOverride(panel, seriesMatcher(pattern), properties)
+		}
+	}
+
+	switch transform := stringField(panel, "transform"); transform {
+	case "", "table":
+	case "timeseries_aggregations":
+		var reducers []any
+		columns, _ := panel["columns"].([]any)
+		for _, c := range columns {
+			column, _ := c.(map[string]any)
+			if reducer, ok := reducerNames[stringField(column, "value")]; ok {
+				reducers = append(reducers, reducer)
+			}
+		}
+		panel["transformations"] = append(transformationList(panel), map[string]any{"id": "reduce", "options": map[string]any{"reducers": reducers}})
+	default:
+		if id, ok := tableTransformations[transform]; ok {
+			panel["transformations"] = append(transformationList(panel), map[string]any{"id": id, "options": map[string]any{}})
+		} else {
+			unconverted = append(unconverted, fmt.Sprintf("transform %q: add transformations to shape the data as before", transform))
+		}
+	}
+	if sort, _ := panel["sort"].(map[string]any); sort["col"] != nil {
+		unconverted = append(unconverted, fmt.Sprintf("sort by column %v: set the sort order by clicking the column header and save the dashboard", sort["col"]))
+	}
+	if showHeader, ok := panel["showHeader"].(bool); ok {
+		options["showHeader"] = showHeader
+	}
+	deleteFields(panel, "styles", "transform", "columns", "sort", "showHeader", "fontSize", "scroll")
+	return "table", unconverted
+}
+
+func transformationList(panel map[string]any) []any {
+	transformations, _ := panel["transformations"].([]any)
+	return transformations
+}
+
+func migratePiechartPanel(panel map[string]any) (string, []string) {
+	var unconverted []string
+	defaults := childObject(childObject(panel, "fieldConfig"), "defaults")
+	options := childObject(panel, "options")
+
+	options["pieType"] = "pie"
+	if stringField(panel, "pieType") == "donut" {
+		options["pieType"] = "donut"
+	}
+	migrateReduceOptions(panel, options, &unconverted)
+	if format := stringField(panel, "format"); format != "" {
+		defaults["unit"] = format
+	}
+	if n, ok := numberValue(panel["decimals"]); ok {
+		defaults["decimals"] = n
+	}
+
+	legend, _ := panel["legend"].(map[string]any)
+	legendOptions := map[string]any{"showLegend": panel["legendType"] != "On graph" && legend["show"] != false, "displayMode": "list", "placement": "bottom", "values": []any{}}
+	switch stringField(panel, "legendType") {
+	case "Right side":
+		legendOptions["placement"] = "right"
+	case "On graph":
+		unconverted = append(unconverted, "legendType 'On graph': labels on the pie are set with the 'Labels' option")
+	}
+	if legend["values"] == true {
+		legendOptions["values"] = append(legendOptions["values"].([]any), "value")
+	}
+	if legend["percentage"] == true {
+		legendOptions["values"] = append(legendOptions["values"].([]any), "percent")
+	}
+	options["legend"] = legendOptions
+
+	if combine, _ := panel["combine"].(map[string]any); combine != nil {
+		if n, _ := numberValue(combine["threshold"]); n > 0 {
+			unconverted = append(unconverted, "combine: slices below a threshold can no longer be combined")
+		}
+	}
+	migrateAliasColors(panel)
+	deleteFields(panel, "pieType", "valueName", "format", "decimals", "legend", "legendType", "combine", "fontSize", "strokeWidth", "nullPointMode", "breakPoint", "cacheTimeout")
+	return "piechart", unconverted
+}
+
+// migrateTextPanel moves the content and mode of old text panels into their
+// options, reporting whether the panel needed it.
+func migrateTextPanel(panel map[string]any) bool {
+	_, hasContent := panel["content"]
+	_, hasMode := panel["mode"]
+	if !hasContent && !hasMode {
+		return false
+	}
+	options := childObject(panel, "options")
+	if content, ok := panel["content"]; ok {
+		options["content"] = content
+	}
+	mode := stringOrDefault(stringField(panel, "mode"), "markdown")
+	if mode == "text" {
+		mode = "markdown"
+	}
+	options["mode"] = mode
+	deleteFields(panel, "content", "mode")
+	return true
+}
+
+// migrateDashboardModel migrates the legacy panels of a dashboard in place.
+func migrateDashboardModel(db map[string]any) []panelMigration {
+	migrations := []panelMigration{}
+	var walk func(panels []any)
+	walk = func(panels []any) {
+		for _, p := range panels {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := panel["id"].(float64)
+			migration := panelMigration{PanelID: int(id), PanelTitle: stringField(panel, "title"), From: stringField(panel, "type")}
+			switch panelType := migration.From; {
+			case panelType == "row":
+				nested, _ := panel["panels"].([]any)
+				walk(nested)
+				continue
+			case panelType == "table" && panel["styles"] != nil:
+				// Tables from before Grafana 7 have column styles.
+				migration.To, migration.Unconverted = migrateTablePanel(panel)
+			case panelType == "text":
+				if !migrateTextPanel(panel) {
+					continue
+				}
+				migration.To = "text"
+			case panelMigrators[panelType] != nil:
+				migration.To, migration.Unconverted = panelMigrators[panelType](panel)
+			case deprecatedPanelTypes[panelType] != "":
+				migration.Unconverted = []string{fmt.Sprintf("there is no automatic migration; recreate the panel as a %s panel", deprecatedPanelTypes[panelType])}
+			default:
+				continue
+			}
+			if migration.To != "" {
+				panel["type"] = migration.To
+				// Grafana fills in the defaults of the new panel type.
+				delete(panel, "pluginVersion")
+			}
+			migrations = append(migrations, migration)
+		}
+	}
+	panels, _ := db["panels"].([]any)
+	walk(panels)
+	return migrations
+}
+
+func migrateDashboardSchema(ctx context.Context, args MigrateDashboardSchemaParams) (*dashboardMigrationResult, error) {
+	db := args.Dashboard
+	switch {
+	case args.UID != "" && db != nil:
+		return nil, fmt.Errorf("only one of uid and dashboard can be set")
+	case args.UID != "":
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if db, ok = dashboard.Dashboard.(map[string]any); !ok {
+			return nil, fmt.Errorf("dashboard is not a JSON object")
+		}
+	case db == nil:
+		return nil, fmt.Errorf("either uid or dashboard is required")
+	}
+	db, err := copyDashboard(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dashboardMigrationResult{
+		UID:       stringField(db, "uid"),
+		Title:     stringField(db, "title"),
+		Panels:    migrateDashboardModel(db),
+		Dashboard: db,
+	}
+	for _, m := range result.Panels {
+		if m.To != "" {
+			result.Migrated++
+		} else {
+			result.Unconverted++
+		}
+	}
+	if _, ok := db["rows"]; ok {
+		result.Notes = append(result.Notes, "The dashboard uses the rows layout from before Grafana 5, whose panels weren't migrated; open and save it in Grafana first to convert it to the grid layout.")
+	}
+	return result, nil
+}
+
+var MigrateDashboardSchema = mcpgrafana.MustTool(
+	"migrate_dashboard_schema",
+	"Convert the legacy panels of a dashboard, by UID or as JSON, to their current equivalents: graph to timeseries (axes, legend, tooltip, thresholds, series overrides and colors), singlestat to stat or gauge, the old table to table (column styles and transforms), the Angular pie chart to piechart, and old text panels. Other deprecated panel types, and settings with no equivalent such as legacy panel alerts, are reported as unconverted, with what to do instead. Returns the migrated dashboard, which isn't saved, with each panel's old and new type and unconverted settings. Review it, then save it with update_dashboard.",
+	migrateDashboardSchema,
+	mcp.WithTitleAnnotation("Migrate dashboard schema"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
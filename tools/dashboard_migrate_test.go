@@ -0,0 +1,167 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyTestDashboard = `{
+	"uid": "legacy", "title": "Legacy", "schemaVersion": 22,
+	"panels": [
+		{"id": 1, "type": "graph", "title": "Requests", "pluginVersion": "6.7.0",
+			"bars": false, "lines": true, "linewidth": 2, "fill": 1, "points": false, "stack": true, "nullPointMode": "connected",
+			"yaxes": [{"format": "reqps", "min": "0", "max": null, "logBase": 1, "show": true}, {"format": "ms", "logBase": 1, "show": true}],
+			"legend": {"show": true, "alignAsTable": true, "rightSide": true, "avg": true, "current": true},
+			"tooltip": {"shared": true, "sort": 2},
+			"thresholds": [{"value": 100, "colorMode": "critical", "op": "gt", "fill": true, "line": true}],
+			"seriesOverrides": [{"alias": "/latency/", "yaxis": 2, "fill": 0, "zindex": 3}],
+			"aliasColors": {"errors": "#d44a3a"},
+			"alert": {"name": "Too many requests"},
+			"targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"}]},
+		{"id": 2, "type": "graph", "title": "Histogram", "xaxis": {"mode": "histogram"}},
+		{"id": 3, "type": "row", "title": "Stats", "collapsed": true, "panels": [
+			{"id": 4, "type": "singlestat", "title": "Uptime", "format": "s", "decimals": 1, "valueName": "current",
+				"thresholds": "60,3600", "colors": ["#d44a3a", "#e5ac0e", "#299c46"], "colorBackground": true,
+				"sparkline": {"show": true}, "gauge": {"show": false}, "prefix": "~",
+				"valueMaps": [{"op": "=", "value": "null", "text": "N/A"}, {"op": "=", "value": "0", "text": "down"}]},
+			{"id": 5, "type": "singlestat", "title": "Load", "valueName": "name", "gauge": {"show": true, "minValue": 0, "maxValue": 10}}
+		]},
+		{"id": 6, "type": "table", "title": "Hosts", "transform": "timeseries_aggregations",
+			"columns": [{"text": "Avg", "value": "avg"}, {"text": "Max", "value": "max"}],
+			"styles": [
+				{"pattern": "Time", "type": "date", "dateFormat": "YYYY-MM-DD"},
+				{"pattern": "/.*/", "type": "number", "unit": "percent", "decimals": 2},
+				{"pattern": "Avg", "type": "number", "alias": "Average", "colorMode": "row", "thresholds": ["50", "80"], "colors": ["green", "orange", "red"], "link": true, "linkUrl": "/d/host?var-host=$__cell"}
+			],
+			"sort": {"col": 2, "desc": true}},
+		{"id": 7, "type": "grafana-piechart-panel", "title": "Share", "pieType": "donut", "valueName": "total", "legendType": "Right side", "legend": {"show": true, "percentage": true}},
+		{"id": 8, "type": "text", "title": "Notes", "mode": "markdown", "content": "# Hello"},
+		{"id": 9, "type": "grafana-worldmap-panel", "title": "Map"},
+		{"id": 10, "type": "timeseries", "title": "Modern"}
+	]
+}`
+
+func TestMigrateDashboardModel(t *testing.T) {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(legacyTestDashboard), &db))
+
+	migrations := migrateDashboardModel(db)
+	var summary []string
+	for _, m := range migrations {
+		summary = append(summary, m.From+"->"+m.To)
+	}
+	assert.Equal(t, []string{
+		"graph->timeseries", "graph->", "singlestat->stat", "singlestat->gauge",
+		"table->table", "grafana-piechart-panel->piechart", "text->text", "grafana-worldmap-panel->",
+	}, summary)
+	assert.Equal(t, []string{
+		"seriesOverrides[/latency/].zindex",
+		"alert: legacy panel alerts were replaced by Grafana Alerting; recreate the alert as an alert rule",
+	}, migrations[0].Unconverted)
+	assert.Contains(t, migrations[1].Unconverted[0], "histogram panel")
+	assert.Contains(t, migrations[3].Unconverted[0], `valueName "name"`)
+	assert.Len(t, migrations[4].Unconverted, 2)
+	assert.Contains(t, migrations[7].Unconverted[0], "geomap")
+
+	panels := db["panels"].([]any)
+	graph := panels[0].(map[string]any)
+	assert.Equal(t, "timeseries", graph["type"])
+	for _, field := range []string{"yaxes", "legend", "seriesOverrides", "aliasColors", "alert", "pluginVersion"} {
+		assert.NotContains(t, graph, field)
+	}
+	defaults := graph["fieldConfig"].(map[string]any)["defaults"].(map[string]any)
+	assert.Equal(t, "reqps", defaults["unit"])
+	assert.Equal(t, 0.0, defaults["min"])
+	assert.Equal(t, map[string]any{
+		"drawStyle": "line", "showPoints": "never", "lineWidth": 2.0, "fillOpacity": 10.0, "spanNulls": true,
+		"stacking":        map[string]any{"mode": "normal", "group": "A"},
+		"thresholdsStyle": map[string]any{"mode": "line+area"},
+	}, defaults["custom"])
+	assert.Equal(t, []any{map[string]any{"color": "green", "value": nil}, map[string]any{"color": "red", "value": 100.0}}, defaults["thresholds"].(map[string]any)["steps"])
+	options := graph["options"].(map[string]any)
+	assert.Equal(t, map[string]any{"showLegend": true, "displayMode": "table", "placement": "right", "calcs": []any{"lastNotNull", "mean"}}, options["legend"])
+	assert.Equal(t, map[string]any{"mode": "multi", "sort": "desc"}, options["tooltip"])
+	assert.Equal(t, []any{
+		map[string]any{"matcher": map[string]any{"id": "byRegexp", "options": "/latency/"}, "properties": []any{
+			map[string]any{"id": "custom.fillOpacity", "value": 0.0},
+			map[string]any{"id": "custom.axisPlacement", "value": "right"},
+			map[string]any{"id": "unit", "value": "ms"},
+		}},
+		map[string]any{"matcher": map[string]any{"id": "byName", "options": "errors"}, "properties": []any{
+			map[string]any{"id": "color", "value": map[string]any{"mode": "fixed", "fixedColor": "#d44a3a"}},
+		}},
+	}, graph["fieldConfig"].(map[string]any)["overrides"])
+	assert.Equal(t, "graph", panels[1].(map[string]any)["type"])
+
+	stat := panels[2].(map[string]any)["panels"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"unit": "s", "decimals": 1.0,
+		"thresholds": map[string]any{"mode": "absolute", "steps": []any{
+			map[string]any{"color": "#d44a3a", "value": nil},
+			map[string]any{"color": "#e5ac0e", "value": 60.0},
+			map[string]any{"color": "#299c46", "value": 3600.0},
+		}},
+		"mappings": []any{
+			map[string]any{"type": "special", "options": map[string]any{"match": "null", "result": map[string]any{"text": "N/A"}}},
+			map[string]any{"type": "value", "options": map[string]any{"0": map[string]any{"text": "down"}}},
+		},
+	}, stat["fieldConfig"].(map[string]any)["defaults"])
+	assert.Equal(t, map[string]any{
+		"reduceOptions": map[string]any{"calcs": []any{"lastNotNull"}, "fields": "", "values": false},
+		"colorMode":     "background",
+		"graphMode":     "area",
+	}, stat["options"])
+
+	table := panels[3].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"id": "reduce", "options": map[string]any{"reducers": []any{"mean", "max"}}}}, table["transformations"])
+	tableConfig := table["fieldConfig"].(map[string]any)
+	assert.Equal(t, map[string]any{"unit": "percent", "decimals": 2.0}, tableConfig["defaults"])
+	overrides := tableConfig["overrides"].([]any)
+	require.Len(t, overrides, 2)
+	avg := overrides[1].(map[string]any)["properties"].([]any)
+	assert.Equal(t, map[string]any{"id": "displayName", "value": "Average"}, avg[0])
+	assert.Equal(t, map[string]any{"id": "links", "value": []any{map[string]any{"title": "", "url": "/d/host?var-host=${__value.raw}", "targetBlank": false}}}, avg[len(avg)-1])
+	assert.NotContains(t, table, "styles")
+
+	pie := panels[4].(map[string]any)["options"].(map[string]any)
+	assert.Equal(t, "donut", pie["pieType"])
+	assert.Equal(t, map[string]any{"showLegend": true, "displayMode": "list", "placement": "right", "values": []any{"percent"}}, pie["legend"])
+
+	text := panels[5].(map[string]any)
+	assert.Equal(t, map[string]any{"content": "# Hello", "mode": "markdown"}, text["options"])
+	assert.NotContains(t, text, "content")
+}
+
+func TestMigrateDashboardSchema(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/legacy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + legacyTestDashboard + `}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := migrateDashboardSchema(ctx, MigrateDashboardSchemaParams{UID: "legacy"})
+	require.NoError(t, err)
+	assert.Equal(t, "Legacy", result.Title)
+	assert.Equal(t, 6, result.Migrated)
+	assert.Equal(t, 2, result.Unconverted)
+	assert.Equal(t, 22.0, result.Dashboard["schemaVersion"])
+
+	// The given dashboard is left as it is.
+	db := map[string]any{"title": "Old", "rows": []any{}, "panels": []any{map[string]any{"id": 1.0, "type": "singlestat"}}}
+	result, err = migrateDashboardSchema(ctx, MigrateDashboardSchemaParams{Dashboard: db})
+	require.NoError(t, err)
+	assert.Equal(t, "singlestat", db["panels"].([]any)[0].(map[string]any)["type"])
+	assert.Equal(t, "stat", result.Dashboard["panels"].([]any)[0].(map[string]any)["type"])
+	assert.Len(t, result.Notes, 1)
+
+	_, err = migrateDashboardSchema(ctx, MigrateDashboardSchemaParams{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,348 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Dashboard patch operations.
+const (
+	patchSetPanelTitle = "set_panel_title"
+	patchSetQuery      = "set_query"
+	patchAddThreshold  = "add_threshold"
+	patchMovePanel     = "move_panel"
+	patchSet           = "set"
+)
+
+// dashboardGridPos is the position and size of a panel on the dashboard grid,
+// which is 24 columns wide.
+type dashboardGridPos struct {
+	X *int `json:"x,omitempty" jsonschema:"description=Optionally\\, the column of the panel's left edge (0-23)"`
+	Y *int `json:"y,omitempty" jsonschema:"description=Optionally\\, the row of the panel's top edge"`
+	W *int `json:"w,omitempty" jsonschema:"description=Optionally\\, the width of the panel in columns (1-24)"`
+	H *int `json:"h,omitempty" jsonschema:"description=Optionally\\, the height of the panel in rows"`
+}
+
+type DashboardPatchOperation struct {
+	Op      string            `json:"op" jsonschema:"required,enum=set_panel_title,enum=set_query,enum=add_threshold,enum=move_panel,enum=set,description=The operation: 'set_panel_title' sets the title of a panel; 'set_query' sets the query of a panel target; 'add_threshold' adds a threshold step to a panel; 'move_panel' changes a panel's grid position or size; 'set' sets the dashboard field at 'path'"`
+	PanelID int               `json:"panelId,omitempty" jsonschema:"description=The ID of the panel to change\\, for all operations but 'set'"`
+	Title   string            `json:"title,omitempty" jsonschema:"description=The new panel title\\, for 'set_panel_title'"`
+	RefID   string            `json:"refId,omitempty" jsonschema:"description=The ref ID of the target whose query to set\\, for 'set_query'. Can be left out if the panel has a single target"`
+	Expr    string            `json:"expr,omitempty" jsonschema:"description=The new query\\, such as a PromQL or LogQL expression\\, for 'set_query'"`
+	Color   string            `json:"color,omitempty" jsonschema:"description=The color of the threshold\\, such as 'red' or '#F2495C'\\, for 'add_threshold'"`
+	GridPos *dashboardGridPos `json:"gridPos,omitempty" jsonschema:"description=The fields of the grid position to change\\, for 'move_panel'"`
+	Path    string            `json:"path,omitempty" jsonschema:"description=The JSONPath-style path of the field to set\\, for 'set'\\, e.g. 'refresh'\\, 'time.from' or 'panels[0].options.legend.showLegend'"`
+	Value   any               `json:"value,omitempty" jsonschema:"description=The threshold value for 'add_threshold'\\, or the new value of the field for 'set'"`
+}
+
+type PatchDashboardParams struct {
+	UID        string                    `json:"uid" jsonschema:"required,description=The UID of the dashboard to patch"`
+	Operations []DashboardPatchOperation `json:"operations" jsonschema:"required,description=The operations to apply\\, in order"`
+	Message    string                    `json:"message,omitempty" jsonschema:"description=Optionally\\, the message recorded in the dashboard's version history. Defaults to a summary of the operations"`
+	DryRun     bool                      `json:"dryRun,omitempty" jsonschema:"description=Set to true to only return the changes the operations would make\\, without saving the dashboard"`
+}
+
+type dashboardPatchResult struct {
+	UID     string        `json:"uid"`
+	URL     string        `json:"url,omitempty"`
+	Version int64         `json:"version,omitempty"`
+	Saved   bool          `json:"saved"`
+	Diff    dashboardDiff `json:"diff"`
+}
+
+// findDashboardPanel finds a panel by ID, including panels nested in
+// collapsed rows.
+func findDashboardPanel(db map[string]any, id int) (map[string]any, error) {
+	var find func(panels []any) map[string]any
+	find = func(panels []any) map[string]any {
+		for _, p := range panels {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			if panelID, _ := panel["id"].(float64); int(panelID) == id {
+				return panel
+			}
+			if nested, ok := panel["panels"].([]any); ok {
+				if found := find(nested); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	panels, _ := db["panels"].([]any)
+	if panel := find(panels); panel != nil {
+		return panel, nil
+	}
+	return nil, fmt.Errorf("panel %d not found", id)
+}
+
+// setPanelQuery sets the query of a panel target, in the field holding its
+// current query, or in `expr` if it has none.
+func setPanelQuery(panel map[string]any, refID, expr string) error {
+	targets, _ := panel["targets"].([]any)
+	if refID == "" && len(targets) != 1 {
+		return fmt.Errorf("the panel has %d targets: set refId to choose one", len(targets))
+	}
+	var target map[string]any
+	for _, t := range targets {
+		if candidate, ok := t.(map[string]any); ok && (refID == "" || stringField(candidate, "refId") == refID) {
+			target = candidate
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("target %q not found", refID)
+	}
+	for _, field := range targetQueryFields {
+		if _, ok := target[field].(string); ok {
+			target[field] = expr
+			return nil
+		}
+	}
+	target["expr"] = expr
+	return nil
+}
+
+// addPanelThreshold adds a step to the absolute thresholds of a panel's
+// field config, creating them with Grafana's default green base step if
+// needed, and replacing the color of an existing step with the same value.
+func addPanelThreshold(panel map[string]any, value float64, color string) {
+	fieldConfig := childObject(panel, "fieldConfig")
+	defaults := childObject(fieldConfig, "defaults")
+	thresholds := childObject(defaults, "thresholds")
+	if _, ok := thresholds["mode"]; !ok {
+		thresholds["mode"] = "absolute"
+	}
+	steps, _ := thresholds["steps"].([]any)
+	if len(steps) == 0 {
+		steps = []any{map[string]any{"color": "green", "value": nil}}
+	}
+	replaced := false
+	for _, s := range steps {
+		if step, ok := s.(map[string]any); ok && step["value"] == value {
+			step["color"] = color
+			replaced = true
+		}
+	}
+	if !replaced {
+		steps = append(steps, map[string]any{"color": color, "value": value})
+	}
+	// The base step has no value and comes first.
+	stepValue := func(s any) (float64, bool) {
+		step, _ := s.(map[string]any)
+		v, ok := step["value"].(float64)
+		return v, ok
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		vi, oki := stepValue(steps[i])
+		vj, okj := stepValue(steps[j])
+		return !oki && okj || oki && okj && vi < vj
+	})
+	thresholds["steps"] = steps
+}
+
+// childObject returns the object in a field of m, creating it if needed.
+func childObject(m map[string]any, key string) map[string]any {
+	child, ok := m[key].(map[string]any)
+	if !ok {
+		child = map[string]any{}
+		m[key] = child
+	}
+	return child
+}
+
+// setDashboardField sets the field at a path made of field names and array
+// indexes, creating missing objects along the way.
+func setDashboardField(db map[string]any, path string, value any) error {
+	segments, err := parseProjectionPath(path)
+	if err != nil {
+		return err
+	}
+	var current any = db
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment.kind {
+		case projectKey:
+			m, ok := current.(map[string]any)
+			if !ok {
+				return fmt.Errorf("cannot set %q: the parent of %q is not an object", path, segment.key)
+			}
+			if last {
+				m[segment.key] = value
+				return nil
+			}
+			if _, ok := m[segment.key]; !ok && segments[i+1].kind == projectKey {
+				m[segment.key] = map[string]any{}
+			}
+			current = m[segment.key]
+		case projectIndex:
+			a, ok := current.([]any)
+			if !ok || segment.index >= len(a) {
+				return fmt.Errorf("cannot set %q: index %d is out of range", path, segment.index)
+			}
+			if last {
+				a[segment.index] = value
+				return nil
+			}
+			current = a[segment.index]
+		default:
+			return fmt.Errorf("cannot set %q: only field names and array indexes are allowed", path)
+		}
+	}
+	return nil
+}
+
+// applyDashboardPatch applies an operation to a dashboard model, returning a
+// short description of it.
+func applyDashboardPatch(db map[string]any, op DashboardPatchOperation) (string, error) {
+	if op.Op == patchSet {
+		if op.Path == "" {
+			return "", fmt.Errorf("path is required")
+		}
+		if root, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(op.Path, "$"), "."), "."); root == "uid" || root == "id" || root == "version" {
+			return "", fmt.Errorf("the dashboard's %s can't be changed", root)
+		}
+		return "set " + op.Path, setDashboardField(db, op.Path, op.Value)
+	}
+
+	panel, err := findDashboardPanel(db, op.PanelID)
+	if err != nil {
+		return "", err
+	}
+	switch op.Op {
+	case patchSetPanelTitle:
+		if op.Title == "" {
+			return "", fmt.Errorf("title is required")
+		}
+		panel["title"] = op.Title
+		return fmt.Sprintf("renamed panel %d", op.PanelID), nil
+	case patchSetQuery:
+		if op.Expr == "" {
+			return "", fmt.Errorf("expr is required")
+		}
+		return fmt.Sprintf("changed query of panel %d", op.PanelID), setPanelQuery(panel, op.RefID, op.Expr)
+	case patchAddThreshold:
+		value, ok := op.Value.(float64)
+		if !ok {
+			return "", fmt.Errorf("value must be a number")
+		}
+		if op.Color == "" {
+			return "", fmt.Errorf("color is required")
+		}
+		addPanelThreshold(panel, value, op.Color)
+		return fmt.Sprintf("added threshold %g to panel %d", value, op.PanelID), nil
+	case patchMovePanel:
+		if op.GridPos == nil {
+			return "", fmt.Errorf("gridPos is required")
+		}
+		pos := op.GridPos
+		switch {
+		case pos.X != nil && (*pos.X < 0 || *pos.X > 23):
+			return "", fmt.Errorf("x must be between 0 and 23")
+		case pos.W != nil && (*pos.W < 1 || *pos.W > 24):
+			return "", fmt.Errorf("w must be between 1 and 24")
+		case pos.Y != nil && *pos.Y < 0, pos.H != nil && *pos.H < 1:
+			return "", fmt.Errorf("y must not be negative and h must be positive")
+		}
+		gridPos := childObject(panel, "gridPos")
+		for field, v := range map[string]*int{"x": pos.X, "y": pos.Y, "w": pos.W, "h": pos.H} {
+			if v != nil {
+				gridPos[field] = float64(*v)
+			}
+		}
+		return fmt.Sprintf("moved panel %d", op.PanelID), nil
+	}
+	return "", fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// copyDashboard returns a deep copy of a dashboard model.
+func copyDashboard(db map[string]any) (map[string]any, error) {
+	data, err := json.Marshal(db)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	return result, json.Unmarshal(data, &result)
+}
+
+func patchDashboard(ctx context.Context, args PatchDashboardParams) (*dashboardPatchResult, error) {
+	if len(args.Operations) == 0 {
+		return nil, fmt.Errorf("at least one operation is required")
+	}
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	before, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+
+	after, err := copyDashboard(before)
+	if err != nil {
+		return nil, fmt.Errorf("copy dashboard: %w", err)
+	}
+	var applied []string
+	for i, op := range args.Operations {
+		description, err := applyDashboardPatch(after, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i+1, op.Op, err)
+		}
+		applied = append(applied, description)
+	}
+
+	version, _ := before["version"].(float64)
+	result := &dashboardPatchResult{UID: args.UID, Diff: diffDashboards(before, after)}
+	result.Diff.UID = args.UID
+	result.Diff.From = dashboardVersionRef{Version: int64(version)}
+	if args.DryRun {
+		return result, nil
+	}
+
+	message := args.Message
+	if message == "" {
+		message = "Patch: " + strings.Join(applied, ", ")
+	}
+	folderUID := ""
+	if dashboard.Meta != nil {
+		folderUID = dashboard.Meta.FolderUID
+	}
+	// The dashboard keeps its version, so saving fails if it changed since
+	// it was read rather than overwriting the change.
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	saved, err := c.Dashboards.PostDashboard(&models.SaveDashboardCommand{
+		Dashboard: after,
+		FolderUID: folderUID,
+		Message:   message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save dashboard %s: %w", args.UID, err)
+	}
+	result.Saved = true
+	if saved.Payload.URL != nil {
+		result.URL = *saved.Payload.URL
+	}
+	if saved.Payload.Version != nil {
+		result.Version = *saved.Payload.Version
+		result.Diff.To = dashboardVersionRef{Version: result.Version, Message: message}
+	}
+	return result, nil
+}
+
+var PatchDashboard = mcpgrafana.MustTool(
+	"patch_dashboard",
+	"Apply targeted changes to an existing dashboard and save it as a new version, without sending the whole dashboard JSON. Operations, applied in order: `set_panel_title` (panelId, title), `set_query` (panelId, refId, expr), `add_threshold` (panelId, value, color), `move_panel` (panelId, gridPos with any of x, y, w, h) and `set` (path, value) for any other dashboard field, such as `time.from` or `panels[0].options.legend.showLegend`. Panels are found by ID, including panels in collapsed rows; use get_dashboard_by_uid with `fields` to find them. Saving fails if the dashboard changed since it was read. Returns the diff of the changes, and the new version and URL unless `dryRun` is set.",
+	patchDashboard,
+	mcp.WithTitleAnnotation("Patch dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
@@ -0,0 +1,135 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const patchTestDashboard = `{
+	"uid": "svc", "title": "Service", "version": 5, "refresh": "1m",
+	"panels": [
+		{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+			"targets": [{"refId": "A", "expr": "rate(http_requests_total[5m])"}]},
+		{"id": 2, "type": "row", "title": "Details", "panels": [
+			{"id": 3, "type": "stat", "title": "Errors", "targets": [{"refId": "A", "expr": "errors"}, {"refId": "B", "expr": "warnings"}],
+				"fieldConfig": {"defaults": {"thresholds": {"mode": "absolute", "steps": [{"color": "green", "value": null}, {"color": "red", "value": 80}]}}}},
+			{"id": 4, "type": "table", "title": "Orders", "targets": [{"refId": "A", "rawSql": "SELECT 1"}]}
+		]}
+	]
+}`
+
+func patchTestModel(t *testing.T) map[string]any {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(patchTestDashboard), &db))
+	return db
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestApplyDashboardPatch(t *testing.T) {
+	db := patchTestModel(t)
+	for _, op := range []DashboardPatchOperation{
+		{Op: patchSetPanelTitle, PanelID: 1, Title: "Request rate"},
+		{Op: patchSetQuery, PanelID: 1, Expr: "sum(rate(http_requests_total[5m]))"},
+		{Op: patchSetQuery, PanelID: 3, RefID: "B", Expr: "warnings_total"},
+		{Op: patchSetQuery, PanelID: 4, Expr: "SELECT 2"},
+		{Op: patchAddThreshold, PanelID: 3, Value: 50.0, Color: "orange"},
+		{Op: patchAddThreshold, PanelID: 3, Value: 80.0, Color: "dark-red"},
+		{Op: patchAddThreshold, PanelID: 1, Value: 10.0, Color: "red"},
+		{Op: patchMovePanel, PanelID: 1, GridPos: &dashboardGridPos{X: intPtr(12), W: intPtr(6)}},
+		{Op: patchSet, Path: "time.from", Value: "now-24h"},
+		{Op: patchSet, Path: "panels[0].options.legend.showLegend", Value: false},
+	} {
+		_, err := applyDashboardPatch(db, op)
+		require.NoError(t, err, op.Op)
+	}
+
+	panel, err := findDashboardPanel(db, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Request rate", panel["title"])
+	assert.Equal(t, "sum(rate(http_requests_total[5m]))", panel["targets"].([]any)[0].(map[string]any)["expr"])
+	assert.Equal(t, map[string]any{"x": 12.0, "y": 0.0, "w": 6.0, "h": 8.0}, panel["gridPos"])
+	assert.Equal(t, map[string]any{"legend": map[string]any{"showLegend": false}}, panel["options"])
+	assert.Equal(t, map[string]any{"mode": "absolute", "steps": []any{
+		map[string]any{"color": "green", "value": nil},
+		map[string]any{"color": "red", "value": 10.0},
+	}}, panel["fieldConfig"].(map[string]any)["defaults"].(map[string]any)["thresholds"])
+
+	nested, err := findDashboardPanel(db, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "warnings_total", nested["targets"].([]any)[1].(map[string]any)["expr"])
+	assert.Equal(t, []any{
+		map[string]any{"color": "green", "value": nil},
+		map[string]any{"color": "orange", "value": 50.0},
+		map[string]any{"color": "dark-red", "value": 80.0},
+	}, nested["fieldConfig"].(map[string]any)["defaults"].(map[string]any)["thresholds"].(map[string]any)["steps"])
+	table, err := findDashboardPanel(db, 4)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 2", table["targets"].([]any)[0].(map[string]any)["rawSql"])
+	assert.Equal(t, map[string]any{"from": "now-24h"}, db["time"])
+
+	for _, op := range []DashboardPatchOperation{
+		{Op: patchSetPanelTitle, PanelID: 9, Title: "x"},
+		{Op: patchSetPanelTitle, PanelID: 1},
+		{Op: patchSetQuery, PanelID: 3, Expr: "x"},
+		{Op: patchSetQuery, PanelID: 3, RefID: "Z", Expr: "x"},
+		{Op: patchAddThreshold, PanelID: 3, Value: "high", Color: "red"},
+		{Op: patchMovePanel, PanelID: 1, GridPos: &dashboardGridPos{W: intPtr(30)}},
+		{Op: patchMovePanel, PanelID: 1},
+		{Op: patchSet, Path: "uid", Value: "other"},
+		{Op: patchSet, Path: "panels[*].title", Value: "x"},
+		{Op: patchSet, Path: "panels[7].title", Value: "x"},
+		{Op: "delete_panel", PanelID: 1},
+	} {
+		_, err := applyDashboardPatch(db, op)
+		assert.Error(t, err, op)
+	}
+}
+
+func TestPatchDashboard(t *testing.T) {
+	var saved map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard":` + patchTestDashboard + `,"meta":{"folderUid":"services"}}`))
+	})
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1,"uid":"svc","url":"/d/svc/service","status":"success","version":6}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	ops := []DashboardPatchOperation{{Op: patchSetPanelTitle, PanelID: 3, Title: "Error count"}, {Op: patchSet, Path: "refresh", Value: "5m"}}
+	result, err := patchDashboard(ctx, PatchDashboardParams{UID: "svc", Operations: ops, DryRun: true})
+	require.NoError(t, err)
+	assert.False(t, result.Saved)
+	assert.Nil(t, saved)
+	assert.Equal(t, []dashboardFieldChange{{Field: "refresh", Before: "1m", After: "5m"}}, result.Diff.Changes)
+	require.Len(t, result.Diff.PanelsChanged, 1)
+	assert.Equal(t, "Error count", result.Diff.PanelsChanged[0].Title)
+
+	result, err = patchDashboard(ctx, PatchDashboardParams{UID: "svc", Operations: ops})
+	require.NoError(t, err)
+	assert.True(t, result.Saved)
+	assert.Equal(t, int64(6), result.Version)
+	assert.Equal(t, "/d/svc/service", result.URL)
+	assert.Equal(t, "services", saved["folderUid"])
+	assert.Equal(t, "Patch: renamed panel 3, set refresh", saved["message"])
+	assert.Nil(t, saved["overwrite"])
+	dashboard := saved["dashboard"].(map[string]any)
+	assert.Equal(t, 5.0, dashboard["version"])
+	assert.Equal(t, "5m", dashboard["refresh"])
+
+	_, err = patchDashboard(ctx, PatchDashboardParams{UID: "svc"})
+	assert.Error(t, err)
+	_, err = patchDashboard(ctx, PatchDashboardParams{UID: "svc", Operations: []DashboardPatchOperation{{Op: patchSetPanelTitle, PanelID: 42, Title: "x"}}})
+	assert.ErrorContains(t, err, "operation 1 (set_panel_title): panel 42 not found")
+}
@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// projectionSegmentKind is the kind of a step in a dashboard field path.
+type projectionSegmentKind int
+
+const (
+	// projectKey selects an object field.
+	projectKey projectionSegmentKind = iota
+	// projectIndex selects an array element.
+	projectIndex
+	// projectWildcard selects every object field or array element.
+	projectWildcard
+	// projectDescendant selects an object field at any depth.
+	projectDescendant
+)
+
+type projectionSegment struct {
+	kind  projectionSegmentKind
+	key   string
+	index int
+}
+
+// projectionMiss marks array elements which none of the paths matched, so
+// that projections of the same array by several paths line up by index
+// before the unmatched elements are dropped.
+type projectionMiss struct{}
+
+// parseProjectionPath parses a JSONPath-style field path, such as
+// `panels[*].targets[*].expr`, `$.templating.list[0].name` or `$..expr`.
+// The leading `$` is optional.
+func parseProjectionPath(path string) ([]projectionSegment, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if s == "" {
+		return nil, fmt.Errorf("invalid field path %q: no fields selected", path)
+	}
+	var segments []projectionSegment
+	for first := true; s != ""; first = false {
+		kind := projectKey
+		switch {
+		case strings.HasPrefix(s, ".."):
+			kind, s = projectDescendant, s[2:]
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+		case strings.HasPrefix(s, "["):
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field path %q: unclosed '['", path)
+			}
+			segment, err := parseProjectionBracket(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid field path %q: %w", path, err)
+			}
+			segments = append(segments, segment)
+			s = s[end+1:]
+			continue
+		case !first:
+			return nil, fmt.Errorf("invalid field path %q: expected '.' or '[' before %q", path, s)
+		}
+
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		name := s[:end]
+		s = s[end:]
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("invalid field path %q: empty field name", path)
+		case name == "*" && kind == projectDescendant:
+			return nil, fmt.Errorf("invalid field path %q: '..' must be followed by a field name", path)
+		case name == "*":
+			segments = append(segments, projectionSegment{kind: projectWildcard})
+		default:
+			segments = append(segments, projectionSegment{kind: kind, key: name})
+		}
+	}
+	return segments, nil
+}
+
+// parseProjectionBracket parses the contents of a bracketed path segment:
+// `*`, an array index, or a quoted field name.
+func parseProjectionBracket(s string) (projectionSegment, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "*":
+		return projectionSegment{kind: projectWildcard}, nil
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return projectionSegment{kind: projectKey, key: s[1 : len(s)-1]}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return projectionSegment{}, fmt.Errorf("expected '*', an array index or a quoted field name in brackets, got %q", s)
+	}
+	return projectionSegment{kind: projectIndex, index: index}, nil
+}
+
+// projectDashboard returns a copy of a dashboard model with only the fields
+// selected by the given paths, keeping their place in the dashboard so that,
+// for example, the titles and queries of a panel stay together. Array
+// elements which no path selects are dropped.
+func projectDashboard(db map[string]any, paths []string) (map[string]any, error) {
+	var result any = map[string]any{}
+	for _, path := range paths {
+		segments, err := parseProjectionPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if projected, ok := projectValue(db, segments); ok {
+			result = mergeProjections(result, projected)
+		}
+	}
+	projected, _ := compactProjection(result).(map[string]any)
+	return projected, nil
+}
+
+// projectValue returns the parts of v selected by the path segments, and
+// whether anything was selected.
+func projectValue(v any, segments []projectionSegment) (any, bool) {
+	if len(segments) == 0 {
+		return v, true
+	}
+	segment, rest := segments[0], segments[1:]
+	switch segment.kind {
+	case projectKey:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		child, ok := m[segment.key]
+		if !ok {
+			return nil, false
+		}
+		projected, ok := projectValue(child, rest)
+		if !ok {
+			return nil, false
+		}
+		return map[string]any{segment.key: projected}, true
+	case projectIndex:
+		a, ok := v.([]any)
+		if !ok || segment.index >= len(a) {
+			return nil, false
+		}
+		projected, ok := projectValue(a[segment.index], rest)
+		if !ok {
+			return nil, false
+		}
+		result := projectionMisses(len(a))
+		result[segment.index] = projected
+		return result, true
+	case projectWildcard:
+		return projectChildren(v, rest)
+	case projectDescendant:
+		// Select the field here, and anywhere below.
+		result, found := projectValue(v, append([]projectionSegment{{kind: projectKey, key: segment.key}}, rest...))
+		if below, ok := projectChildren(v, segments); ok {
+			result, found = mergeProjections(result, below), true
+		}
+		return result, found
+	}
+	return nil, false
+}
+
+// projectChildren projects every field of an object or element of an array
+// by the path segments.
+func projectChildren(v any, segments []projectionSegment) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		result := map[string]any{}
+		for key, child := range v {
+			if projected, ok := projectValue(child, segments); ok {
+				result[key] = projected
+			}
+		}
+		return result, len(result) > 0
+	case []any:
+		result := projectionMisses(len(v))
+		found := false
+		for i, child := range v {
+			if projected, ok := projectValue(child, segments); ok {
+				result[i], found = projected, true
+			}
+		}
+		return result, found
+	}
+	return nil, false
+}
+
+func projectionMisses(n int) []any {
+	result := make([]any, n)
+	for i := range result {
+		result[i] = projectionMiss{}
+	}
+	return result
+}
+
+// mergeProjections merges two projections of the same value.
+func mergeProjections(a, b any) any {
+	switch a := a.(type) {
+	case nil:
+		return b
+	case projectionMiss:
+		return b
+	case map[string]any:
+		bm, ok := b.(map[string]any)
+		if !ok {
+			return a
+		}
+		for key, v := range bm {
+			if existing, ok := a[key]; ok {
+				a[key] = mergeProjections(existing, v)
+			} else {
+				a[key] = v
+			}
+		}
+		return a
+	case []any:
+		ba, ok := b.([]any)
+		if !ok || len(ba) != len(a) {
+			return a
+		}
+		for i := range a {
+			a[i] = mergeProjections(a[i], ba[i])
+		}
+		return a
+	}
+	return a
+}
+
+// compactProjection drops the array elements no path selected.
+func compactProjection(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = compactProjection(child)
+		}
+		return v
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, child := range v {
+			if _, miss := child.(projectionMiss); !miss {
+				result = append(result, compactProjection(child))
+			}
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const projectionTestDashboard = `{
+	"title": "Service",
+	"uid": "svc",
+	"templating": {"list": [{"name": "job", "query": "label_values(job)"}, {"name": "instance"}]},
+	"panels": [
+		{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0}, "targets": [{"refId": "A", "expr": "rate(http_requests_total[5m])"}]},
+		{"id": 2, "type": "text", "title": "Notes", "options": {"content": "long text"}},
+		{"id": 3, "type": "row", "title": "Details", "panels": [
+			{"id": 4, "type": "stat", "title": "Errors", "targets": [{"refId": "A", "expr": "sum(errors_total)"}]}
+		]}
+	]
+}`
+
+func TestProjectDashboard(t *testing.T) {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(projectionTestDashboard), &db))
+
+	for _, tc := range []struct {
+		name   string
+		paths  []string
+		expect string
+	}{
+		{
+			name:   "panel titles, types and queries",
+			paths:  []string{"title", "panels[*].title", "panels[*].type", "$.panels[*].targets[*].expr"},
+			expect: `{"title":"Service","panels":[{"title":"Requests","type":"timeseries","targets":[{"expr":"rate(http_requests_total[5m])"}]},{"title":"Notes","type":"text"},{"title":"Details","type":"row"}]}`,
+		},
+		{
+			name:   "array index and quoted field",
+			paths:  []string{"templating.list[1]['name']", "panels[2].panels[0].title"},
+			expect: `{"templating":{"list":[{"name":"instance"}]},"panels":[{"panels":[{"title":"Errors"}]}]}`,
+		},
+		{
+			name:   "descendants",
+			paths:  []string{"$..expr"},
+			expect: `{"panels":[{"targets":[{"expr":"rate(http_requests_total[5m])"}]},{"panels":[{"targets":[{"expr":"sum(errors_total)"}]}]}]}`,
+		},
+		{
+			name:   "whole subtree with a field of it",
+			paths:  []string{"templating", "templating.list[*].name"},
+			expect: `{"templating":{"list":[{"name":"job","query":"label_values(job)"},{"name":"instance"}]}}`,
+		},
+		{
+			name:   "no matches",
+			paths:  []string{"missing", "panels[9].title", "title.nested"},
+			expect: `{}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			projected, err := projectDashboard(db, tc.paths)
+			require.NoError(t, err)
+			actual, err := json.Marshal(projected)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(actual))
+		})
+	}
+
+	// The dashboard itself is left unchanged.
+	var original map[string]any
+	require.NoError(t, json.Unmarshal([]byte(projectionTestDashboard), &original))
+	assert.Equal(t, original, db)
+}
+
+func TestParseProjectionPath(t *testing.T) {
+	segments, err := parseProjectionPath("$.panels[*].targets[0]..expr")
+	require.NoError(t, err)
+	assert.Equal(t, []projectionSegment{
+		{kind: projectKey, key: "panels"},
+		{kind: projectWildcard},
+		{kind: projectKey, key: "targets"},
+		{kind: projectIndex, index: 0},
+		{kind: projectDescendant, key: "expr"},
+	}, segments)
+
+	for _, path := range []string{"", "$", "panels[", "panels[-1]", "panels[x]", "panels..", "..*", "a..b.", "panels[0]title"} {
+		_, err := parseProjectionPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestGetDashboardByUIDFields(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard":` + projectionTestDashboard + `,"meta":{"folderTitle":"Services"}}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: "svc", Fields: []string{"panels[*].title"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"panels": []any{
+		map[string]any{"title": "Requests"},
+		map[string]any{"title": "Notes"},
+		map[string]any{"title": "Details"},
+	}}, result.Dashboard)
+	assert.Equal(t, "Services", result.Meta.FolderTitle)
+
+	result, err = getDashboardByUID(ctx, GetDashboardByUIDParams{UID: "svc"})
+	require.NoError(t, err)
+	assert.Len(t, result.Dashboard.(map[string]any)["panels"], 3)
+
+	_, err = getDashboardByUID(ctx, GetDashboardByUIDParams{UID: "svc", Fields: []string{"panels["}})
+	assert.ErrorContains(t, err, "invalid field path")
+}
@@ -0,0 +1,72 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDashboardRequest(t *testing.T) {
+	var saved map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":7,"uid":"svc","url":"/d/svc/service","status":"success","version":1}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := updateDashboard(ctx, UpdateDashboardParams{
+		Dashboard: map[string]any{"title": "Service", "panels": []any{}},
+		FolderUID: "services",
+		Message:   "Add service dashboard",
+		Overwrite: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "svc", *result.UID)
+	assert.Equal(t, "/d/svc/service", *result.URL)
+	assert.Equal(t, "services", saved["folderUid"])
+	assert.Equal(t, "Add service dashboard", saved["message"])
+	assert.Equal(t, true, saved["overwrite"])
+	assert.Equal(t, "Service", saved["dashboard"].(map[string]any)["title"])
+
+	_, err = updateDashboard(ctx, UpdateDashboardParams{Dashboard: map[string]any{"panels": []any{}}})
+	assert.ErrorContains(t, err, "title")
+	_, err = updateDashboard(ctx, UpdateDashboardParams{Dashboard: map[string]any{"title": "x"}, FolderUID: "../admin"})
+	assert.Error(t, err)
+}
+
+func TestGetDashboardPanelQueries(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard":{"title":"Service","panels":[
+			{"id":1,"title":"Requests","datasource":{"uid":"prom","type":"prometheus"},"targets":[
+				{"refId":"A","expr":"sum(rate(http_requests_total[5m]))","format":"time_series"},
+				{"refId":"B","expr":"count_over_time({job=\"api\"}[5m])","datasource":{"uid":"loki","type":"loki"}}
+			]},
+			{"id":2,"type":"text","title":"Notes"},
+			{"id":3,"type":"row","title":"Details","panels":[
+				{"id":4,"title":"Slow traces","datasource":{"uid":"tempo","type":"tempo"},"targets":[{"refId":"A","query":"{duration > 1s}"}]},
+				{"id":5,"title":"Orders","datasource":{"uid":"pg","type":"grafana-postgresql-datasource"},"targets":[{"refId":"A","rawSql":"SELECT count(*) FROM orders","format":"table"}]}
+			]}
+		]}}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := GetDashboardPanelQueriesTool(ctx, DashboardPanelQueriesParams{UID: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []panelQuery{
+		{Title: "Requests", PanelID: 1, RefID: "A", Query: "sum(rate(http_requests_total[5m]))", Format: "time_series", Datasource: datasourceInfo{UID: "prom", Type: "prometheus"}},
+		{Title: "Requests", PanelID: 1, RefID: "B", Query: `count_over_time({job="api"}[5m])`, Datasource: datasourceInfo{UID: "loki", Type: "loki"}},
+		{Title: "Slow traces", PanelID: 4, RefID: "A", Query: "{duration > 1s}", Datasource: datasourceInfo{UID: "tempo", Type: "tempo"}},
+		{Title: "Orders", PanelID: 5, RefID: "A", Query: "SELECT count(*) FROM orders", Format: "table", Datasource: datasourceInfo{UID: "pg", Type: "grafana-postgresql-datasource"}},
+	}, result)
+}
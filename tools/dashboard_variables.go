@@ -0,0 +1,568 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	DefaultVariableValuesLimit = 100
+	MaxVariableValuesLimit     = 1000
+
+	// allVariableValue is the current value of a variable with "All"
+	// selected.
+	allVariableValue = "$__all"
+)
+
+type GetDashboardVariableValuesParams struct {
+	UID       string            `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	Variables map[string]string `json:"variables,omitempty" jsonschema:"description=Optionally\\, values to select for variables by name\\, instead of their saved values\\, e.g. {'namespace': 'prod'}. Variables depending on them are evaluated with these values. Use '$__all' to select All"`
+	StartTime string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range to evaluate query variables over\\, in RFC3339\\, Unix time or relative time such as 'now-6h'. Defaults to the dashboard's time range"`
+	EndTime   string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range to evaluate query variables over. Defaults to the dashboard's time range"`
+	Limit     int               `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of possible values to return per variable (default: 100\\, max: 1000)"`
+}
+
+// dashboardVariableValues are the possible and current values of a template
+// variable.
+type dashboardVariableValues struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Label      string          `json:"label,omitempty"`
+	Datasource *datasourceInfo `json:"datasource,omitempty"`
+	// Query is the variable's query, with the variables it depends on
+	// replaced by their current values.
+	Query string `json:"query,omitempty"`
+	// Current holds the selected values, which replace the variable in
+	// queries. With All selected, it holds all values or the variable's
+	// custom all value.
+	Current     []string `json:"current"`
+	All         bool     `json:"all,omitempty"`
+	Multi       bool     `json:"multi,omitempty"`
+	IncludeAll  bool     `json:"includeAll,omitempty"`
+	Values      []string `json:"values"`
+	TotalValues int      `json:"totalValues,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type dashboardVariableResult struct {
+	Start     time.Time                 `json:"start"`
+	End       time.Time                 `json:"end"`
+	Variables []dashboardVariableValues `json:"variables"`
+}
+
+// variableResolver evaluates the template variables of a dashboard in order,
+// so that variables can depend on the current values of earlier ones.
+type variableResolver struct {
+	ctx         context.Context
+	start, end  time.Time
+	current     map[string][]string
+	datasources models.DataSourceList
+}
+
+// interpolate replaces the variables resolved so far in s, along with the
+// time range variables, formatting multiple values as Grafana does for
+// Prometheus and Loki queries unless a format such as `${var:csv}` is given.
+// Unknown variables are left as they are.
+func (r *variableResolver) interpolate(s string) string {
+	rangeDuration := r.end.Sub(r.start)
+	return templateVariableRegex.ReplaceAllStringFunc(s, func(v string) string {
+		name, format, _ := strings.Cut(strings.Trim(v, "$[]{}"), ":")
+		switch name {
+		case "__range":
+			return strconv.FormatInt(int64(rangeDuration.Seconds()), 10) + "s"
+		case "__range_s":
+			return strconv.FormatInt(int64(rangeDuration.Seconds()), 10)
+		case "__range_ms":
+			return strconv.FormatInt(rangeDuration.Milliseconds(), 10)
+		case "__from":
+			return strconv.FormatInt(r.start.UnixMilli(), 10)
+		case "__to":
+			return strconv.FormatInt(r.end.UnixMilli(), 10)
+		}
+		values, ok := r.current[name]
+		if !ok {
+			return v
+		}
+		return formatVariableValues(values, format)
+	})
+}
+
+// formatVariableValues formats the values of a variable for a query.
+func formatVariableValues(values []string, format string) string {
+	switch format {
+	case "csv", "raw":
+		return strings.Join(values, ",")
+	case "pipe":
+		return strings.Join(values, "|")
+	case "json":
+		b, _ := json.Marshal(values)
+		return string(b)
+	case "regex":
+		escaped := make([]string, len(values))
+		for i, v := range values {
+			escaped[i] = regexp.QuoteMeta(v)
+		}
+		if len(escaped) == 1 {
+			return escaped[0]
+		}
+		return "(" + strings.Join(escaped, "|") + ")"
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return formatVariableValues(values, "regex")
+}
+
+// resolveDatasource finds the datasource of a variable query, which may be
+// referenced by UID, by name, by a datasource variable, or not at all for
+// the default datasource.
+func (r *variableResolver) resolveDatasource(ref any) (*models.DataSourceListItemDTO, error) {
+	datasources, err := r.listDatasources()
+	if err != nil {
+		return nil, err
+	}
+	uid := r.interpolate(parseDatasourceRef(ref).UID)
+	for _, ds := range datasources {
+		if uid == "" && ds.IsDefault || uid != "" && (ds.UID == uid || ds.Name == uid) {
+			return ds, nil
+		}
+	}
+	if uid == "" {
+		return nil, fmt.Errorf("no default datasource")
+	}
+	return nil, fmt.Errorf("datasource %q not found", uid)
+}
+
+// listDatasources lists the datasources of the Grafana instance once.
+func (r *variableResolver) listDatasources() (models.DataSourceList, error) {
+	if r.datasources == nil {
+		resp, err := mcpgrafana.GrafanaClientFromContext(r.ctx).Datasources.GetDataSources()
+		if err != nil {
+			return nil, fmt.Errorf("list datasources: %w", err)
+		}
+		r.datasources = resp.Payload
+	}
+	return r.datasources, nil
+}
+
+// variableQuery returns the query of a query variable, which is either a
+// string or, in newer dashboards, an object holding the query. Loki label
+// queries are converted to their string form.
+func variableQuery(v map[string]any) string {
+	switch q := v["query"].(type) {
+	case string:
+		return q
+	case map[string]any:
+		if query, ok := q["query"].(string); ok && query != "" {
+			return query
+		}
+		label, _ := q["label"].(string)
+		stream, _ := q["stream"].(string)
+		switch t, _ := q["type"].(float64); {
+		case t == 0 && label == "":
+			return "label_names()"
+		case stream != "":
+			return fmt.Sprintf("label_values(%s, %s)", stream, label)
+		default:
+			return fmt.Sprintf("label_values(%s)", label)
+		}
+	}
+	return ""
+}
+
+var (
+	labelNamesQueryRegex  = regexp.MustCompile(`^label_names\(\s*(.*?)\s*\)$`)
+	labelValuesQueryRegex = regexp.MustCompile(`^label_values\(\s*(?:(.+)\s*,\s*)?([a-zA-Z_][a-zA-Z0-9_.]*)\s*\)$`)
+	metricsQueryRegex     = regexp.MustCompile(`^metrics\(\s*(.*?)\s*\)$`)
+	queryResultRegex      = regexp.MustCompile(`^query_result\(\s*(.+)\s*\)$`)
+
+	// customVariableValueRegex matches the values of a custom variable,
+	// separated by unescaped commas.
+	customVariableValueRegex = regexp.MustCompile(`(?:\\,|[^,])+`)
+)
+
+// prometheusVariableValues evaluates a Prometheus query variable. Queries
+// not using one of the label_names, label_values or metrics functions are
+// run as instant queries, as with query_result, each sample becoming a value
+// of the form `metric{labels} value timestamp`.
+func (r *variableResolver) prometheusVariableValues(uid, query string) ([]string, error) {
+	promClient, err := promClientFromContext(r.ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if m := labelNamesQueryRegex.FindStringSubmatch(query); m != nil {
+		var matches []string
+		if selector := strings.TrimSpace(m[1]); selector != "" {
+			matches = []string{selector}
+		}
+		names, _, err := promClient.LabelNames(r.ctx, matches, r.start, r.end)
+		return names, err
+	}
+	if m := labelValuesQueryRegex.FindStringSubmatch(query); m != nil {
+		var matches []string
+		if selector := strings.TrimSpace(m[1]); selector != "" {
+			matches = []string{selector}
+		}
+		values, _, err := promClient.LabelValues(r.ctx, m[2], matches, r.start, r.end)
+		return labelValueStrings(values), err
+	}
+	if m := metricsQueryRegex.FindStringSubmatch(query); m != nil {
+		re, err := regexp.Compile(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics regex %q: %w", m[1], err)
+		}
+		names, _, err := promClient.LabelValues(r.ctx, model.MetricNameLabel, nil, r.start, r.end)
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, name := range labelValueStrings(names) {
+			if re.MatchString(name) {
+				values = append(values, name)
+			}
+		}
+		return values, nil
+	}
+	if m := queryResultRegex.FindStringSubmatch(query); m != nil {
+		query = m[1]
+	}
+	result, _, err := promClient.Query(r.ctx, query, r.end)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	switch result := result.(type) {
+	case model.Vector:
+		for _, s := range result {
+			values = append(values, fmt.Sprintf("%s %s %d", s.Metric, s.Value, s.Timestamp))
+		}
+	case *model.Scalar:
+		values = append(values, fmt.Sprintf("%s %d", result.Value, result.Timestamp))
+	default:
+		return nil, fmt.Errorf("query_result needs an instant vector or scalar, got a %s", result.Type())
+	}
+	return values, nil
+}
+
+func labelValueStrings(values model.LabelValues) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = string(v)
+	}
+	return result
+}
+
+// lokiVariableValues evaluates a Loki query variable listing label names or
+// values.
+func (r *variableResolver) lokiVariableValues(uid, query string) ([]string, error) {
+	start, end := r.start.Format(time.RFC3339), r.end.Format(time.RFC3339)
+	if m := labelNamesQueryRegex.FindStringSubmatch(query); m != nil {
+		return listLokiLabelNames(r.ctx, ListLokiLabelNamesParams{DatasourceUID: uid, Selector: m[1], StartRFC3339: start, EndRFC3339: end})
+	}
+	if m := labelValuesQueryRegex.FindStringSubmatch(query); m != nil {
+		return listLokiLabelValues(r.ctx, ListLokiLabelValuesParams{DatasourceUID: uid, LabelName: m[2], Selector: strings.TrimSpace(m[1]), StartRFC3339: start, EndRFC3339: end})
+	}
+	return nil, fmt.Errorf("unsupported Loki variable query %q: expected label_names() or label_values()", query)
+}
+
+// customVariableValues parses the comma-separated values of a custom or
+// interval variable. Commas can be escaped with a backslash, and values of
+// the form `text : value` are reduced to their value.
+func customVariableValues(query string) []string {
+	var values []string
+	for _, part := range customVariableValueRegex.FindAllString(query, -1) {
+		part = strings.TrimSpace(strings.ReplaceAll(part, `\,`, ","))
+		if _, value, ok := strings.Cut(part, " : "); ok {
+			part = strings.TrimSpace(value)
+		}
+		if part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// parseVariableRegex parses the regex of a query variable, written as
+// `/pattern/flags` or as a bare pattern.
+func parseVariableRegex(s string) (*regexp.Regexp, error) {
+	if len(s) > 1 && s[0] == '/' {
+		if end := strings.LastIndex(s, "/"); end > 0 {
+			pattern, flags := s[1:end], s[end+1:]
+			if strings.Contains(flags, "i") {
+				pattern = "(?i)" + pattern
+			}
+			s = pattern
+		}
+	}
+	return regexp.Compile(s)
+}
+
+// filterVariableValues keeps the values matching a variable's regex,
+// replacing each with its `value` named group or first capture group, if
+// any.
+func filterVariableValues(values []string, re *regexp.Regexp) []string {
+	var result []string
+	valueGroup := re.SubexpIndex("value")
+	for _, v := range values {
+		m := re.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+			continue
+		case valueGroup > 0:
+			v = m[valueGroup]
+		case len(m) > 1:
+			v = m[1]
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+// sortVariableValues sorts values as selected by a variable's sort option:
+// alphabetically (1 and 2), numerically (3 and 4, and natural sort 7 and 8),
+// or alphabetically ignoring case (5 and 6), with even options descending.
+func sortVariableValues(values []string, option int) {
+	if option <= 0 {
+		return
+	}
+	less := func(a, b string) bool { return a < b }
+	switch option {
+	case 3, 4, 7, 8:
+		less = func(a, b string) bool {
+			x, errX := strconv.ParseFloat(a, 64)
+			y, errY := strconv.ParseFloat(b, 64)
+			if errX == nil && errY == nil {
+				return x < y
+			}
+			return a < b
+		}
+	case 5, 6:
+		less = func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) }
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		if option%2 == 0 {
+			return less(values[j], values[i])
+		}
+		return less(values[i], values[j])
+	})
+}
+
+// dedupeValues removes repeated values, keeping their first occurrence.
+func dedupeValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// savedVariableValues returns the saved current values of a variable.
+func savedVariableValues(v map[string]any) []string {
+	current, _ := v["current"].(map[string]any)
+	switch value := current["value"].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// possibleValues evaluates the possible values of a variable.
+func (r *variableResolver) possibleValues(v map[string]any, result *dashboardVariableValues) ([]string, error) {
+	switch result.Type {
+	case "custom", "interval":
+		values := customVariableValues(variableQuery(v))
+		if auto, _ := v["auto"].(bool); auto && result.Type == "interval" {
+			values = append([]string{"$__auto_interval_" + result.Name}, values...)
+		}
+		return values, nil
+	case "constant", "textbox":
+		return []string{r.interpolate(variableQuery(v))}, nil
+	case "datasource":
+		datasources, err := r.listDatasources()
+		if err != nil {
+			return nil, err
+		}
+		// The regex selects datasources by name, while the values are UIDs.
+		var re *regexp.Regexp
+		if pattern := stringField(v, "regex"); pattern != "" {
+			if re, err = parseVariableRegex(r.interpolate(pattern)); err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+			}
+		}
+		dsType, _ := v["query"].(string)
+		var values []string
+		for _, ds := range datasources {
+			if ds.Type == dsType && (re == nil || re.MatchString(ds.Name)) {
+				values = append(values, ds.UID)
+			}
+		}
+		return values, nil
+	case "query":
+		ds, err := r.resolveDatasource(v["datasource"])
+		if err != nil {
+			return nil, err
+		}
+		result.Datasource = &datasourceInfo{UID: ds.UID, Type: ds.Type}
+		result.Query = r.interpolate(strings.TrimSpace(variableQuery(v)))
+		switch ds.Type {
+		case "prometheus":
+			return r.prometheusVariableValues(ds.UID, result.Query)
+		case "loki":
+			return r.lokiVariableValues(ds.UID, result.Query)
+		}
+		return nil, fmt.Errorf("query variables of %s datasources can't be evaluated", ds.Type)
+	case "adhoc":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported variable type %q", result.Type)
+}
+
+// resolve evaluates a variable and records its current values for the
+// variables after it.
+func (r *variableResolver) resolve(v map[string]any, selected map[string]string, limit int) dashboardVariableValues {
+	result := dashboardVariableValues{Name: stringField(v, "name"), Type: stringField(v, "type"), Label: stringField(v, "label")}
+	result.Multi, _ = v["multi"].(bool)
+	result.IncludeAll, _ = v["includeAll"].(bool)
+
+	values, err := r.possibleValues(v, &result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if pattern := stringField(v, "regex"); pattern != "" && result.Type == "query" && err == nil {
+		re, err := parseVariableRegex(r.interpolate(pattern))
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid regex %q: %s", pattern, err)
+		} else {
+			values = filterVariableValues(values, re)
+		}
+	}
+	values = dedupeValues(values)
+	if sortOption, ok := v["sort"].(float64); ok {
+		sortVariableValues(values, int(sortOption))
+	}
+
+	current := savedVariableValues(v)
+	if value, ok := selected[result.Name]; ok {
+		current = []string{value}
+	} else if len(values) > 0 && !(len(current) == 1 && current[0] == allVariableValue) {
+		// As in Grafana, values which are no longer possible fall back to
+		// the first possible value.
+		for _, c := range current {
+			if !slices.Contains(values, c) {
+				current = values[:1]
+				break
+			}
+		}
+		if len(current) == 0 {
+			current = values[:1]
+		}
+	}
+	if len(current) == 1 && current[0] == allVariableValue {
+		result.All = true
+		current = values
+		if allValue := stringField(v, "allValue"); allValue != "" {
+			current = []string{allValue}
+		}
+	}
+	result.Current = append([]string{}, current...)
+	r.current[result.Name] = result.Current
+
+	result.Values = values
+	if len(values) > limit {
+		result.Values, result.TotalValues = values[:limit], len(values)
+	}
+	if result.Values == nil {
+		result.Values = []string{}
+	}
+	return result
+}
+
+// dashboardTimeRange returns the saved time range of a dashboard, which
+// defaults to the last 6 hours as in Grafana.
+func dashboardTimeRange(db map[string]any) (string, string) {
+	tr, _ := db["time"].(map[string]any)
+	from, _ := tr["from"].(string)
+	to, _ := tr["to"].(string)
+	return stringOrDefault(from, "now-6h"), stringOrDefault(to, "now")
+}
+
+// parseDashboardTimeRange parses a time range, each end of which defaults to
+// the dashboard's saved time range.
+func parseDashboardTimeRange(db map[string]any, startTime, endTime string) (time.Time, time.Time, error) {
+	from, to := dashboardTimeRange(db)
+	start, err := parseTime(stringOrDefault(startTime, from))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := parseTime(stringOrDefault(endTime, to))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start time %s must be before end time %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+func getDashboardVariableValues(ctx context.Context, args GetDashboardVariableValuesParams) (*dashboardVariableResult, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+
+	start, end, err := parseDashboardTimeRange(db, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultVariableValuesLimit
+	}
+	limit = min(limit, MaxVariableValuesLimit)
+
+	r := &variableResolver{ctx: ctx, start: start, end: end, current: map[string][]string{}}
+	variables, names := dashboardVariables(db)
+	result := &dashboardVariableResult{Start: start, End: end, Variables: []dashboardVariableValues{}}
+	for _, name := range names {
+		result.Variables = append(result.Variables, r.resolve(variables[name], args.Variables, limit))
+	}
+	return result, nil
+}
+
+var GetDashboardVariableValues = mcpgrafana.MustTool(
+	"get_dashboard_variable_values",
+	"Evaluate the template variables of a dashboard, returning the possible and current values of each, in dashboard order. Query variables are run against their Prometheus or Loki datasource (label_names, label_values, metrics and query_result queries) over the dashboard's time range, with the variables they depend on replaced by their current values; custom, interval, constant, textbox and datasource variables are evaluated locally. The variable's regex and sort order are applied. `current` holds the values which replace the variable in panel queries, falling back to the first possible value as in Grafana, and all values (or the custom all value) if All is selected. Set `variables` to select other values, for example to list the pods of another namespace. Use this before running panel queries that reference `$var`.",
+	getDashboardVariableValues,
+	mcp.WithTitleAnnotation("Get dashboard variable values"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,153 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const variablesTestDashboard = `{
+	"uid": "vars",
+	"title": "Variables",
+	"time": {"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T06:00:00Z"},
+	"templating": {"list": [
+		{"name": "ds", "type": "datasource", "query": "prometheus", "regex": "/^Prod/", "current": {"text": "Prod Prometheus", "value": "prom"}},
+		{"name": "namespace", "type": "query", "datasource": {"uid": "${ds}"}, "query": {"query": "label_values(kube_pod_info, namespace)", "refId": "A"},
+			"regex": "/^(?<value>prod-.*)/", "sort": 1, "current": {"text": "prod-old", "value": "prod-old"}},
+		{"name": "pod", "type": "query", "datasource": {"uid": "${ds}"}, "query": "label_values(kube_pod_info{namespace=~\"$namespace\"}, pod)",
+			"multi": true, "includeAll": true, "allValue": ".*", "current": {"text": "All", "value": ["$__all"]}},
+		{"name": "app", "type": "query", "datasource": {"type": "loki", "uid": "loki"}, "query": {"type": 1, "label": "app", "stream": "{namespace=\"$namespace\"}"},
+			"current": {"text": "api", "value": "api"}},
+		{"name": "interval", "type": "interval", "query": "1m,5m,1h", "auto": true, "current": {"value": "5m"}},
+		{"name": "env", "type": "custom", "query": "Production : prod, Staging : staging, a\\,b", "current": {"value": "staging"}},
+		{"name": "tempo", "type": "query", "datasource": {"uid": "tempo"}, "query": "service.name", "current": {"value": "checkout"}}
+	]}
+}`
+
+func TestGetDashboardVariableValues(t *testing.T) {
+	var promRequests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard":` + variablesTestDashboard + `}`))
+	})
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"uid": "prom", "name": "Prod Prometheus", "type": "prometheus", "isDefault": true},
+			{"uid": "prom-dev", "name": "Dev Prometheus", "type": "prometheus"},
+			{"uid": "loki", "name": "Loki", "type": "loki"},
+			{"uid": "tempo", "name": "Tempo", "type": "tempo"}
+		]`))
+	})
+	for _, uid := range []string{"prom", "loki"} {
+		mux.HandleFunc("/api/datasources/uid/"+uid, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uid":"` + uid + `","type":"` + uid + `"}`))
+		})
+	}
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/label/namespace/values", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		promRequests = append(promRequests, r.Form.Get("match[]"))
+		assert.Equal(t, "1704067200", r.Form.Get("start"))
+		assert.Equal(t, "1704088800", r.Form.Get("end"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":["prod-b","dev","prod-a"]}`))
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/label/pod/values", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		promRequests = append(promRequests, r.Form.Get("match[]"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":["api-1","api-2","web-1"]}`))
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/loki/loki/api/v1/label/app/values", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.URL.Query().Get("query"), `{namespace=`))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":["api","web"]}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := getDashboardVariableValues(ctx, GetDashboardVariableValuesParams{UID: "vars", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), result.End.UTC())
+	require.Len(t, result.Variables, 7)
+	byName := map[string]dashboardVariableValues{}
+	for _, v := range result.Variables {
+		byName[v.Name] = v
+	}
+
+	assert.Equal(t, []string{"prom"}, byName["ds"].Values)
+	assert.Equal(t, []string{"prom"}, byName["ds"].Current)
+
+	// The saved namespace is no longer possible, so the first value is used.
+	namespace := byName["namespace"]
+	assert.Equal(t, &datasourceInfo{UID: "prom", Type: "prometheus"}, namespace.Datasource)
+	assert.Equal(t, []string{"prod-a", "prod-b"}, namespace.Values)
+	assert.Equal(t, []string{"prod-a"}, namespace.Current)
+
+	pod := byName["pod"]
+	assert.Equal(t, `label_values(kube_pod_info{namespace=~"prod-a"}, pod)`, pod.Query)
+	assert.True(t, pod.All)
+	assert.Equal(t, []string{".*"}, pod.Current)
+	assert.Equal(t, []string{"api-1", "api-2"}, pod.Values)
+	assert.Equal(t, 3, pod.TotalValues)
+	assert.Equal(t, []string{"kube_pod_info", `kube_pod_info{namespace=~"prod-a"}`}, promRequests)
+
+	assert.Equal(t, `label_values({namespace="prod-a"}, app)`, byName["app"].Query)
+	assert.Equal(t, []string{"api", "web"}, byName["app"].Values)
+	assert.Equal(t, []string{"api"}, byName["app"].Current)
+
+	assert.Equal(t, []string{"$__auto_interval_interval", "1m"}, byName["interval"].Values)
+	assert.Equal(t, []string{"5m"}, byName["interval"].Current)
+	assert.Equal(t, []string{"prod", "staging"}, byName["env"].Values)
+	assert.Equal(t, 3, byName["env"].TotalValues)
+	assert.Equal(t, []string{"staging"}, byName["env"].Current)
+
+	tempo := byName["tempo"]
+	assert.Contains(t, tempo.Error, "tempo datasources can't be evaluated")
+	assert.Equal(t, []string{"checkout"}, tempo.Current)
+	assert.Empty(t, tempo.Values)
+
+	// Selected values are used for dependent variables.
+	promRequests = nil
+	result, err = getDashboardVariableValues(ctx, GetDashboardVariableValuesParams{UID: "vars", Variables: map[string]string{"namespace": "dev", "pod": "web-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev"}, result.Variables[1].Current)
+	assert.Equal(t, []string{"web-1"}, result.Variables[2].Current)
+	assert.False(t, result.Variables[2].All)
+	assert.Equal(t, `kube_pod_info{namespace=~"dev"}`, promRequests[1])
+}
+
+func TestVariableResolverInterpolate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &variableResolver{
+		start:   start,
+		end:     start.Add(time.Hour),
+		current: map[string][]string{"job": {"api"}, "pod": {"a.1", "b"}},
+	}
+	assert.Equal(t, `up{job="api",pod=~"(a\.1|b)"}[3600s]`, r.interpolate(`up{job="$job",pod=~"${pod}"}[$__range]`))
+	assert.Equal(t, "a.1,b a.1|b [[missing]] $other", r.interpolate("${pod:csv} ${pod:pipe} [[missing]] $other"))
+	assert.Equal(t, "1704067200000 3600", r.interpolate("$__from ${__range_s}"))
+}
+
+func TestSortVariableValues(t *testing.T) {
+	for option, expected := range map[int]string{
+		0: "b,10,a,2,B",
+		1: "10,2,B,a,b",
+		2: "b,a,B,2,10",
+		3: "2,10,B,a,b",
+		4: "b,a,B,10,2",
+		5: "10,2,a,b,B",
+	} {
+		values := strings.Split("b,10,a,2,B", ",")
+		sortVariableValues(values, option)
+		assert.Equal(t, expected, strings.Join(values, ","), option)
+	}
+}
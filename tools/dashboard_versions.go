@@ -0,0 +1,438 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	DefaultDashboardVersionsLimit = 20
+	MaxDashboardVersionsLimit     = 100
+)
+
+// dashboardVersion is a saved version of a dashboard, as listed by the
+// dashboard versions API. Data holds the dashboard model, and is only set
+// when fetching a single version.
+type dashboardVersion struct {
+	Version       int64          `json:"version"`
+	ParentVersion int64          `json:"parentVersion,omitempty"`
+	RestoredFrom  int64          `json:"restoredFrom,omitempty"`
+	Created       time.Time      `json:"created"`
+	CreatedBy     string         `json:"createdBy,omitempty"`
+	Message       string         `json:"message,omitempty"`
+	Data          map[string]any `json:"data,omitempty"`
+}
+
+type ListDashboardVersionsParams struct {
+	UID   string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of versions to return\\, most recent first (default: 20\\, max: 100)"`
+}
+
+// getDashboardVersions lists the most recent versions of a dashboard. Grafana
+// 12 wraps the list in an object with a continue token, while earlier
+// versions return the list itself.
+func getDashboardVersions(ctx context.Context, uid string, limit int) ([]dashboardVersion, error) {
+	escapedUID, err := sanitizePathSegment("dashboard UID", uid)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating Grafana client: %w", err)
+	}
+	path := "/api/dashboards/uid/" + escapedUID + "/versions?limit=" + strconv.Itoa(limit)
+	resp, err := client.makeRequest(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("list versions of dashboard %s: %w", uid, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read versions of dashboard %s: %w", uid, err)
+	}
+
+	var versions []dashboardVersion
+	if err := json.Unmarshal(body, &versions); err == nil {
+		return versions, nil
+	}
+	var wrapped struct {
+		Versions []dashboardVersion `json:"versions"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode versions of dashboard %s: %w", uid, err)
+	}
+	return wrapped.Versions, nil
+}
+
+// getDashboardVersion fetches a single version of a dashboard, including its
+// model.
+func getDashboardVersion(ctx context.Context, uid string, version int64) (*dashboardVersion, error) {
+	escapedUID, err := sanitizePathSegment("dashboard UID", uid)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating Grafana client: %w", err)
+	}
+	resp, err := client.makeRequest(ctx, "/api/dashboards/uid/"+escapedUID+"/versions/"+strconv.FormatInt(version, 10))
+	if err != nil {
+		return nil, fmt.Errorf("get version %d of dashboard %s: %w", version, uid, err)
+	}
+	defer resp.Body.Close()
+	var v dashboardVersion
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode version %d of dashboard %s: %w", version, uid, err)
+	}
+	if v.Data == nil {
+		return nil, fmt.Errorf("version %d of dashboard %s has no dashboard model", version, uid)
+	}
+	return &v, nil
+}
+
+func listDashboardVersions(ctx context.Context, args ListDashboardVersionsParams) ([]dashboardVersion, error) {
+	if err := validatePathSegment("uid", args.UID); err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultDashboardVersionsLimit
+	}
+	versions, err := getDashboardVersions(ctx, args.UID, min(limit, MaxDashboardVersionsLimit))
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		versions[i].Data = nil
+	}
+	return versions, nil
+}
+
+var ListDashboardVersions = mcpgrafana.MustTool(
+	"list_dashboard_versions",
+	"List the saved versions of a dashboard, most recent first, with when and by whom each was saved and its commit message. Use diff_dashboard_versions to see what changed between two versions.",
+	listDashboardVersions,
+	mcp.WithTitleAnnotation("List dashboard versions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type DiffDashboardVersionsParams struct {
+	UID  string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	From int64  `json:"from" jsonschema:"required,description=The version to compare from"`
+	To   int64  `json:"to,omitempty" jsonschema:"description=Optionally\\, the version to compare to. Defaults to the current version of the dashboard"`
+}
+
+// dashboardFieldChange is a changed dashboard, panel or variable field.
+type dashboardFieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+type dashboardPanelSummary struct {
+	ID    int    `json:"id,omitempty"`
+	Title string `json:"title"`
+	Type  string `json:"type,omitempty"`
+}
+
+// dashboardQueryChange is an added, removed or changed query of a panel,
+// identified by its ref ID.
+type dashboardQueryChange struct {
+	RefID            string          `json:"refId"`
+	Before           string          `json:"before,omitempty"`
+	After            string          `json:"after,omitempty"`
+	DatasourceBefore *datasourceInfo `json:"datasourceBefore,omitempty"`
+	DatasourceAfter  *datasourceInfo `json:"datasourceAfter,omitempty"`
+}
+
+type dashboardPanelChange struct {
+	dashboardPanelSummary
+	Changes []dashboardFieldChange `json:"changes,omitempty"`
+	Queries []dashboardQueryChange `json:"queries,omitempty"`
+	// OtherFields lists the other changed fields of the panel, such as its
+	// options or field config.
+	OtherFields []string `json:"otherFields,omitempty"`
+}
+
+type dashboardVariableChange struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+type dashboardVersionRef struct {
+	Version   int64     `json:"version"`
+	Created   time.Time `json:"created,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+type dashboardDiff struct {
+	UID              string                    `json:"uid"`
+	From             dashboardVersionRef       `json:"from"`
+	To               dashboardVersionRef       `json:"to"`
+	Changes          []dashboardFieldChange    `json:"changes,omitempty"`
+	PanelsAdded      []dashboardPanelSummary   `json:"panelsAdded,omitempty"`
+	PanelsRemoved    []dashboardPanelSummary   `json:"panelsRemoved,omitempty"`
+	PanelsChanged    []dashboardPanelChange    `json:"panelsChanged,omitempty"`
+	VariablesAdded   []string                  `json:"variablesAdded,omitempty"`
+	VariablesRemoved []string                  `json:"variablesRemoved,omitempty"`
+	VariablesChanged []dashboardVariableChange `json:"variablesChanged,omitempty"`
+}
+
+// dashboardDiffIgnoredFields are top-level dashboard fields which change with
+// every save, or which are compared separately.
+var dashboardDiffIgnoredFields = map[string]bool{"id": true, "version": true, "panels": true, "templating": true}
+
+// panelDiffIgnoredFields are panel fields which are compared separately, or
+// which only change with the layout.
+var panelDiffIgnoredFields = map[string]bool{"id": true, "title": true, "type": true, "datasource": true, "targets": true, "panels": true, "gridPos": true}
+
+// dashboardPanels returns the panels of a dashboard, including panels nested
+// in collapsed rows, keyed by their ID, or by their title if they have none,
+// along with the keys in dashboard order.
+func dashboardPanels(db map[string]any) (map[string]map[string]any, []string) {
+	byKey := map[string]map[string]any{}
+	var keys []string
+	var walk func(panels []any)
+	walk = func(panels []any) {
+		for _, p := range panels {
+			panel, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			key := "title:" + stringField(panel, "title")
+			if id, ok := panel["id"].(float64); ok && id != 0 {
+				key = "id:" + strconv.Itoa(int(id))
+			}
+			if _, ok := byKey[key]; !ok {
+				byKey[key] = panel
+				keys = append(keys, key)
+			}
+			if nested, ok := panel["panels"].([]any); ok {
+				walk(nested)
+			}
+		}
+	}
+	panels, _ := db["panels"].([]any)
+	walk(panels)
+	return byKey, keys
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func panelSummary(panel map[string]any) dashboardPanelSummary {
+	id, _ := panel["id"].(float64)
+	return dashboardPanelSummary{ID: int(id), Title: stringField(panel, "title"), Type: stringField(panel, "type")}
+}
+
+// changedFields returns the sorted names of the fields whose values differ
+// between two objects, skipping the ignored fields.
+func changedFields(before, after map[string]any, ignored map[string]bool) []string {
+	var fields []string
+	for key, v := range before {
+		if !ignored[key] && !reflect.DeepEqual(v, after[key]) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok && !ignored[key] {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// diffPanelQueries compares the queries of a panel's targets by ref ID.
+func diffPanelQueries(before, after map[string]any) []dashboardQueryChange {
+	type query struct {
+		expr string
+		ds   datasourceInfo
+	}
+	queries := func(panel map[string]any) (map[string]query, []string) {
+		byRef := map[string]query{}
+		var refs []string
+		targets, _ := panel["targets"].([]any)
+		for _, t := range targets {
+			target, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			ref := stringField(target, "refId")
+			if _, ok := byRef[ref]; !ok {
+				refs = append(refs, ref)
+			}
+			byRef[ref] = query{expr: targetQuery(target), ds: targetDatasource(panel, target)}
+		}
+		return byRef, refs
+	}
+	beforeQueries, beforeRefs := queries(before)
+	afterQueries, afterRefs := queries(after)
+
+	var changes []dashboardQueryChange
+	for _, ref := range beforeRefs {
+		b := beforeQueries[ref]
+		a, ok := afterQueries[ref]
+		switch {
+		case !ok:
+			changes = append(changes, dashboardQueryChange{RefID: ref, Before: b.expr, DatasourceBefore: &b.ds})
+		case a.expr != b.expr || a.ds != b.ds:
+			change := dashboardQueryChange{RefID: ref, Before: b.expr, After: a.expr}
+			if a.ds != b.ds {
+				change.DatasourceBefore, change.DatasourceAfter = &b.ds, &a.ds
+			}
+			changes = append(changes, change)
+		}
+	}
+	for _, ref := range afterRefs {
+		if _, ok := beforeQueries[ref]; !ok {
+			a := afterQueries[ref]
+			changes = append(changes, dashboardQueryChange{RefID: ref, After: a.expr, DatasourceAfter: &a.ds})
+		}
+	}
+	return changes
+}
+
+// dashboardVariables returns the template variables of a dashboard by name,
+// along with their names in dashboard order.
+func dashboardVariables(db map[string]any) (map[string]map[string]any, []string) {
+	byName := map[string]map[string]any{}
+	var names []string
+	templating, _ := db["templating"].(map[string]any)
+	list, _ := templating["list"].([]any)
+	for _, v := range list {
+		variable, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := stringField(variable, "name")
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = variable
+	}
+	return byName, names
+}
+
+// diffDashboards compares two versions of a dashboard model: its settings,
+// its panels, matched by ID, with their queries, matched by ref ID, and its
+// template variables, matched by name. Layout changes are ignored.
+func diffDashboards(before, after map[string]any) dashboardDiff {
+	var diff dashboardDiff
+	for _, field := range changedFields(before, after, dashboardDiffIgnoredFields) {
+		diff.Changes = append(diff.Changes, dashboardFieldChange{Field: field, Before: before[field], After: after[field]})
+	}
+
+	beforePanels, beforeKeys := dashboardPanels(before)
+	afterPanels, afterKeys := dashboardPanels(after)
+	for _, key := range beforeKeys {
+		b := beforePanels[key]
+		a, ok := afterPanels[key]
+		if !ok {
+			diff.PanelsRemoved = append(diff.PanelsRemoved, panelSummary(b))
+			continue
+		}
+		change := dashboardPanelChange{dashboardPanelSummary: panelSummary(a)}
+		for _, field := range []string{"title", "type"} {
+			if b[field] != a[field] {
+				change.Changes = append(change.Changes, dashboardFieldChange{Field: field, Before: b[field], After: a[field]})
+			}
+		}
+		if bds, ads := parseDatasourceRef(b["datasource"]), parseDatasourceRef(a["datasource"]); bds != ads {
+			change.Changes = append(change.Changes, dashboardFieldChange{Field: "datasource", Before: bds, After: ads})
+		}
+		change.Queries = diffPanelQueries(b, a)
+		change.OtherFields = changedFields(b, a, panelDiffIgnoredFields)
+		if len(change.Changes) > 0 || len(change.Queries) > 0 || len(change.OtherFields) > 0 {
+			diff.PanelsChanged = append(diff.PanelsChanged, change)
+		}
+	}
+	for _, key := range afterKeys {
+		if _, ok := beforePanels[key]; !ok {
+			diff.PanelsAdded = append(diff.PanelsAdded, panelSummary(afterPanels[key]))
+		}
+	}
+
+	beforeVariables, beforeNames := dashboardVariables(before)
+	afterVariables, afterNames := dashboardVariables(after)
+	for _, name := range beforeNames {
+		a, ok := afterVariables[name]
+		if !ok {
+			diff.VariablesRemoved = append(diff.VariablesRemoved, name)
+			continue
+		}
+		if fields := changedFields(beforeVariables[name], a, nil); len(fields) > 0 {
+			diff.VariablesChanged = append(diff.VariablesChanged, dashboardVariableChange{Name: name, Fields: fields})
+		}
+	}
+	for _, name := range afterNames {
+		if _, ok := beforeVariables[name]; !ok {
+			diff.VariablesAdded = append(diff.VariablesAdded, name)
+		}
+	}
+	return diff
+}
+
+func diffDashboardVersions(ctx context.Context, args DiffDashboardVersionsParams) (*dashboardDiff, error) {
+	if err := validatePathSegment("uid", args.UID); err != nil {
+		return nil, err
+	}
+	if args.From <= 0 || args.To < 0 {
+		return nil, fmt.Errorf("versions must be positive")
+	}
+	from, err := getDashboardVersion(ctx, args.UID, args.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *dashboardVersion
+	if args.To > 0 {
+		if to, err = getDashboardVersion(ctx, args.UID, args.To); err != nil {
+			return nil, err
+		}
+	} else {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+		if err != nil {
+			return nil, err
+		}
+		db, ok := dashboard.Dashboard.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("dashboard is not a JSON object")
+		}
+		version, _ := db["version"].(float64)
+		to = &dashboardVersion{Version: int64(version), Data: db}
+		if dashboard.Meta != nil {
+			to.Created = time.Time(dashboard.Meta.Updated)
+			to.CreatedBy = dashboard.Meta.UpdatedBy
+		}
+	}
+
+	diff := diffDashboards(from.Data, to.Data)
+	diff.UID = args.UID
+	diff.From = dashboardVersionRef{Version: from.Version, Created: from.Created, CreatedBy: from.CreatedBy, Message: from.Message}
+	diff.To = dashboardVersionRef{Version: to.Version, Created: to.Created, CreatedBy: to.CreatedBy, Message: to.Message}
+	return &diff, nil
+}
+
+var DiffDashboardVersions = mcpgrafana.MustTool(
+	"diff_dashboard_versions",
+	"Compare two versions of a dashboard, for example to find out what changed on a dashboard before an incident. Returns the changed dashboard settings with their values before and after; the panels added, removed and changed, with changed titles, types, datasources and queries (matched by ref ID) and the names of other changed panel fields such as options; and the template variables added, removed and changed. Panels are matched by ID, including panels in collapsed rows, and layout changes are ignored. Use list_dashboard_versions to find the versions to compare; `to` defaults to the current version.",
+	diffDashboardVersions,
+	mcp.WithTitleAnnotation("Diff dashboard versions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	diffTestDashboardBefore = `{
+		"id": 1, "uid": "svc", "title": "Service", "version": 3, "refresh": "1m", "tags": ["prod"],
+		"templating": {"list": [
+			{"name": "job", "type": "query", "query": "label_values(job)"},
+			{"name": "old", "type": "custom"}
+		]},
+		"panels": [
+			{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0, "y": 0},
+				"datasource": {"uid": "prom", "type": "prometheus"},
+				"targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"}, {"refId": "B", "expr": "up"}]},
+			{"id": 2, "type": "stat", "title": "Errors", "options": {"colorMode": "value"}},
+			{"id": 3, "type": "row", "title": "Details", "panels": [
+				{"id": 4, "type": "table", "title": "Slow queries"}
+			]}
+		]
+	}`
+	diffTestDashboardAfter = `{
+		"id": 1, "uid": "svc", "title": "Service", "version": 4, "refresh": "5m", "tags": ["prod"],
+		"templating": {"list": [
+			{"name": "job", "type": "query", "query": "label_values(up, job)"},
+			{"name": "cluster", "type": "query"}
+		]},
+		"panels": [
+			{"id": 1, "type": "timeseries", "title": "Request rate", "gridPos": {"x": 12, "y": 0},
+				"datasource": {"uid": "prom", "type": "prometheus"},
+				"targets": [{"refId": "A", "expr": "sum(rate(http_requests_total{code!~\"5..\"}[5m]))"}, {"refId": "C", "expr": "down", "datasource": {"uid": "mimir", "type": "prometheus"}}]},
+			{"id": 2, "type": "stat", "title": "Errors", "options": {"colorMode": "background"}, "gridPos": {"x": 0, "y": 8}},
+			{"id": 5, "type": "logs", "title": "Logs"}
+		]
+	}`
+)
+
+func TestDiffDashboards(t *testing.T) {
+	var before, after map[string]any
+	require.NoError(t, json.Unmarshal([]byte(diffTestDashboardBefore), &before))
+	require.NoError(t, json.Unmarshal([]byte(diffTestDashboardAfter), &after))
+
+	diff := diffDashboards(before, after)
+	assert.Equal(t, []dashboardFieldChange{{Field: "refresh", Before: "1m", After: "5m"}}, diff.Changes)
+	assert.Equal(t, []dashboardPanelSummary{{ID: 5, Title: "Logs", Type: "logs"}}, diff.PanelsAdded)
+	assert.Equal(t, []dashboardPanelSummary{
+		{ID: 3, Title: "Details", Type: "row"},
+		{ID: 4, Title: "Slow queries", Type: "table"},
+	}, diff.PanelsRemoved)
+	assert.Equal(t, []dashboardPanelChange{
+		{
+			dashboardPanelSummary: dashboardPanelSummary{ID: 1, Title: "Request rate", Type: "timeseries"},
+			Changes:               []dashboardFieldChange{{Field: "title", Before: "Requests", After: "Request rate"}},
+			Queries: []dashboardQueryChange{
+				{RefID: "A", Before: "sum(rate(http_requests_total[5m]))", After: `sum(rate(http_requests_total{code!~"5.."}[5m]))`},
+				{RefID: "B", Before: "up", DatasourceBefore: &datasourceInfo{UID: "prom", Type: "prometheus"}},
+				{RefID: "C", After: "down", DatasourceAfter: &datasourceInfo{UID: "mimir", Type: "prometheus"}},
+			},
+		},
+		{
+			dashboardPanelSummary: dashboardPanelSummary{ID: 2, Title: "Errors", Type: "stat"},
+			OtherFields:           []string{"options"},
+		},
+	}, diff.PanelsChanged)
+	assert.Equal(t, []string{"cluster"}, diff.VariablesAdded)
+	assert.Equal(t, []string{"old"}, diff.VariablesRemoved)
+	assert.Equal(t, []dashboardVariableChange{{Name: "job", Fields: []string{"query"}}}, diff.VariablesChanged)
+
+	// A dashboard doesn't differ from itself.
+	assert.Equal(t, dashboardDiff{}, diffDashboards(before, before))
+}
+
+func TestDashboardVersionTools(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc/versions", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"id": 14, "dashboardId": 1, "uid": "svc", "parentVersion": 3, "version": 4, "created": "2024-01-02T10:00:00Z", "createdBy": "alice", "message": "Tweak panels"},
+			{"id": 13, "dashboardId": 1, "uid": "svc", "parentVersion": 2, "version": 3, "created": "2024-01-01T10:00:00Z", "createdBy": "bob"}
+		]`))
+	})
+	mux.HandleFunc("/api/dashboards/uid/svc/versions/3", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version": 3, "created": "2024-01-01T10:00:00Z", "createdBy": "bob", "data": ` + diffTestDashboardBefore + `}`))
+	})
+	mux.HandleFunc("/api/dashboards/uid/svc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + diffTestDashboardAfter + `, "meta": {"updated": "2024-01-02T10:00:00Z", "updatedBy": "alice"}}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	versions, err := listDashboardVersions(ctx, ListDashboardVersionsParams{UID: "svc", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, int64(4), versions[0].Version)
+	assert.Equal(t, "alice", versions[0].CreatedBy)
+	assert.Equal(t, "Tweak panels", versions[0].Message)
+
+	diff, err := diffDashboardVersions(ctx, DiffDashboardVersionsParams{UID: "svc", From: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), diff.From.Version)
+	assert.Equal(t, "bob", diff.From.CreatedBy)
+	assert.Equal(t, int64(4), diff.To.Version)
+	assert.Equal(t, "alice", diff.To.CreatedBy)
+	assert.Len(t, diff.PanelsChanged, 2)
+
+	_, err = diffDashboardVersions(ctx, DiffDashboardVersionsParams{UID: "svc", From: 0})
+	assert.Error(t, err)
+	_, err = getDashboardVersions(ctx, "..", 2)
+	assert.ErrorContains(t, err, "invalid dashboard UID")
+	_, err = getDashboardVersion(ctx, "..", 3)
+	assert.ErrorContains(t, err, "invalid dashboard UID")
+}
+
+func TestGetDashboardVersionsWrapped(t *testing.T) {
+	// Grafana 12 wraps the versions in an object with a continue token.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/svc/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"continueToken": "abc", "versions": [{"version": 7, "created": "2024-01-02T10:00:00Z", "createdBy": "alice"}]}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "12.0.0", mux)
+
+	versions, err := listDashboardVersions(ctx, ListDashboardVersionsParams{UID: "svc"})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, int64(7), versions[0].Version)
+}
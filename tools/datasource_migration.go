@@ -0,0 +1,379 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type MigrateDatasourceParams struct {
+	FromUID string `json:"fromUid" jsonschema:"required,description=The UID of the datasource to migrate away from"`
+	ToUID   string `json:"toUid" jsonschema:"required,description=The UID of the datasource to migrate to"`
+	Apply   bool   `json:"apply,omitempty" jsonschema:"description=Set to true to save the rewritten resources. Otherwise only a dry-run diff is returned"`
+}
+
+func (p MigrateDatasourceParams) validate() error {
+	if p.FromUID == "" || p.ToUID == "" {
+		return fmt.Errorf("fromUid and toUid are required")
+	}
+	if p.FromUID == p.ToUID {
+		return fmt.Errorf("fromUid and toUid must be different")
+	}
+	return nil
+}
+
+// rewrittenResource describes the rewrite of a single dashboard or alert rule.
+type rewrittenResource struct {
+	Type  string `json:"type"`
+	UID   string `json:"uid"`
+	Title string `json:"title,omitempty"`
+	// Changes lists each rewritten field as `path: old -> new`.
+	Changes []string `json:"changes"`
+	// Panels lists the dashboard panels with rewritten fields.
+	Panels     []affectedPanel `json:"panels,omitempty"`
+	BackupPath string          `json:"backupPath,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// affectedPanel is a dashboard panel changed by a rewrite.
+type affectedPanel struct {
+	PanelID int    `json:"panelId"`
+	Title   string `json:"title,omitempty"`
+}
+
+// resourceRewriteReport is the result of a tool which rewrites dashboards and
+// alert rules in bulk.
+type resourceRewriteReport struct {
+	Applied   bool                `json:"applied"`
+	BackupDir string              `json:"backupDir,omitempty"`
+	Resources []rewrittenResource `json:"resources"`
+}
+
+// rewriteDatasourceRefs replaces references to one datasource UID with
+// another throughout a dashboard or alert rule JSON model, in place. It
+// rewrites `datasource` references (as objects with a `uid` or as plain
+// strings) and `datasourceUid` fields, and returns the path of each change.
+func rewriteDatasourceRefs(v any, fromUID, toUID string) []string {
+	var changes []string
+	change := func(path string) {
+		changes = append(changes, fmt.Sprintf("%s: %q -> %q", path, fromUID, toUID))
+	}
+	var walk func(v any, path string)
+	walk = func(v any, path string) {
+		switch v := v.(type) {
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				switch k {
+				case "datasource":
+					if s, ok := v[k].(string); ok && s == fromUID {
+						v[k] = toUID
+						change(p)
+						continue
+					}
+					if ref, ok := v[k].(map[string]any); ok && ref["uid"] == fromUID {
+						ref["uid"] = toUID
+						change(p + ".uid")
+						continue
+					}
+				case "datasourceUid":
+					if s, ok := v[k].(string); ok && s == fromUID {
+						v[k] = toUID
+						change(p)
+						continue
+					}
+				}
+				walk(v[k], p)
+			}
+		case []any:
+			for i, item := range v {
+				walk(item, path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	walk(v, "")
+	return changes
+}
+
+// panelPathRegex matches the path of a dashboard panel, which may be nested
+// in a collapsed row, at the start of a change.
+var panelPathRegex = regexp.MustCompile(`^panels\[(\d+)\](?:\.panels\[(\d+)\])?`)
+
+// affectedPanels returns the panels of a dashboard changed by a rewrite, in
+// the order of the changes.
+func affectedPanels(db map[string]any, changes []string) []affectedPanel {
+	var panels []affectedPanel
+	seen := map[string]bool{}
+	for _, change := range changes {
+		m := panelPathRegex.FindStringSubmatch(change)
+		if m == nil || seen[m[0]] {
+			continue
+		}
+		seen[m[0]] = true
+		list, _ := db["panels"].([]any)
+		var panel map[string]any
+		for _, index := range m[1:] {
+			if index == "" {
+				break
+			}
+			i, _ := strconv.Atoi(index)
+			if i >= len(list) {
+				panel = nil
+				break
+			}
+			panel, _ = list[i].(map[string]any)
+			list, _ = panel["panels"].([]any)
+		}
+		if panel != nil {
+			id, _ := panel["id"].(float64)
+			panels = append(panels, affectedPanel{PanelID: int(id), Title: stringField(panel, "title")})
+		}
+	}
+	return panels
+}
+
+// toJSONMap round-trips a value through JSON so it can be rewritten
+// generically.
+func toJSONMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// newResourceRewriteReport returns an empty report. When applying, backups
+// are written to a new directory under the system temporary directory, which
+// clients cannot choose so that they cannot write files elsewhere on the
+// server host.
+func newResourceRewriteReport(apply bool) *resourceRewriteReport {
+	report := &resourceRewriteReport{Applied: apply, Resources: []rewrittenResource{}}
+	if apply {
+		report.BackupDir = filepath.Join(os.TempDir(), "mcp-grafana-backups", time.Now().UTC().Format("20060102T150405Z"))
+	}
+	return report
+}
+
+// writeBackup writes the original JSON of a resource to the backup directory.
+func writeBackup(dir, name string, v any) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create backup directory: %w", err)
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal backup: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+	return path, nil
+}
+
+func migrateDatasource(ctx context.Context, args MigrateDatasourceParams) (*resourceRewriteReport, error) {
+	if err := args.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.ToUID}); err != nil {
+		return nil, err
+	}
+
+	report := newResourceRewriteReport(args.Apply)
+
+	dashboards, err := migrateDashboardDatasources(ctx, args, nil, report.BackupDir)
+	if err != nil {
+		return nil, err
+	}
+	report.Resources = append(report.Resources, dashboards...)
+
+	rules, err := migrateAlertRuleDatasources(ctx, args, report.BackupDir)
+	if err != nil {
+		return nil, err
+	}
+	report.Resources = append(report.Resources, rules...)
+	return report, nil
+}
+
+// migrateDashboardDatasources rewrites the datasource references of the
+// given dashboards, or of all dashboards if none are given.
+func migrateDashboardDatasources(ctx context.Context, args MigrateDatasourceParams, dashboardUIDs []string, backupDir string) ([]rewrittenResource, error) {
+	var hits []grafanaResource
+	for _, uid := range dashboardUIDs {
+		hits = append(hits, grafanaResource{Type: resourceTypeDashboard, UID: uid})
+	}
+	if len(hits) == 0 {
+		var err error
+		if hits, err = searchAllResources(ctx, dashboardTypeStr, resourceTypeDashboard); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []rewrittenResource
+	for _, hit := range hits {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: hit.UID})
+		if err != nil {
+			result = append(result, rewrittenResource{Type: resourceTypeDashboard, UID: hit.UID, Title: hit.Title, Error: err.Error()})
+			continue
+		}
+		db, err := toJSONMap(dashboard.Dashboard)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal dashboard %s: %w", hit.UID, err)
+		}
+		changes := rewriteDatasourceRefs(db, args.FromUID, args.ToUID)
+		if len(changes) == 0 {
+			continue
+		}
+
+		resource := rewrittenResource{
+			Type:    resourceTypeDashboard,
+			UID:     hit.UID,
+			Title:   stringOrDefault(hit.Title, stringField(db, "title")),
+			Changes: changes,
+			Panels:  affectedPanels(db, changes),
+		}
+		if args.Apply {
+			resource.BackupPath, err = writeBackup(backupDir, "dashboard-"+hit.UID+".json", dashboard)
+			if err != nil {
+				return nil, err
+			}
+			resource.Error = saveRewrittenDashboard(ctx, dashboard, db, fmt.Sprintf("Migrate datasource %s to %s", args.FromUID, args.ToUID))
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+func migrateAlertRuleDatasources(ctx context.Context, args MigrateDatasourceParams, backupDir string) ([]rewrittenResource, error) {
+	rules, err := provisionedAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []rewrittenResource
+	for _, rule := range rules {
+		m, err := toJSONMap(rule)
+		if err != nil {
+			return nil, fmt.Errorf("marshal alert rule %s: %w", rule.UID, err)
+		}
+		changes := rewriteDatasourceRefs(m, args.FromUID, args.ToUID)
+		if len(changes) == 0 {
+			continue
+		}
+
+		resource := rewrittenResource{Type: resourceTypeAlertRule, UID: rule.UID, Changes: changes}
+		if rule.Title != nil {
+			resource.Title = *rule.Title
+		}
+		if args.Apply {
+			resource.BackupPath, err = writeBackup(backupDir, "alert-rule-"+rule.UID+".json", rule)
+			if err != nil {
+				return nil, err
+			}
+			resource.Error = saveRewrittenAlertRule(ctx, rule, m)
+		}
+		result = append(result, resource)
+	}
+	return result, nil
+}
+
+// saveRewrittenDashboard saves a rewritten dashboard in its original folder,
+// returning an error message if it could not be saved.
+func saveRewrittenDashboard(ctx context.Context, original *models.DashboardFullWithMeta, rewritten map[string]any, message string) string {
+	var folderUID string
+	if original.Meta != nil {
+		folderUID = original.Meta.FolderUID
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.Dashboards.PostDashboard(&models.SaveDashboardCommand{
+		Dashboard: rewritten,
+		FolderUID: folderUID,
+		Message:   message,
+		Overwrite: true,
+	}); err != nil {
+		return fmt.Sprintf("save dashboard: %s", err)
+	}
+	return ""
+}
+
+// saveRewrittenAlertRule saves a rewritten alert rule, returning an error
+// message if it could not be saved. Rules created in the UI are kept editable
+// there.
+func saveRewrittenAlertRule(ctx context.Context, original *models.ProvisionedAlertRule, rewritten map[string]any) string {
+	b, err := json.Marshal(rewritten)
+	if err != nil {
+		return fmt.Sprintf("marshal alert rule: %s", err)
+	}
+	var rule models.ProvisionedAlertRule
+	if err := json.Unmarshal(b, &rule); err != nil {
+		return fmt.Sprintf("unmarshal alert rule: %s", err)
+	}
+
+	params := provisioning.NewPutAlertRuleParamsWithContext(ctx).
+		WithUID(original.UID).
+		WithBody(&rule).
+		WithXDisableProvenance(provenanceHeader(original.Provenance))
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.Provisioning.PutAlertRule(params); err != nil {
+		return fmt.Sprintf("save alert rule: %s", err)
+	}
+	return ""
+}
+
+var MigrateDatasource = mcpgrafana.MustTool(
+	"migrate_datasource",
+	"Rewrites all dashboards and Grafana-managed alert rules which reference one datasource UID to reference another instead, for example when migrating from Prometheus to Mimir. By default this is a dry run which returns, for each affected resource, the list of fields which would change. Set `apply` to true to save the changes; the original of every changed resource is first backed up as JSON to a new directory under `mcp-grafana-backups` in the server's temporary directory, returned as `backupDir`. Only datasource references by UID are rewritten, so the target datasource should be of a compatible type.",
+	migrateDatasource,
+	mcp.WithTitleAnnotation("Migrate datasource references"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
+
+type ReplaceDashboardDatasourceParams struct {
+	FromUID       string   `json:"fromUid" jsonschema:"required,description=The UID of the datasource to replace"`
+	ToUID         string   `json:"toUid" jsonschema:"required,description=The UID of the datasource to use instead"`
+	DashboardUIDs []string `json:"dashboardUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of the dashboards to rewrite. Defaults to all dashboards"`
+	Apply         bool     `json:"apply,omitempty" jsonschema:"description=Set to true to save the rewritten dashboards. Otherwise only a dry run listing the affected dashboards\\, panels and fields is returned"`
+}
+
+func replaceDashboardDatasource(ctx context.Context, args ReplaceDashboardDatasourceParams) (*resourceRewriteReport, error) {
+	migration := MigrateDatasourceParams{FromUID: args.FromUID, ToUID: args.ToUID, Apply: args.Apply}
+	if err := migration.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.ToUID}); err != nil {
+		return nil, err
+	}
+
+	report := newResourceRewriteReport(args.Apply)
+	dashboards, err := migrateDashboardDatasources(ctx, migration, args.DashboardUIDs, report.BackupDir)
+	if err != nil {
+		return nil, err
+	}
+	report.Resources = append(report.Resources, dashboards...)
+	return report, nil
+}
+
+var ReplaceDashboardDatasource = mcpgrafana.MustTool(
+	"replace_dashboard_datasource",
+	"Rewrites the dashboards which reference one datasource UID, in panels, queries, template variables or annotations, to reference another instead, such as after moving to a new datasource. Use migrate_datasource to also rewrite alert rules. By default this is a dry run which returns each affected dashboard with its affected panels and the fields which would change. Set `apply` to true to save the changes; the original of every changed dashboard is first backed up as JSON to a new directory under `mcp-grafana-backups` in the server's temporary directory, returned as `backupDir`. Limit the rewrite to some dashboards with `dashboardUids`.",
+	replaceDashboardDatasource,
+	mcp.WithTitleAnnotation("Replace dashboard datasource"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
@@ -0,0 +1,130 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteDatasourceRefs(t *testing.T) {
+	var dashboard map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"uid": "prom",
+		"panels": [
+			{
+				"datasource": {"type": "prometheus", "uid": "prom"},
+				"targets": [
+					{"refId": "A", "datasource": {"type": "prometheus", "uid": "prom"}},
+					{"refId": "B", "datasource": {"type": "prometheus", "uid": "other"}}
+				]
+			},
+			{"datasource": "prom"}
+		],
+		"data": [{"refId": "A", "datasourceUid": "prom"}]
+	}`), &dashboard))
+
+	changes := rewriteDatasourceRefs(dashboard, "prom", "mimir")
+	assert.Equal(t, []string{
+		`data[0].datasourceUid: "prom" -> "mimir"`,
+		`panels[0].datasource.uid: "prom" -> "mimir"`,
+		`panels[0].targets[0].datasource.uid: "prom" -> "mimir"`,
+		`panels[1].datasource: "prom" -> "mimir"`,
+	}, changes)
+
+	panels := dashboard["panels"].([]any)
+	targets := panels[0].(map[string]any)["targets"].([]any)
+	assert.Equal(t, "mimir", targets[0].(map[string]any)["datasource"].(map[string]any)["uid"])
+	assert.Equal(t, "other", targets[1].(map[string]any)["datasource"].(map[string]any)["uid"])
+	// Fields which are not datasource references are left alone.
+	assert.Equal(t, "prom", dashboard["uid"])
+
+	assert.Empty(t, rewriteDatasourceRefs(dashboard, "prom", "mimir"))
+}
+
+func TestMigrateDatasourceParams(t *testing.T) {
+	assert.Error(t, MigrateDatasourceParams{FromUID: "prom"}.validate())
+	assert.Error(t, MigrateDatasourceParams{FromUID: "prom", ToUID: "prom"}.validate())
+	assert.NoError(t, MigrateDatasourceParams{FromUID: "prom", ToUID: "mimir"}.validate())
+}
+
+func TestWriteBackup(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	path, err := writeBackup(dir, "dashboard-abc.json", map[string]any{"uid": "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "dashboard-abc.json"), path)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"uid": "abc"}`, string(b))
+}
+
+func TestAffectedPanels(t *testing.T) {
+	db := map[string]any{"panels": []any{
+		map[string]any{"id": 1.0, "title": "CPU"},
+		map[string]any{"id": 2.0, "type": "row", "panels": []any{map[string]any{"id": 3.0, "title": "Memory"}}},
+	}}
+	assert.Equal(t, []affectedPanel{{PanelID: 1, Title: "CPU"}, {PanelID: 3, Title: "Memory"}}, affectedPanels(db, []string{
+		`annotations.list[0].datasource.uid: "prom" -> "mimir"`,
+		`panels[0].datasource.uid: "prom" -> "mimir"`,
+		`panels[0].targets[0].datasource.uid: "prom" -> "mimir"`,
+		`panels[1].panels[0].datasource: "prom" -> "mimir"`,
+		`panels[5].datasource: "prom" -> "mimir"`,
+	}))
+}
+
+func TestReplaceDashboardDatasource(t *testing.T) {
+	var saved map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources/uid/mimir", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid": "mimir", "name": "Mimir", "type": "prometheus"}`))
+	})
+	mux.HandleFunc("/api/dashboards/uid/{uid}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"meta": {"folderUid": "ops"}, "dashboard": {"uid": "` + r.PathValue("uid") + `", "title": "API", "panels": [
+			{"id": 1, "title": "Requests", "datasource": {"type": "prometheus", "uid": "prom"}},
+			{"id": 2, "title": "Logs", "datasource": {"type": "loki", "uid": "loki"}}
+		]}}`))
+	})
+	mux.HandleFunc("POST /api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid": "api", "status": "success"}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	report, err := replaceDashboardDatasource(ctx, ReplaceDashboardDatasourceParams{FromUID: "prom", ToUID: "mimir", DashboardUIDs: []string{"api"}})
+	require.NoError(t, err)
+	assert.False(t, report.Applied)
+	require.Len(t, report.Resources, 1)
+	assert.Equal(t, rewrittenResource{
+		Type:    resourceTypeDashboard,
+		UID:     "api",
+		Title:   "API",
+		Changes: []string{`panels[0].datasource.uid: "prom" -> "mimir"`},
+		Panels:  []affectedPanel{{PanelID: 1, Title: "Requests"}},
+	}, report.Resources[0])
+	assert.Nil(t, saved)
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	report, err = replaceDashboardDatasource(ctx, ReplaceDashboardDatasourceParams{FromUID: "prom", ToUID: "mimir", DashboardUIDs: []string{"api"}, Apply: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Resources[0].Error)
+	assert.FileExists(t, report.Resources[0].BackupPath)
+	assert.Equal(t, filepath.Join(tmp, "mcp-grafana-backups"), filepath.Dir(report.BackupDir))
+	assert.Equal(t, "ops", saved["folderUid"])
+	panel := saved["dashboard"].(map[string]any)["panels"].([]any)[0].(map[string]any)
+	assert.Equal(t, "mimir", panel["datasource"].(map[string]any)["uid"])
+
+	_, err = replaceDashboardDatasource(ctx, ReplaceDashboardDatasourceParams{FromUID: "prom", ToUID: "prom"})
+	assert.Error(t, err)
+}
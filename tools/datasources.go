@@ -78,6 +78,9 @@ type GetDatasourceByUIDParams struct {
 }
 
 func getDatasourceByUID(ctx context.Context, args GetDatasourceByUIDParams) (*models.DataSource, error) {
+	if err := validatePathSegment("uid", args.UID); err != nil {
+		return nil, err
+	}
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	datasource, err := c.Datasources.GetDataSourceByUID(args.UID)
 	if err != nil {
@@ -105,6 +108,9 @@ type GetDatasourceByNameParams struct {
 
 func getDatasourceByName(ctx context.Context, args GetDatasourceByNameParams) (*models.DataSource, error) {
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if err := validatePathSegment("name", args.Name); err != nil {
+		return nil, err
+	}
 	datasource, err := c.Datasources.GetDataSourceByName(args.Name)
 	if err != nil {
 		return nil, fmt.Errorf("get datasource by name %s: %w", args.Name, err)
@@ -126,3 +132,9 @@ func AddDatasourceTools(mcp *server.MCPServer) {
 	GetDatasourceByUID.Register(mcp)
 	GetDatasourceByName.Register(mcp)
 }
+
+// AddDatasourceWriteTools registers datasource tools which modify Grafana.
+// They are only enabled when the server runs with write tools enabled.
+func AddDatasourceWriteTools(mcp *server.MCPServer) {
+	MigrateDatasource.Register(mcp)
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+var grafanaAppURLCache = newTTLCache[string]()
+
+// grafanaLinkURL returns the base URL of links to Grafana in tool results.
+// This is the configured public URL, if any, and otherwise the URL requests
+// are sent to, unless Grafana is reached over a unix socket: that URL is then
+// on localhost, so Grafana's own root URL (appUrl) is used instead.
+func grafanaLinkURL(ctx context.Context) (string, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	if cfg.PublicURL != "" {
+		return cfg.PublicURL, nil
+	}
+	if cfg.UnixSocket == "" {
+		return cfg.URL, nil
+	}
+	return grafanaAppURLCache.getOrLoad(ctx, "appUrl", func() (string, error) {
+		client, err := newAlertingClientFromContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.makeRequest(ctx, "/api/frontend/settings")
+		if err != nil {
+			return "", fmt.Errorf("get Grafana root URL for links: %w", err)
+		}
+		defer resp.Body.Close()
+		var settings struct {
+			AppURL string `json:"appUrl"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&settings); err != nil {
+			return "", fmt.Errorf("decode Grafana frontend settings: %w", err)
+		}
+		if settings.AppURL == "" {
+			return "", fmt.Errorf("grafana has no root URL to build links with; set --grafana-public-url")
+		}
+		return settings.AppURL, nil
+	})
+}
+
+// explorePane is the state of a single Grafana Explore pane, as encoded in
+// the `panes` URL parameter.
+type explorePane struct {
+	Datasource string           `json:"datasource"`
+	Queries    []map[string]any `json:"queries"`
+	Range      exploreRange     `json:"range"`
+}
+
+type exploreRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// exploreURL builds a link to Grafana Explore with a single pane.
+func exploreURL(grafanaURL string, pane explorePane) (string, error) {
+	panes, err := json.Marshal(map[string]explorePane{"a": pane})
+	if err != nil {
+		return "", fmt.Errorf("marshal explore state: %w", err)
+	}
+	params := url.Values{}
+	params.Set("schemaVersion", "1")
+	params.Set("panes", string(panes))
+	return strings.TrimRight(grafanaURL, "/") + "/explore?" + params.Encode(), nil
+}
+
+// grafanaURLTime converts a time to the format used in Grafana URLs. Times
+// relative to now (e.g. 'now-1h') are kept as they are, so the link stays
+// relative, and RFC3339 times are converted to Unix milliseconds. An empty
+// time is replaced with def.
+func grafanaURLTime(s, def string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	if strings.HasPrefix(s, "now") {
+		if _, err := parseTime(s); err != nil {
+			return "", fmt.Errorf("invalid relative time %q: %w", s, err)
+		}
+		return s, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q: must be RFC3339 or relative to now: %w", s, err)
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10), nil
+}
+
+// dashboardURL builds a link to a dashboard with the given query parameters,
+// such as `from`, `to` and `var-<name>`.
+func dashboardURL(grafanaURL, uid, slug string, params url.Values) string {
+	u := strings.TrimRight(grafanaURL, "/") + "/d/" + url.PathEscape(uid)
+	if slug != "" {
+		u += "/" + url.PathEscape(slug)
+	}
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Tool categories whose defaults can be configured.
+const (
+	defaultsCategoryLoki       = "loki"
+	defaultsCategoryTempo      = "tempo"
+	defaultsCategoryPrometheus = "prometheus"
+	defaultsCategoryPyroscope  = "pyroscope"
+)
+
+// builtinToolDefaults are the defaults used unless overridden by the server
+// configuration. Prometheus has no default step, so range queries must
+// specify one unless it is configured.
+var builtinToolDefaults = map[string]mcpgrafana.ToolDefaults{
+	defaultsCategoryLoki:       {TimeRange: time.Hour, Limit: DefaultLokiLogLimit, MaxLimit: MaxLokiLogLimit, SplitInterval: DefaultLokiSplitInterval},
+	defaultsCategoryTempo:      {TimeRange: time.Hour, Limit: DefaultTempoTraceLimit, MaxLimit: MaxTempoTraceLimit, Timeout: defaultTimeout},
+	defaultsCategoryPrometheus: {TimeRange: time.Hour},
+	defaultsCategoryPyroscope:  {TimeRange: time.Hour, Timeout: 10 * time.Second},
+}
+
+// toolDefaultsFor returns the defaults for a tool category, applying any
+// overrides configured for all categories and then for the category itself.
+func toolDefaultsFor(ctx context.Context, category string) mcpgrafana.ToolDefaults {
+	d := builtinToolDefaults[category]
+	overrides := mcpgrafana.GrafanaConfigFromContext(ctx).ToolDefaults
+	for _, o := range []mcpgrafana.ToolDefaults{overrides[""], overrides[category]} {
+		if o.TimeRange > 0 {
+			d.TimeRange = o.TimeRange
+		}
+		if o.Limit > 0 {
+			d.Limit = o.Limit
+		}
+		if o.MaxLimit > 0 {
+			d.MaxLimit = o.MaxLimit
+		}
+		if o.Step > 0 {
+			d.Step = o.Step
+		}
+		if o.MaxLookback > 0 {
+			d.MaxLookback = o.MaxLookback
+		}
+		if o.MaxRange > 0 {
+			d.MaxRange = o.MaxRange
+		}
+		if o.SplitInterval > 0 {
+			d.SplitInterval = o.SplitInterval
+		}
+		if o.Timeout > 0 {
+			d.Timeout = o.Timeout
+		}
+		if o.Retries > 0 {
+			d.Retries = o.Retries
+		}
+		if o.RetryBackoff > 0 {
+			d.RetryBackoff = o.RetryBackoff
+		}
+	}
+	return d
+}
+
+// enforceLimit applies the default limit if none was requested and caps it
+// at the maximum.
+func enforceLimit(d mcpgrafana.ToolDefaults, requestedLimit int) int {
+	limit := requestedLimit
+	if limit <= 0 {
+		limit = d.Limit
+	}
+	if d.MaxLimit > 0 && limit > d.MaxLimit {
+		limit = d.MaxLimit
+	}
+	return limit
+}
+
+// ParseToolDefaults parses a comma-separated list of tool default overrides
+// of the form `[category.]setting=value`, e.g.
+// `time-range=15m,loki.limit=50,prometheus.step=30s`. Settings without a
+// category apply to all categories. The settings are time-range, limit,
+// max-limit, step, max-lookback, max-range, split-interval, timeout, retries
+// and retry-backoff; the categories are loki, tempo, prometheus and
+// pyroscope.
+func ParseToolDefaults(s string) (map[string]mcpgrafana.ToolDefaults, error) {
+	result := map[string]mcpgrafana.ToolDefaults{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tool default %q: expected [category.]setting=value", item)
+		}
+		category, setting, ok := strings.Cut(key, ".")
+		if !ok {
+			category, setting = "", key
+		} else if _, known := builtinToolDefaults[category]; !known {
+			return nil, fmt.Errorf("invalid tool default %q: unknown category %q, expected one of %s", item, category, strings.Join(slices.Sorted(maps.Keys(builtinToolDefaults)), ", "))
+		}
+
+		d := result[category]
+		var err error
+		switch setting {
+		case "time-range":
+			d.TimeRange, err = parsePositiveDuration(value)
+		case "step":
+			d.Step, err = parsePositiveDuration(value)
+		case "max-lookback":
+			d.MaxLookback, err = parsePositiveDuration(value)
+		case "max-range":
+			d.MaxRange, err = parsePositiveDuration(value)
+		case "split-interval":
+			d.SplitInterval, err = parsePositiveDuration(value)
+		case "timeout":
+			d.Timeout, err = parsePositiveDuration(value)
+		case "retry-backoff":
+			d.RetryBackoff, err = parsePositiveDuration(value)
+		case "retries":
+			d.Retries, err = parsePositiveInt(value)
+		case "limit":
+			d.Limit, err = parsePositiveInt(value)
+		case "max-limit":
+			d.MaxLimit, err = parsePositiveInt(value)
+		default:
+			return nil, fmt.Errorf("invalid tool default %q: unknown setting %q, expected time-range, limit, max-limit, step, max-lookback, max-range, split-interval, timeout, retries or retry-backoff", item, setting)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tool default %q: %w", item, err)
+		}
+		result[category] = d
+	}
+	return result, nil
+}
+
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
+
+func parsePositiveInt(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("value must be positive")
+	}
+	return n, nil
+}
@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestParseToolDefaults(t *testing.T) {
+	d, err := ParseToolDefaults("time-range=15m, loki.limit=50,loki.max-limit=500,loki.max-range=24h,loki.split-interval=6h,prometheus.step=30s,prometheus.timeout=1m,retries=2,tempo.retry-backoff=2s")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]mcpgrafana.ToolDefaults{
+		"":           {TimeRange: 15 * time.Minute, Retries: 2},
+		"loki":       {Limit: 50, MaxLimit: 500, MaxRange: 24 * time.Hour, SplitInterval: 6 * time.Hour},
+		"prometheus": {Step: 30 * time.Second, Timeout: time.Minute},
+		"tempo":      {RetryBackoff: 2 * time.Second},
+	}, d)
+
+	d, err = ParseToolDefaults("")
+	require.NoError(t, err)
+	assert.Empty(t, d)
+
+	for _, s := range []string{"time-range", "mimir.limit=5", "loki.offset=5", "limit=-1", "step=soon", "retries=0"} {
+		_, err := ParseToolDefaults(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestToolDefaultsFor(t *testing.T) {
+	assert.Equal(t, builtinToolDefaults["loki"], toolDefaultsFor(context.Background(), "loki"))
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{
+		ToolDefaults: map[string]mcpgrafana.ToolDefaults{
+			"":     {TimeRange: 15 * time.Minute, Limit: 5},
+			"loki": {Limit: 50, MaxLimit: 500},
+		},
+	})
+	assert.Equal(t, mcpgrafana.ToolDefaults{TimeRange: 15 * time.Minute, Limit: 50, MaxLimit: 500, SplitInterval: DefaultLokiSplitInterval}, toolDefaultsFor(ctx, "loki"))
+	assert.Equal(t, mcpgrafana.ToolDefaults{TimeRange: 15 * time.Minute, Limit: 5, MaxLimit: MaxTempoTraceLimit, Timeout: defaultTimeout}, toolDefaultsFor(ctx, "tempo"))
+
+	assert.Equal(t, 50, enforceLogLimit(ctx, 0))
+	assert.Equal(t, 200, enforceLogLimit(ctx, 200))
+	assert.Equal(t, 500, enforceLogLimit(ctx, 1000))
+	assert.Equal(t, 5, enforceTraceLimit(ctx, 0))
+
+	start, end, err := tempoTimeRange(ctx, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, end.Sub(start))
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// exportTTL is how long exported results can be read.
+	exportTTL = 30 * time.Minute
+
+	// maxStoredExports limits how many exported results are kept at once. The
+	// export closest to expiry is dropped to make room.
+	maxStoredExports = 20
+
+	// exportURIPrefix is the prefix of the URIs of exported results.
+	exportURIPrefix = "grafana://exports/"
+)
+
+// Formats results can be exported in.
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// exportSummary is returned by tools instead of results which were exported
+// to a resource.
+type exportSummary struct {
+	ResourceURI string `json:"resourceUri"`
+	MIMEType    string `json:"mimeType"`
+	Items       int    `json:"items"`
+	Bytes       int    `json:"bytes"`
+	ExpiresAt   string `json:"expiresAt"`
+	// LimitReached is set if the export holds as many items as the tool
+	// would return, so more results may exist.
+	LimitReached bool `json:"limitReached,omitempty"`
+}
+
+// storedExport is an exported result, waiting to be read.
+type storedExport struct {
+	scope    string
+	mimeType string
+	data     []byte
+	expires  time.Time
+}
+
+// exportStore keeps exported results in memory, keyed by resource URI.
+type exportStore struct {
+	mu      sync.Mutex
+	exports map[string]storedExport
+}
+
+var exports = &exportStore{exports: map[string]storedExport{}}
+
+// add stores data as a new resource which can only be read with the same
+// Grafana credentials, returning its summary. Exports are read as resources
+// rather than returned as tool results, so they are redacted here.
+func (s *exportStore) add(ctx context.Context, format, mimeType string, data []byte, items int) (*exportSummary, error) {
+	data = []byte(mcpgrafana.RedactText(ctx, string(data)))
+	id, err := newPageToken()
+	if err != nil {
+		return nil, err
+	}
+	uri := exportURIPrefix + id + "." + format
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.exports {
+		if !now.Before(e.expires) {
+			delete(s.exports, k)
+		}
+	}
+	for len(s.exports) >= maxStoredExports {
+		var oldest string
+		for k, e := range s.exports {
+			if oldest == "" || e.expires.Before(s.exports[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(s.exports, oldest)
+	}
+	expires := now.Add(exportTTL)
+	s.exports[uri] = storedExport{scope: cacheKey(ctx, "export"), mimeType: mimeType, data: data, expires: expires}
+	return &exportSummary{
+		ResourceURI: uri,
+		MIMEType:    mimeType,
+		Items:       items,
+		Bytes:       len(data),
+		ExpiresAt:   expires.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// get returns the export stored under a URI.
+func (s *exportStore) get(ctx context.Context, uri string) (storedExport, error) {
+	s.mu.Lock()
+	e, ok := s.exports[uri]
+	s.mu.Unlock()
+	if !ok || !time.Now().Before(e.expires) || e.scope != cacheKey(ctx, "export") {
+		return storedExport{}, fmt.Errorf("export %s not found or expired, run the query again", uri)
+	}
+	return e, nil
+}
+
+// validateExportFormat checks that results can be exported in a format.
+func validateExportFormat(format string) error {
+	if format != exportFormatNDJSON && format != exportFormatCSV {
+		return fmt.Errorf("unknown export format %q, expected %s or %s", format, exportFormatNDJSON, exportFormatCSV)
+	}
+	return nil
+}
+
+// exportLogEntries encodes log entries as NDJSON, one entry per line, or as
+// CSV with timestamp, datasource, labels, line and value columns, and stores
+// them as a resource.
+func exportLogEntries(ctx context.Context, format string, entries []LogEntry) (*exportSummary, error) {
+	if err := validateExportFormat(format); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	var mimeType string
+	switch format {
+	case exportFormatNDJSON:
+		mimeType = "application/x-ndjson"
+		enc := json.NewEncoder(&buf)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return nil, fmt.Errorf("encoding log entry: %w", err)
+			}
+		}
+	case exportFormatCSV:
+		mimeType = "text/csv"
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"timestamp", "datasource", "labels", "line", "value"})
+		for _, entry := range entries {
+			value := ""
+			if entry.Value != nil {
+				value = fmt.Sprint(*entry.Value)
+			}
+			_ = w.Write([]string{entry.Timestamp, entry.Datasource, toLabelSet(entry.Labels).String(), entry.Line, value})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, fmt.Errorf("encoding log entries: %w", err)
+		}
+	}
+	return exports.add(ctx, format, mimeType, buf.Bytes(), len(entries))
+}
+
+// readExport handles reads of exported results.
+func readExport(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	if !strings.HasPrefix(uri, exportURIPrefix) {
+		return nil, fmt.Errorf("unknown resource %s", uri)
+	}
+	e, err := exports.get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: e.mimeType, Text: string(e.data)},
+	}, nil
+}
+
+// addExportResources registers the resource template through which clients
+// read results exported by tools.
+func addExportResources(s *server.MCPServer) {
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate(
+			exportURIPrefix+"{id}",
+			"Exported tool results",
+			mcp.WithTemplateDescription("Full results exported by tools such as query_loki_logs and query_prometheus, as NDJSON or CSV, investigation records exported by export_investigation and dashboards exported by export_dashboard. Exports expire after 30 minutes."),
+		),
+		readExport,
+	)
+}
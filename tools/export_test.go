@@ -0,0 +1,90 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func readExportText(t *testing.T, ctx context.Context, uri string) string {
+	t.Helper()
+	var request mcp.ReadResourceRequest
+	request.Params.URI = uri
+	contents, err := readExport(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	text, ok := contents[0].(mcp.TextResourceContents)
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestExportLogEntries(t *testing.T) {
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://grafana", APIKey: "a"})
+	entries := []LogEntry{
+		{Timestamp: "2", Line: `failed, "retrying"`, Labels: map[string]string{"app": "foo"}},
+		{Timestamp: "1", Line: "started", Labels: map[string]string{"app": "foo"}},
+	}
+
+	summary, err := exportLogEntries(ctx, "csv", entries)
+	require.NoError(t, err)
+	assert.Regexp(t, `^grafana://exports/[0-9a-f]+\.csv$`, summary.ResourceURI)
+	assert.Equal(t, "text/csv", summary.MIMEType)
+	assert.Equal(t, 2, summary.Items)
+	assert.Equal(t, "timestamp,datasource,labels,line,value\n"+
+		"2,,\"{app=\"\"foo\"\"}\",\"failed, \"\"retrying\"\"\",\n"+
+		"1,,\"{app=\"\"foo\"\"}\",started,\n", readExportText(t, ctx, summary.ResourceURI))
+
+	summary, err = exportLogEntries(ctx, "ndjson", entries)
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", summary.MIMEType)
+	assert.Equal(t, `{"timestamp":"2","line":"failed, \"retrying\"","labels":{"app":"foo"}}`+"\n"+
+		`{"timestamp":"1","line":"started","labels":{"app":"foo"}}`+"\n", readExportText(t, ctx, summary.ResourceURI))
+
+	// Exports can only be read with the credentials they were created with.
+	other := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://grafana", APIKey: "b"})
+	var request mcp.ReadResourceRequest
+	request.Params.URI = summary.ResourceURI
+	_, err = readExport(other, request)
+	assert.Error(t, err)
+
+	_, err = exportLogEntries(ctx, "xml", entries)
+	assert.Error(t, err)
+}
+
+func TestQueryLokiLogsExport(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, "5000", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"app":"foo"},"values":[["1700000000000000000","hello"]]}]}}`))
+	})
+
+	result, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, Export: "ndjson"})
+	require.NoError(t, err)
+	summary, ok := unwrapResult(result).(*exportSummary)
+	require.True(t, ok)
+	assert.Equal(t, 1, summary.Items)
+	assert.False(t, summary.LimitReached)
+	assert.Equal(t, `{"timestamp":"1700000000000000000","line":"hello","labels":{"app":"foo"}}`+"\n", readExportText(t, ctx, summary.ResourceURI))
+}
+
+func TestExportLogEntriesRedacted(t *testing.T) {
+	redaction, err := mcpgrafana.ParseRedaction(`ip=\d+\.\d+\.\d+\.\d+`)
+	require.NoError(t, err)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{Redactions: []mcpgrafana.Redaction{redaction}})
+
+	summary, err := exportLogEntries(ctx, "csv", []LogEntry{{Timestamp: "1", Line: "connection from 10.0.0.1"}})
+	require.NoError(t, err)
+	text := readExportText(t, ctx, summary.ResourceURI)
+	assert.NotContains(t, text, "10.0.0.1")
+	assert.Contains(t, text, "<redacted:ip:")
+}
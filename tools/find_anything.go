@@ -0,0 +1,303 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Hit types returned by find_anything.
+const (
+	hitTypeDashboard     = "dashboard"
+	hitTypeMetric        = "metric"
+	hitTypeLogLabelValue = "logLabelValue"
+	hitTypeService       = "service"
+	hitTypeAlertRule     = "alertRule"
+)
+
+const (
+	DefaultFindAnythingLimit = 50
+
+	// maxFindDatasources limits how many datasources of each type are
+	// searched, to bound the fan-out on large instances.
+	maxFindDatasources = 5
+)
+
+// findLokiLabels are the Loki labels whose values are searched, as they
+// usually name services or workloads.
+var findLokiLabels = []string{"service_name", "app", "job", "namespace", "container"}
+
+var allHitTypes = []string{hitTypeDashboard, hitTypeMetric, hitTypeLogLabelValue, hitTypeService, hitTypeAlertRule}
+
+type FindAnythingParams struct {
+	Query     string   `json:"query" jsonschema:"required,description=The free text to search for\\, e.g. a service or metric name"`
+	StartTime string   `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range in which metrics\\, logs and traces must exist\\, in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 1 hour ago"`
+	EndTime   string   `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range in RFC3339 format or relative to now. Defaults to now"`
+	Types     []string `json:"types,omitempty" jsonschema:"description=Optionally\\, the types of hit to search for: dashboard\\, metric\\, logLabelValue\\, service and alertRule. Defaults to all"`
+	Limit     int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of hits to return (default: 50)"`
+}
+
+// findHit is a single result of find_anything.
+type findHit struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	// UID is the UID of the dashboard or alert rule.
+	UID string `json:"uid,omitempty"`
+	// DatasourceUID is the datasource the metric, label value or service
+	// was found in.
+	DatasourceUID string `json:"datasourceUid,omitempty"`
+	// Label is the Loki label whose value matched.
+	Label string `json:"label,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+type findAnythingResult struct {
+	Hits   []findHit `json:"hits"`
+	Errors []string  `json:"errors,omitempty"`
+}
+
+// matchScore scores how well a name matches the query, from 100 for an exact
+// match down to partial matches of the query's words. Zero means no match.
+func matchScore(name, query string) int {
+	name, query = strings.ToLower(name), strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0
+	}
+	switch {
+	case name == query:
+		return 100
+	case strings.HasPrefix(name, query):
+		return 80
+	case strings.Contains(name, query):
+		return 60
+	}
+	words := strings.Fields(query)
+	matched := 0
+	for _, w := range words {
+		if strings.Contains(name, w) {
+			matched++
+		}
+	}
+	if matched == 0 {
+		return 0
+	}
+	return 40 * matched / len(words)
+}
+
+// rankHits sorts hits by score, preferring shorter names on ties.
+func rankHits(hits []findHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if len(hits[i].Name) != len(hits[j].Name) {
+			return len(hits[i].Name) < len(hits[j].Name)
+		}
+		return hits[i].Name < hits[j].Name
+	})
+}
+
+// finder collects hits from concurrent searches.
+type finder struct {
+	query      string
+	start, end time.Time
+
+	mu     sync.Mutex
+	hits   []findHit
+	errors []string
+}
+
+func (f *finder) add(hit findHit) {
+	if hit.Score = matchScore(hit.Name, f.query); hit.Score == 0 {
+		return
+	}
+	f.mu.Lock()
+	f.hits = append(f.hits, hit)
+	f.mu.Unlock()
+}
+
+func (f *finder) fail(source string, err error) {
+	f.mu.Lock()
+	f.errors = append(f.errors, fmt.Sprintf("%s: %s", source, err))
+	f.mu.Unlock()
+}
+
+func (f *finder) dashboards(ctx context.Context) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := search.NewSearchParamsWithContext(ctx).WithQuery(&f.query).WithType(&dashboardTypeStr)
+	resp, err := c.Search.Search(params)
+	if err != nil {
+		f.fail("dashboards", err)
+		return
+	}
+	for _, hit := range resp.Payload {
+		f.add(findHit{Type: hitTypeDashboard, Name: hit.Title, UID: hit.UID, URL: hit.URL})
+	}
+}
+
+func (f *finder) metrics(ctx context.Context, uid string) {
+	client, err := promClientFromContext(ctx, uid)
+	if err != nil {
+		f.fail("metrics in "+uid, err)
+		return
+	}
+	names, _, err := client.LabelValues(ctx, labels.MetricName, nil, f.start, f.end)
+	if err != nil {
+		f.fail("metrics in "+uid, err)
+		return
+	}
+	for _, name := range names {
+		f.add(findHit{Type: hitTypeMetric, Name: string(name), DatasourceUID: uid})
+	}
+}
+
+func (f *finder) logLabelValues(ctx context.Context, uid string) {
+	start, end := f.start.Format(time.RFC3339), f.end.Format(time.RFC3339)
+	names, err := listLokiLabelNames(ctx, ListLokiLabelNamesParams{DatasourceUID: uid, StartRFC3339: start, EndRFC3339: end})
+	if err != nil {
+		f.fail("log labels in "+uid, err)
+		return
+	}
+	for _, label := range findLokiLabels {
+		if !slices.Contains(names, label) {
+			continue
+		}
+		values, err := listLokiLabelValues(ctx, ListLokiLabelValuesParams{DatasourceUID: uid, LabelName: label, StartRFC3339: start, EndRFC3339: end})
+		if err != nil {
+			f.fail("log label values in "+uid, err)
+			return
+		}
+		for _, v := range values {
+			f.add(findHit{Type: hitTypeLogLabelValue, Name: v, DatasourceUID: uid, Label: label})
+		}
+	}
+}
+
+func (f *finder) services(ctx context.Context, uid string) {
+	values, err := listTempoTagValues(ctx, ListTempoTagValuesParams{
+		DatasourceUID: uid,
+		TagName:       "resource.service.name",
+		StartTime:     f.start.Format(time.RFC3339),
+		EndTime:       f.end.Format(time.RFC3339),
+	})
+	if err != nil {
+		f.fail("services in "+uid, err)
+		return
+	}
+	for _, v := range values {
+		f.add(findHit{Type: hitTypeService, Name: v.Value, DatasourceUID: uid})
+	}
+}
+
+func (f *finder) alertRules(ctx context.Context) {
+	rules, err := provisionedAlertRules(ctx)
+	if err != nil {
+		f.fail("alert rules", err)
+		return
+	}
+	for _, r := range rules {
+		if r.Title != nil {
+			f.add(findHit{Type: hitTypeAlertRule, Name: *r.Title, UID: r.UID})
+		}
+	}
+}
+
+func findAnything(ctx context.Context, args FindAnythingParams) (*findAnythingResult, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	types := args.Types
+	if len(types) == 0 {
+		types = allHitTypes
+	}
+	for _, t := range types {
+		if !slices.Contains(allHitTypes, t) {
+			return nil, fmt.Errorf("unknown hit type %q, expected one of %s", t, strings.Join(allHitTypes, ", "))
+		}
+	}
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultFindAnythingLimit
+	}
+
+	f := &finder{query: args.Query, start: start, end: end}
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	if slices.Contains(types, hitTypeDashboard) {
+		run(func() { f.dashboards(ctx) })
+	}
+	if slices.Contains(types, hitTypeAlertRule) {
+		run(func() { f.alertRules(ctx) })
+	}
+	datasourceSearches := map[string]func(context.Context, string){}
+	if slices.Contains(types, hitTypeMetric) {
+		datasourceSearches["prometheus"] = f.metrics
+	}
+	if slices.Contains(types, hitTypeLogLabelValue) {
+		datasourceSearches["loki"] = f.logLabelValues
+	}
+	if slices.Contains(types, hitTypeService) {
+		datasourceSearches["tempo"] = f.services
+	}
+	if len(datasourceSearches) > 0 {
+		c := mcpgrafana.GrafanaClientFromContext(ctx)
+		resp, err := c.Datasources.GetDataSources()
+		if err != nil {
+			f.fail("datasources", err)
+		} else {
+			searched := map[string]int{}
+			for _, ds := range resp.Payload {
+				fn, ok := datasourceSearches[ds.Type]
+				if !ok || searched[ds.Type] >= maxFindDatasources {
+					continue
+				}
+				searched[ds.Type]++
+				uid := ds.UID
+				run(func() { fn(ctx, uid) })
+			}
+		}
+	}
+	wg.Wait()
+
+	rankHits(f.hits)
+	if len(f.hits) > limit {
+		f.hits = f.hits[:limit]
+	}
+	result := &findAnythingResult{Hits: f.hits, Errors: f.errors}
+	if result.Hits == nil {
+		result.Hits = []findHit{}
+	}
+	sort.Strings(result.Errors)
+	return result, nil
+}
+
+var FindAnything = mcpgrafana.MustTool(
+	"find_anything",
+	"Searches everything in Grafana for free text, such as a service name, at once: dashboard titles, Prometheus metric names, Loki label values (of the service_name, app, job, namespace and container labels), Tempo service names and alert rule titles. Metrics, logs and traces must exist within the time range (default: the last hour). Returns a single list of typed hits ranked by how closely they match, with the dashboard or alert rule UID or the datasource UID needed to follow up with other tools. Use this as the starting point for vague questions. Errors from individual sources are reported without failing the search.",
+	findAnything,
+	mcp.WithTitleAnnotation("Find anything"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,96 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchScore(t *testing.T) {
+	assert.Equal(t, 100, matchScore("Checkout", "checkout"))
+	assert.Equal(t, 80, matchScore("checkout_requests_total", "checkout"))
+	assert.Equal(t, 60, matchScore("shop-checkout", "checkout"))
+	assert.Equal(t, 20, matchScore("checkout latency", "checkout errors"))
+	assert.Zero(t, matchScore("cart", "checkout"))
+	assert.Zero(t, matchScore("cart", " "))
+}
+
+func TestFindAnything(t *testing.T) {
+	mux := http.NewServeMux()
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		}
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not found"}`))
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "checkout", r.URL.Query().Get("query"))
+		respond(`[{"uid":"dash-1","title":"Checkout overview","type":"dash-db","url":"/d/dash-1"}]`)(w, r)
+	})
+	mux.HandleFunc("/api/datasources", respond(`[
+		{"uid":"prom","type":"prometheus"},
+		{"uid":"loki","type":"loki"},
+		{"uid":"tempo","type":"tempo"},
+		{"uid":"pyro","type":"grafana-pyroscope-datasource"}
+	]`))
+	for _, uid := range []string{"prom", "loki", "tempo"} {
+		mux.HandleFunc("/api/datasources/uid/"+uid, respond(`{"uid":"`+uid+`"}`))
+	}
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/label/__name__/values",
+		respond(`{"status":"success","data":["checkout_requests_total","cart_requests_total"]}`))
+	mux.HandleFunc("/api/datasources/proxy/uid/loki/loki/api/v1/labels",
+		respond(`{"status":"success","data":["app","level"]}`))
+	mux.HandleFunc("/api/datasources/proxy/uid/loki/loki/api/v1/label/app/values",
+		respond(`{"status":"success","data":["checkout","cart"]}`))
+	mux.HandleFunc("/api/datasources/proxy/uid/tempo/api/v2/search/tag/resource.service.name/values",
+		respond(`{"tagValues":[{"type":"string","value":"checkout-service"}]}`))
+	mux.HandleFunc("/api/v1/provisioning/alert-rules", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := findAnything(ctx, FindAnythingParams{Query: "checkout"})
+	require.NoError(t, err)
+
+	var got []string
+	for _, h := range result.Hits {
+		got = append(got, h.Type+":"+h.Name)
+	}
+	assert.Equal(t, []string{
+		"logLabelValue:checkout",
+		"service:checkout-service",
+		"dashboard:Checkout overview",
+		"metric:checkout_requests_total",
+	}, got)
+	assert.Equal(t, "app", result.Hits[0].Label)
+	assert.Equal(t, "loki", result.Hits[0].DatasourceUID)
+	assert.Equal(t, "dash-1", result.Hits[2].UID)
+
+	// The alert rule search failed but the other sources were still used.
+	require.Len(t, result.Errors, 1)
+	assert.True(t, strings.HasPrefix(result.Errors[0], "alert rules: "))
+
+	t.Run("limited to types", func(t *testing.T) {
+		result, err := findAnything(ctx, FindAnythingParams{Query: "checkout", Types: []string{"metric"}, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, result.Hits, 1)
+		assert.Equal(t, "checkout_requests_total", result.Hits[0].Name)
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := findAnything(ctx, FindAnythingParams{Query: "checkout", Types: []string{"panel"}})
+		assert.ErrorContains(t, err, "unknown hit type")
+	})
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// grafanaVersionTTL is how long the detected version of a Grafana instance is
+// cached for, so upgrades are picked up without restarting the server.
+const grafanaVersionTTL = 10 * time.Minute
+
+const rulerEndpointPath = "/api/ruler/grafana/api/v1/rules"
+
+// grafanaVersion is the major, minor and patch version of a Grafana server.
+type grafanaVersion struct {
+	Major, Minor, Patch int
+}
+
+// parseGrafanaVersion parses versions such as "11.2.0", "10.4.1+security-01"
+// or "12.0.0-pre".
+func parseGrafanaVersion(s string) (grafanaVersion, error) {
+	core, _, _ := strings.Cut(strings.TrimPrefix(s, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Split(core, ".")
+	if len(parts) < 2 {
+		return grafanaVersion{}, fmt.Errorf("invalid Grafana version %q", s)
+	}
+	var nums [3]int
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return grafanaVersion{}, fmt.Errorf("invalid Grafana version %q: %w", s, err)
+		}
+		nums[i] = n
+	}
+	return grafanaVersion{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+func (v grafanaVersion) atLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// grafanaAPI selects between legacy and current Grafana HTTP APIs based on
+// the version of the Grafana server, so tools keep a single surface across
+// Grafana versions. If the version cannot be determined the current APIs are
+// assumed.
+//
+// Only APIs which differ between the supported Grafana versions are
+// negotiated: listing alert rules, and the app platform APIs used to watch
+// dashboards and folders. Other dashboard and folder tools use the
+// /api/dashboards and /api/folders endpoints, which every version from 9.x
+// serves, so they are not negotiated.
+type grafanaAPI struct {
+	version grafanaVersion
+	known   bool
+}
+
+func (a grafanaAPI) supports(major, minor int) bool {
+	return !a.known || a.version.atLeast(major, minor)
+}
+
+// hasAlertRuleListAPI reports whether the provisioning API can list all alert
+// rules. Older versions only expose them through the ruler API.
+func (a grafanaAPI) hasAlertRuleListAPI() bool {
+	return a.supports(10, 0)
+}
+
+// hasAppPlatformAPIs reports whether dashboards and folders are served by the
+// Kubernetes-style app platform APIs under /apis.
+func (a grafanaAPI) hasAppPlatformAPIs() bool {
+	return a.supports(12, 0)
+}
+
+type grafanaAPICacheEntry struct {
+	api     grafanaAPI
+	expires time.Time
+}
+
+var grafanaAPICache = struct {
+	sync.Mutex
+	entries map[string]grafanaAPICacheEntry
+}{entries: map[string]grafanaAPICacheEntry{}}
+
+// grafanaAPIFromContext detects the version of the Grafana instance in the
+// context using its health endpoint, caching the result per Grafana URL. If
+// the health endpoint cannot be read, such as when it is blocked by a proxy,
+// the current APIs are assumed and detection is retried on the next call.
+func grafanaAPIFromContext(ctx context.Context) grafanaAPI {
+	key := mcpgrafana.GrafanaConfigFromContext(ctx).URL
+
+	grafanaAPICache.Lock()
+	entry, ok := grafanaAPICache.entries[key]
+	grafanaAPICache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.api
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	health, err := c.Health.GetHealth()
+	if err != nil {
+		slog.Debug("failed to detect Grafana version, assuming current APIs", "error", err)
+		return grafanaAPI{}
+	}
+	var api grafanaAPI
+	if version, err := parseGrafanaVersion(health.Payload.Version); err == nil {
+		api = grafanaAPI{version: version, known: true}
+	}
+
+	grafanaAPICache.Lock()
+	grafanaAPICache.entries[key] = grafanaAPICacheEntry{api: api, expires: time.Now().Add(grafanaVersionTTL)}
+	grafanaAPICache.Unlock()
+	return api
+}
+
+// rulerRuleGroup is a rule group as returned by the Grafana ruler API.
+type rulerRuleGroup struct {
+	Name  string `json:"name"`
+	Rules []struct {
+		For          string            `json:"for,omitempty"`
+		Labels       map[string]string `json:"labels,omitempty"`
+		Annotations  map[string]string `json:"annotations,omitempty"`
+		GrafanaAlert struct {
+			UID          string               `json:"uid"`
+			Title        string               `json:"title"`
+			Condition    string               `json:"condition"`
+			Data         []*models.AlertQuery `json:"data"`
+			NamespaceUID string               `json:"namespace_uid"`
+			RuleGroup    string               `json:"rule_group"`
+			NoDataState  string               `json:"no_data_state"`
+			ExecErrState string               `json:"exec_err_state"`
+			IsPaused     bool                 `json:"is_paused,omitempty"`
+			Provenance   string               `json:"provenance,omitempty"`
+		} `json:"grafana_alert"`
+	} `json:"rules"`
+}
+
+// provisionedAlertRules lists all Grafana-managed alert rules in the
+// provisioning API format, falling back to the ruler API on Grafana versions
+// which cannot list rules through the provisioning API.
+func provisionedAlertRules(ctx context.Context) ([]*models.ProvisionedAlertRule, error) {
+	if grafanaAPIFromContext(ctx).hasAlertRuleListAPI() {
+		c := mcpgrafana.GrafanaClientFromContext(ctx)
+		rules, err := c.Provisioning.GetAlertRules()
+		if err != nil {
+			return nil, fmt.Errorf("list alert rules: %w", err)
+		}
+		return rules.Payload, nil
+	}
+
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating alerting client: %w", err)
+	}
+	resp, err := client.makeRequest(ctx, rulerEndpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var namespaces map[string][]rulerRuleGroup
+	if err := json.NewDecoder(resp.Body).Decode(&namespaces); err != nil {
+		return nil, fmt.Errorf("failed to decode rules response from %s: %w", rulerEndpointPath, err)
+	}
+	return rulerToProvisionedAlertRules(namespaces), nil
+}
+
+// rulerToProvisionedAlertRules converts rules from the ruler API format,
+// ordering them by folder and rule group.
+func rulerToProvisionedAlertRules(namespaces map[string][]rulerRuleGroup) []*models.ProvisionedAlertRule {
+	var result []*models.ProvisionedAlertRule
+	for _, namespace := range sortedKeys(namespaces) {
+		for _, group := range namespaces[namespace] {
+			for _, r := range group.Rules {
+				ga := r.GrafanaAlert
+				rule := &models.ProvisionedAlertRule{
+					UID:          ga.UID,
+					Title:        &ga.Title,
+					Condition:    &ga.Condition,
+					Data:         ga.Data,
+					FolderUID:    &ga.NamespaceUID,
+					RuleGroup:    &ga.RuleGroup,
+					NoDataState:  &ga.NoDataState,
+					ExecErrState: &ga.ExecErrState,
+					IsPaused:     ga.IsPaused,
+					Labels:       r.Labels,
+					Annotations:  r.Annotations,
+					Provenance:   models.Provenance(ga.Provenance),
+				}
+				if rule.RuleGroup == nil || *rule.RuleGroup == "" {
+					rule.RuleGroup = &group.Name
+				}
+				if d, err := time.ParseDuration(r.For); err == nil {
+					duration := strfmt.Duration(d)
+					rule.For = &duration
+				}
+				result = append(result, rule)
+			}
+		}
+	}
+	return result
+}
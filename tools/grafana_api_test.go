@@ -0,0 +1,163 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGrafanaVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		want    grafanaVersion
+	}{
+		{"11.2.0", grafanaVersion{11, 2, 0}},
+		{"10.4.1+security-01", grafanaVersion{10, 4, 1}},
+		{"12.0.0-pre", grafanaVersion{12, 0, 0}},
+		{"v9.5", grafanaVersion{9, 5, 0}},
+	} {
+		got, err := parseGrafanaVersion(tc.version)
+		require.NoError(t, err, tc.version)
+		assert.Equal(t, tc.want, got, tc.version)
+	}
+
+	for _, version := range []string{"", "11", "eleven.0.0", "11.x"} {
+		_, err := parseGrafanaVersion(version)
+		assert.Error(t, err, version)
+	}
+
+	v := grafanaVersion{10, 4, 1}
+	assert.True(t, v.atLeast(10, 0))
+	assert.True(t, v.atLeast(9, 9))
+	assert.False(t, v.atLeast(10, 5))
+	assert.False(t, v.atLeast(11, 0))
+}
+
+func newMockGrafanaVersionContext(t *testing.T, version string, mux *http.ServeMux) context.Context {
+	t.Helper()
+	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"database":"ok","version":"` + version + `"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { forgetGrafanaVersion(server.URL) })
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{
+		URL:    server.URL,
+		APIKey: "test-api-key",
+	})
+	return mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, "test-api-key"))
+}
+
+// forgetGrafanaVersion removes a test server from the version cache, as its
+// URL may be reused by a later test.
+func forgetGrafanaVersion(url string) {
+	grafanaAPICache.Lock()
+	delete(grafanaAPICache.entries, url)
+	grafanaAPICache.Unlock()
+}
+
+func TestGrafanaAPIFromContext(t *testing.T) {
+	t.Run("cached per Grafana URL", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/api/health", r.URL.Path)
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"database":"ok","version":"9.5.2"}`))
+		}))
+		t.Cleanup(server.Close)
+		t.Cleanup(func() { forgetGrafanaVersion(server.URL) })
+		ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+		ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+		api := grafanaAPIFromContext(ctx)
+		assert.Equal(t, grafanaVersion{9, 5, 2}, api.version)
+		assert.False(t, api.hasAlertRuleListAPI())
+
+		api = grafanaAPIFromContext(ctx)
+		assert.Equal(t, grafanaVersion{9, 5, 2}, api.version)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("unknown version assumes current APIs", func(t *testing.T) {
+		api := grafanaAPIFromContext(newMockGrafanaVersionContext(t, "", http.NewServeMux()))
+		assert.True(t, api.hasAlertRuleListAPI())
+	})
+
+	t.Run("unreachable health endpoint assumes current APIs", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}))
+		t.Cleanup(server.Close)
+		ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+		ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+		api := grafanaAPIFromContext(ctx)
+		assert.False(t, api.known)
+		assert.True(t, api.hasAlertRuleListAPI())
+		assert.True(t, api.hasAppPlatformAPIs())
+	})
+}
+
+func TestProvisionedAlertRules(t *testing.T) {
+	t.Run("provisioning API on current versions", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v1/provisioning/alert-rules", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"uid":"rule-1","title":"High latency","folderUID":"folder-1","ruleGroup":"latency","provenance":"file"}]`))
+		})
+		ctx := newMockGrafanaVersionContext(t, "11.1.0", mux)
+
+		rules, err := provisionedAlertRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, "rule-1", rules[0].UID)
+		assert.Equal(t, "folder-1", *rules[0].FolderUID)
+	})
+
+	t.Run("ruler API on Grafana 9", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc(rulerEndpointPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"Team B": [{"name": "errors", "rules": [
+					{"for": "5m", "labels": {"team": "b"}, "grafana_alert": {"uid": "rule-2", "title": "Errors", "condition": "A", "namespace_uid": "folder-b", "rule_group": "errors", "data": [{"refId": "A", "datasourceUid": "prometheus"}]}}
+				]}],
+				"Team A": [{"name": "latency", "rules": [
+					{"grafana_alert": {"uid": "rule-1", "title": "High latency", "namespace_uid": "folder-a", "provenance": "file"}}
+				]}]
+			}`))
+		})
+		ctx := newMockGrafanaVersionContext(t, "9.3.6", mux)
+
+		rules, err := provisionedAlertRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+
+		assert.Equal(t, "rule-1", rules[0].UID)
+		assert.Equal(t, "folder-a", *rules[0].FolderUID)
+		assert.Equal(t, "latency", *rules[0].RuleGroup)
+		assert.EqualValues(t, "file", rules[0].Provenance)
+		assert.Nil(t, rules[0].For)
+
+		assert.Equal(t, "rule-2", rules[1].UID)
+		assert.Equal(t, "Errors", *rules[1].Title)
+		assert.Equal(t, "errors", *rules[1].RuleGroup)
+		assert.Equal(t, map[string]string{"team": "b"}, rules[1].Labels)
+		require.NotNil(t, rules[1].For)
+		assert.Equal(t, 5*time.Minute, time.Duration(*rules[1].For))
+		require.Len(t, rules[1].Data, 1)
+		assert.Equal(t, "prometheus", rules[1].Data[0].DatasourceUID)
+	})
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// timeRangeClamp reports that a requested time range was narrowed to the
+// maximum lookback or range configured for a datasource type.
+type timeRangeClamp struct {
+	RequestedStart time.Time `json:"requestedStart"`
+	RequestedEnd   time.Time `json:"requestedEnd"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Reason         string    `json:"reason"`
+}
+
+// timeRangeFailure reports a part of a time range which could not be queried
+// when a query was split into several.
+type timeRangeFailure struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Error string    `json:"error"`
+}
+
+// clampedResult wraps a tool result whose time range was clamped, or only
+// partly queried.
+type clampedResult[R any] struct {
+	Result           R                  `json:"result" jsonschema:"required"`
+	Inspect          *queryInspection   `json:"inspect,omitempty"`
+	TimeRangeClamped *timeRangeClamp    `json:"timeRangeClamped,omitempty"`
+	PartialFailures  []timeRangeFailure `json:"partialFailures,omitempty"`
+}
+
+// guarded is the result of a tool wrapped by guardTimeRange: R, or a
+// clampedResult of R if its time range was clamped or partly queried.
+type guarded[R any] struct{ resultValue }
+
+func (guarded[R]) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[R](), reflect.TypeFor[clampedResult[R]]()}
+}
+
+// timeRangeClampRecorder records the clamp applied, and the parts of the time
+// range which failed, during a tool call.
+type timeRangeClampRecorder struct {
+	mu       sync.Mutex
+	clamp    *timeRangeClamp
+	failures []timeRangeFailure
+}
+
+type timeRangeClampRecorderKey struct{}
+
+// clampTimeRange narrows a time range to the maximum lookback and range
+// configured for the tool category, keeping the most recent part of the
+// range. Any clamp is recorded for guardTimeRange to report. It fails if the
+// whole range is older than the maximum lookback.
+func clampTimeRange(ctx context.Context, category string, start, end time.Time) (time.Time, time.Time, error) {
+	d := toolDefaultsFor(ctx, category)
+	clamp := timeRangeClamp{RequestedStart: start, RequestedEnd: end, Start: start, End: end}
+
+	if d.MaxLookback > 0 {
+		earliest := time.Now().Add(-d.MaxLookback)
+		if !end.After(earliest) {
+			return time.Time{}, time.Time{}, fmt.Errorf("time range ends at %s, before the maximum lookback of %s for %s", end.Format(time.RFC3339), d.MaxLookback, category)
+		}
+		if clamp.Start.Before(earliest) {
+			clamp.Start = earliest
+			clamp.Reason = fmt.Sprintf("start time is limited to %s ago for %s", d.MaxLookback, category)
+		}
+	}
+	if d.MaxRange > 0 && clamp.End.Sub(clamp.Start) > d.MaxRange {
+		clamp.Start = clamp.End.Add(-d.MaxRange)
+		clamp.Reason = fmt.Sprintf("time range is limited to %s for %s", d.MaxRange, category)
+	}
+
+	if clamp.Reason != "" {
+		if recorder, ok := ctx.Value(timeRangeClampRecorderKey{}).(*timeRangeClampRecorder); ok {
+			recorder.mu.Lock()
+			recorder.clamp = &clamp
+			recorder.mu.Unlock()
+		}
+	}
+	return clamp.Start, clamp.End, nil
+}
+
+// recordTimeRangeFailure records that part of a tool call's time range could
+// not be queried, for guardTimeRange to report.
+func recordTimeRangeFailure(ctx context.Context, start, end time.Time, err error) {
+	if recorder, ok := ctx.Value(timeRangeClampRecorderKey{}).(*timeRangeClampRecorder); ok {
+		recorder.mu.Lock()
+		recorder.failures = append(recorder.failures, timeRangeFailure{Start: start, End: end, Error: err.Error()})
+		recorder.mu.Unlock()
+	}
+}
+
+// guardTimeRange wraps a tool handler so that, if its time range was clamped,
+// the result is returned together with the requested and actual ranges, and
+// if parts of it failed, with the failed parts. Otherwise the result is
+// returned unchanged.
+func guardTimeRange[T any, R any](fn mcpgrafana.ToolHandlerFunc[T, R]) mcpgrafana.ToolHandlerFunc[T, guarded[R]] {
+	return func(ctx context.Context, args T) (guarded[R], error) {
+		recorder := &timeRangeClampRecorder{}
+		result, err := fn(context.WithValue(ctx, timeRangeClampRecorderKey{}, recorder), args)
+		if err != nil {
+			return guarded[R]{}, err
+		}
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		if recorder.clamp == nil && len(recorder.failures) == 0 {
+			return asResult[guarded[R]](result, nil)
+		}
+		clamped := clampedResult[R]{Result: result, TimeRangeClamped: recorder.clamp, PartialFailures: recorder.failures}
+		// Report the inspection of an inspected result next to the clamp.
+		if inspected, ok := any(&clamped.Result).(interface{ uninspect() *queryInspection }); ok {
+			clamped.Inspect = inspected.uninspect()
+		}
+		return asResult[guarded[R]](clamped, nil)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func withToolDefaults(ctx context.Context, defaults map[string]mcpgrafana.ToolDefaults) context.Context {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	cfg.ToolDefaults = defaults
+	return mcpgrafana.WithGrafanaConfig(ctx, cfg)
+}
+
+func TestClampTimeRange(t *testing.T) {
+	now := time.Now()
+	ctx := withToolDefaults(context.Background(), map[string]mcpgrafana.ToolDefaults{
+		"loki": {MaxLookback: 30 * 24 * time.Hour, MaxRange: 24 * time.Hour},
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		start, end, err := clampTimeRange(ctx, "loki", now.Add(-time.Hour), now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-time.Hour), start)
+		assert.Equal(t, now, end)
+	})
+
+	t.Run("range too long", func(t *testing.T) {
+		start, end, err := clampTimeRange(ctx, "loki", now.Add(-7*24*time.Hour), now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-24*time.Hour), start)
+		assert.Equal(t, now, end)
+	})
+
+	t.Run("start too old", func(t *testing.T) {
+		end := now.Add(-29*24*time.Hour - 12*time.Hour)
+		start, _, err := clampTimeRange(ctx, "loki", now.Add(-90*24*time.Hour), end)
+		require.NoError(t, err)
+		assert.WithinDuration(t, now.Add(-30*24*time.Hour), start, time.Second)
+	})
+
+	t.Run("entirely too old", func(t *testing.T) {
+		_, _, err := clampTimeRange(ctx, "loki", now.Add(-90*24*time.Hour), now.Add(-60*24*time.Hour))
+		assert.ErrorContains(t, err, "before the maximum lookback")
+	})
+
+	t.Run("unlimited category", func(t *testing.T) {
+		start, _, err := clampTimeRange(ctx, "tempo", now.Add(-90*24*time.Hour), now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-90*24*time.Hour), start)
+	})
+}
+
+func TestGuardTimeRange(t *testing.T) {
+	var start, end int64
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		start, _ = strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ = strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+	})
+	ctx = withToolDefaults(ctx, map[string]mcpgrafana.ToolDefaults{"loki": {MaxRange: 24 * time.Hour}})
+	handler := guardTimeRange(queryLokiLogsTool)
+
+	result, err := handler(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, StartRFC3339: "now-90d"})
+	require.NoError(t, err)
+	clamped, ok := unwrapResult(result).(clampedResult[queryLokiLogsResult])
+	require.True(t, ok, "expected a clamped result, got %T", result)
+	assert.Equal(t, []LogEntry{}, unwrapResult(clamped.Result))
+	assert.Contains(t, clamped.TimeRangeClamped.Reason, "limited to 24h0m0s")
+	assert.Equal(t, 24*time.Hour, time.Duration(end-start))
+	assert.Equal(t, 90*24*time.Hour, clamped.TimeRangeClamped.RequestedEnd.Sub(clamped.TimeRangeClamped.RequestedStart).Round(time.Hour))
+
+	t.Run("with debug", func(t *testing.T) {
+		result, err := handler(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, StartRFC3339: "now-90d", Debug: true})
+		require.NoError(t, err)
+		clamped := unwrapResult(result).(clampedResult[queryLokiLogsResult])
+		assert.Equal(t, []LogEntry{}, unwrapResult(clamped.Result))
+		assert.NotNil(t, clamped.Inspect)
+	})
+
+	t.Run("not clamped", func(t *testing.T) {
+		result, err := handler(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, StartRFC3339: "now-1h"})
+		require.NoError(t, err)
+		assert.Equal(t, []LogEntry{}, unwrapResult(result))
+	})
+}
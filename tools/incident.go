@@ -125,6 +125,9 @@ func AddIncidentTools(mcp *server.MCPServer) {
 	CreateIncident.Register(mcp)
 	AddActivityToIncident.Register(mcp)
 	GetIncident.Register(mcp)
+	BuildIncidentTimeline.Register(mcp)
+	ExportInvestigation.Register(mcp)
+	addExportResources(mcp)
 }
 
 type GetIncidentParams struct {
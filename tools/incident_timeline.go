@@ -0,0 +1,470 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/client/annotations"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultTimelineRange is how far back build_incident_timeline looks if
+	// no start time is given.
+	defaultTimelineRange = time.Hour
+
+	// maxTimelineAnnotations limits how many annotations of each kind are
+	// read.
+	maxTimelineAnnotations = 500
+
+	// maxTimelineChangePoints limits how many change points are reported for
+	// each series.
+	maxTimelineChangePoints = 3
+
+	// changePointThreshold is how many standard deviations the mean of a
+	// series must shift by to be reported as a change point.
+	changePointThreshold = 3.0
+
+	// timelineErrorFilter selects log lines which look like errors.
+	timelineErrorFilter = `|~ "(?i)(error|exception|fatal|panic)"`
+)
+
+// Kinds of incident timeline events.
+const (
+	timelineEventAlert           = "alert"
+	timelineEventDeployment      = "deployment"
+	timelineEventChangePoint     = "change_point"
+	timelineEventFirstErrorLog   = "first_error_log"
+	timelineEventFirstErrorTrace = "first_error_trace"
+)
+
+// BuildIncidentTimelineParams defines the parameters for building an incident
+// timeline for a service.
+type BuildIncidentTimelineParams struct {
+	Service              string   `json:"service" jsonschema:"required,description=The name of the affected service. Alert rules are matched by this name in their title or labels"`
+	StartTime            string   `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the incident window in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 1 hour ago"`
+	EndTime              string   `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the incident window in RFC3339 format or relative to now. Defaults to now"`
+	DeploymentTags       []string `json:"deploymentTags,omitempty" jsonschema:"description=Optionally\\, the tags all deployment annotations have (default: deployment). Add the service name if deployments are tagged with it"`
+	PrometheusUID        string   `json:"prometheusUid,omitempty" jsonschema:"description=Optionally\\, the UID of a Prometheus datasource to look for change points in"`
+	MetricExpr           string   `json:"metricExpr,omitempty" jsonschema:"description=Optionally\\, the PromQL expression to look for change points in\\, such as the service's error rate or latency. Requires prometheusUid"`
+	StepSeconds          int      `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the step of the metric query in seconds. Defaults to 1/200th of the window\\, at least 15 seconds"`
+	LokiUID              string   `json:"lokiUid,omitempty" jsonschema:"description=Optionally\\, the UID of a Loki datasource to find the first error log line in"`
+	LogSelector          string   `json:"logSelector,omitempty" jsonschema:"description=Optionally\\, the LogQL stream selector of the service's logs (default: {service_name=\"<service>\"}). Lines matching error\\, exception\\, fatal or panic are errors"`
+	TempoUID             string   `json:"tempoUid,omitempty" jsonschema:"description=Optionally\\, the UID of a Tempo datasource to find the first error trace of the service in"`
+	IncludeAlertResolved bool     `json:"includeAlertResolved,omitempty" jsonschema:"description=Optionally\\, also include alerts returning to normal"`
+}
+
+// timelineEvent is a single event of an incident timeline.
+type timelineEvent struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Source is where the event was found: "annotations" or the UID of a
+	// datasource.
+	Source  string            `json:"source"`
+	Title   string            `json:"title"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+type incidentTimeline struct {
+	Service string          `json:"service"`
+	Start   time.Time       `json:"start"`
+	End     time.Time       `json:"end"`
+	Events  []timelineEvent `json:"events"`
+	// Errors lists the sources which could not be read. The timeline holds
+	// the events of the other sources.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// timelineTimeRange parses the optional start and end of a window, which
+// defaults to the given duration before the end.
+func timelineTimeRange(startStr, endStr string, def time.Duration) (time.Time, time.Time, error) {
+	end := time.Now()
+	var err error
+	if endStr != "" {
+		if end, err = parseTime(endStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	start := end.Add(-def)
+	if startStr != "" {
+		if start, err = parseTime(startStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start timestamp %q must be strictly before end timestamp %q", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+// getTimelineAnnotations reads the annotations of a type and with all the
+// given tags in a window.
+func getTimelineAnnotations(ctx context.Context, annotationType string, tags []string, start, end time.Time) ([]*models.Annotation, error) {
+	from, to, limit := start.UnixMilli(), end.UnixMilli(), int64(maxTimelineAnnotations)
+	params := annotations.NewGetAnnotationsParamsWithContext(ctx).
+		WithType(&annotationType).
+		WithFrom(&from).
+		WithTo(&to).
+		WithLimit(&limit)
+	if len(tags) > 0 {
+		params = params.WithTags(tags)
+	}
+	resp, err := mcpgrafana.GrafanaClientFromContext(ctx).Annotations.GetAnnotations(params)
+	if err != nil {
+		return nil, fmt.Errorf("getting annotations: %w", err)
+	}
+	return resp.Payload, nil
+}
+
+// alertTimelineEvents returns the alert state changes mentioning a service.
+func alertTimelineEvents(ctx context.Context, args BuildIncidentTimelineParams, start, end time.Time) ([]timelineEvent, error) {
+	alerts, err := getTimelineAnnotations(ctx, "alert", nil, start, end)
+	if err != nil {
+		return nil, err
+	}
+	service := strings.ToLower(args.Service)
+	var events []timelineEvent
+	for _, a := range alerts {
+		firing := strings.HasPrefix(a.NewState, "Alerting")
+		resolved := a.NewState == "Normal" && strings.HasPrefix(a.PrevState, "Alerting")
+		if !firing && !(resolved && args.IncludeAlertResolved) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(a.AlertName+" "+a.Text), service) {
+			continue
+		}
+		details := map[string]string{"state": a.NewState, "previousState": a.PrevState}
+		if a.Text != "" {
+			details["text"] = a.Text
+		}
+		if a.DashboardUID != "" {
+			details["dashboardUid"] = a.DashboardUID
+		}
+		events = append(events, timelineEvent{
+			Time:    time.UnixMilli(a.Time),
+			Kind:    timelineEventAlert,
+			Source:  "annotations",
+			Title:   fmt.Sprintf("%s: %s", stringOrDefault(a.AlertName, "alert"), a.NewState),
+			Details: details,
+		})
+	}
+	return events, nil
+}
+
+// deploymentTimelineEvents returns the deployment annotations in the window.
+func deploymentTimelineEvents(ctx context.Context, args BuildIncidentTimelineParams, start, end time.Time) ([]timelineEvent, error) {
+	tags := args.DeploymentTags
+	if len(tags) == 0 {
+		tags = []string{"deployment"}
+	}
+	deployments, err := getTimelineAnnotations(ctx, "annotation", tags, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var events []timelineEvent
+	for _, a := range deployments {
+		events = append(events, timelineEvent{
+			Time:    time.UnixMilli(a.Time),
+			Kind:    timelineEventDeployment,
+			Source:  "annotations",
+			Title:   stringOrDefault(a.Text, "deployment"),
+			Details: map[string]string{"tags": strings.Join(a.Tags, ",")},
+		})
+	}
+	return events, nil
+}
+
+// changePoint is a point where the mean of a series shifts.
+type changePoint struct {
+	Index  int
+	Before float64
+	After  float64
+	Score  float64
+}
+
+// meanAndVariance returns the mean and variance of values.
+func meanAndVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, sq / float64(len(values))
+}
+
+// findChangePoints finds where the mean of the window of values before a
+// point differs from the mean of the window after it by more than
+// changePointThreshold pooled standard deviations. Only the strongest point
+// of nearby candidates is kept, and at most max points are returned, in
+// order.
+func findChangePoints(values []float64, max int) []changePoint {
+	window := len(values) / 10
+	if window < 3 {
+		window = 3
+	}
+	if window > 20 {
+		window = 20
+	}
+	var candidates []changePoint
+	for i := window; i+window <= len(values); i++ {
+		before, beforeVar := meanAndVariance(values[i-window : i])
+		after, afterVar := meanAndVariance(values[i : i+window])
+		// Flat series would make any shift infinitely significant, so the
+		// deviation is at least 1% of the larger mean.
+		sd := math.Max(math.Sqrt((beforeVar+afterVar)/2), 0.01*math.Max(math.Abs(before), math.Abs(after)))
+		if sd == 0 {
+			continue
+		}
+		if score := math.Abs(after-before) / sd; score >= changePointThreshold {
+			candidates = append(candidates, changePoint{Index: i, Before: before, After: after, Score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	var points []changePoint
+	for _, c := range candidates {
+		if len(points) == max {
+			break
+		}
+		near := false
+		for _, p := range points {
+			if c.Index-p.Index < window && p.Index-c.Index < window {
+				near = true
+				break
+			}
+		}
+		if !near {
+			points = append(points, c)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Index < points[j].Index })
+	return points
+}
+
+// formatTimelineValue formats a series value for an event title.
+func formatTimelineValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// changePointTimelineEvents returns the change points of each series of a
+// PromQL range query over the window.
+func changePointTimelineEvents(ctx context.Context, args BuildIncidentTimelineParams, start, end time.Time) ([]timelineEvent, error) {
+	step := time.Duration(args.StepSeconds) * time.Second
+	return queryChangePoints(ctx, args.PrometheusUID, args.MetricExpr, step, start, end, func(model.Metric) string {
+		return args.MetricExpr
+	})
+}
+
+// queryChangePoints runs a PromQL range query and returns the change points of
+// each series as events, titled with the name returned for the series. The
+// step defaults to 1/200th of the window, at least 15 seconds.
+func queryChangePoints(ctx context.Context, datasourceUID, expr string, step time.Duration, start, end time.Time, name func(model.Metric) string) ([]timelineEvent, error) {
+	start, end, err := clampTimeRange(ctx, defaultsCategoryPrometheus, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		step = (end.Sub(start) / 200).Truncate(time.Second)
+		if step < 15*time.Second {
+			step = 15 * time.Second
+		}
+	}
+
+	promClient, err := promClientFromContext(ctx, datasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	result, _, err := promClient.QueryRange(ctx, expr, promv1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus range: %w", err)
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("expected a range vector from %q, got %s", expr, result.Type())
+	}
+
+	var events []timelineEvent
+	for _, series := range matrix {
+		values := make([]float64, 0, len(series.Values))
+		for _, v := range series.Values {
+			values = append(values, float64(v.Value))
+		}
+		for _, p := range findChangePoints(values, maxTimelineChangePoints) {
+			events = append(events, timelineEvent{
+				Time:   series.Values[p.Index].Timestamp.Time(),
+				Kind:   timelineEventChangePoint,
+				Source: datasourceUID,
+				Title:  fmt.Sprintf("%s changed from %s to %s", name(series.Metric), formatTimelineValue(p.Before), formatTimelineValue(p.After)),
+				Details: map[string]string{
+					"series": series.Metric.String(),
+					"score":  strconv.FormatFloat(p.Score, 'f', 1, 64),
+				},
+			})
+		}
+	}
+	return events, nil
+}
+
+// firstErrorLogTimelineEvent returns the first log line of the service which
+// looks like an error.
+func firstErrorLogTimelineEvent(ctx context.Context, args BuildIncidentTimelineParams, start, end time.Time) ([]timelineEvent, error) {
+	start, end, err := clampTimeRange(ctx, defaultsCategoryLoki, start, end)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newLokiClient(ctx, args.LokiUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	selector := stringOrDefault(args.LogSelector, fmt.Sprintf("{service_name=%s}", strconv.Quote(args.Service)))
+	query := selector + " " + timelineErrorFilter
+	streams, err := client.fetchLogs(ctx, query, start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano), 1, "forward")
+	if err != nil {
+		return nil, err
+	}
+	entries := streamsToLogEntries(streams)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	sortLogEntries(entries)
+	first := entries[0]
+	ns, err := strconv.ParseInt(first.Timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing log timestamp %q: %w", first.Timestamp, err)
+	}
+	return []timelineEvent{{
+		Time:   time.Unix(0, ns),
+		Kind:   timelineEventFirstErrorLog,
+		Source: args.LokiUID,
+		Title:  first.Line,
+		Details: map[string]string{
+			"labels": toLabelSet(first.Labels).String(),
+			"query":  query,
+		},
+	}}, nil
+}
+
+// firstErrorTraceTimelineEvent returns the earliest trace found with an
+// error span in the service.
+func firstErrorTraceTimelineEvent(ctx context.Context, args BuildIncidentTimelineParams, start, end time.Time) ([]timelineEvent, error) {
+	start, end, err := clampTimeRange(ctx, defaultsCategoryTempo, start, end)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newTempoClient(ctx, args.TempoUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+	query := fmt.Sprintf("{ resource.service.name = %s && status = error }", strconv.Quote(args.Service))
+	traces, err := client.search(ctx, tempoSearchRequest{Query: query, Start: start, End: end, Limit: MaxTempoTraceLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tempo doesn't return search results in time order, so the earliest
+	// found trace is used.
+	var first *tempoSearchResult
+	var firstStart int64
+	for i, t := range traces {
+		ns, err := strconv.ParseInt(t.StartTimeUnixNano, 10, 64)
+		if err != nil {
+			continue
+		}
+		if first == nil || ns < firstStart {
+			first, firstStart = &traces[i], ns
+		}
+	}
+	if first == nil {
+		return nil, nil
+	}
+	return []timelineEvent{{
+		Time:   time.Unix(0, firstStart),
+		Kind:   timelineEventFirstErrorTrace,
+		Source: args.TempoUID,
+		Title:  strings.TrimSpace(first.RootServiceName + " " + first.RootTraceName),
+		Details: map[string]string{
+			"traceId": first.TraceID,
+			"query":   query,
+		},
+	}}, nil
+}
+
+func buildIncidentTimeline(ctx context.Context, args BuildIncidentTimelineParams) (*incidentTimeline, error) {
+	if args.Service == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+	if args.MetricExpr != "" && args.PrometheusUID == "" {
+		return nil, fmt.Errorf("prometheusUid is required with metricExpr")
+	}
+	start, end, err := timelineTimeRange(args.StartTime, args.EndTime, defaultTimelineRange)
+	if err != nil {
+		return nil, err
+	}
+
+	type source struct {
+		name  string
+		fetch func(context.Context, BuildIncidentTimelineParams, time.Time, time.Time) ([]timelineEvent, error)
+	}
+	sources := []source{
+		{"alerts", alertTimelineEvents},
+		{"deployments", deploymentTimelineEvents},
+	}
+	if args.MetricExpr != "" {
+		sources = append(sources, source{"change points", changePointTimelineEvents})
+	}
+	if args.LokiUID != "" {
+		sources = append(sources, source{"error logs", firstErrorLogTimelineEvent})
+	}
+	if args.TempoUID != "" {
+		sources = append(sources, source{"error traces", firstErrorTraceTimelineEvent})
+	}
+
+	events := make([][]timelineEvent, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events[i], errs[i] = s.fetch(ctx, args, start, end)
+		}()
+	}
+	wg.Wait()
+
+	timeline := &incidentTimeline{Service: args.Service, Start: start, End: end, Events: []timelineEvent{}}
+	for i, s := range sources {
+		if errs[i] != nil {
+			timeline.Errors = append(timeline.Errors, fmt.Sprintf("%s: %s", s.name, errs[i]))
+			continue
+		}
+		timeline.Events = append(timeline.Events, events[i]...)
+	}
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].Time.Before(timeline.Events[j].Time)
+	})
+	return timeline, nil
+}
+
+var BuildIncidentTimeline = mcpgrafana.MustTool(
+	"build_incident_timeline",
+	"Builds a single time-ordered timeline of what happened to a service during an incident window, merging alert firings for the service and deployment annotations from Grafana, change points (sudden shifts of the mean) in a PromQL expression such as the service's error rate, and the first error log line and first error trace of the service. The metric, log and trace sources are only used when their datasource UIDs are given. Each event has a time, kind, source, title and details; sources which fail are listed under errors. Use this as the backbone of an incident summary. Defaults to the last hour.",
+	guardTimeRange(buildIncidentTimeline),
+	mcp.WithTitleAnnotation("Build incident timeline"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,155 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindChangePoints(t *testing.T) {
+	t.Run("step change", func(t *testing.T) {
+		values := make([]float64, 100)
+		for i := range values {
+			values[i] = 1
+			if i >= 60 {
+				values[i] = 5
+			}
+		}
+		points := findChangePoints(values, 3)
+		require.Len(t, points, 1)
+		assert.Equal(t, 60, points[0].Index)
+		assert.Equal(t, 1.0, points[0].Before)
+		assert.Equal(t, 5.0, points[0].After)
+	})
+
+	t.Run("flat and noisy series", func(t *testing.T) {
+		assert.Empty(t, findChangePoints(make([]float64, 50), 3))
+		noisy := make([]float64, 50)
+		for i := range noisy {
+			noisy[i] = float64(10 + i%3)
+		}
+		assert.Empty(t, findChangePoints(noisy, 3))
+	})
+
+	t.Run("short series", func(t *testing.T) {
+		assert.Empty(t, findChangePoints([]float64{1, 100}, 3))
+	})
+}
+
+func TestBuildIncidentTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) int64 { return start.Add(d).UnixMilli() }
+
+	mux := http.NewServeMux()
+	for uid, dsType := range map[string]string{"prom": "prometheus", "loki": "loki", "tempo": "tempo"} {
+		mux.HandleFunc("/api/datasources/uid/"+uid, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uid":"` + uid + `","type":"` + dsType + `"}`))
+		})
+	}
+	mux.HandleFunc("/api/annotations", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.Equal(t, fmt.Sprint(start.UnixMilli()), r.URL.Query().Get("from"))
+		switch r.URL.Query().Get("type") {
+		case "alert":
+			_, _ = fmt.Fprintf(w, `[
+				{"alertName":"Checkout high error rate","newState":"Alerting","prevState":"Normal","text":"{service=checkout}","time":%d},
+				{"alertName":"Payments latency","newState":"Alerting","prevState":"Normal","time":%d},
+				{"alertName":"Checkout high error rate","newState":"Normal","prevState":"Alerting","time":%d}
+			]`, at(20*time.Minute), at(21*time.Minute), at(50*time.Minute))
+		case "annotation":
+			assert.Equal(t, []string{"deployment"}, r.URL.Query()["tags"])
+			_, _ = fmt.Fprintf(w, `[{"text":"Deploy checkout v1.2.3","tags":["deployment"],"time":%d}]`, at(10*time.Minute))
+		default:
+			t.Errorf("unexpected annotation type %q", r.URL.Query().Get("type"))
+		}
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "18", r.Form.Get("step"))
+		values := make([]string, 0, 200)
+		for i := 0; i <= 200; i++ {
+			v := "0.01"
+			if i >= 100 {
+				v = "0.5"
+			}
+			values = append(values, fmt.Sprintf(`[%d,"%s"]`, start.Unix()+int64(i)*18, v))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"service":"checkout"},"values":[` + strings.Join(values, ",") + `]}]}}`))
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/loki/loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{service_name="checkout"} |~ "(?i)(error|exception|fatal|panic)"`, r.URL.Query().Get("query"))
+		assert.Equal(t, "forward", r.URL.Query().Get("direction"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"streams","result":[{"stream":{"service_name":"checkout"},"values":[["%d","connection refused: error"]]}]}}`, start.Add(25*time.Minute).UnixNano())
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/tempo/api/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{ resource.service.name = "checkout" && status = error }`, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"traces":[
+			{"traceID":"late","rootServiceName":"frontend","rootTraceName":"POST /pay","startTimeUnixNano":"%d"},
+			{"traceID":"early","rootServiceName":"frontend","rootTraceName":"POST /checkout","startTimeUnixNano":"%d"}
+		]}`, start.Add(40*time.Minute).UnixNano(), start.Add(27*time.Minute).UnixNano())
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+	timeline, err := buildIncidentTimeline(ctx, BuildIncidentTimelineParams{
+		Service:       "checkout",
+		StartTime:     start.Format(time.RFC3339),
+		EndTime:       start.Add(time.Hour).Format(time.RFC3339),
+		PrometheusUID: "prom",
+		MetricExpr:    `sum(rate(errors_total{service="checkout"}[5m]))`,
+		LokiUID:       "loki",
+		TempoUID:      "tempo",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, timeline.Errors)
+
+	var kinds []string
+	for _, e := range timeline.Events {
+		kinds = append(kinds, e.Kind)
+	}
+	require.Equal(t, []string{"deployment", "alert", "first_error_log", "first_error_trace", "change_point"}, kinds)
+
+	assert.Equal(t, "Deploy checkout v1.2.3", timeline.Events[0].Title)
+	assert.Equal(t, "Checkout high error rate: Alerting", timeline.Events[1].Title)
+	assert.Equal(t, "connection refused: error", timeline.Events[2].Title)
+	assert.Equal(t, start.Add(25*time.Minute), timeline.Events[2].Time.UTC())
+	assert.Equal(t, "early", timeline.Events[3].Details["traceId"])
+	assert.Equal(t, start.Add(30*time.Minute), timeline.Events[4].Time.UTC())
+	assert.Equal(t, `sum(rate(errors_total{service="checkout"}[5m])) changed from 0.01 to 0.5`, timeline.Events[4].Title)
+	assert.Equal(t, "prom", timeline.Events[4].Source)
+
+	t.Run("failing sources are reported", func(t *testing.T) {
+		timeline, err := buildIncidentTimeline(ctx, BuildIncidentTimelineParams{
+			Service:   "checkout",
+			StartTime: start.Format(time.RFC3339),
+			EndTime:   start.Add(time.Hour).Format(time.RFC3339),
+			TempoUID:  "missing",
+		})
+		require.NoError(t, err)
+		require.Len(t, timeline.Errors, 1)
+		assert.Contains(t, timeline.Errors[0], "error traces")
+		assert.Len(t, timeline.Events, 2)
+	})
+
+	t.Run("metric expression requires a datasource", func(t *testing.T) {
+		_, err := buildIncidentTimeline(ctx, BuildIncidentTimelineParams{Service: "checkout", MetricExpr: "up"})
+		assert.ErrorContains(t, err, "prometheusUid")
+	})
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// maxInspectedBodySize limits how much of each raw response body is kept by
+// the query inspector, so debug output stays within a reasonable size.
+const maxInspectedBodySize = 1024 * 1024
+
+// queryInspection mirrors the information shown in Grafana's panel Query
+// Inspector: the query model that was executed and the raw requests sent to
+// the datasource along with their responses.
+type queryInspection struct {
+	Query    map[string]any     `json:"query,omitempty"`
+	Requests []inspectedRequest `json:"requests"`
+}
+
+// inspectedRequest is a single HTTP exchange with a datasource.
+type inspectedRequest struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// inspectedResult wraps a tool result with its query inspection.
+type inspectedResult[R any] struct {
+	Result  R                `json:"result" jsonschema:"required"`
+	Inspect *queryInspection `json:"inspect" jsonschema:"required"`
+}
+
+func (r inspectedResult[R]) inspection() (any, *queryInspection) {
+	return r.Result, r.Inspect
+}
+
+// inspectable is the result of withInspection: R, or an inspectedResult of R
+// when debug is set.
+type inspectable[R any] struct{ resultValue }
+
+func (inspectable[R]) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[R](), reflect.TypeFor[inspectedResult[R]]()}
+}
+
+// uninspect removes the query inspection from a result holding an
+// inspectedResult, leaving only the inspected result, and returns the
+// inspection. It returns nil if the result was not inspected.
+func (r *resultValue) uninspect() *queryInspection {
+	inspected, ok := unwrapResult(r.value).(interface {
+		inspection() (any, *queryInspection)
+	})
+	if !ok {
+		return nil
+	}
+	var inspection *queryInspection
+	r.value, inspection = inspected.inspection()
+	return inspection
+}
+
+// queryInspector records the query model and datasource traffic for a single
+// tool call.
+type queryInspector struct {
+	mu         sync.Mutex
+	inspection queryInspection
+}
+
+type queryInspectorKey struct{}
+
+// withQueryInspector returns a context that records datasource requests made
+// by clients created from it.
+func withQueryInspector(ctx context.Context, inspector *queryInspector) context.Context {
+	return context.WithValue(ctx, queryInspectorKey{}, inspector)
+}
+
+// queryInspectorFromContext returns the inspector in the context, or nil if
+// the tool call is not being inspected.
+func queryInspectorFromContext(ctx context.Context) *queryInspector {
+	inspector, _ := ctx.Value(queryInspectorKey{}).(*queryInspector)
+	return inspector
+}
+
+// setQuery records the query model. It is safe to call on a nil inspector.
+func (qi *queryInspector) setQuery(query map[string]any) {
+	if qi == nil {
+		return
+	}
+	qi.mu.Lock()
+	defer qi.mu.Unlock()
+	qi.inspection.Query = query
+}
+
+func (qi *queryInspector) addRequest(r inspectedRequest) {
+	qi.mu.Lock()
+	defer qi.mu.Unlock()
+	qi.inspection.Requests = append(qi.inspection.Requests, r)
+}
+
+func (qi *queryInspector) result() *queryInspection {
+	qi.mu.Lock()
+	defer qi.mu.Unlock()
+	inspection := qi.inspection
+	if inspection.Requests == nil {
+		inspection.Requests = []inspectedRequest{}
+	}
+	return &inspection
+}
+
+// inspectingRoundTripper records every request and raw response passing
+// through it.
+type inspectingRoundTripper struct {
+	inspector  *queryInspector
+	underlying http.RoundTripper
+}
+
+func (rt *inspectingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	record := inspectedRequest{
+		Method: req.Method,
+		URL:    req.URL.Redacted(),
+	}
+
+	resp, err := rt.underlying.RoundTrip(req)
+	if err != nil {
+		record.Error = err.Error()
+		rt.inspector.addRequest(record)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	record.StatusCode = resp.StatusCode
+	if len(body) > maxInspectedBodySize {
+		body = body[:maxInspectedBodySize]
+		record.Truncated = true
+	}
+	record.Body = string(body)
+	rt.inspector.addRequest(record)
+
+	return resp, nil
+}
+
+// inspectTransport wraps the transport with an inspecting round tripper if
+// the context carries a query inspector.
+func inspectTransport(ctx context.Context, rt http.RoundTripper) http.RoundTripper {
+	inspector := queryInspectorFromContext(ctx)
+	if inspector == nil {
+		return rt
+	}
+	return &inspectingRoundTripper{inspector: inspector, underlying: rt}
+}
+
+// withInspection runs fn and, if debug is set, returns its result together
+// with the query model and raw datasource traffic it produced. If debug is
+// not set the result of fn is returned unchanged.
+func withInspection[T any](ctx context.Context, debug bool, fn func(context.Context) (T, error)) (inspectable[T], error) {
+	if !debug {
+		return asResult[inspectable[T]](fn(ctx))
+	}
+
+	inspector := &queryInspector{}
+	result, err := fn(withQueryInspector(ctx, inspector))
+	if err != nil {
+		return inspectable[T]{}, err
+	}
+	return asResult[inspectable[T]](inspectedResult[T]{
+		Result:  result,
+		Inspect: inspector.result(),
+	}, nil)
+}
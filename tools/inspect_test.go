@@ -0,0 +1,90 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockDatasourceContext creates a context pointing at a mock Grafana server
+// which serves the given datasource UID and delegates proxied datasource
+// requests to the handler. Any other request gets a JSON 404, as Grafana
+// responds for unknown datasources.
+func newMockDatasourceContext(t *testing.T, uid, dsType string, handler http.HandlerFunc) context.Context {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not found"}`))
+	})
+	mux.HandleFunc("/api/datasources/uid/"+uid, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"` + uid + `","type":"` + dsType + `"}`))
+	})
+	mux.Handle("/api/datasources/proxy/uid/"+uid+"/", http.StripPrefix("/api/datasources/proxy/uid/"+uid, handler))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{
+		URL:    server.URL,
+		APIKey: "test-api-key",
+	})
+	return mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, "test-api-key"))
+}
+
+func TestQueryInspection(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"streams","result":[{"stream":{"app":"foo"},"values":[["1700000000000000000","hello"]]}]}}`
+
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	})
+
+	t.Run("debug disabled returns the plain result", func(t *testing.T) {
+		result, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+		})
+		require.NoError(t, err)
+		entries, ok := unwrapResult(result).([]LogEntry)
+		require.True(t, ok)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "hello", entries[0].Line)
+	})
+
+	t.Run("debug enabled includes query model and raw response", func(t *testing.T) {
+		result, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			Limit:         5,
+			Debug:         true,
+		})
+		require.NoError(t, err)
+		inspected, ok := unwrapResult(result).(inspectedResult[paginated[LogEntry]])
+		require.True(t, ok)
+
+		entries, ok := unwrapResult(inspected.Result).([]LogEntry)
+		require.True(t, ok)
+		require.Len(t, entries, 1)
+
+		require.NotNil(t, inspected.Inspect)
+		assert.Equal(t, `{app="foo"}`, inspected.Inspect.Query["expr"])
+		assert.Equal(t, 5, inspected.Inspect.Query["maxLines"])
+		require.Len(t, inspected.Inspect.Requests, 1)
+		req := inspected.Inspect.Requests[0]
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Contains(t, req.URL, "/api/datasources/proxy/uid/loki/loki/api/v1/query_range")
+		assert.Equal(t, http.StatusOK, req.StatusCode)
+		assert.Equal(t, body, req.Body)
+	})
+}
@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// investigationTTL is how long the tool calls of an investigation are
+	// kept after the last call.
+	investigationTTL = 4 * time.Hour
+
+	// maxInvestigationCalls limits how many tool calls are kept per
+	// investigation. The oldest calls are dropped to make room.
+	maxInvestigationCalls = 200
+
+	// maxInvestigationLinks limits how many links are kept per investigation.
+	maxInvestigationLinks = 100
+
+	// maxCallResultChars is how much of each tool result is kept.
+	maxCallResultChars = 1000
+
+	// maxCallArgumentChars is how much of each tool argument is kept, so
+	// that large arguments such as dashboards are not kept in full.
+	maxCallArgumentChars = 200
+)
+
+// investigationCall is a tool call recorded during an investigation.
+type investigationCall struct {
+	Time       time.Time      `json:"time"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	DurationMs float64        `json:"durationMs"`
+	// Result is the start of the text of the result.
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// investigation is the tool calls made in an MCP session, and the links
+// found in their arguments and results.
+type investigation struct {
+	calls   []investigationCall
+	dropped int
+	links   []string
+	updated time.Time
+}
+
+// investigationStore keeps the tool calls of investigations in memory, keyed
+// by the MCP session they were made in.
+type investigationStore struct {
+	mu             sync.Mutex
+	investigations map[string]*investigation
+}
+
+var investigations = &investigationStore{investigations: map[string]*investigation{}}
+
+var linkPattern = regexp.MustCompile(`https?://[^\s"'<>\\` + "`" + `)\]]+`)
+
+// investigationScope returns the key of the investigation of the MCP session
+// in the context. Sessions are also scoped by Grafana credentials, as clients
+// of the stateless HTTP transport choose their session IDs. Calls made
+// outside a session, which can't be told apart, are not recorded.
+func investigationScope(ctx context.Context) (string, bool) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil || session.SessionID() == "" {
+		return "", false
+	}
+	return cacheKey(ctx, "investigation\x00"+session.SessionID()), true
+}
+
+// record adds a tool call to the investigation of its session.
+func (s *investigationStore) record(ctx context.Context, call investigationCall, text string) {
+	scope, ok := investigationScope(ctx)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, inv := range s.investigations {
+		if now.Sub(inv.updated) > investigationTTL {
+			delete(s.investigations, k)
+		}
+	}
+	inv, ok := s.investigations[scope]
+	if !ok {
+		inv = &investigation{}
+		s.investigations[scope] = inv
+	}
+	inv.updated = now
+	if len(inv.calls) >= maxInvestigationCalls {
+		inv.calls = inv.calls[1:]
+		inv.dropped++
+	}
+	inv.calls = append(inv.calls, call)
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		if len(inv.links) < maxInvestigationLinks && !slices.Contains(inv.links, link) {
+			inv.links = append(inv.links, link)
+		}
+	}
+}
+
+// get returns a copy of the investigation of the session in the context,
+// optionally resetting it.
+func (s *investigationStore) get(ctx context.Context, reset bool) investigation {
+	scope, ok := investigationScope(ctx)
+	if !ok {
+		return investigation{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.investigations[scope]
+	if !ok {
+		return investigation{}
+	}
+	if reset {
+		delete(s.investigations, scope)
+	}
+	return investigation{
+		calls:   append([]investigationCall(nil), inv.calls...),
+		dropped: inv.dropped,
+		links:   append([]string(nil), inv.links...),
+		updated: inv.updated,
+	}
+}
+
+// toolResultText returns the text contents of a tool result.
+func toolResultText(result *mcp.CallToolResult) string {
+	if result == nil {
+		return ""
+	}
+	var texts []string
+	for _, c := range result.Content {
+		if t, ok := c.(mcp.TextContent); ok {
+			texts = append(texts, t.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// callArguments returns the arguments of a tool call, with arguments whose
+// JSON is long replaced by its truncated JSON.
+func callArguments(arguments map[string]any) map[string]any {
+	if len(arguments) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(arguments))
+	for k, v := range arguments {
+		out[k] = v
+		if data, err := json.Marshal(v); err == nil && len(data) > maxCallArgumentChars {
+			out[k] = truncate(string(data), maxCallArgumentChars)
+		}
+	}
+	return out
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+// RecordToolCalls is a tool handler middleware recording each tool call, so
+// export_investigation can export them. Calls are kept in memory per MCP
+// session.
+func RecordToolCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+		if request.Params.Name == "export_investigation" {
+			return result, err
+		}
+
+		call := investigationCall{
+			Time:       start.UTC(),
+			Tool:       request.Params.Name,
+			Arguments:  callArguments(request.GetArguments()),
+			DurationMs: durationMs(time.Since(start)),
+		}
+		text := toolResultText(result)
+		switch {
+		case err != nil:
+			call.Error = err.Error()
+		case result != nil && result.IsError:
+			call.Error = truncate(text, maxCallResultChars)
+		default:
+			call.Result = truncate(text, maxCallResultChars)
+		}
+		arguments, _ := json.Marshal(request.GetArguments())
+		investigations.record(ctx, call, string(arguments)+"\n"+text)
+		return result, err
+	}
+}
+
+type ExportInvestigationParams struct {
+	Title        string   `json:"title" jsonschema:"required,description=The title of the investigation\\, e.g. 'Checkout latency spike on 2024-05-01'"`
+	Summary      string   `json:"summary,omitempty" jsonschema:"description=Optionally\\, a summary of what was investigated and found"`
+	Conclusions  []string `json:"conclusions,omitempty" jsonschema:"description=Optionally\\, the conclusions of the investigation\\, such as the root cause and follow-up actions"`
+	KeyResults   []string `json:"keyResults,omitempty" jsonschema:"description=Optionally\\, the results supporting the conclusions\\, e.g. 'p99 latency of checkout rose from 200ms to 2s at 14:05'"`
+	Format       string   `json:"format,omitempty" jsonschema:"enum=markdown,enum=json,description=Optionally\\, the format of the exported record (default: markdown)"`
+	IncidentID   string   `json:"incidentId,omitempty" jsonschema:"description=Optionally\\, the ID of a Grafana Incident to add the record to as a note"`
+	SaveSnapshot bool     `json:"saveSnapshot,omitempty" jsonschema:"description=Optionally\\, also save the record as a Grafana snapshot\\, which can be shared by its URL. Requires write tools to be enabled"`
+	Reset        bool     `json:"reset,omitempty" jsonschema:"description=Optionally\\, forget the recorded tool calls after exporting\\, so the next export starts a new investigation"`
+}
+
+// investigationRecord is the exported record of an investigation.
+type investigationRecord struct {
+	Title       string              `json:"title"`
+	Summary     string              `json:"summary,omitempty"`
+	Conclusions []string            `json:"conclusions,omitempty"`
+	KeyResults  []string            `json:"keyResults,omitempty"`
+	Links       []string            `json:"links,omitempty"`
+	ToolCalls   []investigationCall `json:"toolCalls"`
+	// DroppedToolCalls is the number of earlier tool calls which were not
+	// kept.
+	DroppedToolCalls int       `json:"droppedToolCalls,omitempty"`
+	ExportedAt       time.Time `json:"exportedAt"`
+}
+
+// markdown renders the record as a Markdown document.
+func (r investigationRecord) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Investigation: %s\n\n", r.Title)
+	fmt.Fprintf(&b, "Exported %s", r.ExportedAt.Format("2006-01-02 15:04 MST"))
+	if len(r.ToolCalls) > 0 {
+		fmt.Fprintf(&b, " after %d tool calls from %s", len(r.ToolCalls)+r.DroppedToolCalls, r.ToolCalls[0].Time.Format("2006-01-02 15:04 MST"))
+	}
+	b.WriteString(".\n")
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n\n%s\n", r.Summary)
+	}
+	bullets := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	bullets("Conclusions", r.Conclusions)
+	bullets("Key results", r.KeyResults)
+	bullets("Links", r.Links)
+	if len(r.ToolCalls) > 0 {
+		b.WriteString("\n## Tool calls\n\n")
+		if r.DroppedToolCalls > 0 {
+			fmt.Fprintf(&b, "%d earlier tool calls are not included.\n\n", r.DroppedToolCalls)
+		}
+		for i, c := range r.ToolCalls {
+			arguments, _ := json.Marshal(c.Arguments)
+			fmt.Fprintf(&b, "%d. %s `%s` `%s` (%.0fms)", i+1, c.Time.Format("15:04:05"), c.Tool, arguments, c.DurationMs)
+			if c.Error != "" {
+				fmt.Fprintf(&b, ": failed: %s", truncate(strings.ReplaceAll(c.Error, "\n", " "), 200))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// investigationExport is the result of export_investigation.
+type investigationExport struct {
+	Export             *exportSummary `json:"export"`
+	ToolCalls          int            `json:"toolCalls"`
+	IncidentActivityID string         `json:"incidentActivityId,omitempty"`
+	SnapshotURL        string         `json:"snapshotUrl,omitempty"`
+	// Errors are the failures to add the record to an incident or to save it
+	// as a snapshot. The export itself succeeded.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// saveInvestigationSnapshot saves a Markdown document as a snapshot of a
+// dashboard with a single text panel, returning the snapshot's URL.
+func saveInvestigationSnapshot(ctx context.Context, title, content string) (string, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Snapshots.CreateDashboardSnapshot(&models.CreateDashboardSnapshotCommand{
+		Name: "Investigation: " + title,
+		Dashboard: map[string]any{
+			"title": "Investigation: " + title,
+			"panels": []map[string]any{{
+				"id":      1,
+				"type":    "text",
+				"title":   title,
+				"gridPos": map[string]int{"x": 0, "y": 0, "w": 24, "h": 40},
+				"options": map[string]any{"mode": "markdown", "content": content},
+			}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("save snapshot: %w", err)
+	}
+	return resp.Payload.URL, nil
+}
+
+func exportInvestigation(ctx context.Context, args ExportInvestigationParams) (*investigationExport, error) {
+	if strings.TrimSpace(args.Title) == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	format := stringOrDefault(args.Format, "markdown")
+	if format != "markdown" && format != "json" {
+		return nil, fmt.Errorf("unknown format %q, expected markdown or json", format)
+	}
+	if args.SaveSnapshot && !mcpgrafana.GrafanaConfigFromContext(ctx).WriteToolsEnabled {
+		return nil, fmt.Errorf("saving a snapshot modifies Grafana, which requires the server to run with --enable-write-tools")
+	}
+
+	inv := investigations.get(ctx, args.Reset)
+	record := investigationRecord{
+		Title:            args.Title,
+		Summary:          args.Summary,
+		Conclusions:      args.Conclusions,
+		KeyResults:       args.KeyResults,
+		Links:            inv.links,
+		ToolCalls:        inv.calls,
+		DroppedToolCalls: inv.dropped,
+		ExportedAt:       time.Now().UTC(),
+	}
+	if record.ToolCalls == nil {
+		record.ToolCalls = []investigationCall{}
+	}
+	markdown := record.markdown()
+
+	var data []byte
+	var ext, mimeType string
+	if format == "json" {
+		var err error
+		if data, err = json.MarshalIndent(record, "", "  "); err != nil {
+			return nil, fmt.Errorf("encoding investigation: %w", err)
+		}
+		ext, mimeType = "json", "application/json"
+	} else {
+		data, ext, mimeType = []byte(markdown), "md", "text/markdown"
+	}
+	summary, err := exports.add(ctx, ext, mimeType, data, len(record.ToolCalls))
+	if err != nil {
+		return nil, err
+	}
+	result := &investigationExport{Export: summary, ToolCalls: len(record.ToolCalls)}
+
+	// The summary is written to Grafana below, which no redaction covers.
+	markdown = mcpgrafana.RedactText(ctx, markdown)
+	if args.IncidentID != "" {
+		activity, err := addActivityToIncident(ctx, AddActivityToIncidentParams{IncidentID: args.IncidentID, Body: markdown})
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.IncidentActivityID = activity.ActivityItemID
+		}
+	}
+	if args.SaveSnapshot {
+		url, err := saveInvestigationSnapshot(ctx, args.Title, markdown)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.SnapshotURL = url
+		}
+	}
+	return result, nil
+}
+
+var ExportInvestigation = mcpgrafana.MustTool(
+	"export_investigation",
+	"Exports a record of the current investigation: the given title, summary, conclusions and key results, together with the tool calls made in this session in the last hours and the links found in them. The record is stored as a Markdown or JSON resource whose URI is returned, and can also be added to a Grafana Incident as a note or, if write tools are enabled, saved as a Grafana snapshot. Use this at the end of a root cause analysis to keep a durable record of it.",
+	exportInvestigation,
+	mcp.WithTitleAnnotation("Export investigation"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+)
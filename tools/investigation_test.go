@@ -0,0 +1,156 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// testClientSession is an MCP client session with an ID.
+type testClientSession string
+
+func (s testClientSession) Initialize()                                         {}
+func (s testClientSession) Initialized() bool                                   { return true }
+func (s testClientSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s testClientSession) SessionID() string                                   { return string(s) }
+
+func withClientSession(ctx context.Context, id string) context.Context {
+	return server.NewMCPServer("test", "1.0.0").WithContext(ctx, testClientSession(id))
+}
+
+func callRecordedTool(t *testing.T, ctx context.Context, name string, args map[string]any, handler func() (*mcp.CallToolResult, error)) {
+	t.Helper()
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	_, _ = RecordToolCalls(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handler()
+	})(ctx, request)
+}
+
+func TestExportInvestigation(t *testing.T) {
+	var snapshot map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&snapshot))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"key":"abc","url":"http://grafana.example.com/dashboard/snapshot/abc"}`))
+	})
+	grafana := httptest.NewServer(mux)
+	t.Cleanup(grafana.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: grafana.URL, APIKey: "investigation", WriteToolsEnabled: true})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, grafana.URL, "investigation"))
+	sessionCtx := withClientSession(ctx, "checkout")
+
+	callRecordedTool(t, sessionCtx, "query_loki_logs", map[string]any{"datasourceUid": "loki", "logql": `{app="checkout"}`}, func() (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(`[{"line":"timeout calling payments"}]`), nil
+	})
+	callRecordedTool(t, sessionCtx, "generate_deeplink", map[string]any{"resourceType": "dashboard", "dashboard": strings.Repeat("x", 500)}, func() (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(`{"url":"http://grafana.example.com/d/checkout"}`), nil
+	})
+	callRecordedTool(t, sessionCtx, "query_prometheus", map[string]any{"expr": "up"}, func() (*mcp.CallToolResult, error) {
+		return nil, errors.New("bad_data: parse error")
+	})
+	// Calls made in other sessions, even with the same credentials, belong
+	// to another investigation, and calls made outside sessions are not
+	// recorded.
+	other := withClientSession(ctx, "other")
+	callRecordedTool(t, other, "list_teams", nil, func() (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("[]"), nil
+	})
+	callRecordedTool(t, ctx, "list_teams", nil, func() (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("[]"), nil
+	})
+
+	result, err := exportInvestigation(sessionCtx, ExportInvestigationParams{
+		Title:        "Checkout timeouts",
+		Summary:      "Checkout timed out calling payments.",
+		Conclusions:  []string{"Payments connection pool exhausted"},
+		Format:       "json",
+		SaveSnapshot: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 3, result.ToolCalls)
+	assert.Equal(t, "http://grafana.example.com/dashboard/snapshot/abc", result.SnapshotURL)
+	assert.Equal(t, "application/json", result.Export.MIMEType)
+
+	var record investigationRecord
+	require.NoError(t, json.Unmarshal([]byte(readExportText(t, sessionCtx, result.Export.ResourceURI)), &record))
+	assert.Equal(t, "Checkout timeouts", record.Title)
+	assert.Equal(t, []string{"http://grafana.example.com/d/checkout"}, record.Links)
+	require.Len(t, record.ToolCalls, 3)
+	assert.Equal(t, "query_loki_logs", record.ToolCalls[0].Tool)
+	assert.Equal(t, `{app="checkout"}`, record.ToolCalls[0].Arguments["logql"])
+	assert.Equal(t, `[{"line":"timeout calling payments"}]`, record.ToolCalls[0].Result)
+	assert.Len(t, []rune(record.ToolCalls[1].Arguments["dashboard"].(string)), maxCallArgumentChars+1)
+	assert.Equal(t, "bad_data: parse error", record.ToolCalls[2].Error)
+
+	panels := snapshot["dashboard"].(map[string]any)["panels"].([]any)
+	content := panels[0].(map[string]any)["options"].(map[string]any)["content"].(string)
+	assert.Contains(t, content, "# Investigation: Checkout timeouts")
+	assert.Contains(t, content, "## Conclusions\n\n- Payments connection pool exhausted\n")
+	assert.Contains(t, content, "- http://grafana.example.com/d/checkout\n")
+	assert.Contains(t, content, "`query_prometheus` `{\"expr\":\"up\"}`")
+	assert.Contains(t, content, ": failed: bad_data: parse error\n")
+
+	t.Run("markdown and reset", func(t *testing.T) {
+		result, err := exportInvestigation(sessionCtx, ExportInvestigationParams{Title: "Checkout timeouts", Reset: true})
+		require.NoError(t, err)
+		assert.Equal(t, "text/markdown", result.Export.MIMEType)
+		assert.Regexp(t, `\.md$`, result.Export.ResourceURI)
+		assert.Contains(t, readExportText(t, sessionCtx, result.Export.ResourceURI), "after 3 tool calls")
+
+		result, err = exportInvestigation(sessionCtx, ExportInvestigationParams{Title: "Next"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.ToolCalls)
+	})
+
+	t.Run("export calls are not recorded", func(t *testing.T) {
+		callRecordedTool(t, other, "export_investigation", map[string]any{"title": "x"}, func() (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("{}"), nil
+		})
+		result, err := exportInvestigation(other, ExportInvestigationParams{Title: "Teams"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.ToolCalls)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := exportInvestigation(ctx, ExportInvestigationParams{})
+		assert.ErrorContains(t, err, "title is required")
+		_, err = exportInvestigation(ctx, ExportInvestigationParams{Title: "x", Format: "pdf"})
+		assert.ErrorContains(t, err, "unknown format")
+		readOnly := mcpgrafana.WithGrafanaConfig(ctx, mcpgrafana.GrafanaConfig{URL: grafana.URL, APIKey: "investigation"})
+		_, err = exportInvestigation(readOnly, ExportInvestigationParams{Title: "x", SaveSnapshot: true})
+		assert.ErrorContains(t, err, "--enable-write-tools")
+	})
+}
+
+func TestRecordToolCallsDropsOldestCalls(t *testing.T) {
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://grafana", APIKey: "many-calls"})
+	ctx = withClientSession(ctx, "many-calls")
+	for i := 0; i < maxInvestigationCalls+5; i++ {
+		callRecordedTool(t, ctx, "list_teams", map[string]any{"page": i}, func() (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("[]"), nil
+		})
+	}
+	inv := investigations.get(ctx, true)
+	assert.Len(t, inv.calls, maxInvestigationCalls)
+	assert.Equal(t, 5, inv.dropped)
+	assert.EqualValues(t, 5, inv.calls[0].Arguments["page"])
+}
@@ -0,0 +1,472 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// labelRename describes a label name and/or value rename.
+type labelRename struct {
+	From, To           string
+	FromValue, ToValue string
+}
+
+// renamesValue reports whether label values should be rewritten.
+func (r labelRename) renamesValue() bool {
+	return r.FromValue != "" && r.FromValue != r.ToValue
+}
+
+type queryTokenKind int
+
+const (
+	tokenSpace queryTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenVariable
+	tokenPunct
+)
+
+type queryToken struct {
+	kind       queryTokenKind
+	start, end int
+	text       string
+}
+
+// queryOperators are the multi-character operators of PromQL and LogQL which
+// matter when finding label names.
+var queryOperators = []string{"=~", "!~", "!=", "==", ">=", "<=", "|=", "|~"}
+
+// tokenizeQuery splits a PromQL or LogQL query into tokens. It is not a full
+// lexer: it only distinguishes the tokens needed to find label names, and
+// treats Grafana template variables as opaque tokens.
+func tokenizeQuery(q string) []queryToken {
+	var tokens []queryToken
+	isIdentStart := func(c byte) bool {
+		return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}
+	isIdent := func(c byte) bool {
+		return isIdentStart(c) || c == ':' || (c >= '0' && c <= '9')
+	}
+	for i := 0; i < len(q); {
+		start := i
+		kind := tokenPunct
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = tokenSpace
+			for i < len(q) && strings.ContainsRune(" \t\n\r", rune(q[i])) {
+				i++
+			}
+		case c == '#':
+			kind = tokenSpace
+			for i < len(q) && q[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' || c == '`':
+			kind = tokenString
+			i++
+			for i < len(q) && q[i] != c {
+				if q[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			i = min(i+1, len(q))
+		case c == '$':
+			kind = tokenVariable
+			i++
+			if i < len(q) && q[i] == '{' {
+				for i < len(q) && q[i] != '}' {
+					i++
+				}
+				i = min(i+1, len(q))
+			} else {
+				for i < len(q) && isIdent(q[i]) {
+					i++
+				}
+			}
+		case strings.HasPrefix(q[i:], "[["):
+			kind = tokenVariable
+			if end := strings.Index(q[i:], "]]"); end >= 0 {
+				i += end + 2
+			} else {
+				i = len(q)
+			}
+		case isIdentStart(c):
+			kind = tokenIdent
+			for i < len(q) && isIdent(q[i]) {
+				i++
+			}
+		case c >= '0' && c <= '9':
+			kind = tokenNumber
+			for i < len(q) && (isIdent(q[i]) || q[i] == '.') {
+				i++
+			}
+		default:
+			i++
+			for _, op := range queryOperators {
+				if strings.HasPrefix(q[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+		}
+		tokens = append(tokens, queryToken{kind: kind, start: start, end: i, text: q[start:i]})
+	}
+	return tokens
+}
+
+// unquoteQueryString unquotes a PromQL or LogQL string literal, which may use
+// double quotes, single quotes or backticks.
+func unquoteQueryString(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] {
+		return "", fmt.Errorf("invalid string literal %s", s)
+	}
+	inner := s[1 : len(s)-1]
+	switch s[0] {
+	case '`':
+		return inner, nil
+	case '\'':
+		inner = strings.ReplaceAll(strings.ReplaceAll(inner, `\'`, `'`), `"`, `\"`)
+	}
+	return strconv.Unquote(`"` + inner + `"`)
+}
+
+// quoteQueryString quotes a string using the given quote character.
+func quoteQueryString(s string, quote byte) string {
+	switch quote {
+	case '`':
+		if !strings.Contains(s, "`") {
+			return "`" + s + "`"
+		}
+	case '\'':
+		inner := strconv.Quote(s)
+		inner = strings.ReplaceAll(inner[1:len(inner)-1], `\"`, `"`)
+		return "'" + strings.ReplaceAll(inner, "'", `\'`) + "'"
+	}
+	return strconv.Quote(s)
+}
+
+// groupingKeywords introduce a parenthesised list of label names.
+var groupingKeywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+}
+
+func isMatchOperator(s string) bool {
+	return s == "=" || s == "!=" || s == "=~" || s == "!~"
+}
+
+func isComparisonOperator(s string) bool {
+	return isMatchOperator(s) || s == "==" || s == ">" || s == "<" || s == ">=" || s == "<="
+}
+
+// renameLabelInQuery renames a label in a PromQL or LogQL query. Label names
+// are rewritten where the syntax makes them label names: in series and
+// stream selectors, in grouping clauses such as `by (...)` and `on (...)`,
+// and in LogQL label filters and `unwrap` expressions. Metric names, string
+// contents and line filters are left alone. If a value rename is given,
+// equality matchers on the label in selectors and label filters are
+// rewritten too. Formatting is preserved.
+func renameLabelInQuery(q string, r labelRename) string {
+	tokens := tokenizeQuery(q)
+	replacements := map[int]string{}
+
+	// next returns the index of the next non-space token after i, or -1.
+	next := func(i int) int {
+		for j := i + 1; j < len(tokens); j++ {
+			if tokens[j].kind != tokenSpace {
+				return j
+			}
+		}
+		return -1
+	}
+	text := func(i int) string {
+		if i < 0 {
+			return ""
+		}
+		return tokens[i].text
+	}
+
+	// renameMatcher handles a label matcher or filter starting at the label
+	// name token i.
+	renameMatcher := func(i int) {
+		if tokens[i].text != r.From {
+			return
+		}
+		if r.To != "" && r.To != r.From {
+			replacements[i] = r.To
+		}
+		op := next(i)
+		value := next(op)
+		if !r.renamesValue() || (text(op) != "=" && text(op) != "!=" && text(op) != "==") || value < 0 || tokens[value].kind != tokenString {
+			return
+		}
+		if unquoted, err := unquoteQueryString(tokens[value].text); err == nil && unquoted == r.FromValue {
+			replacements[value] = quoteQueryString(r.ToValue, tokens[value].text[0])
+		}
+	}
+
+	braceDepth, groupDepth := 0, 0
+	afterPipe := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case tokenSpace:
+			continue
+		case tokenPunct:
+			switch t.text {
+			case "{":
+				braceDepth++
+			case "}":
+				braceDepth = max(braceDepth-1, 0)
+			case ")":
+				if groupDepth > 0 {
+					groupDepth--
+				}
+			case "(":
+				if groupDepth > 0 {
+					groupDepth++
+				}
+			}
+			afterPipe = t.text == "|"
+			continue
+		case tokenIdent:
+			nextText := text(next(i))
+			switch {
+			case groupDepth > 0:
+				if t.text == r.From && r.To != "" {
+					replacements[i] = r.To
+				}
+			case braceDepth > 0 && isMatchOperator(nextText):
+				renameMatcher(i)
+			case afterPipe && isComparisonOperator(nextText):
+				renameMatcher(i)
+			case t.text == "unwrap":
+				if j := next(i); j >= 0 && tokens[j].kind == tokenIdent && tokens[j].text == r.From && r.To != "" {
+					replacements[j] = r.To
+				}
+			case braceDepth == 0 && groupingKeywords[t.text] && nextText == "(":
+				groupDepth = 1
+				i = next(i)
+			}
+		}
+		afterPipe = false
+	}
+
+	if len(replacements) == 0 {
+		return q
+	}
+	var b strings.Builder
+	for i, t := range tokens {
+		if r, ok := replacements[i]; ok {
+			b.WriteString(r)
+		} else {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// rewriteQuery renames a label in a query, checking that a valid PromQL query
+// remains valid after the rewrite.
+func rewriteQuery(q string, r labelRename) (string, error) {
+	rewritten := renameLabelInQuery(q, r)
+	if rewritten == q {
+		return q, nil
+	}
+	if _, err := parsePromQL(q); err == nil {
+		if _, err := parsePromQL(rewritten); err != nil {
+			return "", fmt.Errorf("rewritten query %q is not valid PromQL: %w", rewritten, err)
+		}
+	}
+	return rewritten, nil
+}
+
+type RenameLabelParams struct {
+	From           string   `json:"from" jsonschema:"required,description=The label name to rename (or whose values to rewrite)"`
+	To             string   `json:"to,omitempty" jsonschema:"description=The new label name. Leave empty to only rewrite values"`
+	FromValue      string   `json:"fromValue,omitempty" jsonschema:"description=Optionally\\, a label value to rewrite in equality matchers on the label"`
+	ToValue        string   `json:"toValue,omitempty" jsonschema:"description=Optionally\\, the new label value. Required if fromValue is set"`
+	DashboardUIDs  []string `json:"dashboardUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of the dashboards to rewrite. Defaults to all dashboards"`
+	SkipAlertRules bool     `json:"skipAlertRules,omitempty" jsonschema:"description=Optionally\\, set to true to leave alert rules unchanged"`
+	Apply          bool     `json:"apply,omitempty" jsonschema:"description=Set to true to save the rewritten resources. Otherwise only a dry-run diff is returned"`
+}
+
+func (p RenameLabelParams) validate() error {
+	if p.From == "" {
+		return fmt.Errorf("from is required")
+	}
+	if p.To == "" && p.FromValue == "" {
+		return fmt.Errorf("either to or fromValue is required")
+	}
+	if p.FromValue != "" && p.ToValue == "" {
+		return fmt.Errorf("toValue is required when fromValue is set")
+	}
+	return nil
+}
+
+func (p RenameLabelParams) rename() labelRename {
+	return labelRename{From: p.From, To: p.To, FromValue: p.FromValue, ToValue: p.ToValue}
+}
+
+// queryChange formats the rewrite of a query at the given path.
+func queryChange(path, from, to string) string {
+	return fmt.Sprintf("%s: %q -> %q", path, from, to)
+}
+
+// renameLabelInDashboard rewrites every Prometheus and Loki query of the
+// dashboard in place, returning the changes made.
+func renameLabelInDashboard(db map[string]any, r labelRename) ([]string, error) {
+	var changes []string
+	var err error
+	walkDashboardTargets(db, func(panel, target map[string]any, path string) {
+		expr, _ := target["expr"].(string)
+		if expr == "" || err != nil {
+			return
+		}
+		if ds := targetDatasource(panel, target); ds.Type != "" && ds.Type != "prometheus" && ds.Type != "loki" {
+			return
+		}
+		var rewritten string
+		if rewritten, err = rewriteQuery(expr, r); err != nil {
+			err = fmt.Errorf("%s: %w", path, err)
+			return
+		}
+		if rewritten != expr {
+			target["expr"] = rewritten
+			changes = append(changes, queryChange(path+".expr", expr, rewritten))
+		}
+	})
+	return changes, err
+}
+
+// renameLabelInAlertRule rewrites every query of the alert rule in place,
+// returning the changes made. Server-side expressions are left alone.
+func renameLabelInAlertRule(rule map[string]any, r labelRename) ([]string, error) {
+	var changes []string
+	data, _ := rule["data"].([]any)
+	for i, d := range data {
+		query, ok := d.(map[string]any)
+		if !ok || query["datasourceUid"] == "__expr__" {
+			continue
+		}
+		model, _ := query["model"].(map[string]any)
+		expr, _ := model["expr"].(string)
+		if expr == "" {
+			continue
+		}
+		path := fmt.Sprintf("data[%d].model.expr", i)
+		rewritten, err := rewriteQuery(expr, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if rewritten != expr {
+			model["expr"] = rewritten
+			changes = append(changes, queryChange(path, expr, rewritten))
+		}
+	}
+	return changes, nil
+}
+
+func renameLabel(ctx context.Context, args RenameLabelParams) (*resourceRewriteReport, error) {
+	if err := args.validate(); err != nil {
+		return nil, err
+	}
+	r := args.rename()
+
+	report := newResourceRewriteReport(args.Apply)
+
+	uids := args.DashboardUIDs
+	if len(uids) == 0 {
+		hits, err := searchAllResources(ctx, dashboardTypeStr, resourceTypeDashboard)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			uids = append(uids, h.UID)
+		}
+	}
+
+	for _, uid := range uids {
+		resource := rewrittenResource{Type: resourceTypeDashboard, UID: uid}
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			resource.Error = err.Error()
+			report.Resources = append(report.Resources, resource)
+			continue
+		}
+		db, err := toJSONMap(dashboard.Dashboard)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal dashboard %s: %w", uid, err)
+		}
+		resource.Title, _ = db["title"].(string)
+		resource.Changes, err = renameLabelInDashboard(db, r)
+		if err != nil {
+			resource.Error = err.Error()
+			report.Resources = append(report.Resources, resource)
+			continue
+		}
+		if len(resource.Changes) == 0 {
+			continue
+		}
+		if args.Apply {
+			resource.BackupPath, err = writeBackup(report.BackupDir, "dashboard-"+uid+".json", dashboard)
+			if err != nil {
+				return nil, err
+			}
+			resource.Error = saveRewrittenDashboard(ctx, dashboard, db, fmt.Sprintf("Rename label %s", args.From))
+		}
+		report.Resources = append(report.Resources, resource)
+	}
+
+	if args.SkipAlertRules {
+		return report, nil
+	}
+	rules, err := provisionedAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		resource := rewrittenResource{Type: resourceTypeAlertRule, UID: rule.UID}
+		if rule.Title != nil {
+			resource.Title = *rule.Title
+		}
+		m, err := toJSONMap(rule)
+		if err != nil {
+			return nil, fmt.Errorf("marshal alert rule %s: %w", rule.UID, err)
+		}
+		resource.Changes, err = renameLabelInAlertRule(m, r)
+		if err != nil {
+			resource.Error = err.Error()
+			report.Resources = append(report.Resources, resource)
+			continue
+		}
+		if len(resource.Changes) == 0 {
+			continue
+		}
+		if args.Apply {
+			resource.BackupPath, err = writeBackup(report.BackupDir, "alert-rule-"+rule.UID+".json", rule)
+			if err != nil {
+				return nil, err
+			}
+			resource.Error = saveRewrittenAlertRule(ctx, rule, m)
+		}
+		report.Resources = append(report.Resources, resource)
+	}
+	return report, nil
+}
+
+var RenameLabel = mcpgrafana.MustTool(
+	"rename_label",
+	"Renames a label (e.g. `env` to `environment`), and/or rewrites one of its values, across the PromQL and LogQL queries of dashboard panels and Grafana-managed alert rules. Queries are rewritten syntactically rather than with string replacement: label names are changed in selectors, grouping clauses such as `by (...)` and `on (...)`, and LogQL label filters, while metric names, strings and line filters are untouched and formatting is preserved. Values are only rewritten in equality matchers. By default this is a dry run which returns, for each affected resource, each query before and after. Set `apply` to true to save the changes; the original of every changed resource is first backed up as JSON to a new directory under `mcp-grafana-backups` in the server's temporary directory, returned as `backupDir`.",
+	renameLabel,
+	mcp.WithTitleAnnotation("Rename label across queries"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
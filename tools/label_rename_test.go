@@ -0,0 +1,113 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameLabelInQuery(t *testing.T) {
+	rename := labelRename{From: "env", To: "environment"}
+	for _, tc := range []struct {
+		name     string
+		query    string
+		rename   labelRename
+		expected string
+	}{
+		{
+			name:     "selector matchers",
+			query:    `rate(http_requests_total{env="prod", job=~"api.*"}[5m])`,
+			expected: `rate(http_requests_total{environment="prod", job=~"api.*"}[5m])`,
+		},
+		{
+			name:     "grouping clauses",
+			query:    "sum by (env, job) (rate(x[5m])) / on(env) group_left(team) sum without (instance) (y)",
+			expected: "sum by (environment, job) (rate(x[5m])) / on(environment) group_left(team) sum without (instance) (y)",
+		},
+		{
+			name:     "metric names, strings and functions are untouched",
+			query:    `env{instance="env"} + label_replace(env_info, "dst", "$1", "src", "(.*)") + envelope_total`,
+			expected: `env{instance="env"} + label_replace(env_info, "dst", "$1", "src", "(.*)") + envelope_total`,
+		},
+		{
+			name:     "template variables and formatting are preserved",
+			query:    "sum by ($groupBy, env) (\n  rate(x{env=~\"$env\"}[$__rate_interval])\n)",
+			expected: "sum by ($groupBy, environment) (\n  rate(x{environment=~\"$env\"}[$__rate_interval])\n)",
+		},
+		{
+			name:     "LogQL stream selector and label filters",
+			query:    `sum by (env) (count_over_time({app="api", env="prod"} |= "env=prod" | json | env != "dev" | unwrap env [5m]))`,
+			expected: `sum by (environment) (count_over_time({app="api", environment="prod"} |= "env=prod" | json | environment != "dev" | unwrap environment [5m]))`,
+		},
+		{
+			name:     "value rename on equality matchers only",
+			query:    `x{env="prod"} or y{env=~"prod|dev"} or z{env!="prod", stage="prod"}`,
+			rename:   labelRename{From: "env", To: "environment", FromValue: "prod", ToValue: "production"},
+			expected: `x{environment="production"} or y{environment=~"prod|dev"} or z{environment!="production", stage="prod"}`,
+		},
+		{
+			name:     "value rename without name rename",
+			query:    `{env='prod'} | env == "prod"`,
+			rename:   labelRename{From: "env", FromValue: "prod", ToValue: "production"},
+			expected: `{env='production'} | env == "production"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := tc.rename
+			if r.From == "" {
+				r = rename
+			}
+			assert.Equal(t, tc.expected, renameLabelInQuery(tc.query, r))
+		})
+	}
+}
+
+func TestRenameLabelInDashboard(t *testing.T) {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"panels": [
+			{"datasource": {"type": "prometheus", "uid": "prom"}, "targets": [{"expr": "sum by (env) (up)"}, {"expr": "up"}]},
+			{"datasource": {"type": "elasticsearch", "uid": "es"}, "targets": [{"expr": "env:prod"}]},
+			{"type": "row", "panels": [{"targets": [{"expr": "{env=\"prod\"}"}]}]}
+		]
+	}`), &db))
+
+	changes, err := renameLabelInDashboard(db, labelRename{From: "env", To: "environment"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`panels[0].targets[0].expr: "sum by (env) (up)" -> "sum by (environment) (up)"`,
+		`panels[2].panels[0].targets[0].expr: "{env=\"prod\"}" -> "{environment=\"prod\"}"`,
+	}, changes)
+
+	targets := db["panels"].([]any)[0].(map[string]any)["targets"].([]any)
+	assert.Equal(t, "sum by (environment) (up)", targets[0].(map[string]any)["expr"])
+}
+
+func TestRenameLabelInAlertRule(t *testing.T) {
+	var rule map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"data": [
+			{"refId": "A", "datasourceUid": "prom", "model": {"expr": "rate(errors_total{env=\"prod\"}[5m])"}},
+			{"refId": "B", "datasourceUid": "__expr__", "model": {"expression": "A", "type": "reduce"}}
+		]
+	}`), &rule))
+
+	changes, err := renameLabelInAlertRule(rule, labelRename{From: "env", FromValue: "prod", ToValue: "production"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`data[0].model.expr: "rate(errors_total{env=\"prod\"}[5m])" -> "rate(errors_total{env=\"production\"}[5m])"`,
+	}, changes)
+}
+
+func TestRenameLabelParams(t *testing.T) {
+	assert.Error(t, RenameLabelParams{}.validate())
+	assert.Error(t, RenameLabelParams{From: "env"}.validate())
+	assert.Error(t, RenameLabelParams{From: "env", FromValue: "prod"}.validate())
+	assert.NoError(t, RenameLabelParams{From: "env", To: "environment"}.validate())
+	assert.NoError(t, RenameLabelParams{From: "env", FromValue: "prod", ToValue: "production"}.validate())
+}
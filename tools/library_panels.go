@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/client/library_elements"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	DefaultLibraryPanelLimit = 50
+	MaxLibraryPanelLimit     = 100
+
+	// libraryPanelKind is the library element kind of panels, as opposed to
+	// library variables.
+	libraryPanelKind = 1
+)
+
+type ListLibraryPanelsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Optionally\\, part of the name or description of the library panels to list"`
+	Type  string `json:"type,omitempty" jsonschema:"description=Optionally\\, the panel type to list\\, such as 'timeseries' or 'stat'"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of library panels to return (default: 50\\, max: 100)"`
+	Page  int    `json:"page,omitempty" jsonschema:"description=Optionally\\, the page of results to return\\, starting at 1"`
+}
+
+type libraryPanelSummary struct {
+	UID                 string `json:"uid"`
+	Name                string `json:"name"`
+	Type                string `json:"type,omitempty"`
+	Description         string `json:"description,omitempty"`
+	FolderUID           string `json:"folderUid,omitempty"`
+	FolderName          string `json:"folderName,omitempty"`
+	Version             int64  `json:"version"`
+	ConnectedDashboards int64  `json:"connectedDashboards"`
+	Updated             string `json:"updated,omitempty"`
+	UpdatedBy           string `json:"updatedBy,omitempty"`
+}
+
+type libraryPanelList struct {
+	LibraryPanels []libraryPanelSummary `json:"libraryPanels"`
+	TotalCount    int64                 `json:"totalCount"`
+	Page          int64                 `json:"page"`
+}
+
+func summarizeLibraryPanel(e *models.LibraryElementDTO) libraryPanelSummary {
+	summary := libraryPanelSummary{
+		UID:         e.UID,
+		Name:        e.Name,
+		Type:        e.Type,
+		Description: e.Description,
+		FolderUID:   e.FolderUID,
+		Version:     e.Version,
+	}
+	if e.Meta != nil {
+		summary.FolderName = e.Meta.FolderName
+		summary.ConnectedDashboards = e.Meta.ConnectedDashboards
+		if !e.Meta.Updated.IsZero() {
+			summary.Updated = e.Meta.Updated.String()
+		}
+		if e.Meta.UpdatedBy != nil {
+			summary.UpdatedBy = e.Meta.UpdatedBy.Name
+		}
+	}
+	return summary
+}
+
+func listLibraryPanels(ctx context.Context, args ListLibraryPanelsParams) (*libraryPanelList, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLibraryPanelLimit
+	}
+	if limit > MaxLibraryPanelLimit {
+		limit = MaxLibraryPanelLimit
+	}
+	page := int64(args.Page)
+	if page <= 0 {
+		page = 1
+	}
+
+	kind, perPage := int64(libraryPanelKind), int64(limit)
+	params := library_elements.NewGetLibraryElementsParamsWithContext(ctx).
+		WithKind(&kind).
+		WithPerPage(&perPage).
+		WithPage(&page)
+	if args.Query != "" {
+		params.SetSearchString(&args.Query)
+	}
+	if args.Type != "" {
+		params.SetTypeFilter(&args.Type)
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.GetLibraryElements(params)
+	if err != nil {
+		return nil, fmt.Errorf("list library panels: %w", err)
+	}
+
+	result := &libraryPanelList{LibraryPanels: []libraryPanelSummary{}, Page: page}
+	if resp.Payload.Result == nil {
+		return result, nil
+	}
+	result.TotalCount = resp.Payload.Result.TotalCount
+	for _, e := range resp.Payload.Result.Elements {
+		result.LibraryPanels = append(result.LibraryPanels, summarizeLibraryPanel(e))
+	}
+	return result, nil
+}
+
+var ListLibraryPanels = mcpgrafana.MustTool(
+	"list_library_panels",
+	"List library panels, the shared panels which are reused by several dashboards, optionally filtered by name or description and panel type. Returns the UID, name, type, folder, version and the number of connected dashboards of each library panel.",
+	listLibraryPanels,
+	mcp.WithTitleAnnotation("List library panels"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type GetLibraryPanelParams struct {
+	UID  string `json:"uid,omitempty" jsonschema:"description=The UID of the library panel. Either 'uid' or 'name' is required"`
+	Name string `json:"name,omitempty" jsonschema:"description=The name of the library panel\\, if its UID isn't known"`
+}
+
+func getLibraryPanel(ctx context.Context, args GetLibraryPanelParams) (*models.LibraryElementDTO, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	switch {
+	case args.UID != "":
+		if err := validatePathSegment("uid", args.UID); err != nil {
+			return nil, err
+		}
+		resp, err := c.LibraryElements.GetLibraryElementByUID(args.UID)
+		if err != nil {
+			return nil, fmt.Errorf("get library panel %s: %w", args.UID, err)
+		}
+		return resp.Payload.Result, nil
+	case args.Name != "":
+		resp, err := c.LibraryElements.GetLibraryElementByName(args.Name)
+		if err != nil {
+			return nil, fmt.Errorf("get library panel %q: %w", args.Name, err)
+		}
+		var panels []*models.LibraryElementDTO
+		for _, e := range resp.Payload.Result {
+			if e.Kind == libraryPanelKind {
+				panels = append(panels, e)
+			}
+		}
+		switch len(panels) {
+		case 0:
+			return nil, fmt.Errorf("library panel %q not found", args.Name)
+		case 1:
+			return panels[0], nil
+		}
+		uids := make([]string, len(panels))
+		for i, e := range panels {
+			uids[i] = e.UID
+		}
+		return nil, fmt.Errorf("there are %d library panels named %q, in different folders; get one by UID: %s", len(panels), args.Name, strings.Join(uids, ", "))
+	}
+	return nil, fmt.Errorf("either uid or name is required")
+}
+
+var GetLibraryPanel = mcpgrafana.MustTool(
+	"get_library_panel",
+	"Get a library panel by UID or name, including its panel JSON model, its folder and its version, which update_library_panel needs.",
+	getLibraryPanel,
+	mcp.WithTitleAnnotation("Get library panel"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type FindLibraryPanelUsagesParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the library panel"`
+}
+
+type libraryPanelUsage struct {
+	DashboardUID string `json:"dashboardUid"`
+	Title        string `json:"title,omitempty"`
+	URL          string `json:"url,omitempty"`
+	FolderTitle  string `json:"folderTitle,omitempty"`
+	Connected    string `json:"connected,omitempty"`
+}
+
+type libraryPanelUsages struct {
+	UID        string              `json:"uid"`
+	Name       string              `json:"name"`
+	Dashboards []libraryPanelUsage `json:"dashboards"`
+}
+
+func findLibraryPanelUsages(ctx context.Context, args FindLibraryPanelUsagesParams) (*libraryPanelUsages, error) {
+	panel, err := getLibraryPanel(ctx, GetLibraryPanelParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.GetLibraryElementConnections(args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("get connections of library panel %s: %w", args.UID, err)
+	}
+
+	result := &libraryPanelUsages{UID: panel.UID, Name: panel.Name, Dashboards: []libraryPanelUsage{}}
+	var uids []string
+	for _, conn := range resp.Payload.Result {
+		usage := libraryPanelUsage{DashboardUID: conn.ConnectionUID}
+		if !conn.Created.IsZero() {
+			usage.Connected = conn.Created.String()
+		}
+		result.Dashboards = append(result.Dashboards, usage)
+		uids = append(uids, conn.ConnectionUID)
+	}
+	if len(uids) == 0 {
+		return result, nil
+	}
+
+	// Connections only have the dashboards' UIDs, so look up their titles.
+	// The search API expects one dashboardUIDs parameter per UID, while the
+	// generated client joins them with commas.
+	query := url.Values{"type": {dashboardTypeStr}, "dashboardUIDs": uids}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating Grafana client: %w", err)
+	}
+	searchResp, err := client.makeRequest(ctx, "/api/search?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("search dashboards using library panel %s: %w", args.UID, err)
+	}
+	defer searchResp.Body.Close()
+	var hits models.HitList
+	if err := json.NewDecoder(searchResp.Body).Decode(&hits); err != nil {
+		return nil, fmt.Errorf("decode dashboards using library panel %s: %w", args.UID, err)
+	}
+	byUID := make(map[string]*models.Hit, len(hits))
+	for _, hit := range hits {
+		byUID[hit.UID] = hit
+	}
+	for i, usage := range result.Dashboards {
+		if hit, ok := byUID[usage.DashboardUID]; ok {
+			result.Dashboards[i].Title = hit.Title
+			result.Dashboards[i].URL = hit.URL
+			result.Dashboards[i].FolderTitle = hit.FolderTitle
+		}
+	}
+	return result, nil
+}
+
+var FindLibraryPanelUsages = mcpgrafana.MustTool(
+	"find_library_panel_usages",
+	"Find the dashboards which use a library panel, to see what a change to the shared panel will affect. Returns the UID, title, URL and folder of each dashboard.",
+	findLibraryPanelUsages,
+	mcp.WithTitleAnnotation("Find library panel usages"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateLibraryPanelParams struct {
+	Name      string         `json:"name" jsonschema:"required,description=The name of the library panel\\, which must be unique in its folder"`
+	Model     map[string]any `json:"model" jsonschema:"required,description=The panel JSON model\\, as found in a dashboard's 'panels'"`
+	FolderUID string         `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder of the library panel. Defaults to the General folder"`
+	UID       string         `json:"uid,omitempty" jsonschema:"description=Optionally\\, the UID of the library panel. Generated by Grafana if left out"`
+}
+
+// libraryPanelModel returns a panel model ready to save as a library panel.
+// The position and ID of a panel belong to the dashboards using it, and the
+// library panel reference is set by Grafana.
+func libraryPanelModel(model map[string]any) (map[string]any, error) {
+	if len(model) == 0 {
+		return nil, fmt.Errorf("the panel model is required")
+	}
+	if panelType, _ := model["type"].(string); panelType == "" {
+		return nil, fmt.Errorf("the panel model must have a type")
+	}
+	cleaned := make(map[string]any, len(model))
+	for k, v := range model {
+		switch k {
+		case "id", "gridPos", "libraryPanel":
+		default:
+			cleaned[k] = v
+		}
+	}
+	return cleaned, nil
+}
+
+func createLibraryPanel(ctx context.Context, args CreateLibraryPanelParams) (*libraryPanelSummary, error) {
+	if strings.TrimSpace(args.Name) == "" {
+		return nil, fmt.Errorf("the library panel must have a name")
+	}
+	for name, value := range map[string]string{"uid": args.UID, "folderUid": args.FolderUID} {
+		if value == "" {
+			continue
+		}
+		if err := validatePathSegment(name, value); err != nil {
+			return nil, err
+		}
+	}
+	model, err := libraryPanelModel(args.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.CreateLibraryElement(&models.CreateLibraryElementCommand{
+		FolderUID: args.FolderUID,
+		Kind:      libraryPanelKind,
+		Model:     model,
+		Name:      args.Name,
+		UID:       args.UID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create library panel %q: %w", args.Name, err)
+	}
+	summary := summarizeLibraryPanel(resp.Payload.Result)
+	return &summary, nil
+}
+
+var CreateLibraryPanel = mcpgrafana.MustTool(
+	"create_library_panel",
+	"Create a library panel from a panel JSON model, so that it can be shared by several dashboards. The panel's `id` and `gridPos` are dropped, as they belong to the dashboards using it. Returns the UID and version of the new library panel.",
+	createLibraryPanel,
+	mcp.WithTitleAnnotation("Create library panel"),
+)
+
+type UpdateLibraryPanelParams struct {
+	UID       string         `json:"uid" jsonschema:"required,description=The UID of the library panel to update"`
+	Model     map[string]any `json:"model,omitempty" jsonschema:"description=Optionally\\, the new panel JSON model. Defaults to the current model"`
+	Name      string         `json:"name,omitempty" jsonschema:"description=Optionally\\, the new name of the library panel"`
+	FolderUID string         `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder to move the library panel to"`
+	Version   int64          `json:"version,omitempty" jsonschema:"description=Optionally\\, the version of the library panel the change is based on\\, as returned by get_library_panel. The update fails if the library panel has changed since. Defaults to the current version"`
+}
+
+func updateLibraryPanel(ctx context.Context, args UpdateLibraryPanelParams) (*libraryPanelSummary, error) {
+	if args.FolderUID != "" {
+		if err := validatePathSegment("folderUid", args.FolderUID); err != nil {
+			return nil, err
+		}
+	}
+	current, err := getLibraryPanel(ctx, GetLibraryPanelParams{UID: args.UID})
+	if err != nil {
+		return nil, err
+	}
+	if args.Version != 0 && args.Version != current.Version {
+		return nil, fmt.Errorf("library panel %s is at version %d, not %d: it has changed since it was read, get it again and reapply the change", args.UID, current.Version, args.Version)
+	}
+
+	// The update replaces the whole library panel, so unchanged fields are
+	// taken from the current one.
+	cmd := &models.PatchLibraryElementCommand{
+		FolderUID: current.FolderUID,
+		Kind:      libraryPanelKind,
+		Model:     current.Model,
+		Name:      current.Name,
+		UID:       current.UID,
+		Version:   current.Version,
+	}
+	if args.Model != nil {
+		if cmd.Model, err = libraryPanelModel(args.Model); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(args.Name) != "" {
+		cmd.Name = args.Name
+	}
+	if args.FolderUID != "" {
+		cmd.FolderUID = args.FolderUID
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.UpdateLibraryElement(args.UID, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("update library panel %s: %w", args.UID, err)
+	}
+	summary := summarizeLibraryPanel(resp.Payload.Result)
+	return &summary, nil
+}
+
+var UpdateLibraryPanel = mcpgrafana.MustTool(
+	"update_library_panel",
+	"Update a library panel's JSON model, name or folder. The change applies to every dashboard using the library panel; use find_library_panel_usages to see which dashboards those are. Pass the `version` returned by get_library_panel so the update fails rather than overwriting a concurrent change. Returns the new version of the library panel.",
+	updateLibraryPanel,
+	mcp.WithTitleAnnotation("Update library panel"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const libraryPanelTestElement = `{
+	"uid": "errors", "name": "Error rate", "kind": 1, "type": "timeseries", "folderUid": "shared", "version": 3,
+	"model": {"type": "timeseries", "title": "Error rate", "targets": [{"refId": "A", "expr": "sum(rate(errors_total[5m]))"}]},
+	"meta": {"folderName": "Shared", "connectedDashboards": 2, "updated": "2024-01-02T10:00:00Z", "updatedBy": {"name": "alice"}}
+}`
+
+func newLibraryPanelTestMux(t *testing.T) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/library-elements", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("kind"))
+		assert.Equal(t, "error", r.URL.Query().Get("searchString"))
+		assert.Equal(t, "10", r.URL.Query().Get("perPage"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": {"totalCount": 1, "page": 1, "perPage": 10, "elements": [` + libraryPanelTestElement + `]}}`))
+	})
+	mux.HandleFunc("/api/library-elements/errors", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": ` + libraryPanelTestElement + `}`))
+	})
+	mux.HandleFunc("/api/library-elements/name/{name}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Error rate", r.PathValue("name"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": [` + libraryPanelTestElement + `, {"uid": "errors-2", "name": "Error rate", "kind": 1}]}`))
+	})
+	mux.HandleFunc("/api/library-elements/errors/connections/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": [
+			{"connectionId": 1, "connectionUid": "api", "created": "2024-01-01T10:00:00Z"},
+			{"connectionId": 2, "connectionUid": "gone"}
+		]}`))
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"api", "gone"}, r.URL.Query()["dashboardUIDs"])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid": "api", "title": "API", "url": "/d/api/api", "folderTitle": "Services", "type": "dash-db"}]`))
+	})
+	return mux
+}
+
+func TestLibraryPanelReadTools(t *testing.T) {
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", newLibraryPanelTestMux(t))
+
+	list, err := listLibraryPanels(ctx, ListLibraryPanelsParams{Query: "error", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), list.TotalCount)
+	require.Len(t, list.LibraryPanels, 1)
+	assert.Equal(t, libraryPanelSummary{
+		UID: "errors", Name: "Error rate", Type: "timeseries", FolderUID: "shared", FolderName: "Shared",
+		Version: 3, ConnectedDashboards: 2, Updated: "2024-01-02T10:00:00.000Z", UpdatedBy: "alice",
+	}, list.LibraryPanels[0])
+
+	panel, err := getLibraryPanel(ctx, GetLibraryPanelParams{UID: "errors"})
+	require.NoError(t, err)
+	assert.Equal(t, "Error rate", panel.Model.(map[string]any)["title"])
+
+	_, err = getLibraryPanel(ctx, GetLibraryPanelParams{Name: "Error rate"})
+	assert.ErrorContains(t, err, "errors, errors-2")
+	_, err = getLibraryPanel(ctx, GetLibraryPanelParams{})
+	assert.Error(t, err)
+
+	usages, err := findLibraryPanelUsages(ctx, FindLibraryPanelUsagesParams{UID: "errors"})
+	require.NoError(t, err)
+	assert.Equal(t, "Error rate", usages.Name)
+	assert.Equal(t, []libraryPanelUsage{
+		{DashboardUID: "api", Title: "API", URL: "/d/api/api", FolderTitle: "Services", Connected: "2024-01-01T10:00:00.000Z"},
+		{DashboardUID: "gone"},
+	}, usages.Dashboards)
+}
+
+func TestLibraryPanelWriteTools(t *testing.T) {
+	var saved map[string]any
+	mux := newLibraryPanelTestMux(t)
+	save := func(w http.ResponseWriter, r *http.Request) {
+		saved = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": {"uid": "new", "name": "Latency", "kind": 1, "version": 1}}`))
+	}
+	mux.HandleFunc("POST /api/library-elements", save)
+	mux.HandleFunc("PATCH /api/library-elements/errors", save)
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	created, err := createLibraryPanel(ctx, CreateLibraryPanelParams{
+		Name:      "Latency",
+		FolderUID: "shared",
+		Model:     map[string]any{"id": 4, "type": "timeseries", "title": "Latency", "gridPos": map[string]any{"x": 0}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "new", created.UID)
+	assert.Equal(t, "shared", saved["folderUid"])
+	assert.Equal(t, float64(1), saved["kind"])
+	assert.Equal(t, map[string]any{"type": "timeseries", "title": "Latency"}, saved["model"])
+
+	_, err = createLibraryPanel(ctx, CreateLibraryPanelParams{Name: "Latency", Model: map[string]any{"title": "Latency"}})
+	assert.ErrorContains(t, err, "type")
+
+	// Fields which aren't changed are kept.
+	_, err = updateLibraryPanel(ctx, UpdateLibraryPanelParams{UID: "errors", Name: "Errors", Version: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "Errors", saved["name"])
+	assert.Equal(t, "shared", saved["folderUid"])
+	assert.Equal(t, float64(3), saved["version"])
+	assert.Equal(t, "Error rate", saved["model"].(map[string]any)["title"])
+
+	saved = nil
+	_, err = updateLibraryPanel(ctx, UpdateLibraryPanelParams{UID: "errors", Name: "Errors", Version: 2})
+	assert.ErrorContains(t, err, "changed since")
+	assert.Nil(t, saved)
+}
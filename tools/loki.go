@@ -8,13 +8,19 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	mcpgrafana "github.com/grafana/mcp-grafana"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/common/model"
 )
 
 const (
@@ -23,6 +29,10 @@ const (
 
 	// MaxLokiLogLimit is the maximum number of log lines that can be requested
 	MaxLokiLogLimit = 100
+
+	// MaxLokiExportLimit is the maximum number of log lines that can be
+	// exported to a resource, and the default when exporting
+	MaxLokiExportLimit = 5000
 )
 
 type Client struct {
@@ -52,25 +62,15 @@ func newLokiClient(ctx context.Context, uid string) (*Client, error) {
 	}
 
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
-	url := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), uid)
-
-	// Create custom transport with TLS configuration if available
-	var transport http.RoundTripper = http.DefaultTransport
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		var err error
-		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
+	escapedUID, err := sanitizePathSegment("datasource UID", uid)
+	if err != nil {
+		return nil, err
 	}
+	url := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), escapedUID)
 
-	client := &http.Client{
-		Transport: &authRoundTripper{
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			apiKey:      cfg.APIKey,
-			underlying:  transport,
-		},
+	client, err := newQueryHTTPClient(ctx, defaultsCategoryLoki, http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
@@ -115,6 +115,12 @@ func (c *Client) makeRequest(ctx context.Context, method, urlPath string, params
 	}
 	defer resp.Body.Close()
 
+	// Some endpoints, such as the creation of deletion requests, respond
+	// without content
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
 	// Check for non-200 status code
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
@@ -137,14 +143,15 @@ func (c *Client) makeRequest(ctx context.Context, method, urlPath string, params
 	return bytes.TrimSpace(bodyBytes), nil
 }
 
-// fetchData is a generic method to fetch data from Loki API
-func (c *Client) fetchData(ctx context.Context, urlPath string, startRFC3339, endRFC3339 string) ([]string, error) {
+// fetchData is a generic method to fetch label data from Loki API, optionally
+// restricted to the streams matching a selector
+func (c *Client) fetchData(ctx context.Context, urlPath string, selector, startRFC3339, endRFC3339 string) ([]string, error) {
 	params := url.Values{}
-	if startRFC3339 != "" {
-		params.Add("start", startRFC3339)
+	if selector != "" {
+		params.Add("query", selector)
 	}
-	if endRFC3339 != "" {
-		params.Add("end", endRFC3339)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
 	}
 
 	bodyBytes, err := c.makeRequest(ctx, "GET", urlPath, params)
@@ -201,8 +208,9 @@ func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 // ListLokiLabelNamesParams defines the parameters for listing Loki label names
 type ListLokiLabelNamesParams struct {
 	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
-	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	Selector      string `json:"selector,omitempty" jsonschema:"description=Optionally\\, a LogQL stream selector (e.g. '{app=\"nginx\"}') restricting the labels to those of matching streams"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 1 hour ago"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
 }
 
 // listLokiLabelNames lists all label names in a Loki datasource
@@ -212,7 +220,12 @@ func listLokiLabelNames(ctx context.Context, args ListLokiLabelNamesParams) ([]s
 		return nil, fmt.Errorf("creating Loki client: %w", err)
 	}
 
-	result, err := client.fetchData(ctx, "/loki/api/v1/labels", args.StartRFC3339, args.EndRFC3339)
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.fetchData(ctx, "/loki/api/v1/labels", args.Selector, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +240,7 @@ func listLokiLabelNames(ctx context.Context, args ListLokiLabelNamesParams) ([]s
 // ListLokiLabelNames is a tool for listing Loki label names
 var ListLokiLabelNames = mcpgrafana.MustTool(
 	"list_loki_label_names",
-	"Lists all available label names (keys) found in logs within a specified Loki datasource and time range. Returns a list of unique label strings (e.g., `[\"app\", \"env\", \"pod\"]`). If the time range is not provided, it defaults to the last hour.",
+	"Lists all available label names (keys) found in logs within a specified Loki datasource and time range. Returns a list of unique label strings (e.g., `[\"app\", \"env\", \"pod\"]`). Set `selector` to only list the labels of matching streams. Use this with `list_loki_label_values` to discover which streams exist before writing LogQL. If the time range is not provided, it defaults to the last hour.",
 	listLokiLabelNames,
 	mcp.WithTitleAnnotation("List Loki label names"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -238,8 +251,9 @@ var ListLokiLabelNames = mcpgrafana.MustTool(
 type ListLokiLabelValuesParams struct {
 	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
 	LabelName     string `json:"labelName" jsonschema:"required,description=The name of the label to retrieve values for (e.g. 'app'\\, 'env'\\, 'pod')"`
-	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
-	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	Selector      string `json:"selector,omitempty" jsonschema:"description=Optionally\\, a LogQL stream selector (e.g. '{app=\"nginx\"}') restricting the values to those of matching streams"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 1 hour ago"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
 }
 
 // listLokiLabelValues lists all values for a specific label in a Loki datasource
@@ -249,10 +263,17 @@ func listLokiLabelValues(ctx context.Context, args ListLokiLabelValuesParams) ([
 		return nil, fmt.Errorf("creating Loki client: %w", err)
 	}
 
-	// Use the client's fetchData method
-	urlPath := fmt.Sprintf("/loki/api/v1/label/%s/values", args.LabelName)
+	labelName, err := sanitizePathSegment("label name", args.LabelName)
+	if err != nil {
+		return nil, err
+	}
+	urlPath := fmt.Sprintf("/loki/api/v1/label/%s/values", labelName)
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := client.fetchData(ctx, urlPath, args.StartRFC3339, args.EndRFC3339)
+	result, err := client.fetchData(ctx, urlPath, args.Selector, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +289,7 @@ func listLokiLabelValues(ctx context.Context, args ListLokiLabelValuesParams) ([
 // ListLokiLabelValues is a tool for listing Loki label values
 var ListLokiLabelValues = mcpgrafana.MustTool(
 	"list_loki_label_values",
-	"Retrieves all unique values associated with a specific `labelName` within a Loki datasource and time range. Returns a list of string values (e.g., for `labelName=\"env\"`, might return `[\"prod\", \"staging\", \"dev\"]`). Useful for discovering filter options. Defaults to the last hour if the time range is omitted.",
+	"Retrieves all unique values associated with a specific `labelName` within a Loki datasource and time range. Returns a list of string values (e.g., for `labelName=\"env\"`, might return `[\"prod\", \"staging\", \"dev\"]`). Set `selector` to only list the values found in matching streams, e.g. the pods of one app. Useful for discovering filter options. Defaults to the last hour if the time range is omitted.",
 	listLokiLabelValues,
 	mcp.WithTitleAnnotation("List Loki label values"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -313,11 +334,12 @@ func addTimeRangeParams(params url.Values, startRFC3339, endRFC3339 string) erro
 }
 
 // getDefaultTimeRange returns default start and end times if not provided
-// Returns start time (1 hour ago) and end time (now) in RFC3339 format
-func getDefaultTimeRange(startRFC3339, endRFC3339 string) (string, string) {
+// Returns start time (the configured Loki time range ago, 1 hour by default)
+// and end time (now) in RFC3339 format
+func getDefaultTimeRange(ctx context.Context, startRFC3339, endRFC3339 string) (string, string) {
 	if startRFC3339 == "" {
-		// Default to 1 hour ago if not specified
-		startRFC3339 = time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+		// Default to the configured time range ago if not specified
+		startRFC3339 = time.Now().Add(-toolDefaultsFor(ctx, defaultsCategoryLoki).TimeRange).Format(time.RFC3339)
 	}
 	if endRFC3339 == "" {
 		// Default to now if not specified
@@ -326,6 +348,59 @@ func getDefaultTimeRange(startRFC3339, endRFC3339 string) (string, string) {
 	return startRFC3339, endRFC3339
 }
 
+// resolveRelativeTimeRange converts start and end times in any format
+// accepted by parseTime, such as "now-15m" or Unix nanoseconds, to RFC3339.
+// Empty times are left as is.
+func resolveRelativeTimeRange(startStr, endStr string) (string, string, error) {
+	resolve := func(s string) (string, error) {
+		if s == "" {
+			return "", nil
+		}
+		t, err := parseTime(s)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+	start, err := resolve(startStr)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := resolve(endStr)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing end time: %w", err)
+	}
+	return start, end, nil
+}
+
+// lokiTimeRange resolves the start and end of a Loki query, which may be
+// relative to now, applies the default time range and clamps the range to the
+// configured limits. It returns RFC3339 times.
+func lokiTimeRange(ctx context.Context, startStr, endStr string) (string, string, error) {
+	startTime, endTime, err := resolveRelativeTimeRange(startStr, endStr)
+	if err != nil {
+		return "", "", err
+	}
+	startTime, endTime = getDefaultTimeRange(ctx, startTime, endTime)
+
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing end time: %w", err)
+	}
+	clampedStart, clampedEnd, err := clampTimeRange(ctx, defaultsCategoryLoki, start, end)
+	if err != nil {
+		return "", "", err
+	}
+	if !clampedStart.Equal(start) || !clampedEnd.Equal(end) {
+		startTime, endTime = clampedStart.Format(time.RFC3339Nano), clampedEnd.Format(time.RFC3339Nano)
+	}
+	return startTime, endTime, nil
+}
+
 // fetchLogs is a method to fetch logs from Loki API
 func (c *Client) fetchLogs(ctx context.Context, query, startRFC3339, endRFC3339 string, limit int, direction string) ([]LogStream, error) {
 	params := url.Values{}
@@ -364,12 +439,22 @@ func (c *Client) fetchLogs(ctx context.Context, query, startRFC3339, endRFC3339
 
 // QueryLokiLogsParams defines the parameters for querying Loki logs
 type QueryLokiLogsParams struct {
-	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL query to execute against Loki. This can be a simple label matcher or a complex query with filters\\, parsers\\, and expressions. Supports full LogQL syntax including label matchers\\, filter operators\\, pattern expressions\\, and pipeline operations."`
-	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format"`
-	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format"`
-	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of log lines to return (default: 10\\, max: 100)"`
-	Direction     string `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	DatasourceUID  string         `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	DatasourceUIDs []string       `json:"datasourceUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of more Loki datasources to run the same query against concurrently\\, e.g. one per cluster or region. The results are merged and each is attributed to its datasource in 'datasource'"`
+	LogQL          string         `json:"logql" jsonschema:"required,description=The LogQL query to execute against Loki. This can be a simple label matcher or a complex query with filters\\, parsers\\, and expressions. Supports full LogQL syntax including label matchers\\, filter operators\\, pattern expressions\\, and pipeline operations."`
+	StartRFC3339   string         `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-15m'). Defaults to 1 hour ago"`
+	EndRFC3339     string         `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Limit          int            `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of log lines to return (default: 10\\, max: 100)"`
+	Direction      string         `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	EstimateOnly   bool           `json:"estimateOnly,omitempty" jsonschema:"description=Optionally\\, only return the index statistics (streams\\, chunks\\, entries and bytes) of the streams the query reads instead of running it. Use this to gauge the cost of a query before running it"`
+	Debug          bool           `json:"debug,omitempty" jsonschema:"description=Optionally\\, include the query model and the raw request and response exchanged with the datasource in the result\\, like Grafana's Query Inspector. Useful for debugging differences between tool results and the Grafana UI"`
+	PageSize       int            `json:"pageSize,omitempty" jsonschema:"description=Optionally\\, return the log lines in pages of this size. The result is then an object with the lines in 'items' and a 'nextPageToken' while more pages remain"`
+	PageToken      string         `json:"pageToken,omitempty" jsonschema:"description=Optionally\\, the 'nextPageToken' from a previous call to fetch the next page of its results. The other parameters are ignored"`
+	WithCursor     bool           `json:"withCursor,omitempty" jsonschema:"description=Optionally\\, return the log lines as an object with the lines in 'items' and\\, while more lines may remain\\, a 'nextCursor' to continue from. Unlike pageSize\\, each page is queried from Loki\\, so all matching lines can be read 'limit' lines at a time"`
+	Cursor         string         `json:"cursor,omitempty" jsonschema:"description=Optionally\\, the 'nextCursor' from a previous call to fetch the next lines of the same query. The cursor holds the query and its time range\\, so the other parameters are ignored"`
+	Export         string         `json:"export,omitempty" jsonschema:"enum=ndjson,enum=csv,description=Optionally\\, export the log lines as NDJSON or CSV to a resource instead of returning them. The result is then a summary with the 'resourceUri' to read them from. Exports return up to 5000 lines by default. Use this when all the matching logs are needed"`
+	Metadata       []LabelMatcher `json:"metadata,omitempty" jsonschema:"description=Optionally\\, filters on structured metadata\\, such as the OTLP attributes 'trace_id'\\, 'span_id'\\, 'severity_text' or 'service.name' that Loki 3 stores outside the stream labels. Each is added to the query as a label filter directly after the stream selector\\, with dots in names replaced by underscores. Prefer this over writing the filters in 'logql'"`
+	Summarize      bool           `json:"summarize,omitempty" jsonschema:"description=Optionally\\, collapse identical or near-identical log lines (differing only in numbers\\, IDs or IP addresses) into one message each with a count and sample timestamps\\, most frequent first. Useful for noisy services. Only applies to log queries"`
 }
 
 // LogEntry represents a single log entry or metric sample with metadata
@@ -378,31 +463,91 @@ type LogEntry struct {
 	Line      string            `json:"line,omitempty"`  // For log queries
 	Value     *float64          `json:"value,omitempty"` // For metric queries
 	Labels    map[string]string `json:"labels"`
+	// Datasource is the UID of the datasource the entry came from, set when
+	// several datasources were queried
+	Datasource string `json:"datasource,omitempty"`
 }
 
-// enforceLogLimit ensures a log limit value is within acceptable bounds
-func enforceLogLimit(requestedLimit int) int {
-	if requestedLimit <= 0 {
-		return DefaultLokiLogLimit
+// enforceLogLimit ensures a log limit value is within the configured bounds
+func enforceLogLimit(ctx context.Context, requestedLimit int) int {
+	return enforceLimit(toolDefaultsFor(ctx, defaultsCategoryLoki), requestedLimit)
+}
+
+// lokiLogLimit returns the number of log lines to fetch for a query. Exports
+// are not returned inline, so they may fetch up to MaxLokiExportLimit lines.
+func lokiLogLimit(ctx context.Context, args QueryLokiLogsParams) int {
+	if args.Export == "" {
+		return enforceLogLimit(ctx, args.Limit)
 	}
-	if requestedLimit > MaxLokiLogLimit {
-		return MaxLokiLogLimit
+	if args.Limit <= 0 {
+		return MaxLokiExportLimit
 	}
-	return requestedLimit
+	return min(args.Limit, MaxLokiExportLimit)
 }
 
-// queryLokiLogs queries logs from a Loki datasource using LogQL
+// queryLokiLogs queries logs from one or more Loki datasources using LogQL.
+// The entries of several datasources are merged in the query's direction and
+// attributed to their datasource.
 func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, error) {
-	client, err := newLokiClient(ctx, args.DatasourceUID)
+	uids := []string{args.DatasourceUID}
+	for _, uid := range args.DatasourceUIDs {
+		if !slices.Contains(uids, uid) {
+			uids = append(uids, uid)
+		}
+	}
+	if len(uids) == 1 {
+		return queryLokiDatasourceLogs(ctx, args.DatasourceUID, args)
+	}
+
+	results := make([][]LogEntry, len(uids))
+	errs := make([]error, len(uids))
+	var wg sync.WaitGroup
+	for i, uid := range uids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = queryLokiDatasourceLogs(ctx, uid, args)
+		}()
+	}
+	wg.Wait()
+
+	var entries []LogEntry
+	for i, uid := range uids {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("querying datasource %s: %w", uid, errs[i])
+		}
+		for _, entry := range results[i] {
+			entry.Datasource = uid
+			entries = append(entries, entry)
+		}
+	}
+	sortLogEntries(entries)
+	if args.Direction != "forward" {
+		slices.Reverse(entries)
+	}
+	if limit := lokiLogLimit(ctx, args); len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if entries == nil {
+		return []LogEntry{}, nil
+	}
+	return entries, nil
+}
+
+// queryLokiDatasourceLogs runs a log query against a single Loki datasource
+func queryLokiDatasourceLogs(ctx context.Context, uid string, args QueryLokiLogsParams) ([]LogEntry, error) {
+	client, err := newLokiClient(ctx, uid)
 	if err != nil {
 		return nil, fmt.Errorf("creating Loki client: %w", err)
 	}
 
-	// Get default time range if not provided
-	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
 
 	// Apply limit constraints
-	limit := enforceLogLimit(args.Limit)
+	limit := lokiLogLimit(ctx, args)
 
 	// Set default direction if not provided
 	direction := args.Direction
@@ -410,23 +555,38 @@ func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, e
 		direction = "backward" // Most recent logs first
 	}
 
+	queryInspectorFromContext(ctx).setQuery(map[string]any{
+		"refId":      "A",
+		"datasource": datasourceInfo{UID: uid, Type: "loki"},
+		"expr":       args.LogQL,
+		"queryType":  "range",
+		"from":       startTime,
+		"to":         endTime,
+		"maxLines":   limit,
+		"direction":  direction,
+	})
+
+	if windows := lokiSplitWindows(ctx, args.LogQL, startTime, endTime, direction); windows != nil {
+		return client.fetchLogsSplit(ctx, args.LogQL, windows, limit, direction)
+	}
 	streams, err := client.fetchLogs(ctx, args.LogQL, startTime, endTime, limit, direction)
 	if err != nil {
 		return nil, err
 	}
 
-	// Handle empty results
-	if len(streams) == 0 {
-		return []LogEntry{}, nil
-	}
+	return streamsToLogEntries(streams), nil
+}
 
+// streamsToLogEntries converts the streams returned by Loki to a flat list of
+// log entries, skipping values which cannot be parsed
+func streamsToLogEntries(streams []LogStream) []LogEntry {
 	// Convert the streams to a flat list of log entries
 	var entries []LogEntry
 	for _, stream := range streams {
 		for _, value := range stream.Values {
 			if len(value) >= 2 {
 				entry := LogEntry{
-					Timestamp: string(value[0]),
+					Timestamp: strings.Trim(string(value[0]), `"`),
 					Labels:    stream.Stream,
 				}
 
@@ -469,22 +629,617 @@ func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, e
 
 	// If we processed all streams but still have no entries, return an empty slice
 	if len(entries) == 0 {
-		return []LogEntry{}, nil
+		return []LogEntry{}
 	}
 
-	return entries, nil
+	return entries
+}
+
+// maxLokiSummarySamples is the number of sample timestamps kept for each
+// message of a summarized log query
+const maxLokiSummarySamples = 3
+
+// lokiVariablePattern matches the parts of log lines which usually vary
+// between otherwise identical lines: UUIDs, IP addresses, hex IDs and numbers
+var lokiVariablePattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}(?:-[0-9a-f]{4}){3}-[0-9a-f]{12}\b|\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b|\b(?:0x)?[0-9a-f]*\d[0-9a-f]*\b|\d+(?:\.\d+)?`)
+
+// LokiLogMessage is a log message with the number of times it occurred, as
+// returned by summarized log queries
+type LokiLogMessage struct {
+	// Message is the first line seen with this pattern
+	Message string `json:"message"`
+	// Pattern is the message with its varying parts replaced by <_>, when
+	// lines differing in those parts were collapsed
+	Pattern    string   `json:"pattern,omitempty"`
+	Count      int      `json:"count"`
+	Timestamps []string `json:"sampleTimestamps"`
+}
+
+// summarizeLogEntries collapses log lines which only differ in their varying
+// parts, ordering the messages by how often they occurred
+func summarizeLogEntries(entries []LogEntry) ([]LokiLogMessage, error) {
+	var messages []LokiLogMessage
+	byPattern := map[string]int{}
+	for _, entry := range entries {
+		if entry.Value != nil {
+			return nil, fmt.Errorf("summarize is only supported for log queries, not metric queries")
+		}
+		pattern := lokiVariablePattern.ReplaceAllString(entry.Line, "<_>")
+		i, ok := byPattern[pattern]
+		if !ok {
+			i = len(messages)
+			byPattern[pattern] = i
+			messages = append(messages, LokiLogMessage{Message: entry.Line, Timestamps: []string{}})
+		}
+		m := &messages[i]
+		m.Count++
+		if entry.Line != m.Message {
+			m.Pattern = pattern
+		}
+		if len(m.Timestamps) < maxLokiSummarySamples {
+			m.Timestamps = append(m.Timestamps, entry.Timestamp)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Count > messages[j].Count
+	})
+	if messages == nil {
+		return []LokiLogMessage{}, nil
+	}
+	return messages, nil
+}
+
+// queryLokiLogsResult is the result of the query_loki_logs tool, whose shape
+// depends on the arguments.
+type queryLokiLogsResult struct{ resultValue }
+
+func (queryLokiLogsResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{
+		reflect.TypeFor[inspectable[paginated[LogEntry]]](),
+		reflect.TypeFor[inspectable[paginated[LokiLogMessage]]](),
+		reflect.TypeFor[inspectable[*lokiLogPage]](),
+		reflect.TypeFor[inspectable[*exportSummary]](),
+		reflect.TypeFor[inspectable[*lokiQueryEstimate]](),
+	}
+}
+
+// queryLokiLogsTool handles calls to the query_loki_logs tool, adding query
+// inspection details to the result when debug is requested.
+func queryLokiLogsTool(ctx context.Context, args QueryLokiLogsParams) (queryLokiLogsResult, error) {
+	if args.Cursor == "" && args.PageToken == "" {
+		logql, err := withLokiMetadataFilters(args.LogQL, args.Metadata)
+		if err != nil {
+			return queryLokiLogsResult{}, err
+		}
+		args.LogQL = logql
+	}
+	if args.EstimateOnly {
+		return asResult[queryLokiLogsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*lokiQueryEstimate, error) {
+			return estimateLokiQuery(ctx, args.DatasourceUID, args.LogQL, args.StartRFC3339, args.EndRFC3339)
+		}))
+	}
+	if args.WithCursor || args.Cursor != "" {
+		if args.PageSize != 0 || args.PageToken != "" || args.Export != "" || args.Summarize {
+			return queryLokiLogsResult{}, fmt.Errorf("cursors cannot be combined with pageSize, pageToken, export or summarize")
+		}
+		return asResult[queryLokiLogsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*lokiLogPage, error) {
+			return queryLokiLogsPage(ctx, args)
+		}))
+	}
+	if args.Export != "" {
+		if err := validateExportFormat(args.Export); err != nil {
+			return queryLokiLogsResult{}, err
+		}
+		return asResult[queryLokiLogsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*exportSummary, error) {
+			entries, err := queryLokiLogs(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			summary, err := exportLogEntries(ctx, args.Export, entries)
+			if err != nil {
+				return nil, err
+			}
+			summary.LimitReached = len(entries) >= lokiLogLimit(ctx, args)
+			return summary, nil
+		}))
+	}
+	if args.Summarize {
+		return asResult[queryLokiLogsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (paginated[LokiLogMessage], error) {
+			return withPagination(ctx, "query_loki_logs", args.PageSize, args.PageToken, func(ctx context.Context) ([]LokiLogMessage, error) {
+				entries, err := queryLokiLogs(ctx, args)
+				if err != nil {
+					return nil, err
+				}
+				return summarizeLogEntries(entries)
+			})
+		}))
+	}
+	return asResult[queryLokiLogsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (paginated[LogEntry], error) {
+		return withPagination(ctx, "query_loki_logs", args.PageSize, args.PageToken, func(ctx context.Context) ([]LogEntry, error) {
+			return queryLokiLogs(ctx, args)
+		})
+	}))
 }
 
 // QueryLokiLogs is a tool for querying logs from Loki
 var QueryLokiLogs = mcpgrafana.MustTool(
 	"query_loki_logs",
-	"Executes a LogQL query against a Loki datasource to retrieve log entries or metric values. Returns a list of results, each containing a timestamp, labels, and either a log line (`line`) or a numeric metric value (`value`). Defaults to the last hour, a limit of 10 entries, and 'backward' direction (newest first). Supports full LogQL syntax for log and metric queries (e.g., `{app=\"foo\"} |= \"error\"`, `rate({app=\"bar\"}[1m])`). Prefer using `query_loki_stats` first to check stream size and `list_loki_label_names` and `list_loki_label_values` to verify labels exist.",
-	queryLokiLogs,
+	"Executes a LogQL query against a Loki datasource to retrieve log entries or metric values. Returns a list of results, each containing a timestamp, labels, and either a log line (`line`) or a numeric metric value (`value`). Times may be RFC3339 or relative to now (e.g. `now-15m`). Defaults to the last hour, a limit of 10 entries, and 'backward' direction (newest first). Supports full LogQL syntax for log and metric queries (e.g., `{app=\"foo\"} |= \"error\"`, `rate({app=\"bar\"}[1m])`). Prefer using `query_loki_stats` first to check stream size and `list_loki_label_names` and `list_loki_label_values` to verify labels exist. Use `query_loki_metrics` to get metric queries as time series with a controllable step. Set `estimateOnly` to only get the index statistics of the streams the query reads. Set `datasourceUids` to also run the query against more Loki datasources, such as one per region, merging the results with each attributed to its `datasource`. Set `export` to `ndjson` or `csv` to write up to 5000 lines to a resource and only get its `resourceUri`, for when all matching logs are needed. To filter on structured metadata such as the OTLP attributes `trace_id` or `severity_text`, use `metadata` rather than writing the filters in `logql`. Set `summarize` to collapse identical or near-identical lines into messages with a count and sample timestamps, most frequent first. Set `pageSize` to receive the results in pages, passing the returned `nextPageToken` as `pageToken` to fetch the next one. To read more lines than the limit, set `withCursor` and pass each returned `nextCursor` as `cursor` to query the next lines, without overlaps or gaps. Set `debug` to also return the query model and raw datasource response.",
+	guardTimeRange(queryLokiLogsTool),
 	mcp.WithTitleAnnotation("Query Loki logs"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// DefaultLokiContextLines is the default number of lines fetched on each side
+// of a log line by get_loki_log_context
+const DefaultLokiContextLines = 10
+
+// GetLokiLogContextParams defines the parameters for fetching the lines around
+// a log line
+type GetLokiLogContextParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Selector      string `json:"selector" jsonschema:"required,description=The LogQL stream selector of the log line\\, usually its labels (e.g. '{app=\"foo\"\\, pod=\"foo-1\"}'). Line filters may be added to narrow the context"`
+	Timestamp     string `json:"timestamp" jsonschema:"required,description=The timestamp of the log line\\, either in Unix nanoseconds as returned by query_loki_logs or in RFC3339 format"`
+	Line          string `json:"line,omitempty" jsonschema:"description=Optionally\\, the text of the log line\\, to tell it apart from other lines with the same timestamp"`
+	Before        int    `json:"before,omitempty" jsonschema:"description=Optionally\\, the number of lines to fetch before the log line (default: 10\\, max: 100)"`
+	After         int    `json:"after,omitempty" jsonschema:"description=Optionally\\, the number of lines to fetch after the log line (default: 10\\, max: 100)"`
+}
+
+// lokiLogContext is a log line with the lines around it, oldest first
+type lokiLogContext struct {
+	Before []LogEntry `json:"before"`
+	Line   *LogEntry  `json:"line"`
+	After  []LogEntry `json:"after"`
+}
+
+// sortLogEntries sorts log entries from several streams by timestamp, oldest
+// first
+func sortLogEntries(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(entries[i].Timestamp, 10, 64)
+		tj, _ := strconv.ParseInt(entries[j].Timestamp, 10, 64)
+		return ti < tj
+	})
+}
+
+// getLokiLogContext fetches the lines before and after a log line, like
+// Grafana's "show context". Lines are searched for within the configured
+// default Loki time range on each side of the log line.
+func getLokiLogContext(ctx context.Context, args GetLokiLogContextParams) (*lokiLogContext, error) {
+	ts, err := parseTime(args.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing timestamp: %w", err)
+	}
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	d := toolDefaultsFor(ctx, defaultsCategoryLoki)
+	before, after := enforceContextLines(d, args.Before), enforceContextLines(d, args.After)
+	window := d.TimeRange
+
+	// Loki's end time is exclusive, so this excludes the line itself.
+	streams, err := client.fetchLogs(ctx, args.Selector, ts.Add(-window).Format(time.RFC3339Nano), ts.Format(time.RFC3339Nano), before, "backward")
+	if err != nil {
+		return nil, fmt.Errorf("fetching lines before: %w", err)
+	}
+	result := &lokiLogContext{Before: streamsToLogEntries(streams)}
+	sortLogEntries(result.Before)
+	if len(result.Before) > before {
+		result.Before = result.Before[len(result.Before)-before:]
+	}
+
+	// Fetch an extra line, as the line itself is included from its start time.
+	streams, err = client.fetchLogs(ctx, args.Selector, ts.Format(time.RFC3339Nano), ts.Add(window).Format(time.RFC3339Nano), after+1, "forward")
+	if err != nil {
+		return nil, fmt.Errorf("fetching lines after: %w", err)
+	}
+	entries := streamsToLogEntries(streams)
+	sortLogEntries(entries)
+	tsNanos := strconv.FormatInt(ts.UnixNano(), 10)
+	result.After = []LogEntry{}
+	for i := range entries {
+		e := entries[i]
+		if result.Line == nil && e.Timestamp == tsNanos && (args.Line == "" || e.Line == args.Line) {
+			result.Line = &e
+			continue
+		}
+		result.After = append(result.After, e)
+	}
+	if len(result.After) > after {
+		result.After = result.After[:after]
+	}
+	return result, nil
+}
+
+// enforceContextLines applies the default number of context lines and caps it
+// at the configured maximum Loki limit
+func enforceContextLines(d mcpgrafana.ToolDefaults, requested int) int {
+	if requested <= 0 {
+		requested = DefaultLokiContextLines
+	}
+	return enforceLimit(d, requested)
+}
+
+// GetLokiLogContext is a tool for fetching the lines around a log line
+var GetLokiLogContext = mcpgrafana.MustTool(
+	"get_loki_log_context",
+	"Fetches the log lines immediately before and after a given log line in a Loki datasource, like Grafana's \"show context\". Pass the stream selector (usually the labels of the line returned by `query_loki_logs`) and the line's timestamp. Returns the lines before and after, oldest first, and the line itself if found. Use this to understand an error line that a search returned in isolation. Defaults to 10 lines on each side.",
+	getLokiLogContext,
+	mcp.WithTitleAnnotation("Get Loki log context"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+const (
+	// DefaultLokiTailSeconds is how long tail_loki_logs tails for by default
+	DefaultLokiTailSeconds = 30
+
+	// MaxLokiTailSeconds is the longest tail_loki_logs can tail for
+	MaxLokiTailSeconds = 300
+
+	// DefaultLokiTailLimit is the default number of lines tail_loki_logs
+	// returns before stopping
+	DefaultLokiTailLimit = 100
+
+	// MaxLokiTailLimit is the maximum number of lines tail_loki_logs can return
+	MaxLokiTailLimit = 1000
+)
+
+// lokiTailPollInterval is how often tail_loki_logs polls Loki for new lines.
+var lokiTailPollInterval = 2 * time.Second
+
+// lokiTailLookback is how long before the end of the previous poll each poll
+// of tail_loki_logs starts, like the delay_for of Loki's tail API, so lines
+// which reach Loki after the poll covering their timestamp are still found.
+var lokiTailLookback = 5 * time.Second
+
+// TailLokiLogsParams defines the parameters for tailing Loki logs
+type TailLokiLogsParams struct {
+	DatasourceUID   string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL           string `json:"logql" jsonschema:"required,description=The LogQL log query to tail\\, e.g. '{app=\"foo\"} |= \"error\"'. Metric queries are not supported"`
+	DurationSeconds int    `json:"durationSeconds,omitempty" jsonschema:"description=Optionally\\, how long to tail for in seconds (default: 30\\, max: 300)"`
+	Limit           int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the number of lines after which to stop tailing (default: 100\\, max: 1000)"`
+}
+
+// lokiTail is the result of tail_loki_logs. LimitReached is set when tailing
+// stopped early because the line limit was reached.
+type lokiTail struct {
+	Entries      []LogEntry `json:"entries"`
+	LimitReached bool       `json:"limitReached"`
+}
+
+// tailLokiLogs follows the lines matching a query as they are ingested,
+// sending each new batch of lines to the client as a logging notification.
+// Grafana's datasource proxy does not forward Loki's tail websocket, so new
+// lines are fetched by polling query_range. Lines are often ingested some time
+// after their timestamp, so each poll overlaps the previous one by
+// lokiTailLookback, skipping the lines it already returned.
+func tailLokiLogs(ctx context.Context, args TailLokiLogsParams) (*lokiTail, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	duration := args.DurationSeconds
+	if duration <= 0 {
+		duration = DefaultLokiTailSeconds
+	}
+	duration = min(duration, MaxLokiTailSeconds)
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiTailLimit
+	}
+	limit = min(limit, MaxLokiTailLimit)
+
+	tailCtx, cancel := context.WithTimeout(ctx, time.Duration(duration)*time.Second)
+	defer cancel()
+	ticker := time.NewTicker(lokiTailPollInterval)
+	defer ticker.Stop()
+
+	result := &lokiTail{Entries: []LogEntry{}}
+	start := time.Now()
+	to := start
+	// seen holds the timestamps of the lines returned so far which later
+	// polls may find again, keyed by timestamp and logEntryHash.
+	seen := map[string]int64{}
+	for {
+		select {
+		case <-tailCtx.Done():
+			return result, nil
+		case <-ticker.C:
+		}
+
+		from := to.Add(-lokiTailLookback)
+		if from.Before(start) {
+			from = start
+		}
+		for key, ts := range seen {
+			if ts < from.UnixNano() {
+				delete(seen, key)
+			}
+		}
+		to = time.Now()
+		// The lines found again count towards Loki's limit too.
+		streams, err := client.fetchLogs(tailCtx, args.LogQL, from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano), limit-len(result.Entries)+len(seen), "forward")
+		if err != nil {
+			if tailCtx.Err() != nil {
+				return result, nil
+			}
+			return nil, err
+		}
+
+		var entries []LogEntry
+		for _, e := range streamsToLogEntries(streams) {
+			key := e.Timestamp + "/" + logEntryHash(e)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key], _ = strconv.ParseInt(e.Timestamp, 10, 64)
+			entries = append(entries, e)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		sortLogEntries(entries)
+		entries = entries[:min(len(entries), limit-len(result.Entries))]
+		result.Entries = append(result.Entries, entries...)
+		sortLogEntries(result.Entries)
+		mcpgrafana.SendLogMessage(ctx, mcp.LoggingLevelInfo, "tail_loki_logs", entries)
+		if len(result.Entries) >= limit {
+			result.LimitReached = true
+			return result, nil
+		}
+	}
+}
+
+// TailLokiLogs is a tool for following Loki logs as they are ingested
+var TailLokiLogs = mcpgrafana.MustTool(
+	"tail_loki_logs",
+	"Follows the log lines matching a LogQL query in a Loki datasource as they are ingested, like `logcli --tail`. Blocks for up to `durationSeconds` (default 30, max 300) or until `limit` lines (default 100, max 1000) have arrived, and returns the new lines oldest first. Each batch of lines is also sent to the client as a `notifications/message` logging notification as it arrives. Use this to watch logs during an active incident or while reproducing an issue; use `query_loki_logs` for lines that were already ingested.",
+	tailLokiLogs,
+	mcp.WithTitleAnnotation("Tail Loki logs"),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// QueryLokiMetricsParams defines the parameters for LogQL metric queries
+type QueryLokiMetricsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL metric query to execute\\, e.g. 'sum by (level) (rate({app=\"foo\"} |= \"error\" [5m]))'. Supports range aggregations such as rate and count_over_time\\, unwrapped range aggregations such as 'avg_over_time({app=\"foo\"} | logfmt | unwrap latency [5m])' and vector aggregations over them"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 1 hour ago"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the step between samples in seconds. If omitted Loki picks a step based on the time range"`
+	EstimateOnly  bool   `json:"estimateOnly,omitempty" jsonschema:"description=Optionally\\, only return the index statistics (streams\\, chunks\\, entries and bytes) of the streams the query reads instead of running it. Use this to gauge the cost of a query before running it"`
+	Debug         bool   `json:"debug,omitempty" jsonschema:"description=Optionally\\, include the query model and the raw request and response exchanged with the datasource in the result\\, like Grafana's Query Inspector. Useful for debugging differences between tool results and the Grafana UI"`
+}
+
+// fetchMetrics runs a LogQL metric query against Loki's query_range API,
+// returning the resulting time series.
+func (c *Client) fetchMetrics(ctx context.Context, query, startRFC3339, endRFC3339 string, step time.Duration) (model.Matrix, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+	if step > 0 {
+		params.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	}
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var queryResponse struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &queryResponse); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if queryResponse.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+	if queryResponse.Data.ResultType != model.ValMatrix.String() {
+		return nil, fmt.Errorf("query returned %s rather than a matrix, use query_loki_logs for log queries", queryResponse.Data.ResultType)
+	}
+
+	var matrix model.Matrix
+	if err := json.Unmarshal(queryResponse.Data.Result, &matrix); err != nil {
+		return nil, fmt.Errorf("unmarshalling matrix: %w", err)
+	}
+	return matrix, nil
+}
+
+// queryLokiMetrics runs a LogQL metric query, returning a matrix of samples
+func queryLokiMetrics(ctx context.Context, args QueryLokiMetricsParams) (model.Matrix, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	step := time.Duration(args.StepSeconds) * time.Second
+	if step <= 0 {
+		step = toolDefaultsFor(ctx, defaultsCategoryLoki).Step
+	}
+
+	query := map[string]any{
+		"refId":      "A",
+		"datasource": datasourceInfo{UID: args.DatasourceUID, Type: "loki"},
+		"expr":       args.LogQL,
+		"queryType":  "range",
+		"from":       startTime,
+		"to":         endTime,
+	}
+	if step > 0 {
+		query["step"] = step.String()
+	}
+	queryInspectorFromContext(ctx).setQuery(query)
+
+	matrix, err := client.fetchMetrics(ctx, args.LogQL, startTime, endTime, step)
+	if err != nil {
+		return nil, err
+	}
+	if matrix == nil {
+		return model.Matrix{}, nil
+	}
+	return matrix, nil
+}
+
+// queryLokiMetricsResult is the result of the query_loki_metrics tool: a
+// matrix, or an estimate when estimateOnly is set.
+type queryLokiMetricsResult struct{ resultValue }
+
+func (queryLokiMetricsResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{
+		reflect.TypeFor[inspectable[[]prometheusSeriesSchema]](),
+		reflect.TypeFor[inspectable[*lokiQueryEstimate]](),
+	}
+}
+
+// queryLokiMetricsTool handles calls to the query_loki_metrics tool, adding
+// query inspection details to the result when debug is requested.
+func queryLokiMetricsTool(ctx context.Context, args QueryLokiMetricsParams) (queryLokiMetricsResult, error) {
+	if args.EstimateOnly {
+		return asResult[queryLokiMetricsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*lokiQueryEstimate, error) {
+			return estimateLokiQuery(ctx, args.DatasourceUID, args.LogQL, args.StartRFC3339, args.EndRFC3339)
+		}))
+	}
+	return asResult[queryLokiMetricsResult](withInspection(ctx, args.Debug, func(ctx context.Context) (model.Matrix, error) {
+		return queryLokiMetrics(ctx, args)
+	}))
+}
+
+// QueryLokiMetrics is a tool for running LogQL metric queries
+var QueryLokiMetrics = mcpgrafana.MustTool(
+	"query_loki_metrics",
+	"Executes a LogQL metric query against a Loki datasource over a time range and returns the resulting time series as a matrix, each series with its labels and [timestamp, value] samples. Use this to quantify logs, e.g. error rates with `sum(rate({app=\"foo\"} |= \"error\" [5m]))`, line counts with `count_over_time`, or latencies extracted with `unwrap`. Set `stepSeconds` to control the resolution and `estimateOnly` to only get the index statistics of the streams the query reads. Times may be RFC3339 or relative to now (e.g. `now-6h`) and default to the last hour. Use `query_loki_logs` to retrieve the log lines themselves.",
+	guardTimeRange(queryLokiMetricsTool),
+	mcp.WithTitleAnnotation("Query Loki metrics"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// QueryLokiInstantParams defines the parameters for running a LogQL metric
+// query at a single point in time
+type QueryLokiInstantParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL metric query to evaluate\\, e.g. 'sum(rate({app=\"foo\"} |= \"error\" [5m]))' for the current error rate over the last 5 minutes"`
+	Time          string `json:"time,omitempty" jsonschema:"description=Optionally\\, the time to evaluate the query at in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to now"`
+	Debug         bool   `json:"debug,omitempty" jsonschema:"description=Optionally\\, include the query model and the raw request and response exchanged with the datasource in the result\\, like Grafana's Query Inspector. Useful for debugging differences between tool results and the Grafana UI"`
+}
+
+// fetchInstant evaluates a LogQL metric query at a single time using Loki's
+// query API. Scalar results are returned as a vector with a single sample
+// without labels.
+func (c *Client) fetchInstant(ctx context.Context, query string, ts time.Time) (model.Vector, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	params.Add("time", strconv.FormatInt(ts.UnixNano(), 10))
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/query", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var queryResponse struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &queryResponse); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if queryResponse.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+
+	switch queryResponse.Data.ResultType {
+	case model.ValVector.String():
+		var vector model.Vector
+		if err := json.Unmarshal(queryResponse.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("unmarshalling vector: %w", err)
+		}
+		return vector, nil
+	case model.ValScalar.String():
+		var scalar model.Scalar
+		if err := json.Unmarshal(queryResponse.Data.Result, &scalar); err != nil {
+			return nil, fmt.Errorf("unmarshalling scalar: %w", err)
+		}
+		return model.Vector{{Metric: model.Metric{}, Value: scalar.Value, Timestamp: scalar.Timestamp}}, nil
+	}
+	return nil, fmt.Errorf("query returned %s rather than a vector, use query_loki_logs for log queries", queryResponse.Data.ResultType)
+}
+
+// queryLokiInstant evaluates a LogQL metric query at a single time, returning
+// one sample per series
+func queryLokiInstant(ctx context.Context, args QueryLokiInstantParams) (model.Vector, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	ts := time.Now()
+	if args.Time != "" {
+		if ts, err = parseTime(args.Time); err != nil {
+			return nil, fmt.Errorf("parsing time: %w", err)
+		}
+	}
+
+	queryInspectorFromContext(ctx).setQuery(map[string]any{
+		"refId":      "A",
+		"datasource": datasourceInfo{UID: args.DatasourceUID, Type: "loki"},
+		"expr":       args.LogQL,
+		"queryType":  "instant",
+		"to":         ts.Format(time.RFC3339Nano),
+	})
+
+	vector, err := client.fetchInstant(ctx, args.LogQL, ts)
+	if err != nil {
+		return nil, err
+	}
+	if vector == nil {
+		return model.Vector{}, nil
+	}
+	return vector, nil
+}
+
+// queryLokiInstantResult is the result of the query_loki_instant tool.
+type queryLokiInstantResult struct{ resultValue }
+
+func (queryLokiInstantResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[inspectable[[]prometheusSampleSchema]]()}
+}
+
+// queryLokiInstantTool handles calls to the query_loki_instant tool, adding
+// query inspection details to the result when debug is requested.
+func queryLokiInstantTool(ctx context.Context, args QueryLokiInstantParams) (queryLokiInstantResult, error) {
+	return asResult[queryLokiInstantResult](withInspection(ctx, args.Debug, func(ctx context.Context) (model.Vector, error) {
+		return queryLokiInstant(ctx, args)
+	}))
+}
+
+// QueryLokiInstant is a tool for evaluating LogQL metric queries at a single
+// point in time
+var QueryLokiInstant = mcpgrafana.MustTool(
+	"query_loki_instant",
+	"Evaluates a LogQL metric query against a Loki datasource at a single point in time and returns one sample per series, each with its labels, timestamp and value. Use this to get a current aggregate value, such as the error rate over the last 5 minutes with `sum(rate({app=\"foo\"} |= \"error\" [5m]))` or the number of lines in the last hour with `sum(count_over_time({app=\"foo\"}[1h]))`, without the larger payload of `query_loki_metrics`. The time may be RFC3339 or relative to now and defaults to now.",
+	queryLokiInstantTool,
+	mcp.WithTitleAnnotation("Query Loki instant"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 // fetchStats is a method to fetch stats data from Loki API
 func (c *Client) fetchStats(ctx context.Context, query, startRFC3339, endRFC3339 string) (*Stats, error) {
 	params := url.Values{}
@@ -509,46 +1264,502 @@ func (c *Client) fetchStats(ctx context.Context, query, startRFC3339, endRFC3339
 	return &stats, nil
 }
 
+// VolumeEntry is the volume of logs ingested for a set of label values, as
+// returned by Loki's index/volume endpoint
+type VolumeEntry struct {
+	Labels map[string]string `json:"labels"`
+	Bytes  int64             `json:"bytes"`
+}
+
+// fetchVolume is a method to fetch the volume of the streams matching a
+// selector from Loki API, broken down by the target labels if given
+func (c *Client) fetchVolume(ctx context.Context, query, startRFC3339, endRFC3339 string, targetLabels []string, limit int) ([]VolumeEntry, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+	if len(targetLabels) > 0 {
+		params.Add("targetLabels", strings.Join(targetLabels, ","))
+		params.Add("aggregateBy", "labels")
+	}
+	if limit > 0 {
+		params.Add("limit", strconv.Itoa(limit))
+	}
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/index/volume", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumeResponse struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result model.Vector `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &volumeResponse); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if volumeResponse.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+
+	entries := make([]VolumeEntry, 0, len(volumeResponse.Data.Result))
+	for _, sample := range volumeResponse.Data.Result {
+		labels := make(map[string]string, len(sample.Metric))
+		for k, v := range sample.Metric {
+			labels[string(k)] = string(v)
+		}
+		entries = append(entries, VolumeEntry{Labels: labels, Bytes: int64(sample.Value)})
+	}
+	return entries, nil
+}
+
+// lokiStreamSelectors returns the distinct stream selectors in a LogQL query,
+// such as `{app="foo"}` in `rate({app="foo"} |= "error" [5m])`, ignoring
+// braces inside strings.
+func lokiStreamSelectors(logql string) []string {
+	var selectors []string
+	seen := map[string]bool{}
+	for _, span := range lokiStreamSelectorSpans(logql) {
+		selector := logql[span[0]:span[1]]
+		if !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
+
+// lokiStreamSelectorSpans returns the start and end offsets of each stream
+// selector in a LogQL query, in order.
+func lokiStreamSelectorSpans(logql string) [][2]int {
+	var spans [][2]int
+	var quote rune
+	start := -1
+	escaped := false
+	for i, r := range logql {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+		case r == '{' && start < 0:
+			start = i
+		case r == '}' && start >= 0:
+			spans = append(spans, [2]int{start, i + 1})
+			start = -1
+		}
+	}
+	return spans
+}
+
+var lokiMetadataNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// withLokiMetadataFilters adds a label filter for each matcher directly after
+// every stream selector of a LogQL query. Filters there apply to the stream
+// labels and structured metadata, which is where Loki stores OTLP attributes
+// such as trace_id and severity_text, before any parser adds labels. Dots and
+// other characters invalid in label names are replaced with underscores, as
+// Loki does for OTLP attribute names, so 'service.name' matches
+// 'service_name'.
+func withLokiMetadataFilters(logql string, matchers []LabelMatcher) (string, error) {
+	if len(matchers) == 0 {
+		return logql, nil
+	}
+	var filters strings.Builder
+	for _, m := range matchers {
+		name := strings.Map(func(r rune) rune {
+			if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, strings.TrimSpace(m.Name))
+		if !lokiMetadataNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid metadata name %q", m.Name)
+		}
+		op := stringOrDefault(m.Type, "=")
+		if _, ok := matchTypeMap[op]; !ok {
+			return "", fmt.Errorf("invalid matcher type %q for metadata %q: must be one of =, !=, =~ or !~", m.Type, m.Name)
+		}
+		fmt.Fprintf(&filters, " | %s%s%s", name, op, strconv.Quote(m.Value))
+	}
+
+	spans := lokiStreamSelectorSpans(logql)
+	if len(spans) == 0 {
+		return "", fmt.Errorf("metadata filters need a LogQL query with a stream selector")
+	}
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(logql[last:span[1]])
+		b.WriteString(filters.String())
+		last = span[1]
+	}
+	b.WriteString(logql[last:])
+	return b.String(), nil
+}
+
+// lokiQueryEstimate is the index statistics of the streams read by a LogQL
+// query, returned instead of the query's results in estimateOnly mode.
+type lokiQueryEstimate struct {
+	Start     string                 `json:"start"`
+	End       string                 `json:"end"`
+	Total     Stats                  `json:"total"`
+	Selectors []lokiSelectorEstimate `json:"selectors"`
+}
+
+type lokiSelectorEstimate struct {
+	Selector string `json:"selector"`
+	Stats
+}
+
+// estimateLokiQuery fetches the index statistics of each stream selector in a
+// LogQL query over its time range.
+func estimateLokiQuery(ctx context.Context, uid, logql, startStr, endStr string) (*lokiQueryEstimate, error) {
+	client, err := newLokiClient(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	startTime, endTime, err := lokiTimeRange(ctx, startStr, endStr)
+	if err != nil {
+		return nil, err
+	}
+	selectors := lokiStreamSelectors(logql)
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no stream selector found in LogQL query %q", logql)
+	}
+
+	estimate := &lokiQueryEstimate{Start: startTime, End: endTime, Selectors: []lokiSelectorEstimate{}}
+	for _, selector := range selectors {
+		stats, err := client.fetchStats(ctx, selector, startTime, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("fetching stats for %s: %w", selector, err)
+		}
+		estimate.Total.Streams += stats.Streams
+		estimate.Total.Chunks += stats.Chunks
+		estimate.Total.Entries += stats.Entries
+		estimate.Total.Bytes += stats.Bytes
+		estimate.Selectors = append(estimate.Selectors, lokiSelectorEstimate{Selector: selector, Stats: *stats})
+	}
+	return estimate, nil
+}
+
+// StatsResult is the result of the query_loki_stats tool
+type StatsResult struct {
+	Stats
+	Volume []VolumeEntry `json:"volume,omitempty"`
+}
+
 // QueryLokiStatsParams defines the parameters for querying Loki stats
 type QueryLokiStatsParams struct {
-	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL matcher expression to execute. This parameter only accepts label matcher expressions and does not support full LogQL queries. Line filters\\, pattern operations\\, and metric aggregations are not supported by the stats API endpoint. Only simple label selectors can be used here."`
-	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format"`
-	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format"`
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string   `json:"logql" jsonschema:"required,description=The LogQL matcher expression to execute. This parameter only accepts label matcher expressions and does not support full LogQL queries. Line filters\\, pattern operations\\, and metric aggregations are not supported by the stats API endpoint. Only simple label selectors can be used here."`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-24h'). Defaults to 1 hour ago"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Volume        bool     `json:"volume,omitempty" jsonschema:"description=Optionally\\, also return the volume of logs ingested for the matching streams from Loki's index/volume endpoint"`
+	VolumeBy      []string `json:"volumeBy,omitempty" jsonschema:"description=Optionally\\, the labels to break the volume down by (e.g. ['service_name']). Implies volume"`
+	VolumeLimit   int      `json:"volumeLimit,omitempty" jsonschema:"description=Optionally\\, the maximum number of volume entries to return\\, largest first (default: 10)"`
 }
 
-// queryLokiStats queries stats from a Loki datasource using LogQL
-func queryLokiStats(ctx context.Context, args QueryLokiStatsParams) (*Stats, error) {
+// DefaultLokiVolumeLimit is the default number of volume entries returned by
+// query_loki_stats
+const DefaultLokiVolumeLimit = 10
+
+// queryLokiStats queries stats, and optionally volume, from a Loki datasource
+// using LogQL
+func queryLokiStats(ctx context.Context, args QueryLokiStatsParams) (*StatsResult, error) {
 	client, err := newLokiClient(ctx, args.DatasourceUID)
 	if err != nil {
 		return nil, fmt.Errorf("creating Loki client: %w", err)
 	}
 
-	// Get default time range if not provided
-	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
 
 	stats, err := client.fetchStats(ctx, args.LogQL, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
+	result := &StatsResult{Stats: *stats}
 
-	return stats, nil
+	if args.Volume || len(args.VolumeBy) > 0 {
+		limit := args.VolumeLimit
+		if limit <= 0 {
+			limit = DefaultLokiVolumeLimit
+		}
+		result.Volume, err = client.fetchVolume(ctx, args.LogQL, startTime, endTime, args.VolumeBy, limit)
+		if err != nil {
+			return nil, fmt.Errorf("fetching volume: %w", err)
+		}
+	}
+
+	return result, nil
 }
 
 // QueryLokiStats is a tool for querying stats from Loki
 var QueryLokiStats = mcpgrafana.MustTool(
 	"query_loki_stats",
-	"Retrieves statistics about log streams matching a given LogQL *selector* within a Loki datasource and time range. Returns an object containing the count of streams, chunks, entries, and total bytes (e.g., `{\"streams\": 5, \"chunks\": 50, \"entries\": 10000, \"bytes\": 512000}`). The `logql` parameter **must** be a simple label selector (e.g., `{app=\"nginx\", env=\"prod\"}`) and does not support line filters, parsers, or aggregations. Defaults to the last hour if the time range is omitted.",
-	queryLokiStats,
+	"Retrieves statistics about log streams matching a given LogQL *selector* within a Loki datasource and time range. Returns an object containing the count of streams, chunks, entries, and total bytes (e.g., `{\"streams\": 5, \"chunks\": 50, \"entries\": 10000, \"bytes\": 512000}`). The `logql` parameter **must** be a simple label selector (e.g., `{app=\"nginx\", env=\"prod\"}`) and does not support line filters, parsers, or aggregations. Set `volume` to also return the bytes ingested for the matching streams, or `volumeBy` to break that down by labels (e.g. `[\"service_name\"]`), largest first. Use this to estimate how much data a selector will touch and pick a sensible time range before running expensive queries. Times may be RFC3339 or relative to now and default to the last hour.",
+	guardTimeRange(queryLokiStats),
 	mcp.WithTitleAnnotation("Get Loki log statistics"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// DefaultLokiPatternLimit is the default number of patterns returned by
+// query_loki_patterns
+const DefaultLokiPatternLimit = 20
+
+// lokiPatternsResponse is the response of Loki's patterns endpoint. Samples
+// are [unix seconds, count] pairs.
+type lokiPatternsResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Pattern string     `json:"pattern"`
+		Level   string     `json:"level,omitempty"`
+		Samples [][2]int64 `json:"samples"`
+	} `json:"data"`
+}
+
+// LokiPattern is a log line template detected by Loki, with the number of
+// lines matching it in the time range
+type LokiPattern struct {
+	Pattern string `json:"pattern"`
+	Level   string `json:"level,omitempty"`
+	Count   int64  `json:"count"`
+}
+
+// lokiPatterns is the result of query_loki_patterns
+type lokiPatterns struct {
+	Patterns      []LokiPattern `json:"patterns"`
+	TotalPatterns int           `json:"totalPatterns"`
+}
+
+// QueryLokiPatternsParams defines the parameters for querying detected log
+// patterns
+type QueryLokiPatternsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Selector      string `json:"selector" jsonschema:"required,description=The LogQL stream selector to detect patterns for (e.g. '{service_name=\"checkout\"}'). Line filters and parsers are not supported"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-3h'). Defaults to 1 hour ago"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of patterns to return\\, most frequent first (default: 20\\, max: 100)"`
+}
+
+// fetchPatterns fetches the patterns Loki detected in the streams matching a
+// selector
+func (c *Client) fetchPatterns(ctx context.Context, selector, startRFC3339, endRFC3339 string) (*lokiPatternsResponse, error) {
+	params := url.Values{}
+	params.Add("query", selector)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/patterns", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response lokiPatternsResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+	return &response, nil
+}
+
+// queryLokiPatterns returns the log patterns detected by Loki for a selector,
+// most frequent first
+func queryLokiPatterns(ctx context.Context, args QueryLokiPatternsParams) (*lokiPatterns, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.fetchPatterns(ctx, args.Selector, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]LokiPattern, 0, len(response.Data))
+	for _, p := range response.Data {
+		pattern := LokiPattern{Pattern: p.Pattern, Level: p.Level}
+		for _, sample := range p.Samples {
+			pattern.Count += sample[1]
+		}
+		patterns = append(patterns, pattern)
+	}
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return patterns[i].Count > patterns[j].Count
+	})
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiPatternLimit
+	}
+	limit = min(limit, MaxLokiLogLimit)
+	result := &lokiPatterns{Patterns: patterns, TotalPatterns: len(patterns)}
+	if len(result.Patterns) > limit {
+		result.Patterns = result.Patterns[:limit]
+	}
+	return result, nil
+}
+
+// QueryLokiPatterns is a tool for querying the log patterns detected by Loki
+var QueryLokiPatterns = mcpgrafana.MustTool(
+	"query_loki_patterns",
+	"Returns the log line templates (patterns) Loki's pattern ingester detected in the streams matching a LogQL stream selector, with the number of lines matching each in the time range, most frequent first. Variable parts of lines are shown as `<_>`, e.g. `<_> level=error msg=\"failed to connect\" host=<_>`. Use this to see what a service is logging, and how often, without reading thousands of raw lines, then query the interesting patterns with `query_loki_logs`. Requires the pattern ingester to be enabled in Loki. Times may be RFC3339 or relative to now and default to the last hour.",
+	guardTimeRange(queryLokiPatterns),
+	mcp.WithTitleAnnotation("Query Loki log patterns"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// DefaultLokiSeriesLimit is the default number of series returned by
+// list_loki_series
+const DefaultLokiSeriesLimit = 100
+
+// MaxLokiSeriesLimit is the maximum number of series list_loki_series returns
+const MaxLokiSeriesLimit = 1000
+
+// lokiSeriesResponse is the response of Loki's series endpoint
+type lokiSeriesResponse struct {
+	Status string              `json:"status"`
+	Data   []map[string]string `json:"data"`
+}
+
+// lokiSeries is the result of list_loki_series
+type lokiSeries struct {
+	Series      []map[string]string `json:"series"`
+	TotalSeries int                 `json:"totalSeries"`
+}
+
+// ListLokiSeriesParams defines the parameters for listing Loki series
+type ListLokiSeriesParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Match         []string `json:"match" jsonschema:"required,description=One or more LogQL stream selectors (e.g. '{app=\"nginx\"}'). Series matching any of them are returned"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-3h'). Defaults to 1 hour ago"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return (default: 100\\, max: 1000)"`
+}
+
+// fetchSeries fetches the label sets of the streams matching any of the
+// selectors
+func (c *Client) fetchSeries(ctx context.Context, match []string, startRFC3339, endRFC3339 string) ([]map[string]string, error) {
+	params := url.Values{}
+	for _, m := range match {
+		params.Add("match[]", m)
+	}
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/series", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response lokiSeriesResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+	return response.Data, nil
+}
+
+// listLokiSeries lists the label sets of the streams matching any of the
+// given selectors, sorted by their labels
+func listLokiSeries(ctx context.Context, args ListLokiSeriesParams) (*lokiSeries, error) {
+	if len(args.Match) == 0 {
+		return nil, fmt.Errorf("at least one match selector is required")
+	}
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := client.fetchSeries(ctx, args.Match, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		series = []map[string]string{}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return toLabelSet(series[i]).String() < toLabelSet(series[j]).String()
+	})
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiSeriesLimit
+	}
+	limit = min(limit, MaxLokiSeriesLimit)
+	result := &lokiSeries{Series: series, TotalSeries: len(series)}
+	if len(result.Series) > limit {
+		result.Series = result.Series[:limit]
+	}
+	return result, nil
+}
+
+// toLabelSet converts a map of labels to a model.LabelSet
+func toLabelSet(labels map[string]string) model.LabelSet {
+	set := make(model.LabelSet, len(labels))
+	for k, v := range labels {
+		set[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return set
+}
+
+// ListLokiSeries is a tool for listing the streams in Loki
+var ListLokiSeries = mcpgrafana.MustTool(
+	"list_loki_series",
+	"Lists the streams (unique label sets) in a Loki datasource matching one or more LogQL stream selectors within a time range, e.g. `[{\"app\": \"nginx\", \"env\": \"prod\", \"pod\": \"nginx-1\"}]`. Returns the series sorted by their labels and the total number found. Use this to see which label combinations actually exist rather than guessing from `list_loki_label_names` and `list_loki_label_values`. Times may be RFC3339 or relative to now and default to the last hour.",
+	guardTimeRange(listLokiSeries),
+	mcp.WithTitleAnnotation("List Loki series"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 // AddLokiTools registers all Loki tools with the MCP server
 func AddLokiTools(mcp *server.MCPServer) {
 	ListLokiLabelNames.Register(mcp)
 	ListLokiLabelValues.Register(mcp)
 	QueryLokiStats.Register(mcp)
 	QueryLokiLogs.Register(mcp)
+	QueryLokiMetrics.Register(mcp)
+	QueryLokiInstant.Register(mcp)
+	GetLokiLogContext.Register(mcp)
+	TailLokiLogs.Register(mcp)
+	QueryLokiPatterns.Register(mcp)
+	ListLokiSeries.Register(mcp)
+	ListLokiRules.Register(mcp)
+	FindTracesForLogs.Register(mcp)
+	SummarizeLokiErrors.Register(mcp)
+	GetLokiStatus.Register(mcp)
+	DetectLokiAnomalies.Register(mcp)
+	ListLokiDeleteRequests.Register(mcp)
+	addExportResources(mcp)
 }
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// DefaultLokiAnomalyZScore is the default number of baseline standard
+	// deviations the target volume must differ by to be anomalous.
+	DefaultLokiAnomalyZScore = 3.0
+	// DefaultLokiAnomalyRatio is the default factor the target volume must
+	// exceed the baseline by, or fall below it by, to be anomalous.
+	DefaultLokiAnomalyRatio = 2.0
+	// DefaultLokiAnomalyBaseline is the default length of the baseline
+	// window before the target window.
+	DefaultLokiAnomalyBaseline = 24 * time.Hour
+	// DefaultLokiAnomalyLimit is the default number of anomalies returned.
+	DefaultLokiAnomalyLimit = 20
+
+	// maxLokiAnomalySteps is the most steps the baseline and target windows
+	// are divided into. The step is widened for longer windows.
+	maxLokiAnomalySteps = 1000
+)
+
+type DetectLokiAnomaliesParams struct {
+	DatasourceUID        string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource to query"`
+	Selector             string   `json:"selector" jsonschema:"required,description=The LogQL stream selector of the logs to analyze\\, optionally followed by line filters\\, e.g. '{namespace=\"shop\"}' or '{app=\"checkout\"} |= \"error\"'"`
+	GroupBy              []string `json:"groupBy,omitempty" jsonschema:"description=Optionally\\, the labels to aggregate volumes by\\, e.g. ['service_name']. Defaults to each stream separately"`
+	StartRFC3339         string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start of the target window to check for anomalies\\, in RFC3339 format or relative to now (e.g. 'now-15m'). Defaults to 1 hour ago"`
+	EndRFC3339           string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end of the target window in RFC3339 format or relative to now. Defaults to now"`
+	BaselineStartRFC3339 string   `json:"baselineStartRfc3339,omitempty" jsonschema:"description=Optionally\\, the start of the baseline window\\, which ends where the target window starts\\, in RFC3339 format or relative to now. Defaults to 24 hours before the target window"`
+	StepSeconds          int      `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the length of the intervals lines are counted in. Defaults to a tenth of the target window and at least a minute"`
+	ZScoreThreshold      float64  `json:"zScoreThreshold,omitempty" jsonschema:"description=Optionally\\, how many baseline standard deviations the target volume must differ by to be anomalous (default: 3)"`
+	RatioThreshold       float64  `json:"ratioThreshold,omitempty" jsonschema:"description=Optionally\\, the factor the target volume must exceed or fall below the baseline by to be anomalous (default: 2)"`
+	Limit                int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of anomalies to return\\, most significant first (default: 20)"`
+}
+
+// lokiVolumeAnomaly is a stream, or group of streams, whose log volume in the
+// target window differs abnormally from the baseline.
+type lokiVolumeAnomaly struct {
+	Labels map[string]string `json:"labels"`
+	// Kind is "spike" or "drop".
+	Kind string `json:"kind"`
+	// BaselineLines and TargetLines are the mean number of lines per step.
+	BaselineLines float64 `json:"baselineLines"`
+	TargetLines   float64 `json:"targetLines"`
+	// Ratio is TargetLines divided by BaselineLines, omitted for streams
+	// without lines in the baseline.
+	Ratio  *float64 `json:"ratio,omitempty"`
+	ZScore float64  `json:"zScore"`
+}
+
+// lokiAnomalies is the result of detect_loki_anomalies.
+type lokiAnomalies struct {
+	BaselineStart string `json:"baselineStart"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Step          string `json:"step"`
+	Query         string `json:"query"`
+	// Streams is the number of streams, or groups, analyzed.
+	Streams   int                 `json:"streams"`
+	Anomalies []lokiVolumeAnomaly `json:"anomalies"`
+}
+
+// volumeAnomaly compares the counts of a series in the baseline steps to the
+// mean of its counts in the target steps. The standard deviation is at least
+// the square root of the baseline mean, as for a Poisson process, and at
+// least one line, so flat or sparse baselines don't make any change
+// significant. It returns nil if the change is within the thresholds.
+func volumeAnomaly(baseline, target []float64, zThreshold, ratioThreshold float64) *lokiVolumeAnomaly {
+	mean, variance := meanAndVariance(baseline)
+	targetMean, _ := meanAndVariance(target)
+	sd := math.Max(math.Sqrt(variance), math.Max(math.Sqrt(mean), 1))
+	a := &lokiVolumeAnomaly{
+		BaselineLines: math.Round(mean*100) / 100,
+		TargetLines:   math.Round(targetMean*100) / 100,
+		ZScore:        math.Round((targetMean-mean)/sd*100) / 100,
+	}
+	if mean > 0 {
+		ratio := math.Round(targetMean/mean*100) / 100
+		a.Ratio = &ratio
+	}
+	switch {
+	case a.ZScore >= zThreshold && (mean == 0 || targetMean/mean >= ratioThreshold):
+		a.Kind = "spike"
+	case a.ZScore <= -zThreshold && targetMean/mean <= 1/ratioThreshold:
+		a.Kind = "drop"
+	default:
+		return nil
+	}
+	return a
+}
+
+func detectLokiAnomalies(ctx context.Context, args DetectLokiAnomaliesParams) (*lokiAnomalies, error) {
+	if !strings.HasPrefix(strings.TrimSpace(args.Selector), "{") {
+		return nil, fmt.Errorf("selector must be a LogQL stream selector, optionally followed by line filters")
+	}
+	for _, label := range args.GroupBy {
+		if !model.LabelName(label).IsValidLegacy() {
+			return nil, fmt.Errorf("invalid label name %q", label)
+		}
+	}
+	zThreshold := args.ZScoreThreshold
+	if zThreshold <= 0 {
+		zThreshold = DefaultLokiAnomalyZScore
+	}
+	ratioThreshold := args.RatioThreshold
+	if ratioThreshold <= 1 {
+		ratioThreshold = DefaultLokiAnomalyRatio
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiAnomalyLimit
+	}
+
+	startStr, endStr, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	baselineStart := start.Add(-DefaultLokiAnomalyBaseline)
+	if args.BaselineStartRFC3339 != "" {
+		if baselineStart, err = parseTime(args.BaselineStartRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing baseline start time: %w", err)
+		}
+	}
+	if baselineStart, _, err = clampTimeRange(ctx, defaultsCategoryLoki, baselineStart, end); err != nil {
+		return nil, err
+	}
+
+	step := time.Duration(args.StepSeconds) * time.Second
+	if step <= 0 {
+		step = max(end.Sub(start)/10, time.Minute).Truncate(time.Second)
+	}
+	step = max(step, (end.Sub(baselineStart)/maxLokiAnomalySteps).Truncate(time.Second)+time.Second)
+	baselineSteps := int(start.Sub(baselineStart) / step)
+	targetSteps := int(end.Sub(start) / step)
+	if baselineSteps < 2 {
+		return nil, fmt.Errorf("the baseline window must span at least 2 steps of %s, it starts at %s", step, baselineStart.Format(time.RFC3339))
+	}
+	if targetSteps < 1 {
+		return nil, fmt.Errorf("the target window must span at least a step of %s", step)
+	}
+	// Align the baseline so that the target window starts on a step. The
+	// count at each step is of the lines in the step before it, so the first
+	// count is a step after the baseline starts.
+	baselineStart = start.Add(-time.Duration(baselineSteps) * step)
+
+	query := fmt.Sprintf("count_over_time(%s [%s])", strings.TrimSpace(args.Selector), model.Duration(step))
+	if len(args.GroupBy) > 0 {
+		query = fmt.Sprintf("sum by (%s) (%s)", strings.Join(args.GroupBy, ", "), query)
+	}
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	matrix, err := client.fetchMetrics(ctx, query, baselineStart.Add(step).Format(time.RFC3339), end.Format(time.RFC3339), step)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &lokiAnomalies{
+		BaselineStart: baselineStart.Format(time.RFC3339),
+		Start:         start.Format(time.RFC3339),
+		End:           end.Format(time.RFC3339),
+		Step:          step.String(),
+		Query:         query,
+		Streams:       len(matrix),
+		Anomalies:     []lokiVolumeAnomaly{},
+	}
+	for _, series := range matrix {
+		// Steps without lines have no sample, so they count as zero.
+		counts := make([]float64, baselineSteps+targetSteps)
+		for _, sample := range series.Values {
+			i := int(math.Round(float64(sample.Timestamp.Time().Sub(baselineStart))/float64(step))) - 1
+			if i >= 0 && i < len(counts) {
+				counts[i] = float64(sample.Value)
+			}
+		}
+		a := volumeAnomaly(counts[:baselineSteps], counts[baselineSteps:], zThreshold, ratioThreshold)
+		if a == nil {
+			continue
+		}
+		a.Labels = make(map[string]string, len(series.Metric))
+		for k, v := range series.Metric {
+			a.Labels[string(k)] = string(v)
+		}
+		result.Anomalies = append(result.Anomalies, *a)
+	}
+	sort.SliceStable(result.Anomalies, func(i, j int) bool {
+		return math.Abs(result.Anomalies[i].ZScore) > math.Abs(result.Anomalies[j].ZScore)
+	})
+	if len(result.Anomalies) > limit {
+		result.Anomalies = result.Anomalies[:limit]
+	}
+	return result, nil
+}
+
+var DetectLokiAnomalies = mcpgrafana.MustTool(
+	"detect_loki_anomalies",
+	"Detects abnormal spikes and drops in log volume. Counts the lines of each stream matching a LogQL selector, or of each group of streams with `groupBy`, per step over a baseline window and a target window, and returns the streams whose mean volume in the target window differs from the baseline by at least `zScoreThreshold` standard deviations (default 3) and by a factor of at least `ratioThreshold` (default 2), most significant first. The target window defaults to the last hour and the baseline to the 24 hours before it. Use this to triage what changed during an incident, then look at the anomalous streams with `query_loki_logs` or `query_loki_patterns`.",
+	guardTimeRange(detectLokiAnomalies),
+	mcp.WithTitleAnnotation("Detect Loki log volume anomalies"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,152 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeAnomaly(t *testing.T) {
+	baseline := []float64{90, 110, 100, 95, 105, 100}
+	assert.Nil(t, volumeAnomaly(baseline, []float64{120, 110}, 3, 2), "within the noise")
+
+	spike := volumeAnomaly(baseline, []float64{400, 420}, 3, 2)
+	require.NotNil(t, spike)
+	assert.Equal(t, "spike", spike.Kind)
+	assert.Equal(t, 4.1, *spike.Ratio)
+
+	// Significant, but less than the ratio threshold.
+	assert.Nil(t, volumeAnomaly(baseline, []float64{150, 150}, 3, 2))
+
+	drop := volumeAnomaly(baseline, []float64{0, 10}, 3, 2)
+	require.NotNil(t, drop)
+	assert.Equal(t, "drop", drop.Kind)
+
+	appeared := volumeAnomaly([]float64{0, 0, 0}, []float64{50}, 3, 2)
+	require.NotNil(t, appeared)
+	assert.Equal(t, "spike", appeared.Kind)
+	assert.Nil(t, appeared.Ratio)
+
+	// Sparse streams are not anomalous for a few extra lines.
+	assert.Nil(t, volumeAnomaly([]float64{0, 1, 0, 0}, []float64{2}, 3, 2))
+}
+
+func TestDetectLokiAnomalies(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var query string
+	var queryStart, step int64
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		query = r.URL.Query().Get("query")
+		queryStart, _ = strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		stepSeconds, _ := strconv.ParseFloat(r.URL.Query().Get("step"), 64)
+		step = int64(stepSeconds * 1e9)
+
+		series := map[string]func(ts time.Time, i int) float64{
+			"checkout": func(ts time.Time, i int) float64 {
+				if !ts.After(start) {
+					return float64(100 + i%3*5)
+				}
+				return 400
+			},
+			"payments": func(ts time.Time, i int) float64 {
+				if !ts.After(start) {
+					return float64(200 - i%2*10)
+				}
+				return 0
+			},
+			"cart": func(ts time.Time, i int) float64 { return float64(50 + i%4) },
+			"search": func(ts time.Time, i int) float64 {
+				if !ts.After(start) {
+					return 0
+				}
+				return 30
+			},
+		}
+		var result []map[string]any
+		for app, value := range series {
+			values := [][]any{}
+			i := 0
+			for ts := queryStart; ts <= end; ts += step {
+				// Loki omits the steps without lines.
+				if v := value(time.Unix(0, ts), i); v > 0 {
+					values = append(values, []any{float64(ts) / 1e9, strconv.FormatFloat(v, 'f', -1, 64)})
+				}
+				i++
+			}
+			result = append(result, map[string]any{"metric": map[string]string{"app": app}, "values": values})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]any{"resultType": "matrix", "result": result},
+		}))
+	})
+
+	result, err := detectLokiAnomalies(ctx, DetectLokiAnomaliesParams{
+		DatasourceUID: "loki",
+		Selector:      `{namespace="shop"}`,
+		GroupBy:       []string{"app"},
+		StartRFC3339:  "2024-01-02T00:00:00Z",
+		EndRFC3339:    "2024-01-02T01:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `sum by (app) (count_over_time({namespace="shop"} [6m]))`, query)
+	assert.Equal(t, int64(6*time.Minute), step)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 6, 0, 0, time.UTC).UnixNano(), queryStart)
+	assert.Equal(t, "2024-01-01T00:00:00Z", result.BaselineStart)
+	assert.Equal(t, 4, result.Streams)
+
+	kinds := map[string]string{}
+	for _, a := range result.Anomalies {
+		kinds[a.Labels["app"]] = a.Kind
+	}
+	assert.Equal(t, map[string]string{"checkout": "spike", "payments": "drop", "search": "spike"}, kinds)
+	for i := 1; i < len(result.Anomalies); i++ {
+		assert.GreaterOrEqual(t, math.Abs(result.Anomalies[i-1].ZScore), math.Abs(result.Anomalies[i].ZScore))
+	}
+	for _, a := range result.Anomalies {
+		if a.Labels["app"] == "payments" {
+			assert.Equal(t, 0.0, a.TargetLines)
+			assert.Equal(t, 195.0, a.BaselineLines)
+		}
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		result, err := detectLokiAnomalies(ctx, DetectLokiAnomaliesParams{
+			DatasourceUID: "loki",
+			Selector:      `{namespace="shop"}`,
+			GroupBy:       []string{"app"},
+			StartRFC3339:  "2024-01-02T00:00:00Z",
+			EndRFC3339:    "2024-01-02T01:00:00Z",
+			Limit:         1,
+		})
+		require.NoError(t, err)
+		assert.Len(t, result.Anomalies, 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := detectLokiAnomalies(ctx, DetectLokiAnomaliesParams{DatasourceUID: "loki", Selector: `rate({app="foo"}[5m])`})
+		assert.ErrorContains(t, err, "stream selector")
+		_, err = detectLokiAnomalies(ctx, DetectLokiAnomaliesParams{DatasourceUID: "loki", Selector: `{app="foo"}`, GroupBy: []string{"service.name"}})
+		assert.ErrorContains(t, err, "invalid label name")
+		_, err = detectLokiAnomalies(ctx, DetectLokiAnomaliesParams{
+			DatasourceUID:        "loki",
+			Selector:             `{app="foo"}`,
+			StartRFC3339:         "2024-01-02T00:00:00Z",
+			EndRFC3339:           "2024-01-02T01:00:00Z",
+			BaselineStartRFC3339: "2024-01-01T23:55:00Z",
+		})
+		assert.ErrorContains(t, err, "baseline window must span at least 2 steps")
+	})
+}
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// lokiCursor is the position in the results of a log query after which the
+// next page of lines continues. It carries the query and its resolved time
+// range, so that later pages are not shifted by times relative to now.
+type lokiCursor struct {
+	DatasourceUIDs []string `json:"d"`
+	LogQL          string   `json:"q"`
+	Direction      string   `json:"o"`
+	Start          string   `json:"s"`
+	End            string   `json:"e"`
+	Limit          int      `json:"l"`
+	// Timestamp is the Unix nanosecond timestamp of the last line returned.
+	Timestamp int64 `json:"t,omitempty"`
+	// Seen are the hashes of the lines returned with that timestamp, which
+	// the next page skips since its range includes the timestamp.
+	Seen []string `json:"h,omitempty"`
+}
+
+func (c lokiCursor) encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encoding cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeLokiCursor(s string) (lokiCursor, error) {
+	var c lokiCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || len(c.DatasourceUIDs) == 0 || c.LogQL == "" || c.Limit <= 0 {
+		return lokiCursor{}, fmt.Errorf("invalid cursor, pass the 'nextCursor' of a previous call unchanged")
+	}
+	return c, nil
+}
+
+// logEntryHash identifies a log line among the lines with the same
+// timestamp.
+func logEntryHash(e LogEntry) string {
+	h := fnv.New64a()
+	_, _ = fmt.Fprint(h, e.Datasource, "\x00", toLabelSet(e.Labels).String(), "\x00", e.Line)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// lokiLogPage is a page of log lines. NextCursor is omitted once no more lines
+// remain.
+type lokiLogPage struct {
+	Items      []LogEntry `json:"items"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// queryLokiLogsPage returns the first page of the lines matching a log query,
+// or the page continuing from a cursor. Each page queries Loki for the lines
+// from the timestamp of the last line of the previous page, skipping the lines
+// with that timestamp which were already returned, so pages neither overlap
+// nor miss lines.
+func queryLokiLogsPage(ctx context.Context, args QueryLokiLogsParams) (*lokiLogPage, error) {
+	var c lokiCursor
+	if args.Cursor != "" {
+		var err error
+		if c, err = decodeLokiCursor(args.Cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		start, end, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+		if err != nil {
+			return nil, err
+		}
+		uids := []string{args.DatasourceUID}
+		for _, uid := range args.DatasourceUIDs {
+			if !slices.Contains(uids, uid) {
+				uids = append(uids, uid)
+			}
+		}
+		c = lokiCursor{
+			DatasourceUIDs: uids,
+			LogQL:          args.LogQL,
+			Direction:      stringOrDefault(args.Direction, "backward"),
+			Start:          start,
+			End:            end,
+			Limit:          enforceLogLimit(ctx, args.Limit),
+		}
+	}
+
+	// Loki's start time is inclusive and its end time exclusive.
+	start, end := c.Start, c.End
+	if c.Timestamp != 0 {
+		if c.Direction == "forward" {
+			start = strconv.FormatInt(c.Timestamp, 10)
+		} else {
+			end = strconv.FormatInt(c.Timestamp+1, 10)
+		}
+	}
+	fetch := QueryLokiLogsParams{
+		DatasourceUID:  c.DatasourceUIDs[0],
+		DatasourceUIDs: c.DatasourceUIDs[1:],
+		LogQL:          c.LogQL,
+		StartRFC3339:   start,
+		EndRFC3339:     end,
+		Limit:          c.Limit + len(c.Seen),
+		Direction:      c.Direction,
+	}
+	entries, err := queryLokiLogs(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines from different streams are not interleaved by time, so order
+	// them in the query direction before choosing the page.
+	slices.SortStableFunc(entries, func(a, b LogEntry) int {
+		ta, _ := strconv.ParseInt(a.Timestamp, 10, 64)
+		tb, _ := strconv.ParseInt(b.Timestamp, 10, 64)
+		if c.Direction == "forward" {
+			return cmp.Compare(ta, tb)
+		}
+		return cmp.Compare(tb, ta)
+	})
+
+	more := len(entries) >= lokiLogLimit(ctx, fetch)
+	page := &lokiLogPage{Items: []LogEntry{}}
+	for _, e := range entries {
+		if e.Value != nil {
+			return nil, fmt.Errorf("cursors are only supported for log queries, not metric queries")
+		}
+		ts, _ := strconv.ParseInt(e.Timestamp, 10, 64)
+		if ts == c.Timestamp && slices.Contains(c.Seen, logEntryHash(e)) {
+			continue
+		}
+		if len(page.Items) == c.Limit {
+			more = true
+			break
+		}
+		page.Items = append(page.Items, e)
+	}
+	if !more {
+		return page, nil
+	}
+
+	next := c
+	if len(page.Items) == 0 {
+		// Every line fetched has the cursor's timestamp and was returned
+		// before. Move past the timestamp to make progress.
+		next.Seen = nil
+		if c.Direction == "forward" {
+			next.Timestamp++
+		} else {
+			next.Timestamp--
+		}
+	} else {
+		last, _ := strconv.ParseInt(page.Items[len(page.Items)-1].Timestamp, 10, 64)
+		if last != c.Timestamp {
+			next.Timestamp, next.Seen = last, nil
+		}
+		for _, e := range page.Items {
+			if e.Timestamp == page.Items[len(page.Items)-1].Timestamp {
+				next.Seen = append(next.Seen, logEntryHash(e))
+			}
+		}
+	}
+	if page.NextCursor, err = next.encode(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,202 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLokiLogsCursor(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	lines := []struct {
+		ts   int64
+		line string
+	}{
+		{base + 10, "a"}, {base + 20, "b"}, {base + 20, "c"}, {base + 20, "d"}, {base + 30, "e"}, {base + 40, "f"},
+	}
+	var requests int
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		requests++
+		q := r.URL.Query()
+		start, err := strconv.ParseInt(q.Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(q.Get("end"), 10, 64)
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(q.Get("limit"))
+		require.NoError(t, err)
+
+		// Loki's start is inclusive and its end exclusive.
+		values := [][]string{}
+		for _, l := range lines {
+			if l.ts >= start && l.ts < end {
+				values = append(values, []string{strconv.FormatInt(l.ts, 10), l.line})
+			}
+		}
+		if q.Get("direction") == "backward" {
+			slices.Reverse(values)
+		}
+		if len(values) > limit {
+			values = values[:limit]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "streams",
+				"result":     []any{map[string]any{"stream": map[string]string{"app": "foo"}, "values": values}},
+			},
+		}))
+	})
+
+	readAll := func(t *testing.T, direction string) []string {
+		t.Helper()
+		args := QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			StartRFC3339:  "2024-01-01T00:00:00Z",
+			EndRFC3339:    "2024-01-01T01:00:00Z",
+			Limit:         2,
+			Direction:     direction,
+			WithCursor:    true,
+		}
+		var got []string
+		for i := 0; i < 10; i++ {
+			page, err := queryLokiLogsPage(ctx, args)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Items), 2)
+			for _, e := range page.Items {
+				got = append(got, e.Line)
+			}
+			if page.NextCursor == "" {
+				return got
+			}
+			// Parameters other than the cursor are ignored.
+			args = QueryLokiLogsParams{Cursor: page.NextCursor, LogQL: "ignored"}
+		}
+		t.Fatal("cursor did not reach the end of the results")
+		return nil
+	}
+
+	t.Run("forward", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, readAll(t, "forward"))
+	})
+
+	t.Run("backward", func(t *testing.T) {
+		assert.Equal(t, []string{"f", "e", "d", "c", "b", "a"}, readAll(t, "backward"))
+	})
+
+	t.Run("all lines on one page", func(t *testing.T) {
+		requests = 0
+		page, err := queryLokiLogsPage(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			StartRFC3339:  "2024-01-01T00:00:00Z",
+			EndRFC3339:    "2024-01-01T01:00:00Z",
+			Limit:         10,
+		})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 6)
+		assert.Empty(t, page.NextCursor)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := queryLokiLogsPage(ctx, QueryLokiLogsParams{Cursor: "not-a-cursor"})
+		assert.ErrorContains(t, err, "invalid cursor")
+	})
+
+	t.Run("combined with pageSize", func(t *testing.T) {
+		_, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, WithCursor: true, PageSize: 2})
+		assert.ErrorContains(t, err, "cursors cannot be combined")
+	})
+}
+
+func TestQueryLokiLogsCursorMultipleStreams(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	type podLine struct {
+		pod  string
+		ts   int64
+		line string
+	}
+	lines := []podLine{
+		{"a", base + 10, "a"}, {"b", base + 20, "b"}, {"a", base + 30, "c"}, {"b", base + 40, "d"}, {"a", base + 50, "e"}, {"b", base + 60, "f"},
+	}
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		start, err := strconv.ParseInt(q.Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(q.Get("end"), 10, 64)
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(q.Get("limit"))
+		require.NoError(t, err)
+
+		// Loki picks the first lines in the query direction across all
+		// streams, but returns them grouped by stream.
+		matched := slices.DeleteFunc(slices.Clone(lines), func(l podLine) bool {
+			return l.ts < start || l.ts >= end
+		})
+		if q.Get("direction") == "backward" {
+			slices.Reverse(matched)
+		}
+		if len(matched) > limit {
+			matched = matched[:limit]
+		}
+		values := map[string][][]string{"a": {}, "b": {}}
+		for _, l := range matched {
+			values[l.pod] = append(values[l.pod], []string{strconv.FormatInt(l.ts, 10), l.line})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "streams",
+				"result": []any{
+					map[string]any{"stream": map[string]string{"pod": "a"}, "values": values["a"]},
+					map[string]any{"stream": map[string]string{"pod": "b"}, "values": values["b"]},
+				},
+			},
+		}))
+	})
+
+	for direction, want := range map[string][]string{
+		"forward":  {"a", "b", "c", "d", "e", "f"},
+		"backward": {"f", "e", "d", "c", "b", "a"},
+	} {
+		t.Run(direction, func(t *testing.T) {
+			args := QueryLokiLogsParams{
+				DatasourceUID: "loki",
+				LogQL:         `{pod=~"a|b"}`,
+				StartRFC3339:  "2024-01-01T00:00:00Z",
+				EndRFC3339:    "2024-01-01T01:00:00Z",
+				Limit:         4,
+				Direction:     direction,
+				WithCursor:    true,
+			}
+			page, err := queryLokiLogsPage(ctx, args)
+			require.NoError(t, err)
+			var got []string
+			for _, e := range page.Items {
+				got = append(got, e.Line)
+			}
+			require.NotEmpty(t, page.NextCursor)
+
+			page, err = queryLokiLogsPage(ctx, QueryLokiLogsParams{Cursor: page.NextCursor})
+			require.NoError(t, err)
+			for _, e := range page.Items {
+				got = append(got, e.Line)
+			}
+			assert.Equal(t, want, got)
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+)
+
+const lokiDeletePath = "/loki/api/v1/delete"
+
+// CreateLokiDeleteRequestParams defines the parameters for requesting the
+// deletion of log lines from a Loki datasource.
+type CreateLokiDeleteRequestParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource"`
+	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL log query selecting the lines to delete: a stream selector optionally followed by line filters\\, e.g. '{app=\"checkout\"} |= \"user=42\"'"`
+	StartRFC3339  string `json:"startRfc3339" jsonschema:"required,description=The start of the time range to delete lines from\\, in RFC3339 format or relative to now (e.g. 'now-30d')"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end of the time range to delete lines from\\, in RFC3339 format or relative to now. Defaults to now"`
+	MaxInterval   string `json:"maxInterval,omitempty" jsonschema:"description=Optionally\\, the longest time range Loki processes at once (e.g. '24h')\\, splitting the request into smaller ones"`
+}
+
+func createLokiDeleteRequest(ctx context.Context, args CreateLokiDeleteRequestParams) (string, error) {
+	if len(lokiStreamSelectorSpans(args.LogQL)) == 0 {
+		return "", fmt.Errorf("logql must be a log query with a stream selector")
+	}
+	if args.StartRFC3339 == "" {
+		return "", fmt.Errorf("startRfc3339 is required")
+	}
+	start, err := parseTime(args.StartRFC3339)
+	if err != nil {
+		return "", fmt.Errorf("parsing start time: %w", err)
+	}
+	end := time.Now()
+	if args.EndRFC3339 != "" {
+		if end, err = parseTime(args.EndRFC3339); err != nil {
+			return "", fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return "", fmt.Errorf("start time %s must be before end time %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	params := url.Values{}
+	params.Add("query", args.LogQL)
+	params.Add("start", strconv.FormatInt(start.Unix(), 10))
+	params.Add("end", strconv.FormatInt(end.Unix(), 10))
+	if args.MaxInterval != "" {
+		if _, err := model.ParseDuration(args.MaxInterval); err != nil {
+			return "", fmt.Errorf("parsing max interval: %w", err)
+		}
+		params.Add("max_interval", args.MaxInterval)
+	}
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return "", fmt.Errorf("creating Loki client: %w", err)
+	}
+	if _, err := client.makeRequest(ctx, http.MethodPost, lokiDeletePath, params); err != nil {
+		return "", fmt.Errorf("create Loki delete request: %w", err)
+	}
+	return fmt.Sprintf("Requested the deletion of the lines matching %s from %s to %s. Loki deletes them after its cancellation period; use list_loki_delete_requests to follow the request's status.",
+		args.LogQL, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)), nil
+}
+
+var CreateLokiDeleteRequest = mcpgrafana.MustTool(
+	"create_loki_delete_request",
+	"Requests the deletion of the log lines matching a LogQL query in a time range from a Loki datasource, for example to remove personal data for compliance. The query is a stream selector optionally followed by line filters. Loki processes the request asynchronously after its cancellation period, and deleted lines cannot be recovered. Requires deletion to be enabled in Loki's compactor. Check the lines with query_loki_logs first, and use list_loki_delete_requests to follow the request.",
+	createLokiDeleteRequest,
+	mcp.WithTitleAnnotation("Create Loki delete request"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+// ListLokiDeleteRequestsParams defines the parameters for listing the
+// deletion requests of a Loki datasource.
+type ListLokiDeleteRequestsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource"`
+}
+
+// lokiDeleteRequest is a request to delete log lines from Loki.
+type lokiDeleteRequest struct {
+	RequestID string `json:"requestId"`
+	Query     string `json:"query"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	// Status is 'received' until Loki starts processing the request, then
+	// 'processing' or 'processed'.
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func listLokiDeleteRequests(ctx context.Context, args ListLokiDeleteRequestsParams) ([]lokiDeleteRequest, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	bodyBytes, err := client.makeRequest(ctx, http.MethodGet, lokiDeletePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("list Loki delete requests: %w", err)
+	}
+
+	var response []struct {
+		RequestID string     `json:"request_id"`
+		Query     string     `json:"query"`
+		StartTime model.Time `json:"start_time"`
+		EndTime   model.Time `json:"end_time"`
+		Status    string     `json:"status"`
+		CreatedAt model.Time `json:"created_at"`
+	}
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	requests := make([]lokiDeleteRequest, 0, len(response))
+	for _, r := range response {
+		requests = append(requests, lokiDeleteRequest{
+			RequestID: r.RequestID,
+			Query:     r.Query,
+			Start:     r.StartTime.Time().UTC().Format(time.RFC3339),
+			End:       r.EndTime.Time().UTC().Format(time.RFC3339),
+			Status:    r.Status,
+			CreatedAt: r.CreatedAt.Time().UTC().Format(time.RFC3339),
+		})
+	}
+	return requests, nil
+}
+
+var ListLokiDeleteRequests = mcpgrafana.MustTool(
+	"list_loki_delete_requests",
+	"Lists the log deletion requests of a Loki datasource, with their query, time range, status ('received', 'processing' or 'processed') and creation time. Requires deletion to be enabled in Loki's compactor.",
+	listLokiDeleteRequests,
+	mcp.WithTitleAnnotation("List Loki delete requests"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
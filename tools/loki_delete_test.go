@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestCreateLokiDeleteRequest(t *testing.T) {
+	var query map[string]string
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, lokiDeletePath, r.URL.Path)
+		query = map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	msg, err := createLokiDeleteRequest(ctx, CreateLokiDeleteRequestParams{
+		DatasourceUID: "loki",
+		LogQL:         `{app="checkout"} |= "user=42"`,
+		StartRFC3339:  "2024-01-01T00:00:00Z",
+		EndRFC3339:    "2024-01-02T00:00:00Z",
+		MaxInterval:   "1h",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, msg, "from 2024-01-01T00:00:00Z to 2024-01-02T00:00:00Z")
+	assert.Equal(t, map[string]string{
+		"query":        `{app="checkout"} |= "user=42"`,
+		"start":        "1704067200",
+		"end":          "1704153600",
+		"max_interval": "1h",
+	}, query)
+
+	t.Run("end defaults to now", func(t *testing.T) {
+		_, err := createLokiDeleteRequest(ctx, CreateLokiDeleteRequestParams{DatasourceUID: "loki", LogQL: `{app="checkout"}`, StartRFC3339: "now-1d"})
+		require.NoError(t, err)
+		start, err := strconv.ParseInt(query["start"], 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(-24*time.Hour).Unix(), start, 5)
+	})
+
+	for name, args := range map[string]CreateLokiDeleteRequestParams{
+		"no selector":     {DatasourceUID: "loki", LogQL: "vector(1)", StartRFC3339: "now-1d"},
+		"no start":        {DatasourceUID: "loki", LogQL: `{app="checkout"}`},
+		"start after end": {DatasourceUID: "loki", LogQL: `{app="checkout"}`, StartRFC3339: "now", EndRFC3339: "now-1d"},
+		"max interval":    {DatasourceUID: "loki", LogQL: `{app="checkout"}`, StartRFC3339: "now-1d", MaxInterval: "daily"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := createLokiDeleteRequest(ctx, args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCreateLokiDeleteRequestNotRetried(t *testing.T) {
+	var attempts int
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	cfg.ToolDefaults = map[string]mcpgrafana.ToolDefaults{"loki": {Retries: 2, RetryBackoff: time.Millisecond}}
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, cfg)
+
+	// Loki may have accepted the request before the gateway failed, so it
+	// must not be sent again.
+	_, err := createLokiDeleteRequest(ctx, CreateLokiDeleteRequestParams{DatasourceUID: "loki", LogQL: `{app="checkout"}`, StartRFC3339: "now-1d"})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestListLokiDeleteRequests(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, lokiDeletePath, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"request_id":"a1b2","start_time":1704067200,"end_time":1704153600.000,"query":"{app=\"checkout\"}","status":"received","created_at":1704240000.5}]`))
+	})
+
+	requests, err := listLokiDeleteRequests(ctx, ListLokiDeleteRequestsParams{DatasourceUID: "loki"})
+	require.NoError(t, err)
+	assert.Equal(t, []lokiDeleteRequest{{
+		RequestID: "a1b2",
+		Query:     `{app="checkout"}`,
+		Start:     "2024-01-01T00:00:00Z",
+		End:       "2024-01-02T00:00:00Z",
+		Status:    "received",
+		CreatedAt: "2024-01-03T00:00:00Z",
+	}}, requests)
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// DefaultLokiErrorServices is the default number of regressions returned
+	// by summarize_loki_errors.
+	DefaultLokiErrorServices = 10
+	// MaxLokiErrorServices is the maximum number of regressions returned by
+	// summarize_loki_errors.
+	MaxLokiErrorServices = 50
+)
+
+type SummarizeLokiErrorsParams struct {
+	DatasourceUID  string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource to query"`
+	StartRFC3339   string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the window in RFC3339 format or relative to now (e.g. 'now-15m'). Defaults to 1 hour ago. The window is compared to the window of the same length before it"`
+	EndRFC3339     string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the window in RFC3339 format or relative to now. Defaults to now"`
+	Namespace      string `json:"namespace,omitempty" jsonschema:"description=Optionally\\, only count the logs of services in this namespace"`
+	NamespaceLabel string `json:"namespaceLabel,omitempty" jsonschema:"description=Optionally\\, the label holding the namespace (default: namespace)"`
+	ServiceLabel   string `json:"serviceLabel,omitempty" jsonschema:"description=Optionally\\, the label identifying services (default: service_name)"`
+	ErrorFilter    string `json:"errorFilter,omitempty" jsonschema:"description=Optionally\\, the LogQL pipeline selecting error lines. Defaults to lines matching error\\, exception\\, fatal or panic. Use e.g. '| detected_level=\"error\"' when Loki detects log levels"`
+	Limit          int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of regressions to return (default: 10\\, max: 50)"`
+}
+
+// lokiServiceErrors is the error log rate of a service in a window and in the
+// window before it.
+type lokiServiceErrors struct {
+	Service string `json:"service"`
+	// ErrorsPerSecond is the rate of error lines in the window.
+	ErrorsPerSecond float64 `json:"errorsPerSecond"`
+	// PreviousErrorsPerSecond is the rate of error lines in the previous
+	// window.
+	PreviousErrorsPerSecond float64 `json:"previousErrorsPerSecond"`
+	// ChangePercent is the relative change of the rate, omitted for services
+	// without errors in the previous window.
+	ChangePercent *float64 `json:"changePercent,omitempty"`
+	// New is set for services without errors in the previous window.
+	New bool `json:"new,omitempty"`
+}
+
+// lokiErrorSummary is the result of summarize_loki_errors.
+type lokiErrorSummary struct {
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	PreviousStart string `json:"previousStart"`
+	Query         string `json:"query"`
+	// Services is the number of services logging errors in either window.
+	Services int `json:"services"`
+	// Regressions are the services whose error rate increased the most, most
+	// increased first.
+	Regressions []lokiServiceErrors `json:"regressions"`
+}
+
+// lokiErrorCounts maps each service to its number of error lines.
+func lokiErrorCounts(ctx context.Context, client *Client, query, serviceLabel string, ts time.Time) (map[string]float64, error) {
+	vector, err := client.fetchInstant(ctx, query, ts)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]float64, len(vector))
+	for _, sample := range vector {
+		counts[string(sample.Metric[model.LabelName(serviceLabel)])] += float64(sample.Value)
+	}
+	return counts, nil
+}
+
+func summarizeLokiErrors(ctx context.Context, args SummarizeLokiErrorsParams) (*lokiErrorSummary, error) {
+	serviceLabel := stringOrDefault(args.ServiceLabel, "service_name")
+	namespaceLabel := stringOrDefault(args.NamespaceLabel, "namespace")
+	for _, name := range []string{serviceLabel, namespaceLabel} {
+		if !model.LabelName(name).IsValidLegacy() {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiErrorServices
+	}
+	limit = min(limit, MaxLokiErrorServices)
+
+	startStr, endStr, err := lokiTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	window := end.Sub(start).Truncate(time.Second)
+	if window <= 0 {
+		return nil, fmt.Errorf("the time range must be at least one second long")
+	}
+
+	selector := serviceLabel + `=~".+"`
+	if args.Namespace != "" {
+		selector += ", " + namespaceLabel + "=" + strconv.Quote(args.Namespace)
+	}
+	query := fmt.Sprintf("sum by (%s) (count_over_time({%s} %s [%s]))",
+		serviceLabel, selector, stringOrDefault(args.ErrorFilter, timelineErrorFilter), model.Duration(window))
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	current, err := lokiErrorCounts(ctx, client, query, serviceLabel, end)
+	if err != nil {
+		return nil, fmt.Errorf("querying the error rate: %w", err)
+	}
+	previous, err := lokiErrorCounts(ctx, client, query, serviceLabel, start)
+	if err != nil {
+		return nil, fmt.Errorf("querying the previous error rate: %w", err)
+	}
+
+	seconds := window.Seconds()
+	summary := &lokiErrorSummary{
+		Start:         start.Format(time.RFC3339),
+		End:           end.Format(time.RFC3339),
+		PreviousStart: start.Add(-window).Format(time.RFC3339),
+		Query:         query,
+		Regressions:   []lokiServiceErrors{},
+	}
+	services := map[string]bool{}
+	for service := range previous {
+		services[service] = true
+	}
+	for service, count := range current {
+		services[service] = true
+		before := previous[service]
+		if count <= before {
+			continue
+		}
+		s := lokiServiceErrors{
+			Service:                 service,
+			ErrorsPerSecond:         count / seconds,
+			PreviousErrorsPerSecond: before / seconds,
+			New:                     before == 0,
+		}
+		if before > 0 {
+			change := math.Round((count-before)/before*1000) / 10
+			s.ChangePercent = &change
+		}
+		summary.Regressions = append(summary.Regressions, s)
+	}
+	summary.Services = len(services)
+	sort.Slice(summary.Regressions, func(i, j int) bool {
+		a, b := summary.Regressions[i], summary.Regressions[j]
+		if da, db := a.ErrorsPerSecond-a.PreviousErrorsPerSecond, b.ErrorsPerSecond-b.PreviousErrorsPerSecond; da != db {
+			return da > db
+		}
+		return a.Service < b.Service
+	})
+	if len(summary.Regressions) > limit {
+		summary.Regressions = summary.Regressions[:limit]
+	}
+	return summary, nil
+}
+
+var SummarizeLokiErrors = mcpgrafana.MustTool(
+	"summarize_loki_errors",
+	"Computes the rate of error log lines of every service in a Loki datasource over a time range, optionally in one namespace, compares it to the window of the same length before, and returns the services whose error rate increased the most, with both rates and the change in percent. Use this as the first step when asked what is broken, instead of querying the logs of each service. Services are identified by the `service_name` label and error lines by matching error, exception, fatal or panic unless `serviceLabel` or `errorFilter` say otherwise. Times may be RFC3339 or relative to now and default to the last hour. Follow up with `query_loki_logs` or `query_loki_patterns` on the regressed services.",
+	guardTimeRange(summarizeLokiErrors),
+	mcp.WithTitleAnnotation("Summarize Loki errors"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,93 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeLokiErrors(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	var queries []string
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query", r.URL.Path)
+		queries = append(queries, r.URL.Query().Get("query"))
+		ts, err := strconv.ParseInt(r.URL.Query().Get("time"), 10, 64)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		switch ts {
+		case end.UnixNano():
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"service_name":"checkout"},"value":[1704070800,"7200"]},
+				{"metric":{"service_name":"payments"},"value":[1704070800,"360"]},
+				{"metric":{"service_name":"cart"},"value":[1704070800,"10"]},
+				{"metric":{"service_name":"search"},"value":[1704070800,"36"]}]}}`))
+		case start.UnixNano():
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"service_name":"checkout"},"value":[1704067200,"3600"]},
+				{"metric":{"service_name":"cart"},"value":[1704067200,"20"]},
+				{"metric":{"service_name":"search"},"value":[1704067200,"36"]},
+				{"metric":{"service_name":"auth"},"value":[1704067200,"5"]}]}}`))
+		default:
+			t.Errorf("unexpected query time %d", ts)
+		}
+	})
+
+	summary, err := summarizeLokiErrors(ctx, SummarizeLokiErrorsParams{
+		DatasourceUID: "loki",
+		StartRFC3339:  "2024-01-01T00:00:00Z",
+		EndRFC3339:    "2024-01-01T01:00:00Z",
+		Namespace:     "shop",
+	})
+	require.NoError(t, err)
+	want := `sum by (service_name) (count_over_time({service_name=~".+", namespace="shop"} ` + timelineErrorFilter + ` [1h]))`
+	assert.Equal(t, []string{want, want}, queries)
+	assert.Equal(t, want, summary.Query)
+	assert.Equal(t, "2023-12-31T23:00:00Z", summary.PreviousStart)
+	assert.Equal(t, 5, summary.Services)
+
+	require.Len(t, summary.Regressions, 2)
+	checkout := summary.Regressions[0]
+	assert.Equal(t, "checkout", checkout.Service)
+	assert.Equal(t, 2.0, checkout.ErrorsPerSecond)
+	assert.Equal(t, 1.0, checkout.PreviousErrorsPerSecond)
+	require.NotNil(t, checkout.ChangePercent)
+	assert.Equal(t, 100.0, *checkout.ChangePercent)
+	assert.False(t, checkout.New)
+
+	payments := summary.Regressions[1]
+	assert.Equal(t, "payments", payments.Service)
+	assert.Equal(t, 0.1, payments.ErrorsPerSecond)
+	assert.Nil(t, payments.ChangePercent)
+	assert.True(t, payments.New)
+
+	t.Run("limit and custom labels", func(t *testing.T) {
+		queries = nil
+		summary, err := summarizeLokiErrors(ctx, SummarizeLokiErrorsParams{
+			DatasourceUID: "loki",
+			StartRFC3339:  "2024-01-01T00:00:00Z",
+			EndRFC3339:    "2024-01-01T01:00:00Z",
+			ServiceLabel:  "app",
+			ErrorFilter:   `| detected_level="error"`,
+			Limit:         1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `sum by (app) (count_over_time({app=~".+"} | detected_level="error" [1h]))`, queries[0])
+		// The mock's series are labelled service_name, so they count as one
+		// service without a name.
+		require.Len(t, summary.Regressions, 1)
+	})
+
+	t.Run("invalid label", func(t *testing.T) {
+		_, err := summarizeLokiErrors(ctx, SummarizeLokiErrorsParams{DatasourceUID: "loki", ServiceLabel: "service.name"})
+		assert.ErrorContains(t, err, "invalid label name")
+	})
+}
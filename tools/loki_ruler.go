@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AddLokiWriteTools registers Loki tools which modify datasource state. They
+// are only enabled when the server runs with write tools enabled.
+func AddLokiWriteTools(mcp *server.MCPServer) {
+	UpdateLokiRuleGroup.Register(mcp)
+	CreateLokiDeleteRequest.Register(mcp)
+}
+
+// lokiRule is a recording or alerting rule evaluated by the Loki ruler.
+type lokiRule struct {
+	Record      string            `json:"record,omitempty" jsonschema:"description=The name of the metric a recording rule writes. Set either record or alert"`
+	Alert       string            `json:"alert,omitempty" jsonschema:"description=The name of an alerting rule. Set either record or alert"`
+	Expr        string            `json:"expr" jsonschema:"required,description=The LogQL metric query the rule evaluates"`
+	For         string            `json:"for,omitempty" jsonschema:"description=Optionally\\, how long an alerting rule's condition must hold before it fires (e.g. '5m')"`
+	Labels      map[string]string `json:"labels,omitempty" jsonschema:"description=Optionally\\, labels added to the recorded series or alerts"`
+	Annotations map[string]string `json:"annotations,omitempty" jsonschema:"description=Optionally\\, annotations of an alerting rule\\, such as summary"`
+}
+
+// lokiRuleGroup is a group of rules evaluated together by the Loki ruler.
+type lokiRuleGroup struct {
+	Namespace string     `json:"namespace,omitempty"`
+	Name      string     `json:"name"`
+	Interval  string     `json:"interval,omitempty"`
+	Rules     []lokiRule `json:"rules"`
+}
+
+// lokiRulerPath returns the path of the Grafana ruler API for a datasource,
+// optionally for a namespace.
+func lokiRulerPath(datasourceUID, namespace string) (string, error) {
+	escapedUID, err := sanitizePathSegment("datasource UID", datasourceUID)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/api/ruler/%s/api/v1/rules", escapedUID)
+	if namespace == "" {
+		return path, nil
+	}
+	escapedNamespace, err := sanitizePathSegment("namespace", namespace)
+	if err != nil {
+		return "", err
+	}
+	return path + "/" + escapedNamespace, nil
+}
+
+// ListLokiRulesParams defines the parameters for listing the rules of a Loki
+// datasource.
+type ListLokiRulesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"description=Optionally\\, only list the rule groups of this namespace"`
+}
+
+func listLokiRules(ctx context.Context, args ListLokiRulesParams) ([]lokiRuleGroup, error) {
+	path, err := lokiRulerPath(args.DatasourceUID, args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID}); err != nil {
+		return nil, err
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating alerting client: %w", err)
+	}
+
+	resp, err := client.makeRequest(ctx, path)
+	if err != nil {
+		// The Loki ruler responds with a 404 if there are no rule groups.
+		if strings.Contains(err.Error(), "no rule groups found") {
+			return []lokiRuleGroup{}, nil
+		}
+		return nil, fmt.Errorf("list Loki rules: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var namespaces map[string][]lokiRuleGroup
+	if err := json.NewDecoder(resp.Body).Decode(&namespaces); err != nil {
+		return nil, fmt.Errorf("failed to decode rules response from %s: %w", path, err)
+	}
+	groups := []lokiRuleGroup{}
+	for _, namespace := range sortedKeys(namespaces) {
+		for _, group := range namespaces[namespace] {
+			group.Namespace = namespace
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+var ListLokiRules = mcpgrafana.MustTool(
+	"list_loki_rules",
+	"Lists the recording and alerting rules evaluated by the ruler of a Loki datasource, as rule groups with their namespace, evaluation interval and rules. Each rule has a 'record' (the metric it writes) or an 'alert' name, its LogQL expression, and its labels and annotations.",
+	listLokiRules,
+	mcp.WithTitleAnnotation("List Loki rules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// UpdateLokiRuleGroupParams defines the parameters for creating or replacing
+// a rule group of a Loki datasource.
+type UpdateLokiRuleGroupParams struct {
+	DatasourceUID string     `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource"`
+	Namespace     string     `json:"namespace" jsonschema:"required,description=The namespace of the rule group"`
+	Name          string     `json:"name" jsonschema:"required,description=The name of the rule group"`
+	Interval      string     `json:"interval,omitempty" jsonschema:"description=Optionally\\, how often the rules are evaluated (e.g. '1m'). Defaults to the ruler's evaluation interval"`
+	Rules         []lokiRule `json:"rules" jsonschema:"required,description=The rules of the group. They replace all the rules of an existing group"`
+}
+
+func (p UpdateLokiRuleGroupParams) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+	for i, r := range p.Rules {
+		if (r.Record == "") == (r.Alert == "") {
+			return fmt.Errorf("rule %d: exactly one of record and alert is required", i)
+		}
+		if r.Expr == "" {
+			return fmt.Errorf("rule %d: expr is required", i)
+		}
+		if r.Record != "" && (r.For != "" || len(r.Annotations) > 0) {
+			return fmt.Errorf("rule %d: recording rules cannot have for or annotations", i)
+		}
+	}
+	return nil
+}
+
+func updateLokiRuleGroup(ctx context.Context, args UpdateLokiRuleGroupParams) (string, error) {
+	if err := args.validate(); err != nil {
+		return "", err
+	}
+	if args.Namespace == "" {
+		return "", fmt.Errorf("namespace is required")
+	}
+	path, err := lokiRulerPath(args.DatasourceUID, args.Namespace)
+	if err != nil {
+		return "", err
+	}
+	if _, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID}); err != nil {
+		return "", err
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating alerting client: %w", err)
+	}
+
+	body, err := json.Marshal(lokiRuleGroup{Name: args.Name, Interval: args.Interval, Rules: args.Rules})
+	if err != nil {
+		return "", fmt.Errorf("encoding rule group: %w", err)
+	}
+	resp, err := client.do(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("update Loki rule group: %w", err)
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("Saved rule group %q in namespace %q with %d rules", args.Name, args.Namespace, len(args.Rules)), nil
+}
+
+var UpdateLokiRuleGroup = mcpgrafana.MustTool(
+	"update_loki_rule_group",
+	"Creates a rule group in the ruler of a Loki datasource, or replaces all the rules of an existing group with the same namespace and name. Rules are recording rules, which write the result of a LogQL metric query as a metric, or alerting rules. For example, a frequently run LogQL metric query can be turned into a recording rule so it is precomputed. Use list_loki_rules first to keep the existing rules of a group.",
+	updateLokiRuleGroup,
+	mcp.WithTitleAnnotation("Create or update Loki rule group"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
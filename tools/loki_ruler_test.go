@@ -0,0 +1,115 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockRulerContext returns a context for a Grafana with a Loki datasource
+// whose ruler API is served by handler.
+func newMockRulerContext(t *testing.T, handler http.HandlerFunc) context.Context {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources/uid/loki", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"loki","type":"loki"}`))
+	})
+	mux.HandleFunc("/api/ruler/loki/api/v1/rules", handler)
+	mux.HandleFunc("/api/ruler/loki/api/v1/rules/", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL, APIKey: "test-api-key"})
+	return mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, "test-api-key"))
+}
+
+func TestListLokiRules(t *testing.T) {
+	ctx := newMockRulerContext(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ruler/loki/api/v1/rules":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"payments": [{"name":"errors","interval":"1m","rules":[{"alert":"HighErrors","expr":"sum(rate({app=\"payments\"} |= \"error\" [5m])) > 10","for":"5m","annotations":{"summary":"Many errors"}}]}],
+				"checkout": [{"name":"rates","rules":[{"record":"checkout:log_lines:rate5m","expr":"sum(rate({app=\"checkout\"}[5m]))","labels":{"team":"shop"}}]}]
+			}`))
+		case "/api/ruler/loki/api/v1/rules/empty":
+			http.Error(w, "no rule groups found", http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	groups, err := listLokiRules(ctx, ListLokiRulesParams{DatasourceUID: "loki"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "checkout", groups[0].Namespace)
+	assert.Equal(t, "checkout:log_lines:rate5m", groups[0].Rules[0].Record)
+	assert.Equal(t, map[string]string{"team": "shop"}, groups[0].Rules[0].Labels)
+	assert.Equal(t, "payments", groups[1].Namespace)
+	assert.Equal(t, "1m", groups[1].Interval)
+	assert.Equal(t, "HighErrors", groups[1].Rules[0].Alert)
+	assert.Equal(t, "5m", groups[1].Rules[0].For)
+
+	groups, err = listLokiRules(ctx, ListLokiRulesParams{DatasourceUID: "loki", Namespace: "empty"})
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	_, err = listLokiRules(ctx, ListLokiRulesParams{DatasourceUID: "loki", Namespace: "../grafana"})
+	assert.ErrorContains(t, err, "invalid namespace")
+}
+
+func TestUpdateLokiRuleGroup(t *testing.T) {
+	var got lokiRuleGroup
+	ctx := newMockRulerContext(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/ruler/loki/api/v1/rules/checkout", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"rule group updated successfully"}`))
+	})
+
+	rule := lokiRule{Record: "checkout:log_lines:rate5m", Expr: `sum(rate({app="checkout"}[5m]))`}
+	msg, err := updateLokiRuleGroup(ctx, UpdateLokiRuleGroupParams{
+		DatasourceUID: "loki",
+		Namespace:     "checkout",
+		Name:          "rates",
+		Interval:      "1m",
+		Rules:         []lokiRule{rule},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, msg, `"rates"`)
+	assert.Equal(t, lokiRuleGroup{Name: "rates", Interval: "1m", Rules: []lokiRule{rule}}, got)
+
+	for name, args := range map[string]UpdateLokiRuleGroupParams{
+		"no namespace":         {DatasourceUID: "loki", Name: "rates", Rules: []lokiRule{rule}},
+		"no rules":             {DatasourceUID: "loki", Namespace: "checkout", Name: "rates"},
+		"record and alert":     {DatasourceUID: "loki", Namespace: "checkout", Name: "rates", Rules: []lokiRule{{Record: "a", Alert: "b", Expr: "vector(1)"}}},
+		"no expr":              {DatasourceUID: "loki", Namespace: "checkout", Name: "rates", Rules: []lokiRule{{Record: "a"}}},
+		"recording rule's for": {DatasourceUID: "loki", Namespace: "checkout", Name: "rates", Rules: []lokiRule{{Record: "a", Expr: "vector(1)", For: "5m"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := updateLokiRuleGroup(ctx, args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLokiRulerPath(t *testing.T) {
+	path, err := lokiRulerPath("loki", "team a")
+	require.NoError(t, err)
+	assert.Equal(t, "/api/ruler/loki/api/v1/rules/team%20a", path)
+
+	for _, namespace := range []string{"../admin", "..%2F..%2Fadmin"} {
+		_, err = lokiRulerPath("loki", namespace)
+		assert.ErrorContains(t, err, "invalid namespace")
+	}
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLokiSplitInterval is the longest time range of a log query sent
+	// to Loki at once, unless configured otherwise. Longer queries are split.
+	DefaultLokiSplitInterval = 24 * time.Hour
+
+	// maxLokiSplitQueries is the most queries a log query is split into. The
+	// interval of longer ranges is widened to stay within it.
+	maxLokiSplitQueries = 30
+)
+
+// lokiTimeWindow is a part of the time range of a split query. Like Loki's,
+// its start is inclusive and its end exclusive.
+type lokiTimeWindow struct {
+	start, end time.Time
+}
+
+// lokiSplitWindows returns the windows a log query is split into, in the
+// order they are queried: newest first for backward queries and oldest first
+// for forward ones. It returns nil if the query should not be split, because
+// it is a metric query or its range is within the configured split interval.
+func lokiSplitWindows(ctx context.Context, logql, startRFC3339, endRFC3339, direction string) []lokiTimeWindow {
+	// Log queries start with their stream selector.
+	if !strings.HasPrefix(strings.TrimSpace(logql), "{") {
+		return nil
+	}
+	interval := toolDefaultsFor(ctx, defaultsCategoryLoki).SplitInterval
+	start, err := time.Parse(time.RFC3339, startRFC3339)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(time.RFC3339, endRFC3339)
+	if err != nil {
+		return nil
+	}
+	if interval <= 0 || end.Sub(start) <= interval {
+		return nil
+	}
+	if n := (end.Sub(start) + interval - 1) / interval; n > maxLokiSplitQueries {
+		interval = (end.Sub(start) + maxLokiSplitQueries - 1) / maxLokiSplitQueries
+	}
+
+	var windows []lokiTimeWindow
+	for s := start; s.Before(end); s = s.Add(interval) {
+		windows = append(windows, lokiTimeWindow{start: s, end: minTime(s.Add(interval), end)})
+	}
+	if direction != "forward" {
+		slices.Reverse(windows)
+	}
+	return windows
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// fetchLogsSplit runs a log query over each window in turn, until the limit
+// is reached, and merges their lines in the order of the query. Windows which
+// fail are skipped and recorded for guardTimeRange to report, unless all of
+// them fail.
+func (c *Client) fetchLogsSplit(ctx context.Context, query string, windows []lokiTimeWindow, limit int, direction string) ([]LogEntry, error) {
+	entries := []LogEntry{}
+	var failed []lokiTimeWindow
+	var errs []error
+	for _, w := range windows {
+		streams, err := c.fetchLogs(ctx, query, w.start.Format(time.RFC3339Nano), w.end.Format(time.RFC3339Nano), limit-len(entries), direction)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed, errs = append(failed, w), append(errs, err)
+			continue
+		}
+		window := streamsToLogEntries(streams)
+		sortLogEntries(window)
+		if direction != "forward" {
+			slices.Reverse(window)
+		}
+		entries = append(entries, window...)
+		if len(entries) >= limit {
+			break
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(windows) {
+		return nil, fmt.Errorf("querying %s to %s: %w", failed[0].start.Format(time.RFC3339), failed[0].end.Format(time.RFC3339), errs[0])
+	}
+	for i, w := range failed {
+		recordTimeRangeFailure(ctx, w.start, w.end, errs[i])
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
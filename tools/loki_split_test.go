@@ -0,0 +1,127 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestLokiSplitWindows(t *testing.T) {
+	ctx := withToolDefaults(context.Background(), map[string]mcpgrafana.ToolDefaults{"loki": {SplitInterval: 24 * time.Hour}})
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	windows := lokiSplitWindows(ctx, `{app="foo"}`, "2024-01-01T00:00:00Z", "2024-01-03T12:00:00Z", "forward")
+	assert.Equal(t, []lokiTimeWindow{
+		{day(1), day(2)},
+		{day(2), day(3)},
+		{day(3), day(3).Add(12 * time.Hour)},
+	}, windows)
+
+	windows = lokiSplitWindows(ctx, `{app="foo"}`, "2024-01-01T00:00:00Z", "2024-01-03T00:00:00Z", "")
+	assert.Equal(t, []lokiTimeWindow{{day(2), day(3)}, {day(1), day(2)}}, windows)
+
+	// Long ranges are split into at most maxLokiSplitQueries windows.
+	windows = lokiSplitWindows(ctx, `{app="foo"}`, "2023-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "forward")
+	assert.Len(t, windows, maxLokiSplitQueries)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), windows[0].start)
+	assert.Equal(t, day(1), windows[len(windows)-1].end)
+
+	assert.Nil(t, lokiSplitWindows(ctx, `{app="foo"}`, "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z", ""), "within the interval")
+	assert.Nil(t, lokiSplitWindows(ctx, `sum(rate({app="foo"}[5m]))`, "2024-01-01T00:00:00Z", "2024-01-07T00:00:00Z", ""), "metric query")
+}
+
+func TestQueryLokiLogsSplit(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	var queried []time.Time
+	failDay := 0
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		queried = append(queried, time.Unix(0, start).UTC())
+		if time.Unix(0, start).UTC().Day() == failDay {
+			http.Error(w, "the query time range exceeds the limit", http.StatusBadRequest)
+			return
+		}
+		// One line at noon on each of the 7 days.
+		values := [][]string{}
+		for d := 1; d <= 7; d++ {
+			ts := day(d).Add(12 * time.Hour).UnixNano()
+			if ts >= start && ts < end && len(values) < limit {
+				values = append(values, []string{strconv.FormatInt(ts, 10), "day " + strconv.Itoa(d)})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "streams",
+				"result":     []any{map[string]any{"stream": map[string]string{"app": "foo"}, "values": values}},
+			},
+		}))
+	})
+	handler := guardTimeRange(queryLokiLogsTool)
+	args := QueryLokiLogsParams{
+		DatasourceUID: "loki",
+		LogQL:         `{app="foo"}`,
+		StartRFC3339:  "2024-01-01T00:00:00Z",
+		EndRFC3339:    "2024-01-08T00:00:00Z",
+		Limit:         3,
+	}
+	lines := func(entries []LogEntry) []string {
+		var lines []string
+		for _, e := range entries {
+			lines = append(lines, e.Line)
+		}
+		return lines
+	}
+
+	result, err := handler(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"day 7", "day 6", "day 5"}, lines(unwrapResult(result).([]LogEntry)))
+	assert.Equal(t, []time.Time{day(7), day(6), day(5)}, queried, "stops once the limit is reached")
+
+	t.Run("forward", func(t *testing.T) {
+		queried = nil
+		args := args
+		args.Direction = "forward"
+		result, err := handler(ctx, args)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"day 1", "day 2", "day 3"}, lines(unwrapResult(result).([]LogEntry)))
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		failDay = 6
+		t.Cleanup(func() { failDay = 0 })
+		result, err := handler(ctx, args)
+		require.NoError(t, err)
+		clamped, ok := unwrapResult(result).(clampedResult[queryLokiLogsResult])
+		require.True(t, ok, "expected the failures to be reported, got %T", result)
+		assert.Nil(t, clamped.TimeRangeClamped)
+		assert.Equal(t, []string{"day 7", "day 5", "day 4"}, lines(unwrapResult(clamped.Result).([]LogEntry)))
+		require.Len(t, clamped.PartialFailures, 1)
+		assert.Equal(t, day(6), clamped.PartialFailures[0].Start)
+		assert.Equal(t, day(7), clamped.PartialFailures[0].End)
+		assert.Contains(t, clamped.PartialFailures[0].Error, "exceeds the limit")
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		failDay = 1
+		t.Cleanup(func() { failDay = 0 })
+		args := args
+		args.StartRFC3339, args.EndRFC3339 = "2024-01-01T00:00:00Z", "2024-01-01T12:00:00Z"
+		_, err := handler(withToolDefaults(ctx, map[string]mcpgrafana.ToolDefaults{"loki": {SplitInterval: time.Hour}}), args)
+		assert.ErrorContains(t, err, "querying 2024-01-01T11:00:00Z to 2024-01-01T12:00:00Z")
+	})
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GetLokiStatusParams defines the parameters for checking the status of a
+// Loki datasource.
+type GetLokiStatusParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource"`
+}
+
+// lokiBuildInfo is the build information reported by Loki.
+type lokiBuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion,omitempty"`
+}
+
+// lokiStatus is the result of get_loki_status. Each check is reported
+// separately, so that one failing does not hide the others.
+type lokiStatus struct {
+	DatasourceUID string `json:"datasourceUid"`
+	// Reachable reports whether any of Loki's endpoints responded.
+	Reachable bool           `json:"reachable"`
+	BuildInfo *lokiBuildInfo `json:"buildInfo,omitempty"`
+	// Ready reports whether Loki's readiness endpoint reported it ready to
+	// serve queries.
+	Ready        bool   `json:"ready"`
+	ReadyMessage string `json:"readyMessage,omitempty"`
+	// MetricsAvailable reports whether Loki's metrics endpoint is exposed
+	// through the datasource proxy.
+	MetricsAvailable bool `json:"metricsAvailable"`
+	// Uptime is how long the Loki process has been running, from its
+	// metrics.
+	Uptime    string  `json:"uptime,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+	// Errors lists the checks which failed.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// processUptime returns how long ago a process started according to its
+// process_start_time_seconds metric in the Prometheus text format, or zero
+// if it is missing.
+func processUptime(metrics []byte, now time.Time) time.Duration {
+	scanner := bufio.NewScanner(bytes.NewReader(metrics))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "process_start_time_seconds ")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || seconds <= 0 {
+			return 0
+		}
+		return now.Sub(time.Unix(0, int64(seconds*float64(time.Second)))).Truncate(time.Second)
+	}
+	return 0
+}
+
+func getLokiStatus(ctx context.Context, args GetLokiStatusParams) (*lokiStatus, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	status := &lokiStatus{DatasourceUID: args.DatasourceUID}
+
+	start := time.Now()
+	body, err := client.makeRequest(ctx, http.MethodGet, "/loki/api/v1/status/buildinfo", nil)
+	status.LatencyMs = durationMs(time.Since(start))
+	if err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("build info: %s", err))
+	} else {
+		status.Reachable = true
+		var info lokiBuildInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("build info: unmarshalling response: %s", err))
+		} else {
+			status.BuildInfo = &info
+		}
+	}
+
+	body, err = client.makeRequest(ctx, http.MethodGet, "/ready", nil)
+	if err != nil {
+		// Loki responds with 503 and the reason while it is not ready.
+		status.ReadyMessage = err.Error()
+		if strings.Contains(err.Error(), "status code 503") {
+			status.Reachable = true
+		}
+	} else {
+		status.Reachable = true
+		status.Ready = true
+		status.ReadyMessage = string(body)
+	}
+
+	body, err = client.makeRequest(ctx, http.MethodGet, "/metrics", nil)
+	if err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("metrics: %s", err))
+	} else {
+		status.Reachable = true
+		status.MetricsAvailable = true
+		if uptime := processUptime(body, time.Now()); uptime > 0 {
+			status.Uptime = uptime.String()
+		}
+	}
+	return status, nil
+}
+
+var GetLokiStatus = mcpgrafana.MustTool(
+	"get_loki_status",
+	"Checks the connectivity and health of a Loki datasource through Grafana's datasource proxy: whether Loki is reachable and ready to serve queries, its version and build information, whether its metrics endpoint is exposed, and its uptime. Each check is reported separately with its error. Use this when Loki queries fail unexpectedly, to tell connectivity, readiness and version problems apart from problems with the query.",
+	getLokiStatus,
+	mcp.WithTitleAnnotation("Get Loki status"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
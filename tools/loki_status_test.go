@@ -0,0 +1,69 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLokiStatus(t *testing.T) {
+	started := time.Now().Add(-90 * time.Minute).Truncate(time.Second)
+	ready := true
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loki/api/v1/status/buildinfo":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"version":"3.4.2","revision":"a1b2c3","branch":"HEAD","buildUser":"root","buildDate":"2025-02-01T00:00:00Z","goVersion":"go1.23.6"}`))
+		case "/ready":
+			if !ready {
+				http.Error(w, "Ingester not ready: waiting for 15s after being ready", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("ready"))
+		case "/metrics":
+			_, _ = fmt.Fprintf(w, "# HELP process_start_time_seconds Start time.\n# TYPE process_start_time_seconds gauge\nprocess_start_time_seconds %d\n", started.Unix())
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	status, err := getLokiStatus(ctx, GetLokiStatusParams{DatasourceUID: "loki"})
+	require.NoError(t, err)
+	assert.True(t, status.Reachable)
+	assert.True(t, status.Ready)
+	assert.Equal(t, "ready", status.ReadyMessage)
+	assert.Equal(t, &lokiBuildInfo{Version: "3.4.2", Revision: "a1b2c3", Branch: "HEAD", BuildDate: "2025-02-01T00:00:00Z", GoVersion: "go1.23.6"}, status.BuildInfo)
+	assert.True(t, status.MetricsAvailable)
+	assert.Regexp(t, `^1h30m[01]s$`, status.Uptime)
+	assert.Empty(t, status.Errors)
+
+	t.Run("not ready", func(t *testing.T) {
+		ready = false
+		t.Cleanup(func() { ready = true })
+		status, err := getLokiStatus(ctx, GetLokiStatusParams{DatasourceUID: "loki"})
+		require.NoError(t, err)
+		assert.True(t, status.Reachable)
+		assert.False(t, status.Ready)
+		assert.Contains(t, status.ReadyMessage, "Ingester not ready")
+	})
+}
+
+func TestGetLokiStatusUnreachable(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	status, err := getLokiStatus(ctx, GetLokiStatusParams{DatasourceUID: "loki"})
+	require.NoError(t, err)
+	assert.False(t, status.Reachable)
+	assert.False(t, status.Ready)
+	assert.False(t, status.MetricsAvailable)
+	assert.Nil(t, status.BuildInfo)
+	assert.Len(t, status.Errors, 2)
+}
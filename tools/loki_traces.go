@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// traceIDLabels are the labels and structured metadata commonly holding the
+// trace ID of a log line.
+var traceIDLabels = []string{"trace_id", "traceID", "traceId", "traceid"}
+
+// traceIDPattern finds trace IDs such as `trace_id=4bf92f3577b34da6` or
+// `"traceId": "4bf92f3577b34da6a3ce929d0e0e4736"` in log lines.
+var traceIDPattern = regexp.MustCompile(`(?i)\btrace_?id["']?\s*[=:]\s*["']?([0-9a-f]{16,32})\b`)
+
+// lokiDerivedField is a derived field of a Loki datasource, which links a
+// value extracted from log lines, such as a trace ID, to another datasource.
+type lokiDerivedField struct {
+	Name string `json:"name"`
+	// MatcherRegex is the regular expression extracting the value, or the
+	// name of the label holding it if MatcherType is "label".
+	MatcherRegex  string `json:"matcherRegex"`
+	MatcherType   string `json:"matcherType,omitempty"`
+	DatasourceUID string `json:"datasourceUid,omitempty"`
+}
+
+// traceIDExtractor finds the trace ID of a log line using the datasource's
+// derived fields, falling back to common labels and line formats.
+type traceIDExtractor struct {
+	fields   []lokiDerivedField
+	patterns []*regexp.Regexp
+}
+
+// newTraceIDExtractor returns an extractor for the derived fields of a Loki
+// datasource which link to another datasource. Fields with invalid regular
+// expressions are ignored.
+func newTraceIDExtractor(jsonData any) *traceIDExtractor {
+	e := &traceIDExtractor{}
+	data, err := json.Marshal(jsonData)
+	if err != nil {
+		return e
+	}
+	var settings struct {
+		DerivedFields []lokiDerivedField `json:"derivedFields"`
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return e
+	}
+	for _, f := range settings.DerivedFields {
+		if f.DatasourceUID == "" || f.MatcherRegex == "" {
+			continue
+		}
+		var re *regexp.Regexp
+		if f.MatcherType != "label" {
+			if re, err = regexp.Compile(f.MatcherRegex); err != nil {
+				continue
+			}
+		}
+		e.fields = append(e.fields, f)
+		e.patterns = append(e.patterns, re)
+	}
+	return e
+}
+
+// tempoUID returns the UID of the datasource the first derived field links
+// to, if any.
+func (e *traceIDExtractor) tempoUID() string {
+	if len(e.fields) == 0 {
+		return ""
+	}
+	return e.fields[0].DatasourceUID
+}
+
+// extract returns the trace ID of a log entry and where it was found.
+func (e *traceIDExtractor) extract(entry LogEntry) (string, string) {
+	for i, f := range e.fields {
+		if e.patterns[i] == nil {
+			if v := entry.Labels[f.MatcherRegex]; v != "" {
+				return v, f.Name
+			}
+			continue
+		}
+		if m := e.patterns[i].FindStringSubmatch(entry.Line); m != nil {
+			if len(m) > 1 {
+				return m[1], f.Name
+			}
+			return m[0], f.Name
+		}
+	}
+	for _, label := range traceIDLabels {
+		if v := entry.Labels[label]; v != "" {
+			return v, label
+		}
+	}
+	if m := traceIDPattern.FindStringSubmatch(entry.Line); m != nil {
+		return m[1], "line"
+	}
+	return "", ""
+}
+
+// FindTracesForLogsParams defines the parameters for finding the traces of
+// the log lines matching a LogQL query.
+type FindTracesForLogsParams struct {
+	DatasourceUID    string `json:"datasourceUid" jsonschema:"required,description=The UID of the Loki datasource to query"`
+	LogQL            string `json:"logql" jsonschema:"required,description=The LogQL log query selecting the log lines\\, e.g. '{app=\"checkout\"} |= \"error\"'"`
+	StartRFC3339     string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-15m'). Defaults to 1 hour ago"`
+	EndRFC3339       string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Limit            int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of log lines to read (default: 10\\, max: 100)"`
+	MaxTraces        int    `json:"maxTraces,omitempty" jsonschema:"description=Optionally\\, the maximum number of trace IDs to return (default: 20\\, max: 100)"`
+	IncludeSummaries bool   `json:"includeSummaries,omitempty" jsonschema:"description=Optionally\\, fetch each trace from Tempo and include a summary with its root span\\, duration and error count"`
+	TempoUID         string `json:"tempoUid,omitempty" jsonschema:"description=Optionally\\, the UID of the Tempo datasource to fetch traces from. Defaults to the datasource linked by the Loki datasource's derived fields"`
+}
+
+// logTraceSummary summarizes a trace found in logs.
+type logTraceSummary struct {
+	RootService string  `json:"rootService"`
+	RootName    string  `json:"rootName"`
+	DurationMs  float64 `json:"durationMs"`
+	Spans       int     `json:"spans"`
+	ErrorSpans  int     `json:"errorSpans"`
+}
+
+// logTrace is a trace referenced by log lines.
+type logTrace struct {
+	TraceID string `json:"traceId"`
+	// Source is the derived field, label or "line" the trace ID was found in.
+	Source    string           `json:"source"`
+	LogLines  int              `json:"logLines"`
+	FirstSeen time.Time        `json:"firstSeen"`
+	LastSeen  time.Time        `json:"lastSeen"`
+	Summary   *logTraceSummary `json:"summary,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type tracesForLogs struct {
+	LogLines int `json:"logLines"`
+	// TempoUID is the Tempo datasource the traces are in, if known.
+	TempoUID string     `json:"tempoUid,omitempty"`
+	Traces   []logTrace `json:"traces"`
+}
+
+// summarizeTrace returns the root span, duration and error count of a trace.
+// The root span is the earliest span without a parent, or the earliest span
+// if the trace is incomplete.
+func summarizeTrace(spans []tempoSpan) *logTraceSummary {
+	summary := &logTraceSummary{Spans: len(spans)}
+	if len(spans) == 0 {
+		return summary
+	}
+	var root, earliest *tempoSpan
+	start, end := spans[0].Start, spans[0].Start.Add(spans[0].Duration)
+	for i, s := range spans {
+		if s.IsError {
+			summary.ErrorSpans++
+		}
+		if s.Start.Before(start) {
+			start = s.Start
+		}
+		if e := s.Start.Add(s.Duration); e.After(end) {
+			end = e
+		}
+		if earliest == nil || s.Start.Before(earliest.Start) {
+			earliest = &spans[i]
+		}
+		if s.ParentSpanID == "" && (root == nil || s.Start.Before(root.Start)) {
+			root = &spans[i]
+		}
+	}
+	if root == nil {
+		root = earliest
+	}
+	summary.RootService = root.ServiceName
+	summary.RootName = root.Name
+	summary.DurationMs = durationMs(end.Sub(start))
+	return summary
+}
+
+func findTracesForLogs(ctx context.Context, args FindTracesForLogsParams) (*tracesForLogs, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return nil, err
+	}
+	extractor := newTraceIDExtractor(ds.JSONData)
+
+	entries, err := queryLokiDatasourceLogs(ctx, args.DatasourceUID, QueryLokiLogsParams{
+		DatasourceUID: args.DatasourceUID,
+		LogQL:         args.LogQL,
+		StartRFC3339:  args.StartRFC3339,
+		EndRFC3339:    args.EndRFC3339,
+		Limit:         args.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortLogEntries(entries)
+
+	result := &tracesForLogs{LogLines: len(entries), TempoUID: stringOrDefault(args.TempoUID, extractor.tempoUID()), Traces: []logTrace{}}
+	maxTraces := enforceTraceLimit(ctx, args.MaxTraces)
+	index := map[string]int{}
+	for _, entry := range entries {
+		if entry.Value != nil {
+			return nil, fmt.Errorf("find_traces_for_logs requires a log query, not a metric query")
+		}
+		id, source := extractor.extract(entry)
+		if id == "" {
+			continue
+		}
+		ns, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		ts := time.Unix(0, ns).UTC()
+		key := strings.ToLower(id)
+		if i, ok := index[key]; ok {
+			result.Traces[i].LogLines++
+			result.Traces[i].LastSeen = ts
+			continue
+		}
+		if len(result.Traces) == maxTraces {
+			continue
+		}
+		index[key] = len(result.Traces)
+		result.Traces = append(result.Traces, logTrace{TraceID: id, Source: source, LogLines: 1, FirstSeen: ts, LastSeen: ts})
+	}
+
+	if !args.IncludeSummaries || len(result.Traces) == 0 {
+		return result, nil
+	}
+	if result.TempoUID == "" {
+		return nil, fmt.Errorf("tempoUid is required to include summaries, since no derived field of the Loki datasource links to a Tempo datasource")
+	}
+	client, err := newTempoClient(ctx, result.TempoUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+	ids := make([]string, 0, len(result.Traces))
+	for _, t := range result.Traces {
+		ids = append(ids, t.TraceID)
+	}
+	for i, t := range client.traces(ctx, ids) {
+		if t.Err != nil {
+			result.Traces[i].Error = t.Err.Error()
+			continue
+		}
+		result.Traces[i].Summary = summarizeTrace(t.Trace.spans())
+	}
+	return result, nil
+}
+
+var FindTracesForLogs = mcpgrafana.MustTool(
+	"find_traces_for_logs",
+	"Runs a LogQL log query and returns the distinct trace IDs referenced by the matching log lines, in the order they first appear, with how many lines reference each. Trace IDs are extracted using the Loki datasource's derived fields, falling back to trace ID labels or structured metadata (trace_id, traceID) and 'trace_id=...' in the line. Optionally fetches each trace from Tempo and summarizes its root span, duration and errors. Use get_tempo_traces_batch or generate_tempo_deeplink to dig into the traces found.",
+	guardTimeRange(findTracesForLogs),
+	mcp.WithTitleAnnotation("Find traces for logs"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
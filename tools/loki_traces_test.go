@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceIDExtractor(t *testing.T) {
+	extractor := newTraceIDExtractor(map[string]any{
+		"derivedFields": []map[string]any{
+			{"name": "Unlinked", "matcherRegex": "req=(\\w+)"},
+			{"name": "Invalid", "matcherRegex": "(", "datasourceUid": "tempo"},
+			{"name": "TraceID", "matcherRegex": "traceID=(\\w+)", "datasourceUid": "tempo"},
+			{"name": "SpanTrace", "matcherType": "label", "matcherRegex": "otel_trace", "datasourceUid": "tempo2"},
+		},
+	})
+	assert.Equal(t, "tempo", extractor.tempoUID())
+
+	for _, tc := range []struct {
+		name       string
+		entry      LogEntry
+		id, source string
+	}{
+		{"derived field", LogEntry{Line: "level=error req=r1 traceID=abc123"}, "abc123", "TraceID"},
+		{"derived label", LogEntry{Line: "boom", Labels: map[string]string{"otel_trace": "def456"}}, "def456", "SpanTrace"},
+		{"label", LogEntry{Line: "boom", Labels: map[string]string{"trace_id": "0af7651916cd43dd"}}, "0af7651916cd43dd", "trace_id"},
+		{"line", LogEntry{Line: `{"msg":"boom","traceId":"4bf92f3577b34da6a3ce929d0e0e4736"}`}, "4bf92f3577b34da6a3ce929d0e0e4736", "line"},
+		{"none", LogEntry{Line: "level=error req=r1"}, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			id, source := extractor.extract(tc.entry)
+			assert.Equal(t, tc.id, id)
+			assert.Equal(t, tc.source, source)
+		})
+	}
+
+	assert.Empty(t, newTraceIDExtractor(nil).tempoUID())
+}
+
+func TestSummarizeTrace(t *testing.T) {
+	var trace otlpTrace
+	require.NoError(t, json.Unmarshal([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, true)), &trace))
+	assert.Equal(t, &logTraceSummary{RootService: "frontend", RootName: "GET /", DurationMs: 100, Spans: 3, ErrorSpans: 1}, summarizeTrace(trace.spans()))
+	assert.Equal(t, &logTraceSummary{}, summarizeTrace(nil))
+}
+
+func TestFindTracesForLogs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources/uid/loki", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"loki","type":"loki","jsonData":{"derivedFields":[{"name":"TraceID","matcherRegex":"traceID=(\\w+)","datasourceUid":"tempo"}]}}`))
+	})
+	mux.HandleFunc("/api/datasources/uid/tempo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"tempo","type":"tempo"}`))
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/loki/loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{app="checkout"} |= "error"`, r.URL.Query().Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"app":"checkout"},"values":[
+				["%d","level=error traceID=ABC123 retry failed"],
+				["%d","level=error no trace"],
+				["%d","level=error traceID=abc123 connection refused"]
+			]},
+			{"stream":{"app":"checkout","trace_id":"def456"},"values":[
+				["%d","level=error timeout"]
+			]}
+		]}}`, start.Add(3*time.Minute).UnixNano(), start.Add(2*time.Minute).UnixNano(), start.Add(time.Minute).UnixNano(), start.Add(2*time.Minute).UnixNano())
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/tempo/api/traces/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/datasources/proxy/uid/tempo/api/traces/abc123":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, true)))
+		default:
+			http.Error(w, "trace not found", http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+	params := FindTracesForLogsParams{
+		DatasourceUID: "loki",
+		LogQL:         `{app="checkout"} |= "error"`,
+		StartRFC3339:  start.Format(time.RFC3339),
+		EndRFC3339:    start.Add(time.Hour).Format(time.RFC3339),
+		Limit:         100,
+	}
+
+	t.Run("trace IDs", func(t *testing.T) {
+		result, err := findTracesForLogs(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, 4, result.LogLines)
+		assert.Equal(t, "tempo", result.TempoUID)
+		assert.Equal(t, []logTrace{
+			{TraceID: "abc123", Source: "TraceID", LogLines: 2, FirstSeen: start.Add(time.Minute), LastSeen: start.Add(3 * time.Minute)},
+			{TraceID: "def456", Source: "trace_id", LogLines: 1, FirstSeen: start.Add(2 * time.Minute), LastSeen: start.Add(2 * time.Minute)},
+		}, result.Traces)
+	})
+
+	t.Run("max traces", func(t *testing.T) {
+		p := params
+		p.MaxTraces = 1
+		result, err := findTracesForLogs(ctx, p)
+		require.NoError(t, err)
+		require.Len(t, result.Traces, 1)
+		assert.Equal(t, "abc123", result.Traces[0].TraceID)
+	})
+
+	t.Run("summaries", func(t *testing.T) {
+		p := params
+		p.IncludeSummaries = true
+		result, err := findTracesForLogs(ctx, p)
+		require.NoError(t, err)
+		require.Len(t, result.Traces, 2)
+		assert.Equal(t, &logTraceSummary{RootService: "frontend", RootName: "GET /", DurationMs: 100, Spans: 3, ErrorSpans: 1}, result.Traces[0].Summary)
+		assert.Nil(t, result.Traces[1].Summary)
+		assert.Contains(t, result.Traces[1].Error, "trace not found")
+	})
+}
@@ -0,0 +1,574 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestQueryLokiLogsRelativeTimes(t *testing.T) {
+	var start, end int64
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		var err error
+		start, err = strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err = strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+	})
+
+	now := time.Now()
+	_, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+		DatasourceUID: "loki",
+		LogQL:         `{app="foo"}`,
+		StartRFC3339:  "now-15m",
+		EndRFC3339:    "now",
+	})
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-15*time.Minute), time.Unix(0, start), 5*time.Second)
+	assert.WithinDuration(t, now, time.Unix(0, end), 5*time.Second)
+
+	t.Run("RFC3339", func(t *testing.T) {
+		_, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			StartRFC3339:  "2024-01-01T00:00:00Z",
+			EndRFC3339:    "2024-01-01T01:00:00Z",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), start)
+		assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC).UnixNano(), end)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			StartRFC3339:  "yesterday-ish",
+		})
+		assert.ErrorContains(t, err, "parsing start time")
+	})
+
+	t.Run("Unix nanoseconds and date math", func(t *testing.T) {
+		// The range spans years, so keep it in one query.
+		ctx := withToolDefaults(ctx, map[string]mcpgrafana.ToolDefaults{"loki": {SplitInterval: 100 * 365 * 24 * time.Hour}})
+		_, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"}`,
+			StartRFC3339:  "1704067200000000000",
+			EndRFC3339:    "now/d",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), start)
+		y, m, d := time.Now().UTC().Date()
+		assert.Equal(t, time.Date(y, m, d, 0, 0, 0, 0, time.UTC).UnixNano(), end)
+	})
+}
+
+func TestListLokiLabelNamesRelativeTimes(t *testing.T) {
+	var start, end int64
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		start, err = strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err = strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":["app"]}`))
+	})
+
+	now := time.Now()
+	_, err := listLokiLabelNames(ctx, ListLokiLabelNamesParams{DatasourceUID: "loki", StartRFC3339: "now-6h"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-6*time.Hour), time.Unix(0, start), 5*time.Second)
+	assert.WithinDuration(t, now, time.Unix(0, end), 5*time.Second)
+
+	_, err = listLokiLabelValues(ctx, ListLokiLabelValuesParams{DatasourceUID: "loki", LabelName: "app", StartRFC3339: "2024-01-01T00:00:00Z", EndRFC3339: "2024-01-01T02:00:00+01:00"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), start)
+	assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC).UnixNano(), end)
+}
+
+func TestListLokiLabelsSelector(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{app="nginx"}`, r.URL.Query().Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/loki/api/v1/labels":
+			_, _ = w.Write([]byte(`{"status":"success","data":["app","pod"]}`))
+		case "/loki/api/v1/label/pod/values":
+			_, _ = w.Write([]byte(`{"status":"success","data":["nginx-1","nginx-2"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	names, err := listLokiLabelNames(ctx, ListLokiLabelNamesParams{DatasourceUID: "loki", Selector: `{app="nginx"}`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "pod"}, names)
+
+	values, err := listLokiLabelValues(ctx, ListLokiLabelValuesParams{DatasourceUID: "loki", LabelName: "pod", Selector: `{app="nginx"}`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nginx-1", "nginx-2"}, values)
+}
+
+func TestQueryLokiMetrics(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("query") == `{app="foo"}` {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+			return
+		}
+		assert.Equal(t, "30", r.URL.Query().Get("step"))
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"level":"error"},"values":[[1700000000,"0.5"],[1700000030,"1.25"]]}
+		]}}`))
+	})
+
+	matrix, err := queryLokiMetrics(ctx, QueryLokiMetricsParams{
+		DatasourceUID: "loki",
+		LogQL:         `sum by (level) (rate({app="foo"} |= "error" [5m]))`,
+		StartRFC3339:  "now-1h",
+		StepSeconds:   30,
+	})
+	require.NoError(t, err)
+	require.Len(t, matrix, 1)
+	assert.Equal(t, "error", string(matrix[0].Metric["level"]))
+	require.Len(t, matrix[0].Values, 2)
+	assert.Equal(t, 1.25, float64(matrix[0].Values[1].Value))
+	assert.Equal(t, int64(1700000030000), int64(matrix[0].Values[1].Timestamp))
+
+	t.Run("log query", func(t *testing.T) {
+		_, err := queryLokiMetrics(ctx, QueryLokiMetricsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`})
+		assert.ErrorContains(t, err, "use query_loki_logs")
+	})
+}
+
+func TestQueryLokiStatsVolume(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{env="prod"}`, r.URL.Query().Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/loki/api/v1/index/stats":
+			_, _ = w.Write([]byte(`{"streams":5,"chunks":50,"entries":10000,"bytes":512000}`))
+		case "/loki/api/v1/index/volume":
+			assert.Equal(t, "service_name", r.URL.Query().Get("targetLabels"))
+			assert.Equal(t, "labels", r.URL.Query().Get("aggregateBy"))
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"service_name":"checkout"},"value":[1700000000,"400000"]},
+				{"metric":{"service_name":"cart"},"value":[1700000000,"112000"]}
+			]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	result, err := queryLokiStats(ctx, QueryLokiStatsParams{DatasourceUID: "loki", LogQL: `{env="prod"}`})
+	require.NoError(t, err)
+	assert.Equal(t, 512000, result.Bytes)
+	assert.Nil(t, result.Volume)
+
+	result, err = queryLokiStats(ctx, QueryLokiStatsParams{DatasourceUID: "loki", LogQL: `{env="prod"}`, VolumeBy: []string{"service_name"}})
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Streams)
+	assert.Equal(t, []VolumeEntry{
+		{Labels: map[string]string{"service_name": "checkout"}, Bytes: 400000},
+		{Labels: map[string]string{"service_name": "cart"}, Bytes: 112000},
+	}, result.Volume)
+}
+
+func TestLokiStreamSelectors(t *testing.T) {
+	assert.Equal(t, []string{`{app="foo"}`}, lokiStreamSelectors(`sum(rate({app="foo"} |= "error" [5m]))`))
+	assert.Equal(t, []string{`{app="foo"}`, `{app="bar", env=~"prod|dev"}`},
+		lokiStreamSelectors(`count_over_time({app="foo"}[1m]) / count_over_time({app="bar", env=~"prod|dev"}[1m]) + count_over_time({app="foo"}[1m])`))
+	assert.Equal(t, []string{`{app="foo"}`}, lokiStreamSelectors(`{app="foo"} | line_format "{{.msg}} \"{x}\"" | label_format x=`+"`{{.y}}`"))
+	assert.Empty(t, lokiStreamSelectors(`vector(1)`))
+}
+
+func TestWithLokiMetadataFilters(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		logql    string
+		matchers []LabelMatcher
+		want     string
+	}{
+		{
+			name:     "log query",
+			logql:    `{service_name="checkout"} | json | level="error"`,
+			matchers: []LabelMatcher{{Name: "trace_id", Value: "4bf92f3577b34da6", Type: "="}, {Name: "severity_text", Value: "ERROR|FATAL", Type: "=~"}},
+			want:     `{service_name="checkout"} | trace_id="4bf92f3577b34da6" | severity_text=~"ERROR|FATAL" | json | level="error"`,
+		},
+		{
+			name:     "OTLP attribute names",
+			logql:    `{app="foo"}`,
+			matchers: []LabelMatcher{{Name: "k8s.pod.name", Value: `a"b`}},
+			want:     `{app="foo"} | k8s_pod_name="a\"b"`,
+		},
+		{
+			name:     "metric query",
+			logql:    `sum(rate({app="foo"} |= "x" [5m])) / sum(rate({app="bar"}[5m]))`,
+			matchers: []LabelMatcher{{Name: "severity_text", Value: "DEBUG", Type: "!="}},
+			want:     `sum(rate({app="foo"} | severity_text!="DEBUG" |= "x" [5m])) / sum(rate({app="bar"} | severity_text!="DEBUG"[5m]))`,
+		},
+		{
+			name:  "no matchers",
+			logql: `{app="foo"}`,
+			want:  `{app="foo"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := withLokiMetadataFilters(tc.logql, tc.matchers)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := withLokiMetadataFilters(`{app="foo"}`, []LabelMatcher{{Name: "trace_id", Value: "x", Type: "~"}})
+		assert.ErrorContains(t, err, "invalid matcher type")
+		_, err = withLokiMetadataFilters(`{app="foo"}`, []LabelMatcher{{Name: "1abc", Value: "x"}})
+		assert.ErrorContains(t, err, "invalid metadata name")
+		_, err = withLokiMetadataFilters(`vector(1)`, []LabelMatcher{{Name: "trace_id", Value: "x"}})
+		assert.ErrorContains(t, err, "stream selector")
+	})
+
+	t.Run("query tool", func(t *testing.T) {
+		var query string
+		ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query().Get("query")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+		})
+		_, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{
+			DatasourceUID: "loki",
+			LogQL:         `{app="foo"} |= "timeout"`,
+			Metadata:      []LabelMatcher{{Name: "trace_id", Value: "abc", Type: "="}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{app="foo"} | trace_id="abc" |= "timeout"`, query)
+	})
+}
+
+func TestEstimateLokiQuery(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/index/stats", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("query") {
+		case `{app="foo"}`:
+			_, _ = w.Write([]byte(`{"streams":2,"chunks":10,"entries":1000,"bytes":4096}`))
+		case `{app="bar"}`:
+			_, _ = w.Write([]byte(`{"streams":1,"chunks":5,"entries":100,"bytes":1024}`))
+		default:
+			t.Errorf("unexpected selector %q", r.URL.Query().Get("query"))
+		}
+	})
+
+	result, err := queryLokiMetricsTool(ctx, QueryLokiMetricsParams{
+		DatasourceUID: "loki",
+		LogQL:         `sum(rate({app="foo"} |= "error" [5m])) / sum(rate({app="bar"}[5m]))`,
+		EstimateOnly:  true,
+	})
+	require.NoError(t, err)
+	estimate, ok := unwrapResult(result).(*lokiQueryEstimate)
+	require.True(t, ok, "expected an estimate, got %T", result)
+	assert.Equal(t, Stats{Streams: 3, Chunks: 15, Entries: 1100, Bytes: 5120}, estimate.Total)
+	require.Len(t, estimate.Selectors, 2)
+	assert.Equal(t, `{app="bar"}`, estimate.Selectors[1].Selector)
+	assert.Equal(t, 1024, estimate.Selectors[1].Bytes)
+
+	_, err = queryLokiLogsTool(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `vector(1)`, EstimateOnly: true})
+	assert.ErrorContains(t, err, "no stream selector")
+}
+
+func TestGetLokiLogContext(t *testing.T) {
+	const ts = int64(1700000000000000000)
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		q := r.URL.Query()
+		assert.Equal(t, `{app="foo"}`, q.Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		switch q.Get("direction") {
+		case "backward":
+			assert.Equal(t, strconv.FormatInt(ts, 10), q.Get("end"))
+			assert.Equal(t, "2", q.Get("limit"))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["1699999999000000000","before 2"],["1699999998000000000","before 1"]]}
+			]}}`))
+		case "forward":
+			assert.Equal(t, strconv.FormatInt(ts, 10), q.Get("start"))
+			assert.Equal(t, "3", q.Get("limit"))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["1700000000000000000","other line"],["1700000000000000000","the error"],["1700000001000000000","after 1"]]}
+			]}}`))
+		}
+	})
+
+	result, err := getLokiLogContext(ctx, GetLokiLogContextParams{
+		DatasourceUID: "loki",
+		Selector:      `{app="foo"}`,
+		Timestamp:     strconv.FormatInt(ts, 10),
+		Line:          "the error",
+		Before:        2,
+		After:         2,
+	})
+	require.NoError(t, err)
+	lines := func(entries []LogEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Line)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"before 1", "before 2"}, lines(result.Before))
+	require.NotNil(t, result.Line)
+	assert.Equal(t, "the error", result.Line.Line)
+	assert.Equal(t, "1700000000000000000", result.Line.Timestamp)
+	assert.Equal(t, []string{"other line", "after 1"}, lines(result.After))
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		_, err := getLokiLogContext(ctx, GetLokiLogContextParams{DatasourceUID: "loki", Selector: `{app="foo"}`, Timestamp: "the day before"})
+		assert.ErrorContains(t, err, "parsing timestamp")
+	})
+}
+
+func TestTailLokiLogs(t *testing.T) {
+	defer func(d time.Duration) { lokiTailPollInterval = d }(lokiTailPollInterval)
+	lokiTailPollInterval = 10 * time.Millisecond
+	defer func(d time.Duration) { lokiTailLookback = d }(lokiTailLookback)
+	lokiTailLookback = 0
+
+	var polls int
+	var lastEnd string
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		q := r.URL.Query()
+		assert.Equal(t, `{app="foo"}`, q.Get("query"))
+		assert.Equal(t, "forward", q.Get("direction"))
+		if lastEnd != "" {
+			assert.Equal(t, lastEnd, q.Get("start"), "each poll should start where the previous one ended")
+		}
+		lastEnd = q.Get("end")
+		polls++
+		w.Header().Set("Content-Type", "application/json")
+		switch polls {
+		case 1:
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+		case 2:
+			assert.Equal(t, "3", q.Get("limit"))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["1700000001000000000","second"],["1700000000000000000","first"]]}
+			]}}`))
+		default:
+			assert.Equal(t, "1", q.Get("limit"))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["1700000002000000000","third"]]}
+			]}}`))
+		}
+	})
+
+	result, err := tailLokiLogs(ctx, TailLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, Limit: 3})
+	require.NoError(t, err)
+	assert.True(t, result.LimitReached)
+	require.Len(t, result.Entries, 3)
+	assert.Equal(t, "first", result.Entries[0].Line)
+	assert.Equal(t, "second", result.Entries[1].Line)
+	assert.Equal(t, "third", result.Entries[2].Line)
+	assert.Equal(t, 3, polls)
+}
+
+func TestTailLokiLogsLateLines(t *testing.T) {
+	defer func(d time.Duration) { lokiTailPollInterval = d }(lokiTailPollInterval)
+	lokiTailPollInterval = 10 * time.Millisecond
+
+	var polls int
+	var firstEnd int64
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		start, err := strconv.ParseInt(q.Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(q.Get("end"), 10, 64)
+		require.NoError(t, err)
+		polls++
+		early := strconv.FormatInt(start, 10)
+		w.Header().Set("Content-Type", "application/json")
+		switch polls {
+		case 1:
+			firstEnd = end
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["` + early + `","on time"]]}
+			]}}`))
+		default:
+			// A line timestamped within the first poll only reached Loki
+			// after it.
+			assert.Less(t, start, firstEnd, "polls should overlap")
+			late := strconv.FormatInt(firstEnd-1, 10)
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"foo"},"values":[["` + late + `","late"],["` + early + `","on time"]]}
+			]}}`))
+		}
+	})
+
+	result, err := tailLokiLogs(ctx, TailLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="foo"}`, Limit: 2})
+	require.NoError(t, err)
+	assert.True(t, result.LimitReached)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "on time", result.Entries[0].Line)
+	assert.Equal(t, "late", result.Entries[1].Line)
+	assert.Equal(t, 2, polls)
+}
+
+func TestQueryLokiPatterns(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/patterns", r.URL.Path)
+		assert.Equal(t, `{service_name="checkout"}`, r.URL.Query().Get("query"))
+		assert.NotEmpty(t, r.URL.Query().Get("start"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"pattern":"<_> level=info msg=\"request done\" <_>","level":"info","samples":[[1711839260,3],[1711839270,4]]},
+			{"pattern":"<_> level=error msg=\"failed to connect\" <_>","level":"error","samples":[[1711839260,20]]},
+			{"pattern":"<_> starting <_>","samples":[[1711839260,1]]}
+		]}`))
+	})
+
+	result, err := queryLokiPatterns(ctx, QueryLokiPatternsParams{DatasourceUID: "loki", Selector: `{service_name="checkout"}`, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalPatterns)
+	assert.Equal(t, []LokiPattern{
+		{Pattern: `<_> level=error msg="failed to connect" <_>`, Level: "error", Count: 20},
+		{Pattern: `<_> level=info msg="request done" <_>`, Level: "info", Count: 7},
+	}, result.Patterns)
+}
+
+func TestQueryLokiLogsSummarize(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"app":"checkout"},"values":[
+				["1711839290000000000","request 8f14e45f-ceea-467f-a0e9-6e8b3c5f2a1b failed after 120ms"],
+				["1711839280000000000","starting worker"],
+				["1711839270000000000","request 1c9b2d7e-3f4a-4b5c-8d6e-7f8a9b0c1d2e failed after 98ms"],
+				["1711839260000000000","request 1c9b2d7e-3f4a-4b5c-8d6e-7f8a9b0c1d2e failed after 98ms"]
+			]}
+		]}}`))
+	})
+
+	result, err := queryLokiLogsTool(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app="checkout"}`, Summarize: true})
+	require.NoError(t, err)
+	assert.Equal(t, []LokiLogMessage{
+		{
+			Message:    "request 8f14e45f-ceea-467f-a0e9-6e8b3c5f2a1b failed after 120ms",
+			Pattern:    "request <_> failed after <_>ms",
+			Count:      3,
+			Timestamps: []string{"1711839290000000000", "1711839270000000000", "1711839260000000000"},
+		},
+		{Message: "starting worker", Count: 1, Timestamps: []string{"1711839280000000000"}},
+	}, unwrapResult(result))
+
+	_, err = summarizeLogEntries([]LogEntry{{Timestamp: "1", Value: new(float64)}})
+	assert.Error(t, err)
+}
+
+func TestListLokiSeries(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/series", r.URL.Path)
+		assert.Equal(t, []string{`{app="nginx"}`, `{app="api"}`}, r.URL.Query()["match[]"])
+		assert.NotEmpty(t, r.URL.Query().Get("start"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"app":"nginx","pod":"nginx-2"},
+			{"app":"api","pod":"api-1"},
+			{"app":"nginx","pod":"nginx-1"}
+		]}`))
+	})
+
+	result, err := listLokiSeries(ctx, ListLokiSeriesParams{DatasourceUID: "loki", Match: []string{`{app="nginx"}`, `{app="api"}`}, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalSeries)
+	assert.Equal(t, []map[string]string{
+		{"app": "api", "pod": "api-1"},
+		{"app": "nginx", "pod": "nginx-1"},
+	}, result.Series)
+
+	_, err = listLokiSeries(ctx, ListLokiSeriesParams{DatasourceUID: "loki"})
+	assert.Error(t, err)
+}
+
+func TestQueryLokiLogsMultipleDatasources(t *testing.T) {
+	mux := http.NewServeMux()
+	for uid, values := range map[string]string{
+		"eu": `["1700000000000000003","eu newest"],["1700000000000000001","eu oldest"]`,
+		"us": `["1700000000000000002","us middle"]`,
+	} {
+		mux.HandleFunc("/api/datasources/uid/"+uid, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"uid":"` + uid + `","type":"loki"}`))
+		})
+		mux.HandleFunc("/api/datasources/proxy/uid/"+uid+"/loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `{app="checkout"}`, r.URL.Query().Get("query"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"app":"checkout"},"values":[` + values + `]}]}}`))
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+	entries, err := queryLokiLogs(ctx, QueryLokiLogsParams{DatasourceUID: "eu", DatasourceUIDs: []string{"us", "eu"}, LogQL: `{app="checkout"}`, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "eu newest", entries[0].Line)
+	assert.Equal(t, "eu", entries[0].Datasource)
+	assert.Equal(t, "us middle", entries[1].Line)
+	assert.Equal(t, "us", entries[1].Datasource)
+
+	_, err = queryLokiLogs(ctx, QueryLokiLogsParams{DatasourceUID: "eu", DatasourceUIDs: []string{"missing"}, LogQL: `{app="checkout"}`})
+	assert.ErrorContains(t, err, "querying datasource missing")
+}
+
+func TestQueryLokiInstant(t *testing.T) {
+	var resultType, result string
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/query", r.URL.Path)
+		assert.Equal(t, `sum(rate({app="foo"} |= "error" [5m]))`, r.URL.Query().Get("query"))
+		ts, err := strconv.ParseInt(r.URL.Query().Get("time"), 10, 64)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), time.Unix(0, ts), time.Minute)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"` + resultType + `","result":` + result + `}}`))
+	})
+	args := QueryLokiInstantParams{DatasourceUID: "loki", LogQL: `sum(rate({app="foo"} |= "error" [5m]))`, Time: "now-1h"}
+
+	resultType, result = "vector", `[{"metric":{"level":"error"},"value":[1711839260,"0.5"]}]`
+	vector, err := queryLokiInstant(ctx, args)
+	require.NoError(t, err)
+	require.Len(t, vector, 1)
+	assert.Equal(t, "error", string(vector[0].Metric["level"]))
+	assert.Equal(t, 0.5, float64(vector[0].Value))
+
+	resultType, result = "scalar", `[1711839260,"2"]`
+	vector, err = queryLokiInstant(ctx, args)
+	require.NoError(t, err)
+	require.Len(t, vector, 1)
+	assert.Equal(t, 2.0, float64(vector[0].Value))
+
+	resultType, result = "streams", `[]`
+	_, err = queryLokiInstant(ctx, args)
+	assert.ErrorContains(t, err, "use query_loki_logs")
+}
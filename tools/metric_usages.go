@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type FindMetricUsagesParams struct {
+	Metric         string   `json:"metric" jsonschema:"required,description=The name of the metric to find\\, e.g. 'http_requests_total'"`
+	DashboardUIDs  []string `json:"dashboardUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of the dashboards to search. Defaults to all dashboards"`
+	SkipAlertRules bool     `json:"skipAlertRules,omitempty" jsonschema:"description=Optionally\\, set to true to only search dashboards"`
+}
+
+// metricUsage is a dashboard panel query or alert rule query referencing a
+// metric.
+type metricUsage struct {
+	// Type is "dashboard" or "alertRule".
+	Type  string `json:"type"`
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	// Path is the JSON path of the query in the resource.
+	Path       string `json:"path"`
+	PanelID    int    `json:"panelId,omitempty"`
+	PanelTitle string `json:"panelTitle,omitempty"`
+	RefID      string `json:"refId,omitempty"`
+	Expr       string `json:"expr"`
+}
+
+type metricUsagesReport struct {
+	Metric            string        `json:"metric"`
+	DashboardsChecked int           `json:"dashboardsChecked"`
+	AlertRulesChecked int           `json:"alertRulesChecked"`
+	Usages            []metricUsage `json:"usages"`
+	Errors            []string      `json:"errors,omitempty"`
+}
+
+// queryUsesMetric reports whether a query references a metric. Valid PromQL
+// is checked by its series selectors, including those matching `__name__`.
+// Other queries, such as those which don't parse, are checked for the metric
+// name as an identifier outside of strings.
+func queryUsesMetric(expr, metric string) bool {
+	if parsed, err := parsePromQL(expr); err == nil {
+		for _, s := range promqlSelectors(parsed) {
+			if s.Metric == metric {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range tokenizeQuery(expr) {
+		if t.kind == tokenIdent && t.text == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// metricUsagesInDashboard returns the panel queries of the dashboard which
+// reference the metric. Queries of datasources other than Prometheus are
+// ignored.
+func metricUsagesInDashboard(db map[string]any, metric string) []metricUsage {
+	var usages []metricUsage
+	walkDashboardTargets(db, func(panel, target map[string]any, path string) {
+		expr, _ := target["expr"].(string)
+		if expr == "" || !queryUsesMetric(expr, metric) {
+			return
+		}
+		if ds := targetDatasource(panel, target); ds.Type != "" && ds.Type != "prometheus" {
+			return
+		}
+		title, _ := panel["title"].(string)
+		id, _ := panel["id"].(float64)
+		refID, _ := target["refId"].(string)
+		usages = append(usages, metricUsage{
+			Type:       resourceTypeDashboard,
+			Path:       path + ".expr",
+			PanelID:    int(id),
+			PanelTitle: title,
+			RefID:      refID,
+			Expr:       expr,
+		})
+	})
+	return usages
+}
+
+// metricUsagesInAlertRule returns the queries of the alert rule which
+// reference the metric. Server-side expressions are ignored.
+func metricUsagesInAlertRule(rule map[string]any, metric string) []metricUsage {
+	var usages []metricUsage
+	data, _ := rule["data"].([]any)
+	for i, d := range data {
+		query, ok := d.(map[string]any)
+		if !ok || query["datasourceUid"] == "__expr__" {
+			continue
+		}
+		queryModel, _ := query["model"].(map[string]any)
+		expr, _ := queryModel["expr"].(string)
+		if expr == "" || !queryUsesMetric(expr, metric) {
+			continue
+		}
+		refID, _ := query["refId"].(string)
+		usages = append(usages, metricUsage{
+			Type:  resourceTypeAlertRule,
+			Path:  fmt.Sprintf("data[%d].model.expr", i),
+			RefID: refID,
+			Expr:  expr,
+		})
+	}
+	return usages
+}
+
+func findMetricUsages(ctx context.Context, args FindMetricUsagesParams) (*metricUsagesReport, error) {
+	if args.Metric == "" {
+		return nil, fmt.Errorf("metric is required")
+	}
+	if !model.IsValidLegacyMetricName(args.Metric) {
+		return nil, fmt.Errorf("invalid metric name %q", args.Metric)
+	}
+
+	uids := args.DashboardUIDs
+	if len(uids) == 0 {
+		hits, err := searchAllResources(ctx, dashboardTypeStr, resourceTypeDashboard)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			uids = append(uids, h.UID)
+		}
+	}
+
+	report := &metricUsagesReport{Metric: args.Metric, Usages: []metricUsage{}}
+	for _, uid := range uids {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		db, ok := dashboard.Dashboard.(map[string]any)
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("dashboard %s is not a JSON object", uid))
+			continue
+		}
+		report.DashboardsChecked++
+		title, _ := db["title"].(string)
+		for _, u := range metricUsagesInDashboard(db, args.Metric) {
+			u.UID, u.Title = uid, title
+			report.Usages = append(report.Usages, u)
+		}
+	}
+
+	if args.SkipAlertRules {
+		return report, nil
+	}
+	rules, err := provisionedAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		m, err := toJSONMap(rule)
+		if err != nil {
+			return nil, fmt.Errorf("marshal alert rule %s: %w", rule.UID, err)
+		}
+		report.AlertRulesChecked++
+		for _, u := range metricUsagesInAlertRule(m, args.Metric) {
+			u.UID = rule.UID
+			if rule.Title != nil {
+				u.Title = *rule.Title
+			}
+			report.Usages = append(report.Usages, u)
+		}
+	}
+	return report, nil
+}
+
+var FindMetricUsages = mcpgrafana.MustTool(
+	"find_metric_usages",
+	"Finds where a Prometheus metric is used: every dashboard panel query and Grafana-managed alert rule query which references the metric, across the given dashboards (or all dashboards). Queries are parsed, so only selectors of the metric match, not other metrics sharing a prefix or strings containing its name. Returns each usage with the dashboard or alert rule, the panel and the query. Use this before renaming or deprecating a metric to see what would break, and `rename_label` or `find_broken_panels` to fix or check the queries afterwards.",
+	findMetricUsages,
+	mcp.WithTitleAnnotation("Find metric usages"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
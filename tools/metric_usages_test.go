@@ -0,0 +1,67 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryUsesMetric(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{`http_requests_total`, true},
+		{`sum by (job) (rate(http_requests_total{job="api"}[$__rate_interval]))`, true},
+		{`{__name__="http_requests_total", job="api"}`, true},
+		{`rate(http_requests_total_created[5m])`, false},
+		{`up{job="http_requests_total"}`, false},
+		{`{__name__=~"http_.*"}`, false},
+		// Unparseable queries fall back to matching identifiers.
+		{`rate(http_requests_total[5m]`, true},
+		{`rate(other[5m]`, false},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			assert.Equal(t, tc.expected, queryUsesMetric(tc.expr, "http_requests_total"))
+		})
+	}
+}
+
+func TestMetricUsagesInDashboard(t *testing.T) {
+	var db map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"panels": [
+			{"id": 1, "title": "Requests", "datasource": {"type": "prometheus", "uid": "prom"}, "targets": [
+				{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"},
+				{"refId": "B", "expr": "up"}
+			]},
+			{"id": 2, "datasource": {"type": "loki", "uid": "loki"}, "targets": [{"expr": "{app=\"http_requests_total\"}"}]},
+			{"type": "row", "panels": [{"id": 4, "title": "Nested", "targets": [{"refId": "A", "expr": "http_requests_total"}]}]}
+		]
+	}`), &db))
+
+	assert.Equal(t, []metricUsage{
+		{Type: "dashboard", Path: "panels[0].targets[0].expr", PanelID: 1, PanelTitle: "Requests", RefID: "A", Expr: "sum(rate(http_requests_total[5m]))"},
+		{Type: "dashboard", Path: "panels[2].panels[0].targets[0].expr", PanelID: 4, PanelTitle: "Nested", RefID: "A", Expr: "http_requests_total"},
+	}, metricUsagesInDashboard(db, "http_requests_total"))
+}
+
+func TestMetricUsagesInAlertRule(t *testing.T) {
+	var rule map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"data": [
+			{"refId": "A", "datasourceUid": "prom", "model": {"expr": "rate(http_requests_total{code=~\"5..\"}[5m])"}},
+			{"refId": "B", "datasourceUid": "__expr__", "model": {"expression": "A", "type": "reduce"}}
+		]
+	}`), &rule))
+
+	assert.Equal(t, []metricUsage{
+		{Type: "alertRule", Path: "data[0].model.expr", RefID: "A", Expr: `rate(http_requests_total{code=~"5.."}[5m])`},
+	}, metricUsagesInAlertRule(rule, "http_requests_total"))
+	assert.Empty(t, metricUsagesInAlertRule(rule, "up"))
+}
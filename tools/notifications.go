@@ -0,0 +1,279 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const receiversTestPath = "/api/alertmanager/grafana/config/api/v1/receivers/test"
+
+// NotificationConfig configures where send_notification delivers
+// notifications. Each configured sink receives every notification unless the
+// caller picks one.
+type NotificationConfig struct {
+	// SlackWebhookURL is the URL of a Slack incoming webhook.
+	SlackWebhookURL string
+	// WebhookURL is the URL notifications are POSTed to as JSON.
+	WebhookURL string
+	// EmailAddresses are sent notifications by email, using Grafana's SMTP
+	// settings.
+	EmailAddresses []string
+}
+
+// Enabled reports whether any notification sink is configured.
+func (c NotificationConfig) Enabled() bool {
+	return c.SlackWebhookURL != "" || c.WebhookURL != "" || len(c.EmailAddresses) > 0
+}
+
+// notification is a message sent to a sink.
+type notification struct {
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Severity string    `json:"severity"`
+	Links    []string  `json:"links,omitempty"`
+	Source   string    `json:"source"`
+	Time     time.Time `json:"time"`
+}
+
+// text renders the notification as plain text, with its links on separate
+// lines.
+func (n notification) text() string {
+	var b strings.Builder
+	b.WriteString(n.Message)
+	for _, link := range n.Links {
+		b.WriteString("\n" + link)
+	}
+	return b.String()
+}
+
+// notificationSink delivers notifications somewhere durable.
+type notificationSink interface {
+	name() string
+	send(ctx context.Context, n notification) error
+}
+
+// slackSink posts notifications to a Slack incoming webhook.
+type slackSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (s slackSink) name() string { return "slack" }
+
+func (s slackSink) send(ctx context.Context, n notification) error {
+	return postJSON(ctx, s.httpClient, s.url, map[string]string{
+		"text": fmt.Sprintf("*[%s] %s*\n%s", strings.ToUpper(n.Severity), n.Title, n.text()),
+	})
+}
+
+// webhookSink posts notifications as JSON to any URL.
+type webhookSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (s webhookSink) name() string { return "webhook" }
+
+func (s webhookSink) send(ctx context.Context, n notification) error {
+	return postJSON(ctx, s.httpClient, s.url, n)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshalling notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// emailSink sends notifications by email through Grafana's receiver testing
+// API, so they use the SMTP server Grafana is configured with.
+type emailSink struct {
+	addresses []string
+}
+
+func (s emailSink) name() string { return "email" }
+
+// testReceiversResponse is the part of Grafana's receiver test response
+// reporting whether each integration succeeded.
+type testReceiversResponse struct {
+	Receivers []struct {
+		Configs []struct {
+			Status string `json:"status"`
+			Error  string `json:"error,omitempty"`
+		} `json:"grafana_managed_receiver_configs"`
+	} `json:"receivers"`
+}
+
+func (s emailSink) send(ctx context.Context, n notification) error {
+	body, err := json.Marshal(map[string]any{
+		"receivers": []map[string]any{{
+			"name": "mcp-grafana",
+			"grafana_managed_receiver_configs": []map[string]any{{
+				"name": "mcp-grafana",
+				"type": "email",
+				"settings": map[string]any{
+					"addresses":   strings.Join(s.addresses, ";"),
+					"singleEmail": true,
+					"subject":     fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity), n.Title),
+					"message":     n.text(),
+				},
+			}},
+		}},
+		"alert": map[string]any{
+			"labels":      map[string]string{"alertname": n.Title, "severity": n.Severity},
+			"annotations": map[string]string{"summary": n.Title, "description": n.Message},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshalling notification: %w", err)
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := client.do(ctx, http.MethodPost, receiversTestPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending email through Grafana: %w", err)
+	}
+	defer resp.Body.Close()
+	var result testReceiversResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding response from %s: %w", receiversTestPath, err)
+	}
+	for _, r := range result.Receivers {
+		for _, c := range r.Configs {
+			if c.Status == "failed" {
+				return fmt.Errorf("sending email through Grafana: %s", c.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func newNotificationSinks(cfg NotificationConfig) []notificationSink {
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	var sinks []notificationSink
+	if cfg.SlackWebhookURL != "" {
+		sinks = append(sinks, slackSink{url: cfg.SlackWebhookURL, httpClient: httpClient})
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, webhookSink{url: cfg.WebhookURL, httpClient: httpClient})
+	}
+	if len(cfg.EmailAddresses) > 0 {
+		sinks = append(sinks, emailSink{addresses: cfg.EmailAddresses})
+	}
+	return sinks
+}
+
+type SendNotificationParams struct {
+	Title    string   `json:"title" jsonschema:"required,description=A short title for the notification\\, e.g. 'Checkout error rate doubled after deploy 1.42'"`
+	Message  string   `json:"message" jsonschema:"required,description=The findings to deliver. Include the evidence and next steps\\, since the reader won't see the conversation"`
+	Severity string   `json:"severity,omitempty" jsonschema:"enum=info,enum=warning,enum=critical,description=Optionally\\, the severity of the notification (default: info)"`
+	Links    []string `json:"links,omitempty" jsonschema:"description=Optionally\\, links to include\\, such as dashboards or Explore URLs"`
+	Sink     string   `json:"sink,omitempty" jsonschema:"enum=slack,enum=webhook,enum=email,description=Optionally\\, the sink to send to. Defaults to all configured sinks"`
+}
+
+// notificationDelivery reports whether a notification reached a sink.
+type notificationDelivery struct {
+	Sink  string `json:"sink"`
+	Sent  bool   `json:"sent"`
+	Error string `json:"error,omitempty"`
+}
+
+func sendNotification(ctx context.Context, sinks []notificationSink, args SendNotificationParams) ([]notificationDelivery, error) {
+	if strings.TrimSpace(args.Title) == "" || strings.TrimSpace(args.Message) == "" {
+		return nil, fmt.Errorf("title and message are required")
+	}
+	severity := stringOrDefault(args.Severity, "info")
+	switch severity {
+	case "info", "warning", "critical":
+	default:
+		return nil, fmt.Errorf("invalid severity %q: must be info, warning or critical", severity)
+	}
+
+	targets := sinks
+	if args.Sink != "" {
+		targets = nil
+		names := make([]string, 0, len(sinks))
+		for _, s := range sinks {
+			names = append(names, s.name())
+			if s.name() == args.Sink {
+				targets = append(targets, s)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("sink %q is not configured; configured sinks: %s", args.Sink, strings.Join(names, ", "))
+		}
+	}
+
+	n := notification{
+		Title:    args.Title,
+		Message:  args.Message,
+		Severity: severity,
+		Links:    args.Links,
+		Source:   "mcp-grafana",
+		Time:     time.Now().UTC(),
+	}
+	deliveries := make([]notificationDelivery, 0, len(targets))
+	sent := false
+	for _, s := range targets {
+		d := notificationDelivery{Sink: s.name(), Sent: true}
+		if err := s.send(ctx, n); err != nil {
+			d.Sent, d.Error = false, err.Error()
+		}
+		sent = sent || d.Sent
+		deliveries = append(deliveries, d)
+	}
+	if !sent {
+		errs := make([]string, 0, len(deliveries))
+		for _, d := range deliveries {
+			errs = append(errs, d.Sink+": "+d.Error)
+		}
+		return nil, fmt.Errorf("notification was not delivered: %s", strings.Join(errs, "; "))
+	}
+	return deliveries, nil
+}
+
+// AddNotificationTools registers the send_notification tool, delivering to
+// the configured sinks.
+func AddNotificationTools(s *server.MCPServer, cfg NotificationConfig) {
+	sinks := newNotificationSinks(cfg)
+	tool := mcpgrafana.MustTool(
+		"send_notification",
+		"Sends a notification with a title, message and optional links to the sinks configured for this server, such as a Slack channel, a webhook or email addresses. Use this to deliver findings, such as the summary of an investigation, somewhere durable. Only send a notification when the user asks for one. Returns whether each sink received the notification.",
+		func(ctx context.Context, args SendNotificationParams) ([]notificationDelivery, error) {
+			return sendNotification(ctx, sinks, args)
+		},
+		mcp.WithTitleAnnotation("Send notification"),
+		mcp.WithIdempotentHintAnnotation(false),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(false),
+	)
+	tool.Register(s)
+}
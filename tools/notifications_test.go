@@ -0,0 +1,106 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendNotification(t *testing.T) {
+	var slack map[string]string
+	var webhook notification
+	var email map[string]any
+	emailStatus := "ok"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&slack))
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&webhook))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	})
+	mux.HandleFunc(receiversTestPath, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&email))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"receivers":[{"name":"mcp-grafana","grafana_managed_receiver_configs":[{"name":"mcp-grafana","status":"` + emailStatus + `","error":"dial tcp: connection refused"}]}]}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL, APIKey: "test-api-key"})
+
+	sinks := newNotificationSinks(NotificationConfig{
+		SlackWebhookURL: server.URL + "/slack",
+		WebhookURL:      server.URL + "/webhook",
+		EmailAddresses:  []string{"oncall@example.com", "sre@example.com"},
+	})
+	args := SendNotificationParams{
+		Title:    "Checkout errors after deploy",
+		Message:  "Error rate doubled at 12:05.",
+		Severity: "warning",
+		Links:    []string{"https://grafana.example.com/d/checkout"},
+	}
+
+	deliveries, err := sendNotification(ctx, sinks, args)
+	require.NoError(t, err)
+	assert.Equal(t, []notificationDelivery{{Sink: "slack", Sent: true}, {Sink: "webhook", Sent: true}, {Sink: "email", Sent: true}}, deliveries)
+
+	assert.Equal(t, "*[WARNING] Checkout errors after deploy*\nError rate doubled at 12:05.\nhttps://grafana.example.com/d/checkout", slack["text"])
+	assert.Equal(t, "Checkout errors after deploy", webhook.Title)
+	assert.Equal(t, "warning", webhook.Severity)
+	assert.Equal(t, "mcp-grafana", webhook.Source)
+	assert.Equal(t, args.Links, webhook.Links)
+	settings := email["receivers"].([]any)[0].(map[string]any)["grafana_managed_receiver_configs"].([]any)[0].(map[string]any)["settings"].(map[string]any)
+	assert.Equal(t, "oncall@example.com;sre@example.com", settings["addresses"])
+	assert.Equal(t, "[WARNING] Checkout errors after deploy", settings["subject"])
+
+	t.Run("one sink", func(t *testing.T) {
+		deliveries, err := sendNotification(ctx, sinks, SendNotificationParams{Title: "t", Message: "m", Sink: "webhook"})
+		require.NoError(t, err)
+		assert.Equal(t, []notificationDelivery{{Sink: "webhook", Sent: true}}, deliveries)
+		assert.Equal(t, "info", webhook.Severity)
+	})
+
+	t.Run("unconfigured sink", func(t *testing.T) {
+		_, err := sendNotification(ctx, sinks[:1], SendNotificationParams{Title: "t", Message: "m", Sink: "email"})
+		assert.ErrorContains(t, err, `sink "email" is not configured; configured sinks: slack`)
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		emailStatus = "failed"
+		t.Cleanup(func() { emailStatus = "ok" })
+		deliveries, err := sendNotification(ctx, sinks, SendNotificationParams{Title: "t", Message: "m"})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 3)
+		assert.False(t, deliveries[2].Sent)
+		assert.Contains(t, deliveries[2].Error, "connection refused")
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		broken := newNotificationSinks(NotificationConfig{WebhookURL: server.URL + "/broken"})
+		_, err := sendNotification(ctx, broken, SendNotificationParams{Title: "t", Message: "m"})
+		assert.ErrorContains(t, err, "webhook: webhook returned status code 404")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := sendNotification(ctx, sinks, SendNotificationParams{Title: "t"})
+		assert.ErrorContains(t, err, "title and message are required")
+		_, err = sendNotification(ctx, sinks, SendNotificationParams{Title: "t", Message: "m", Severity: "page"})
+		assert.ErrorContains(t, err, "invalid severity")
+	})
+}
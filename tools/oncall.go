@@ -139,6 +139,9 @@ func listOnCallSchedules(ctx context.Context, args ListOnCallSchedulesParams) ([
 	}
 
 	if args.ScheduleID != "" {
+		if err := validatePathSegment("schedule ID", args.ScheduleID); err != nil {
+			return nil, err
+		}
 		schedule, _, err := scheduleService.GetSchedule(args.ScheduleID, &aapi.GetScheduleOptions{})
 		if err != nil {
 			return nil, fmt.Errorf("getting OnCall schedule %s: %w", args.ScheduleID, err)
@@ -205,6 +208,9 @@ func getOnCallShift(ctx context.Context, args GetOnCallShiftParams) (*aapi.OnCal
 		return nil, fmt.Errorf("getting OnCall shift service: %w", err)
 	}
 
+	if err := validatePathSegment("shift ID", args.ShiftID); err != nil {
+		return nil, err
+	}
 	shift, _, err := shiftService.GetOnCallShift(args.ShiftID, &aapi.GetOnCallShiftOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("getting OnCall shift %s: %w", args.ShiftID, err)
@@ -239,6 +245,9 @@ func getCurrentOnCallUsers(ctx context.Context, args GetCurrentOnCallUsersParams
 		return nil, fmt.Errorf("getting OnCall schedule service: %w", err)
 	}
 
+	if err := validatePathSegment("schedule ID", args.ScheduleID); err != nil {
+		return nil, err
+	}
 	schedule, _, err := scheduleService.GetSchedule(args.ScheduleID, &aapi.GetScheduleOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("getting schedule %s: %w", args.ScheduleID, err)
@@ -330,6 +339,9 @@ func listOnCallUsers(ctx context.Context, args ListOnCallUsersParams) ([]*aapi.U
 	}
 
 	if args.UserID != "" {
+		if err := validatePathSegment("user ID", args.UserID); err != nil {
+			return nil, err
+		}
 		user, _, err := userService.GetUser(args.UserID, &aapi.GetUserOptions{})
 		if err != nil {
 			return nil, fmt.Errorf("getting OnCall user %s: %w", args.UserID, err)
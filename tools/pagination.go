@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// pageTokenTTL is how long the remaining pages of a result are kept.
+	pageTokenTTL = 10 * time.Minute
+
+	// maxStoredResults limits how many paginated results are kept at once.
+	// The result closest to expiry is dropped to make room.
+	maxStoredResults = 100
+)
+
+// resultPage is a page of a tool's list result. NextPageToken is omitted on
+// the last page.
+type resultPage[T any] struct {
+	Items         []T    `json:"items" jsonschema:"required"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// paginated is the result of withPagination: all the items, or a page of
+// them when a page size or token is set.
+type paginated[T any] struct{ resultValue }
+
+func (paginated[T]) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[[]T](), reflect.TypeFor[*resultPage[T]]()}
+}
+
+// storedResult is the remainder of a paginated result, waiting to be fetched.
+type storedResult struct {
+	tool  string
+	scope string
+	// items is a slice of the result's item type.
+	items    any
+	pageSize int
+	expires  time.Time
+}
+
+// pageStore keeps the remaining items of paginated results in memory, keyed
+// by page token.
+type pageStore struct {
+	mu      sync.Mutex
+	results map[string]storedResult
+}
+
+var pages = &pageStore{results: map[string]storedResult{}}
+
+// storePage returns the first pageSize items, storing the rest in s under a
+// new token.
+func storePage[T any](ctx context.Context, s *pageStore, tool string, items []T, pageSize int) (*resultPage[T], error) {
+	if len(items) <= pageSize {
+		return &resultPage[T]{Items: items}, nil
+	}
+	token, err := newPageToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, r := range s.results {
+		if !now.Before(r.expires) {
+			delete(s.results, k)
+		}
+	}
+	for len(s.results) >= maxStoredResults {
+		var oldest string
+		for k, r := range s.results {
+			if oldest == "" || r.expires.Before(s.results[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(s.results, oldest)
+	}
+	s.results[token] = storedResult{
+		tool:     tool,
+		scope:    cacheKey(ctx, tool),
+		items:    items[pageSize:],
+		pageSize: pageSize,
+		expires:  now.Add(pageTokenTTL),
+	}
+	return &resultPage[T]{Items: items[:pageSize], NextPageToken: token}, nil
+}
+
+// nextPage returns the page stored in s under a token, moving the rest of the
+// result to a new token.
+func nextPage[T any](ctx context.Context, s *pageStore, tool, token string) (*resultPage[T], error) {
+	s.mu.Lock()
+	r, ok := s.results[token]
+	delete(s.results, token)
+	s.mu.Unlock()
+	items, isT := r.items.([]T)
+	if !ok || !isT || !time.Now().Before(r.expires) || r.tool != tool || r.scope != cacheKey(ctx, tool) {
+		return nil, fmt.Errorf("invalid or expired page token, run the query again without a pageToken")
+	}
+	return storePage(ctx, s, tool, items, r.pageSize)
+}
+
+func newPageToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating page token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withPagination runs fn and, if pageSize is set, returns the first page of
+// its items with a nextPageToken for the rest. If pageToken is set, fn is not
+// run and the next page of the earlier result is returned instead. If neither
+// is set the items are returned unchanged.
+func withPagination[T any](ctx context.Context, tool string, pageSize int, pageToken string, fn func(context.Context) ([]T, error)) (paginated[T], error) {
+	if pageToken != "" {
+		return asResult[paginated[T]](nextPage[T](ctx, pages, tool, pageToken))
+	}
+	if pageSize < 0 {
+		return paginated[T]{}, fmt.Errorf("invalid pageSize: %d, must be greater than 0", pageSize)
+	}
+	items, err := fn(ctx)
+	if err != nil || pageSize == 0 {
+		return asResult[paginated[T]](items, err)
+	}
+	return asResult[paginated[T]](storePage(ctx, pages, tool, items, pageSize))
+}
@@ -0,0 +1,83 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestWithPagination(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	fetch := func(context.Context) ([]int, error) {
+		calls++
+		return []int{1, 2, 3, 4, 5}, nil
+	}
+
+	t.Run("without page size", func(t *testing.T) {
+		result, err := withPagination(ctx, "test", 0, "", fetch)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, unwrapResult(result))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		calls = 0
+		result, err := withPagination(ctx, "test", 2, "", fetch)
+		require.NoError(t, err)
+		page := unwrapResult(result).(*resultPage[int])
+		assert.Equal(t, []int{1, 2}, page.Items)
+		require.NotEmpty(t, page.NextPageToken)
+
+		result, err = withPagination(ctx, "test", 2, page.NextPageToken, fetch)
+		require.NoError(t, err)
+		next := unwrapResult(result).(*resultPage[int])
+		assert.Equal(t, []int{3, 4}, next.Items)
+		require.NotEmpty(t, next.NextPageToken)
+
+		result, err = withPagination(ctx, "test", 2, next.NextPageToken, fetch)
+		require.NoError(t, err)
+		last := unwrapResult(result).(*resultPage[int])
+		assert.Equal(t, []int{5}, last.Items)
+		assert.Empty(t, last.NextPageToken)
+		assert.Equal(t, 1, calls, "later pages should not re-run the query")
+
+		_, err = withPagination(ctx, "test", 2, page.NextPageToken, fetch)
+		assert.ErrorContains(t, err, "invalid or expired page token")
+	})
+
+	t.Run("single page", func(t *testing.T) {
+		result, err := withPagination(ctx, "test", 10, "", fetch)
+		require.NoError(t, err)
+		page := unwrapResult(result).(*resultPage[int])
+		assert.Len(t, page.Items, 5)
+		assert.Empty(t, page.NextPageToken)
+	})
+
+	t.Run("token scoped to tool and credentials", func(t *testing.T) {
+		result, err := withPagination(ctx, "test", 2, "", fetch)
+		require.NoError(t, err)
+		token := unwrapResult(result).(*resultPage[int]).NextPageToken
+
+		_, err = withPagination(ctx, "other", 2, token, fetch)
+		assert.Error(t, err)
+
+		result, err = withPagination(ctx, "test", 2, "", fetch)
+		require.NoError(t, err)
+		token = unwrapResult(result).(*resultPage[int]).NextPageToken
+		other := mcpgrafana.WithGrafanaConfig(ctx, mcpgrafana.GrafanaConfig{APIKey: "other"})
+		_, err = withPagination(other, "test", 2, token, fetch)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid page size", func(t *testing.T) {
+		_, err := withPagination(ctx, "test", -1, "", fetch)
+		assert.Error(t, err)
+	})
+}
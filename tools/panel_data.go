@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// DefaultPanelDataRows is the number of rows query_panel_data returns per
+// frame unless told otherwise.
+const DefaultPanelDataRows = 100
+
+// expressionDatasourceUID is the UID of server-side expressions, which
+// compute queries from the results of other queries.
+const expressionDatasourceUID = "__expr__"
+
+type QueryPanelDataParams struct {
+	DashboardUID  string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelID       int               `json:"panelId" jsonschema:"required,description=The ID of the panel\\, including panels in collapsed rows"`
+	Variables     map[string]string `json:"variables,omitempty" jsonschema:"description=Optionally\\, values to select for template variables by name\\, instead of their saved values\\, e.g. {'namespace': 'prod'}. Use '$__all' to select All"`
+	StartTime     string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range\\, in RFC3339\\, Unix time or relative time such as 'now-6h'. Defaults to the dashboard's time range"`
+	EndTime       string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range. Defaults to the dashboard's time range"`
+	MaxDataPoints int               `json:"maxDataPoints,omitempty" jsonschema:"description=Optionally\\, the maximum number of points per series\\, from which the query interval is chosen (default: the panel's maxDataPoints or 100)"`
+	MaxRows       int               `json:"maxRows,omitempty" jsonschema:"description=Optionally\\, the maximum number of rows returned per frame (default: 100)"`
+}
+
+type panelDataField struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Unit   string            `json:"unit,omitempty"`
+	// Values holds the field's values, with times in RFC3339.
+	Values []any `json:"values"`
+}
+
+type panelDataFrame struct {
+	Name   string           `json:"name,omitempty"`
+	Fields []panelDataField `json:"fields"`
+	Rows   int              `json:"rows"`
+	// Truncated is set when only the first maxRows rows are returned.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type panelQueryData struct {
+	RefID      string         `json:"refId"`
+	Datasource datasourceInfo `json:"datasource"`
+	// Query is the query with template variables replaced.
+	Query  string           `json:"query,omitempty"`
+	Error  string           `json:"error,omitempty"`
+	Frames []panelDataFrame `json:"frames"`
+}
+
+type panelData struct {
+	PanelID    int                 `json:"panelId"`
+	PanelTitle string              `json:"panelTitle"`
+	Start      time.Time           `json:"start"`
+	End        time.Time           `json:"end"`
+	IntervalMs int64               `json:"intervalMs"`
+	Variables  map[string][]string `json:"variables,omitempty"`
+	Queries    []panelQueryData    `json:"queries"`
+}
+
+// dataFrame is a data frame as returned by /api/ds/query, with its values
+// stored by column.
+type dataFrame struct {
+	Schema struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			Name   string            `json:"name"`
+			Type   string            `json:"type"`
+			Labels map[string]string `json:"labels"`
+			Config struct {
+				Unit string `json:"unit"`
+			} `json:"config"`
+		} `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values [][]any `json:"values"`
+	} `json:"data"`
+}
+
+type dsQueryResponse struct {
+	Results map[string]struct {
+		Error  string      `json:"error"`
+		Frames []dataFrame `json:"frames"`
+	} `json:"results"`
+}
+
+// interpolateTarget copies a query model, replacing template variables in
+// all of its strings.
+func interpolateTarget(r *variableResolver, v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.interpolate(v)
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = interpolateTarget(r, item)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, item := range v {
+			s[i] = interpolateTarget(r, item)
+		}
+		return s
+	}
+	return v
+}
+
+// panelQueryInterval returns the interval of a panel's queries: the round
+// step returning at most maxDataPoints points, or the panel's minimum
+// interval if that is longer.
+func panelQueryInterval(ctx context.Context, r *variableResolver, panel map[string]any, maxDataPoints int) time.Duration {
+	interval := prometheusStep(ctx, 0, r.start, r.end, maxDataPoints)
+	if minInterval, err := model.ParseDuration(r.interpolate(stringField(panel, "interval"))); err == nil && time.Duration(minInterval) > interval {
+		interval = time.Duration(minInterval)
+	}
+	return interval
+}
+
+// summarizeFrame converts a data frame to its fields, keeping at most
+// maxRows rows.
+func summarizeFrame(f dataFrame, maxRows int) panelDataFrame {
+	frame := panelDataFrame{Name: f.Schema.Name, Fields: []panelDataField{}}
+	for i, field := range f.Schema.Fields {
+		var values []any
+		if i < len(f.Data.Values) {
+			values = f.Data.Values[i]
+		}
+		frame.Rows = max(frame.Rows, len(values))
+		if len(values) > maxRows {
+			values, frame.Truncated = values[:maxRows], true
+		}
+		if field.Type == "time" {
+			for j, v := range values {
+				if ms, ok := v.(float64); ok {
+					values[j] = time.UnixMilli(int64(ms)).UTC().Format(time.RFC3339)
+				}
+			}
+		}
+		if values == nil {
+			values = []any{}
+		}
+		frame.Fields = append(frame.Fields, panelDataField{
+			Name:   field.Name,
+			Type:   field.Type,
+			Labels: field.Labels,
+			Unit:   field.Config.Unit,
+			Values: values,
+		})
+	}
+	return frame
+}
+
+// dashboardPanelQueries are the queries of a dashboard panel, with the
+// dashboard's template variables evaluated to interpolate them.
+type dashboardPanelQueries struct {
+	db, panel map[string]any
+	targets   []any
+	variables *variableResolver
+}
+
+// loadPanelQueries gets a dashboard panel with queries, and evaluates the
+// dashboard's template variables over the time range, with the selected
+// values.
+func loadPanelQueries(ctx context.Context, uid string, panelID int, startTime, endTime string, selected map[string]string) (*dashboardPanelQueries, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	panel, err := findDashboardPanel(db, panelID)
+	if err != nil {
+		return nil, err
+	}
+	targets, _ := panel["targets"].([]any)
+	if len(targets) == 0 {
+		if _, ok := panel["libraryPanel"]; ok {
+			return nil, fmt.Errorf("panel %d is a library panel; get its queries with get_library_panel", panelID)
+		}
+		return nil, fmt.Errorf("panel %d has no queries", panelID)
+	}
+	start, end, err := parseDashboardTimeRange(db, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &variableResolver{ctx: ctx, start: start, end: end, current: map[string][]string{}}
+	variables, names := dashboardVariables(db)
+	for _, name := range names {
+		r.resolve(variables[name], selected, DefaultVariableValuesLimit)
+	}
+	return &dashboardPanelQueries{db: db, panel: panel, targets: targets, variables: r}, nil
+}
+
+// resolveTargetDatasource returns the datasource of a panel target, with
+// datasource variables and the default datasource resolved.
+func resolveTargetDatasource(r *variableResolver, panel, target map[string]any) (datasourceInfo, error) {
+	ds := targetDatasource(panel, target)
+	if ds.UID == expressionDatasourceUID || ds.Type == expressionDatasourceUID {
+		return datasourceInfo{UID: expressionDatasourceUID, Type: expressionDatasourceUID}, nil
+	}
+	resolved, err := r.resolveDatasource(map[string]any{"uid": ds.UID})
+	if err != nil {
+		return datasourceInfo{}, err
+	}
+	return datasourceInfo{UID: resolved.UID, Type: resolved.Type}, nil
+}
+
+func queryPanelData(ctx context.Context, args QueryPanelDataParams) (*panelData, error) {
+	q, err := loadPanelQueries(ctx, args.DashboardUID, args.PanelID, args.StartTime, args.EndTime, args.Variables)
+	if err != nil {
+		return nil, err
+	}
+	panel, targets, r := q.panel, q.targets, q.variables
+	start, end := r.start, r.end
+
+	maxDataPoints := args.MaxDataPoints
+	if maxDataPoints <= 0 {
+		if panelMax, ok := panel["maxDataPoints"].(float64); ok {
+			maxDataPoints = int(panelMax)
+		}
+	}
+	maxDataPoints = prometheusMaxDataPoints(maxDataPoints)
+	interval := panelQueryInterval(ctx, r, panel, maxDataPoints)
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = DefaultPanelDataRows
+	}
+
+	// Hidden queries are only run when expressions may depend on them, and
+	// their results aren't shown, as in Grafana.
+	hasExpressions := false
+	for _, t := range targets {
+		if target, ok := t.(map[string]any); ok && targetDatasource(panel, target).UID == expressionDatasourceUID {
+			hasExpressions = true
+		}
+	}
+
+	result := &panelData{
+		PanelID:    args.PanelID,
+		PanelTitle: stringField(panel, "title"),
+		Start:      start,
+		End:        end,
+		IntervalMs: interval.Milliseconds(),
+		Variables:  r.current,
+		Queries:    []panelQueryData{},
+	}
+	if len(result.Variables) == 0 {
+		result.Variables = nil
+	}
+	hidden := map[string]bool{}
+	queries := []any{}
+	for _, t := range targets {
+		target, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		refID := stringField(target, "refId")
+		if h, _ := target["hide"].(bool); h {
+			if !hasExpressions {
+				continue
+			}
+			hidden[refID] = true
+		}
+		ds, err := resolveTargetDatasource(r, panel, target)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", refID, err)
+		}
+
+		query := interpolateTarget(r, target).(map[string]any)
+		query["datasource"] = ds
+		query["intervalMs"] = interval.Milliseconds()
+		query["maxDataPoints"] = maxDataPoints
+		queries = append(queries, query)
+		if !hidden[refID] {
+			result.Queries = append(result.Queries, panelQueryData{RefID: refID, Datasource: ds, Query: targetQuery(query), Frames: []panelDataFrame{}})
+		}
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("all queries of panel %d are hidden", args.PanelID)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"queries": queries,
+		"from":    strconv.FormatInt(start.UnixMilli(), 10),
+		"to":      strconv.FormatInt(end.UnixMilli(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.do(ctx, http.MethodPost, "/api/ds/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("query panel %d: %w", args.PanelID, err)
+	}
+	defer resp.Body.Close()
+	var data dsQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode query response: %w", err)
+	}
+
+	for i, q := range result.Queries {
+		res, ok := data.Results[q.RefID]
+		if !ok {
+			result.Queries[i].Error = "no result returned"
+			continue
+		}
+		result.Queries[i].Error = res.Error
+		for _, f := range res.Frames {
+			result.Queries[i].Frames = append(result.Queries[i].Frames, summarizeFrame(f, maxRows))
+		}
+	}
+	return result, nil
+}
+
+var QueryPanelData = mcpgrafana.MustTool(
+	"query_panel_data",
+	"Run the queries of a dashboard panel through Grafana, as the panel does, and return the resulting data frames, so you see the same numbers as a user looking at the dashboard. Template variables are evaluated as in get_dashboard_variable_values and replaced in the queries; set `variables` to select other values. The time range defaults to the dashboard's. Works with any datasource and with server-side expressions; hidden queries are not returned. Returns, per query, its refId, datasource, interpolated query, error if any and frames, each with its fields (name, type, labels, unit and values, with times in RFC3339) and row count. Frames are truncated to `maxRows` rows.",
+	queryPanelData,
+	mcp.WithTitleAnnotation("Query panel data"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
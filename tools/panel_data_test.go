@@ -0,0 +1,106 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const panelDataTestDashboard = `{
+	"uid": "api", "title": "API", "time": {"from": "now-1h", "to": "now"},
+	"templating": {"list": [
+		{"name": "datasource", "type": "datasource", "query": "prometheus", "current": {"value": "prom"}},
+		{"name": "job", "type": "custom", "query": "api,web", "current": {"value": "api"}}
+	]},
+	"panels": [
+		{"id": 1, "type": "row", "collapsed": true, "panels": [
+			{"id": 2, "type": "timeseries", "title": "Requests", "interval": "5m", "datasource": {"type": "prometheus", "uid": "${datasource}"},
+				"targets": [
+					{"refId": "A", "expr": "sum(rate(http_requests_total{job=\"$job\"}[$__rate_interval]))", "hide": true},
+					{"refId": "B", "datasource": {"type": "__expr__", "uid": "__expr__"}, "type": "math", "expression": "$A * 60"}
+				]}
+		]},
+		{"id": 3, "type": "stat", "title": "Empty"}
+	]
+}`
+
+func TestSummarizeFrame(t *testing.T) {
+	var f dataFrame
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"schema": {"name": "up", "fields": [
+			{"name": "Time", "type": "time"},
+			{"name": "Value", "type": "number", "labels": {"job": "api"}, "config": {"unit": "reqps"}}
+		]},
+		"data": {"values": [[1700000000000, 1700000060000, 1700000120000], [1, 2.5, null]]}
+	}`), &f))
+
+	assert.Equal(t, panelDataFrame{
+		Name: "up",
+		Fields: []panelDataField{
+			{Name: "Time", Type: "time", Values: []any{"2023-11-14T22:13:20Z", "2023-11-14T22:14:20Z"}},
+			{Name: "Value", Type: "number", Labels: map[string]string{"job": "api"}, Unit: "reqps", Values: []any{1.0, 2.5}},
+		},
+		Rows:      3,
+		Truncated: true,
+	}, summarizeFrame(f, 2))
+}
+
+func TestQueryPanelData(t *testing.T) {
+	var request struct {
+		From    string           `json:"from"`
+		To      string           `json:"to"`
+		Queries []map[string]any `json:"queries"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/uid/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": ` + panelDataTestDashboard + `}`))
+	})
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid": "prom", "name": "Prometheus", "type": "prometheus", "isDefault": true}, {"uid": "prom-eu", "name": "EU", "type": "prometheus"}]`))
+	})
+	mux.HandleFunc("POST /api/ds/query", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results": {
+			"A": {"frames": [{"schema": {"fields": [{"name": "Value", "type": "number"}]}, "data": {"values": [[1]]}}]},
+			"B": {"frames": [{"schema": {"refId": "B", "fields": [{"name": "Time", "type": "time"}, {"name": "B", "type": "number"}]}, "data": {"values": [[1700000000000], [60]]}}]}
+		}}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := queryPanelData(ctx, QueryPanelDataParams{DashboardUID: "api", PanelID: 2, Variables: map[string]string{"datasource": "prom-eu", "job": "web"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Requests", result.PanelTitle)
+	assert.Equal(t, []string{"web"}, result.Variables["job"])
+	// The panel's minimum interval is longer than the step for 100 points
+	// over an hour.
+	assert.Equal(t, int64(300000), result.IntervalMs)
+
+	// The hidden query is run for the expression, but not returned.
+	require.Len(t, request.Queries, 2)
+	assert.Equal(t, `sum(rate(http_requests_total{job="web"}[$__rate_interval]))`, request.Queries[0]["expr"])
+	assert.Equal(t, map[string]any{"uid": "prom-eu", "type": "prometheus"}, request.Queries[0]["datasource"])
+	assert.Equal(t, float64(100), request.Queries[0]["maxDataPoints"])
+	assert.Equal(t, map[string]any{"uid": "__expr__", "type": "__expr__"}, request.Queries[1]["datasource"])
+	assert.Equal(t, strconv.FormatInt(result.Start.UnixMilli(), 10), request.From)
+
+	require.Len(t, result.Queries, 1)
+	assert.Equal(t, "B", result.Queries[0].RefID)
+	assert.Equal(t, "$A * 60", result.Queries[0].Query)
+	require.Len(t, result.Queries[0].Frames, 1)
+	assert.Equal(t, []any{60.0}, result.Queries[0].Frames[0].Fields[1].Values)
+
+	_, err = queryPanelData(ctx, QueryPanelDataParams{DashboardUID: "api", PanelID: 3})
+	assert.ErrorContains(t, err, "panel 3 has no queries")
+	_, err = queryPanelData(ctx, QueryPanelDataParams{DashboardUID: "api", PanelID: 4})
+	assert.ErrorContains(t, err, "panel 4 not found")
+}
@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/client/teams"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Dashboard and folder permission levels, as used by the Grafana API.
+var permissionLevels = map[string]models.PermissionType{
+	"view":  1,
+	"edit":  2,
+	"admin": 4,
+}
+
+func permissionName(p models.PermissionType) string {
+	switch p {
+	case 1:
+		return "View"
+	case 2:
+		return "Edit"
+	case 4:
+		return "Admin"
+	}
+	return fmt.Sprintf("Unknown (%d)", p)
+}
+
+type GetDashboardPermissionsParams struct {
+	UID       string `json:"uid,omitempty" jsonschema:"description=The UID of the dashboard. Either 'uid' or 'folderUid' is required"`
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=The UID of the folder\\, to get the permissions of a folder rather than a dashboard"`
+}
+
+// permissionTarget returns whether the permissions of a folder rather than a
+// dashboard are requested, and the UID of the dashboard or folder.
+func permissionTarget(uid, folderUID string) (bool, string, error) {
+	switch {
+	case uid != "" && folderUID != "":
+		return false, "", fmt.Errorf("only one of uid and folderUid can be set")
+	case uid != "":
+		return false, uid, validatePathSegment("uid", uid)
+	case folderUID != "":
+		return true, folderUID, validatePathSegment("folderUid", folderUID)
+	}
+	return false, "", fmt.Errorf("either uid or folderUid is required")
+}
+
+// dashboardPermission is a permission granted to a role, team or user.
+type dashboardPermission struct {
+	Role       string `json:"role,omitempty"`
+	TeamID     int64  `json:"teamId,omitempty"`
+	Team       string `json:"team,omitempty"`
+	UserID     int64  `json:"userId,omitempty"`
+	UserLogin  string `json:"userLogin,omitempty"`
+	UserEmail  string `json:"userEmail,omitempty"`
+	Permission string `json:"permission"`
+	// Inherited permissions are granted on the folder of a dashboard, and
+	// can only be changed there.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+type dashboardPermissions struct {
+	UID         string                `json:"uid"`
+	Folder      bool                  `json:"folder,omitempty"`
+	Permissions []dashboardPermission `json:"permissions"`
+}
+
+func (p dashboardPermission) principal() string {
+	switch {
+	case p.TeamID != 0:
+		return fmt.Sprintf("team:%d", p.TeamID)
+	case p.UserID != 0:
+		return fmt.Sprintf("user:%d", p.UserID)
+	}
+	return "role:" + p.Role
+}
+
+func getPermissionList(ctx context.Context, folder bool, uid string) ([]*models.DashboardACLInfoDTO, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if folder {
+		resp, err := c.FolderPermissions.GetFolderPermissionList(uid)
+		if err != nil {
+			return nil, fmt.Errorf("get permissions of folder %s: %w", uid, err)
+		}
+		return resp.Payload, nil
+	}
+	resp, err := c.DashboardPermissions.GetDashboardPermissionsListByUID(uid)
+	if err != nil {
+		return nil, fmt.Errorf("get permissions of dashboard %s: %w", uid, err)
+	}
+	return resp.Payload, nil
+}
+
+func getDashboardPermissions(ctx context.Context, args GetDashboardPermissionsParams) (*dashboardPermissions, error) {
+	folder, uid, err := permissionTarget(args.UID, args.FolderUID)
+	if err != nil {
+		return nil, err
+	}
+	acl, err := getPermissionList(ctx, folder, uid)
+	if err != nil {
+		return nil, err
+	}
+	result := &dashboardPermissions{UID: uid, Folder: folder, Permissions: []dashboardPermission{}}
+	for _, item := range acl {
+		result.Permissions = append(result.Permissions, dashboardPermission{
+			Role:       item.Role,
+			TeamID:     item.TeamID,
+			Team:       item.Team,
+			UserID:     item.UserID,
+			UserLogin:  item.UserLogin,
+			UserEmail:  item.UserEmail,
+			Permission: permissionName(item.Permission),
+			Inherited:  item.Inherited,
+		})
+	}
+	return result, nil
+}
+
+var GetDashboardPermissions = mcpgrafana.MustTool(
+	"get_dashboard_permissions",
+	"Get who can view, edit or administer a dashboard or folder: the permissions granted to roles (Viewer, Editor), teams and users. Permissions of a dashboard inherited from its folder are marked as `inherited`. Server and organization admins can access everything regardless of these permissions.",
+	getDashboardPermissions,
+	mcp.WithTitleAnnotation("Get dashboard permissions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type DashboardPermissionChange struct {
+	Role       string `json:"role,omitempty" jsonschema:"enum=Viewer,enum=Editor,description=The role to grant the permission to. One of 'role'\\, 'team'\\, 'teamId'\\, 'user' or 'userId' is required"`
+	Team       string `json:"team,omitempty" jsonschema:"description=The name of the team to grant the permission to"`
+	TeamID     int64  `json:"teamId,omitempty" jsonschema:"description=The ID of the team to grant the permission to"`
+	User       string `json:"user,omitempty" jsonschema:"description=The login or email of the user to grant the permission to"`
+	UserID     int64  `json:"userId,omitempty" jsonschema:"description=The ID of the user to grant the permission to"`
+	Permission string `json:"permission" jsonschema:"required,enum=View,enum=Edit,enum=Admin,enum=None,description=The permission to grant. 'None' removes the permission"`
+}
+
+type SetDashboardPermissionsParams struct {
+	UID         string                      `json:"uid,omitempty" jsonschema:"description=The UID of the dashboard. Either 'uid' or 'folderUid' is required"`
+	FolderUID   string                      `json:"folderUid,omitempty" jsonschema:"description=The UID of the folder\\, to set the permissions of a folder rather than a dashboard"`
+	Permissions []DashboardPermissionChange `json:"permissions" jsonschema:"required,description=The permissions to grant or remove"`
+	Replace     bool                        `json:"replace,omitempty" jsonschema:"description=Set to true to replace all permissions of the dashboard or folder with 'permissions'. By default\\, other permissions are kept"`
+}
+
+// resolvePrincipal fills in the team or user ID of a permission change given
+// by name.
+func resolvePrincipal(ctx context.Context, change DashboardPermissionChange) (dashboardPermission, error) {
+	p := dashboardPermission{Role: change.Role, TeamID: change.TeamID, UserID: change.UserID}
+	set := 0
+	for _, s := range []bool{change.Role != "", change.Team != "", change.TeamID != 0, change.User != "", change.UserID != 0} {
+		if s {
+			set++
+		}
+	}
+	if set != 1 {
+		return p, fmt.Errorf("exactly one of role, team, teamId, user and userId must be set for each permission")
+	}
+	if change.Role != "" && change.Role != "Viewer" && change.Role != "Editor" {
+		return p, fmt.Errorf("invalid role %q: expected 'Viewer' or 'Editor'", change.Role)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	switch {
+	case change.Team != "":
+		params := teams.NewSearchTeamsParamsWithContext(ctx).WithName(&change.Team)
+		resp, err := c.Teams.SearchTeams(params)
+		if err != nil {
+			return p, fmt.Errorf("find team %q: %w", change.Team, err)
+		}
+		for _, team := range resp.Payload.Teams {
+			if team.Name == change.Team {
+				p.TeamID, p.Team = team.ID, team.Name
+			}
+		}
+		if p.TeamID == 0 {
+			return p, fmt.Errorf("team %q not found", change.Team)
+		}
+	case change.User != "":
+		resp, err := c.Users.GetUserByLoginOrEmail(change.User)
+		if err != nil {
+			return p, fmt.Errorf("find user %q: %w", change.User, err)
+		}
+		p.UserID, p.UserLogin = resp.Payload.ID, resp.Payload.Login
+	}
+	return p, nil
+}
+
+func setDashboardPermissions(ctx context.Context, args SetDashboardPermissionsParams) (*dashboardPermissions, error) {
+	folder, uid, err := permissionTarget(args.UID, args.FolderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(args.Permissions) == 0 && !args.Replace {
+		return nil, fmt.Errorf("no permissions given")
+	}
+
+	// The API replaces all permissions which aren't inherited, so the
+	// permissions which aren't changed are sent again.
+	items := map[string]*models.DashboardACLUpdateItem{}
+	if !args.Replace {
+		acl, err := getPermissionList(ctx, folder, uid)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range acl {
+			if item.Inherited {
+				continue
+			}
+			p := dashboardPermission{Role: item.Role, TeamID: item.TeamID, UserID: item.UserID}
+			items[p.principal()] = &models.DashboardACLUpdateItem{
+				Role: item.Role, TeamID: item.TeamID, UserID: item.UserID, Permission: item.Permission,
+			}
+		}
+	}
+	for _, change := range args.Permissions {
+		p, err := resolvePrincipal(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(change.Permission, "none") {
+			delete(items, p.principal())
+			continue
+		}
+		level, ok := permissionLevels[strings.ToLower(change.Permission)]
+		if !ok {
+			return nil, fmt.Errorf("invalid permission %q: expected 'View', 'Edit', 'Admin' or 'None'", change.Permission)
+		}
+		items[p.principal()] = &models.DashboardACLUpdateItem{
+			Role: p.Role, TeamID: p.TeamID, UserID: p.UserID, Permission: level,
+		}
+	}
+
+	keys := sortedKeys(items)
+	cmd := &models.UpdateDashboardACLCommand{Items: make([]*models.DashboardACLUpdateItem, 0, len(keys))}
+	for _, k := range keys {
+		cmd.Items = append(cmd.Items, items[k])
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if folder {
+		if _, err := c.FolderPermissions.UpdateFolderPermissions(uid, cmd); err != nil {
+			return nil, fmt.Errorf("set permissions of folder %s: %w", uid, err)
+		}
+	} else {
+		if _, err := c.DashboardPermissions.UpdateDashboardPermissionsByUID(uid, cmd); err != nil {
+			return nil, fmt.Errorf("set permissions of dashboard %s: %w", uid, err)
+		}
+	}
+
+	return getDashboardPermissions(ctx, GetDashboardPermissionsParams{UID: args.UID, FolderUID: args.FolderUID})
+}
+
+var SetDashboardPermissions = mcpgrafana.MustTool(
+	"set_dashboard_permissions",
+	"Grant, change or remove permissions of roles, teams or users on a dashboard or folder. Teams and users can be given by name, login or email. Other permissions are kept unless `replace` is set; permissions inherited from a dashboard's folder can only be changed on the folder. Returns the resulting permissions.",
+	setDashboardPermissions,
+	mcp.WithTitleAnnotation("Set dashboard permissions"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
@@ -0,0 +1,98 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardPermissions(t *testing.T) {
+	var saved []map[string]any
+	acl := `[
+		{"role": "Viewer", "permission": 1, "inherited": true},
+		{"role": "Editor", "permission": 2},
+		{"teamId": 3, "team": "SRE", "permission": 4},
+		{"userId": 7, "userLogin": "bob", "userEmail": "bob@example.com", "permission": 1}
+	]`
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/dashboards/uid/svc/permissions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(acl))
+	})
+	mux.HandleFunc("POST /api/dashboards/uid/svc/permissions", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Items []map[string]any `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		saved = body.Items
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message": "Dashboard permissions updated"}`))
+	})
+	mux.HandleFunc("GET /api/folders/ops/permissions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"role": "Viewer", "permission": 1}]`))
+	})
+	mux.HandleFunc("GET /api/teams/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Payments", r.URL.Query().Get("name"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalCount": 1, "teams": [{"id": 9, "name": "Payments"}]}`))
+	})
+	mux.HandleFunc("GET /api/users/lookup", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bob@example.com", r.URL.Query().Get("loginOrEmail"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 7, "login": "bob"}`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	perms, err := getDashboardPermissions(ctx, GetDashboardPermissionsParams{UID: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []dashboardPermission{
+		{Role: "Viewer", Permission: "View", Inherited: true},
+		{Role: "Editor", Permission: "Edit"},
+		{TeamID: 3, Team: "SRE", Permission: "Admin"},
+		{UserID: 7, UserLogin: "bob", UserEmail: "bob@example.com", Permission: "View"},
+	}, perms.Permissions)
+
+	folder, err := getDashboardPermissions(ctx, GetDashboardPermissionsParams{FolderUID: "ops"})
+	require.NoError(t, err)
+	assert.True(t, folder.Folder)
+	assert.Len(t, folder.Permissions, 1)
+
+	_, err = getDashboardPermissions(ctx, GetDashboardPermissionsParams{UID: "svc", FolderUID: "ops"})
+	assert.Error(t, err)
+
+	// Unchanged permissions are kept, but not inherited ones.
+	_, err = setDashboardPermissions(ctx, SetDashboardPermissionsParams{UID: "svc", Permissions: []DashboardPermissionChange{
+		{Team: "Payments", Permission: "Edit"},
+		{User: "bob@example.com", Permission: "None"},
+		{Role: "Editor", Permission: "View"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"role": "Editor", "permission": float64(1)},
+		{"teamId": float64(3), "permission": float64(4)},
+		{"teamId": float64(9), "permission": float64(2)},
+	}, saved)
+
+	_, err = setDashboardPermissions(ctx, SetDashboardPermissionsParams{UID: "svc", Replace: true, Permissions: []DashboardPermissionChange{
+		{TeamID: 3, Permission: "Admin"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"teamId": float64(3), "permission": float64(4)}}, saved)
+
+	for _, change := range []DashboardPermissionChange{
+		{Permission: "View"},
+		{Role: "Viewer", TeamID: 3, Permission: "View"},
+		{Role: "Admin", Permission: "View"},
+		{Role: "Viewer", Permission: "Owner"},
+	} {
+		_, err = setDashboardPermissions(ctx, SetDashboardPermissionsParams{UID: "svc", Permissions: []DashboardPermissionChange{change}})
+		assert.Error(t, err, change)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +18,6 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 )
@@ -30,6 +33,16 @@ var (
 )
 
 func promClientFromContext(ctx context.Context, uid string) (promv1.API, error) {
+	c, err := promAPIClientFromContext(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return promv1.NewAPI(c), nil
+}
+
+// promAPIClientFromContext returns an HTTP client for the Prometheus API of a
+// datasource, for requests whose responses promv1.API doesn't fully decode.
+func promAPIClientFromContext(ctx context.Context, uid string) (api.Client, error) {
 	// First check if the datasource exists
 	_, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
 	if err != nil {
@@ -37,43 +50,35 @@ func promClientFromContext(ctx context.Context, uid string) (promv1.API, error)
 	}
 
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
-	url := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), uid)
+	escapedUID, err := sanitizePathSegment("datasource UID", uid)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), escapedUID)
 
-	// Create custom transport with TLS configuration if available
-	rt := api.DefaultRoundTripper
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		customTransport, err := tlsConfig.HTTPTransport(rt.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
-		rt = customTransport
-	}
-
-	if cfg.AccessToken != "" && cfg.IDToken != "" {
-		rt = config.NewHeadersRoundTripper(&config.Headers{
-			Headers: map[string]config.Header{
-				"X-Access-Token": {
-					Secrets: []config.Secret{config.Secret(cfg.AccessToken)},
-				},
-				"X-Grafana-Id": {
-					Secrets: []config.Secret{config.Secret(cfg.IDToken)},
-				},
-			},
-		}, rt)
-	} else if cfg.APIKey != "" {
-		rt = config.NewAuthorizationCredentialsRoundTripper(
-			"Bearer", config.NewInlineSecret(cfg.APIKey), rt,
-		)
+	httpClient, err := newQueryHTTPClient(ctx, defaultsCategoryPrometheus, api.DefaultRoundTripper.(*http.Transport))
+	if err != nil {
+		return nil, err
 	}
 	c, err := api.NewClient(api.Config{
-		Address:      url,
-		RoundTripper: rt,
+		Address: url,
+		Client:  httpClient,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating Prometheus client: %w", err)
 	}
+	return promQueryClient{c}, nil
+}
 
-	return promv1.NewAPI(c), nil
+// promQueryClient marks the requests of a Prometheus API client as safe to
+// retry: queries are sent as POST requests, but the tools only use endpoints
+// which read from Prometheus.
+type promQueryClient struct {
+	api.Client
+}
+
+func (c promQueryClient) Do(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	return c.Client.Do(withRetryableRequests(ctx), req)
 }
 
 type ListPrometheusMetricMetadataParams struct {
@@ -111,15 +116,59 @@ var ListPrometheusMetricMetadata = mcpgrafana.MustTool(
 )
 
 type QueryPrometheusParams struct {
-	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
-	StartTime     string `json:"startTime" jsonschema:"required,description=The start time. Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
-	EndTime       string `json:"endTime,omitempty" jsonschema:"description=The end time. Required if queryType is 'range'\\, ignored if queryType is 'instant' Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
-	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=The time series step size in seconds. Required if queryType is 'range'\\, ignored if queryType is 'instant'"`
-	QueryType     string `json:"queryType,omitempty" jsonschema:"description=The type of query to use. Either 'range' or 'instant'"`
+	DatasourceUID  string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	DatasourceUIDs []string `json:"datasourceUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of more Prometheus datasources to run the same query against concurrently\\, e.g. one per cluster or Mimir tenant. The result is then a list with each datasource's result\\, or its error\\, attributed to it in 'datasource'"`
+	Expr           string   `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
+	StartTime      string   `json:"startTime" jsonschema:"required,description=The start time. Supported formats are RFC3339\\, relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'\\, '6 hours ago') or 'today'\\, 'yesterday'\\, 'this week'\\, 'last week'\\, 'this month' and 'last month'\\, which start at midnight UTC. Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
+	EndTime        string   `json:"endTime,omitempty" jsonschema:"description=The end time. Required if queryType is 'range'\\, ignored if queryType is 'instant'. Supported formats are the same as for startTime (e.g. 'now'\\, 'now-1.5h'\\, 'today'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
+	StepSeconds    int      `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the time series step size in seconds. Defaults to the server's configured step\\, or to a round step returning at most maxDataPoints samples per series. Ignored if queryType is 'instant'"`
+	MaxDataPoints  int      `json:"maxDataPoints,omitempty" jsonschema:"description=Optionally\\, the maximum number of samples returned per series by a range query (default: 100). Denser results are downsampled by averaging consecutive samples"`
+	QueryType      string   `json:"queryType,omitempty" jsonschema:"description=The type of query to use. Either 'range' or 'instant'"`
+	EstimateOnly   bool     `json:"estimateOnly,omitempty" jsonschema:"description=Optionally\\, only return the number of series matched by each selector in the expression (and for range queries the resulting number of samples) instead of running the query. Use this to gauge the result size of a query before running it"`
+	Export         string   `json:"export,omitempty" jsonschema:"enum=csv,enum=ndjson,description=Optionally\\, export the full result as CSV or NDJSON to a resource instead of returning it. Range queries are then not downsampled: maxDataPoints defaults to 11000. The result is a summary of each series with the 'resourceUri' to read the samples from. Use this to load the data into a notebook or spreadsheet"`
+	Debug          bool     `json:"debug,omitempty" jsonschema:"description=Optionally\\, include the query model and the raw request and response exchanged with the datasource in the result\\, like Grafana's Query Inspector. Useful for debugging differences between tool results and the Grafana UI"`
+}
+
+// naturalTimes are the words accepted as times, with the date math they
+// stand for. Days, weeks and months start at midnight UTC.
+var naturalTimes = map[string]string{
+	"today":      "now/d",
+	"yesterday":  "now-1d/d",
+	"this week":  "now/w",
+	"last week":  "now-1w/w",
+	"this month": "now/M",
+	"last month": "now-1M/M",
 }
 
+// agoPattern matches times such as "6 hours ago" or "1 day ago".
+var agoPattern = regexp.MustCompile(`^(\d+)\s*(second|minute|hour|day|week|month|year)s?\s+ago$`)
+
+// agoUnits are the date math units of the units matched by agoPattern.
+var agoUnits = map[string]string{
+	"second": "s",
+	"minute": "m",
+	"hour":   "h",
+	"day":    "d",
+	"week":   "w",
+	"month":  "M",
+	"year":   "y",
+}
+
+// parseTime parses a time given relative to now, such as "now-1h",
+// Grafana's "now-1d/d", "yesterday" or "6 hours ago", as an RFC3339 or ISO
+// 8601 date, or as a Unix timestamp. It is shared by all tools taking times
+// so they accept the same formats.
 func parseTime(timeStr string) (time.Time, error) {
+	timeStr = strings.Trim(strings.TrimSpace(timeStr), `"`)
+	if v, err := strconv.ParseInt(timeStr, 10, 64); err == nil {
+		return unixTime(v), nil
+	}
+	natural := strings.Join(strings.Fields(strings.ToLower(timeStr)), " ")
+	if dateMath, ok := naturalTimes[natural]; ok {
+		timeStr = dateMath
+	} else if m := agoPattern.FindStringSubmatch(natural); m != nil {
+		timeStr = "now-" + m[1] + agoUnits[m[2]]
+	}
 	tr := gtime.TimeRange{
 		From: timeStr,
 		Now:  time.Now(),
@@ -127,6 +176,26 @@ func parseTime(timeStr string) (time.Time, error) {
 	return tr.ParseFrom()
 }
 
+// unixTime converts a Unix timestamp to a time, telling seconds,
+// milliseconds, microseconds and nanoseconds apart by their magnitude. This
+// lets Loki's nanosecond timestamps be passed back to any tool.
+func unixTime(v int64) time.Time {
+	abs := v
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= 1e17:
+		return time.Unix(0, v)
+	case abs >= 1e14:
+		return time.UnixMicro(v)
+	case abs >= 1e11:
+		return time.UnixMilli(v)
+	default:
+		return time.Unix(v, 0)
+	}
+}
+
 func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Value, error) {
 	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
 	if err != nil {
@@ -145,18 +214,30 @@ func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Val
 	}
 
 	if queryType == "range" {
-		if args.StepSeconds == 0 {
-			return nil, fmt.Errorf("stepSeconds must be provided when queryType is 'range'")
-		}
-
 		var endTime time.Time
 		endTime, err = parseTime(args.EndTime)
 		if err != nil {
 			return nil, fmt.Errorf("parsing end time: %w", err)
 		}
-
-		step := time.Duration(args.StepSeconds) * time.Second
-		result, _, err := promClient.QueryRange(ctx, args.Expr, promv1.Range{
+		startTime, endTime, err = clampTimeRange(ctx, defaultsCategoryPrometheus, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+		maxDataPoints := prometheusMaxDataPoints(args.MaxDataPoints)
+		step := prometheusStep(ctx, args.StepSeconds, startTime, endTime, maxDataPoints)
+
+		queryInspectorFromContext(ctx).setQuery(map[string]any{
+			"refId":         "A",
+			"datasource":    datasourceInfo{UID: args.DatasourceUID, Type: "prometheus"},
+			"expr":          args.Expr,
+			"range":         true,
+			"instant":       false,
+			"from":          startTime.Format(time.RFC3339),
+			"to":            endTime.Format(time.RFC3339),
+			"intervalMs":    step.Milliseconds(),
+			"maxDataPoints": maxDataPoints,
+		})
+		result, err := queryPrometheusRangeChunked(ctx, promClient, args.Expr, promv1.Range{
 			Start: startTime,
 			End:   endTime,
 			Step:  step,
@@ -164,8 +245,19 @@ func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Val
 		if err != nil {
 			return nil, fmt.Errorf("querying Prometheus range: %w", err)
 		}
+		if matrix, ok := result.(model.Matrix); ok {
+			return downsampleMatrix(matrix, maxDataPoints), nil
+		}
 		return result, nil
 	} else if queryType == "instant" {
+		queryInspectorFromContext(ctx).setQuery(map[string]any{
+			"refId":      "A",
+			"datasource": datasourceInfo{UID: args.DatasourceUID, Type: "prometheus"},
+			"expr":       args.Expr,
+			"range":      false,
+			"instant":    true,
+			"time":       startTime.Format(time.RFC3339),
+		})
 		result, _, err := promClient.Query(ctx, args.Expr, startTime)
 		if err != nil {
 			return nil, fmt.Errorf("querying Prometheus instant: %w", err)
@@ -176,15 +268,171 @@ func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Val
 	return nil, fmt.Errorf("invalid query type: %s", queryType)
 }
 
+// prometheusQueryEstimate is the size of a PromQL query reported by the
+// series API, returned instead of the query's results in estimateOnly mode.
+type prometheusQueryEstimate struct {
+	// Series is the total number of series matched by the selectors.
+	Series    int                          `json:"series"`
+	Selectors []prometheusSelectorEstimate `json:"selectors"`
+	// Samples is an upper bound on the number of samples a range query
+	// reads, assuming every series has a sample at every step.
+	Samples int64 `json:"samples,omitempty"`
+}
+
+type prometheusSelectorEstimate struct {
+	Selector string `json:"selector"`
+	Series   int    `json:"series"`
+}
+
+// estimatePrometheusQuery counts the series matched by each selector in the
+// query over its time range.
+func estimatePrometheusQuery(ctx context.Context, args QueryPrometheusParams) (*prometheusQueryEstimate, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	expr, err := parsePromQL(args.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, err := parseTime(args.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	endTime, step := startTime, time.Duration(0)
+	if args.QueryType == "" || args.QueryType == "range" {
+		if endTime, err = parseTime(args.EndTime); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+		if startTime, endTime, err = clampTimeRange(ctx, defaultsCategoryPrometheus, startTime, endTime); err != nil {
+			return nil, err
+		}
+		step = prometheusStep(ctx, args.StepSeconds, startTime, endTime, args.MaxDataPoints)
+	}
+
+	estimate := &prometheusQueryEstimate{Selectors: []prometheusSelectorEstimate{}}
+	seen := map[string]bool{}
+	for _, sel := range promqlSelectors(expr) {
+		matchers := make([]string, 0, len(sel.Matchers))
+		for _, m := range sel.Matchers {
+			matchers = append(matchers, m.String())
+		}
+		selector := "{" + strings.Join(matchers, ", ") + "}"
+		if seen[selector] {
+			continue
+		}
+		seen[selector] = true
+
+		series, _, err := promClient.Series(ctx, []string{selector}, startTime, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("counting series for %s: %w", selector, err)
+		}
+		estimate.Series += len(series)
+		estimate.Selectors = append(estimate.Selectors, prometheusSelectorEstimate{Selector: selector, Series: len(series)})
+	}
+	if step > 0 {
+		estimate.Samples = int64(estimate.Series) * (int64(endTime.Sub(startTime)/step) + 1)
+	}
+	return estimate, nil
+}
+
+// queryPrometheusResult is the result of the query_prometheus and
+// query_prometheus_instant tools, whose shape depends on the arguments.
+type queryPrometheusResult struct{ resultValue }
+
+func (queryPrometheusResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{
+		reflect.TypeFor[inspectable[prometheusValue]](),
+		reflect.TypeFor[inspectable[[]prometheusDatasourceResult]](),
+		reflect.TypeFor[inspectable[*prometheusQueryEstimate]](),
+		reflect.TypeFor[inspectable[*prometheusExport]](),
+	}
+}
+
+// queryPrometheusTool handles calls to the query_prometheus tool, adding
+// query inspection details to the result when debug is requested.
+func queryPrometheusTool(ctx context.Context, args QueryPrometheusParams) (queryPrometheusResult, error) {
+	uids := prometheusDatasourceUIDs(args.DatasourceUID, args.DatasourceUIDs)
+	if args.Export != "" {
+		if len(uids) > 1 || args.EstimateOnly {
+			return queryPrometheusResult{}, fmt.Errorf("export cannot be combined with datasourceUids or estimateOnly")
+		}
+		if err := validateExportFormat(args.Export); err != nil {
+			return queryPrometheusResult{}, err
+		}
+		if args.MaxDataPoints <= 0 {
+			args.MaxDataPoints = MaxPrometheusExportDataPoints
+		}
+		return asResult[queryPrometheusResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*prometheusExport, error) {
+			value, err := queryPrometheus(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			return exportPrometheusResult(ctx, args.Export, value)
+		}))
+	}
+	if len(uids) > 1 {
+		return asResult[queryPrometheusResult](queryPrometheusDatasources(ctx, uids, args))
+	}
+	if args.EstimateOnly {
+		return asResult[queryPrometheusResult](withInspection(ctx, args.Debug, func(ctx context.Context) (*prometheusQueryEstimate, error) {
+			return estimatePrometheusQuery(ctx, args)
+		}))
+	}
+	return asResult[queryPrometheusResult](withInspection(ctx, args.Debug, func(ctx context.Context) (prometheusValue, error) {
+		value, err := queryPrometheus(ctx, args)
+		if err != nil {
+			return prometheusValue{}, err
+		}
+		return decodePrometheusHistograms(value), nil
+	}))
+}
+
 var QueryPrometheus = mcpgrafana.MustTool(
 	"query_prometheus",
-	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc.",
-	queryPrometheus,
+	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Range queries return at most `maxDataPoints` samples per series (default 100): the step is chosen from the time range unless given, and denser results are downsampled. Ranges spanning days are queried in up to 10 chunks, and each chunk's series are sent as they arrive in the `data` field of progress notifications to clients which request them. Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc. Set `datasourceUids` to run the same query against several datasources at once, such as one per cluster. Set `estimateOnly` to only count the series the query touches before running it. Set `export` to `csv` or `ndjson` to export the full, undownsampled result to an MCP resource and only get a summary of each series, e.g. to analyze the data in a notebook. Native histogram samples are returned with their count, sum, buckets and estimated 50th, 90th and 99th percentiles. Set `debug` to also return the query model and raw datasource response.",
+	guardTimeRange(queryPrometheusTool),
 	mcp.WithTitleAnnotation("Query Prometheus metrics"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+type QueryPrometheusInstantParams struct {
+	DatasourceUID  string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	DatasourceUIDs []string `json:"datasourceUids,omitempty" jsonschema:"description=Optionally\\, the UIDs of more Prometheus datasources to run the same query against concurrently\\, e.g. one per cluster or Mimir tenant. The result is then a list with each datasource's result\\, or its error\\, attributed to it in 'datasource'"`
+	Expr           string   `json:"expr" jsonschema:"required,description=The PromQL expression to evaluate"`
+	Time           string   `json:"time,omitempty" jsonschema:"description=Optionally\\, the time to evaluate the expression at\\, in RFC3339 format\\, relative to now (e.g. 'now-1h' or '1 hour ago') or 'today' or 'yesterday' for their midnight UTC. Defaults to now"`
+	Debug          bool     `json:"debug,omitempty" jsonschema:"description=Optionally\\, include the query model and the raw request and response exchanged with the datasource in the result\\, like Grafana's Query Inspector"`
+}
+
+// queryPrometheusInstantTool handles calls to the query_prometheus_instant
+// tool. It runs the query as an instant query_prometheus call at the given
+// time.
+func queryPrometheusInstantTool(ctx context.Context, args QueryPrometheusInstantParams) (queryPrometheusResult, error) {
+	ts := args.Time
+	if ts == "" {
+		ts = "now"
+	}
+	return queryPrometheusTool(ctx, QueryPrometheusParams{
+		DatasourceUID:  args.DatasourceUID,
+		DatasourceUIDs: args.DatasourceUIDs,
+		Expr:           args.Expr,
+		StartTime:      ts,
+		QueryType:      "instant",
+		Debug:          args.Debug,
+	})
+}
+
+var QueryPrometheusInstant = mcpgrafana.MustTool(
+	"query_prometheus_instant",
+	"Evaluates a PromQL expression against a Prometheus datasource at a single point in time and returns its value: a vector with one sample per series, each with its labels, timestamp and value, or a scalar or string for expressions which evaluate to one. Use this for current values, such as `sum(rate(http_requests_total[5m]))`, and `query_prometheus` for values over a time range. The time may be RFC3339 or relative to now and defaults to now.",
+	queryPrometheusInstantTool,
+	mcp.WithTitleAnnotation("Query Prometheus instant"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 type ListPrometheusMetricNamesParams struct {
 	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
 	Regex         string `json:"regex" jsonschema:"description=The regex to match against the metric names"`
@@ -302,11 +550,42 @@ func (s Selector) Matches(lbls labels.Labels) (bool, error) {
 	return matchers.Matches(lbls), nil
 }
 
+// prometheusLabelScope returns the series selectors and time range the label
+// tools are restricted to. A metric restricts each selector to its series.
+// Unset times are left zero so Prometheus applies its own defaults.
+func prometheusLabelScope(metric string, matches []Selector, startStr, endStr string) ([]string, time.Time, time.Time, error) {
+	var startTime, endTime time.Time
+	var err error
+	if startStr != "" {
+		if startTime, err = parseTime(startStr); err != nil {
+			return nil, time.Time{}, time.Time{}, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if endStr != "" {
+		if endTime, err = parseTime(endStr); err != nil {
+			return nil, time.Time{}, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+
+	if metric != "" && len(matches) == 0 {
+		matches = []Selector{{}}
+	}
+	var matchers []string
+	for _, m := range matches {
+		if metric != "" {
+			m.Filters = append(slices.Clone(m.Filters), LabelMatcher{Name: "__name__", Value: metric, Type: "="})
+		}
+		matchers = append(matchers, m.String())
+	}
+	return matchers, startTime, endTime, nil
+}
+
 type ListPrometheusLabelNamesParams struct {
 	DatasourceUID string     `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Metric        string     `json:"metric,omitempty" jsonschema:"description=Optionally\\, only list the labels of series of this metric"`
 	Matches       []Selector `json:"matches,omitempty" jsonschema:"description=Optionally\\, a list of label matchers to filter the results by"`
-	StartRFC3339  string     `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the time range to filter the results by"`
-	EndRFC3339    string     `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the time range to filter the results by"`
+	StartRFC3339  string     `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the time range to filter the results by\\, in RFC3339 format or relative to now (e.g. 'now-1h')"`
+	EndRFC3339    string     `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the time range to filter the results by\\, in RFC3339 format or relative to now"`
 	Limit         int        `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of results to return"`
 }
 
@@ -321,21 +600,9 @@ func listPrometheusLabelNames(ctx context.Context, args ListPrometheusLabelNames
 		limit = 100
 	}
 
-	var startTime, endTime time.Time
-	if args.StartRFC3339 != "" {
-		if startTime, err = time.Parse(time.RFC3339, args.StartRFC3339); err != nil {
-			return nil, fmt.Errorf("parsing start time: %w", err)
-		}
-	}
-	if args.EndRFC3339 != "" {
-		if endTime, err = time.Parse(time.RFC3339, args.EndRFC3339); err != nil {
-			return nil, fmt.Errorf("parsing end time: %w", err)
-		}
-	}
-
-	var matchers []string
-	for _, m := range args.Matches {
-		matchers = append(matchers, m.String())
+	matchers, startTime, endTime, err := prometheusLabelScope(args.Metric, args.Matches, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
 	}
 
 	labelNames, _, err := promClient.LabelNames(ctx, matchers, startTime, endTime)
@@ -353,7 +620,7 @@ func listPrometheusLabelNames(ctx context.Context, args ListPrometheusLabelNames
 
 var ListPrometheusLabelNames = mcpgrafana.MustTool(
 	"list_prometheus_label_names",
-	"List label names in a Prometheus datasource. Allows filtering by series selectors and time range.",
+	"List label names in a Prometheus datasource. Allows filtering by metric, series selectors and time range. Use this with `metric` to discover the labels a metric can be filtered by.",
 	listPrometheusLabelNames,
 	mcp.WithTitleAnnotation("List Prometheus label names"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -363,9 +630,10 @@ var ListPrometheusLabelNames = mcpgrafana.MustTool(
 type ListPrometheusLabelValuesParams struct {
 	DatasourceUID string     `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
 	LabelName     string     `json:"labelName" jsonschema:"required,description=The name of the label to query"`
+	Metric        string     `json:"metric,omitempty" jsonschema:"description=Optionally\\, only list the values of the label on series of this metric"`
 	Matches       []Selector `json:"matches,omitempty" jsonschema:"description=Optionally\\, a list of selectors to filter the results by"`
-	StartRFC3339  string     `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query"`
-	EndRFC3339    string     `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query"`
+	StartRFC3339  string     `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query\\, in RFC3339 format or relative to now (e.g. 'now-1h')"`
+	EndRFC3339    string     `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query\\, in RFC3339 format or relative to now"`
 	Limit         int        `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of results to return"`
 }
 
@@ -380,23 +648,14 @@ func listPrometheusLabelValues(ctx context.Context, args ListPrometheusLabelValu
 		limit = 100
 	}
 
-	var startTime, endTime time.Time
-	if args.StartRFC3339 != "" {
-		if startTime, err = time.Parse(time.RFC3339, args.StartRFC3339); err != nil {
-			return nil, fmt.Errorf("parsing start time: %w", err)
-		}
-	}
-	if args.EndRFC3339 != "" {
-		if endTime, err = time.Parse(time.RFC3339, args.EndRFC3339); err != nil {
-			return nil, fmt.Errorf("parsing end time: %w", err)
-		}
+	matchers, startTime, endTime, err := prometheusLabelScope(args.Metric, args.Matches, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
 	}
 
-	var matchers []string
-	for _, m := range args.Matches {
-		matchers = append(matchers, m.String())
+	if err := validatePathSegment("label name", args.LabelName); err != nil {
+		return nil, err
 	}
-
 	labelValues, _, err := promClient.LabelValues(ctx, args.LabelName, matchers, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("listing Prometheus label values: %w", err)
@@ -412,17 +671,118 @@ func listPrometheusLabelValues(ctx context.Context, args ListPrometheusLabelValu
 
 var ListPrometheusLabelValues = mcpgrafana.MustTool(
 	"list_prometheus_label_values",
-	"Get the values for a specific label name in Prometheus. Allows filtering by series selectors and time range.",
+	"Get the values for a specific label name in Prometheus. Allows filtering by metric, series selectors and time range. Use this with `metric` to discover the values a metric's label can be filtered by.",
 	listPrometheusLabelValues,
 	mcp.WithTitleAnnotation("List Prometheus label values"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// prometheusTimeRange parses optional start and end times, defaulting to the
+// configured Prometheus time range (the last hour by default). Times may be
+// RFC3339 or relative to now (e.g. 'now-1h').
+func prometheusTimeRange(ctx context.Context, startStr, endStr string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if startStr != "" {
+		if start, err = parseTime(startStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if endStr != "" {
+		if end, err = parseTime(endStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	return validateTimeRange(ctx, defaultsCategoryPrometheus, start, end)
+}
+
+// DefaultPrometheusSeriesLimit is the default number of series returned by
+// list_prometheus_series
+const DefaultPrometheusSeriesLimit = 100
+
+// MaxPrometheusSeriesLimit is the maximum number of series
+// list_prometheus_series returns
+const MaxPrometheusSeriesLimit = 1000
+
+// prometheusSeries is the result of list_prometheus_series
+type prometheusSeries struct {
+	Series      []model.LabelSet `json:"series"`
+	TotalSeries int              `json:"totalSeries"`
+}
+
+type ListPrometheusSeriesParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Match         []string `json:"match" jsonschema:"required,description=One or more series selectors (e.g. 'http_requests_total{job=\"api\"}'). Series matching any of them are returned"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format or relative to now (e.g. 'now-3h'). Defaults to 1 hour ago"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return (default: 100\\, max: 1000)"`
+}
+
+// listPrometheusSeries lists the label sets of the series matching any of the
+// given selectors, sorted by their labels
+func listPrometheusSeries(ctx context.Context, args ListPrometheusSeriesParams) (*prometheusSeries, error) {
+	if len(args.Match) == 0 {
+		return nil, fmt.Errorf("at least one match selector is required")
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+
+	startTime, endTime, err := prometheusTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	series, _, err := promClient.Series(ctx, args.Match, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus series: %w", err)
+	}
+	if series == nil {
+		series = []model.LabelSet{}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].String() < series[j].String()
+	})
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPrometheusSeriesLimit
+	}
+	limit = min(limit, MaxPrometheusSeriesLimit)
+	result := &prometheusSeries{Series: series, TotalSeries: len(series)}
+	if len(result.Series) > limit {
+		result.Series = result.Series[:limit]
+	}
+	return result, nil
+}
+
+var ListPrometheusSeries = mcpgrafana.MustTool(
+	"list_prometheus_series",
+	"Lists the series (unique label sets) in a Prometheus datasource matching one or more series selectors within a time range, e.g. `[{\"__name__\": \"http_requests_total\", \"job\": \"api\", \"code\": \"500\"}]`. Returns the series sorted by their labels and the total number found. Use this to see which label combinations a metric actually carries before aggregating it, rather than guessing from `list_prometheus_label_names` and `list_prometheus_label_values`. Times may be RFC3339 or relative to now and default to the last hour.",
+	guardTimeRange(listPrometheusSeries),
+	mcp.WithTitleAnnotation("List Prometheus series"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 func AddPrometheusTools(mcp *server.MCPServer) {
 	ListPrometheusMetricMetadata.Register(mcp)
 	QueryPrometheus.Register(mcp)
+	QueryPrometheusInstant.Register(mcp)
+	QueryPrometheusBatch.Register(mcp)
 	ListPrometheusMetricNames.Register(mcp)
 	ListPrometheusLabelNames.Register(mcp)
 	ListPrometheusLabelValues.Register(mcp)
+	ListPrometheusSeries.Register(mcp)
+	QueryPrometheusExemplars.Register(mcp)
+	ListPrometheusRules.Register(mcp)
+	GetPrometheusTSDBStatus.Register(mcp)
+	ValidatePromQL.Register(mcp)
+	GetPrometheusTopK.Register(mcp)
+	SuggestPrometheusRecordingRule.Register(mcp)
+	EstimatePrometheusQueryCost.Register(mcp)
+	ComparePrometheusWindows.Register(mcp)
+	addExportResources(mcp)
 }
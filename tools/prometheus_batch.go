@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// maxPrometheusBatchQueries is the most queries query_prometheus_batch
+	// runs in one call.
+	maxPrometheusBatchQueries = 20
+
+	// prometheusBatchConcurrency is the most queries of a batch run at once.
+	prometheusBatchConcurrency = 5
+)
+
+// PrometheusBatchQuery is a named PromQL expression of a batch.
+type PrometheusBatchQuery struct {
+	Name string `json:"name" jsonschema:"required,description=A unique name for the query\\, which keys its result (e.g. 'rate'\\, 'errors' or 'duration')"`
+	Expr string `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
+}
+
+type QueryPrometheusBatchParams struct {
+	DatasourceUID string                 `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Queries       []PrometheusBatchQuery `json:"queries" jsonschema:"required,description=The named PromQL expressions to evaluate (at most 20)"`
+	StartTime     string                 `json:"startTime" jsonschema:"required,description=The start time of range queries\\, or the time of instant queries. Supported formats are RFC3339\\, relative to now (e.g. 'now-1h' or '1 hour ago') or 'today' and 'yesterday'"`
+	EndTime       string                 `json:"endTime,omitempty" jsonschema:"description=The end time of range queries\\, in the same formats as startTime. Defaults to now"`
+	StepSeconds   int                    `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the step size of range queries in seconds. Defaults to a round step returning at most maxDataPoints samples per series"`
+	MaxDataPoints int                    `json:"maxDataPoints,omitempty" jsonschema:"description=Optionally\\, the maximum number of samples returned per series by range queries (default: 100)"`
+	QueryType     string                 `json:"queryType,omitempty" jsonschema:"enum=range,enum=instant,description=Optionally\\, the type of all the queries: 'range' (the default) or 'instant'"`
+}
+
+// prometheusBatchResult is the result of one query of a batch, or its error.
+type prometheusBatchResult struct {
+	Result prometheusValue `json:"result,omitzero"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func queryPrometheusBatch(ctx context.Context, args QueryPrometheusBatchParams) (map[string]prometheusBatchResult, error) {
+	if len(args.Queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	if len(args.Queries) > maxPrometheusBatchQueries {
+		return nil, fmt.Errorf("too many queries: %d (max: %d)", len(args.Queries), maxPrometheusBatchQueries)
+	}
+	seen := map[string]bool{}
+	for _, q := range args.Queries {
+		if q.Name == "" || q.Expr == "" {
+			return nil, fmt.Errorf("every query needs a name and an expr")
+		}
+		if seen[q.Name] {
+			return nil, fmt.Errorf("duplicate query name %q", q.Name)
+		}
+		seen[q.Name] = true
+	}
+
+	start, err := resolvePrometheusTime(args.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	endTime := args.EndTime
+	if endTime == "" {
+		endTime = "now"
+	}
+	end, err := resolvePrometheusTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+
+	results := make([]prometheusBatchResult, len(args.Queries))
+	sem := make(chan struct{}, prometheusBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, q := range args.Queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			value, err := queryPrometheus(ctx, QueryPrometheusParams{
+				DatasourceUID: args.DatasourceUID,
+				Expr:          q.Expr,
+				StartTime:     start,
+				EndTime:       end,
+				StepSeconds:   args.StepSeconds,
+				MaxDataPoints: args.MaxDataPoints,
+				QueryType:     args.QueryType,
+			})
+			if err != nil {
+				results[i] = prometheusBatchResult{Error: err.Error()}
+				return
+			}
+			results[i] = prometheusBatchResult{Result: decodePrometheusHistograms(value)}
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]prometheusBatchResult, len(results))
+	for i, q := range args.Queries {
+		byName[q.Name] = results[i]
+	}
+	return byName, nil
+}
+
+var QueryPrometheusBatch = mcpgrafana.MustTool(
+	"query_prometheus_batch",
+	"Evaluates several named PromQL expressions concurrently against a Prometheus datasource over the same time range, as range queries (the default) or instant queries. Returns an object keyed by query name with each query's `result`, or its `error` if that query failed, so one failing query doesn't fail the others. Use this to fetch related metrics together, such as the request rate, error rate and latency (RED metrics) of a service, instead of calling `query_prometheus` once per query.",
+	guardTimeRange(queryPrometheusBatch),
+	mcp.WithTitleAnnotation("Query Prometheus batch"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,62 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPrometheusBatch(t *testing.T) {
+	var mu sync.Mutex
+	ranges := map[string]bool{}
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		mu.Lock()
+		ranges[r.Form.Get("start")+"-"+r.Form.Get("end")] = true
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("query") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"api"},"values":[[1704067200,"1"]]}]}}`))
+	})
+
+	results, err := queryPrometheusBatch(ctx, QueryPrometheusBatchParams{
+		DatasourceUID: "prom",
+		Queries: []PrometheusBatchQuery{
+			{Name: "rate", Expr: `sum(rate(http_requests_total[5m]))`},
+			{Name: "errors", Expr: `sum(rate(http_requests_total{code=~"5.."}[5m]))`},
+			{Name: "broken", Expr: "bad"},
+		},
+		StartTime: "now-1h",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Empty(t, results["rate"].Error)
+	assert.IsType(t, model.Matrix{}, unwrapResult(results["rate"].Result))
+	assert.IsType(t, model.Matrix{}, unwrapResult(results["errors"].Result))
+	assert.Nil(t, unwrapResult(results["broken"].Result))
+	assert.Contains(t, results["broken"].Error, "parse error")
+	assert.Len(t, ranges, 1, "all queries cover the same time range")
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := queryPrometheusBatch(ctx, QueryPrometheusBatchParams{DatasourceUID: "prom", StartTime: "now-1h"})
+		assert.ErrorContains(t, err, "at least one query")
+		_, err = queryPrometheusBatch(ctx, QueryPrometheusBatchParams{
+			DatasourceUID: "prom",
+			Queries:       []PrometheusBatchQuery{{Name: "a", Expr: "up"}, {Name: "a", Expr: "up"}},
+			StartTime:     "now-1h",
+		})
+		assert.ErrorContains(t, err, `duplicate query name "a"`)
+	})
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// prometheusChunkRange is the shortest time range split into chunks, and
+	// the shortest chunk.
+	prometheusChunkRange = 24 * time.Hour
+	// maxPrometheusChunks is the most chunks a range query is split into.
+	maxPrometheusChunks = 10
+)
+
+// prometheusRangeChunks splits a range query spanning days into at most
+// maxPrometheusChunks consecutive ranges of about a day or more. Chunks start
+// on a step of the whole range and don't overlap, so together they evaluate
+// the expression at the same times as the whole range.
+func prometheusRangeChunks(r promv1.Range) []promv1.Range {
+	span := r.End.Sub(r.Start)
+	if span <= prometheusChunkRange || r.Step <= 0 {
+		return []promv1.Range{r}
+	}
+	steps := int64(span/r.Step) + 1
+	chunks := min(int64(span/prometheusChunkRange), maxPrometheusChunks)
+	stepsPerChunk := (steps + chunks - 1) / chunks
+	ranges := make([]promv1.Range, 0, chunks)
+	for first := int64(0); first < steps; first += stepsPerChunk {
+		last := min(first+stepsPerChunk, steps) - 1
+		ranges = append(ranges, promv1.Range{
+			Start: r.Start.Add(time.Duration(first) * r.Step),
+			End:   r.Start.Add(time.Duration(last) * r.Step),
+			Step:  r.Step,
+		})
+	}
+	return ranges
+}
+
+// appendMatrix appends the samples of each series of a chunk to the same
+// series of the preceding chunks. The chunk's series are copied rather than
+// reused, so the chunk is left as it was sent to the client.
+func appendMatrix(matrix, chunk model.Matrix) model.Matrix {
+	index := make(map[model.Fingerprint]*model.SampleStream, len(matrix))
+	for _, s := range matrix {
+		index[s.Metric.Fingerprint()] = s
+	}
+	for _, s := range chunk {
+		if existing, ok := index[s.Metric.Fingerprint()]; ok {
+			existing.Values = append(existing.Values, s.Values...)
+			existing.Histograms = append(existing.Histograms, s.Histograms...)
+			continue
+		}
+		copied := *s
+		index[s.Metric.Fingerprint()] = &copied
+		matrix = append(matrix, &copied)
+	}
+	return matrix
+}
+
+// queryPrometheusRangeChunked runs a range query spanning days as a sequence
+// of shorter queries, so that each datasource request stays short, streaming
+// the series of each chunk as it completes to clients which asked for
+// progress, and stopping when the context is cancelled. Shorter ranges are
+// queried at once.
+func queryPrometheusRangeChunked(ctx context.Context, promClient promv1.API, expr string, r promv1.Range) (model.Value, error) {
+	chunks := prometheusRangeChunks(r)
+	if len(chunks) == 1 {
+		result, _, err := promClient.QueryRange(ctx, expr, r)
+		return result, err
+	}
+
+	var matrix model.Matrix
+	for i, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("querying chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		result, _, err := promClient.QueryRange(ctx, expr, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("querying chunk %d of %d (%s to %s): %w", i+1, len(chunks), chunk.Start.Format(time.RFC3339), chunk.End.Format(time.RFC3339), err)
+		}
+		chunkMatrix, ok := result.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("expected a matrix result, got a %s", result.Type())
+		}
+		mcpgrafana.SendProgressData(ctx, float64(i+1), float64(len(chunks)), fmt.Sprintf("Queried %s to %s: %d series in chunk", chunk.Start.Format(time.RFC3339), chunk.End.Format(time.RFC3339), len(chunkMatrix)), chunkMatrix)
+		matrix = appendMatrix(matrix, chunkMatrix)
+	}
+	if matrix == nil {
+		matrix = model.Matrix{}
+	}
+	return matrix, nil
+}
@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusRangeChunks(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	day := promv1.Range{Start: start, End: start.Add(24 * time.Hour), Step: time.Hour}
+	assert.Equal(t, []promv1.Range{day}, prometheusRangeChunks(day))
+
+	// 73 steps over 3 days are split into 3 chunks of at most 25 steps.
+	r := promv1.Range{Start: start, End: start.Add(72 * time.Hour), Step: time.Hour}
+	chunks := prometheusRangeChunks(r)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, start, chunks[0].Start)
+	assert.Equal(t, start.Add(24*time.Hour), chunks[0].End)
+	assert.Equal(t, start.Add(25*time.Hour), chunks[1].Start)
+	assert.Equal(t, r.End, chunks[2].End)
+	for i := 1; i < len(chunks); i++ {
+		assert.Equal(t, chunks[i-1].End.Add(r.Step), chunks[i].Start)
+	}
+
+	// Long ranges are split into at most 10 chunks: 61 steps into chunks of
+	// 7 steps.
+	month := promv1.Range{Start: start, End: start.Add(30 * 24 * time.Hour), Step: 12 * time.Hour}
+	chunks = prometheusRangeChunks(month)
+	assert.Len(t, chunks, 9)
+	assert.Equal(t, month.End, chunks[len(chunks)-1].End)
+}
+
+func TestQueryPrometheusRangeChunked(t *testing.T) {
+	var requests int
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		requests++
+		start, err := strconv.ParseFloat(r.Form.Get("start"), 64)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		// Series b only has samples in the first chunk.
+		b := ""
+		if requests == 1 {
+			b = fmt.Sprintf(`,{"metric":{"pod":"b"},"values":[[%.0f,"2"]]}`, start)
+		}
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"pod":"a"},"values":[[%.0f,"%d"]]}%s]}}`, start, requests, b)
+	})
+	promClient, err := promClientFromContext(ctx, "prom")
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := promv1.Range{Start: start, End: start.Add(72 * time.Hour), Step: time.Hour}
+	result, err := queryPrometheusRangeChunked(ctx, promClient, "up", r)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	matrix, ok := result.(model.Matrix)
+	require.True(t, ok)
+	require.Len(t, matrix, 2)
+	assert.Equal(t, model.LabelValue("a"), matrix[0].Metric["pod"])
+	require.Len(t, matrix[0].Values, 3)
+	assert.Equal(t, model.TimeFromUnix(start.Add(25*time.Hour).Unix()), matrix[0].Values[1].Timestamp)
+	assert.Equal(t, model.SampleValue(3), matrix[0].Values[2].Value)
+	assert.Len(t, matrix[1].Values, 1)
+
+	// Cancelled queries stop before the next chunk.
+	requests = 0
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = queryPrometheusRangeChunked(cancelled, promClient, "up", r)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, requests)
+}
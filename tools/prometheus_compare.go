@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// DefaultPrometheusCompareOffset is the default offset of the baseline
+	// window, comparing with the same hours last week.
+	DefaultPrometheusCompareOffset = 7 * 24 * time.Hour
+	// DefaultPrometheusCompareLimit is the default number of series returned
+	// by compare_prometheus_windows.
+	DefaultPrometheusCompareLimit = 20
+	// MaxPrometheusCompareLimit is the maximum number of series returned by
+	// compare_prometheus_windows.
+	MaxPrometheusCompareLimit = 100
+)
+
+type ComparePrometheusWindowsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to compare\\, e.g. 'sum(rate(http_requests_total[5m]))'"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the target window\\, in RFC3339 format or relative to now (e.g. 'now-6h' or '6 hours ago'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the target window\\, in the same formats as startTime. Defaults to now"`
+	Offset        string `json:"offset,omitempty" jsonschema:"description=Optionally\\, how long before the target window the baseline window is\\, as a Prometheus duration such as '1d' or '1w' (default: 1w)"`
+	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the step of both windows in seconds. Defaults to a round step giving at most 100 samples per window"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return\\, those which changed the most first (default: 20\\, max: 100)"`
+	IncludePoints bool   `json:"includePoints,omitempty" jsonschema:"description=Optionally\\, also return the aligned samples of each series with their deltas"`
+}
+
+// prometheusWindowPoint is a step of the target window with the sample of
+// the baseline window the offset before it.
+type prometheusWindowPoint struct {
+	Time         string   `json:"time"`
+	Target       float64  `json:"target"`
+	Baseline     float64  `json:"baseline"`
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+}
+
+// prometheusWindowSeries compares a series in the target and baseline
+// windows.
+type prometheusWindowSeries struct {
+	Labels model.Metric `json:"labels"`
+	// OnlyIn is "target" or "baseline" for series missing from the other
+	// window.
+	OnlyIn string `json:"onlyIn,omitempty"`
+	// TargetAvg and BaselineAvg are the means of the samples of the steps
+	// present in both windows, or of all samples of series in one window.
+	TargetAvg   *float64 `json:"targetAvg,omitempty"`
+	BaselineAvg *float64 `json:"baselineAvg,omitempty"`
+	// DeltaPercent is the change of TargetAvg from BaselineAvg, omitted if
+	// BaselineAvg is zero or missing.
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+	// MaxDeltaPercent is the largest change of an aligned step, by absolute
+	// value.
+	MaxDeltaPercent *float64                `json:"maxDeltaPercent,omitempty"`
+	AlignedSteps    int                     `json:"alignedSteps"`
+	Points          []prometheusWindowPoint `json:"points,omitempty"`
+}
+
+// prometheusWindowComparison is the result of compare_prometheus_windows.
+type prometheusWindowComparison struct {
+	Expr          string `json:"expr"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	BaselineStart string `json:"baselineStart"`
+	BaselineEnd   string `json:"baselineEnd"`
+	Offset        string `json:"offset"`
+	Step          string `json:"step"`
+	// TotalSeries is the number of series in either window, of which the
+	// most changed are returned.
+	TotalSeries int                      `json:"totalSeries"`
+	Series      []prometheusWindowSeries `json:"series"`
+}
+
+// deltaPercent returns the change from baseline to target in percent,
+// rounded to two decimals, or nil if the baseline is zero.
+func deltaPercent(target, baseline float64) *float64 {
+	if baseline == 0 {
+		return nil
+	}
+	d := math.Round((target-baseline)/math.Abs(baseline)*10000) / 100
+	return &d
+}
+
+// finiteMean returns the mean of the finite values, or nil if there are
+// none.
+func finiteMean(values []float64) *float64 {
+	sum, n := 0.0, 0
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	mean := sum / float64(n)
+	return &mean
+}
+
+func sampleValues(pairs []model.SamplePair) []float64 {
+	values := make([]float64, len(pairs))
+	for i, p := range pairs {
+		values[i] = float64(p.Value)
+	}
+	return values
+}
+
+// compareWindowSeries aligns the samples of a series in the baseline window
+// with those of the target window the offset later. Either may be nil for
+// series missing from a window.
+func compareWindowSeries(target, baseline *model.SampleStream, offset time.Duration, includePoints bool) prometheusWindowSeries {
+	switch {
+	case baseline == nil:
+		return prometheusWindowSeries{Labels: target.Metric, OnlyIn: "target", TargetAvg: finiteMean(sampleValues(target.Values))}
+	case target == nil:
+		return prometheusWindowSeries{Labels: baseline.Metric, OnlyIn: "baseline", BaselineAvg: finiteMean(sampleValues(baseline.Values))}
+	}
+
+	baselineAt := make(map[model.Time]float64, len(baseline.Values))
+	for _, p := range baseline.Values {
+		baselineAt[p.Timestamp.Add(offset)] = float64(p.Value)
+	}
+	series := prometheusWindowSeries{Labels: target.Metric}
+	var targetValues, baselineValues []float64
+	for _, p := range target.Values {
+		b, ok := baselineAt[p.Timestamp]
+		t := float64(p.Value)
+		if !ok || math.IsNaN(t) || math.IsInf(t, 0) || math.IsNaN(b) || math.IsInf(b, 0) {
+			continue
+		}
+		targetValues = append(targetValues, t)
+		baselineValues = append(baselineValues, b)
+		delta := deltaPercent(t, b)
+		if delta != nil && (series.MaxDeltaPercent == nil || math.Abs(*delta) > math.Abs(*series.MaxDeltaPercent)) {
+			series.MaxDeltaPercent = delta
+		}
+		if includePoints {
+			series.Points = append(series.Points, prometheusWindowPoint{
+				Time:         p.Timestamp.Time().UTC().Format(time.RFC3339),
+				Target:       t,
+				Baseline:     b,
+				DeltaPercent: delta,
+			})
+		}
+	}
+	series.AlignedSteps = len(targetValues)
+	series.TargetAvg, series.BaselineAvg = finiteMean(targetValues), finiteMean(baselineValues)
+	if series.TargetAvg != nil && series.BaselineAvg != nil {
+		series.DeltaPercent = deltaPercent(*series.TargetAvg, *series.BaselineAvg)
+	}
+	return series
+}
+
+// windowChange orders compared series: those in only one window first, then
+// by their absolute change, with series whose change is unknown last.
+func windowChange(s prometheusWindowSeries) float64 {
+	switch {
+	case s.OnlyIn != "":
+		return math.Inf(1)
+	case s.DeltaPercent == nil:
+		return -1
+	}
+	return math.Abs(*s.DeltaPercent)
+}
+
+func comparePrometheusWindows(ctx context.Context, args ComparePrometheusWindowsParams) (*prometheusWindowComparison, error) {
+	if args.Expr == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	offset := DefaultPrometheusCompareOffset
+	if args.Offset != "" {
+		d, err := model.ParseDuration(args.Offset)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid offset %q, must be a positive duration such as '1d' or '1w'", args.Offset)
+		}
+		offset = time.Duration(d)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPrometheusCompareLimit
+	}
+	limit = min(limit, MaxPrometheusCompareLimit)
+
+	start, end, err := prometheusTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	baselineStart, baselineEnd := start.Add(-offset), end.Add(-offset)
+	if lookback := toolDefaultsFor(ctx, defaultsCategoryPrometheus).MaxLookback; lookback > 0 && baselineStart.Before(time.Now().Add(-lookback)) {
+		return nil, fmt.Errorf("baseline window starts at %s, before the maximum lookback of %s for %s", baselineStart.Format(time.RFC3339), lookback, defaultsCategoryPrometheus)
+	}
+	step := prometheusStep(ctx, args.StepSeconds, start, end, DefaultPrometheusMaxDataPoints)
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	queryWindow := func(start, end time.Time) (model.Matrix, error) {
+		result, _, err := promClient.QueryRange(ctx, args.Expr, promv1.Range{Start: start, End: end, Step: step})
+		if err != nil {
+			return nil, fmt.Errorf("querying Prometheus range: %w", err)
+		}
+		matrix, ok := result.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("expected a matrix result, got a %s", result.Type())
+		}
+		return matrix, nil
+	}
+	target, err := queryWindow(start, end)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := queryWindow(baselineStart, baselineEnd)
+	if err != nil {
+		return nil, fmt.Errorf("baseline window: %w", err)
+	}
+
+	baselineByLabels := make(map[model.Fingerprint]*model.SampleStream, len(baseline))
+	for _, s := range baseline {
+		baselineByLabels[s.Metric.Fingerprint()] = s
+	}
+	var series []prometheusWindowSeries
+	for _, s := range target {
+		fp := s.Metric.Fingerprint()
+		series = append(series, compareWindowSeries(s, baselineByLabels[fp], offset, args.IncludePoints))
+		delete(baselineByLabels, fp)
+	}
+	for _, s := range baseline {
+		if _, ok := baselineByLabels[s.Metric.Fingerprint()]; ok {
+			series = append(series, compareWindowSeries(nil, s, offset, args.IncludePoints))
+		}
+	}
+	sort.SliceStable(series, func(i, j int) bool { return windowChange(series[i]) > windowChange(series[j]) })
+
+	comparison := &prometheusWindowComparison{
+		Expr:          args.Expr,
+		Start:         start.Format(time.RFC3339),
+		End:           end.Format(time.RFC3339),
+		BaselineStart: baselineStart.Format(time.RFC3339),
+		BaselineEnd:   baselineEnd.Format(time.RFC3339),
+		Offset:        model.Duration(offset).String(),
+		Step:          step.String(),
+		TotalSeries:   len(series),
+		Series:        series[:min(len(series), limit)],
+	}
+	if comparison.Series == nil {
+		comparison.Series = []prometheusWindowSeries{}
+	}
+	return comparison, nil
+}
+
+var ComparePrometheusWindows = mcpgrafana.MustTool(
+	"compare_prometheus_windows",
+	"Compares a PromQL expression over a target time window (default: the last hour) with a baseline window an offset earlier (default: 1w, the same hours last week), answering questions like \"is this normal for a Monday?\" in one call. Both windows are queried with the same step and their series matched by labels and aligned step by step. Returns for each series its average in both windows and the change in percent, and the largest change of a single step, with the series which changed the most, and those present in only one window, first. Set `includePoints` to also get the aligned samples.",
+	guardTimeRange(comparePrometheusWindows),
+	mcp.WithTitleAnnotation("Compare Prometheus time windows"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
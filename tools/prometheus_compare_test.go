@@ -0,0 +1,98 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareWindowSeries(t *testing.T) {
+	offset := 24 * time.Hour
+	day := model.Time(offset.Milliseconds())
+	metric := model.Metric{"job": "api"}
+	baseline := &model.SampleStream{Metric: metric, Values: []model.SamplePair{{Timestamp: 0, Value: 10}, {Timestamp: 60000, Value: 20}, {Timestamp: 120000, Value: 0}}}
+	target := &model.SampleStream{Metric: metric, Values: []model.SamplePair{{Timestamp: day, Value: 12}, {Timestamp: day + 60000, Value: 30}, {Timestamp: day + 120000, Value: 5}, {Timestamp: day + 180000, Value: 100}}}
+
+	s := compareWindowSeries(target, baseline, offset, true)
+	// The last target step has no baseline sample.
+	assert.Equal(t, 3, s.AlignedSteps)
+	assert.InDelta(t, 47.0/3, *s.TargetAvg, 1e-9)
+	assert.InDelta(t, 10, *s.BaselineAvg, 1e-9)
+	assert.Equal(t, 56.67, *s.DeltaPercent)
+	assert.Equal(t, 50.0, *s.MaxDeltaPercent)
+	require.Len(t, s.Points, 3)
+	assert.Equal(t, 20.0, *s.Points[0].DeltaPercent)
+	assert.Nil(t, s.Points[2].DeltaPercent)
+
+	s = compareWindowSeries(target, nil, offset, false)
+	assert.Equal(t, "target", s.OnlyIn)
+	assert.InDelta(t, 36.75, *s.TargetAvg, 1e-9)
+	assert.Nil(t, s.DeltaPercent)
+}
+
+func TestComparePrometheusWindows(t *testing.T) {
+	var starts []string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		starts = append(starts, r.Form.Get("start"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("start") == "1704067200" {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"pod":"a"},"values":[[1704067200,"10"],[1704069000,"10"]]},
+				{"metric":{"pod":"b"},"values":[[1704067200,"10"],[1704069000,"10"]]},
+				{"metric":{"pod":"c"},"values":[[1704067200,"1"]]}
+			]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"pod":"a"},"values":[[1704672000,"11"],[1704673800,"11"]]},
+			{"metric":{"pod":"b"},"values":[[1704672000,"20"],[1704673800,"30"]]},
+			{"metric":{"pod":"d"},"values":[[1704672000,"1"]]}
+		]}}`))
+	})
+
+	comparison, err := comparePrometheusWindows(ctx, ComparePrometheusWindowsParams{
+		DatasourceUID: "prom",
+		Expr:          "sum by (pod) (rate(http_requests_total[5m]))",
+		StartTime:     "2024-01-08T00:00:00Z",
+		EndTime:       "2024-01-08T01:00:00Z",
+		StepSeconds:   1800,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1704672000", "1704067200"}, starts)
+	assert.Equal(t, "2024-01-01T00:00:00Z", comparison.BaselineStart)
+	assert.Equal(t, "1w", comparison.Offset)
+	assert.Equal(t, 4, comparison.TotalSeries)
+
+	var order []string
+	for _, s := range comparison.Series {
+		order = append(order, string(s.Labels["pod"]))
+	}
+	assert.Equal(t, []string{"d", "c", "b", "a"}, order)
+	assert.Equal(t, "baseline", comparison.Series[1].OnlyIn)
+	assert.Equal(t, 150.0, *comparison.Series[2].DeltaPercent)
+	assert.Equal(t, 10.0, *comparison.Series[3].DeltaPercent)
+
+	comparison, err = comparePrometheusWindows(ctx, ComparePrometheusWindowsParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2024-01-08T00:00:00Z",
+		EndTime:       "2024-01-08T01:00:00Z",
+		StepSeconds:   1800,
+		Limit:         1,
+	})
+	require.NoError(t, err)
+	assert.Len(t, comparison.Series, 1)
+	assert.Equal(t, 4, comparison.TotalSeries)
+
+	_, err = comparePrometheusWindows(ctx, ComparePrometheusWindowsParams{DatasourceUID: "prom", Expr: "up", Offset: "-1d"})
+	assert.Error(t, err)
+}
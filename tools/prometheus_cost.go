@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// DefaultPrometheusCostProbe is the length of the end of a query's time range
+// estimate_prometheus_query_cost runs the query over, unless ten steps are
+// longer.
+const DefaultPrometheusCostProbe = time.Hour
+
+const (
+	// maxEstimatedPeakSamples is Prometheus' default --query.max-samples, the
+	// most samples a query may hold in memory at once.
+	maxEstimatedPeakSamples = 50_000_000
+
+	// maxEstimatedEvalSeconds is Grafana's default data proxy timeout.
+	maxEstimatedEvalSeconds = 30
+)
+
+type EstimatePrometheusQueryCostParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to estimate the cost of"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the range query to estimate\\, in RFC3339 format or relative to now (e.g. 'now-30d' or '30 days ago'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the range query to estimate\\, in the same formats as startTime. Defaults to now"`
+	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=Optionally\\, the step of the range query in seconds. Defaults to the step query_prometheus would use"`
+	MaxDataPoints int    `json:"maxDataPoints,omitempty" jsonschema:"description=Optionally\\, the maxDataPoints the range query would be run with (default: 100)\\, which determines its default step"`
+}
+
+// prometheusCostProbe is the measured cost of running a query over the end
+// of its time range.
+type prometheusCostProbe struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Steps  int64  `json:"steps"`
+	Series int    `json:"series"`
+	// The statistics are omitted if the datasource doesn't return them.
+	EvalSeconds           *float64 `json:"evalSeconds,omitempty"`
+	TotalQueryableSamples *int64   `json:"totalQueryableSamples,omitempty"`
+	PeakSamples           *int64   `json:"peakSamples,omitempty"`
+}
+
+// prometheusCostEstimate is the cost of a query over its whole time range,
+// extrapolated from the probe by its number of steps.
+type prometheusCostEstimate struct {
+	EvalSeconds           *float64 `json:"evalSeconds,omitempty"`
+	TotalQueryableSamples *int64   `json:"totalQueryableSamples,omitempty"`
+	// PeakSamples is an upper bound, since not every sample of a range query
+	// is held in memory at once.
+	PeakSamples *int64 `json:"peakSamples,omitempty"`
+	// ResultSamples is the number of samples returned if every series of the
+	// probe has a sample at every step.
+	ResultSamples int64 `json:"resultSamples"`
+}
+
+// prometheusQueryCostReport is the result of estimate_prometheus_query_cost.
+type prometheusQueryCostReport struct {
+	Expr  string `json:"expr"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Step  string `json:"step"`
+	Steps int64  `json:"steps"`
+	// SeriesTouched is the number of series matched by the selectors of the
+	// expression over the whole time range.
+	SeriesTouched int                          `json:"seriesTouched"`
+	Selectors     []prometheusSelectorEstimate `json:"selectors"`
+	Probe         prometheusCostProbe          `json:"probe"`
+	Estimated     prometheusCostEstimate       `json:"estimated"`
+	Warnings      []string                     `json:"warnings,omitempty"`
+}
+
+// prometheusRangeSteps returns the number of evaluations of a range query.
+func prometheusRangeSteps(start, end time.Time, step time.Duration) int64 {
+	return int64(end.Sub(start)/step) + 1
+}
+
+func estimatePrometheusQueryCost(ctx context.Context, args EstimatePrometheusQueryCostParams) (*prometheusQueryCostReport, error) {
+	if args.Expr == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	start, end, err := prometheusTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	step := prometheusStep(ctx, args.StepSeconds, start, end, args.MaxDataPoints)
+	report := &prometheusQueryCostReport{
+		Expr:  args.Expr,
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
+		Step:  step.String(),
+		Steps: prometheusRangeSteps(start, end, step),
+	}
+
+	estimate, err := estimatePrometheusQuery(ctx, QueryPrometheusParams{
+		DatasourceUID: args.DatasourceUID,
+		Expr:          args.Expr,
+		StartTime:     report.Start,
+		EndTime:       report.End,
+		StepSeconds:   int(step / time.Second),
+		QueryType:     "range",
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.SeriesTouched, report.Selectors = estimate.Series, estimate.Selectors
+
+	// Run the query over the end of the time range, with enough steps to
+	// measure the cost of each evaluation.
+	probeStart := end.Add(-max(DefaultPrometheusCostProbe, 10*step))
+	if probeStart.Before(start) {
+		probeStart = start
+	}
+	matrix, stats, err := queryPrometheusRangeWithStats(ctx, args.DatasourceUID, args.Expr, promv1.Range{Start: probeStart, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	}
+	report.Probe = prometheusCostProbe{
+		Start:  probeStart.Format(time.RFC3339),
+		End:    report.End,
+		Steps:  prometheusRangeSteps(probeStart, end, step),
+		Series: len(matrix),
+	}
+	report.Estimated.ResultSamples = int64(len(matrix)) * report.Steps
+	if stats == nil {
+		report.Warnings = append(report.Warnings, "The datasource didn't return query statistics, so only the number of series was measured.")
+		return report, nil
+	}
+
+	ratio := float64(report.Steps) / float64(report.Probe.Steps)
+	evalSeconds := stats.Timings.EvalTotalTime * ratio
+	totalSamples := int64(float64(stats.Samples.TotalQueryableSamples) * ratio)
+	peakSamples := int64(float64(stats.Samples.PeakSamples) * ratio)
+	report.Probe.EvalSeconds = &stats.Timings.EvalTotalTime
+	report.Probe.TotalQueryableSamples = &stats.Samples.TotalQueryableSamples
+	report.Probe.PeakSamples = &stats.Samples.PeakSamples
+	report.Estimated.EvalSeconds = &evalSeconds
+	report.Estimated.TotalQueryableSamples = &totalSamples
+	report.Estimated.PeakSamples = &peakSamples
+
+	if peakSamples > maxEstimatedPeakSamples {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("The query may hold up to %d samples in memory at once, more than Prometheus allows by default (%d), and fail. Use a shorter time range, a longer step or a recording rule.", peakSamples, maxEstimatedPeakSamples))
+	}
+	if evalSeconds > maxEstimatedEvalSeconds {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("The query is estimated to take %.0fs to evaluate, longer than Grafana's default data proxy timeout (%ds).", evalSeconds, maxEstimatedEvalSeconds))
+	}
+	return report, nil
+}
+
+var EstimatePrometheusQueryCost = mcpgrafana.MustTool(
+	"estimate_prometheus_query_cost",
+	"Estimates the cost of a PromQL range query before running it. Counts the series matched by the expression's selectors over the whole time range, and runs the query with query statistics over only the end of the time range (the last hour, or ten steps if longer) to measure its evaluation time and the samples it reads. These are extrapolated to the whole time range by its number of steps, with warnings when the query would likely exceed Prometheus' sample limit or Grafana's timeout. Use this before running long range queries, such as over 30 days, with `query_prometheus`, whose default step it uses.",
+	guardTimeRange(estimatePrometheusQueryCost),
+	mcp.WithTitleAnnotation("Estimate Prometheus query cost"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,79 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatePrometheusQueryCost(t *testing.T) {
+	var probe map[string][]string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/series":
+			assert.Equal(t, []string{`{__name__="http_requests_total"}`}, r.Form["match[]"])
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"http_requests_total","pod":"a"},{"__name__":"http_requests_total","pod":"b"},{"__name__":"http_requests_total","pod":"c"}]}`))
+		case "/api/v1/query_range":
+			probe = r.PostForm
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"pod":"a"},"values":[[1706742000,"1"]]},
+				{"metric":{"pod":"b"},"values":[[1706742000,"2"]]}
+			],"stats":{"timings":{"evalTotalTime":0.55},"samples":{"totalQueryableSamples":1100000,"peakSamples":1100000}}}}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	report, err := estimatePrometheusQueryCost(ctx, EstimatePrometheusQueryCostParams{
+		DatasourceUID: "prom",
+		Expr:          `sum by (pod) (rate(http_requests_total[5m]))`,
+		StartTime:     "2024-01-01T00:00:00Z",
+		EndTime:       "2024-01-31T00:00:00Z",
+		StepSeconds:   3600,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1h0m0s", report.Step)
+	assert.Equal(t, int64(721), report.Steps)
+	assert.Equal(t, 3, report.SeriesTouched)
+
+	// The probe covers the last ten steps.
+	assert.Equal(t, []string{"all"}, probe["stats"])
+	assert.Equal(t, []string{"1706623200"}, probe["start"])
+	assert.Equal(t, "2024-01-30T14:00:00Z", report.Probe.Start)
+	assert.Equal(t, int64(11), report.Probe.Steps)
+	assert.Equal(t, 2, report.Probe.Series)
+	assert.Equal(t, int64(1100000), *report.Probe.TotalQueryableSamples)
+
+	require.NotNil(t, report.Estimated.EvalSeconds)
+	assert.InDelta(t, 36.05, *report.Estimated.EvalSeconds, 1e-6)
+	assert.InDelta(t, 72_100_000, float64(*report.Estimated.TotalQueryableSamples), 1)
+	assert.InDelta(t, 72_100_000, float64(*report.Estimated.PeakSamples), 1)
+	assert.Equal(t, int64(2*721), report.Estimated.ResultSamples)
+	assert.Len(t, report.Warnings, 2)
+}
+
+func TestEstimatePrometheusQueryCostWithoutStats(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/series" {
+			_, _ = w.Write([]byte(`{"status":"success","data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	})
+
+	report, err := estimatePrometheusQueryCost(ctx, EstimatePrometheusQueryCostParams{DatasourceUID: "prom", Expr: "up"})
+	require.NoError(t, err)
+	// The whole default hour is probed.
+	assert.Equal(t, report.Start, report.Probe.Start)
+	assert.Equal(t, report.Steps, report.Probe.Steps)
+	assert.Nil(t, report.Estimated.EvalSeconds)
+	assert.Len(t, report.Warnings, 1)
+}
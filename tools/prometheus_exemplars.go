@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultPrometheusExemplarLimit is the default number of exemplars returned
+// per series by query_prometheus_exemplars.
+const DefaultPrometheusExemplarLimit = 10
+
+type QueryPrometheusExemplarsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression whose series' exemplars to return\\, e.g. 'http_request_duration_seconds_bucket{job=\"api\"}'"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-15m'). Defaults to 1 hour ago"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of exemplars to return per series\\, most recent first (default: 10)"`
+}
+
+// prometheusExemplar is an exemplar of a series, usually linking one of its
+// samples to the trace it was recorded in.
+type prometheusExemplar struct {
+	// TraceID is the exemplar's trace ID label, if it has one.
+	TraceID   string         `json:"traceId,omitempty"`
+	Labels    model.LabelSet `json:"labels"`
+	Value     float64        `json:"value"`
+	Timestamp string         `json:"timestamp"`
+}
+
+// prometheusSeriesExemplars are the exemplars of a series.
+type prometheusSeriesExemplars struct {
+	SeriesLabels model.LabelSet       `json:"seriesLabels"`
+	Exemplars    []prometheusExemplar `json:"exemplars"`
+}
+
+// prometheusExemplars is the result of query_prometheus_exemplars.
+type prometheusExemplars struct {
+	Series []prometheusSeriesExemplars `json:"series"`
+	// TraceIDs are the distinct trace IDs of the returned exemplars, most
+	// recent first.
+	TraceIDs []string `json:"traceIds"`
+}
+
+// exemplarTraceID returns the trace ID label of an exemplar, if any.
+func exemplarTraceID(labels model.LabelSet) string {
+	for _, label := range traceIDLabels {
+		if v := labels[model.LabelName(label)]; v != "" {
+			return string(v)
+		}
+	}
+	return ""
+}
+
+func queryPrometheusExemplars(ctx context.Context, args QueryPrometheusExemplarsParams) (*prometheusExemplars, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	startTime, endTime, err := prometheusTimeRange(ctx, args.StartRFC3339, args.EndRFC3339)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPrometheusExemplarLimit
+	}
+
+	results, err := promClient.QueryExemplars(ctx, args.Expr, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus exemplars: %w", err)
+	}
+
+	result := &prometheusExemplars{Series: []prometheusSeriesExemplars{}, TraceIDs: []string{}}
+	type tracedExemplar struct {
+		traceID string
+		ts      model.Time
+	}
+	var traced []tracedExemplar
+	for _, r := range results {
+		exemplars := r.Exemplars
+		sort.SliceStable(exemplars, func(i, j int) bool { return exemplars[i].Timestamp > exemplars[j].Timestamp })
+		if len(exemplars) > limit {
+			exemplars = exemplars[:limit]
+		}
+		series := prometheusSeriesExemplars{SeriesLabels: r.SeriesLabels, Exemplars: make([]prometheusExemplar, 0, len(exemplars))}
+		for _, e := range exemplars {
+			traceID := exemplarTraceID(e.Labels)
+			series.Exemplars = append(series.Exemplars, prometheusExemplar{
+				TraceID:   traceID,
+				Labels:    e.Labels,
+				Value:     float64(e.Value),
+				Timestamp: e.Timestamp.Time().UTC().Format(time.RFC3339Nano),
+			})
+			if traceID != "" {
+				traced = append(traced, tracedExemplar{traceID: traceID, ts: e.Timestamp})
+			}
+		}
+		result.Series = append(result.Series, series)
+	}
+	sort.SliceStable(result.Series, func(i, j int) bool {
+		return result.Series[i].SeriesLabels.String() < result.Series[j].SeriesLabels.String()
+	})
+
+	sort.SliceStable(traced, func(i, j int) bool { return traced[i].ts > traced[j].ts })
+	seen := map[string]bool{}
+	for _, t := range traced {
+		if !seen[t.traceID] {
+			seen[t.traceID] = true
+			result.TraceIDs = append(result.TraceIDs, t.traceID)
+		}
+	}
+	return result, nil
+}
+
+var QueryPrometheusExemplars = mcpgrafana.MustTool(
+	"query_prometheus_exemplars",
+	"Returns the exemplars of the series selected by a PromQL expression within a time range, grouped by series with the most recent first. Exemplars link samples, typically of histogram buckets such as `http_request_duration_seconds_bucket`, to the traces they were recorded in: each exemplar's trace ID is returned in `traceId`, and the distinct trace IDs of all exemplars in `traceIds`. Use this to pivot from a metric, such as a latency spike, to example traces, then fetch them with the Tempo tools. Times may be RFC3339 or relative to now and default to the last hour.",
+	guardTimeRange(queryPrometheusExemplars),
+	mcp.WithTitleAnnotation("Query Prometheus exemplars"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,56 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPrometheusExemplars(t *testing.T) {
+	var query string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/query_exemplars", r.URL.Path)
+		query = r.Form.Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"seriesLabels":{"__name__":"http_request_duration_seconds_bucket","le":"1","job":"web"},
+			 "exemplars":[{"labels":{"span_id":"b7ad6b7169203331"},"value":"0.2","timestamp":1704067100}]},
+			{"seriesLabels":{"__name__":"http_request_duration_seconds_bucket","le":"1","job":"api"},
+			 "exemplars":[
+				{"labels":{"trace_id":"aaaa"},"value":"0.5","timestamp":1704067000},
+				{"labels":{"traceID":"bbbb"},"value":"0.7","timestamp":1704067200.5},
+				{"labels":{"trace_id":"aaaa"},"value":"0.4","timestamp":1704066900}
+			 ]}
+		]}`))
+	})
+
+	result, err := queryPrometheusExemplars(ctx, QueryPrometheusExemplarsParams{
+		DatasourceUID: "prom",
+		Expr:          `http_request_duration_seconds_bucket{le="1"}`,
+		StartRFC3339:  "2024-01-01T00:00:00Z",
+		EndRFC3339:    "2024-01-01T01:00:00Z",
+		Limit:         2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `http_request_duration_seconds_bucket{le="1"}`, query)
+	require.Len(t, result.Series, 2)
+
+	api := result.Series[0]
+	assert.Equal(t, model.LabelValue("api"), api.SeriesLabels["job"])
+	assert.Equal(t, []prometheusExemplar{
+		{TraceID: "bbbb", Labels: model.LabelSet{"traceID": "bbbb"}, Value: 0.7, Timestamp: "2024-01-01T00:00:00.5Z"},
+		{TraceID: "aaaa", Labels: model.LabelSet{"trace_id": "aaaa"}, Value: 0.5, Timestamp: "2023-12-31T23:56:40Z"},
+	}, api.Exemplars)
+
+	web := result.Series[1]
+	require.Len(t, web.Exemplars, 1)
+	assert.Empty(t, web.Exemplars[0].TraceID)
+	assert.Equal(t, []string{"bbbb", "aaaa"}, result.TraceIDs)
+}
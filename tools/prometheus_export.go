@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// MaxPrometheusExportDataPoints is the maximum number of samples per
+	// series of exported range queries, the most Prometheus returns.
+	MaxPrometheusExportDataPoints = 11000
+
+	// maxPrometheusExportSeriesStats is the most series summarized inline
+	// by exports.
+	maxPrometheusExportSeriesStats = 50
+)
+
+// prometheusSeriesStats summarizes the samples of an exported series. NaN
+// and infinite samples are counted but not summarized.
+type prometheusSeriesStats struct {
+	Labels  model.Metric `json:"labels"`
+	Samples int          `json:"samples"`
+	Min     float64      `json:"min"`
+	Max     float64      `json:"max"`
+	Avg     float64      `json:"avg"`
+	Last    float64      `json:"last"`
+}
+
+// prometheusExport is returned by query_prometheus instead of results which
+// were exported to a resource.
+type prometheusExport struct {
+	*exportSummary
+	Series int `json:"series"`
+	// SeriesStats summarizes the first series of the export.
+	SeriesStats []prometheusSeriesStats `json:"seriesStats"`
+}
+
+// prometheusSampleRow is a sample of an exported result.
+type prometheusSampleRow struct {
+	Timestamp string       `json:"timestamp"`
+	Labels    model.Metric `json:"labels"`
+	// Value is a string as in the Prometheus API, since JSON has no NaN.
+	Value string `json:"value"`
+}
+
+var errPrometheusHistogramExport = errors.New("native histograms can't be exported, export histogram_count(), histogram_sum() or histogram_quantile() of them instead")
+
+// prometheusResultSeries returns the series of a vector or matrix result.
+func prometheusResultSeries(value model.Value) ([]*model.SampleStream, error) {
+	switch v := value.(type) {
+	case model.Matrix:
+		if matrixHasHistograms(v) {
+			return nil, errPrometheusHistogramExport
+		}
+		return v, nil
+	case model.Vector:
+		if vectorHasHistograms(v) {
+			return nil, errPrometheusHistogramExport
+		}
+		series := make([]*model.SampleStream, 0, len(v))
+		for _, s := range v {
+			series = append(series, &model.SampleStream{
+				Metric: s.Metric,
+				Values: []model.SamplePair{{Timestamp: s.Timestamp, Value: s.Value}},
+			})
+		}
+		return series, nil
+	}
+	return nil, fmt.Errorf("only vector and matrix results can be exported, got a %s", value.Type())
+}
+
+// summarizeSeries returns the statistics of a series.
+func summarizeSeries(s *model.SampleStream) prometheusSeriesStats {
+	stats := prometheusSeriesStats{Labels: s.Metric, Samples: len(s.Values)}
+	n, sum := 0, 0.0
+	for _, p := range s.Values {
+		v := float64(p.Value)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if n == 0 || v < stats.Min {
+			stats.Min = v
+		}
+		if n == 0 || v > stats.Max {
+			stats.Max = v
+		}
+		n++
+		sum += v
+		stats.Last = v
+	}
+	if n > 0 {
+		stats.Avg = sum / float64(n)
+	}
+	return stats
+}
+
+// exportPrometheusResult encodes the samples of a result as NDJSON, one
+// sample per line, or as CSV with a timestamp column, a column per label and
+// a value column, and stores them as a resource. Summaries of the series are
+// returned with the resource.
+func exportPrometheusResult(ctx context.Context, format string, value model.Value) (*prometheusExport, error) {
+	if err := validateExportFormat(format); err != nil {
+		return nil, err
+	}
+	series, err := prometheusResultSeries(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var mimeType string
+	samples := 0
+	switch format {
+	case exportFormatNDJSON:
+		mimeType = "application/x-ndjson"
+		enc := json.NewEncoder(&buf)
+		for _, s := range series {
+			for _, p := range s.Values {
+				if err := enc.Encode(prometheusSampleRow{
+					Timestamp: p.Timestamp.Time().UTC().Format(time.RFC3339Nano),
+					Labels:    s.Metric,
+					Value:     p.Value.String(),
+				}); err != nil {
+					return nil, fmt.Errorf("encoding sample: %w", err)
+				}
+				samples++
+			}
+		}
+	case exportFormatCSV:
+		mimeType = "text/csv"
+		seen := map[model.LabelName]bool{}
+		var names []string
+		for _, s := range series {
+			for name := range s.Metric {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, string(name))
+				}
+			}
+		}
+		sort.Strings(names)
+		w := csv.NewWriter(&buf)
+		_ = w.Write(append(append([]string{"timestamp"}, names...), "value"))
+		row := make([]string, len(names)+2)
+		for _, s := range series {
+			for i, name := range names {
+				row[i+1] = string(s.Metric[model.LabelName(name)])
+			}
+			for _, p := range s.Values {
+				row[0] = p.Timestamp.Time().UTC().Format(time.RFC3339Nano)
+				row[len(row)-1] = strconv.FormatFloat(float64(p.Value), 'f', -1, 64)
+				_ = w.Write(row)
+				samples++
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, fmt.Errorf("encoding samples: %w", err)
+		}
+	}
+	summary, err := exports.add(ctx, format, mimeType, buf.Bytes(), samples)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &prometheusExport{
+		exportSummary: summary,
+		Series:        len(series),
+		SeriesStats:   make([]prometheusSeriesStats, 0, min(len(series), maxPrometheusExportSeriesStats)),
+	}
+	for _, s := range series[:min(len(series), maxPrometheusExportSeriesStats)] {
+		export.SeriesStats = append(export.SeriesStats, summarizeSeries(s))
+	}
+	return export, nil
+}
@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPrometheusResult(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", nil)
+	matrix := model.Matrix{
+		{
+			Metric: model.Metric{"__name__": "up", "job": "api"},
+			Values: []model.SamplePair{{Timestamp: 1700000000000, Value: 1}, {Timestamp: 1700000015000, Value: 0}},
+		},
+		{
+			Metric: model.Metric{"__name__": "up", "instance": "db:9090"},
+			Values: []model.SamplePair{{Timestamp: 1700000000000, Value: model.SampleValue(math.NaN())}, {Timestamp: 1700000015000, Value: 0.5}},
+		},
+	}
+
+	export, err := exportPrometheusResult(ctx, "csv", matrix)
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", export.MIMEType)
+	assert.Equal(t, 4, export.Items)
+	assert.Equal(t, 2, export.Series)
+	assert.Equal(t, "timestamp,__name__,instance,job,value\n"+
+		"2023-11-14T22:13:20Z,up,,api,1\n"+
+		"2023-11-14T22:13:35Z,up,,api,0\n"+
+		"2023-11-14T22:13:20Z,up,db:9090,,NaN\n"+
+		"2023-11-14T22:13:35Z,up,db:9090,,0.5\n", readExportText(t, ctx, export.ResourceURI))
+	assert.Equal(t, []prometheusSeriesStats{
+		{Labels: matrix[0].Metric, Samples: 2, Min: 0, Max: 1, Avg: 0.5, Last: 0},
+		{Labels: matrix[1].Metric, Samples: 2, Min: 0.5, Max: 0.5, Avg: 0.5, Last: 0.5},
+	}, export.SeriesStats)
+
+	vector := model.Vector{{Metric: model.Metric{"job": "api"}, Timestamp: 1700000000000, Value: 2}}
+	export, err = exportPrometheusResult(ctx, "ndjson", vector)
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", export.MIMEType)
+	assert.Equal(t, `{"timestamp":"2023-11-14T22:13:20Z","labels":{"job":"api"},"value":"2"}`+"\n", readExportText(t, ctx, export.ResourceURI))
+
+	_, err = exportPrometheusResult(ctx, "csv", &model.Scalar{Value: 1})
+	assert.Error(t, err)
+}
+
+func TestQueryPrometheusExport(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		// An hour with up to 11000 samples per series gives a 1s step.
+		assert.Equal(t, "1", r.Form.Get("step"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"api"},"values":[[1700000000,"1"],[1700000001,"3"]]}]}}`))
+	})
+
+	result, err := queryPrometheusTool(ctx, QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2023-11-14T22:00:00Z",
+		EndTime:       "2023-11-14T23:00:00Z",
+		QueryType:     "range",
+		Export:        "csv",
+	})
+	require.NoError(t, err)
+	export, ok := unwrapResult(result).(*prometheusExport)
+	require.True(t, ok)
+	assert.Equal(t, 2, export.Items)
+	assert.Equal(t, []prometheusSeriesStats{{Labels: model.Metric{"job": "api"}, Samples: 2, Min: 1, Max: 3, Avg: 2, Last: 3}}, export.SeriesStats)
+	assert.Equal(t, "timestamp,job,value\n2023-11-14T22:13:20Z,api,1\n2023-11-14T22:13:21Z,api,3\n", readExportText(t, ctx, export.ResourceURI))
+
+	_, err = queryPrometheusTool(ctx, QueryPrometheusParams{DatasourceUIDs: []string{"a", "b"}, Expr: "up", Export: "csv"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// prometheusDatasourceResult is the result of a query run against one of
+// several datasources, or its error.
+type prometheusDatasourceResult struct {
+	Datasource string                    `json:"datasource"`
+	Result     prometheusDatasourceValue `json:"result,omitzero"`
+	Error      string                    `json:"error,omitempty"`
+}
+
+// prometheusDatasourceValue is the result of a query against one of several
+// datasources: its value, or an estimate when estimateOnly is set.
+type prometheusDatasourceValue struct{ resultValue }
+
+func (prometheusDatasourceValue) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[prometheusValue](), reflect.TypeFor[*prometheusQueryEstimate]()}
+}
+
+// prometheusDatasourceUIDs returns a datasource followed by any more to query,
+// without duplicates.
+func prometheusDatasourceUIDs(uid string, more []string) []string {
+	uids := []string{uid}
+	seen := map[string]bool{uid: true}
+	for _, u := range more {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			uids = append(uids, u)
+		}
+	}
+	return uids
+}
+
+// resolvePrometheusTime resolves a time which may be relative to now to a
+// Unix timestamp in milliseconds, so that queries run concurrently cover the
+// same time range. Empty times are left empty.
+func resolvePrometheusTime(timeStr string) (string, error) {
+	if timeStr == "" {
+		return "", nil
+	}
+	t, err := parseTime(timeStr)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10), nil
+}
+
+// fanOutPrometheus runs a query against each datasource concurrently and
+// returns their results in the order of the datasources. A datasource
+// failing doesn't fail the others.
+func fanOutPrometheus(ctx context.Context, uids []string, query func(ctx context.Context, uid string) (prometheusDatasourceValue, error)) []prometheusDatasourceResult {
+	results := make([]prometheusDatasourceResult, len(uids))
+	sem := make(chan struct{}, prometheusBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, uid := range uids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result, err := query(ctx, uid)
+			results[i] = prometheusDatasourceResult{Datasource: uid, Result: result}
+			if err != nil {
+				results[i] = prometheusDatasourceResult{Datasource: uid, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// queryPrometheusDatasources handles query_prometheus calls naming several
+// datasources, running the call against each of them concurrently over the
+// same time range.
+func queryPrometheusDatasources(ctx context.Context, uids []string, args QueryPrometheusParams) (inspectable[[]prometheusDatasourceResult], error) {
+	var err error
+	if args.StartTime, err = resolvePrometheusTime(args.StartTime); err != nil {
+		return inspectable[[]prometheusDatasourceResult]{}, fmt.Errorf("parsing start time: %w", err)
+	}
+	if args.EndTime, err = resolvePrometheusTime(args.EndTime); err != nil {
+		return inspectable[[]prometheusDatasourceResult]{}, fmt.Errorf("parsing end time: %w", err)
+	}
+	return withInspection(ctx, args.Debug, func(ctx context.Context) ([]prometheusDatasourceResult, error) {
+		return fanOutPrometheus(ctx, uids, func(ctx context.Context, uid string) (prometheusDatasourceValue, error) {
+			args := args
+			args.DatasourceUID, args.DatasourceUIDs = uid, nil
+			if args.EstimateOnly {
+				return asResult[prometheusDatasourceValue](estimatePrometheusQuery(ctx, args))
+			}
+			value, err := queryPrometheus(ctx, args)
+			if err != nil {
+				return prometheusDatasourceValue{}, err
+			}
+			return asResult[prometheusDatasourceValue](decodePrometheusHistograms(value), nil)
+		}), nil
+	})
+}
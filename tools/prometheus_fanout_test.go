@@ -0,0 +1,71 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestPrometheusDatasourceUIDs(t *testing.T) {
+	assert.Equal(t, []string{"a"}, prometheusDatasourceUIDs("a", nil))
+	assert.Equal(t, []string{"a", "b", "c"}, prometheusDatasourceUIDs("a", []string{"b", "a", "", "c", "b"}))
+}
+
+func TestQueryPrometheusFanOut(t *testing.T) {
+	var mu sync.Mutex
+	times := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/api/datasources/uid/") {
+			uid := strings.TrimPrefix(r.URL.Path, "/api/datasources/uid/")
+			_, _ = w.Write([]byte(`{"uid":"` + uid + `","type":"prometheus"}`))
+			return
+		}
+		uid, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/datasources/proxy/uid/"), "/")
+		require.Equal(t, "api/v1/query", path)
+		require.NoError(t, r.ParseForm())
+		mu.Lock()
+		times[r.Form.Get("time")] = true
+		mu.Unlock()
+		if uid == "eu" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"execution","error":"cluster down"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"cluster":"` + uid + `"},"value":[1704067200,"1"]}]}}`))
+	}))
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL, APIKey: "test-api-key"})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, "test-api-key"))
+
+	result, err := queryPrometheusInstantTool(ctx, QueryPrometheusInstantParams{
+		DatasourceUID:  "us",
+		DatasourceUIDs: []string{"eu", "ap"},
+		Expr:           "up",
+		Time:           "now",
+	})
+	require.NoError(t, err)
+	results, ok := unwrapResult(result).([]prometheusDatasourceResult)
+	require.True(t, ok, "expected per-datasource results, got %T", result)
+	require.Len(t, results, 3)
+	assert.Equal(t, "us", results[0].Datasource)
+	assert.Equal(t, model.LabelValue("us"), unwrapResult(results[0].Result).(model.Vector)[0].Metric["cluster"])
+	assert.Equal(t, "eu", results[1].Datasource)
+	assert.Nil(t, unwrapResult(results[1].Result))
+	assert.Contains(t, results[1].Error, "cluster down")
+	assert.Equal(t, "ap", results[2].Datasource)
+	assert.Empty(t, results[2].Error)
+	assert.Len(t, times, 1, "all datasources are queried at the same time")
+}
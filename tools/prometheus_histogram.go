@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/invopop/jsonschema"
+	"github.com/prometheus/common/model"
+)
+
+// prometheusHistogramQuantiles are the quantiles estimated for each native
+// histogram sample of a result.
+var prometheusHistogramQuantiles = []float64{0.5, 0.9, 0.99}
+
+// prometheusHistogramBucket is a bucket of a native histogram sample.
+type prometheusHistogramBucket struct {
+	// Range is the interval of the bucket in mathematical notation, e.g.
+	// "(0.5,1]".
+	Range string            `json:"range"`
+	Lower model.FloatString `json:"lower" jsonschema:"type=string"`
+	Upper model.FloatString `json:"upper" jsonschema:"type=string"`
+	Count model.FloatString `json:"count" jsonschema:"type=string"`
+}
+
+// prometheusHistogram is a native histogram sample. Numbers are strings, as
+// in the Prometheus API, since they may be NaN or infinite.
+type prometheusHistogram struct {
+	Timestamp model.Time                  `json:"timestamp" jsonschema:"type=number"`
+	Count     model.FloatString           `json:"count" jsonschema:"type=string"`
+	Sum       model.FloatString           `json:"sum" jsonschema:"type=string"`
+	Buckets   []prometheusHistogramBucket `json:"buckets"`
+	// Quantiles are estimates of the 50th, 90th and 99th percentiles, keyed
+	// by quantile.
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+}
+
+// prometheusHistogramSeries is a series of a matrix result with its native
+// histogram samples decoded.
+type prometheusHistogramSeries struct {
+	Metric     model.Metric          `json:"metric"`
+	Values     []model.SamplePair    `json:"values,omitempty"`
+	Histograms []prometheusHistogram `json:"histograms,omitempty"`
+}
+
+// JSONSchemaExtend describes the float samples as they are encoded.
+func (prometheusHistogramSeries) JSONSchemaExtend(schema *jsonschema.Schema) {
+	schema.Properties.Set("values", &jsonschema.Schema{Type: "array", Items: prometheusSamplePairSchema{}.JSONSchema()})
+}
+
+// prometheusHistogramSample is a sample of a vector result with its native
+// histogram decoded.
+type prometheusHistogramSample struct {
+	Metric    model.Metric         `json:"metric"`
+	Value     *model.SamplePair    `json:"value,omitempty"`
+	Histogram *prometheusHistogram `json:"histogram,omitempty"`
+}
+
+// JSONSchemaExtend describes the float sample as it is encoded.
+func (prometheusHistogramSample) JSONSchemaExtend(schema *jsonschema.Schema) {
+	schema.Properties.Set("value", prometheusSamplePairSchema{}.JSONSchema())
+}
+
+// prometheusValue is the result of a Prometheus query with any native
+// histograms decoded.
+type prometheusValue struct{ resultValue }
+
+func (prometheusValue) ResultTypes() []reflect.Type {
+	return []reflect.Type{
+		reflect.TypeFor[[]prometheusSeriesSchema](),
+		reflect.TypeFor[[]prometheusHistogramSeries](),
+		reflect.TypeFor[[]prometheusSampleSchema](),
+		reflect.TypeFor[[]prometheusHistogramSample](),
+		// Scalars and strings are encoded like a float sample.
+		reflect.TypeFor[prometheusSamplePairSchema](),
+	}
+}
+
+// decodePrometheusHistograms returns the result with its native histogram
+// samples decoded into their count, sum, buckets and quantile estimates,
+// instead of the positional arrays of the Prometheus API. Results without
+// native histograms are returned as they are.
+func decodePrometheusHistograms(value model.Value) prometheusValue {
+	switch v := value.(type) {
+	case model.Matrix:
+		if !matrixHasHistograms(v) {
+			return prometheusValue{resultValue{v}}
+		}
+		series := make([]prometheusHistogramSeries, 0, len(v))
+		for _, s := range v {
+			decoded := prometheusHistogramSeries{Metric: s.Metric, Values: s.Values}
+			for _, h := range s.Histograms {
+				decoded.Histograms = append(decoded.Histograms, decodePrometheusHistogram(h.Timestamp, h.Histogram))
+			}
+			series = append(series, decoded)
+		}
+		return prometheusValue{resultValue{series}}
+	case model.Vector:
+		if !vectorHasHistograms(v) {
+			return prometheusValue{resultValue{v}}
+		}
+		samples := make([]prometheusHistogramSample, 0, len(v))
+		for _, s := range v {
+			decoded := prometheusHistogramSample{Metric: s.Metric}
+			if s.Histogram != nil {
+				h := decodePrometheusHistogram(s.Timestamp, s.Histogram)
+				decoded.Histogram = &h
+			} else {
+				decoded.Value = &model.SamplePair{Timestamp: s.Timestamp, Value: s.Value}
+			}
+			samples = append(samples, decoded)
+		}
+		return prometheusValue{resultValue{samples}}
+	}
+	return prometheusValue{resultValue{value}}
+}
+
+func matrixHasHistograms(matrix model.Matrix) bool {
+	for _, s := range matrix {
+		if len(s.Histograms) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func vectorHasHistograms(vector model.Vector) bool {
+	for _, s := range vector {
+		if s.Histogram != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func decodePrometheusHistogram(ts model.Time, h *model.SampleHistogram) prometheusHistogram {
+	decoded := prometheusHistogram{
+		Timestamp: ts,
+		Count:     h.Count,
+		Sum:       h.Sum,
+		Buckets:   make([]prometheusHistogramBucket, 0, len(h.Buckets)),
+	}
+	for _, b := range h.Buckets {
+		decoded.Buckets = append(decoded.Buckets, prometheusHistogramBucket{
+			Range: histogramBucketRange(b),
+			Lower: b.Lower,
+			Upper: b.Upper,
+			Count: b.Count,
+		})
+	}
+	for _, q := range prometheusHistogramQuantiles {
+		v := nativeHistogramQuantile(q, h)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if decoded.Quantiles == nil {
+			decoded.Quantiles = map[string]float64{}
+		}
+		decoded.Quantiles[strconv.FormatFloat(q, 'f', -1, 64)] = v
+	}
+	return decoded
+}
+
+// histogramBucketRange formats the interval of a bucket, whose boundaries
+// are encoded by the Prometheus API as 0 for left-open, 1 for right-open, 2
+// for open and 3 for closed intervals.
+func histogramBucketRange(b *model.HistogramBucket) string {
+	left, right := "(", ")"
+	if b.Boundaries == 1 || b.Boundaries == 3 {
+		left = "["
+	}
+	if b.Boundaries == 0 || b.Boundaries == 3 {
+		right = "]"
+	}
+	return fmt.Sprintf("%s%g,%g%s", left, float64(b.Lower), float64(b.Upper), right)
+}
+
+// nativeHistogramQuantile estimates the q-quantile of a native histogram
+// sample, interpolating linearly within the bucket the quantile falls in. It
+// returns NaN for empty histograms and, like histogram_quantile(), -Inf for
+// q < 0 and +Inf for q > 1. The result can differ slightly from
+// histogram_quantile(), which interpolates exponentially within the buckets
+// of exponential schemas.
+func nativeHistogramQuantile(q float64, h *model.SampleHistogram) float64 {
+	switch {
+	case math.IsNaN(q):
+		return math.NaN()
+	case q < 0:
+		return math.Inf(-1)
+	case q > 1:
+		return math.Inf(1)
+	}
+	buckets := make([]*model.HistogramBucket, 0, len(h.Buckets))
+	total := 0.0
+	for _, b := range h.Buckets {
+		if b.Count > 0 {
+			buckets = append(buckets, b)
+			total += float64(b.Count)
+		}
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+	sort.SliceStable(buckets, func(i, j int) bool { return buckets[i].Lower < buckets[j].Lower })
+
+	rank := q * total
+	cumulative := 0.0
+	for _, b := range buckets {
+		count := float64(b.Count)
+		cumulative += count
+		if cumulative < rank {
+			continue
+		}
+		lower, upper := float64(b.Lower), float64(b.Upper)
+		switch {
+		case math.IsInf(upper, 1):
+			return lower
+		case math.IsInf(lower, -1):
+			return upper
+		}
+		return lower + (upper-lower)*(rank-(cumulative-count))/count
+	}
+	return float64(buckets[len(buckets)-1].Upper)
+}
@@ -0,0 +1,116 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNativeHistogram() *model.SampleHistogram {
+	return &model.SampleHistogram{
+		Count: 10,
+		Sum:   12.5,
+		Buckets: model.HistogramBuckets{
+			{Boundaries: 0, Lower: 1, Upper: 2, Count: 6},
+			{Boundaries: 3, Lower: -0.001, Upper: 0.001, Count: 2},
+			{Boundaries: 0, Lower: 2, Upper: 4, Count: 2},
+		},
+	}
+}
+
+func TestNativeHistogramQuantile(t *testing.T) {
+	h := testNativeHistogram()
+	for _, tc := range []struct {
+		q    float64
+		want float64
+	}{
+		{q: 0, want: -0.001},
+		{q: 0.2, want: 0.001},
+		{q: 0.5, want: 1.5},
+		{q: 0.8, want: 2},
+		{q: 0.9, want: 3},
+		{q: 1, want: 4},
+	} {
+		assert.InDelta(t, tc.want, nativeHistogramQuantile(tc.q, h), 1e-9, "q=%g", tc.q)
+	}
+
+	assert.True(t, math.IsInf(nativeHistogramQuantile(-1, h), -1))
+	assert.True(t, math.IsInf(nativeHistogramQuantile(2, h), 1))
+	assert.True(t, math.IsNaN(nativeHistogramQuantile(0.5, &model.SampleHistogram{})))
+
+	// The quantile of an unbounded bucket is its finite bound.
+	open := &model.SampleHistogram{Count: 1, Buckets: model.HistogramBuckets{{Lower: 8, Upper: model.FloatString(math.Inf(1)), Count: 1}}}
+	assert.Equal(t, 8.0, nativeHistogramQuantile(0.99, open))
+}
+
+func TestDecodePrometheusHistograms(t *testing.T) {
+	floats := model.Matrix{{Metric: model.Metric{"job": "api"}, Values: []model.SamplePair{{Timestamp: 1700000000000, Value: 1}}}}
+	assert.Equal(t, floats, unwrapResult(decodePrometheusHistograms(floats)))
+
+	matrix := model.Matrix{{
+		Metric:     model.Metric{"job": "api"},
+		Histograms: []model.SampleHistogramPair{{Timestamp: 1700000000000, Histogram: testNativeHistogram()}},
+	}}
+	series, ok := unwrapResult(decodePrometheusHistograms(matrix)).([]prometheusHistogramSeries)
+	require.True(t, ok)
+	quantiles := series[0].Histograms[0].Quantiles
+	assert.InDelta(t, 1.5, quantiles["0.5"], 1e-9)
+	assert.InDelta(t, 3, quantiles["0.9"], 1e-9)
+	assert.InDelta(t, 3.9, quantiles["0.99"], 1e-9)
+	series[0].Histograms[0].Quantiles = nil
+	b, err := json.Marshal(series)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"metric":{"job":"api"},"histograms":[{
+		"timestamp":1700000000,
+		"count":"10",
+		"sum":"12.5",
+		"buckets":[
+			{"range":"(1,2]","lower":"1","upper":"2","count":"6"},
+			{"range":"[-0.001,0.001]","lower":"-0.001","upper":"0.001","count":"2"},
+			{"range":"(2,4]","lower":"2","upper":"4","count":"2"}
+		]
+	}]}]`, string(b))
+
+	vector := model.Vector{
+		{Metric: model.Metric{"job": "api"}, Timestamp: 1700000000000, Histogram: &model.SampleHistogram{Count: 0, Sum: 0}},
+		{Metric: model.Metric{"job": "db"}, Timestamp: 1700000000000, Value: 2},
+	}
+	b, err = json.Marshal(decodePrometheusHistograms(vector))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"metric":{"job":"api"},"histogram":{"timestamp":1700000000,"count":"0","sum":"0","buckets":[]}},
+		{"metric":{"job":"db"},"value":[1700000000,"2"]}
+	]`, string(b))
+
+	_, err = exportPrometheusResult(newMockDatasourceContext(t, "prom", "prometheus", nil), "csv", matrix)
+	assert.ErrorIs(t, err, errPrometheusHistogramExport)
+}
+
+func TestQueryPrometheusNativeHistograms(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"api"},"histogram":[1700000000,{"count":"4","sum":"6","buckets":[[0,"1","2","4"]]}]}
+		]}}`))
+	})
+
+	result, err := queryPrometheusTool(ctx, QueryPrometheusParams{DatasourceUID: "prom", Expr: "http_request_duration_seconds", StartTime: "2023-11-14T22:13:20Z", QueryType: "instant"})
+	require.NoError(t, err)
+	samples, ok := unwrapResult(result).([]prometheusHistogramSample)
+	require.True(t, ok)
+	require.Len(t, samples, 1)
+	require.NotNil(t, samples[0].Histogram)
+	assert.Equal(t, model.FloatString(4), samples[0].Histogram.Count)
+	assert.Equal(t, "(1,2]", samples[0].Histogram.Buckets[0].Range)
+	assert.InDelta(t, 1.5, samples[0].Histogram.Quantiles["0.5"], 1e-9)
+	assert.InDelta(t, 1.99, samples[0].Histogram.Quantiles["0.99"], 1e-9)
+}
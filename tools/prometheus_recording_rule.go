@@ -0,0 +1,428 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPrometheusRecordingRuleInterval is the default evaluation interval
+// of suggested recording rules.
+const DefaultPrometheusRecordingRuleInterval = time.Minute
+
+// maxRecordedSeries is the number of series above which recording an
+// expression is warned to be costly.
+const maxRecordedSeries = 1000
+
+// counterFunctions are the functions whose argument is a counter, whose
+// `_total` suffix is dropped from recording rule names.
+var counterFunctions = map[string]bool{"rate": true, "irate": true, "increase": true}
+
+type SuggestPrometheusRecordingRuleParams struct {
+	DatasourceUID   string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr            string `json:"expr" jsonschema:"required,description=The slow PromQL expression to record\\, such as a dashboard panel query. Label matchers using template variables are moved from the rule to the rewritten query"`
+	StartTime       string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range to measure the expression's cost over\\, in RFC3339 format or relative to now (e.g. 'now-6h' or '6 hours ago'). Defaults to 1 hour ago"`
+	EndTime         string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range to measure the expression's cost over\\, in the same formats as startTime. Defaults to now"`
+	IntervalSeconds int    `json:"intervalSeconds,omitempty" jsonschema:"description=Optionally\\, how often the rule is evaluated in seconds (default: 60)"`
+	Group           string `json:"group,omitempty" jsonschema:"description=Optionally\\, the name of the rule group. Defaults to one named after the metric"`
+}
+
+// prometheusQueryStats are the statistics Prometheus returns for a query run
+// with stats=all.
+type prometheusQueryStats struct {
+	Timings struct {
+		EvalTotalTime float64 `json:"evalTotalTime"`
+	} `json:"timings"`
+	Samples struct {
+		TotalQueryableSamples int64 `json:"totalQueryableSamples"`
+		PeakSamples           int64 `json:"peakSamples"`
+	} `json:"samples"`
+}
+
+// prometheusQueryCost is the measured cost of a range query.
+type prometheusQueryCost struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Step  string `json:"step"`
+	// Series is the number of series returned, which the rule would record.
+	Series int `json:"series"`
+	// The statistics are omitted if the datasource doesn't return them.
+	EvalSeconds           *float64 `json:"evalSeconds,omitempty"`
+	TotalQueryableSamples *int64   `json:"totalQueryableSamples,omitempty"`
+	PeakSamples           *int64   `json:"peakSamples,omitempty"`
+	// RecordedSamples is the number of samples the rewritten query reads over
+	// the same time range once the rule has been evaluated over it.
+	RecordedSamples int64 `json:"recordedSamples"`
+}
+
+// prometheusRecordingRule is the result of suggest_prometheus_recording_rule.
+type prometheusRecordingRule struct {
+	Record   string `json:"record"`
+	Expr     string `json:"expr"`
+	Group    string `json:"group"`
+	Interval string `json:"interval"`
+	// YAML is the rule group in the Prometheus rule file format.
+	YAML string `json:"yaml"`
+	// DashboardQuery queries the recorded series in place of the expression.
+	DashboardQuery string              `json:"dashboardQuery"`
+	Cost           prometheusQueryCost `json:"cost"`
+	Warnings       []string            `json:"warnings,omitempty"`
+}
+
+// recordingRuleExpr prepares an expression for recording. Interval variables
+// become fixed durations, as in interpolatePromQLVariables. Label matchers
+// using other template variables can't be recorded, so they are removed and
+// their labels kept by the expression's aggregations and vector matching
+// instead, to be matched on the recorded series. It returns the expression
+// and the removed matchers, with their variables restored.
+func recordingRuleExpr(expr string) (parser.Expr, []string, error) {
+	// Each variable gets its own placeholder so that it can be restored.
+	variables := map[string]string{}
+	placeholders := map[string]string{}
+	expr = templateVariableRegex.ReplaceAllStringFunc(expr, func(v string) string {
+		if interpolatePromQLVariables(v) != promqlVariablePlaceholder {
+			return v
+		}
+		if p, ok := placeholders[v]; ok {
+			return p
+		}
+		p := fmt.Sprintf("%s%d__", promqlVariablePlaceholder, len(placeholders))
+		placeholders[v], variables[p] = p, v
+		return p
+	})
+	restore := func(s string) string {
+		for p, v := range variables {
+			s = strings.ReplaceAll(s, p, v)
+		}
+		return s
+	}
+
+	parsed, err := parsePromQL(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parsed.Type() != parser.ValueTypeVector {
+		return nil, nil, fmt.Errorf("expr must return an instant vector, got a %s", parsed.Type())
+	}
+
+	var matchers, moved []string
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		kept := make([]*labels.Matcher, 0, len(vs.LabelMatchers))
+		for _, m := range vs.LabelMatchers {
+			switch {
+			case !strings.Contains(m.Value, promqlVariablePlaceholder):
+				kept = append(kept, m)
+				continue
+			case m.Name == labels.MetricName:
+				err = fmt.Errorf("the metric name of %s is a template variable", restore(vs.String()))
+				return err
+			}
+			if matcher := restore(m.String()); !slices.Contains(matchers, matcher) {
+				matchers = append(matchers, matcher)
+			}
+			if !slices.Contains(moved, m.Name) {
+				moved = append(moved, m.Name)
+			}
+		}
+		if !slices.ContainsFunc(kept, func(m *labels.Matcher) bool { return !m.Matches("") }) {
+			err = fmt.Errorf("%s only matches on template variables", restore(vs.String()))
+			return err
+		}
+		vs.LabelMatchers = kept
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(moved) > 0 {
+		// keep makes labels part of a label list, or removes them from it if
+		// the list names the labels to drop.
+		keep := func(list []string, drop bool) []string {
+			if drop {
+				return slices.DeleteFunc(list, func(l string) bool { return slices.Contains(moved, l) })
+			}
+			for _, l := range moved {
+				if !slices.Contains(list, l) {
+					list = append(list, l)
+				}
+			}
+			return list
+		}
+		parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+			switch n := node.(type) {
+			case *parser.AggregateExpr:
+				n.Grouping = keep(n.Grouping, n.Without)
+			case *parser.BinaryExpr:
+				if n.VectorMatching != nil && (n.VectorMatching.On || len(n.VectorMatching.MatchingLabels) > 0) {
+					n.VectorMatching.MatchingLabels = keep(n.VectorMatching.MatchingLabels, !n.VectorMatching.On)
+				}
+			}
+			return nil
+		})
+	}
+	if strings.Contains(parsed.String(), promqlVariablePlaceholder) {
+		return nil, nil, fmt.Errorf("template variables can only be recorded in label matcher values")
+	}
+	return parsed, matchers, nil
+}
+
+// recordingRuleName names a recording rule following the Prometheus
+// level:metric:operations convention: the level is the labels the outermost
+// aggregation keeps, the metric is the name of the metric recorded, without
+// `_total` for counters, and the operations are the functions applied,
+// newest first. Sums are implied, as is conventional.
+func recordingRuleName(expr parser.Expr) (string, error) {
+	metric, operations := recordingRuleParts(expr)
+	if metric == "" {
+		return "", fmt.Errorf("expr doesn't select a metric")
+	}
+	var level []string
+	aggregated, quantile := false, false
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.AggregateExpr:
+			if !aggregated && !n.Without {
+				level = slices.Clone(n.Grouping)
+			}
+			aggregated = true
+		case *parser.Call:
+			quantile = quantile || n.Func.Name == "histogram_quantile"
+		}
+		return nil
+	})
+	switch {
+	case len(operations) > 0:
+	case aggregated:
+		operations = []string{"sum"}
+	default:
+		return "", fmt.Errorf("expr only selects series, so recording it wouldn't make it cheaper")
+	}
+	// Quantiles of histograms drop the le label.
+	if quantile {
+		level = slices.DeleteFunc(level, func(l string) bool { return l == model.BucketLabel })
+	}
+	return strings.Join(level, "_") + ":" + metric + ":" + strings.Join(operations, "_"), nil
+}
+
+// recordingRuleParts returns the metric and operations of an expression for
+// recordingRuleName.
+func recordingRuleParts(expr parser.Expr) (string, []string) {
+	switch n := expr.(type) {
+	case *parser.ParenExpr:
+		return recordingRuleParts(n.Expr)
+	case *parser.StepInvariantExpr:
+		return recordingRuleParts(n.Expr)
+	case *parser.VectorSelector:
+		if n.Name != "" {
+			return n.Name, nil
+		}
+		for _, m := range n.LabelMatchers {
+			if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+				return m.Value, nil
+			}
+		}
+	case *parser.MatrixSelector:
+		return recordingRuleParts(n.VectorSelector)
+	case *parser.SubqueryExpr:
+		return recordingRuleParts(n.Expr)
+	case *parser.AggregateExpr:
+		metric, operations := recordingRuleParts(n.Expr)
+		if n.Op != parser.SUM {
+			operations = append([]string{n.Op.String()}, operations...)
+		}
+		return metric, operations
+	case *parser.Call:
+		if n.Func.Name == "histogram_quantile" && len(n.Args) == 2 {
+			metric, operations := recordingRuleParts(n.Args[1])
+			operation := "histogram_quantile"
+			if q, ok := n.Args[0].(*parser.NumberLiteral); ok {
+				operation = "p" + strings.ReplaceAll(strconv.FormatFloat(math.Round(q.Val*1000)/10, 'f', -1, 64), ".", "")
+			}
+			return strings.TrimSuffix(metric, "_bucket"), append([]string{operation}, operations...)
+		}
+		for _, arg := range n.Args {
+			var r time.Duration
+			switch a := arg.(type) {
+			case *parser.MatrixSelector:
+				r = a.Range
+			case *parser.SubqueryExpr:
+				r = a.Range
+			default:
+				continue
+			}
+			metric, operations := recordingRuleParts(arg)
+			if counterFunctions[n.Func.Name] {
+				metric = strings.TrimSuffix(metric, "_total")
+			}
+			return metric, append([]string{n.Func.Name + model.Duration(r).String()}, operations...)
+		}
+		for _, arg := range n.Args {
+			if arg.Type() == parser.ValueTypeVector {
+				metric, operations := recordingRuleParts(arg)
+				return metric, append([]string{n.Func.Name}, operations...)
+			}
+		}
+	case *parser.BinaryExpr:
+		lhsVector, rhsVector := n.LHS.Type() == parser.ValueTypeVector, n.RHS.Type() == parser.ValueTypeVector
+		switch {
+		case lhsVector && rhsVector:
+			metric, operations := recordingRuleParts(n.LHS)
+			if n.Op != parser.DIV {
+				return metric, operations
+			}
+			if rhsMetric, _ := recordingRuleParts(n.RHS); rhsMetric != "" && rhsMetric != metric {
+				metric += "_per_" + rhsMetric
+			}
+			return metric, append([]string{"ratio"}, operations...)
+		case rhsVector:
+			return recordingRuleParts(n.RHS)
+		default:
+			return recordingRuleParts(n.LHS)
+		}
+	}
+	return "", nil
+}
+
+// queryPrometheusRangeWithStats runs a range query with stats=all. promv1.API
+// discards the statistics of queries, so the request is made directly. The
+// statistics are nil if the datasource doesn't return them.
+func queryPrometheusRangeWithStats(ctx context.Context, uid, expr string, r promv1.Range) (model.Matrix, *prometheusQueryStats, error) {
+	client, err := promAPIClientFromContext(ctx, uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	form := url.Values{}
+	form.Set("query", expr)
+	form.Set("start", strconv.FormatFloat(float64(r.Start.UnixMilli())/1000, 'f', -1, 64))
+	form.Set("end", strconv.FormatFloat(float64(r.End.UnixMilli())/1000, 'f', -1, 64))
+	form.Set("step", strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64))
+	form.Set("stats", string(promv1.AllStatsValue))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.URL("/api/v1/query_range", nil).String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, body, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying Prometheus range: %w", err)
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result model.Matrix          `json:"result"`
+			Stats  *prometheusQueryStats `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, nil, fmt.Errorf("decoding Prometheus response (status %d): %w", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, nil, fmt.Errorf("querying Prometheus range: %s (status %d)", result.Error, resp.StatusCode)
+	}
+	return result.Data.Result, result.Data.Stats, nil
+}
+
+func suggestPrometheusRecordingRule(ctx context.Context, args SuggestPrometheusRecordingRuleParams) (*prometheusRecordingRule, error) {
+	expr, matchers, err := recordingRuleExpr(args.Expr)
+	if err != nil {
+		return nil, err
+	}
+	record, err := recordingRuleName(expr)
+	if err != nil {
+		return nil, err
+	}
+	interval := DefaultPrometheusRecordingRuleInterval
+	if args.IntervalSeconds > 0 {
+		interval = time.Duration(args.IntervalSeconds) * time.Second
+	}
+	group := args.Group
+	if group == "" {
+		group = strings.Split(record, ":")[1] + ".rules"
+	}
+
+	rule := &prometheusRecordingRule{
+		Record:         record,
+		Expr:           expr.String(),
+		Group:          group,
+		Interval:       model.Duration(interval).String(),
+		DashboardQuery: record,
+	}
+	if len(matchers) > 0 {
+		rule.DashboardQuery += "{" + strings.Join(matchers, ", ") + "}"
+	}
+	type ruleYAML struct {
+		Record string `yaml:"record"`
+		Expr   string `yaml:"expr"`
+	}
+	type groupYAML struct {
+		Name     string     `yaml:"name"`
+		Interval string     `yaml:"interval"`
+		Rules    []ruleYAML `yaml:"rules"`
+	}
+	b, err := yaml.Marshal(map[string][]groupYAML{
+		"groups": {{Name: rule.Group, Interval: rule.Interval, Rules: []ruleYAML{{Record: rule.Record, Expr: rule.Expr}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal rule group: %w", err)
+	}
+	rule.YAML = string(b)
+
+	start, end, err := prometheusTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	step := prometheusStep(ctx, 0, start, end, DefaultPrometheusMaxDataPoints)
+	matrix, stats, err := queryPrometheusRangeWithStats(ctx, args.DatasourceUID, rule.Expr, promv1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	}
+	rule.Cost = prometheusQueryCost{
+		Start:           start.Format(time.RFC3339),
+		End:             end.Format(time.RFC3339),
+		Step:            step.String(),
+		Series:          len(matrix),
+		RecordedSamples: int64(len(matrix)) * (int64(end.Sub(start)/interval) + 1),
+	}
+	if stats != nil {
+		rule.Cost.EvalSeconds = &stats.Timings.EvalTotalTime
+		rule.Cost.TotalQueryableSamples = &stats.Samples.TotalQueryableSamples
+		rule.Cost.PeakSamples = &stats.Samples.PeakSamples
+	} else {
+		rule.Warnings = append(rule.Warnings, "The datasource didn't return query statistics, so only the number of series was measured.")
+	}
+	if len(matrix) > maxRecordedSeries {
+		rule.Warnings = append(rule.Warnings, fmt.Sprintf("The rule would record %d series. Consider aggregating away high-cardinality labels.", len(matrix)))
+	}
+	return rule, nil
+}
+
+var SuggestPrometheusRecordingRule = mcpgrafana.MustTool(
+	"suggest_prometheus_recording_rule",
+	"Suggests a recording rule for a slow PromQL expression, such as a dashboard panel query. Returns the rule's name, following the Prometheus `level:metric:operations` naming convention, its expression, group and evaluation interval, the rule group as YAML ready for a Prometheus rule file, and the dashboard query rewritten to read the recorded series. Label matchers using template variables such as `$namespace` are moved from the rule to the rewritten query, with their labels kept by the rule's aggregations, and interval variables such as `$__rate_interval` become fixed durations. The rule's expression is run over the time range (default: the last hour) with query statistics to measure its cost: its evaluation time, the samples it reads, and the series the rule would record.",
+	guardTimeRange(suggestPrometheusRecordingRule),
+	mcp.WithTitleAnnotation("Suggest Prometheus recording rule"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
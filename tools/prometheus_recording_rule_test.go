@@ -0,0 +1,107 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingRuleName(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		expected string
+	}{
+		{`sum by (job) (rate(http_requests_total[5m]))`, "job:http_requests:rate5m"},
+		{`sum without (instance) (rate(http_requests_total[5m]))`, ":http_requests:rate5m"},
+		{`sum by (job, path) (http_requests_in_flight)`, "job_path:http_requests_in_flight:sum"},
+		{`max by (pod) (container_memory_working_set_bytes)`, "pod:container_memory_working_set_bytes:max"},
+		{`histogram_quantile(0.99, sum by (le, job) (rate(request_duration_seconds_bucket[5m])))`, "job:request_duration_seconds:p99_rate5m"},
+		{`histogram_quantile(0.999, sum by (le) (rate(request_duration_seconds_bucket[1m])))`, ":request_duration_seconds:p999_rate1m"},
+		{`sum by (path) (rate(request_failures_total[5m])) / sum by (path) (rate(requests_total[5m]))`, "path:request_failures_per_requests:ratio_rate5m"},
+		{`avg by (instance) (avg_over_time(node_load1[10m]))`, "instance:node_load1:avg_avg_over_time10m"},
+		{`rate(http_requests_total[5m]) * 60`, ":http_requests:rate5m"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := parsePromQL(tc.expr)
+			require.NoError(t, err)
+			name, err := recordingRuleName(expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+
+	for _, expr := range []string{`up{job="api"}`, `vector(1)`} {
+		parsed, err := parsePromQL(expr)
+		require.NoError(t, err)
+		_, err = recordingRuleName(parsed)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestRecordingRuleExpr(t *testing.T) {
+	expr, matchers, err := recordingRuleExpr(`sum by (pod) (rate(http_requests_total{namespace="$namespace", cluster=~"${cluster:regex}", code="500"}[$__rate_interval]))`)
+	require.NoError(t, err)
+	assert.Equal(t, `sum by (pod, namespace, cluster) (rate(http_requests_total{code="500"}[5m]))`, expr.String())
+	assert.Equal(t, []string{`namespace="$namespace"`, `cluster=~"${cluster:regex}"`}, matchers)
+
+	expr, matchers, err = recordingRuleExpr(`sum without (namespace, instance) (up{namespace="$namespace"}) / on (job) group_left sum by (job) (up)`)
+	require.NoError(t, err)
+	assert.Equal(t, `sum without (instance) (up) / on (job, namespace) group_left () sum by (job, namespace) (up)`, expr.String())
+	assert.Equal(t, []string{`namespace="$namespace"`}, matchers)
+
+	_, _, err = recordingRuleExpr(`sum({job="$job"})`)
+	assert.ErrorContains(t, err, "only matches on template variables")
+	_, _, err = recordingRuleExpr(`sum by ($label) (up)`)
+	assert.Error(t, err)
+	_, _, err = recordingRuleExpr(`sum(rate(http_requests_total[5m]))[1h:]`)
+	assert.ErrorContains(t, err, "instant vector")
+}
+
+func TestSuggestPrometheusRecordingRule(t *testing.T) {
+	var form map[string][]string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"namespace":"shop","pod":"cart"},"values":[[1704067200,"1"]]},
+			{"metric":{"namespace":"shop","pod":"checkout"},"values":[[1704067200,"2"]]}
+		],"stats":{"timings":{"evalTotalTime":1.5},"samples":{"totalQueryableSamples":120000,"peakSamples":4000}}}}`))
+	})
+
+	rule, err := suggestPrometheusRecordingRule(ctx, SuggestPrometheusRecordingRuleParams{
+		DatasourceUID: "prom",
+		Expr:          `sum by (pod) (rate(http_requests_total{namespace="$namespace"}[$__rate_interval]))`,
+		StartTime:     "2024-01-01T00:00:00Z",
+		EndTime:       "2024-01-01T01:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pod_namespace:http_requests:rate5m", rule.Record)
+	assert.Equal(t, `sum by (pod, namespace) (rate(http_requests_total[5m]))`, rule.Expr)
+	assert.Equal(t, `pod_namespace:http_requests:rate5m{namespace="$namespace"}`, rule.DashboardQuery)
+	assert.Equal(t, "http_requests.rules", rule.Group)
+	assert.Equal(t, "1m", rule.Interval)
+	assert.Equal(t, `groups:
+    - name: http_requests.rules
+      interval: 1m
+      rules:
+        - record: pod_namespace:http_requests:rate5m
+          expr: sum by (pod, namespace) (rate(http_requests_total[5m]))
+`, rule.YAML)
+
+	assert.Equal(t, []string{rule.Expr}, form["query"])
+	assert.Equal(t, []string{"all"}, form["stats"])
+	assert.Equal(t, []string{"1704067200"}, form["start"])
+	assert.Equal(t, 2, rule.Cost.Series)
+	require.NotNil(t, rule.Cost.TotalQueryableSamples)
+	assert.Equal(t, int64(120000), *rule.Cost.TotalQueryableSamples)
+	assert.Equal(t, 1.5, *rule.Cost.EvalSeconds)
+	assert.Equal(t, int64(2*61), rule.Cost.RecordedSamples)
+	assert.Empty(t, rule.Warnings)
+}
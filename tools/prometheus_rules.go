@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+type ListPrometheusRulesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Type          string `json:"type,omitempty" jsonschema:"enum=alerting,enum=recording,description=Optionally\\, only list alerting or recording rules"`
+	State         string `json:"state,omitempty" jsonschema:"enum=firing,enum=pending,enum=inactive,description=Optionally\\, only list alerting rules in this state"`
+	Search        string `json:"search,omitempty" jsonschema:"description=Optionally\\, only list rules whose name\\, query\\, group or label values contain this text (case-insensitive)\\, e.g. a service name"`
+}
+
+// prometheusRule is a recording or alerting rule evaluated by Prometheus.
+type prometheusRule struct {
+	// Type is "alerting" or "recording".
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Query       string         `json:"query"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Annotations model.LabelSet `json:"annotations,omitempty"`
+	// For is how long an alerting rule's condition must hold before it fires.
+	For string `json:"for,omitempty"`
+	// State is the state of an alerting rule: "firing", "pending" or
+	// "inactive".
+	State          string `json:"state,omitempty"`
+	ActiveAlerts   int    `json:"activeAlerts,omitempty"`
+	Health         string `json:"health"`
+	LastError      string `json:"lastError,omitempty"`
+	LastEvaluation string `json:"lastEvaluation,omitempty"`
+}
+
+// prometheusRuleGroup is a group of rules evaluated together.
+type prometheusRuleGroup struct {
+	Name     string           `json:"name"`
+	File     string           `json:"file,omitempty"`
+	Interval string           `json:"interval,omitempty"`
+	Rules    []prometheusRule `json:"rules"`
+}
+
+// toPrometheusRule converts a rule returned by the rules API, returning false
+// for rules of unknown types.
+func toPrometheusRule(rule any) (prometheusRule, bool) {
+	switch r := rule.(type) {
+	case promv1.AlertingRule:
+		active := 0
+		for _, a := range r.Alerts {
+			if a.State == promv1.AlertStateFiring || a.State == promv1.AlertStatePending {
+				active++
+			}
+		}
+		pr := prometheusRule{
+			Type:         "alerting",
+			Name:         r.Name,
+			Query:        r.Query,
+			Labels:       r.Labels,
+			Annotations:  r.Annotations,
+			State:        r.State,
+			ActiveAlerts: active,
+			Health:       string(r.Health),
+			LastError:    r.LastError,
+		}
+		if r.Duration > 0 {
+			pr.For = time.Duration(r.Duration * float64(time.Second)).String()
+		}
+		if !r.LastEvaluation.IsZero() {
+			pr.LastEvaluation = r.LastEvaluation.UTC().Format(time.RFC3339)
+		}
+		return pr, true
+	case promv1.RecordingRule:
+		pr := prometheusRule{
+			Type:      "recording",
+			Name:      r.Name,
+			Query:     r.Query,
+			Labels:    r.Labels,
+			Health:    string(r.Health),
+			LastError: r.LastError,
+		}
+		if !r.LastEvaluation.IsZero() {
+			pr.LastEvaluation = r.LastEvaluation.UTC().Format(time.RFC3339)
+		}
+		return pr, true
+	}
+	return prometheusRule{}, false
+}
+
+func (p ListPrometheusRulesParams) validate() error {
+	switch p.Type {
+	case "", "alerting", "recording":
+	default:
+		return fmt.Errorf("invalid rule type %q, must be 'alerting' or 'recording'", p.Type)
+	}
+	switch p.State {
+	case "", "firing", "pending", "inactive":
+	default:
+		return fmt.Errorf("invalid rule state %q, must be 'firing', 'pending' or 'inactive'", p.State)
+	}
+	return nil
+}
+
+// matches returns whether a rule of the named group passes the filters.
+func (p ListPrometheusRulesParams) matches(group string, rule prometheusRule) bool {
+	if p.Type != "" && rule.Type != p.Type {
+		return false
+	}
+	if p.State != "" && rule.State != p.State {
+		return false
+	}
+	if p.Search == "" {
+		return true
+	}
+	search := strings.ToLower(p.Search)
+	texts := []string{rule.Name, rule.Query, group}
+	for _, v := range rule.Labels {
+		texts = append(texts, string(v))
+	}
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func listPrometheusRules(ctx context.Context, args ListPrometheusRulesParams) ([]prometheusRuleGroup, error) {
+	if err := args.validate(); err != nil {
+		return nil, err
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	result, err := promClient.Rules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus rules: %w", err)
+	}
+
+	groups := []prometheusRuleGroup{}
+	for _, g := range result.Groups {
+		group := prometheusRuleGroup{Name: g.Name, File: g.File, Rules: []prometheusRule{}}
+		if g.Interval > 0 {
+			group.Interval = time.Duration(g.Interval * float64(time.Second)).String()
+		}
+		for _, rule := range g.Rules {
+			r, ok := toPrometheusRule(rule)
+			if ok && args.matches(g.Name, r) {
+				group.Rules = append(group.Rules, r)
+			}
+		}
+		if len(group.Rules) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+var ListPrometheusRules = mcpgrafana.MustTool(
+	"list_prometheus_rules",
+	"Lists the recording and alerting rules evaluated by a Prometheus datasource, as rule groups with their file and evaluation interval. Each rule has its type, name, PromQL query, labels, health, last error and last evaluation time; alerting rules also have their annotations, 'for' duration, state (firing, pending or inactive) and number of active alerts. Filter by `type`, `state`, or `search` text matched against rule names, queries, groups and label values, e.g. to answer which alerts exist for a service. Groups without matching rules are omitted.",
+	listPrometheusRules,
+	mcp.WithTitleAnnotation("List Prometheus rules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
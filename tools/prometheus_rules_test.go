@@ -0,0 +1,68 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prometheusRulesResponse = `{"status":"success","data":{"groups":[
+	{"name":"checkout","file":"/etc/prometheus/rules/checkout.yml","interval":60,"rules":[
+		{"type":"alerting","name":"CheckoutHighErrorRate","query":"job:errors:ratio5m{job=\"checkout\"} > 0.05","duration":300,
+		 "labels":{"severity":"page","service":"checkout"},"annotations":{"summary":"High error rate"},
+		 "alerts":[{"labels":{"alertname":"CheckoutHighErrorRate"},"annotations":{},"state":"firing","activeAt":"2024-01-01T00:00:00Z","value":"0.1"}],
+		 "health":"ok","evaluationTime":0.001,"lastEvaluation":"2024-01-01T00:05:00Z","state":"firing"},
+		{"type":"recording","name":"job:errors:ratio5m","query":"sum by (job) (rate(errors_total[5m]))","health":"err","lastError":"many-to-many matching",
+		 "evaluationTime":0.001,"lastEvaluation":"2024-01-01T00:05:00Z"}
+	]},
+	{"name":"payments","file":"/etc/prometheus/rules/payments.yml","interval":30,"rules":[
+		{"type":"alerting","name":"PaymentsDown","query":"up{job=\"payments\"} == 0","duration":0,"labels":{"service":"payments"},
+		 "annotations":{},"alerts":[],"health":"ok","evaluationTime":0.001,"lastEvaluation":"2024-01-01T00:05:00Z","state":"inactive"}
+	]}
+]}}`
+
+func TestListPrometheusRules(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/rules", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(prometheusRulesResponse))
+	})
+
+	groups, err := listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "prom"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "1m0s", groups[0].Interval)
+	require.Len(t, groups[0].Rules, 2)
+	alert := groups[0].Rules[0]
+	assert.Equal(t, "alerting", alert.Type)
+	assert.Equal(t, "5m0s", alert.For)
+	assert.Equal(t, "firing", alert.State)
+	assert.Equal(t, 1, alert.ActiveAlerts)
+	assert.Equal(t, "2024-01-01T00:05:00Z", alert.LastEvaluation)
+	recording := groups[0].Rules[1]
+	assert.Equal(t, "recording", recording.Type)
+	assert.Equal(t, "err", recording.Health)
+	assert.Equal(t, "many-to-many matching", recording.LastError)
+
+	t.Run("filters", func(t *testing.T) {
+		groups, err := listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "prom", Type: "alerting", Search: "PAYMENTS"})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, "payments", groups[0].Name)
+		assert.Equal(t, "PaymentsDown", groups[0].Rules[0].Name)
+
+		groups, err = listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "prom", State: "firing"})
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		require.Len(t, groups[0].Rules, 1)
+		assert.Equal(t, "CheckoutHighErrorRate", groups[0].Rules[0].Name)
+
+		_, err = listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "prom", Type: "alert"})
+		assert.ErrorContains(t, err, "invalid rule type")
+	})
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"github.com/invopop/jsonschema"
+	"github.com/prometheus/common/model"
+)
+
+// The Prometheus client's result types encode each sample as a [timestamp,
+// value] pair rather than as their fields, so the output schemas of tools
+// returning them can't be reflected from their Go types. The types in this
+// file describe their encoding instead.
+
+// prometheusSamplePairSchema describes a float sample: its timestamp in
+// seconds and its value as a string, since it may be NaN or infinite.
+type prometheusSamplePairSchema struct{}
+
+func (prometheusSamplePairSchema) JSONSchema() *jsonschema.Schema {
+	return samplePairSchema(&jsonschema.Schema{Type: "string"})
+}
+
+// prometheusHistogramPairSchema describes a native histogram sample: its
+// timestamp in seconds and the histogram.
+type prometheusHistogramPairSchema struct{}
+
+func (prometheusHistogramPairSchema) JSONSchema() *jsonschema.Schema {
+	return samplePairSchema(&jsonschema.Schema{Type: "object"})
+}
+
+func samplePairSchema(value *jsonschema.Schema) *jsonschema.Schema {
+	two := uint64(2)
+	return &jsonschema.Schema{
+		Type:        "array",
+		PrefixItems: []*jsonschema.Schema{{Type: "number"}, value},
+		MinItems:    &two,
+		MaxItems:    &two,
+	}
+}
+
+// prometheusSeriesSchema describes a series of a model.Matrix.
+type prometheusSeriesSchema struct {
+	Metric     model.Metric                    `json:"metric"`
+	Values     []prometheusSamplePairSchema    `json:"values,omitempty"`
+	Histograms []prometheusHistogramPairSchema `json:"histograms,omitempty"`
+}
+
+// prometheusSampleSchema describes a sample of a model.Vector.
+type prometheusSampleSchema struct {
+	Metric    model.Metric                  `json:"metric"`
+	Value     prometheusSamplePairSchema    `json:"value,omitempty"`
+	Histogram prometheusHistogramPairSchema `json:"histogram,omitempty"`
+}
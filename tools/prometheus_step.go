@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// DefaultPrometheusMaxDataPoints is the default maximum number of samples
+// returned per series by Prometheus range queries.
+const DefaultPrometheusMaxDataPoints = 100
+
+// prometheusSteps are the steps chosen automatically for range queries, so
+// that samples fall on round times.
+var prometheusSteps = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// prometheusMaxDataPoints returns the maximum number of samples per series of
+// a range query.
+func prometheusMaxDataPoints(maxDataPoints int) int {
+	if maxDataPoints <= 0 {
+		return DefaultPrometheusMaxDataPoints
+	}
+	return max(maxDataPoints, 2)
+}
+
+// prometheusStep returns the step of a range query: the one given, else the
+// configured default, else the smallest round step returning at most
+// maxDataPoints samples per series.
+func prometheusStep(ctx context.Context, stepSeconds int, start, end time.Time, maxDataPoints int) time.Duration {
+	if stepSeconds > 0 {
+		return time.Duration(stepSeconds) * time.Second
+	}
+	if step := toolDefaultsFor(ctx, defaultsCategoryPrometheus).Step; step > 0 {
+		return step
+	}
+	// A range query returns a sample at the start and at every step after it.
+	minStep := end.Sub(start) / time.Duration(prometheusMaxDataPoints(maxDataPoints)-1)
+	for _, step := range prometheusSteps {
+		if step >= minStep {
+			return step
+		}
+	}
+	days := (minStep + 24*time.Hour - 1) / (24 * time.Hour)
+	return days * 24 * time.Hour
+}
+
+// downsampleMatrix reduces each series to at most maxDataPoints samples by
+// averaging consecutive samples, each average taking the timestamp of the
+// last sample it covers.
+func downsampleMatrix(matrix model.Matrix, maxDataPoints int) model.Matrix {
+	for _, series := range matrix {
+		n := len(series.Values)
+		if n <= maxDataPoints {
+			continue
+		}
+		size := (n + maxDataPoints - 1) / maxDataPoints
+		values := make([]model.SamplePair, 0, (n+size-1)/size)
+		for i := 0; i < n; i += size {
+			bucket := series.Values[i:min(i+size, n)]
+			var sum float64
+			for _, v := range bucket {
+				sum += float64(v.Value)
+			}
+			values = append(values, model.SamplePair{
+				Timestamp: bucket[len(bucket)-1].Timestamp,
+				Value:     model.SampleValue(sum / float64(len(bucket))),
+			})
+		}
+		series.Values = values
+	}
+	return matrix
+}
@@ -0,0 +1,105 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestPrometheusStep(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		rng           time.Duration
+		maxDataPoints int
+		expected      time.Duration
+	}{
+		{time.Hour, 0, time.Minute},
+		{10 * time.Minute, 0, 10 * time.Second},
+		{24 * time.Hour, 0, 15 * time.Minute},
+		{7 * 24 * time.Hour, 0, 2 * time.Hour},
+		{time.Hour, 1000, 5 * time.Second},
+		{365 * 24 * time.Hour, 0, 4 * 24 * time.Hour},
+	} {
+		t.Run(fmt.Sprintf("%s/%d", tc.rng, tc.maxDataPoints), func(t *testing.T) {
+			step := prometheusStep(ctx, 0, start, start.Add(tc.rng), tc.maxDataPoints)
+			assert.Equal(t, tc.expected, step)
+			assert.LessOrEqual(t, int(tc.rng/step)+1, prometheusMaxDataPoints(tc.maxDataPoints))
+		})
+	}
+
+	assert.Equal(t, 15*time.Second, prometheusStep(ctx, 15, start, start.Add(24*time.Hour), 0), "explicit step")
+	ctx = withToolDefaults(ctx, map[string]mcpgrafana.ToolDefaults{"prometheus": {Step: 30 * time.Second}})
+	assert.Equal(t, 30*time.Second, prometheusStep(ctx, 0, start, start.Add(24*time.Hour), 0), "configured step")
+}
+
+func TestDownsampleMatrix(t *testing.T) {
+	values := make([]model.SamplePair, 10)
+	for i := range values {
+		values[i] = model.SamplePair{Timestamp: model.Time(i * 1000), Value: model.SampleValue(i)}
+	}
+	matrix := downsampleMatrix(model.Matrix{
+		{Metric: model.Metric{"job": "api"}, Values: values},
+		{Metric: model.Metric{"job": "web"}, Values: values[:3]},
+	}, 4)
+	assert.Equal(t, []model.SamplePair{
+		{Timestamp: 2000, Value: 1},
+		{Timestamp: 5000, Value: 4},
+		{Timestamp: 8000, Value: 7},
+		{Timestamp: 9000, Value: 9},
+	}, matrix[0].Values)
+	assert.Len(t, matrix[1].Values, 3)
+}
+
+func TestQueryPrometheusMaxDataPoints(t *testing.T) {
+	var step string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/query_range", r.URL.Path)
+		step = r.Form.Get("step")
+		start, _ := strconv.ParseFloat(r.Form.Get("start"), 64)
+		end, _ := strconv.ParseFloat(r.Form.Get("end"), 64)
+		stepSeconds, _ := strconv.ParseFloat(step, 64)
+		var values []string
+		for ts := start; ts <= end; ts += stepSeconds {
+			values = append(values, fmt.Sprintf(`[%g,"1"]`, ts))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"api"},"values":[%s]}]}}`, strings.Join(values, ","))
+	})
+
+	result, err := queryPrometheus(ctx, QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2024-01-01T00:00:00Z",
+		EndTime:       "2024-01-01T06:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "300", step)
+	assert.Len(t, result.(model.Matrix)[0].Values, 73)
+
+	result, err = queryPrometheus(ctx, QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2024-01-01T00:00:00Z",
+		EndTime:       "2024-01-01T06:00:00Z",
+		StepSeconds:   60,
+		MaxDataPoints: 50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "60", step)
+	assert.LessOrEqual(t, len(result.(model.Matrix)[0].Values), 50)
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultPrometheusTopK is the default number of series returned by
+// get_prometheus_topk.
+const DefaultPrometheusTopK = 10
+
+// MaxPrometheusTopK is the maximum number of series returned by
+// get_prometheus_topk.
+const MaxPrometheusTopK = 100
+
+// prometheusTopKAggregations are the aggregations series can be grouped by.
+var prometheusTopKAggregations = []string{"sum", "avg", "max", "min", "count"}
+
+type GetPrometheusTopKParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr          string   `json:"expr" jsonschema:"required,description=The PromQL expression whose series to rank\\, e.g. 'container_memory_working_set_bytes' or 'rate(http_requests_total[5m])'"`
+	By            []string `json:"by,omitempty" jsonschema:"description=Optionally\\, the labels to group series by before ranking them\\, e.g. ['namespace'\\, 'pod']. Defaults to ranking each series"`
+	Aggregation   string   `json:"aggregation,omitempty" jsonschema:"enum=sum,enum=avg,enum=max,enum=min,enum=count,description=Optionally\\, how the series of a group are aggregated when grouping with 'by' (default: sum)"`
+	K             int      `json:"k,omitempty" jsonschema:"description=Optionally\\, the number of series to return (default: 10\\, max: 100)"`
+	Bottom        bool     `json:"bottom,omitempty" jsonschema:"description=Optionally\\, return the series with the lowest values instead of the highest"`
+	Time          string   `json:"time,omitempty" jsonschema:"description=Optionally\\, the time to evaluate the expression at\\, in RFC3339 format or relative to now (e.g. 'now-1h' or '1 hour ago'). Defaults to now"`
+}
+
+// prometheusTopKSeries is a ranked series of get_prometheus_topk.
+type prometheusTopKSeries struct {
+	Rank   int            `json:"rank"`
+	Labels model.LabelSet `json:"labels"`
+	Value  float64        `json:"value"`
+}
+
+// prometheusTopK is the result of get_prometheus_topk.
+type prometheusTopK struct {
+	Query  string                 `json:"query"`
+	Time   string                 `json:"time"`
+	Series []prometheusTopKSeries `json:"series"`
+}
+
+// prometheusTopKQuery builds the topk, or bottomk, query of the arguments.
+func prometheusTopKQuery(args GetPrometheusTopKParams, k int) (string, error) {
+	expr := strings.TrimSpace(args.Expr)
+	if expr == "" {
+		return "", fmt.Errorf("expr is required")
+	}
+	aggregation := args.Aggregation
+	if aggregation == "" {
+		aggregation = "sum"
+	}
+	if !slices.Contains(prometheusTopKAggregations, aggregation) {
+		return "", fmt.Errorf("invalid aggregation %q, must be one of %s", aggregation, strings.Join(prometheusTopKAggregations, ", "))
+	}
+	for _, label := range args.By {
+		if !model.LabelName(label).IsValidLegacy() {
+			return "", fmt.Errorf("invalid label name %q", label)
+		}
+	}
+	if len(args.By) > 0 {
+		expr = fmt.Sprintf("%s by (%s) (%s)", aggregation, strings.Join(args.By, ", "), expr)
+	}
+	function := "topk"
+	if args.Bottom {
+		function = "bottomk"
+	}
+	return fmt.Sprintf("%s(%d, %s)", function, k, expr), nil
+}
+
+func getPrometheusTopK(ctx context.Context, args GetPrometheusTopKParams) (*prometheusTopK, error) {
+	k := args.K
+	if k <= 0 {
+		k = DefaultPrometheusTopK
+	}
+	k = min(k, MaxPrometheusTopK)
+	query, err := prometheusTopKQuery(args, k)
+	if err != nil {
+		return nil, err
+	}
+	evalTime := time.Now()
+	if args.Time != "" {
+		if evalTime, err = parseTime(args.Time); err != nil {
+			return nil, fmt.Errorf("parsing time: %w", err)
+		}
+	}
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	result, _, err := promClient.Query(ctx, query, evalTime)
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus: %w", err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("expr must return an instant vector, got a %s", result.Type())
+	}
+
+	// NaN and infinite values can't be ranked or encoded as JSON, and topk
+	// doesn't order its result, so rank the series here.
+	vector = slices.DeleteFunc(vector, func(s *model.Sample) bool {
+		return math.IsNaN(float64(s.Value)) || math.IsInf(float64(s.Value), 0)
+	})
+	sortPrometheusTopK(vector, args.Bottom)
+	topK := &prometheusTopK{
+		Query:  query,
+		Time:   evalTime.UTC().Format(time.RFC3339),
+		Series: make([]prometheusTopKSeries, 0, len(vector)),
+	}
+	for i, sample := range vector {
+		topK.Series = append(topK.Series, prometheusTopKSeries{
+			Rank:   i + 1,
+			Labels: model.LabelSet(sample.Metric),
+			Value:  float64(sample.Value),
+		})
+	}
+	return topK, nil
+}
+
+// sortPrometheusTopK sorts samples by value, highest first or lowest first
+// for bottomk, and then by labels.
+func sortPrometheusTopK(vector model.Vector, bottom bool) {
+	slices.SortStableFunc(vector, func(a, b *model.Sample) int {
+		if a.Value != b.Value {
+			if (a.Value > b.Value) != bottom {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Metric.String(), b.Metric.String())
+	})
+}
+
+var GetPrometheusTopK = mcpgrafana.MustTool(
+	"get_prometheus_topk",
+	"Ranks the series of a PromQL expression by value at a point in time and returns the top `k` (default 10) with their labels and values, highest first, or the lowest with `bottom`. Series can be grouped by labels with `by`, aggregating each group with `aggregation` (default sum), so you don't need to write the aggregation yourself. Use this to answer questions like \"which pods use the most memory\" (`container_memory_working_set_bytes` by `pod`) or \"which services have the most errors\".",
+	getPrometheusTopK,
+	mcp.WithTitleAnnotation("Get Prometheus top-k series"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
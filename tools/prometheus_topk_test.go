@@ -0,0 +1,72 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusTopKQuery(t *testing.T) {
+	query, err := prometheusTopKQuery(GetPrometheusTopKParams{Expr: "up"}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "topk(10, up)", query)
+
+	query, err = prometheusTopKQuery(GetPrometheusTopKParams{Expr: "container_memory_working_set_bytes", By: []string{"namespace", "pod"}}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "topk(5, sum by (namespace, pod) (container_memory_working_set_bytes))", query)
+
+	query, err = prometheusTopKQuery(GetPrometheusTopKParams{Expr: "up", By: []string{"job"}, Aggregation: "count", Bottom: true}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "bottomk(3, count by (job) (up))", query)
+
+	_, err = prometheusTopKQuery(GetPrometheusTopKParams{Expr: "up", Aggregation: "stddev"}, 10)
+	assert.ErrorContains(t, err, "invalid aggregation")
+	_, err = prometheusTopKQuery(GetPrometheusTopKParams{Expr: "up", By: []string{"k8s.pod"}}, 10)
+	assert.ErrorContains(t, err, "invalid label name")
+	_, err = prometheusTopKQuery(GetPrometheusTopKParams{Expr: " "}, 10)
+	assert.ErrorContains(t, err, "expr is required")
+}
+
+func TestGetPrometheusTopK(t *testing.T) {
+	var query, evalTime string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		query, evalTime = r.Form.Get("query"), r.Form.Get("time")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"pod":"cart"},"value":[1704067200,"100"]},
+			{"metric":{"pod":"checkout"},"value":[1704067200,"300"]},
+			{"metric":{"pod":"search"},"value":[1704067200,"NaN"]},
+			{"metric":{"pod":"api"},"value":[1704067200,"300"]}
+		]}}`))
+	})
+
+	topK, err := getPrometheusTopK(ctx, GetPrometheusTopKParams{
+		DatasourceUID: "prom",
+		Expr:          "container_memory_working_set_bytes",
+		By:            []string{"pod"},
+		K:             500,
+		Time:          "2024-01-01T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "topk(100, sum by (pod) (container_memory_working_set_bytes))", query)
+	assert.Equal(t, "1704067200", evalTime)
+	assert.Equal(t, "2024-01-01T00:00:00Z", topK.Time)
+	assert.Equal(t, []prometheusTopKSeries{
+		{Rank: 1, Labels: model.LabelSet{"pod": "api"}, Value: 300},
+		{Rank: 2, Labels: model.LabelSet{"pod": "checkout"}, Value: 300},
+		{Rank: 3, Labels: model.LabelSet{"pod": "cart"}, Value: 100},
+	}, topK.Series)
+
+	topK, err = getPrometheusTopK(ctx, GetPrometheusTopKParams{DatasourceUID: "prom", Expr: "up", Bottom: true})
+	require.NoError(t, err)
+	assert.Equal(t, "bottomk(10, up)", query)
+	assert.Equal(t, model.LabelValue("cart"), topK.Series[0].Labels["pod"])
+}
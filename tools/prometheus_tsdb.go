@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// DefaultPrometheusTSDBStatusLimit is the default number of entries of each
+// list returned by get_prometheus_tsdb_status, as in Prometheus.
+const DefaultPrometheusTSDBStatusLimit = 10
+
+// MaxPrometheusTSDBStatusLimit is the maximum number of entries of each list
+// returned by get_prometheus_tsdb_status.
+const MaxPrometheusTSDBStatusLimit = 100
+
+type GetPrometheusTSDBStatusParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the number of entries of each list to return (default: 10\\, max: 100)"`
+}
+
+// prometheusTSDBStat is an entry of a list of TSDB statistics. Percent is
+// the share of all series in the head block, for series counts.
+type prometheusTSDBStat struct {
+	Name    string   `json:"name"`
+	Value   uint64   `json:"value"`
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// prometheusTSDBStatus is the result of get_prometheus_tsdb_status. The
+// statistics are of the head block, which holds the most recent samples.
+type prometheusTSDBStatus struct {
+	NumSeries     int    `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int    `json:"chunkCount"`
+	MinTime       string `json:"minTime,omitempty"`
+	MaxTime       string `json:"maxTime,omitempty"`
+
+	SeriesCountByMetricName     []prometheusTSDBStat `json:"seriesCountByMetricName"`
+	SeriesCountByLabelValuePair []prometheusTSDBStat `json:"seriesCountByLabelValuePair"`
+	LabelValueCountByLabelName  []prometheusTSDBStat `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []prometheusTSDBStat `json:"memoryInBytesByLabelName"`
+}
+
+// toPrometheusTSDBStats converts a list of statistics, adding their share of
+// the total if it is positive.
+func toPrometheusTSDBStats(stats []promv1.Stat, total int) []prometheusTSDBStat {
+	result := make([]prometheusTSDBStat, 0, len(stats))
+	for _, s := range stats {
+		stat := prometheusTSDBStat{Name: s.Name, Value: s.Value}
+		if total > 0 {
+			percent := math.Round(float64(s.Value)/float64(total)*10000) / 100
+			stat.Percent = &percent
+		}
+		result = append(result, stat)
+	}
+	return result
+}
+
+func getPrometheusTSDBStatus(ctx context.Context, args GetPrometheusTSDBStatusParams) (*prometheusTSDBStatus, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPrometheusTSDBStatusLimit
+	}
+	limit = min(limit, MaxPrometheusTSDBStatusLimit)
+
+	tsdb, err := promClient.TSDB(ctx, promv1.WithLimit(uint64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus TSDB status: %w", err)
+	}
+
+	head := tsdb.HeadStats
+	status := &prometheusTSDBStatus{
+		NumSeries:                   head.NumSeries,
+		NumLabelPairs:               head.NumLabelPairs,
+		ChunkCount:                  head.ChunkCount,
+		SeriesCountByMetricName:     toPrometheusTSDBStats(tsdb.SeriesCountByMetricName, head.NumSeries),
+		SeriesCountByLabelValuePair: toPrometheusTSDBStats(tsdb.SeriesCountByLabelValuePair, head.NumSeries),
+		LabelValueCountByLabelName:  toPrometheusTSDBStats(tsdb.LabelValueCountByLabelName, 0),
+		MemoryInBytesByLabelName:    toPrometheusTSDBStats(tsdb.MemoryInBytesByLabelName, 0),
+	}
+	// An empty head block reports the extreme int64 values.
+	if head.MinTime > 0 && head.MinTime <= head.MaxTime {
+		status.MinTime = time.UnixMilli(int64(head.MinTime)).UTC().Format(time.RFC3339)
+		status.MaxTime = time.UnixMilli(int64(head.MaxTime)).UTC().Format(time.RFC3339)
+	}
+	return status, nil
+}
+
+var GetPrometheusTSDBStatus = mcpgrafana.MustTool(
+	"get_prometheus_tsdb_status",
+	"Returns cardinality statistics of the head block of a Prometheus datasource's TSDB, which holds the most recent samples: its numbers of series, label pairs and chunks, the metrics with the most series, the label-value pairs in the most series, the labels with the most values and the labels using the most memory. Series counts include their percentage of all series. Use this to diagnose cardinality explosions, then look at the offending metrics with `list_prometheus_series` or `list_prometheus_label_values`. Not all Prometheus-compatible datasources support this.",
+	getPrometheusTSDBStatus,
+	mcp.WithTitleAnnotation("Get Prometheus TSDB status"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
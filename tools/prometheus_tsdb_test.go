@@ -0,0 +1,46 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPrometheusTSDBStatus(t *testing.T) {
+	var limit string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/status/tsdb", r.URL.Path)
+		limit = r.URL.Query().Get("limit")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{
+			"headStats":{"numSeries":1000,"numLabelPairs":200,"chunkCount":3000,"minTime":1704067200000,"maxTime":1704074400000},
+			"seriesCountByMetricName":[{"name":"http_requests_total","value":600},{"name":"up","value":10}],
+			"labelValueCountByLabelName":[{"name":"request_id","value":550}],
+			"memoryInBytesByLabelName":[{"name":"request_id","value":40000}],
+			"seriesCountByLabelValuePair":[{"name":"job=api","value":700}]
+		}}`))
+	})
+
+	status, err := getPrometheusTSDBStatus(ctx, GetPrometheusTSDBStatusParams{DatasourceUID: "prom", Limit: 500})
+	require.NoError(t, err)
+	assert.Equal(t, "100", limit)
+	assert.Equal(t, 1000, status.NumSeries)
+	assert.Equal(t, "2024-01-01T00:00:00Z", status.MinTime)
+	assert.Equal(t, "2024-01-01T02:00:00Z", status.MaxTime)
+	require.Len(t, status.SeriesCountByMetricName, 2)
+	assert.Equal(t, "http_requests_total", status.SeriesCountByMetricName[0].Name)
+	require.NotNil(t, status.SeriesCountByMetricName[0].Percent)
+	assert.Equal(t, 60.0, *status.SeriesCountByMetricName[0].Percent)
+	assert.Equal(t, 70.0, *status.SeriesCountByLabelValuePair[0].Percent)
+	assert.Nil(t, status.LabelValueCountByLabelName[0].Percent)
+	assert.Equal(t, uint64(40000), status.MemoryInBytesByLabelName[0].Value)
+
+	_, err = getPrometheusTSDBStatus(ctx, GetPrometheusTSDBStatusParams{DatasourceUID: "prom"})
+	require.NoError(t, err)
+	assert.Equal(t, "10", limit)
+}
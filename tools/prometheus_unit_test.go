@@ -1,9 +1,16 @@
+//go:build unit
+// +build unit
+
 package tools
 
 import (
+	"net/http"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -67,9 +74,21 @@ func TestParseRelativeTime(t *testing.T) {
 			input:         "now-1.5h",
 			expectedError: true,
 		},
+		{
+			name:          "6 hours ago",
+			input:         "6 hours ago",
+			expectedError: false,
+			expectedDelta: -6 * time.Hour,
+		},
+		{
+			name:          "1 Day  ago",
+			input:         "1 Day  ago",
+			expectedError: false,
+			expectedDelta: -day,
+		},
 		{
 			name:          "invalid format",
-			input:         "yesterday",
+			input:         "the day before",
 			expectedError: true,
 		},
 		{
@@ -115,3 +134,192 @@ func TestParseRelativeTime(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeFormats(t *testing.T) {
+	want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, input := range []string{
+		"2024-01-01T00:00:00Z",
+		"2024-01-01T01:00:00+01:00",
+		"2024-01-01",
+		"1704067200",
+		"1704067200000",
+		"1704067200000000",
+		"1704067200000000000",
+		`"1704067200000000000"`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			result, err := parseTime(input)
+			require.NoError(t, err)
+			assert.True(t, want.Equal(result), "got %s", result)
+		})
+	}
+}
+
+func TestParseNaturalTime(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for input, want := range map[string]time.Time{
+		"today":      today,
+		"Yesterday":  today.AddDate(0, 0, -1),
+		"this month": time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC),
+		"last month": time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		t.Run(input, func(t *testing.T) {
+			result, err := parseTime(input)
+			require.NoError(t, err)
+			assert.True(t, want.Equal(result), "got %s", result)
+		})
+	}
+
+	thisWeek, err := parseTime("this week")
+	require.NoError(t, err)
+	lastWeek, err := parseTime("last week")
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, thisWeek.Sub(lastWeek))
+	assert.False(t, thisWeek.After(today))
+}
+
+func TestEstimatePrometheusQuery(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/series", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("match[]") {
+		case `{code=~"5..", __name__="http_requests_total"}`:
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"http_requests_total","code":"500"},{"__name__":"http_requests_total","code":"503"}]}`))
+		case `{__name__="http_requests_total"}`:
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"__name__":"http_requests_total","code":"200"},{"__name__":"http_requests_total","code":"500"},{"__name__":"http_requests_total","code":"503"}]}`))
+		default:
+			t.Errorf("unexpected selector %q", r.Form.Get("match[]"))
+		}
+	})
+
+	result, err := queryPrometheusTool(ctx, QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          `sum(rate(http_requests_total{code=~"5.."}[5m])) / sum(rate(http_requests_total[5m]))`,
+		StartTime:     "now-1h",
+		EndTime:       "now",
+		StepSeconds:   60,
+		EstimateOnly:  true,
+	})
+	require.NoError(t, err)
+	estimate, ok := unwrapResult(result).(*prometheusQueryEstimate)
+	require.True(t, ok, "expected an estimate, got %T", result)
+	assert.Equal(t, 5, estimate.Series)
+	assert.Equal(t, []prometheusSelectorEstimate{
+		{Selector: `{code=~"5..", __name__="http_requests_total"}`, Series: 2},
+		{Selector: `{__name__="http_requests_total"}`, Series: 3},
+	}, estimate.Selectors)
+	assert.Equal(t, int64(5*61), estimate.Samples)
+}
+
+func TestQueryPrometheusInstant(t *testing.T) {
+	var evalTime string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/query", r.URL.Path)
+		evalTime = r.Form.Get("time")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("query") {
+		case "up":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"api"},"value":[1704067200,"1"]}]}}`))
+		case "scalar(up)":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1704067200,"1"]}}`))
+		default:
+			t.Errorf("unexpected query %q", r.Form.Get("query"))
+		}
+	})
+
+	result, err := queryPrometheusInstantTool(ctx, QueryPrometheusInstantParams{DatasourceUID: "prom", Expr: "up", Time: "2024-01-01T00:00:00Z"})
+	require.NoError(t, err)
+	assert.Equal(t, "1704067200", evalTime)
+	vector, ok := unwrapResult(result).(model.Vector)
+	require.True(t, ok, "expected a vector, got %T", result)
+	require.Len(t, vector, 1)
+	assert.Equal(t, model.LabelValue("api"), vector[0].Metric["job"])
+	assert.Equal(t, model.SampleValue(1), vector[0].Value)
+
+	before := time.Now().Add(-time.Second)
+	result, err = queryPrometheusInstantTool(ctx, QueryPrometheusInstantParams{DatasourceUID: "prom", Expr: "scalar(up)"})
+	require.NoError(t, err)
+	assert.IsType(t, &model.Scalar{}, unwrapResult(result))
+	seconds, err := strconv.ParseFloat(evalTime, 64)
+	require.NoError(t, err)
+	assert.False(t, time.Unix(int64(seconds), 0).Before(before.Truncate(time.Second)), "defaults to now")
+}
+
+func TestListPrometheusLabelsScope(t *testing.T) {
+	var matches []string
+	var start string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		matches, start = r.Form["match[]"], r.Form.Get("start")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/labels":
+			_, _ = w.Write([]byte(`{"status":"success","data":["__name__","code","job"]}`))
+		case "/api/v1/label/code/values":
+			_, _ = w.Write([]byte(`{"status":"success","data":["200","500"]}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	names, err := listPrometheusLabelNames(ctx, ListPrometheusLabelNamesParams{
+		DatasourceUID: "prom",
+		Metric:        "http_requests_total",
+		StartRFC3339:  "2024-01-01T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"__name__", "code", "job"}, names)
+	assert.Equal(t, []string{`{__name__='http_requests_total'}`}, matches)
+	assert.Equal(t, "1704067200", start)
+
+	before := time.Now().Add(-time.Hour - time.Second)
+	values, err := listPrometheusLabelValues(ctx, ListPrometheusLabelValuesParams{
+		DatasourceUID: "prom",
+		LabelName:     "code",
+		Metric:        "http_requests_total",
+		Matches: []Selector{
+			{Filters: []LabelMatcher{{Name: "job", Value: "api"}}},
+			{Filters: []LabelMatcher{{Name: "job", Value: "web"}}},
+		},
+		StartRFC3339: "now-1h",
+	})
+	require.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, []string{`{job='api', __name__='http_requests_total'}`, `{job='web', __name__='http_requests_total'}`}, matches)
+	seconds, err := strconv.ParseFloat(start, 64)
+	require.NoError(t, err)
+	assert.False(t, time.Unix(int64(seconds), 0).Before(before.Truncate(time.Second)), "relative start time")
+}
+
+func TestListPrometheusSeries(t *testing.T) {
+	var matches []string
+	ctx := newMockDatasourceContext(t, "prom", "prometheus", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "/api/v1/series", r.URL.Path)
+		matches = r.Form["match[]"]
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":[
+			{"__name__":"http_requests_total","job":"web","code":"200"},
+			{"__name__":"http_requests_total","job":"api","code":"500"},
+			{"__name__":"http_requests_total","job":"api","code":"200"}
+		]}`))
+	})
+
+	result, err := listPrometheusSeries(ctx, ListPrometheusSeriesParams{
+		DatasourceUID: "prom",
+		Match:         []string{`http_requests_total{job="api"}`, `http_requests_total{job="web"}`},
+		Limit:         2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`http_requests_total{job="api"}`, `http_requests_total{job="web"}`}, matches)
+	assert.Equal(t, 3, result.TotalSeries)
+	assert.Equal(t, []model.LabelSet{
+		{"__name__": "http_requests_total", "job": "api", "code": "200"},
+		{"__name__": "http_requests_total", "job": "web", "code": "200"},
+	}, result.Series)
+
+	_, err = listPrometheusSeries(ctx, ListPrometheusSeriesParams{DatasourceUID: "prom"})
+	assert.ErrorContains(t, err, "at least one match selector is required")
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// promqlVariablePlaceholder replaces user-defined template variables in a
+// query so that it can be parsed. Selectors and labels whose names contain it
+// cannot be checked statically.
+const promqlVariablePlaceholder = "__grafana_var__"
+
+// templateVariableRegex matches Grafana template variables in their `$var`,
+// `${var}`, `${var:format}` and `[[var]]` forms.
+var templateVariableRegex = regexp.MustCompile(`\$\{[^}]+\}|\$[a-zA-Z_][a-zA-Z0-9_]*|\[\[[^\]]+\]\]`)
+
+// interpolatePromQLVariables replaces Grafana template variables with values
+// that keep the query parseable: global interval variables become a
+// duration (or an integer for their `_ms` and `_s` variants), and all other
+// variables become promqlVariablePlaceholder.
+func interpolatePromQLVariables(expr string) string {
+	return templateVariableRegex.ReplaceAllStringFunc(expr, func(v string) string {
+		name := strings.Trim(v, "$[]{}")
+		name, _, _ = strings.Cut(name, ":")
+		switch {
+		case !strings.HasPrefix(name, "__"):
+			return promqlVariablePlaceholder
+		case strings.HasSuffix(name, "_ms"), strings.HasSuffix(name, "_s"):
+			return "300"
+		case name == "__interval", name == "__rate_interval", name == "__range", name == "__auto":
+			return "5m"
+		}
+		return promqlVariablePlaceholder
+	})
+}
+
+// parsePromQL parses a PromQL expression after interpolating template
+// variables.
+func parsePromQL(expr string) (parser.Expr, error) {
+	parsed, err := parser.ParseExpr(interpolatePromQLVariables(expr))
+	if err != nil {
+		return nil, fmt.Errorf("parsing PromQL: %w", err)
+	}
+	return parsed, nil
+}
+
+// promqlSelector is a series selector referenced by a PromQL expression.
+type promqlSelector struct {
+	// Metric is the metric name, or empty if the selector has no fixed name.
+	Metric string
+	// Labels are the label names matched on, excluding __name__.
+	Labels []string
+	// Matchers are all of the selector's label matchers.
+	Matchers []*labels.Matcher
+}
+
+// hasVariable reports whether the selector's metric name was a template
+// variable.
+func (s promqlSelector) hasVariable() bool {
+	return strings.Contains(s.Metric, promqlVariablePlaceholder)
+}
+
+// promqlSelectors returns every series selector in the expression, in the
+// order they appear.
+func promqlSelectors(expr parser.Expr) []promqlSelector {
+	var selectors []promqlSelector
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		s := promqlSelector{Matchers: vs.LabelMatchers}
+		for _, m := range vs.LabelMatchers {
+			if m.Name == labels.MetricName {
+				if m.Type == labels.MatchEqual {
+					s.Metric = m.Value
+				}
+				continue
+			}
+			s.Labels = append(s.Labels, m.Name)
+		}
+		selectors = append(selectors, s)
+		return nil
+	})
+	return selectors
+}
@@ -0,0 +1,41 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolatePromQLVariables(t *testing.T) {
+	for _, tc := range []struct {
+		expr, expected string
+	}{
+		{`rate(http_requests_total[$__rate_interval])`, `rate(http_requests_total[5m])`},
+		{`rate(http_requests_total{job="$job"}[$__interval])`, `rate(http_requests_total{job="__grafana_var__"}[5m])`},
+		{`sum(x{env=~"${env:regex}"}) / $__range_s`, `sum(x{env=~"__grafana_var__"}) / 300`},
+		{`[[metric]]{instance="a"}`, `__grafana_var__{instance="a"}`},
+	} {
+		assert.Equal(t, tc.expected, interpolatePromQLVariables(tc.expr))
+	}
+}
+
+func TestPromQLSelectors(t *testing.T) {
+	expr, err := parsePromQL(`sum by (job) (rate(http_requests_total{job="$job", code=~"5.."}[$__rate_interval])) / on() group_left {__name__="up", instance!=""} + $metric`)
+	require.NoError(t, err)
+
+	selectors := promqlSelectors(expr)
+	require.Len(t, selectors, 3)
+	assert.Equal(t, "http_requests_total", selectors[0].Metric)
+	assert.Equal(t, []string{"job", "code"}, selectors[0].Labels)
+	assert.False(t, selectors[0].hasVariable())
+	assert.Equal(t, "up", selectors[1].Metric)
+	assert.Equal(t, []string{"instance"}, selectors[1].Labels)
+	assert.True(t, selectors[2].hasVariable())
+
+	_, err = parsePromQL(`rate(foo[$window])`)
+	assert.Error(t, err)
+}
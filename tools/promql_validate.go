@@ -0,0 +1,374 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+type ValidatePromQLParams struct {
+	Expr string `json:"expr" jsonschema:"required,description=The PromQL expression to validate. Grafana template variables such as $job or $__rate_interval are allowed"`
+}
+
+// promqlError is a syntax or type error in a PromQL expression. Line and
+// Column are 1-based and point at the start of the offending part.
+type promqlError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// promqlValidation is the result of validate_promql.
+type promqlValidation struct {
+	Valid  bool          `json:"valid"`
+	Errors []promqlError `json:"errors,omitempty"`
+	// Type is the type the expression evaluates to: "vector", "matrix",
+	// "scalar" or "string".
+	Type    string   `json:"type,omitempty"`
+	Metrics []string `json:"metrics,omitempty"`
+	// Explanation describes in plain language what the expression computes.
+	Explanation string `json:"explanation,omitempty"`
+	// Warnings are likely mistakes in a valid expression.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// promqlPosition converts an offset in a query to a 1-based line and column.
+func promqlPosition(query string, pos int) (int, int) {
+	if pos < 0 || pos > len(query) {
+		return 0, 0
+	}
+	line, lineStart := 1, 0
+	for i, c := range query[:pos] {
+		if c == '\n' {
+			line++
+			lineStart = i + 1
+		}
+	}
+	return line, pos - lineStart + 1
+}
+
+func validatePromQL(ctx context.Context, args ValidatePromQLParams) (*promqlValidation, error) {
+	if strings.TrimSpace(args.Expr) == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	query := interpolatePromQLVariables(args.Expr)
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		result := &promqlValidation{}
+		var parseErrs parser.ParseErrors
+		if !errors.As(err, &parseErrs) {
+			result.Errors = append(result.Errors, promqlError{Message: err.Error()})
+			return result, nil
+		}
+		for _, e := range parseErrs {
+			line, col := promqlPosition(query, int(e.PositionRange.Start))
+			result.Errors = append(result.Errors, promqlError{Message: e.Err.Error(), Line: line, Column: col})
+		}
+		if query != args.Expr {
+			result.Warnings = append(result.Warnings, "error positions refer to the expression with its template variables replaced")
+		}
+		return result, nil
+	}
+
+	result := &promqlValidation{
+		Valid:       true,
+		Type:        string(expr.Type()),
+		Explanation: explainPromQL(expr),
+		Warnings:    lintPromQL(expr),
+	}
+	seen := map[string]bool{}
+	for _, s := range promqlSelectors(expr) {
+		if s.Metric != "" && !s.hasVariable() && !seen[s.Metric] {
+			seen[s.Metric] = true
+			result.Metrics = append(result.Metrics, s.Metric)
+		}
+	}
+	sort.Strings(result.Metrics)
+	return result, nil
+}
+
+// promqlFunctionPhrases describe what common PromQL functions compute, taking
+// the description of their range or instant vector argument.
+var promqlFunctionPhrases = map[string]string{
+	"rate":               "the per-second rate of increase of %s",
+	"irate":              "the per-second rate of increase between the last two samples of %s",
+	"increase":           "the increase of %s",
+	"delta":              "the difference between the first and last values of %s",
+	"idelta":             "the difference between the last two samples of %s",
+	"deriv":              "the per-second derivative of %s",
+	"resets":             "the number of counter resets in %s",
+	"changes":            "the number of times the value changed in %s",
+	"avg_over_time":      "the average value of %s",
+	"min_over_time":      "the minimum value of %s",
+	"max_over_time":      "the maximum value of %s",
+	"sum_over_time":      "the sum of the values of %s",
+	"count_over_time":    "the number of samples in %s",
+	"last_over_time":     "the most recent value of %s",
+	"stddev_over_time":   "the standard deviation of the values of %s",
+	"present_over_time":  "whether there are samples in %s",
+	"absent":             "1 if %s has no series, and nothing otherwise",
+	"absent_over_time":   "1 if %s has no samples, and nothing otherwise",
+	"abs":                "the absolute value of %s",
+	"ceil":               "%s rounded up",
+	"floor":              "%s rounded down",
+	"round":              "%s rounded",
+	"sort":               "%s sorted in ascending order",
+	"sort_desc":          "%s sorted in descending order",
+	"timestamp":          "the timestamps of the samples of %s",
+	"scalar":             "the value of %s as a scalar",
+	"vector":             "%s as a vector without labels",
+	"histogram_count":    "the number of observations of the native histogram %s",
+	"histogram_sum":      "the sum of the observations of the native histogram %s",
+	"histogram_avg":      "the average of the observations of the native histogram %s",
+	"quantile_over_time": "the %s quantile of the values of %s",
+	"histogram_quantile": "the %s quantile of the histogram %s",
+	"clamp_min":          "%s, raised to at least %s",
+	"clamp_max":          "%s, lowered to at most %s",
+	"predict_linear":     "the value of %s predicted %s seconds ahead by linear regression",
+}
+
+var promqlAggregationPhrases = map[string]string{
+	"sum":      "the sum of %s",
+	"avg":      "the average of %s",
+	"min":      "the minimum of %s",
+	"max":      "the maximum of %s",
+	"count":    "the number of series of %s",
+	"group":    "one series per group of %s",
+	"stddev":   "the standard deviation of %s",
+	"stdvar":   "the variance of %s",
+	"topk":     "the %s largest series of %s",
+	"bottomk":  "the %s smallest series of %s",
+	"quantile": "the %s quantile of %s",
+	"limitk":   "at most %s series of %s",
+}
+
+var promqlOperatorPhrases = map[string]string{
+	"+":      "plus",
+	"-":      "minus",
+	"*":      "multiplied by",
+	"/":      "divided by",
+	"%":      "modulo",
+	"^":      "to the power of",
+	"atan2":  "atan2 with",
+	"==":     "equal to",
+	"!=":     "not equal to",
+	">":      "greater than",
+	"<":      "less than",
+	">=":     "greater than or equal to",
+	"<=":     "less than or equal to",
+	"and":    "where also present in",
+	"or":     "together with the series missing from it of",
+	"unless": "except where present in",
+}
+
+// explainPromQL describes in plain language what a parsed expression
+// computes.
+func explainPromQL(expr parser.Expr) string {
+	switch e := expr.(type) {
+	case *parser.ParenExpr:
+		return explainPromQL(e.Expr)
+	case *parser.StepInvariantExpr:
+		return explainPromQL(e.Expr)
+	case *parser.NumberLiteral:
+		return strconv.FormatFloat(e.Val, 'g', -1, 64)
+	case *parser.StringLiteral:
+		return strconv.Quote(e.Val)
+	case *parser.UnaryExpr:
+		return "the negation of " + explainPromQL(e.Expr)
+	case *parser.VectorSelector:
+		return explainPromQLSelector(e)
+	case *parser.MatrixSelector:
+		return fmt.Sprintf("%s over the last %s", explainPromQL(e.VectorSelector), model.Duration(e.Range))
+	case *parser.SubqueryExpr:
+		step := "the default step"
+		if e.Step > 0 {
+			step = model.Duration(e.Step).String()
+		}
+		return fmt.Sprintf("%s, evaluated every %s over the last %s", explainPromQL(e.Expr), step, model.Duration(e.Range))
+	case *parser.Call:
+		return explainPromQLCall(e)
+	case *parser.AggregateExpr:
+		return explainPromQLAggregation(e)
+	case *parser.BinaryExpr:
+		return explainPromQLBinary(e)
+	}
+	return expr.String()
+}
+
+func explainPromQLSelector(vs *parser.VectorSelector) string {
+	var matchers []string
+	for _, m := range vs.LabelMatchers {
+		if m.Name != labels.MetricName || m.Type != labels.MatchEqual || vs.Name == "" {
+			matchers = append(matchers, m.String())
+		}
+	}
+	var b strings.Builder
+	if vs.Name != "" {
+		fmt.Fprintf(&b, "the %s series", vs.Name)
+		if len(matchers) > 0 {
+			fmt.Fprintf(&b, " with %s", strings.Join(matchers, ", "))
+		}
+	} else {
+		fmt.Fprintf(&b, "the series matching {%s}", strings.Join(matchers, ", "))
+	}
+	if vs.OriginalOffset != 0 {
+		fmt.Fprintf(&b, " as of %s earlier", model.Duration(vs.OriginalOffset))
+	}
+	return b.String()
+}
+
+func explainPromQLCall(call *parser.Call) string {
+	args := make([]string, 0, len(call.Args))
+	for _, a := range call.Args {
+		args = append(args, explainPromQL(a))
+	}
+	if phrase, ok := promqlFunctionPhrases[call.Func.Name]; ok {
+		if n := strings.Count(phrase, "%s"); len(args) >= n {
+			values := make([]any, n)
+			for i := range values {
+				values[i] = args[i]
+			}
+			return fmt.Sprintf(phrase, values...)
+		}
+	}
+	return fmt.Sprintf("%s() of %s", call.Func.Name, strings.Join(args, " and "))
+}
+
+func explainPromQLAggregation(agg *parser.AggregateExpr) string {
+	op := agg.Op.String()
+	inner := explainPromQL(agg.Expr)
+	var s string
+	switch {
+	case op == "count_values":
+		s = fmt.Sprintf("the number of series with each value of %s, in the label %s", inner, explainPromQL(agg.Param))
+	case promqlAggregationPhrases[op] == "":
+		s = fmt.Sprintf("%s of %s", op, inner)
+	case agg.Param != nil:
+		s = fmt.Sprintf(promqlAggregationPhrases[op], explainPromQL(agg.Param), inner)
+	default:
+		s = fmt.Sprintf(promqlAggregationPhrases[op], inner)
+	}
+	switch {
+	case agg.Without:
+		return fmt.Sprintf("%s, for each combination of labels other than %s", s, strings.Join(agg.Grouping, ", "))
+	case len(agg.Grouping) > 0:
+		return fmt.Sprintf("%s, for each %s", s, strings.Join(agg.Grouping, ", "))
+	case op == "topk" || op == "bottomk" || op == "limitk":
+		return s
+	}
+	return s + ", across all series"
+}
+
+func explainPromQLBinary(b *parser.BinaryExpr) string {
+	op := b.Op.String()
+	lhs, rhs := explainPromQL(b.LHS), explainPromQL(b.RHS)
+	phrase := promqlOperatorPhrases[op]
+	if phrase == "" {
+		phrase = op
+	}
+	var s string
+	switch {
+	case b.Op.IsComparisonOperator() && b.ReturnBool:
+		s = fmt.Sprintf("1 where %s is %s %s and 0 otherwise", lhs, phrase, rhs)
+	case b.Op.IsComparisonOperator():
+		s = fmt.Sprintf("%s, keeping only the series %s %s", lhs, phrase, rhs)
+	default:
+		s = fmt.Sprintf("%s %s %s", lhs, phrase, rhs)
+	}
+	if m := b.VectorMatching; m != nil && (m.On || len(m.MatchingLabels) > 0) {
+		if m.On {
+			s += fmt.Sprintf(", matching series on %s", strings.Join(m.MatchingLabels, ", "))
+		} else {
+			s += fmt.Sprintf(", matching series ignoring %s", strings.Join(m.MatchingLabels, ", "))
+		}
+	}
+	return s
+}
+
+// promqlCounterSuffixes are the suffixes of metric names which are usually
+// counters.
+var promqlCounterSuffixes = []string{"_total", "_count", "_sum", "_bucket"}
+
+// promqlRegexMeta matches regular expression syntax in a label matcher's
+// value.
+var promqlRegexMeta = regexp.MustCompile(`[.*+?()\[\]{}|^$\\]`)
+
+// lintPromQL returns likely mistakes in a valid expression.
+func lintPromQL(expr parser.Expr) []string {
+	var warnings []string
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.Call:
+			switch n.Func.Name {
+			case "rate", "irate", "increase", "resets":
+				for _, s := range promqlSelectors(n) {
+					if s.Metric == "" || s.hasVariable() {
+						continue
+					}
+					counter := false
+					for _, suffix := range promqlCounterSuffixes {
+						counter = counter || strings.HasSuffix(s.Metric, suffix)
+					}
+					if !counter {
+						warnings = append(warnings, fmt.Sprintf("%s() is meant for counters, but %s does not look like one: counter names usually end in _total. Use deriv() or delta() for gauges", n.Func.Name, s.Metric))
+					}
+				}
+			case "histogram_quantile":
+				if len(n.Args) < 2 {
+					break
+				}
+				if agg, ok := unwrapPromQLParens(n.Args[1]).(*parser.AggregateExpr); ok {
+					hasLe := false
+					for _, l := range agg.Grouping {
+						hasLe = hasLe || l == "le"
+					}
+					if hasLe == agg.Without {
+						warnings = append(warnings, "histogram_quantile() needs the le label, but the aggregation inside it drops it: aggregate by (le, ...)")
+					}
+				}
+			}
+		case *parser.VectorSelector:
+			for _, m := range n.LabelMatchers {
+				if (m.Type == labels.MatchRegexp || m.Type == labels.MatchNotRegexp) && m.Value != "" && !promqlRegexMeta.MatchString(m.Value) && !strings.Contains(m.Value, promqlVariablePlaceholder) {
+					op := "="
+					if m.Type == labels.MatchNotRegexp {
+						op = "!="
+					}
+					warnings = append(warnings, fmt.Sprintf("%s is a regular expression matching a single value, %s%s%q is simpler and faster", m, m.Name, op, m.Value))
+				}
+			}
+		}
+		return nil
+	})
+	return warnings
+}
+
+// unwrapPromQLParens returns the expression inside any parentheses.
+func unwrapPromQLParens(expr parser.Expr) parser.Expr {
+	for {
+		p, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+var ValidatePromQL = mcpgrafana.MustTool(
+	"validate_promql",
+	"Validates a PromQL expression without running it. Reports syntax and type errors with their line and column, or for valid expressions the type of their result (vector, matrix, scalar or string), the metrics they select, a plain-language explanation of what they compute, and warnings about likely mistakes, such as rate() of a gauge or histogram_quantile() without the le label. Grafana template variables are allowed. Use this to check and correct a query before running it with `query_prometheus`.",
+	validatePromQL,
+	mcp.WithTitleAnnotation("Validate PromQL"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,70 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePromQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{
+			Expr: `sum by (job) (rate(http_requests_total{code=~"5.."}[5m])) / sum by (job) (rate(http_requests_total[5m])) > 0.05`,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, "vector", result.Type)
+		assert.Equal(t, []string{"http_requests_total"}, result.Metrics)
+		assert.Equal(t, `the sum of the per-second rate of increase of the http_requests_total series with code=~"5.." over the last 5m, for each job `+
+			`divided by the sum of the per-second rate of increase of the http_requests_total series over the last 5m, for each job, `+
+			`keeping only the series greater than 0.05`, result.Explanation)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("histogram quantile", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{
+			Expr: `histogram_quantile(0.99, sum by (job) (rate(http_request_duration_seconds_bucket{job=~"$job"}[$__rate_interval])))`,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, "the 0.99 quantile of the histogram the sum of the per-second rate of increase of the http_request_duration_seconds_bucket series with job=~\"__grafana_var__\" over the last 5m, for each job", result.Explanation)
+		require.Len(t, result.Warnings, 1)
+		assert.Contains(t, result.Warnings[0], "le label")
+	})
+
+	t.Run("lints", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{Expr: `rate(node_memory_free_bytes{instance=~"host1"}[5m])`})
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		require.Len(t, result.Warnings, 2)
+		assert.Contains(t, result.Warnings[0], "rate() is meant for counters")
+		assert.Contains(t, result.Warnings[1], `instance="host1"`)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{Expr: "sum(rate(http_requests_total[5m])\n  by (job)"})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.NotEmpty(t, result.Errors)
+		assert.Equal(t, 2, result.Errors[0].Line)
+		assert.Positive(t, result.Errors[0].Column)
+		assert.Empty(t, result.Explanation)
+	})
+
+	t.Run("type error", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{Expr: "rate(http_requests_total)"})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0].Message, "expected type range vector")
+		assert.Equal(t, 1, result.Errors[0].Line)
+		assert.Equal(t, 6, result.Errors[0].Column)
+	})
+}
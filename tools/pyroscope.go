@@ -36,7 +36,7 @@ internal and rarely useful to users. If the time range is not provided, it defau
 var ListPyroscopeLabelNames = mcpgrafana.MustTool(
 	"list_pyroscope_label_names",
 	listPyroscopeLabelNamesToolPrompt,
-	listPyroscopeLabelNames,
+	guardTimeRange(listPyroscopeLabelNames),
 	mcp.WithTitleAnnotation("List Pyroscope label names"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -62,7 +62,7 @@ func listPyroscopeLabelNames(ctx context.Context, args ListPyroscopeLabelNamesPa
 		return nil, fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
 	}
 
-	start, end, err = validateTimeRange(start, end)
+	start, end, err = validateTimeRange(ctx, defaultsCategoryPyroscope, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +95,7 @@ is not provided, it defaults to the last hour.
 var ListPyroscopeLabelValues = mcpgrafana.MustTool(
 	"list_pyroscope_label_values",
 	listPyroscopeLabelValuesToolPrompt,
-	listPyroscopeLabelValues,
+	guardTimeRange(listPyroscopeLabelValues),
 	mcp.WithTitleAnnotation("List Pyroscope label values"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -127,7 +127,7 @@ func listPyroscopeLabelValues(ctx context.Context, args ListPyroscopeLabelValues
 		return nil, fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
 	}
 
-	start, end, err = validateTimeRange(start, end)
+	start, end, err = validateTimeRange(ctx, defaultsCategoryPyroscope, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +161,7 @@ for every service. If the time range is not provided, it defaults to the last ho
 var ListPyroscopeProfileTypes = mcpgrafana.MustTool(
 	"list_pyroscope_profile_types",
 	listPyroscopeProfileTypesToolPrompt,
-	listPyroscopeProfileTypes,
+	guardTimeRange(listPyroscopeProfileTypes),
 	mcp.WithTitleAnnotation("List Pyroscope profile types"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -184,7 +184,7 @@ func listPyroscopeProfileTypes(ctx context.Context, args ListPyroscopeProfileTyp
 		return nil, fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
 	}
 
-	start, end, err = validateTimeRange(start, end)
+	start, end, err = validateTimeRange(ctx, defaultsCategoryPyroscope, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -223,7 +223,7 @@ list_pyroscope_label_values tool to fetch available label values. The returned p
 var FetchPyroscopeProfile = mcpgrafana.MustTool(
 	"fetch_pyroscope_profile",
 	fetchPyroscopeProfileToolPrompt,
-	fetchPyroscopeProfile,
+	guardTimeRange(fetchPyroscopeProfile),
 	mcp.WithTitleAnnotation("Fetch Pyroscope profile"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -257,7 +257,7 @@ func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams
 		return "", fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
 	}
 
-	start, end, err = validateTimeRange(start, end)
+	start, end, err = validateTimeRange(ctx, defaultsCategoryPyroscope, start, end)
 	if err != nil {
 		return "", err
 	}
@@ -286,17 +286,12 @@ func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams
 
 func newPyroscopeClient(ctx context.Context, uid string) (*pyroscopeClient, error) {
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
-	httpClient := &http.Client{
-		Transport: &authRoundTripper{
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			apiKey:      cfg.APIKey,
-			underlying:  http.DefaultTransport,
-		},
-		Timeout: 10 * time.Second,
+	httpClient, err := newQueryHTTPClient(ctx, defaultsCategoryPyroscope, http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	_, err = getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
 	if err != nil {
 		return nil, err
 	}
@@ -307,7 +302,8 @@ func newPyroscopeClient(ctx context.Context, uid string) (*pyroscopeClient, erro
 	}
 	base = base.JoinPath("api", "datasources", "proxy", "uid", uid)
 
-	querierClient := querierv1connect.NewQuerierServiceClient(httpClient, base.String())
+	// The querier API is called with POST requests, which only run queries.
+	querierClient := querierv1connect.NewQuerierServiceClient(retryableHTTPClient{httpClient}, base.String())
 
 	client := &pyroscopeClient{
 		QuerierServiceClient: querierClient,
@@ -424,20 +420,23 @@ func rfc3339OrDefault(s string, def time.Time) (time.Time, error) {
 	return def, nil
 }
 
-func validateTimeRange(start time.Time, end time.Time) (time.Time, time.Time, error) {
+// validateTimeRange defaults the end to now and the start to the default time
+// range of the tool category before the end, checks the start is before the
+// end, and clamps the range to the category's limits.
+func validateTimeRange(ctx context.Context, category string, start time.Time, end time.Time) (time.Time, time.Time, error) {
 	if end.IsZero() {
 		end = time.Now()
 	}
 
 	if start.IsZero() {
-		start = end.Add(-1 * time.Hour)
+		start = end.Add(-toolDefaultsFor(ctx, category).TimeRange)
 	}
 
 	if start.After(end) || start.Equal(end) {
 		return time.Time{}, time.Time{}, fmt.Errorf("start timestamp %q must be strictly before end timestamp %q", start.Format(time.RFC3339), end.Format(time.RFC3339))
 	}
 
-	return start, end, nil
+	return clampTimeRange(ctx, category, start, end)
 }
 
 var cleanupRegex = regexp.MustCompile(`(?m)(fontsize=\d+ )|(id="node\d+" )|(labeltooltip=".*?\)" )|(tooltip=".*?\)" )|(N\d+ -> N\d+).*|(N\d+ \[label="other.*\n)|(shape=box )|(fillcolor="#\w{6}")|(color="#\w{6}" )`)
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// defaultQueryRetryBackoff is the default wait before the first retry of a
+// failed datasource request, doubled for each further retry.
+const defaultQueryRetryBackoff = 500 * time.Millisecond
+
+// maxQueryRetryWait is the longest wait before a retry, including waits
+// requested by the datasource with Retry-After.
+const maxQueryRetryWait = 30 * time.Second
+
+// newQueryHTTPClient returns the HTTP client the tools of a datasource
+// category use to query datasources through the Grafana datasource proxy. It
+// authenticates with the Grafana credentials of the context, records the
+// requests for debug results, and applies the category's configured timeout
+// and retries.
+func newQueryHTTPClient(ctx context.Context, category string, defaultTransport *http.Transport) (*http.Client, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+
+	// Create the transport for the TLS configuration and unix socket, if any
+	transport, err := cfg.HTTPTransport(defaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
+	}
+
+	d := toolDefaultsFor(ctx, category)
+	var rt http.RoundTripper = &authRoundTripper{
+		accessToken: cfg.AccessToken,
+		idToken:     cfg.IDToken,
+		apiKey:      cfg.APIKey,
+		underlying:  transport,
+	}
+	if d.Retries > 0 {
+		backoff := d.RetryBackoff
+		if backoff <= 0 {
+			backoff = defaultQueryRetryBackoff
+		}
+		rt = &retryRoundTripper{retries: d.Retries, backoff: backoff, underlying: rt}
+	}
+	return &http.Client{
+		Transport: inspectTransport(ctx, rt),
+		Timeout:   d.Timeout,
+	}, nil
+}
+
+type retryableRequestsKey struct{}
+
+// withRetryableRequests marks the requests sent with a context as safe to
+// retry. This is only needed for POST requests which just run queries, such as
+// those to Prometheus' /api/v1/query, since GET and HEAD requests are always
+// retried.
+func withRetryableRequests(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableRequestsKey{}, true)
+}
+
+// retryableRequest reports whether a request can be sent again without side
+// effects.
+func retryableRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+	retryable, _ := req.Context().Value(retryableRequestsKey{}).(bool)
+	return retryable
+}
+
+// retryableHTTPClient marks all requests it sends as safe to retry, for APIs
+// which are called with POST requests that only run queries.
+type retryableHTTPClient struct {
+	*http.Client
+}
+
+func (c retryableHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.Client.Do(req.WithContext(withRetryableRequests(req.Context())))
+}
+
+// retryRoundTripper retries requests which failed with a connection error, or
+// with a status suggesting the datasource is briefly unavailable, waiting
+// with exponential backoff between attempts. Only requests which are safe to
+// retry, and whose body can be replayed, are retried.
+type retryRoundTripper struct {
+	retries    int
+	backoff    time.Duration
+	underlying http.RoundTripper
+}
+
+// retryableStatus reports whether a response status is worth retrying.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryableRequest(req) {
+		return rt.underlying.RoundTrip(req)
+	}
+	wait := rt.backoff
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("replaying request body: %w", err)
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := rt.underlying.RoundTrip(r)
+		retryable := err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) ||
+			err == nil && retryableStatus(resp.StatusCode)
+		if !retryable || attempt >= rt.retries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := wait
+		if resp != nil {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(min(delay, maxQueryRetryWait))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
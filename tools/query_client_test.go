@@ -0,0 +1,94 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestQueryHTTPClientRetries(t *testing.T) {
+	var attempts int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{
+		APIKey: "key",
+		ToolDefaults: map[string]mcpgrafana.ToolDefaults{
+			"prometheus": {Retries: 2, RetryBackoff: time.Millisecond, Timeout: 5 * time.Second},
+		},
+	})
+	client, err := newQueryHTTPClient(ctx, defaultsCategoryPrometheus, http.DefaultTransport.(*http.Transport))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+
+	req, err := http.NewRequestWithContext(withRetryableRequests(ctx), http.MethodPost, server.URL, strings.NewReader("query=up"))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []string{"query=up", "query=up", "query=up"}, bodies)
+
+	// POST requests which aren't marked as safe to retry are sent once.
+	attempts = 0
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("query=up"))
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, attempts)
+
+	// Without retries configured, failures are returned at once.
+	attempts = 0
+	client, err = newQueryHTTPClient(mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{APIKey: "key"}), defaultsCategoryLoki, http.DefaultTransport.(*http.Transport))
+	require.NoError(t, err)
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryRoundTripperStopsWhenCancelled(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &retryRoundTripper{retries: 3, backoff: time.Millisecond, underlying: http.DefaultTransport}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, attempts)
+}
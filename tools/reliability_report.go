@@ -0,0 +1,399 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultReportRange is the period covered by reliability reports unless
+	// a start time is given.
+	defaultReportRange = 7 * 24 * time.Hour
+
+	// maxReportItems limits the number of alerts and endpoints listed in a
+	// reliability report.
+	maxReportItems = 10
+)
+
+// GetReliabilityReportParams defines the parameters for compiling a
+// reliability report for a team.
+type GetReliabilityReportParams struct {
+	Team          string   `json:"team" jsonschema:"required,description=The team to report on. SLOs and alert rules are matched by the value of their team label"`
+	TeamLabel     string   `json:"teamLabel,omitempty" jsonschema:"description=Optionally\\, the label of SLOs and alert rules holding the team (default: team)"`
+	Services      []string `json:"services,omitempty" jsonschema:"description=Optionally\\, the services owned by the team. Required for the slowest endpoints and anomalies\\, which come from span metrics"`
+	PrometheusUID string   `json:"prometheusUid,omitempty" jsonschema:"description=Optionally\\, the UID of the Prometheus datasource holding the span metrics generated from traces (traces_spanmetrics_*). Required for the slowest endpoints and anomalies"`
+	StartTime     string   `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the report period in RFC3339 format or relative to now (e.g. 'now-14d'). Defaults to 7 days ago"`
+	EndTime       string   `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the report period in RFC3339 format or relative to now. Defaults to now"`
+}
+
+// sloReport is the compliance of an SLO over a report period.
+type sloReport struct {
+	Name      string  `json:"name"`
+	UUID      string  `json:"uuid"`
+	Objective float64 `json:"objective"`
+	// Compliance is the fraction of good events in the period, if known.
+	Compliance *float64 `json:"compliance,omitempty"`
+	// ErrorBudgetSpent is the fraction of the period's error budget used, so
+	// values above 1 mean the objective was missed.
+	ErrorBudgetSpent *float64 `json:"errorBudgetSpent,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// alertNoise is how often an alert rule fired in a report period.
+type alertNoise struct {
+	Name    string `json:"name"`
+	Firings int    `json:"firings"`
+}
+
+// endpointLatency is the 95th percentile latency of a service's endpoint.
+type endpointLatency struct {
+	Service  string  `json:"service"`
+	Endpoint string  `json:"endpoint"`
+	P95Ms    float64 `json:"p95Ms"`
+}
+
+type reliabilityReport struct {
+	Team             string            `json:"team"`
+	Start            time.Time         `json:"start"`
+	End              time.Time         `json:"end"`
+	SLOs             []sloReport       `json:"slos"`
+	NoisiestAlerts   []alertNoise      `json:"noisiestAlerts"`
+	SlowestEndpoints []endpointLatency `json:"slowestEndpoints,omitempty"`
+	Anomalies        []timelineEvent   `json:"anomalies,omitempty"`
+	// Errors lists the sections which could not be compiled.
+	Errors []string `json:"errors,omitempty"`
+	// Markdown is the report formatted for posting to chat.
+	Markdown string `json:"markdown"`
+}
+
+// promRange formats a duration as a PromQL range.
+func promRange(d time.Duration) string {
+	return model.Duration(d.Truncate(time.Minute)).String()
+}
+
+// servicesMatcher returns a label matcher for any of the services.
+func servicesMatcher(services []string) string {
+	quoted := make([]string, 0, len(services))
+	for _, s := range services {
+		quoted = append(quoted, regexp.QuoteMeta(s))
+	}
+	return "service=~" + strconv.Quote(strings.Join(quoted, "|"))
+}
+
+// querySLOCompliance returns the fraction of good events of an SLO over a
+// period, from the recording rules of the Grafana SLO app.
+func querySLOCompliance(ctx context.Context, s slo, start, end time.Time) (float64, error) {
+	if s.DestinationDatasource.UID == "" {
+		return 0, fmt.Errorf("the SLO has no destination datasource")
+	}
+	promClient, err := promClientFromContext(ctx, s.DestinationDatasource.UID)
+	if err != nil {
+		return 0, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	selector := fmt.Sprintf("{grafana_slo_uuid=%s}", strconv.Quote(s.UUID))
+	window := promRange(end.Sub(start))
+	expr := fmt.Sprintf("sum(sum_over_time(grafana_slo_success_rate_5m%[1]s[%[2]s])) / sum(sum_over_time(grafana_slo_total_rate_5m%[1]s[%[2]s]))", selector, window)
+	result, _, err := promClient.Query(ctx, expr, end)
+	if err != nil {
+		return 0, fmt.Errorf("querying Prometheus instant: %w", err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok || len(vector) == 0 || math.IsNaN(float64(vector[0].Value)) {
+		return 0, fmt.Errorf("no SLO data in the period")
+	}
+	return float64(vector[0].Value), nil
+}
+
+// reportSLOs returns the compliance of the team's SLOs.
+func reportSLOs(ctx context.Context, args GetReliabilityReportParams, start, end time.Time) ([]sloReport, error) {
+	slos, err := listSLOs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing SLOs: %w", err)
+	}
+	reports := []sloReport{}
+	for _, s := range slos {
+		if !hasSLOLabel(s, args.TeamLabel, args.Team) {
+			continue
+		}
+		r := sloReport{Name: s.Name, UUID: s.UUID}
+		if len(s.Objectives) > 0 {
+			r.Objective = s.Objectives[0].Value
+		}
+		compliance, err := querySLOCompliance(ctx, s, start, end)
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Compliance = &compliance
+			if r.Objective > 0 && r.Objective < 1 {
+				spent := (1 - compliance) / (1 - r.Objective)
+				r.ErrorBudgetSpent = &spent
+			}
+		}
+		reports = append(reports, r)
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Name < reports[j].Name })
+	return reports, nil
+}
+
+func hasSLOLabel(s slo, key, value string) bool {
+	for _, l := range s.Labels {
+		if l.Key == key && l.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// reportNoisiestAlerts counts the firings of the team's alert rules.
+func reportNoisiestAlerts(ctx context.Context, args GetReliabilityReportParams, start, end time.Time) ([]alertNoise, error) {
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating alerting client: %w", err)
+	}
+	rules, err := client.GetRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	firings := map[string]int{}
+	for _, group := range rules.Data.RuleGroups {
+		for _, rule := range group.Rules {
+			if rule.Labels.Get(args.TeamLabel) == args.Team {
+				firings[rule.Name] = 0
+			}
+		}
+	}
+	if len(firings) == 0 {
+		return []alertNoise{}, nil
+	}
+
+	alerts, err := getTimelineAnnotations(ctx, "alert", nil, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alerts {
+		if _, ok := firings[a.AlertName]; ok && strings.HasPrefix(a.NewState, "Alerting") {
+			firings[a.AlertName]++
+		}
+	}
+	noise := []alertNoise{}
+	for name, n := range firings {
+		if n > 0 {
+			noise = append(noise, alertNoise{Name: name, Firings: n})
+		}
+	}
+	sort.Slice(noise, func(i, j int) bool {
+		if noise[i].Firings != noise[j].Firings {
+			return noise[i].Firings > noise[j].Firings
+		}
+		return noise[i].Name < noise[j].Name
+	})
+	if len(noise) > maxReportItems {
+		noise = noise[:maxReportItems]
+	}
+	return noise, nil
+}
+
+// reportSlowestEndpoints returns the server endpoints of the team's services
+// with the highest 95th percentile latency over the period, from span metrics.
+func reportSlowestEndpoints(ctx context.Context, args GetReliabilityReportParams, start, end time.Time) ([]endpointLatency, error) {
+	promClient, err := promClientFromContext(ctx, args.PrometheusUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	expr := fmt.Sprintf(
+		`topk(%d, histogram_quantile(0.95, sum by (service, span_name, le) (rate(traces_spanmetrics_latency_bucket{%s, span_kind="SPAN_KIND_SERVER"}[%s]))))`,
+		maxReportItems, servicesMatcher(args.Services), promRange(end.Sub(start)),
+	)
+	result, _, err := promClient.Query(ctx, expr, end)
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus instant: %w", err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("expected an instant vector from %q, got %s", expr, result.Type())
+	}
+	endpoints := []endpointLatency{}
+	for _, sample := range vector {
+		if math.IsNaN(float64(sample.Value)) {
+			continue
+		}
+		endpoints = append(endpoints, endpointLatency{
+			Service:  string(sample.Metric["service"]),
+			Endpoint: string(sample.Metric["span_name"]),
+			P95Ms:    float64(sample.Value) * 1000,
+		})
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool { return endpoints[i].P95Ms > endpoints[j].P95Ms })
+	return endpoints, nil
+}
+
+// reportAnomalies returns the change points of the hourly error rates of the
+// team's services, from span metrics.
+func reportAnomalies(ctx context.Context, args GetReliabilityReportParams, start, end time.Time) ([]timelineEvent, error) {
+	expr := fmt.Sprintf(
+		`sum by (service) (rate(traces_spanmetrics_calls_total{%s, span_kind="SPAN_KIND_SERVER", status_code="STATUS_CODE_ERROR"}[1h]))`,
+		servicesMatcher(args.Services),
+	)
+	events, err := queryChangePoints(ctx, args.PrometheusUID, expr, time.Hour, start, end, func(m model.Metric) string {
+		return fmt.Sprintf("Error rate of %s", m["service"])
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if events == nil {
+		return []timelineEvent{}, nil
+	}
+	return events, nil
+}
+
+// formatOptionalPercent formats an optional fraction as a percentage.
+func formatOptionalPercent(f *float64, precision int) string {
+	if f == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*f*100, 'f', precision, 64) + "%"
+}
+
+// markdown formats the report for posting to chat.
+func (r *reliabilityReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Reliability report: %s\n\n", r.Team)
+	fmt.Fprintf(&b, "_%s to %s_\n\n", r.Start.UTC().Format("2006-01-02 15:04"), r.End.UTC().Format("2006-01-02 15:04 MST"))
+
+	b.WriteString("## SLOs\n\n")
+	if len(r.SLOs) == 0 {
+		b.WriteString("No SLOs found.\n\n")
+	} else {
+		b.WriteString("| SLO | Objective | Compliance | Error budget spent |\n|---|---|---|---|\n")
+		for _, s := range r.SLOs {
+			objective := s.Objective
+			status := ""
+			if s.Compliance != nil && *s.Compliance < s.Objective {
+				status = " (missed)"
+			}
+			fmt.Fprintf(&b, "| %s%s | %s | %s | %s |\n", s.Name, status, formatOptionalPercent(&objective, 2), formatOptionalPercent(s.Compliance, 3), formatOptionalPercent(s.ErrorBudgetSpent, 0))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Noisiest alerts\n\n")
+	if len(r.NoisiestAlerts) == 0 {
+		b.WriteString("No alerts fired.\n\n")
+	} else {
+		b.WriteString("| Alert | Firings |\n|---|---|\n")
+		for _, a := range r.NoisiestAlerts {
+			fmt.Fprintf(&b, "| %s | %d |\n", a.Name, a.Firings)
+		}
+		b.WriteString("\n")
+	}
+
+	if r.SlowestEndpoints != nil {
+		b.WriteString("## Slowest endpoints\n\n")
+		if len(r.SlowestEndpoints) == 0 {
+			b.WriteString("No endpoint latencies found.\n\n")
+		} else {
+			b.WriteString("| Service | Endpoint | p95 |\n|---|---|---|\n")
+			for _, e := range r.SlowestEndpoints {
+				fmt.Fprintf(&b, "| %s | %s | %sms |\n", e.Service, e.Endpoint, strconv.FormatFloat(e.P95Ms, 'f', 0, 64))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if r.Anomalies != nil {
+		b.WriteString("## Notable anomalies\n\n")
+		if len(r.Anomalies) == 0 {
+			b.WriteString("No anomalies found.\n\n")
+		} else {
+			for _, e := range r.Anomalies {
+				fmt.Fprintf(&b, "- %s: %s\n", e.Time.UTC().Format("Mon 2006-01-02 15:04"), e.Title)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		b.WriteString("## Missing data\n\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(&b, "- %s\n", e)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func getReliabilityReport(ctx context.Context, args GetReliabilityReportParams) (*reliabilityReport, error) {
+	if args.Team == "" {
+		return nil, fmt.Errorf("team is required")
+	}
+	args.TeamLabel = stringOrDefault(args.TeamLabel, "team")
+	start, end, err := timelineTimeRange(args.StartTime, args.EndTime, defaultReportRange)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &reliabilityReport{Team: args.Team, Start: start, End: end, SLOs: []sloReport{}, NoisiestAlerts: []alertNoise{}}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = map[string]error{}
+	)
+	section := func(name string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	section("SLOs", func() (err error) {
+		report.SLOs, err = reportSLOs(ctx, args, start, end)
+		return err
+	})
+	section("noisiest alerts", func() (err error) {
+		report.NoisiestAlerts, err = reportNoisiestAlerts(ctx, args, start, end)
+		return err
+	})
+	if args.PrometheusUID != "" && len(args.Services) > 0 {
+		section("slowest endpoints", func() (err error) {
+			report.SlowestEndpoints, err = reportSlowestEndpoints(ctx, args, start, end)
+			return err
+		})
+		section("anomalies", func() (err error) {
+			report.Anomalies, err = reportAnomalies(ctx, args, start, end)
+			return err
+		})
+	}
+	wg.Wait()
+
+	for _, name := range sortedKeys(errs) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", name, errs[name]))
+	}
+	report.Markdown = report.markdown()
+	return report, nil
+}
+
+var GetReliabilityReport = mcpgrafana.MustTool(
+	"get_reliability_report",
+	"Compiles a reliability report for a team over a period, by default the last 7 days: the compliance and error budget spent of the team's SLOs in the Grafana SLO app, the team's alert rules which fired most often, and, given the team's services and a Prometheus datasource with span metrics, the slowest endpoints and sudden changes in error rates. SLOs and alert rules belong to the team if their team label matches. Returns the data as structured fields and as Markdown ready to post to chat; sections which could not be compiled are listed under errors.",
+	guardTimeRange(getReliabilityReport),
+	mcp.WithTitleAnnotation("Get reliability report"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
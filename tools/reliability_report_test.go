@@ -0,0 +1,131 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReliabilityReport(t *testing.T) {
+	end := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-7 * 24 * time.Hour)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/plugins/grafana-slo-app/resources/v1/slo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"slos":[
+			{"uuid":"a","name":"Checkout availability","objectives":[{"value":0.999,"window":"28d"}],"destinationDatasource":{"uid":"prom"},"labels":[{"key":"team","value":"shop"}]},
+			{"uuid":"b","name":"Search latency","objectives":[{"value":0.99,"window":"28d"}],"destinationDatasource":{"uid":"prom"},"labels":[{"key":"team","value":"search"}]}
+		]}`))
+	})
+	mux.HandleFunc("/api/datasources/uid/prom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"uid":"prom","type":"prometheus"}`))
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		query := r.Form.Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(query, "grafana_slo_success_rate_5m"):
+			assert.Contains(t, query, `[1w]`)
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1704672000,"0.998"]}]}}`))
+		case strings.Contains(query, "histogram_quantile"):
+			assert.Contains(t, query, `service=~"checkout|cart"`)
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"service":"cart","span_name":"GET /cart"},"value":[1704672000,"0.12"]},
+				{"metric":{"service":"checkout","span_name":"POST /pay"},"value":[1704672000,"1.5"]}
+			]}}`))
+		default:
+			t.Errorf("unexpected query %q", query)
+		}
+	})
+	mux.HandleFunc("/api/datasources/proxy/uid/prom/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "3600", r.Form.Get("step"))
+		values := make([]string, 0, 169)
+		for i := 0; i <= 168; i++ {
+			v := "0"
+			if i >= 100 {
+				v = "2"
+			}
+			values = append(values, fmt.Sprintf(`[%d,"%s"]`, start.Unix()+int64(i)*3600, v))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"service":"checkout"},"values":[` + strings.Join(values, ",") + `]}]}}`))
+	})
+	mux.HandleFunc("/api/prometheus/grafana/api/v1/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"groups":[{"name":"g","rules":[
+			{"name":"CheckoutErrors","labels":{"team":"shop"}},
+			{"name":"CartLatency","labels":{"team":"shop"}},
+			{"name":"SearchDown","labels":{"team":"search"}}
+		]}]}}`))
+	})
+	mux.HandleFunc("/api/annotations", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"alertName":"CheckoutErrors","newState":"Alerting","time":1704200000000},
+			{"alertName":"CheckoutErrors","newState":"Normal","time":1704200100000},
+			{"alertName":"CheckoutErrors","newState":"Alerting","time":1704300000000},
+			{"alertName":"CartLatency","newState":"Alerting","time":1704300000000},
+			{"alertName":"SearchDown","newState":"Alerting","time":1704300000000}
+		]`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+	ctx = mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, ""))
+
+	report, err := getReliabilityReport(ctx, GetReliabilityReportParams{
+		Team:          "shop",
+		Services:      []string{"checkout", "cart"},
+		PrometheusUID: "prom",
+		EndTime:       end.Format(time.RFC3339),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, start, report.Start.UTC())
+
+	require.Len(t, report.SLOs, 1)
+	assert.Equal(t, "Checkout availability", report.SLOs[0].Name)
+	require.NotNil(t, report.SLOs[0].Compliance)
+	assert.Equal(t, 0.998, *report.SLOs[0].Compliance)
+	require.NotNil(t, report.SLOs[0].ErrorBudgetSpent)
+	assert.InDelta(t, 2.0, *report.SLOs[0].ErrorBudgetSpent, 1e-9)
+
+	assert.Equal(t, []alertNoise{{Name: "CheckoutErrors", Firings: 2}, {Name: "CartLatency", Firings: 1}}, report.NoisiestAlerts)
+
+	require.Len(t, report.SlowestEndpoints, 2)
+	assert.Equal(t, endpointLatency{Service: "checkout", Endpoint: "POST /pay", P95Ms: 1500}, report.SlowestEndpoints[0])
+
+	require.Len(t, report.Anomalies, 1)
+	assert.Equal(t, "Error rate of checkout changed from 0 to 2", report.Anomalies[0].Title)
+	assert.Equal(t, start.Add(100*time.Hour), report.Anomalies[0].Time.UTC())
+
+	assert.Contains(t, report.Markdown, "# Reliability report: shop")
+	assert.Contains(t, report.Markdown, "| Checkout availability (missed) | 99.90% | 99.800% | 200% |")
+	assert.Contains(t, report.Markdown, "| CheckoutErrors | 2 |")
+	assert.Contains(t, report.Markdown, "| checkout | POST /pay | 1500ms |")
+	assert.Contains(t, report.Markdown, "- Fri 2024-01-05 04:00: Error rate of checkout changed from 0 to 2")
+
+	t.Run("without span metrics", func(t *testing.T) {
+		report, err := getReliabilityReport(ctx, GetReliabilityReportParams{Team: "search", EndTime: end.Format(time.RFC3339)})
+		require.NoError(t, err)
+		assert.Nil(t, report.SlowestEndpoints)
+		assert.Nil(t, report.Anomalies)
+		assert.NotContains(t, report.Markdown, "Slowest endpoints")
+		assert.Equal(t, []alertNoise{{Name: "SearchDown", Firings: 1}}, report.NoisiestAlerts)
+	})
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	DefaultRenderWidth  = 1000
+	DefaultRenderHeight = 500
+	MaxRenderSize       = 3000
+
+	// renderPanelTimeout is how long the image renderer may take to render a
+	// panel. Panels with slow queries may need most of it.
+	renderPanelTimeout = 60 * time.Second
+	// maxRenderedImageBytes limits the size of a rendered image.
+	maxRenderedImageBytes = 10 << 20
+)
+
+type RenderPanelImageParams struct {
+	DashboardUID string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelID      int               `json:"panelId" jsonschema:"required,description=The ID of the panel to render"`
+	StartTime    string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range\\, in RFC3339\\, Unix time or relative time such as 'now-6h' (default: now-1h)"`
+	EndTime      string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range\\, in RFC3339\\, Unix time or relative time such as 'now' (default: now)"`
+	Variables    map[string]string `json:"variables,omitempty" jsonschema:"description=Optionally\\, values of the dashboard's template variables by name\\, e.g. {'job': 'api'}"`
+	Width        int               `json:"width,omitempty" jsonschema:"description=Optionally\\, the width of the image in pixels (default: 1000\\, max: 3000)"`
+	Height       int               `json:"height,omitempty" jsonschema:"description=Optionally\\, the height of the image in pixels (default: 500\\, max: 3000)"`
+	Theme        string            `json:"theme,omitempty" jsonschema:"enum=light,enum=dark,description=Optionally\\, the theme of the image. Defaults to the Grafana default theme"`
+}
+
+// renderPanelQuery returns the query parameters of a panel render request.
+// Times are passed as Unix milliseconds, so relative and natural language
+// times are resolved the same way as by the query tools.
+func renderPanelQuery(args RenderPanelImageParams) (url.Values, error) {
+	start, end := time.Now().Add(-time.Hour), time.Now()
+	var err error
+	if args.StartTime != "" {
+		if start, err = parseTime(args.StartTime); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if args.EndTime != "" {
+		if end, err = parseTime(args.EndTime); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start time %s must be before end time %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	width, height := args.Width, args.Height
+	if width <= 0 {
+		width = DefaultRenderWidth
+	}
+	if height <= 0 {
+		height = DefaultRenderHeight
+	}
+	if width > MaxRenderSize || height > MaxRenderSize {
+		return nil, fmt.Errorf("the image can be at most %dx%d pixels", MaxRenderSize, MaxRenderSize)
+	}
+
+	query := url.Values{}
+	query.Set("panelId", strconv.Itoa(args.PanelID))
+	query.Set("from", strconv.FormatInt(start.UnixMilli(), 10))
+	query.Set("to", strconv.FormatInt(end.UnixMilli(), 10))
+	query.Set("width", strconv.Itoa(width))
+	query.Set("height", strconv.Itoa(height))
+	query.Set("timeout", strconv.Itoa(int(renderPanelTimeout.Seconds())))
+	switch args.Theme {
+	case "":
+	case "light", "dark":
+		query.Set("theme", args.Theme)
+	default:
+		return nil, fmt.Errorf("invalid theme %q: expected 'light' or 'dark'", args.Theme)
+	}
+	for name, value := range args.Variables {
+		query.Set("var-"+strings.TrimPrefix(name, "var-"), value)
+	}
+	return query, nil
+}
+
+func renderPanelImage(ctx context.Context, args RenderPanelImageParams) (*mcp.CallToolResult, error) {
+	dashboardUID, err := sanitizePathSegment("dashboardUid", args.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+	if args.PanelID <= 0 {
+		return nil, fmt.Errorf("panelId must be a positive panel ID")
+	}
+	query, err := renderPanelQuery(args)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating Grafana client: %w", err)
+	}
+	// Leave the renderer time to time out itself, so its error is returned.
+	client.httpClient.Timeout = renderPanelTimeout + 10*time.Second
+	// The dashboard slug is required in the path, but any slug will do.
+	resp, err := client.makeRequest(ctx, "/render/d-solo/"+dashboardUID+"/_?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("render panel %d of dashboard %s (is the Grafana image renderer installed?): %w", args.PanelID, args.DashboardUID, err)
+	}
+	defer resp.Body.Close()
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRenderedImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read rendered image: %w", err)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("the renderer returned %s rather than an image: %s", stringOrDefault(mimeType, "no content type"), truncate(string(body), 512))
+	}
+	if len(body) > maxRenderedImageBytes {
+		return nil, fmt.Errorf("the rendered image is larger than %d bytes; render a smaller image", maxRenderedImageBytes)
+	}
+
+	from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+	to, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf("Panel %d of dashboard %s from %s to %s.", args.PanelID, args.DashboardUID,
+				time.UnixMilli(from).UTC().Format(time.RFC3339), time.UnixMilli(to).UTC().Format(time.RFC3339))),
+			mcp.NewImageContent(base64.StdEncoding.EncodeToString(body), mimeType),
+		},
+	}, nil
+}
+
+var RenderPanelImage = mcpgrafana.MustTool(
+	"render_panel_image",
+	"Render a dashboard panel as an image using the Grafana image renderer, for a time range and template variable values, so that the graph can be looked at. Use get_dashboard_by_uid or get_dashboard_panel_queries to find the panel IDs of a dashboard. Requires the Grafana image renderer plugin or service.",
+	renderPanelImage,
+	mcp.WithTitleAnnotation("Render panel image"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,91 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPanelImage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake image")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/render/d-solo/svc/_", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "4", q.Get("panelId"))
+		assert.Equal(t, "1704067200000", q.Get("from"))
+		assert.Equal(t, "1704070800000", q.Get("to"))
+		assert.Equal(t, "800", q.Get("width"))
+		assert.Equal(t, "500", q.Get("height"))
+		assert.Equal(t, "dark", q.Get("theme"))
+		assert.Equal(t, "api", q.Get("var-job"))
+		assert.Equal(t, "prod", q.Get("var-cluster"))
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(png)
+	})
+	mux.HandleFunc("/render/d-solo/broken/_", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html>login</html>"))
+	})
+	mux.HandleFunc("/render/d-solo/norenderer/_", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "No image renderer available/installed", http.StatusInternalServerError)
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := renderPanelImage(ctx, RenderPanelImageParams{
+		DashboardUID: "svc",
+		PanelID:      4,
+		StartTime:    "2024-01-01T00:00:00Z",
+		EndTime:      "2024-01-01T01:00:00Z",
+		Variables:    map[string]string{"job": "api", "var-cluster": "prod"},
+		Width:        800,
+		Theme:        "dark",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "Panel 4 of dashboard svc from 2024-01-01T00:00:00Z to 2024-01-01T01:00:00Z.", result.Content[0].(mcp.TextContent).Text)
+	image, ok := result.Content[1].(mcp.ImageContent)
+	require.True(t, ok)
+	assert.Equal(t, "image/png", image.MIMEType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(png), image.Data)
+
+	_, err = renderPanelImage(ctx, RenderPanelImageParams{DashboardUID: "broken", PanelID: 1})
+	assert.ErrorContains(t, err, "text/html rather than an image")
+	_, err = renderPanelImage(ctx, RenderPanelImageParams{DashboardUID: "norenderer", PanelID: 1})
+	assert.ErrorContains(t, err, "No image renderer available")
+
+	for _, args := range []RenderPanelImageParams{
+		{DashboardUID: "svc"},
+		{DashboardUID: "svc", PanelID: 1, Width: MaxRenderSize + 1},
+		{DashboardUID: "svc", PanelID: 1, Theme: "blue"},
+		{DashboardUID: "svc", PanelID: 1, StartTime: "now", EndTime: "now-1h"},
+		{DashboardUID: "../api", PanelID: 1},
+		{DashboardUID: "..", PanelID: 1},
+	} {
+		_, err := renderPanelImage(ctx, args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestRenderPanelQueryDefaults(t *testing.T) {
+	query, err := renderPanelQuery(RenderPanelImageParams{PanelID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "1000", query.Get("width"))
+	assert.Equal(t, "500", query.Get("height"))
+	assert.Empty(t, query.Get("theme"))
+	assert.Equal(t, "60", query.Get("timeout"))
+	from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	require.NoError(t, err)
+	to, err := strconv.ParseInt(query.Get("to"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Milliseconds(), to-from, 1000)
+}
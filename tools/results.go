@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"encoding/json"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// resultValue holds the value of a tool result which takes one of several
+// shapes. Types embedding it implement mcpgrafana.UnionResult by listing the
+// types the value may have in a ResultTypes method, and marshal to the value.
+type resultValue struct {
+	value any
+}
+
+func (r resultValue) ResultValue() any {
+	return r.value
+}
+
+func (r resultValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.value)
+}
+
+// asResult wraps a tool result in the union type U, passing its error
+// through, so that a call returning a result and an error can be returned
+// directly as one of U's shapes.
+func asResult[U ~struct{ resultValue }](value any, err error) (U, error) {
+	return U{resultValue{value}}, err
+}
+
+// unwrapResult returns the value of a tool result, following any results
+// which take one of several shapes.
+func unwrapResult(result any) any {
+	for {
+		union, ok := result.(mcpgrafana.UnionResult)
+		if !ok {
+			return result
+		}
+		result = union.ResultValue()
+	}
+}
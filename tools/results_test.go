@@ -0,0 +1,75 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestResultValue(t *testing.T) {
+	page := paginated[LogEntry]{resultValue{&resultPage[LogEntry]{Items: []LogEntry{{Line: "a"}}, NextPageToken: "next"}}}
+	inspected := inspectable[paginated[LogEntry]]{resultValue{inspectedResult[paginated[LogEntry]]{
+		Result:  page,
+		Inspect: &queryInspection{Requests: []inspectedRequest{}},
+	}}}
+	clamped := guarded[inspectable[paginated[LogEntry]]]{resultValue{clampedResult[inspectable[paginated[LogEntry]]]{
+		Result:           inspected,
+		TimeRangeClamped: &timeRangeClamp{Reason: "limited"},
+	}}}
+
+	assert.Equal(t, page.ResultValue(), unwrapResult(inspected).(inspectedResult[paginated[LogEntry]]).Result.ResultValue())
+	b, err := json.Marshal(clamped)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"result": {
+			"result": {"items": [{"timestamp": "", "line": "a", "labels": null}], "nextPageToken": "next"},
+			"inspect": {"requests": []}
+		},
+		"timeRangeClamped": {"requestedStart": "0001-01-01T00:00:00Z", "requestedEnd": "0001-01-01T00:00:00Z", "start": "0001-01-01T00:00:00Z", "end": "0001-01-01T00:00:00Z", "reason": "limited"}
+	}`, string(b))
+
+	t.Run("zero results are omitted", func(t *testing.T) {
+		b, err := json.Marshal(prometheusBatchResult{Error: "parse error"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error": "parse error"}`, string(b))
+
+		b, err = json.Marshal(prometheusBatchResult{Result: decodePrometheusHistograms(model.Vector{})})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"result": []}`, string(b))
+	})
+}
+
+func TestUnionResultOutputSchemas(t *testing.T) {
+	for _, tool := range []mcpgrafana.Tool{
+		QueryLokiLogs,
+		QueryLokiMetrics,
+		QueryLokiInstant,
+		QueryPrometheus,
+		QueryPrometheusInstant,
+		SearchTempoTraces,
+		ListAlertRules,
+		AggregateTempoTracesFlamegraph,
+	} {
+		t.Run(tool.Tool.Name, func(t *testing.T) {
+			var schema struct {
+				Type  string           `json:"type"`
+				AnyOf []map[string]any `json:"anyOf"`
+			}
+			require.NoError(t, json.Unmarshal(tool.Tool.RawOutputSchema, &schema))
+			assert.Equal(t, "object", schema.Type)
+			require.NotEmpty(t, schema.AnyOf, "the schema should list the shapes of the result")
+			for _, alternative := range schema.AnyOf {
+				assert.Equal(t, "object", alternative["type"])
+				assert.NotEmpty(t, alternative["properties"])
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// validatePathSegment checks that a value given to a tool, such as a UID,
+// trace ID or label name, can be used as a single segment of a URL path.
+// Values which could change the route of the request, such as ".." or values
+// containing slashes or percent-encoded sequences, are rejected rather than
+// escaped, since Grafana and datasource proxies may decode or clean the path
+// before routing it.
+func validatePathSegment(name, value string) error {
+	switch {
+	case value == "":
+		return fmt.Errorf("%s is required", name)
+	case value == "." || value == "..":
+		return fmt.Errorf("invalid %s %q", name, value)
+	case strings.ContainsAny(value, `/\?#%`):
+		return fmt.Errorf("invalid %s %q: must not contain '/', '\\', '?', '#' or '%%'", name, value)
+	case strings.IndexFunc(value, unicode.IsControl) >= 0:
+		return fmt.Errorf("invalid %s %q: must not contain control characters", name, value)
+	}
+	return nil
+}
+
+// sanitizePathSegment validates a value given to a tool for use as a single
+// segment of a URL path and returns it escaped.
+func sanitizePathSegment(name, value string) (string, error) {
+	if err := validatePathSegment(name, value); err != nil {
+		return "", err
+	}
+	return url.PathEscape(value), nil
+}
@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizePathSegment(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		escaped string
+		valid   bool
+	}{
+		{value: "abc-123_XYZ", escaped: "abc-123_XYZ", valid: true},
+		{value: "resource.service.name", escaped: "resource.service.name", valid: true},
+		{value: "my datasource", escaped: "my%20datasource", valid: true},
+		{value: "%2e%2e"},
+		{value: "..%2Fadmin"},
+		{value: ""},
+		{value: "."},
+		{value: ".."},
+		{value: "../admin/users"},
+		{value: `..\admin`},
+		{value: "abc?x=1"},
+		{value: "abc#x"},
+		{value: "abc\n"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			escaped, err := sanitizePathSegment("uid", tc.value)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.escaped, escaped)
+		})
+	}
+}
+
+func TestPathTraversalRejected(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "loki", "loki", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+
+	_, err := listLokiLabelValues(ctx, ListLokiLabelValuesParams{DatasourceUID: "loki", LabelName: "../../../api/admin/users"})
+	assert.ErrorContains(t, err, "invalid label name")
+
+	_, err = getDatasourceByUID(context.Background(), GetDatasourceByUIDParams{UID: ".."})
+	assert.ErrorContains(t, err, "invalid uid")
+}
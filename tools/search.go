@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -15,7 +16,8 @@ import (
 var dashboardTypeStr = "dash-db"
 
 type SearchDashboardsParams struct {
-	Query string `json:"query" jsonschema:"description=The query to search for"`
+	Query string   `json:"query" jsonschema:"description=The query to search for"`
+	Tags  []string `json:"tags,omitempty" jsonschema:"description=Optionally\\, tags the dashboards must all have\\, e.g. ['team-payments']. Use list_dashboard_tags to find the tags in use"`
 }
 
 func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.HitList, error) {
@@ -25,6 +27,10 @@ func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.
 		params.SetQuery(&args.Query)
 		params.SetType(&dashboardTypeStr)
 	}
+	if len(args.Tags) > 0 {
+		params.SetTag(args.Tags)
+		params.SetType(&dashboardTypeStr)
+	}
 	search, err := c.Search.Search(params)
 	if err != nil {
 		return nil, fmt.Errorf("search dashboards for %+v: %w", c, err)
@@ -34,13 +40,49 @@ func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.
 
 var SearchDashboards = mcpgrafana.MustTool(
 	"search_dashboards",
-	"Search for Grafana dashboards by a query string. Returns a list of matching dashboards with details like title, UID, folder, tags, and URL.",
+	"Search for Grafana dashboards by a query string, tags, or both. With several tags, only dashboards having all of them are returned. Returns a list of matching dashboards with details like title, UID, folder, tags, and URL.",
 	searchDashboards,
 	mcp.WithTitleAnnotation("Search dashboards"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+type ListDashboardTagsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Optionally\\, only list tags containing this text\\, ignoring case"`
+}
+
+type dashboardTag struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+func listDashboardTags(ctx context.Context, args ListDashboardTagsParams) ([]dashboardTag, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Dashboards.GetDashboardTags()
+	if err != nil {
+		return nil, fmt.Errorf("list dashboard tags: %w", err)
+	}
+	query := strings.ToLower(args.Query)
+	tags := []dashboardTag{}
+	for _, item := range resp.Payload {
+		if strings.Contains(strings.ToLower(item.Term), query) {
+			tags = append(tags, dashboardTag{Tag: item.Term, Count: item.Count})
+		}
+	}
+	return tags, nil
+}
+
+var ListDashboardTags = mcpgrafana.MustTool(
+	"list_dashboard_tags",
+	"List the tags used by dashboards, with the number of dashboards having each, to navigate large Grafana instances by team, service or other taxonomy. Pass tags to search_dashboards to find the dashboards having them.",
+	listDashboardTags,
+	mcp.WithTitleAnnotation("List dashboard tags"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 func AddSearchTools(mcp *server.MCPServer) {
 	SearchDashboards.Register(mcp)
+	ListDashboardTags.Register(mcp)
+	FindAnything.Register(mcp)
 }
@@ -0,0 +1,45 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDashboardsByTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"team-payments", "prod"}, r.URL.Query()["tag"])
+		assert.Equal(t, "dash-db", r.URL.Query().Get("type"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid": "pay", "title": "Payments", "type": "dash-db", "tags": ["prod", "team-payments"]}]`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	result, err := searchDashboards(ctx, SearchDashboardsParams{Tags: []string{"team-payments", "prod"}})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "pay", result[0].UID)
+}
+
+func TestListDashboardTags(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"term": "prod", "count": 12}, {"term": "team-payments", "count": 3}, {"term": "Team-Search", "count": 1}]`))
+	})
+	ctx := newMockGrafanaVersionContext(t, "11.0.0", mux)
+
+	tags, err := listDashboardTags(ctx, ListDashboardTagsParams{})
+	require.NoError(t, err)
+	assert.Len(t, tags, 3)
+
+	tags, err = listDashboardTags(ctx, ListDashboardTagsParams{Query: "team"})
+	require.NoError(t, err)
+	assert.Equal(t, []dashboardTag{{Tag: "team-payments", Count: 3}, {Tag: "Team-Search", Count: 1}}, tags)
+}
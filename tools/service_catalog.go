@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// ServiceCatalogConfig configures where lookup_service finds services. At
+// most one of File and BackstageURL should be set.
+type ServiceCatalogConfig struct {
+	// File is the path to a YAML file listing services.
+	File string
+	// BackstageURL is the base URL of a Backstage instance whose catalog
+	// components are used as services.
+	BackstageURL string
+	// BackstageToken is an optional bearer token for the Backstage API.
+	BackstageToken string
+}
+
+// Enabled reports whether a service catalog is configured.
+func (c ServiceCatalogConfig) Enabled() bool {
+	return c.File != "" || c.BackstageURL != ""
+}
+
+const DefaultLookupServiceLimit = 5
+
+// catalogService is a service in the catalog, mapping its name to its owners
+// and to the Grafana resources and selectors used to observe it.
+type catalogService struct {
+	Name        string   `json:"name" yaml:"name"`
+	Aliases     []string `json:"aliases,omitempty" yaml:"aliases"`
+	Description string   `json:"description,omitempty" yaml:"description"`
+	Team        string   `json:"team,omitempty" yaml:"team"`
+	Repo        string   `json:"repo,omitempty" yaml:"repo"`
+	// Dashboards are the UIDs of the service's dashboards.
+	Dashboards []string `json:"dashboards,omitempty" yaml:"dashboards"`
+	// DashboardSelector selects the service's dashboards by tag, as in the
+	// Backstage Grafana plugin's `grafana/dashboard-selector` annotation.
+	DashboardSelector string `json:"dashboardSelector,omitempty" yaml:"dashboardSelector"`
+	// Labels are the labels identifying the service's metrics, logs and
+	// alerts, e.g. {service_name: checkout}.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels"`
+}
+
+// serviceCatalog loads the services in a catalog.
+type serviceCatalog interface {
+	services(ctx context.Context) ([]catalogService, error)
+}
+
+// fileServiceCatalog is a catalog defined in a YAML file of the form
+// `services: [{name: checkout, team: payments, ...}]`. The file is re-read
+// on each lookup so it can be edited without restarting the server.
+type fileServiceCatalog struct {
+	path string
+}
+
+func (c fileServiceCatalog) services(ctx context.Context) ([]catalogService, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("read service catalog: %w", err)
+	}
+	var file struct {
+		Services []catalogService `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("parse service catalog %s: %w", c.path, err)
+	}
+	return file.Services, nil
+}
+
+// backstageServiceCatalog uses the components in a Backstage software
+// catalog as services.
+type backstageServiceCatalog struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// backstageEntity is the subset of a Backstage catalog entity used here.
+type backstageEntity struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Title       string            `json:"title"`
+		Description string            `json:"description"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Owner string `json:"owner"`
+	} `json:"spec"`
+}
+
+var backstageCatalogCache = newTTLCache[[]catalogService]()
+
+func (c backstageServiceCatalog) services(ctx context.Context) ([]catalogService, error) {
+	return backstageCatalogCache.getOrLoad(ctx, c.baseURL, func() ([]catalogService, error) {
+		u, err := url.Parse(strings.TrimRight(c.baseURL, "/") + "/api/catalog/entities")
+		if err != nil {
+			return nil, fmt.Errorf("invalid Backstage URL: %w", err)
+		}
+		u.RawQuery = url.Values{"filter": {"kind=component"}}.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("create Backstage request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("query Backstage catalog: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return nil, fmt.Errorf("Backstage API returned status code %d: %s", resp.StatusCode, string(body))
+		}
+		var entities []backstageEntity
+		if err := json.NewDecoder(resp.Body).Decode(&entities); err != nil {
+			return nil, fmt.Errorf("decode Backstage entities: %w", err)
+		}
+		services := make([]catalogService, 0, len(entities))
+		for _, e := range entities {
+			services = append(services, backstageService(e))
+		}
+		return services, nil
+	})
+}
+
+// backstageService converts a Backstage component, reading the annotations
+// used by the Backstage Grafana plugin.
+func backstageService(e backstageEntity) catalogService {
+	s := catalogService{
+		Name:              e.Metadata.Name,
+		Description:       e.Metadata.Description,
+		Team:              strings.TrimPrefix(e.Spec.Owner, "group:"),
+		DashboardSelector: e.Metadata.Annotations["grafana/dashboard-selector"],
+	}
+	if e.Metadata.Title != "" && e.Metadata.Title != e.Metadata.Name {
+		s.Aliases = []string{e.Metadata.Title}
+	}
+	if slug := e.Metadata.Annotations["github.com/project-slug"]; slug != "" {
+		s.Repo = "https://github.com/" + slug
+	}
+	if selector := e.Metadata.Annotations["grafana/alert-label-selector"]; selector != "" {
+		s.Labels = map[string]string{}
+		for _, pair := range strings.Split(selector, ",") {
+			if k, v, ok := strings.Cut(pair, "="); ok {
+				s.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+	return s
+}
+
+type LookupServiceParams struct {
+	Query string `json:"query" jsonschema:"required,description=The name or description of the service\\, e.g. 'checkout'"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of matching services to return (default: 5)"`
+}
+
+// serviceMatch is a service matching a lookup.
+type serviceMatch struct {
+	catalogService
+	Score int `json:"score"`
+}
+
+// serviceMatchScore scores a service by its best matching name or alias,
+// falling back to a weaker match on its description.
+func serviceMatchScore(s catalogService, query string) int {
+	score := matchScore(s.Name, query)
+	for _, alias := range s.Aliases {
+		score = max(score, matchScore(alias, query))
+	}
+	if score == 0 {
+		score = matchScore(s.Description, query) / 2
+	}
+	return score
+}
+
+func lookupService(ctx context.Context, catalog serviceCatalog, args LookupServiceParams) ([]serviceMatch, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLookupServiceLimit
+	}
+	services, err := catalog.services(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []serviceMatch{}
+	for _, s := range services {
+		if score := serviceMatchScore(s, args.Query); score > 0 {
+			matches = append(matches, serviceMatch{catalogService: s, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func newServiceCatalog(cfg ServiceCatalogConfig) serviceCatalog {
+	if cfg.File != "" {
+		return fileServiceCatalog{path: cfg.File}
+	}
+	return backstageServiceCatalog{
+		baseURL:    cfg.BackstageURL,
+		token:      cfg.BackstageToken,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// AddServiceCatalogTools registers the lookup_service tool using the given
+// catalog.
+func AddServiceCatalogTools(s *server.MCPServer, cfg ServiceCatalogConfig) {
+	catalog := newServiceCatalog(cfg)
+	tool := mcpgrafana.MustTool(
+		"lookup_service",
+		"Looks up a service by name, alias or description in the service catalog, returning the best matches with the owning team, source repository, dashboard UIDs or dashboard tag selector, and the labels identifying the service's metrics, logs and alerts. Use this to turn a vague reference such as 'the checkout thing' into concrete label selectors and owners before querying.",
+		func(ctx context.Context, args LookupServiceParams) ([]serviceMatch, error) {
+			return lookupService(ctx, catalog, args)
+		},
+		mcp.WithTitleAnnotation("Look up service"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	tool.Register(s)
+}
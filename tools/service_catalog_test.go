@@ -0,0 +1,90 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileServiceCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+services:
+  - name: checkout
+    aliases: [checkout-api]
+    description: Takes payment for the basket
+    team: payments
+    repo: https://github.com/example/checkout
+    dashboards: [checkout-overview]
+    labels:
+      service_name: checkout
+  - name: cart
+    description: Stores the basket before checkout
+    team: shopping
+`), 0o600))
+	catalog := newServiceCatalog(ServiceCatalogConfig{File: path})
+
+	matches, err := lookupService(context.Background(), catalog, LookupServiceParams{Query: "Checkout"})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "checkout", matches[0].Name)
+	assert.Equal(t, 100, matches[0].Score)
+	assert.Equal(t, "payments", matches[0].Team)
+	assert.Equal(t, []string{"checkout-overview"}, matches[0].Dashboards)
+	assert.Equal(t, map[string]string{"service_name": "checkout"}, matches[0].Labels)
+	// The cart service only matches on its description.
+	assert.Equal(t, "cart", matches[1].Name)
+	assert.Equal(t, 30, matches[1].Score)
+
+	matches, err = lookupService(context.Background(), catalog, LookupServiceParams{Query: "search"})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	_, err = lookupService(context.Background(), newServiceCatalog(ServiceCatalogConfig{File: filepath.Join(t.TempDir(), "missing.yaml")}), LookupServiceParams{Query: "x"})
+	assert.ErrorContains(t, err, "read service catalog")
+}
+
+func TestBackstageServiceCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/catalog/entities", r.URL.Path)
+		assert.Equal(t, "kind=component", r.URL.Query().Get("filter"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{
+			"metadata": {
+				"name": "checkout-service",
+				"title": "Checkout",
+				"annotations": {
+					"github.com/project-slug": "example/checkout",
+					"grafana/dashboard-selector": "tags @> 'checkout'",
+					"grafana/alert-label-selector": "service=checkout, env=prod"
+				}
+			},
+			"spec": {"owner": "group:payments"}
+		}]`))
+	}))
+	t.Cleanup(server.Close)
+	catalog := newServiceCatalog(ServiceCatalogConfig{BackstageURL: server.URL + "/", BackstageToken: "secret"})
+
+	matches, err := lookupService(context.Background(), catalog, LookupServiceParams{Query: "checkout"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, catalogService{
+		Name:              "checkout-service",
+		Aliases:           []string{"Checkout"},
+		Team:              "payments",
+		Repo:              "https://github.com/example/checkout",
+		DashboardSelector: "tags @> 'checkout'",
+		Labels:            map[string]string{"service": "checkout", "env": "prod"},
+	}, matches[0].catalogService)
+	assert.Equal(t, 100, matches[0].Score)
+}
@@ -111,14 +111,10 @@ type siftClient struct {
 }
 
 func newSiftClient(cfg mcpgrafana.GrafanaConfig) (*siftClient, error) {
-	// Create custom transport with TLS configuration if available
-	var transport http.RoundTripper = http.DefaultTransport
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		var err error
-		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
+	// Create the transport for the TLS configuration and unix socket, if any
+	transport, err := cfg.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
 	}
 
 	client := &http.Client{
@@ -0,0 +1,442 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultSLOWindow is the compliance window of proposed and created SLOs.
+	DefaultSLOWindow = "28d"
+
+	// sloAPIPath is the path of the SLOs in the Grafana SLO app API.
+	sloAPIPath = "/api/plugins/grafana-slo-app/resources/v1/slo"
+)
+
+// availabilityTargets are the availability objectives proposed for a
+// service, strictest first.
+var availabilityTargets = []float64{0.9999, 0.999, 0.995, 0.99, 0.95, 0.9}
+
+// latencyTargets are the fractions of requests which should be faster than
+// the proposed latency thresholds.
+var latencyTargets = []float64{0.95, 0.99}
+
+// AddSLOWriteTools registers SLO tools which modify Grafana. They are only
+// enabled when the server runs with write tools enabled.
+func AddSLOWriteTools(mcp *server.MCPServer) {
+	CreateSLO.Register(mcp)
+}
+
+// ProposeTempoSLOsParams defines the parameters for proposing SLOs for a
+// service from its traces.
+type ProposeTempoSLOsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	Service       string `json:"service" jsonschema:"required,description=The name of the service\\, as in the service.name resource attribute"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-7d'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+	TraceLimit    int    `json:"traceLimit,omitempty" jsonschema:"description=Optionally\\, the maximum number of traces to analyze (default: 20\\, max: 100). More traces give more reliable proposals"`
+	Window        string `json:"window,omitempty" jsonschema:"description=Optionally\\, the compliance window of the proposed SLOs (default: 28d)"`
+}
+
+// tempoLatencyPercentiles are the observed latencies of a service's
+// requests, in milliseconds.
+type tempoLatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// sloProposal is an SLO proposed by propose_tempo_slos, in the shape taken by
+// create_slo.
+type sloProposal struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Objective is the proposed fraction of good requests, e.g. 0.995.
+	Objective float64 `json:"objective"`
+	Window    string  `json:"window"`
+	// ThresholdMs is the latency under which requests are good, for latency
+	// SLOs.
+	ThresholdMs float64 `json:"thresholdMs,omitempty"`
+	// HistoricalCompliance is the fraction of the analyzed requests which
+	// were good.
+	HistoricalCompliance float64 `json:"historicalCompliance"`
+	SuccessMetric        string  `json:"successMetric"`
+	TotalMetric          string  `json:"totalMetric"`
+}
+
+type tempoSLOProposals struct {
+	Service        string                  `json:"service"`
+	TracesSearched int                     `json:"tracesSearched"`
+	Requests       int                     `json:"requests"`
+	FailedRequests int                     `json:"failedRequests"`
+	Availability   float64                 `json:"availability"`
+	LatencyMs      tempoLatencyPercentiles `json:"latencyMs"`
+	Proposals      []sloProposal           `json:"proposals"`
+	Errors         []string                `json:"errors,omitempty"`
+}
+
+// serviceEntrySpans returns the spans of a trace where requests enter a
+// service: spans of the service whose parent is missing or belongs to
+// another service.
+func serviceEntrySpans(spans []tempoSpan, service string) []tempoSpan {
+	services := make(map[string]string, len(spans))
+	for _, s := range spans {
+		services[s.SpanID] = s.ServiceName
+	}
+	var entries []tempoSpan
+	for _, s := range spans {
+		if s.ServiceName != service {
+			continue
+		}
+		if parent, ok := services[s.ParentSpanID]; s.ParentSpanID == "" || !ok || parent != service {
+			entries = append(entries, s)
+		}
+	}
+	return entries
+}
+
+// durationPercentile returns the nearest-rank percentile of sorted durations.
+func durationPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// roundLatencyThreshold rounds a latency up to the nearest 1, 2.5 or 5 times
+// a power of ten milliseconds, so proposed thresholds match common histogram
+// buckets.
+func roundLatencyThreshold(d time.Duration) float64 {
+	ms := durationMs(d)
+	for scale := 1.0; ; scale *= 10 {
+		for _, step := range []float64{1, 2.5, 5} {
+			if threshold := step * scale; threshold >= ms {
+				return threshold
+			}
+		}
+	}
+}
+
+// spanMetricsSelector returns the label matchers of the span metrics of a
+// service's server spans, with any extra matchers.
+func spanMetricsSelector(service string, extra ...string) string {
+	matchers := append([]string{"service=" + strconv.Quote(service), `span_kind="SPAN_KIND_SERVER"`}, extra...)
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// formatPercent formats a fraction as a percentage without trailing zeros.
+func formatPercent(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1e6)/1e4, 'f', -1, 64) + "%"
+}
+
+// proposeSLOs proposes an availability SLO and latency SLOs for a service
+// from the durations and outcomes of its requests.
+func proposeSLOs(service, window string, requests []tempoSpan) []sloProposal {
+	if len(requests) == 0 {
+		return []sloProposal{}
+	}
+	total := float64(len(requests))
+	failed := 0
+	for _, r := range requests {
+		if r.IsError {
+			failed++
+		}
+	}
+	availability := 1 - float64(failed)/total
+
+	// Propose the strictest target the service met, or the loosest one if it
+	// met none, so the proposal is achievable.
+	target := availabilityTargets[len(availabilityTargets)-1]
+	for _, t := range availabilityTargets {
+		if availability >= t {
+			target = t
+			break
+		}
+	}
+	proposals := []sloProposal{{
+		Name:                 fmt.Sprintf("%s availability", service),
+		Description:          fmt.Sprintf("%s of requests to %s succeed.", formatPercent(target), service),
+		Objective:            target,
+		Window:               window,
+		HistoricalCompliance: availability,
+		SuccessMetric:        "traces_spanmetrics_calls_total" + spanMetricsSelector(service, `status_code!="STATUS_CODE_ERROR"`),
+		TotalMetric:          "traces_spanmetrics_calls_total" + spanMetricsSelector(service),
+	}}
+
+	durations := make([]time.Duration, 0, len(requests))
+	for _, r := range requests {
+		durations = append(durations, r.Duration)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	for _, t := range latencyTargets {
+		threshold := roundLatencyThreshold(durationPercentile(durations, t))
+		fast := sort.Search(len(durations), func(i int) bool { return durationMs(durations[i]) > threshold })
+		le := strconv.FormatFloat(threshold/1000, 'f', -1, 64)
+		proposals = append(proposals, sloProposal{
+			Name:                 fmt.Sprintf("%s latency p%s", service, strconv.FormatFloat(t*100, 'f', -1, 64)),
+			Description:          fmt.Sprintf("%s of requests to %s complete within %sms.", formatPercent(t), service, strconv.FormatFloat(threshold, 'f', -1, 64)),
+			Objective:            t,
+			Window:               window,
+			ThresholdMs:          threshold,
+			HistoricalCompliance: float64(fast) / total,
+			SuccessMetric:        "traces_spanmetrics_latency_bucket" + spanMetricsSelector(service, "le="+strconv.Quote(le)),
+			TotalMetric:          "traces_spanmetrics_latency_count" + spanMetricsSelector(service),
+		})
+	}
+	return proposals
+}
+
+func proposeTempoSLOs(ctx context.Context, args ProposeTempoSLOsParams) (*tempoSLOProposals, error) {
+	if args.Service == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	query := fmt.Sprintf("{ resource.service.name = %s }", strconv.Quote(args.Service))
+	_, traces, err := client.searchAndFetchTraces(ctx, query, start, end, enforceTraceLimit(ctx, args.TraceLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tempoSLOProposals{Service: args.Service}
+	var requests []tempoSpan
+	for _, t := range traces {
+		if t.Err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("trace %s: %s", t.TraceID, t.Err))
+			continue
+		}
+		requests = append(requests, serviceEntrySpans(t.Trace.spans(), args.Service)...)
+		result.TracesSearched++
+	}
+
+	result.Requests = len(requests)
+	result.Proposals = proposeSLOs(args.Service, stringOrDefault(args.Window, DefaultSLOWindow), requests)
+	if len(requests) == 0 {
+		return result, nil
+	}
+	durations := make([]time.Duration, 0, len(requests))
+	for _, r := range requests {
+		if r.IsError {
+			result.FailedRequests++
+		}
+		durations = append(durations, r.Duration)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	result.Availability = 1 - float64(result.FailedRequests)/float64(result.Requests)
+	result.LatencyMs = tempoLatencyPercentiles{
+		P50: durationMs(durationPercentile(durations, 0.5)),
+		P90: durationMs(durationPercentile(durations, 0.9)),
+		P95: durationMs(durationPercentile(durations, 0.95)),
+		P99: durationMs(durationPercentile(durations, 0.99)),
+	}
+	return result, nil
+}
+
+var ProposeTempoSLOs = mcpgrafana.MustTool(
+	"propose_tempo_slos",
+	"Analyzes the requests a service handled over a period, taken from its spans in a Tempo datasource, and proposes SLOs: an availability target and latency thresholds for 95% and 99% of requests, each with the fraction of the analyzed requests which met it. Requests are the spans where calls enter the service. Proposals include ratio queries over the span metrics generated from traces (traces_spanmetrics_*), ready to pass to create_slo. Defaults to the last hour and 20 traces (max 100); use a longer period and more traces for more reliable proposals.",
+	guardTimeRange(proposeTempoSLOs),
+	mcp.WithTitleAnnotation("Propose SLOs from traces"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// CreateSLOParams defines the parameters for creating an SLO in the Grafana
+// SLO app.
+type CreateSLOParams struct {
+	Name                     string  `json:"name" jsonschema:"required,description=The name of the SLO"`
+	Description              string  `json:"description,omitempty" jsonschema:"description=Optionally\\, a description of the SLO"`
+	SuccessMetric            string  `json:"successMetric" jsonschema:"required,description=The PromQL selector counting good events\\, e.g. a successMetric proposed by propose_tempo_slos"`
+	TotalMetric              string  `json:"totalMetric" jsonschema:"required,description=The PromQL selector counting all events"`
+	Objective                float64 `json:"objective" jsonschema:"required,description=The target fraction of good events\\, between 0 and 1 (e.g. 0.995)"`
+	Window                   string  `json:"window,omitempty" jsonschema:"description=Optionally\\, the compliance window (default: 28d)"`
+	DestinationDatasourceUID string  `json:"destinationDatasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource the SLO's recording rules write to"`
+}
+
+func (p CreateSLOParams) validate() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("name is required")
+	case p.SuccessMetric == "" || p.TotalMetric == "":
+		return fmt.Errorf("successMetric and totalMetric are required")
+	case p.Objective <= 0 || p.Objective >= 1:
+		return fmt.Errorf("objective must be between 0 and 1, got %v", p.Objective)
+	case p.DestinationDatasourceUID == "":
+		return fmt.Errorf("destinationDatasourceUid is required")
+	}
+	return nil
+}
+
+type sloMetric struct {
+	PrometheusMetric string `json:"prometheusMetric"`
+}
+
+type sloRatioQuery struct {
+	SuccessMetric sloMetric `json:"successMetric"`
+	TotalMetric   sloMetric `json:"totalMetric"`
+}
+
+type sloQuery struct {
+	Type  string        `json:"type"`
+	Ratio sloRatioQuery `json:"ratio"`
+}
+
+type sloObjective struct {
+	Value  float64 `json:"value"`
+	Window string  `json:"window"`
+}
+
+type sloDestinationDatasource struct {
+	UID string `json:"uid"`
+}
+
+type sloLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// slo is an SLO as accepted and returned by the Grafana SLO app API.
+type slo struct {
+	UUID                  string                   `json:"uuid,omitempty"`
+	Name                  string                   `json:"name"`
+	Description           string                   `json:"description"`
+	Query                 sloQuery                 `json:"query"`
+	Objectives            []sloObjective           `json:"objectives"`
+	DestinationDatasource sloDestinationDatasource `json:"destinationDatasource"`
+	Labels                []sloLabel               `json:"labels,omitempty"`
+}
+
+type createdSLO struct {
+	UUID    string `json:"uuid"`
+	Message string `json:"message,omitempty"`
+}
+
+// sloRequest sends a request with an optional JSON body to the Grafana SLO
+// app API, returning the response body.
+func sloRequest(ctx context.Context, method, path string, reqBody any) ([]byte, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	// Create the transport for the TLS configuration and unix socket, if any
+	transport, err := cfg.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom transport: %w", err)
+	}
+	client := &http.Client{
+		Transport: &authRoundTripper{
+			accessToken: cfg.AccessToken,
+			idToken:     cfg.IDToken,
+			apiKey:      cfg.APIKey,
+			underlying:  transport,
+		},
+	}
+
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(cfg.URL, "/")+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("SLO API returned status code %d: %s", resp.StatusCode, string(buf))
+	}
+	return buf, nil
+}
+
+// postSLO creates an SLO through the Grafana SLO app API.
+func postSLO(ctx context.Context, s slo) (*createdSLO, error) {
+	buf, err := sloRequest(ctx, http.MethodPost, sloAPIPath, s)
+	if err != nil {
+		return nil, err
+	}
+	var created createdSLO
+	if err := json.Unmarshal(buf, &created); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	}
+	return &created, nil
+}
+
+// listSLOs lists the SLOs of the Grafana SLO app.
+func listSLOs(ctx context.Context) ([]slo, error) {
+	buf, err := sloRequest(ctx, http.MethodGet, sloAPIPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		SLOs []slo `json:"slos"`
+	}
+	if err := json.Unmarshal(buf, &list); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	}
+	return list.SLOs, nil
+}
+
+func createSLO(ctx context.Context, args CreateSLOParams) (*createdSLO, error) {
+	if err := args.validate(); err != nil {
+		return nil, err
+	}
+	return postSLO(ctx, slo{
+		Name:        args.Name,
+		Description: args.Description,
+		Query: sloQuery{
+			Type: "ratio",
+			Ratio: sloRatioQuery{
+				SuccessMetric: sloMetric{PrometheusMetric: args.SuccessMetric},
+				TotalMetric:   sloMetric{PrometheusMetric: args.TotalMetric},
+			},
+		},
+		Objectives:            []sloObjective{{Value: args.Objective, Window: stringOrDefault(args.Window, DefaultSLOWindow)}},
+		DestinationDatasource: sloDestinationDatasource{UID: args.DestinationDatasourceUID},
+	})
+}
+
+var CreateSLO = mcpgrafana.MustTool(
+	"create_slo",
+	"Creates an SLO in the Grafana SLO app from a ratio of good to total events, such as a proposal returned by propose_tempo_slos. Returns the UUID of the new SLO. Requires the Grafana SLO app.",
+	createSLO,
+	mcp.WithTitleAnnotation("Create SLO"),
+	mcp.WithDestructiveHintAnnotation(false),
+)
@@ -0,0 +1,125 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundLatencyThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		ms   float64
+		want float64
+	}{
+		{0.3, 1},
+		{1, 1},
+		{1.5, 2.5},
+		{70, 100},
+		{120, 250},
+		{260, 500},
+		{3200, 5000},
+	} {
+		assert.Equal(t, tc.want, roundLatencyThreshold(time.Duration(tc.ms*float64(time.Millisecond))), "%vms", tc.ms)
+	}
+}
+
+func TestProposeTempoSLOs(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/search":
+			assert.Equal(t, `{ resource.service.name = "backend" }`, r.URL.Query().Get("q"))
+			_, _ = w.Write([]byte(`{"traces":[{"traceID":"1"},{"traceID":"2"}]}`))
+		case "/api/traces/1":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, false)))
+		case "/api/traces/2":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAg==", 70, true)))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	result, err := proposeTempoSLOs(ctx, ProposeTempoSLOsParams{DatasourceUID: "tempo", Service: "backend"})
+	require.NoError(t, err)
+
+	// Both backend spans of each trace are called by the frontend.
+	assert.Equal(t, 2, result.TracesSearched)
+	assert.Equal(t, 4, result.Requests)
+	assert.Equal(t, 1, result.FailedRequests)
+	assert.Equal(t, 0.75, result.Availability)
+	assert.Equal(t, 70.0, result.LatencyMs.P99)
+
+	require.Len(t, result.Proposals, 3)
+	availability := result.Proposals[0]
+	assert.Equal(t, "backend availability", availability.Name)
+	assert.Equal(t, 0.9, availability.Objective)
+	assert.Equal(t, 0.75, availability.HistoricalCompliance)
+	assert.Equal(t, "28d", availability.Window)
+	assert.Equal(t, `traces_spanmetrics_calls_total{service="backend", span_kind="SPAN_KIND_SERVER", status_code!="STATUS_CODE_ERROR"}`, availability.SuccessMetric)
+	assert.Equal(t, `traces_spanmetrics_calls_total{service="backend", span_kind="SPAN_KIND_SERVER"}`, availability.TotalMetric)
+
+	latency := result.Proposals[1]
+	assert.Equal(t, "backend latency p95", latency.Name)
+	assert.Equal(t, 0.95, latency.Objective)
+	assert.Equal(t, 100.0, latency.ThresholdMs)
+	assert.Equal(t, 1.0, latency.HistoricalCompliance)
+	assert.Equal(t, `traces_spanmetrics_latency_bucket{service="backend", span_kind="SPAN_KIND_SERVER", le="0.1"}`, latency.SuccessMetric)
+
+	t.Run("only counts the requests entering the service", func(t *testing.T) {
+		var trace otlpTrace
+		require.NoError(t, json.Unmarshal([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, false)), &trace))
+		entries := serviceEntrySpans(trace.spans(), "frontend")
+		require.Len(t, entries, 1)
+		assert.Equal(t, "GET /", entries[0].Name)
+	})
+
+	t.Run("no requests", func(t *testing.T) {
+		requests := serviceEntrySpans(nil, "backend")
+		assert.Empty(t, proposeSLOs("backend", "28d", requests))
+	})
+}
+
+func TestCreateSLO(t *testing.T) {
+	var got slo
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/plugins/grafana-slo-app/resources/v1/slo", r.URL.Path)
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"uuid":"abc123","message":"SLO created"}`))
+	}))
+	defer server.Close()
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL, APIKey: "test-api-key"})
+
+	created, err := createSLO(ctx, CreateSLOParams{
+		Name:                     "backend availability",
+		SuccessMetric:            `traces_spanmetrics_calls_total{status_code!="STATUS_CODE_ERROR"}`,
+		TotalMetric:              `traces_spanmetrics_calls_total`,
+		Objective:                0.99,
+		DestinationDatasourceUID: "prom",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", created.UUID)
+
+	assert.Equal(t, "ratio", got.Query.Type)
+	assert.Equal(t, `traces_spanmetrics_calls_total{status_code!="STATUS_CODE_ERROR"}`, got.Query.Ratio.SuccessMetric.PrometheusMetric)
+	assert.Equal(t, []sloObjective{{Value: 0.99, Window: "28d"}}, got.Objectives)
+	assert.Equal(t, "prom", got.DestinationDatasource.UID)
+
+	t.Run("validates the objective", func(t *testing.T) {
+		_, err := createSLO(ctx, CreateSLOParams{Name: "x", SuccessMetric: "a", TotalMetric: "b", Objective: 99, DestinationDatasourceUID: "prom"})
+		assert.ErrorContains(t, err, "objective")
+	})
+}
@@ -0,0 +1,1190 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultTempoTraceLimit is the default number of traces fetched by tools
+	// which aggregate over a search result.
+	DefaultTempoTraceLimit = 20
+
+	// MaxTempoTraceLimit is the maximum number of traces that can be fetched
+	// by tools which aggregate over a search result.
+	MaxTempoTraceLimit = 100
+
+	// tempoFetchConcurrency limits how many traces are fetched at once.
+	tempoFetchConcurrency = 5
+)
+
+func AddTempoTools(mcp *server.MCPServer) {
+	AggregateTempoTracesFlamegraph.Register(mcp)
+	FindTempoSlowestSpans.Register(mcp)
+	GenerateTempoDeeplink.Register(mcp)
+	GetTempoTracesBatch.Register(mcp)
+	SearchTempoTraces.Register(mcp)
+	GetTempoSpanEvents.Register(mcp)
+	ListTempoTagNames.Register(mcp)
+	ListTempoTagValues.Register(mcp)
+	ProposeTempoSLOs.Register(mcp)
+}
+
+// tempoClient is a client for the Tempo HTTP API, accessed through the
+// Grafana datasource proxy.
+type tempoClient struct {
+	httpClient *http.Client
+	base       *url.URL
+}
+
+func newTempoClient(ctx context.Context, uid string) (*tempoClient, error) {
+	// First check if the datasource exists
+	_, err := cachedDatasourceByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newQueryHTTPClient(ctx, defaultsCategoryTempo, http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base url: %w", err)
+	}
+
+	return &tempoClient{
+		httpClient: httpClient,
+		base:       base.JoinPath("api", "datasources", "proxy", "uid", uid),
+	}, nil
+}
+
+// get makes a GET request to the Tempo API and returns the response body.
+func (c *tempoClient) get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	u := c.base.JoinPath(path)
+	if params != nil {
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Tempo API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Read the response body with a limit to prevent memory issues
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("empty response from Tempo API")
+	}
+	return body, nil
+}
+
+// tempoSearchResult is a single trace returned by Tempo's search API.
+type tempoSearchResult struct {
+	TraceID           string `json:"traceID"`
+	RootServiceName   string `json:"rootServiceName,omitempty"`
+	RootTraceName     string `json:"rootTraceName,omitempty"`
+	StartTimeUnixNano string `json:"startTimeUnixNano,omitempty"`
+	DurationMs        int64  `json:"durationMs,omitempty"`
+	// SpanSet and SpanSets are the spans matched by the query. Older Tempo
+	// versions only return a single span set.
+	SpanSet  json.RawMessage   `json:"spanSet,omitempty"`
+	SpanSets []json.RawMessage `json:"spanSets,omitempty"`
+}
+
+// tempoSearchRequest is a TraceQL search using Tempo's /api/search endpoint.
+type tempoSearchRequest struct {
+	Query      string
+	Start, End time.Time
+	Limit      int
+	// MostRecent asks Tempo to return the most recent matching traces
+	// rather than the first ones found, at the cost of a slower search.
+	MostRecent bool
+}
+
+// search runs a TraceQL query using Tempo's /api/search endpoint.
+func (c *tempoClient) search(ctx context.Context, req tempoSearchRequest) ([]tempoSearchResult, error) {
+	response, err := c.searchResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return response.Traces, nil
+}
+
+// tempoSearchMetrics are the job metrics Tempo reports for a search.
+type tempoSearchMetrics struct {
+	InspectedTraces otlpUint64 `json:"inspectedTraces"`
+	InspectedBytes  otlpUint64 `json:"inspectedBytes"`
+	TotalBlocks     otlpUint64 `json:"totalBlocks"`
+	CompletedJobs   otlpUint64 `json:"completedJobs"`
+	TotalJobs       otlpUint64 `json:"totalJobs"`
+	TotalBlockBytes otlpUint64 `json:"totalBlockBytes"`
+}
+
+type tempoSearchResponse struct {
+	Traces  []tempoSearchResult `json:"traces"`
+	Metrics tempoSearchMetrics  `json:"metrics"`
+}
+
+// searchResponse runs a search, returning the matching traces together with
+// the search's job metrics.
+func (c *tempoClient) searchResponse(ctx context.Context, req tempoSearchRequest) (*tempoSearchResponse, error) {
+	params := url.Values{}
+	params.Set("q", req.Query)
+	params.Set("start", strconv.FormatInt(req.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(req.End.Unix(), 10))
+	params.Set("limit", strconv.Itoa(req.Limit))
+	if req.MostRecent {
+		params.Set("mostRecent", "true")
+	}
+
+	body, err := c.get(ctx, "/api/search", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response tempoSearchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling search response (content: %s): %w", string(body), err)
+	}
+	return &response, nil
+}
+
+// trace fetches a single trace by ID in OTLP JSON format.
+func (c *tempoClient) trace(ctx context.Context, traceID string) (*otlpTrace, error) {
+	escapedID, err := sanitizePathSegment("trace ID", traceID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.get(ctx, "/api/traces/"+escapedID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace otlpTrace
+	if err := json.Unmarshal(body, &trace); err != nil {
+		return nil, fmt.Errorf("unmarshalling trace %s: %w", traceID, err)
+	}
+	return &trace, nil
+}
+
+// traceResult is the outcome of fetching one trace as part of a batch.
+type traceResult struct {
+	TraceID string
+	Trace   *otlpTrace
+	Err     error
+}
+
+// traces fetches the given traces concurrently, preserving the order of the
+// provided IDs. Errors are reported per trace rather than failing the batch.
+func (c *tempoClient) traces(ctx context.Context, traceIDs []string) []traceResult {
+	results := make([]traceResult, len(traceIDs))
+	sem := make(chan struct{}, tempoFetchConcurrency)
+	var wg sync.WaitGroup
+	for i, id := range traceIDs {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			trace, err := c.trace(ctx, id)
+			results[i] = traceResult{TraceID: id, Trace: trace, Err: err}
+		}(i, id)
+	}
+	wg.Wait()
+	return results
+}
+
+// searchAndFetchTraces runs a TraceQL search and fetches every matching trace.
+func (c *tempoClient) searchAndFetchTraces(ctx context.Context, query string, start, end time.Time, limit int) ([]tempoSearchResult, []traceResult, error) {
+	matches, err := c.search(ctx, tempoSearchRequest{Query: query, Start: start, End: end, Limit: limit})
+	if err != nil {
+		return nil, nil, fmt.Errorf("searching traces: %w", err)
+	}
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.TraceID)
+	}
+	return matches, c.traces(ctx, ids), nil
+}
+
+// otlpTrace is a trace in the OTLP JSON format returned by Tempo. Older Tempo
+// versions use `batches` and `instrumentationLibrarySpans`, newer ones use
+// `resourceSpans` and `scopeSpans`.
+type otlpTrace struct {
+	Batches       []otlpResourceSpans `json:"batches,omitempty"`
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans,omitempty"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	} `json:"resource"`
+	ScopeSpans                  []otlpScopeSpans `json:"scopeSpans,omitempty"`
+	InstrumentationLibrarySpans []otlpScopeSpans `json:"instrumentationLibrarySpans,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              json.RawMessage `json:"kind,omitempty"`
+	StartTimeUnixNano otlpUint64      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   otlpUint64      `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue  `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            struct {
+		Code    json.RawMessage `json:"code,omitempty"`
+		Message string          `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano otlpUint64     `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	IntValue    *otlpUint64     `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	ArrayValue  json.RawMessage `json:"arrayValue,omitempty"`
+}
+
+// String renders the value as a string.
+func (v otlpAnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return strconv.FormatUint(uint64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64)
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.ArrayValue != nil:
+		return string(v.ArrayValue)
+	}
+	return ""
+}
+
+// otlpUint64 is a uint64 which may be encoded as either a JSON number or a
+// JSON string, as produced by protobuf's JSON encoding.
+type otlpUint64 uint64
+
+func (u *otlpUint64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*u = 0
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		// Negative int values are legal for attributes; keep their bit pattern.
+		i, ierr := strconv.ParseInt(s, 10, 64)
+		if ierr != nil {
+			return fmt.Errorf("invalid integer %s: %w", s, err)
+		}
+		n = uint64(i)
+	}
+	*u = otlpUint64(n)
+	return nil
+}
+
+// tempoSpan is a flattened span with its resource's service name attached.
+type tempoSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	ServiceName  string
+	Name         string
+	Start        time.Time
+	Duration     time.Duration
+	IsError      bool
+	Attributes   []otlpKeyValue
+	Events       []otlpEvent
+}
+
+// spans returns all spans in the trace, flattened across resources.
+func (t *otlpTrace) spans() []tempoSpan {
+	var result []tempoSpan
+	for _, rs := range append(t.Batches, t.ResourceSpans...) {
+		service := attributeValue(rs.Resource.Attributes, "service.name")
+		for _, ss := range append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...) {
+			for _, s := range ss.Spans {
+				start := int64(s.StartTimeUnixNano)
+				end := int64(s.EndTimeUnixNano)
+				duration := time.Duration(end - start)
+				if duration < 0 {
+					duration = 0
+				}
+				result = append(result, tempoSpan{
+					TraceID:      normalizeOTLPID(s.TraceID),
+					SpanID:       normalizeOTLPID(s.SpanID),
+					ParentSpanID: normalizeOTLPID(s.ParentSpanID),
+					ServiceName:  service,
+					Name:         s.Name,
+					Start:        time.Unix(0, start).UTC(),
+					Duration:     duration,
+					IsError:      isOTLPErrorStatus(s.Status.Code),
+					Attributes:   s.Attributes,
+					Events:       s.Events,
+				})
+			}
+		}
+	}
+	return result
+}
+
+func attributeValue(attrs []otlpKeyValue, key string) string {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+// isOTLPErrorStatus reports whether a span status code denotes an error. The
+// code may be encoded as the enum name or its numeric value.
+func isOTLPErrorStatus(code json.RawMessage) bool {
+	s := strings.Trim(string(code), `"`)
+	return s == "STATUS_CODE_ERROR" || s == "2"
+}
+
+// normalizeOTLPID converts base64 encoded IDs, as produced by protobuf's JSON
+// encoding, to the hex representation used by Tempo and Grafana. IDs that are
+// already hex encoded are returned lower-cased.
+func normalizeOTLPID(id string) string {
+	if id == "" {
+		return ""
+	}
+	if _, err := hex.DecodeString(id); err == nil && (len(id) == 16 || len(id) == 32) {
+		return strings.ToLower(id)
+	}
+	if b, err := base64.StdEncoding.DecodeString(id); err == nil {
+		return hex.EncodeToString(b)
+	}
+	return id
+}
+
+// tempoTimeRange parses optional start and end times, defaulting to the
+// configured Tempo time range (the last hour by default). Times may be RFC3339
+// or relative to now (e.g. 'now-1h').
+func tempoTimeRange(ctx context.Context, startStr, endStr string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if startStr != "" {
+		if start, err = parseTime(startStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if endStr != "" {
+		if end, err = parseTime(endStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	return validateTimeRange(ctx, defaultsCategoryTempo, start, end)
+}
+
+// enforceTraceLimit ensures a trace limit value is within the configured
+// bounds.
+func enforceTraceLimit(ctx context.Context, requestedLimit int) int {
+	return enforceLimit(toolDefaultsFor(ctx, defaultsCategoryTempo), requestedLimit)
+}
+
+// AggregateTempoTracesFlamegraphParams defines the parameters for aggregating
+// traces into a flamegraph.
+type AggregateTempoTracesFlamegraphParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	Query         string `json:"query,omitempty" jsonschema:"description=The TraceQL query selecting the traces to aggregate (defaults to {})"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of traces to aggregate (default: 20\\, max: 100)"`
+}
+
+// flamegraphNode is a node in an aggregated trace flamegraph. Spans are
+// merged with their siblings when they share a service and span name.
+type flamegraphNode struct {
+	Service         string            `json:"service"`
+	Name            string            `json:"name"`
+	Count           int               `json:"count"`
+	ErrorCount      int               `json:"errorCount,omitempty"`
+	TotalDurationMs float64           `json:"totalDurationMs"`
+	SelfDurationMs  float64           `json:"selfDurationMs"`
+	Children        []*flamegraphNode `json:"children,omitempty"`
+
+	children map[string]*flamegraphNode
+}
+
+type tempoFlamegraph struct {
+	TracesMatched    int             `json:"tracesMatched"`
+	TracesAggregated int             `json:"tracesAggregated"`
+	Errors           []string        `json:"errors,omitempty"`
+	Root             *flamegraphNode `json:"root"`
+}
+
+func (n *flamegraphNode) child(service, name string) *flamegraphNode {
+	key := service + "\x00" + name
+	if n.children == nil {
+		n.children = map[string]*flamegraphNode{}
+	}
+	c, ok := n.children[key]
+	if !ok {
+		c = &flamegraphNode{Service: service, Name: name}
+		n.children[key] = c
+		n.Children = append(n.Children, c)
+	}
+	return c
+}
+
+// sortChildren orders children by total duration, largest first.
+func (n *flamegraphNode) sortChildren() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].TotalDurationMs > n.Children[j].TotalDurationMs
+	})
+	for _, c := range n.Children {
+		c.sortChildren()
+	}
+}
+
+// addTraceToFlamegraph merges the span tree of a trace into the flamegraph.
+func addTraceToFlamegraph(root *flamegraphNode, spans []tempoSpan) {
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+	}
+	children := map[string][]tempoSpan{}
+	var roots []tempoSpan
+	for _, s := range spans {
+		if s.ParentSpanID == "" || !ids[s.ParentSpanID] {
+			roots = append(roots, s)
+			continue
+		}
+		children[s.ParentSpanID] = append(children[s.ParentSpanID], s)
+	}
+
+	var add func(parent *flamegraphNode, s tempoSpan, depth int)
+	add = func(parent *flamegraphNode, s tempoSpan, depth int) {
+		node := parent.child(s.ServiceName, s.Name)
+		node.Count++
+		if s.IsError {
+			node.ErrorCount++
+		}
+		duration := durationMs(s.Duration)
+		node.TotalDurationMs += duration
+
+		var childDuration float64
+		for _, c := range children[s.SpanID] {
+			childDuration += durationMs(c.Duration)
+		}
+		if self := duration - childDuration; self > 0 {
+			node.SelfDurationMs += self
+		}
+
+		// Guard against cyclic parent references in malformed traces.
+		if depth > len(spans) {
+			return
+		}
+		for _, c := range children[s.SpanID] {
+			add(node, c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		add(root, r, 0)
+		root.TotalDurationMs += durationMs(r.Duration)
+	}
+	root.Count++
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func aggregateTempoTracesFlamegraph(ctx context.Context, args AggregateTempoTracesFlamegraphParams) (*tempoFlamegraph, error) {
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	query := stringOrDefault(args.Query, "{}")
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	matches, traces, err := client.searchAndFetchTraces(ctx, query, start, end, enforceTraceLimit(ctx, args.Limit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tempoFlamegraph{
+		TracesMatched: len(matches),
+		Root:          &flamegraphNode{Name: "all traces"},
+	}
+	for _, t := range traces {
+		if t.Err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("trace %s: %s", t.TraceID, t.Err))
+			continue
+		}
+		addTraceToFlamegraph(result.Root, t.Trace.spans())
+		result.TracesAggregated++
+	}
+	result.Root.sortChildren()
+	return result, nil
+}
+
+var AggregateTempoTracesFlamegraph = mcpgrafana.MustTool(
+	"aggregate_tempo_traces_flamegraph",
+	"Fetches the traces matching a TraceQL query in a Tempo datasource and merges them into a single flamegraph-style tree, similar to Tempo's 'Aggregate by' feature. Spans are merged with their siblings when they share a service and span name, and each node reports the span count, error count, total duration and self duration (excluding children) in milliseconds. Children are ordered by total duration, largest first. Defaults to the last hour and 20 traces (max 100).",
+	guardTimeRange(aggregateTempoTracesFlamegraph),
+	mcp.WithTitleAnnotation("Aggregate Tempo traces into a flamegraph"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// DefaultTempoSpanLimit is the default number of spans returned by
+// find_tempo_slowest_spans.
+const DefaultTempoSpanLimit = 10
+
+// FindTempoSlowestSpansParams defines the parameters for finding the slowest
+// spans across matching traces.
+type FindTempoSlowestSpansParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	Query         string `json:"query,omitempty" jsonschema:"description=The TraceQL query selecting the traces to search (defaults to {})"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+	TraceLimit    int    `json:"traceLimit,omitempty" jsonschema:"description=Optionally\\, the maximum number of traces to search (default: 20\\, max: 100)"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the number of slowest spans to return (default: 10)"`
+}
+
+// slowSpan is a single span returned by find_tempo_slowest_spans.
+type slowSpan struct {
+	Service    string    `json:"service"`
+	Name       string    `json:"name"`
+	DurationMs float64   `json:"durationMs"`
+	TraceID    string    `json:"traceId"`
+	SpanID     string    `json:"spanId"`
+	Start      time.Time `json:"start"`
+	IsError    bool      `json:"isError,omitempty"`
+}
+
+type tempoSlowestSpans struct {
+	TracesMatched  int        `json:"tracesMatched"`
+	TracesSearched int        `json:"tracesSearched"`
+	Errors         []string   `json:"errors,omitempty"`
+	Spans          []slowSpan `json:"spans"`
+}
+
+// slowestSpans returns the n longest spans, longest first.
+func slowestSpans(spans []tempoSpan, n int) []slowSpan {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Duration > spans[j].Duration
+	})
+	if len(spans) > n {
+		spans = spans[:n]
+	}
+	result := make([]slowSpan, 0, len(spans))
+	for _, s := range spans {
+		result = append(result, slowSpan{
+			Service:    s.ServiceName,
+			Name:       s.Name,
+			DurationMs: durationMs(s.Duration),
+			TraceID:    s.TraceID,
+			SpanID:     s.SpanID,
+			Start:      s.Start,
+			IsError:    s.IsError,
+		})
+	}
+	return result
+}
+
+func findTempoSlowestSpans(ctx context.Context, args FindTempoSlowestSpansParams) (*tempoSlowestSpans, error) {
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	query := stringOrDefault(args.Query, "{}")
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultTempoSpanLimit
+	}
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	matches, traces, err := client.searchAndFetchTraces(ctx, query, start, end, enforceTraceLimit(ctx, args.TraceLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &tempoSlowestSpans{TracesMatched: len(matches)}
+	var spans []tempoSpan
+	for _, t := range traces {
+		if t.Err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("trace %s: %s", t.TraceID, t.Err))
+			continue
+		}
+		spans = append(spans, t.Trace.spans()...)
+		result.TracesSearched++
+	}
+	result.Spans = slowestSpans(spans, limit)
+	return result, nil
+}
+
+var FindTempoSlowestSpans = mcpgrafana.MustTool(
+	"find_tempo_slowest_spans",
+	"Searches a Tempo datasource for traces matching a TraceQL query and returns the N slowest individual spans across all of them, longest first, with their service, span name, duration in milliseconds, trace ID and span ID. Use this to find which operation is the bottleneck in one call. Defaults to the last hour, 20 traces (max 100) and 10 spans.",
+	guardTimeRange(findTempoSlowestSpans),
+	mcp.WithTitleAnnotation("Find slowest Tempo spans"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// GenerateTempoDeeplinkParams defines the parameters for building a link to a
+// trace or TraceQL search in Grafana Explore.
+type GenerateTempoDeeplinkParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource"`
+	TraceID       string `json:"traceId,omitempty" jsonschema:"description=The ID of the trace to link to. Exactly one of traceId and query is required"`
+	Query         string `json:"query,omitempty" jsonschema:"description=The TraceQL query to link to. Exactly one of traceId and query is required"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 'now-1h'"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to 'now'"`
+}
+
+func (p GenerateTempoDeeplinkParams) validate() error {
+	if (p.TraceID == "") == (p.Query == "") {
+		return fmt.Errorf("exactly one of traceId and query is required")
+	}
+	return nil
+}
+
+func generateTempoDeeplink(ctx context.Context, args GenerateTempoDeeplinkParams) (string, error) {
+	if err := args.validate(); err != nil {
+		return "", err
+	}
+	from, err := grafanaURLTime(args.StartTime, "now-1h")
+	if err != nil {
+		return "", fmt.Errorf("parsing start time: %w", err)
+	}
+	to, err := grafanaURLTime(args.EndTime, "now")
+	if err != nil {
+		return "", fmt.Errorf("parsing end time: %w", err)
+	}
+
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return "", err
+	}
+
+	// Tempo's TraceQL editor opens the trace view when given a trace ID.
+	query := stringOrDefault(strings.TrimSpace(args.TraceID), args.Query)
+	grafanaURL, err := grafanaLinkURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	return exploreURL(grafanaURL, explorePane{
+		Datasource: ds.UID,
+		Queries: []map[string]any{{
+			"refId":      "A",
+			"datasource": datasourceInfo{UID: ds.UID, Type: ds.Type},
+			"queryType":  "traceql",
+			"query":      query,
+		}},
+		Range: exploreRange{From: from, To: to},
+	})
+}
+
+var GenerateTempoDeeplink = mcpgrafana.MustTool(
+	"generate_tempo_deeplink",
+	"Builds a Grafana Explore URL for a trace ID or TraceQL query in a Tempo datasource, with the time range encoded, so the user can continue the investigation in the Grafana UI. Relative times such as 'now-1h' are kept relative in the link. Returns the URL.",
+	generateTempoDeeplink,
+	mcp.WithTitleAnnotation("Generate Tempo deeplink"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// GetTempoTracesBatchParams defines the parameters for fetching several traces
+// at once.
+type GetTempoTracesBatchParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	TraceIDs      []string `json:"traceIds" jsonschema:"required,description=The IDs of the traces to fetch (max: 100)"`
+}
+
+// traceSpanSummary is a compact representation of a span.
+type traceSpanSummary struct {
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	Service      string            `json:"service"`
+	Name         string            `json:"name"`
+	Start        time.Time         `json:"start"`
+	DurationMs   float64           `json:"durationMs"`
+	IsError      bool              `json:"isError,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// batchTrace is a single trace in a batch. Error is set if the trace could
+// not be fetched.
+type batchTrace struct {
+	TraceID string             `json:"traceId"`
+	Spans   []traceSpanSummary `json:"spans,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+func summarizeSpans(spans []tempoSpan) []traceSpanSummary {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+	result := make([]traceSpanSummary, 0, len(spans))
+	for _, s := range spans {
+		var attrs map[string]string
+		if len(s.Attributes) > 0 {
+			attrs = make(map[string]string, len(s.Attributes))
+			for _, a := range s.Attributes {
+				attrs[a.Key] = a.Value.String()
+			}
+		}
+		result = append(result, traceSpanSummary{
+			SpanID:       s.SpanID,
+			ParentSpanID: s.ParentSpanID,
+			Service:      s.ServiceName,
+			Name:         s.Name,
+			Start:        s.Start,
+			DurationMs:   durationMs(s.Duration),
+			IsError:      s.IsError,
+			Attributes:   attrs,
+		})
+	}
+	return result
+}
+
+func getTempoTracesBatch(ctx context.Context, args GetTempoTracesBatchParams) ([]batchTrace, error) {
+	if len(args.TraceIDs) == 0 {
+		return nil, fmt.Errorf("at least one trace ID is required")
+	}
+	if len(args.TraceIDs) > MaxTempoTraceLimit {
+		return nil, fmt.Errorf("too many trace IDs: %d (max: %d)", len(args.TraceIDs), MaxTempoTraceLimit)
+	}
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	traces := client.traces(ctx, args.TraceIDs)
+	result := make([]batchTrace, 0, len(traces))
+	for _, t := range traces {
+		bt := batchTrace{TraceID: t.TraceID}
+		if t.Err != nil {
+			bt.Error = t.Err.Error()
+		} else {
+			bt.Spans = summarizeSpans(t.Trace.spans())
+		}
+		result = append(result, bt)
+	}
+	return result, nil
+}
+
+var GetTempoTracesBatch = mcpgrafana.MustTool(
+	"get_tempo_traces_batch",
+	"Fetches several traces from a Tempo datasource concurrently, in the order given. Each trace is returned with its spans (span ID, parent span ID, service, name, start time, duration in milliseconds, error status and attributes) ordered by start time, or with an `error` field if that trace could not be fetched. Use this instead of fetching exemplar traces one at a time.",
+	getTempoTracesBatch,
+	mcp.WithTitleAnnotation("Get Tempo traces"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// SearchTempoTracesParams defines the parameters for searching traces.
+type SearchTempoTracesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	Query         string `json:"query,omitempty" jsonschema:"description=The TraceQL query to run (defaults to {})"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of traces to return (default: 20\\, max: 100)"`
+	MostRecent    bool   `json:"mostRecent,omitempty" jsonschema:"description=Optionally\\, return the most recent matching traces first. Searches are slower as Tempo must scan the whole time range"`
+	MetadataOnly  bool   `json:"metadataOnly,omitempty" jsonschema:"description=Optionally\\, only return trace IDs\\, root service and span names\\, start times and durations\\, omitting the matched span sets"`
+	EstimateOnly  bool   `json:"estimateOnly,omitempty" jsonschema:"description=Optionally\\, only return Tempo's job metrics for the search (the number of blocks\\, jobs and bytes in the time range) instead of the traces. Use this to gauge the cost of a search before running it"`
+	PageSize      int    `json:"pageSize,omitempty" jsonschema:"description=Optionally\\, return the traces in pages of this size. The result is then an object with the traces in 'items' and a 'nextPageToken' while more pages remain"`
+	PageToken     string `json:"pageToken,omitempty" jsonschema:"description=Optionally\\, the 'nextPageToken' from a previous call to fetch the next page of its results. The other parameters are ignored"`
+}
+
+// tempoSearchEstimate is the size of a search reported by Tempo, returned
+// instead of the traces in estimateOnly mode.
+type tempoSearchEstimate struct {
+	Start   time.Time          `json:"start"`
+	End     time.Time          `json:"end"`
+	Metrics tempoSearchMetrics `json:"metrics"`
+}
+
+// estimateTempoSearch runs the search for a single trace to get the job
+// metrics Tempo reports for the whole time range.
+func estimateTempoSearch(ctx context.Context, args SearchTempoTracesParams) (*tempoSearchEstimate, error) {
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+	response, err := client.searchResponse(ctx, tempoSearchRequest{
+		Query: stringOrDefault(args.Query, "{}"),
+		Start: start,
+		End:   end,
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching traces: %w", err)
+	}
+	return &tempoSearchEstimate{Start: start, End: end, Metrics: response.Metrics}, nil
+}
+
+// searchTempoTracesResult is the result of the search_tempo_traces tool: the
+// traces, or an estimate when estimateOnly is set.
+type searchTempoTracesResult struct{ resultValue }
+
+func (searchTempoTracesResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeFor[paginated[tempoSearchResult]](), reflect.TypeFor[*tempoSearchEstimate]()}
+}
+
+// searchTempoTracesTool handles calls to the search_tempo_traces tool,
+// returning only the search's job metrics when an estimate is requested and
+// paginating the traces when a page size is set.
+func searchTempoTracesTool(ctx context.Context, args SearchTempoTracesParams) (searchTempoTracesResult, error) {
+	if args.EstimateOnly {
+		return asResult[searchTempoTracesResult](estimateTempoSearch(ctx, args))
+	}
+	return asResult[searchTempoTracesResult](withPagination(ctx, "search_tempo_traces", args.PageSize, args.PageToken, func(ctx context.Context) ([]tempoSearchResult, error) {
+		return searchTempoTraces(ctx, args)
+	}))
+}
+
+func searchTempoTraces(ctx context.Context, args SearchTempoTracesParams) ([]tempoSearchResult, error) {
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	traces, err := client.search(ctx, tempoSearchRequest{
+		Query:      stringOrDefault(args.Query, "{}"),
+		Start:      start,
+		End:        end,
+		Limit:      enforceTraceLimit(ctx, args.Limit),
+		MostRecent: args.MostRecent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching traces: %w", err)
+	}
+	if args.MetadataOnly {
+		for i := range traces {
+			traces[i].SpanSet = nil
+			traces[i].SpanSets = nil
+		}
+	}
+	if traces == nil {
+		traces = []tempoSearchResult{}
+	}
+	return traces, nil
+}
+
+var SearchTempoTraces = mcpgrafana.MustTool(
+	"search_tempo_traces",
+	"Searches a Tempo datasource for traces matching a TraceQL query, returning each trace's ID, root service and span name, start time, duration in milliseconds and the span sets matched by the query. Set `mostRecent` to get the newest traces first and `metadataOnly` to omit the span sets when only trace IDs and durations are needed. Set `estimateOnly` to only get the number of blocks, jobs and bytes Tempo would search. Set `pageSize` to receive the traces in pages, passing the returned `nextPageToken` as `pageToken` to fetch the next one. Defaults to the last hour and 20 traces (max 100).",
+	guardTimeRange(searchTempoTracesTool),
+	mcp.WithTitleAnnotation("Search Tempo traces"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// GetTempoSpanEventsParams defines the parameters for fetching the events
+// attached to the spans of a trace.
+type GetTempoSpanEventsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	TraceID       string `json:"traceId" jsonschema:"required,description=The ID of the trace"`
+	ErrorsOnly    bool   `json:"errorsOnly,omitempty" jsonschema:"description=Optionally\\, only return events of spans with an error status"`
+}
+
+// spanEvent is a span event, with the OpenTelemetry exception attributes
+// extracted.
+type spanEvent struct {
+	Time                time.Time         `json:"time"`
+	Name                string            `json:"name"`
+	ExceptionType       string            `json:"exceptionType,omitempty"`
+	ExceptionMessage    string            `json:"exceptionMessage,omitempty"`
+	ExceptionStacktrace string            `json:"exceptionStacktrace,omitempty"`
+	Attributes          map[string]string `json:"attributes,omitempty"`
+}
+
+// spanEvents are the events of a single span.
+type spanEvents struct {
+	SpanID  string      `json:"spanId"`
+	Service string      `json:"service"`
+	Name    string      `json:"name"`
+	IsError bool        `json:"isError,omitempty"`
+	Events  []spanEvent `json:"events"`
+}
+
+// eventsBySpan returns the events of each span which has any, ordered by
+// span start time.
+func eventsBySpan(spans []tempoSpan, errorsOnly bool) []spanEvents {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+	result := []spanEvents{}
+	for _, s := range spans {
+		if len(s.Events) == 0 || (errorsOnly && !s.IsError) {
+			continue
+		}
+		se := spanEvents{SpanID: s.SpanID, Service: s.ServiceName, Name: s.Name, IsError: s.IsError}
+		for _, e := range s.Events {
+			event := spanEvent{Time: time.Unix(0, int64(e.TimeUnixNano)).UTC(), Name: e.Name}
+			for _, a := range e.Attributes {
+				switch a.Key {
+				case "exception.type":
+					event.ExceptionType = a.Value.String()
+				case "exception.message":
+					event.ExceptionMessage = a.Value.String()
+				case "exception.stacktrace":
+					event.ExceptionStacktrace = a.Value.String()
+				default:
+					if event.Attributes == nil {
+						event.Attributes = map[string]string{}
+					}
+					event.Attributes[a.Key] = a.Value.String()
+				}
+			}
+			se.Events = append(se.Events, event)
+		}
+		result = append(result, se)
+	}
+	return result
+}
+
+func getTempoSpanEvents(ctx context.Context, args GetTempoSpanEventsParams) ([]spanEvents, error) {
+	if args.TraceID == "" {
+		return nil, fmt.Errorf("traceId is required")
+	}
+
+	client, err := newTempoClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+
+	trace, err := client.trace(ctx, args.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching trace %s: %w", args.TraceID, err)
+	}
+	return eventsBySpan(trace.spans(), args.ErrorsOnly), nil
+}
+
+var GetTempoSpanEvents = mcpgrafana.MustTool(
+	"get_tempo_span_events",
+	"Returns only the events attached to the spans of a trace in a Tempo datasource, grouped by span and ordered by span start time. Exception events have their `exception.type`, `exception.message` and `exception.stacktrace` attributes extracted, so stack traces can be read without fetching the whole trace. Set `errorsOnly` to only include spans with an error status. Spans without events are omitted.",
+	getTempoSpanEvents,
+	mcp.WithTitleAnnotation("Get Tempo span events"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// tempoTagScope is a set of attribute names returned by Tempo's tag search.
+type tempoTagScope struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// tempoTagValue is a value of a Tempo attribute with its TraceQL type, such
+// as string, int, float, bool or duration
+type tempoTagValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+var (
+	tempoTagNamesCache  = newTTLCache[[]tempoTagScope]()
+	tempoTagValuesCache = newTTLCache[[]tempoTagValue]()
+)
+
+// tagNames lists attribute names using Tempo's /api/v2/search/tags endpoint.
+func (c *tempoClient) tagNames(ctx context.Context, scope, query string, start, end time.Time) ([]tempoTagScope, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	if scope != "" {
+		params.Set("scope", scope)
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	body, err := c.get(ctx, "/api/v2/search/tags", params)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Scopes []tempoTagScope `json:"scopes"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling tags response: %w", err)
+	}
+	return response.Scopes, nil
+}
+
+// tagValues lists the values of an attribute using Tempo's
+// /api/v2/search/tag/{tag}/values endpoint.
+func (c *tempoClient) tagValues(ctx context.Context, tag, query string, start, end time.Time) ([]tempoTagValue, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	escapedTag, err := sanitizePathSegment("tag name", tag)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.get(ctx, "/api/v2/search/tag/"+escapedTag+"/values", params)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		TagValues []tempoTagValue `json:"tagValues"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling tag values response: %w", err)
+	}
+	return response.TagValues, nil
+}
+
+// ListTempoTagNamesParams defines the parameters for listing attribute names.
+type ListTempoTagNamesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	Scope         string `json:"scope,omitempty" jsonschema:"enum=resource,enum=span,enum=intrinsic,description=Optionally\\, only list attributes in this scope"`
+	Query         string `json:"query,omitempty" jsonschema:"description=Optionally\\, a TraceQL query restricting the attributes to those of matching spans"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+}
+
+func listTempoTagNames(ctx context.Context, args ListTempoTagNamesParams) ([]tempoTagScope, error) {
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{args.DatasourceUID, args.Scope, args.Query, args.StartTime, args.EndTime}, "\x00")
+	scopes, err := tempoTagNamesCache.getOrLoad(ctx, key, func() ([]tempoTagScope, error) {
+		client, err := newTempoClient(ctx, args.DatasourceUID)
+		if err != nil {
+			return nil, fmt.Errorf("creating Tempo client: %w", err)
+		}
+		return client.tagNames(ctx, args.Scope, args.Query, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		scopes = []tempoTagScope{}
+	}
+	return scopes, nil
+}
+
+var ListTempoTagNames = mcpgrafana.MustTool(
+	"list_tempo_tag_names",
+	"Lists the span and resource attribute names (tags) in a Tempo datasource, grouped by scope (resource, span or intrinsic), for use in TraceQL queries. Defaults to attributes seen in the last hour.",
+	guardTimeRange(listTempoTagNames),
+	mcp.WithTitleAnnotation("List Tempo tag names"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// ListTempoTagValuesParams defines the parameters for listing the values of
+// an attribute.
+type ListTempoTagValuesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource to query"`
+	TagName       string `json:"tagName" jsonschema:"required,description=The scoped attribute name\\, e.g. 'resource.service.name' or 'span.http.status_code'"`
+	Query         string `json:"query,omitempty" jsonschema:"description=Optionally\\, a TraceQL query restricting the values to those of matching spans"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start time in RFC3339 format or relative to now (e.g. 'now-1h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end time in RFC3339 format or relative to now. Defaults to now"`
+}
+
+// listTempoTagValues returns the values of an attribute with their types.
+func listTempoTagValues(ctx context.Context, args ListTempoTagValuesParams) ([]tempoTagValue, error) {
+	if args.TagName == "" {
+		return nil, fmt.Errorf("tagName is required")
+	}
+	start, end, err := tempoTimeRange(ctx, args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{args.DatasourceUID, args.TagName, args.Query, args.StartTime, args.EndTime}, "\x00")
+	values, err := tempoTagValuesCache.getOrLoad(ctx, key, func() ([]tempoTagValue, error) {
+		client, err := newTempoClient(ctx, args.DatasourceUID)
+		if err != nil {
+			return nil, fmt.Errorf("creating Tempo client: %w", err)
+		}
+		return client.tagValues(ctx, args.TagName, args.Query, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+var ListTempoTagValues = mcpgrafana.MustTool(
+	"list_tempo_tag_values",
+	"Lists the values of a span or resource attribute in a Tempo datasource, such as the service names for 'resource.service.name', each with its TraceQL type (e.g. string, int, float, bool or duration). Use the type to write correctly typed TraceQL comparisons: quote string values (`resource.service.name = \"checkout\"`) but not int, float, bool or duration values (`span.http.status_code = 500`, `duration > 2s`). Defaults to values seen in the last hour.",
+	guardTimeRange(listTempoTagValues),
+	mcp.WithTitleAnnotation("List Tempo tag values"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
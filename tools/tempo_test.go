@@ -0,0 +1,368 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTrace returns an OTLP JSON trace with a root span in the frontend
+// service calling two backend spans. The duration of the backend query span
+// is given in milliseconds, and it errors if failed is set.
+func testTrace(traceID string, queryMs int, failed bool) string {
+	status := `{}`
+	if failed {
+		status = `{"code":"STATUS_CODE_ERROR"}`
+	}
+	const ms = 1_000_000
+	return fmt.Sprintf(`{"batches":[
+		{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"frontend"}}]},
+		 "scopeSpans":[{"spans":[
+			{"traceId":"%[1]s","spanId":"AAAAAAAAAAE=","name":"GET /","startTimeUnixNano":"0","endTimeUnixNano":"%[2]d"}
+		 ]}]},
+		{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"backend"}}]},
+		 "scopeSpans":[{"spans":[
+			{"traceId":"%[1]s","spanId":"AAAAAAAAAAI=","parentSpanId":"AAAAAAAAAAE=","name":"query","startTimeUnixNano":"%[3]d","endTimeUnixNano":"%[4]d","status":%[5]s},
+			{"traceId":"%[1]s","spanId":"AAAAAAAAAAM=","parentSpanId":"AAAAAAAAAAE=","name":"cache","startTimeUnixNano":"0","endTimeUnixNano":"%[3]d"}
+		 ]}]}
+	]}`, traceID, 100*ms, ms, (1+queryMs)*ms, status)
+}
+
+func TestOTLPTraceSpans(t *testing.T) {
+	var trace otlpTrace
+	require.NoError(t, json.Unmarshal([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, true)), &trace))
+
+	spans := trace.spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "00000000000000000000000000000001", spans[0].TraceID)
+	assert.Equal(t, "0000000000000001", spans[0].SpanID)
+	assert.Equal(t, "frontend", spans[0].ServiceName)
+	assert.Equal(t, "100ms", spans[0].Duration.String())
+	assert.Equal(t, "0000000000000001", spans[1].ParentSpanID)
+	assert.Equal(t, "backend", spans[1].ServiceName)
+	assert.True(t, spans[1].IsError)
+	assert.False(t, spans[2].IsError)
+}
+
+func TestAggregateTempoTracesFlamegraph(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/search":
+			assert.Equal(t, `{ .http.status_code = 200 }`, r.URL.Query().Get("q"))
+			assert.Equal(t, "3", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"traces":[{"traceID":"1"},{"traceID":"2"},{"traceID":"3"}]}`))
+		case r.URL.Path == "/api/traces/1":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, false)))
+		case r.URL.Path == "/api/traces/2":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAg==", 70, true)))
+		case strings.HasPrefix(r.URL.Path, "/api/traces/"):
+			http.Error(w, "trace not found", http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	result, err := aggregateTempoTracesFlamegraph(ctx, AggregateTempoTracesFlamegraphParams{
+		DatasourceUID: "tempo",
+		Query:         `{ .http.status_code = 200 }`,
+		Limit:         3,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.TracesMatched)
+	assert.Equal(t, 2, result.TracesAggregated)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "trace 3")
+
+	root := result.Root
+	assert.Equal(t, 2, root.Count)
+	assert.Equal(t, 200.0, root.TotalDurationMs)
+	require.Len(t, root.Children, 1)
+
+	frontend := root.Children[0]
+	assert.Equal(t, "frontend", frontend.Service)
+	assert.Equal(t, "GET /", frontend.Name)
+	assert.Equal(t, 2, frontend.Count)
+	assert.Equal(t, 200.0, frontend.TotalDurationMs)
+	// Each trace spends 1ms in the cache and the query duration in the query
+	// span, leaving the rest as the root span's self time.
+	assert.Equal(t, 200.0-2-120, frontend.SelfDurationMs)
+
+	require.Len(t, frontend.Children, 2)
+	query := frontend.Children[0]
+	assert.Equal(t, "backend", query.Service)
+	assert.Equal(t, "query", query.Name)
+	assert.Equal(t, 2, query.Count)
+	assert.Equal(t, 1, query.ErrorCount)
+	assert.Equal(t, 120.0, query.TotalDurationMs)
+	assert.Equal(t, 120.0, query.SelfDurationMs)
+	assert.Equal(t, "cache", frontend.Children[1].Name)
+	assert.Equal(t, 2.0, frontend.Children[1].TotalDurationMs)
+}
+
+func TestEnforceTraceLimit(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, DefaultTempoTraceLimit, enforceTraceLimit(ctx, 0))
+	assert.Equal(t, 10, enforceTraceLimit(ctx, 10))
+	assert.Equal(t, MaxTempoTraceLimit, enforceTraceLimit(ctx, 1000))
+}
+
+func TestFindTempoSlowestSpans(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/search":
+			_, _ = w.Write([]byte(`{"traces":[{"traceID":"1"},{"traceID":"2"}]}`))
+		case "/api/traces/1":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, false)))
+		case "/api/traces/2":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAg==", 150, true)))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	result, err := findTempoSlowestSpans(ctx, FindTempoSlowestSpansParams{
+		DatasourceUID: "tempo",
+		Limit:         3,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.TracesMatched)
+	assert.Equal(t, 2, result.TracesSearched)
+	require.Len(t, result.Spans, 3)
+
+	// The second trace's query span outlasts its 100ms root span.
+	assert.Equal(t, "query", result.Spans[0].Name)
+	assert.Equal(t, "backend", result.Spans[0].Service)
+	assert.Equal(t, 150.0, result.Spans[0].DurationMs)
+	assert.Equal(t, "00000000000000000000000000000002", result.Spans[0].TraceID)
+	assert.Equal(t, "0000000000000002", result.Spans[0].SpanID)
+	assert.True(t, result.Spans[0].IsError)
+	assert.Equal(t, "GET /", result.Spans[1].Name)
+	assert.Equal(t, 100.0, result.Spans[1].DurationMs)
+	assert.Equal(t, "GET /", result.Spans[2].Name)
+}
+
+func TestGenerateTempoDeeplink(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+
+	t.Run("trace ID with relative range", func(t *testing.T) {
+		link, err := generateTempoDeeplink(ctx, GenerateTempoDeeplinkParams{
+			DatasourceUID: "tempo",
+			TraceID:       "abc123",
+			StartTime:     "now-6h",
+		})
+		require.NoError(t, err)
+
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		assert.Equal(t, "/explore", u.Path)
+		assert.Equal(t, "1", u.Query().Get("schemaVersion"))
+		assert.JSONEq(t, `{"a": {
+			"datasource": "tempo",
+			"queries": [{"refId": "A", "datasource": {"uid": "tempo", "type": "tempo"}, "queryType": "traceql", "query": "abc123"}],
+			"range": {"from": "now-6h", "to": "now"}
+		}}`, u.Query().Get("panes"))
+	})
+
+	t.Run("TraceQL query with absolute range", func(t *testing.T) {
+		link, err := generateTempoDeeplink(ctx, GenerateTempoDeeplinkParams{
+			DatasourceUID: "tempo",
+			Query:         `{ status = error }`,
+			StartTime:     "2024-01-01T00:00:00Z",
+			EndTime:       "2024-01-01T01:00:00Z",
+		})
+		require.NoError(t, err)
+
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		var panes map[string]explorePane
+		require.NoError(t, json.Unmarshal([]byte(u.Query().Get("panes")), &panes))
+		assert.Equal(t, `{ status = error }`, panes["a"].Queries[0]["query"])
+		assert.Equal(t, exploreRange{From: "1704067200000", To: "1704070800000"}, panes["a"].Range)
+	})
+
+	t.Run("requires exactly one of trace ID and query", func(t *testing.T) {
+		_, err := generateTempoDeeplink(ctx, GenerateTempoDeeplinkParams{DatasourceUID: "tempo"})
+		assert.Error(t, err)
+		_, err = generateTempoDeeplink(ctx, GenerateTempoDeeplinkParams{DatasourceUID: "tempo", TraceID: "a", Query: "{}"})
+		assert.Error(t, err)
+	})
+}
+
+func TestGetTempoTracesBatch(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/traces/1":
+			_, _ = w.Write([]byte(testTrace("AAAAAAAAAAAAAAAAAAAAAQ==", 50, true)))
+		default:
+			http.Error(w, "trace not found", http.StatusNotFound)
+		}
+	})
+
+	result, err := getTempoTracesBatch(ctx, GetTempoTracesBatchParams{
+		DatasourceUID: "tempo",
+		TraceIDs:      []string{"1", "2"},
+	})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	assert.Equal(t, "1", result[0].TraceID)
+	assert.Empty(t, result[0].Error)
+	require.Len(t, result[0].Spans, 3)
+	assert.Equal(t, "GET /", result[0].Spans[0].Name)
+	assert.Equal(t, "0000000000000001", result[0].Spans[1].ParentSpanID)
+
+	assert.Equal(t, "2", result[1].TraceID)
+	assert.Contains(t, result[1].Error, "404")
+	assert.Empty(t, result[1].Spans)
+
+	_, err = getTempoTracesBatch(ctx, GetTempoTracesBatchParams{DatasourceUID: "tempo"})
+	assert.Error(t, err)
+}
+
+func TestSearchTempoTraces(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("mostRecent"))
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"traces":[{"traceID":"1","rootServiceName":"frontend","durationMs":120,"spanSets":[{"spans":[{"spanID":"a"}],"matched":1}]}]}`))
+	})
+
+	t.Run("with span sets", func(t *testing.T) {
+		traces, err := searchTempoTraces(ctx, SearchTempoTracesParams{DatasourceUID: "tempo", Limit: 5, MostRecent: true})
+		require.NoError(t, err)
+		require.Len(t, traces, 1)
+		assert.Equal(t, "frontend", traces[0].RootServiceName)
+		assert.Equal(t, int64(120), traces[0].DurationMs)
+		assert.Len(t, traces[0].SpanSets, 1)
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		traces, err := searchTempoTraces(ctx, SearchTempoTracesParams{DatasourceUID: "tempo", Limit: 5, MostRecent: true, MetadataOnly: true})
+		require.NoError(t, err)
+		require.Len(t, traces, 1)
+		assert.Equal(t, "1", traces[0].TraceID)
+		assert.Nil(t, traces[0].SpanSets)
+
+		b, err := json.Marshal(traces[0])
+		require.NoError(t, err)
+		assert.NotContains(t, string(b), "spanSet")
+	})
+}
+
+func TestGetTempoSpanEvents(t *testing.T) {
+	const trace = `{"resourceSpans":[
+		{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		 "scopeSpans":[{"spans":[
+			{"spanId":"0000000000000002","name":"charge","startTimeUnixNano":"2000","endTimeUnixNano":"3000","status":{"code":2},
+			 "events":[{"timeUnixNano":"2500","name":"exception","attributes":[
+				{"key":"exception.type","value":{"stringValue":"PaymentError"}},
+				{"key":"exception.message","value":{"stringValue":"card declined"}},
+				{"key":"exception.stacktrace","value":{"stringValue":"at charge (pay.go:10)"}},
+				{"key":"retry","value":{"intValue":"1"}}
+			 ]}]},
+			{"spanId":"0000000000000001","name":"GET /checkout","startTimeUnixNano":"1000","endTimeUnixNano":"4000",
+			 "events":[{"timeUnixNano":"1500","name":"cache miss"}]},
+			{"spanId":"0000000000000003","name":"render","startTimeUnixNano":"3000","endTimeUnixNano":"3500"}
+		 ]}]}
+	]}`
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/traces/abc", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(trace))
+	})
+
+	t.Run("all spans with events", func(t *testing.T) {
+		spans, err := getTempoSpanEvents(ctx, GetTempoSpanEventsParams{DatasourceUID: "tempo", TraceID: "abc"})
+		require.NoError(t, err)
+		require.Len(t, spans, 2)
+		assert.Equal(t, "GET /checkout", spans[0].Name)
+		assert.Equal(t, "cache miss", spans[0].Events[0].Name)
+
+		assert.Equal(t, "charge", spans[1].Name)
+		assert.Equal(t, "checkout", spans[1].Service)
+		assert.True(t, spans[1].IsError)
+		require.Len(t, spans[1].Events, 1)
+		event := spans[1].Events[0]
+		assert.Equal(t, "PaymentError", event.ExceptionType)
+		assert.Equal(t, "card declined", event.ExceptionMessage)
+		assert.Equal(t, "at charge (pay.go:10)", event.ExceptionStacktrace)
+		assert.Equal(t, map[string]string{"retry": "1"}, event.Attributes)
+	})
+
+	t.Run("errors only", func(t *testing.T) {
+		spans, err := getTempoSpanEvents(ctx, GetTempoSpanEventsParams{DatasourceUID: "tempo", TraceID: "abc", ErrorsOnly: true})
+		require.NoError(t, err)
+		require.Len(t, spans, 1)
+		assert.Equal(t, "0000000000000002", spans[0].SpanID)
+	})
+}
+
+func TestListTempoTags(t *testing.T) {
+	requests := map[string]int{}
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/search/tags":
+			assert.Equal(t, "resource", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"scopes":[{"name":"resource","tags":["service.name","k8s.namespace.name"]}]}`))
+		case "/api/v2/search/tag/resource.service.name/values":
+			_, _ = w.Write([]byte(`{"tagValues":[{"type":"string","value":"checkout"},{"type":"string","value":"cart"}]}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+	ctx = withCacheTTL(ctx, time.Minute)
+
+	for range 2 {
+		scopes, err := listTempoTagNames(ctx, ListTempoTagNamesParams{DatasourceUID: "tempo", Scope: "resource"})
+		require.NoError(t, err)
+		require.Len(t, scopes, 1)
+		assert.Equal(t, []string{"service.name", "k8s.namespace.name"}, scopes[0].Tags)
+
+		values, err := listTempoTagValues(ctx, ListTempoTagValuesParams{DatasourceUID: "tempo", TagName: "resource.service.name"})
+		require.NoError(t, err)
+		assert.Equal(t, []tempoTagValue{{Type: "string", Value: "checkout"}, {Type: "string", Value: "cart"}}, values)
+	}
+	assert.Equal(t, map[string]int{
+		"/api/v2/search/tags":                             1,
+		"/api/v2/search/tag/resource.service.name/values": 1,
+	}, requests)
+}
+
+func TestEstimateTempoSearch(t *testing.T) {
+	ctx := newMockDatasourceContext(t, "tempo", "tempo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"traces":[{"traceID":"1"}],"metrics":{"inspectedBytes":"2048","totalBlocks":12,"completedJobs":3,"totalJobs":40,"totalBlockBytes":"987654321"}}`))
+	})
+
+	result, err := searchTempoTracesTool(ctx, SearchTempoTracesParams{DatasourceUID: "tempo", Query: `{status=error}`, EstimateOnly: true})
+	require.NoError(t, err)
+	estimate, ok := unwrapResult(result).(*tempoSearchEstimate)
+	require.True(t, ok, "expected an estimate, got %T", result)
+	assert.Equal(t, otlpUint64(12), estimate.Metrics.TotalBlocks)
+	assert.Equal(t, otlpUint64(40), estimate.Metrics.TotalJobs)
+	assert.Equal(t, otlpUint64(987654321), estimate.Metrics.TotalBlockBytes)
+	assert.Equal(t, time.Hour, estimate.End.Sub(estimate.Start))
+}
@@ -5,7 +5,9 @@ package mcpgrafana
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -524,3 +526,229 @@ func TestCreateJSONSchemaFromHandler(t *testing.T) {
 	assert.Equal(t, "boolean", optionalProperty.Type)
 	assert.Equal(t, "An optional parameter", optionalProperty.Description)
 }
+
+func sliceToolHandler(ctx context.Context, params testToolParams) ([]TestResult, error) {
+	return []TestResult{{Name: params.Name, Value: params.Value}}, nil
+}
+
+func anyToolHandler(ctx context.Context, params testToolParams) (any, error) {
+	if params.Name == "list" {
+		return []string{"a", "b"}, nil
+	}
+	return TestResult{Name: params.Name, Value: params.Value}, nil
+}
+
+// testUnionResult is a list of TestResults, or a single TestResult.
+type testUnionResult struct{ value any }
+
+func (testUnionResult) ResultTypes() []reflect.Type {
+	return []reflect.Type{reflect.TypeOf([]TestResult{}), reflect.TypeOf(TestResult{})}
+}
+
+func (r testUnionResult) ResultValue() any { return r.value }
+
+func (r testUnionResult) MarshalJSON() ([]byte, error) { return json.Marshal(r.value) }
+
+func unionToolHandler(ctx context.Context, params testToolParams) (testUnionResult, error) {
+	if params.Name == "list" {
+		return testUnionResult{[]TestResult{{Name: "a"}, {Name: "b"}}}, nil
+	}
+	return testUnionResult{TestResult{Name: params.Name, Value: params.Value}}, nil
+}
+
+// testTree is a recursive result.
+type testTree struct {
+	Name     string     `json:"name"`
+	Children []testTree `json:"children"`
+}
+
+// mismatchedResult marshals to its JSON, which need not match the output
+// schema reflected from its type.
+type mismatchedResult struct {
+	JSON string `json:"name"`
+}
+
+func (r mismatchedResult) MarshalJSON() ([]byte, error) { return []byte(r.JSON), nil }
+
+func TestStructuredOutput(t *testing.T) {
+	ctx := context.Background()
+	request := func(name string) mcp.CallToolRequest {
+		var r mcp.CallToolRequest
+		r.Params.Arguments = map[string]any{"name": name, "value": 65}
+		return r
+	}
+
+	t.Run("struct result", func(t *testing.T) {
+		tool, handler, err := ConvertTool("struct_tool", "A struct tool", structToolHandler)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"object","properties":{"name":{"type":"string"},"value":{"type":"integer"}}}`, string(tool.RawOutputSchema))
+
+		result, err := handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"name": "test", "value": json.Number("65")}, result.StructuredContent)
+		require.Len(t, result.Content, 1)
+		assert.Equal(t, `{"name":"test","value":65}`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("slice result is wrapped", func(t *testing.T) {
+		tool, handler, err := ConvertTool("slice_tool", "A slice tool", sliceToolHandler)
+		require.NoError(t, err)
+		var schema map[string]any
+		require.NoError(t, json.Unmarshal(tool.RawOutputSchema, &schema))
+		assert.Equal(t, "object", schema["type"])
+		assert.Equal(t, []any{"result"}, schema["required"])
+
+		result, err := handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"result": []any{map[string]any{"name": "test", "value": json.Number("65")}}}, result.StructuredContent)
+		assert.Equal(t, `[{"name":"test","value":65}]`, result.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("interface result", func(t *testing.T) {
+		tool, handler, err := ConvertTool("any_tool", "An any tool", anyToolHandler)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"object"}`, string(tool.RawOutputSchema))
+
+		result, err := handler(ctx, request("list"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"result": []any{"a", "b"}}, result.StructuredContent)
+
+		result, err = handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"name": "test", "value": json.Number("65")}, result.StructuredContent)
+	})
+
+	t.Run("union result", func(t *testing.T) {
+		tool, handler, err := ConvertTool("union_tool", "A union tool", unionToolHandler)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"object","anyOf":[
+			{"type":"object","properties":{"name":{"type":"string"},"value":{"type":"integer"}}},
+			{"type":"object","properties":{"result":{"type":"array","items":{"type":"object","properties":{"name":{"type":"string"},"value":{"type":"integer"}}}}},"required":["result"]}
+		]}`, string(tool.RawOutputSchema))
+
+		result, err := handler(ctx, request("list"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"result": []any{
+			map[string]any{"name": "a", "value": json.Number("0")},
+			map[string]any{"name": "b", "value": json.Number("0")},
+		}}, result.StructuredContent)
+
+		result, err = handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"name": "test", "value": json.Number("65")}, result.StructuredContent)
+
+		_, handler, err = ConvertTool("nil_union_tool", "A union tool", func(ctx context.Context, params testToolParams) (testUnionResult, error) {
+			return testUnionResult{}, nil
+		})
+		require.NoError(t, err)
+		result, err = handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("nested union result", func(t *testing.T) {
+		tool, _, err := ConvertTool("nested_union_tool", "A nested union tool", func(ctx context.Context, params testToolParams) (struct {
+			Result testUnionResult `json:"result"`
+		}, error) {
+			return struct {
+				Result testUnionResult `json:"result"`
+			}{}, nil
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"object","properties":{"result":{"anyOf":[
+			{"type":"array","items":{"type":"object","properties":{"name":{"type":"string"},"value":{"type":"integer"}}}},
+			{"type":"object","properties":{"name":{"type":"string"},"value":{"type":"integer"}}}
+		]}}}`, string(tool.RawOutputSchema))
+	})
+
+	t.Run("recursive result", func(t *testing.T) {
+		tool, _, err := ConvertTool("tree_tool", "A tree tool", func(ctx context.Context, params testToolParams) (testTree, error) {
+			return testTree{Name: params.Name}, nil
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"object","properties":{
+			"name":{"type":"string"},
+			"children":{"type":"array","items":{"type":"object"}}
+		}}`, string(tool.RawOutputSchema))
+	})
+
+	t.Run("result not matching the output schema is returned", func(t *testing.T) {
+		_, handler, err := ConvertTool("mismatch_tool", "A mismatched tool", func(ctx context.Context, params testToolParams) (mismatchedResult, error) {
+			return mismatchedResult{`{"name": 1}`}, nil
+		})
+		require.NoError(t, err)
+
+		result, err := handler(ctx, request("test"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"name": json.Number("1")}, result.StructuredContent)
+	})
+
+	t.Run("text results have no output schema", func(t *testing.T) {
+		tool, _, err := ConvertTool("string_tool", "A string tool", stringToolHandler)
+		require.NoError(t, err)
+		assert.Nil(t, tool.RawOutputSchema)
+
+		tool, _, err = ConvertTool("test_tool", "A test tool", testToolHandler)
+		require.NoError(t, err)
+		assert.Nil(t, tool.RawOutputSchema)
+	})
+}
+
+func TestValidateStructuredContent(t *testing.T) {
+	schema := createOutputSchema(reflect.TypeOf([]TestResult{}))
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"result": []any{}}))
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"result": nil}))
+	assert.ErrorContains(t, validateStructuredContent(schema, map[string]any{}), `missing required property "result"`)
+	assert.ErrorContains(t, validateStructuredContent(schema, map[string]any{"result": "a"}), `property "result" is not of type array`)
+
+	schema = createOutputSchema(reflect.TypeOf(TestResult{}))
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"name": "a", "value": float64(1)}))
+	assert.ErrorContains(t, validateStructuredContent(schema, map[string]any{"name": "a", "value": 1.5}), `property "value" is not of type integer`)
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"name": "a", "value": json.Number("9007199254740993")}))
+	assert.ErrorContains(t, validateStructuredContent(schema, map[string]any{"name": "a", "value": json.Number("1.5")}), `property "value" is not of type integer`)
+
+	schema = createOutputSchema(reflect.TypeOf(testUnionResult{}))
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"result": []any{}}))
+	assert.NoError(t, validateStructuredContent(schema, map[string]any{"name": "a", "value": float64(1)}))
+	assert.ErrorContains(t, validateStructuredContent(schema, map[string]any{"value": "a"}), `result matches none of the result types`)
+}
+
+func TestVersionedTool(t *testing.T) {
+	v1 := MustTool("versioned_tool", "Returns a list.", sliceToolHandler)
+	v2 := MustTool("versioned_tool", "Returns an object.", structToolHandler)
+	versioned := NewVersionedTool("It will be removed in the next release.", v1, v2)
+
+	names := func(tools []Tool) []string {
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.Tool.Name)
+		}
+		return out
+	}
+
+	t.Run("unversioned name serves oldest version", func(t *testing.T) {
+		tools := versioned.Tools()
+		assert.Equal(t, []string{"versioned_tool_v1", "versioned_tool_v2", "versioned_tool"}, names(tools))
+		assert.Equal(t, "Returns a list.\n\nDeprecated: this version of the tool is kept for compatibility. Use versioned_tool_v2 for the latest version. It will be removed in the next release.", tools[0].Tool.Description)
+		assert.Equal(t, "Returns an object.", tools[1].Tool.Description)
+		assert.Equal(t, tools[0].Tool.Description, tools[2].Tool.Description)
+		assert.Equal(t, v1.Tool.RawOutputSchema, tools[2].Tool.RawOutputSchema)
+		// The versions passed in are not modified.
+		assert.Equal(t, "versioned_tool", v1.Tool.Name)
+	})
+
+	t.Run("unversioned name serves latest version", func(t *testing.T) {
+		ServeLatestToolVersions = true
+		defer func() { ServeLatestToolVersions = false }()
+		tools := versioned.Tools()
+		assert.Equal(t, []string{"versioned_tool_v1", "versioned_tool_v2", "versioned_tool"}, names(tools))
+		assert.Equal(t, "Returns an object.", tools[2].Tool.Description)
+		assert.Equal(t, v2.Tool.RawOutputSchema, tools[2].Tool.RawOutputSchema)
+	})
+
+	t.Run("versions must share a name", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewVersionedTool("", v1, MustTool("other_tool", "Other.", structToolHandler))
+		})
+	})
+}